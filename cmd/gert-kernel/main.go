@@ -11,6 +11,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -42,6 +43,12 @@ var rootCmd = &cobra.Command{
 
 // --- validate ---
 
+var (
+	validateStrict     bool
+	validateCheckTools bool
+	validateProfile    bool
+)
+
 var validateCmd = &cobra.Command{
 	Use:   "validate [runbook.yaml]",
 	Short: "Validate a kernel/v0 runbook YAML (3-phase pipeline)",
@@ -62,14 +69,30 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		return runValidateTool(filePath)
 	}
 
-	rb, errs := kvalidate.ValidateFile(filePath)
+	rb, errs, profile := kvalidate.ValidateFileProfiled(filePath)
+	if validateProfile {
+		fmt.Fprintf(os.Stderr, "  Validation profile: parse %dms, schema %dms, domain %dms\n",
+			profile.ParseMs, profile.SchemaMs, profile.DomainMs)
+	}
+	if rb != nil {
+		toolErrs := kvalidate.CheckToolFiles(rb, filepath.Dir(filePath))
+		if validateCheckTools {
+			errs = append(errs, toolErrs...)
+		} else if len(toolErrs) > 0 {
+			fmt.Fprintf(os.Stderr, "  ⚠ %d tool definition(s) missing or unreadable — add --check-tools to verify tool files\n", len(toolErrs))
+		}
+	}
 	if len(errs) > 0 {
 		var errors []*kvalidate.ValidationError
 		var warnings []*kvalidate.ValidationError
+		var hints []*kvalidate.ValidationError
 		for _, e := range errs {
-			if e.Severity == "warning" {
+			switch {
+			case e.Severity == "hint":
+				hints = append(hints, e)
+			case e.Severity == "warning" && !validateStrict:
 				warnings = append(warnings, e)
-			} else {
+			default:
 				errors = append(errors, e)
 			}
 		}
@@ -79,6 +102,12 @@ func runValidate(cmd *cobra.Command, args []string) error {
 				fmt.Fprintf(os.Stderr, "    at: %s\n", w.Path)
 			}
 		}
+		for _, h := range hints {
+			fmt.Fprintf(os.Stderr, "  ℹ [%s] %s\n", h.Phase, h.Message)
+			if h.Path != "" {
+				fmt.Fprintf(os.Stderr, "    at: %s\n", h.Path)
+			}
+		}
 		if len(errors) > 0 {
 			fmt.Fprintf(os.Stderr, "Validation failed: %d error(s)\n\n", len(errors))
 			for i, e := range errors {
@@ -99,7 +128,7 @@ func runValidateTool(filePath string) error {
 	if len(errs) > 0 {
 		var errors []*kvalidate.ValidationError
 		for _, e := range errs {
-			if e.Severity == "error" {
+			if e.Severity == "error" || validateStrict {
 				errors = append(errors, e)
 			}
 		}
@@ -157,9 +186,16 @@ var versionCmd = &cobra.Command{
 // --- exec ---
 
 var (
-	execMode  string
-	execVars  []string
-	execTrace string
+	execMode         string
+	execVars         []string
+	execVarFiles     []string
+	execTrace        string
+	execJSONOutput   bool
+	execSkipHooks    bool
+	execNoCache      bool
+	execStrict       bool
+	execAllowEffects []string
+	execTimeout      string
 )
 
 var execCmd = &cobra.Command{
@@ -169,9 +205,46 @@ var execCmd = &cobra.Command{
 	RunE:  runExec,
 }
 
-func runExec(cmd *cobra.Command, args []string) error {
+// execJSONResult is the --json-output document: the RunResult plus the
+// engine's final state.
+type execJSONResult struct {
+	RunID         string           `json:"runId"`
+	Status        string           `json:"status"`
+	Outcome       *kschema.Outcome `json:"outcome,omitempty"`
+	StepsExecuted int              `json:"stepsExecuted"`
+	Duration      string           `json:"duration"`
+	Error         string           `json:"error,omitempty"`
+	Vars          map[string]any   `json:"vars"`
+}
+
+func runExec(cmd *cobra.Command, args []string) (err error) {
 	filePath := args[0]
 
+	// With --json-output, stdout carries only the result document, written
+	// on every exit path; progress output moves to stderr.
+	jsonResult := &execJSONResult{RunID: "run-1", Status: "failed", Duration: "0s", Vars: map[string]any{}}
+	if execJSONOutput {
+		defer func() {
+			if err != nil && jsonResult.Error == "" {
+				jsonResult.Error = err.Error()
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if encErr := enc.Encode(jsonResult); encErr != nil && err == nil {
+				err = encErr
+			}
+		}()
+	}
+
+	var runTimeout time.Duration
+	if execTimeout != "" {
+		d, err := time.ParseDuration(execTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid --timeout %q: expected a positive duration like 30m", execTimeout)
+		}
+		runTimeout = d
+	}
+
 	// Validate first
 	rb, errs := kvalidate.ValidateFile(filePath)
 	if errs != nil {
@@ -187,8 +260,14 @@ func runExec(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Parse --var flags
-	vars := make(map[string]string)
+	// Load --var-file files, then apply --var flags on top
+	vars, err := engine.LoadVarFiles(execVarFiles)
+	if err != nil {
+		return err
+	}
+	for _, w := range engine.SecretVarWarnings(rb, vars) {
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", w)
+	}
 	for _, v := range execVars {
 		parts := strings.SplitN(v, "=", 2)
 		if len(parts) != 2 {
@@ -207,24 +286,85 @@ func runExec(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	// Export spans to an OTLP collector alongside the JSONL trace
+	if endpoint := os.Getenv(trace.EnvOTLPEndpoint); endpoint != "" {
+		headers := trace.ParseOTLPHeaders(os.Getenv(trace.EnvOTLPHeaders))
+		exp, err := trace.NewOTLPExporter(endpoint, headers)
+		if err != nil {
+			return fmt.Errorf("trace: %w", err)
+		}
+		if tw == nil {
+			tw = trace.NewWriter(io.Discard, "run-1")
+		}
+		tw.AddSink(exp)
+		defer func() {
+			if err := tw.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "  ⚠ OTLP export: %v\n", err)
+			}
+		}()
+	}
+
 	// Build run config
 	baseDir := filepath.Dir(filePath)
 	cfg := engine.RunConfig{
-		RunID:   "run-1",
-		Mode:    execMode,
-		Vars:    vars,
-		BaseDir: baseDir,
-		Trace:   tw,
+		RunID:           "run-1",
+		Mode:            execMode,
+		Vars:            vars,
+		BaseDir:         baseDir,
+		Trace:           tw,
+		SkipHooks:       execSkipHooks,
+		NoCache:         execNoCache,
+		StrictContracts: execStrict,
+	}
+	// --allow-effects limits the effects tool steps may declare
+	if cmd.Flags().Changed("allow-effects") {
+		cfg.GovernancePolicy = &kschema.GovernancePolicy{AllowedEffects: execAllowEffects}
+	}
+	out := io.Writer(os.Stdout)
+	if execJSONOutput {
+		out = os.Stderr
+		cfg.Stdout = out
 	}
 
+	banner := fmt.Sprintf("▶ %s (mode: %s", rb.Meta.Name, execMode)
+	if runTimeout > 0 {
+		banner += ", timeout: " + runTimeout.String()
+	}
+	fmt.Fprintln(out, banner+")")
+
+	ctx := context.Background()
+	if runTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, runTimeout)
+		defer cancel()
+	}
 	eng := engine.New(rb, cfg)
-	result := eng.Run(context.Background())
+	for _, w := range eng.Warnings() {
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", w)
+	}
+	result := eng.Run(ctx)
+
+	jsonResult.Status = result.Status
+	jsonResult.Outcome = result.Outcome
+	jsonResult.StepsExecuted = len(eng.VisitedSteps)
+	jsonResult.Duration = result.Duration.String()
+	jsonResult.Vars = eng.Vars()
+
+	// On timeout, keep the partial run for inspection
+	if result.Outcome != nil && result.Outcome.Category == kschema.OutcomeTimedOut {
+		dir, err := engine.WriteManifest(eng.Manifest(result))
+		if err != nil {
+			return fmt.Errorf("run timed out after %s: %w", runTimeout, err)
+		}
+		fmt.Fprintf(out, "\n✗ Timed out after %s\n  Artifacts: %s\n", runTimeout, dir)
+		return fmt.Errorf("run timed out after %s", runTimeout)
+	}
 
 	if result.Outcome != nil {
-		fmt.Printf("\n✓ Outcome: %s (%s)\n", result.Outcome.Category, result.Outcome.Code)
+		fmt.Fprintf(out, "\n✓ Outcome: %s (%s)\n", result.Outcome.Category, result.Outcome.Code)
 		if result.Outcome.Meta != nil {
 			for k, v := range result.Outcome.Meta {
-				fmt.Printf("  %s: %v\n", k, v)
+				fmt.Fprintf(out, "  %s: %v\n", k, v)
 			}
 		}
 	}
@@ -233,19 +373,31 @@ func runExec(cmd *cobra.Command, args []string) error {
 		return result.Error
 	}
 
-	fmt.Printf("  Duration: %s\n", result.Duration)
+	fmt.Fprintf(out, "  Duration: %s\n", result.Duration)
 	return nil
 }
 
 func init() {
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Treat warnings as errors (non-zero exit on any warning)")
+	validateCmd.Flags().BoolVar(&validateCheckTools, "check-tools", false, "Fail when a declared tool's definition file is missing or malformed")
+	validateCmd.Flags().BoolVar(&validateProfile, "profile", false, "Print how long each validation phase took")
+
 	execCmd.Flags().StringVar(&execMode, "mode", "real", "Execution mode: real or dry-run")
 	execCmd.Flags().StringArrayVar(&execVars, "var", nil, "Set a variable (key=value), repeatable")
+	execCmd.Flags().StringArrayVar(&execVarFiles, "var-file", nil, "Load variables from a YAML file, repeatable (--var overrides)")
 	execCmd.Flags().StringVar(&execTrace, "trace", "", "Write trace to JSONL file")
+	execCmd.Flags().BoolVar(&execJSONOutput, "json-output", false, "Write the run result as a JSON object to stdout (progress goes to stderr)")
+	execCmd.Flags().BoolVar(&execSkipHooks, "skip-hooks", false, "Don't invoke step pre_hook/post_hook (offline or replay runs)")
+	execCmd.Flags().BoolVar(&execNoCache, "no-cache", false, "Ignore step cache: settings and always execute tool steps")
+	execCmd.Flags().BoolVar(&execStrict, "strict-contracts", false, "Fail tool steps whose inputs don't match the tool's contract.inputs (default: warn)")
+	execCmd.Flags().StringVar(&execTimeout, "timeout", "", "Stop the run after this long (e.g. 30m) with a timed_out outcome")
+	execCmd.Flags().StringSliceVar(&execAllowEffects, "allow-effects", nil, "Only run tool steps whose contract effects are in this list (e.g. reads,network)")
 
 	testCmd.Flags().StringVar(&testScenario, "scenario", "", "Run only the named scenario (default: all)")
 	testCmd.Flags().BoolVar(&testJSON, "json", false, "Output results as JSON")
 	testCmd.Flags().BoolVar(&testFailFast, "fail-fast", false, "Stop after first failure")
 	testCmd.Flags().StringVar(&testTimeout, "timeout", "30s", "Per-scenario timeout")
+	testCmd.Flags().IntVar(&testParallel, "parallel", 1, "Number of scenarios to replay concurrently")
 
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(execCmd)
@@ -262,8 +414,9 @@ var schemaCmd = &cobra.Command{
 }
 
 var schemaRunbookCmd = &cobra.Command{
-	Use:   "runbook",
-	Short: "Export kernel/v0 runbook JSON Schema",
+	Use:     "runbook",
+	Aliases: []string{"export"},
+	Short:   "Export kernel/v0 runbook JSON Schema",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		data, err := kschema.GenerateRunbookJSONSchema()
 		if err != nil {
@@ -299,6 +452,7 @@ var (
 	testJSON     bool
 	testFailFast bool
 	testTimeout  string
+	testParallel int
 )
 
 var testCmd = &cobra.Command{
@@ -313,10 +467,14 @@ func runTest(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("invalid --timeout: %w", err)
 	}
+	if testParallel < 1 {
+		return fmt.Errorf("invalid --parallel %d: must be at least 1", testParallel)
+	}
 
 	runner := &ktesting.Runner{
-		Timeout:  timeout,
-		FailFast: testFailFast,
+		Timeout:     timeout,
+		FailFast:    testFailFast,
+		Concurrency: testParallel,
 	}
 
 	allPassed := true
@@ -14,18 +14,13 @@ import (
 )
 
 func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "Usage: gert-tui <runbook.yaml> [--mode real|dry-run|replay] [--var key=value] [--scenario name]")
-		os.Exit(1)
-	}
-
-	filePath := os.Args[1]
+	filePath := ""
 	mode := "real"
 	scenario := ""
 	vars := make(map[string]string)
 
-	// Parse flags
-	for i := 2; i < len(os.Args); i++ {
+	// Parse flags; the runbook is the first non-flag argument
+	for i := 1; i < len(os.Args); i++ {
 		arg := os.Args[i]
 		switch {
 		case arg == "--mode" && i+1 < len(os.Args):
@@ -40,24 +35,61 @@ func main() {
 			if len(parts) == 2 {
 				vars[parts[0]] = parts[1]
 			}
+		case strings.HasPrefix(arg, "-"):
+			fmt.Fprintln(os.Stderr, "Usage: gert-tui [runbook.yaml] [--mode real|dry-run|replay] [--var key=value] [--scenario name]")
+			os.Exit(1)
+		case filePath == "":
+			filePath = arg
 		}
 	}
 
+	open := func(path string) (tea.Model, error) {
+		return loadRunbook(path, mode, scenario, vars)
+	}
+
+	var model tea.Model
+	if filePath != "" {
+		m, err := open(filePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		model = m
+	} else {
+		// No runbook given: pick one from the current directory
+		items, err := tui.FindRunbooks(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if len(items) == 0 {
+			fmt.Fprintln(os.Stderr, "No kernel runbooks found under the current directory")
+			os.Exit(1)
+		}
+		model = tui.NewPickerModel(items, open)
+	}
+
+	p := tea.NewProgram(model, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadRunbook validates a runbook and returns the step execution model,
+// configured to start the engine with the startup flags.
+func loadRunbook(filePath, mode, scenario string, vars map[string]string) (tea.Model, error) {
 	// Validate and load runbook
 	rb, errs := kvalidate.ValidateFile(filePath)
-	if errs != nil {
-		for _, e := range errs {
-			if e.Severity == "error" {
-				fmt.Fprintf(os.Stderr, "  [%s] %s\n", e.Phase, e.Message)
-			}
-		}
-		for _, e := range errs {
-			if e.Severity == "error" {
-				fmt.Fprintln(os.Stderr, "Validation failed")
-				os.Exit(1)
-			}
+	var failures []string
+	for _, e := range errs {
+		if e.Severity == "error" {
+			failures = append(failures, fmt.Sprintf("  [%s] %s", e.Phase, e.Message))
 		}
 	}
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("%s\nValidation failed", strings.Join(failures, "\n"))
+	}
 
 	model := tui.NewModel(rb)
 
@@ -76,8 +108,7 @@ func main() {
 		scenarioDir := filepath.Join(dir, "scenarios", base, scenario)
 		s, err := replay.LoadScenarioDir(scenarioDir)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading scenario %q: %v\n", scenario, err)
-			os.Exit(1)
+			return nil, fmt.Errorf("error loading scenario %q: %w", scenario, err)
 		}
 		runCfg.ToolExec = replay.NewReplayExecutor(s)
 		runCfg.Mode = "replay"
@@ -85,10 +116,5 @@ func main() {
 
 	// Wire engine config into model — engine starts on Init()
 	model.SetRunConfig(runCfg)
-
-	p := tea.NewProgram(model, tea.WithAltScreen())
-	if _, err := p.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
+	return model, nil
 }
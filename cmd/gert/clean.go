@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/engine"
+	"github.com/ormasoftchile/gert/pkg/runs"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cleanOlderThan string
+	cleanDryRun    bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove finished run directories older than a retention period",
+	Args:  cobra.NoArgs,
+	RunE:  runClean,
+}
+
+func runClean(cmd *cobra.Command, args []string) error {
+	ttl, err := parseAge(cleanOlderThan)
+	if err != nil {
+		return fmt.Errorf("invalid --older-than: %w", err)
+	}
+
+	removed, err := runs.Clean(historyDir, ttl, cleanDryRun)
+	verb := "removed"
+	if cleanDryRun {
+		verb = "would remove"
+	}
+	for _, r := range removed {
+		fmt.Printf("  %s %s (ended %s)\n", verb, r.RunID, r.EndedAt.Local().Format(time.DateTime))
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Printf("No runs older than %s in %s/\n", cleanOlderThan, historyDir)
+	}
+	return nil
+}
+
+// parseAge parses a retention period: a Go duration such as "12h", optionally
+// led by a whole number of days, as in "7d" or "1d12h".
+func parseAge(s string) (time.Duration, error) {
+	days, rest, ok := strings.Cut(s, "d")
+	if !ok {
+		return time.ParseDuration(s)
+	}
+	n, err := strconv.Atoi(days)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	d := time.Duration(n) * 24 * time.Hour
+	if rest == "" {
+		return d, nil
+	}
+	extra, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d + extra, nil
+}
+
+func init() {
+	cleanCmd.Flags().StringVar(&cleanOlderThan, "older-than", "7d", "Remove runs that ended longer ago than this (e.g. 7d, 12h)")
+	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "List the runs that would be removed without removing them")
+	cleanCmd.Flags().StringVar(&historyDir, "output-dir", engine.DefaultRunsDir, "Base directory of run artifacts (as given to exec --output-dir)")
+	rootCmd.AddCommand(cleanCmd)
+}
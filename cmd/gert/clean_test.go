@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCleanCmd(t *testing.T) {
+	dir := t.TempDir()
+	defer func(d string) { historyDir = d }(historyDir)
+	historyDir = dir
+	defer func() { cleanOlderThan, cleanDryRun = "7d", false }()
+
+	ended := func(ago time.Duration) string {
+		return "ended_at: \"" + time.Now().Add(-ago).UTC().Format(time.RFC3339) + "\"\n"
+	}
+	for id, manifest := range map[string]string{
+		"run-old":     ended(10 * 24 * time.Hour),
+		"run-recent":  ended(time.Hour),
+		"run-running": "started_at: \"2026-01-01T00:00:00Z\"\n",
+	} {
+		os.MkdirAll(filepath.Join(dir, id), 0o755)
+		os.WriteFile(filepath.Join(dir, id, "run.yaml"), []byte(manifest), 0o644)
+	}
+
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"clean", "--dry-run"})
+		return rootCmd.Execute()
+	})
+	if !strings.Contains(out, "would remove run-old") || strings.Contains(out, "run-recent") {
+		t.Errorf("dry-run output:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-old")); err != nil {
+		t.Fatal("--dry-run removed a run")
+	}
+	cleanDryRun = false
+
+	out = captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"clean", "--older-than", "30m"})
+		return rootCmd.Execute()
+	})
+	if !strings.Contains(out, "removed run-old") || !strings.Contains(out, "removed run-recent") {
+		t.Errorf("output:\n%s", out)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "run-running" {
+		t.Errorf("left %v, want only run-running", entries)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input string
+		want  time.Duration
+	}{
+		{"7d", 7 * 24 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"0d", 0},
+		{"36h", 36 * time.Hour},
+	}
+	for _, tt := range tests {
+		d, err := parseAge(tt.input)
+		if err != nil || d != tt.want {
+			t.Errorf("parseAge(%q) = %v, %v; want %v", tt.input, d, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"d", "-1d", "1.5d", "7days", "seven"} {
+		if _, err := parseAge(bad); err == nil {
+			t.Errorf("parseAge(%q): expected error", bad)
+		}
+	}
+}
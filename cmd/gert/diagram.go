@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/diagram"
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diagramFormat string
+	diagramOut    string
+	diagramSVG    bool
+)
+
+var diagramCmd = &cobra.Command{
+	Use:   "diagram [runbook.yaml]",
+	Short: "Render a runbook as a Mermaid, D2, PlantUML or ASCII diagram",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runDiagram,
+}
+
+func runDiagram(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+	format := diagram.Format(diagramFormat)
+
+	rb, errs := kvalidate.ValidateFile(filePath)
+	for _, e := range errs {
+		if e.Severity == "error" {
+			return fmt.Errorf("validation failed for %s: %s", filePath, e)
+		}
+	}
+
+	src, err := diagram.GenerateKernel(rb, format)
+	if err != nil {
+		return err
+	}
+
+	out := []byte(src)
+	if diagramSVG {
+		out, err = renderSVG(format, src)
+		if err != nil {
+			return err
+		}
+		if diagramOut == "" {
+			diagramOut = strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".svg"
+		}
+	}
+
+	if diagramOut == "" {
+		_, err := os.Stdout.Write(out)
+		return err
+	}
+	if err := os.WriteFile(diagramOut, out, 0o644); err != nil {
+		return fmt.Errorf("write diagram: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "  ✓ %s\n", diagramOut)
+	return nil
+}
+
+// svgRenderer describes the external CLI that turns diagram source into SVG.
+// Args receives the source and destination file paths.
+type svgRenderer struct {
+	bin     string
+	ext     string
+	install string
+	args    func(in, out string) []string
+}
+
+var svgRenderers = map[diagram.Format]svgRenderer{
+	diagram.FormatMermaid: {
+		bin: "mmdc", ext: ".mmd", install: "npm install -g @mermaid-js/mermaid-cli",
+		args: func(in, out string) []string { return []string{"-i", in, "-o", out} },
+	},
+	diagram.FormatD2: {
+		bin: "d2", ext: ".d2", install: "see https://d2lang.com/tour/install",
+		args: func(in, out string) []string { return []string{in, out} },
+	},
+	diagram.FormatPlantUML: {
+		bin: "plantuml", ext: ".puml", install: "see https://plantuml.com/download",
+		args: func(in, out string) []string { return []string{"-tsvg", "-o", filepath.Dir(out), in} },
+	},
+}
+
+// renderSVG shells out to the format's CLI renderer and returns the SVG bytes.
+func renderSVG(format diagram.Format, src string) ([]byte, error) {
+	r, ok := svgRenderers[format]
+	if !ok {
+		return nil, fmt.Errorf("--svg is not supported for format %q", format)
+	}
+	bin, err := exec.LookPath(r.bin)
+	if err != nil {
+		return nil, fmt.Errorf("--svg requires %q on PATH (%s)", r.bin, r.install)
+	}
+
+	dir, err := os.MkdirTemp("", "gert-diagram-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "diagram"+r.ext)
+	out := filepath.Join(dir, "diagram.svg")
+	if err := os.WriteFile(in, []byte(src), 0o644); err != nil {
+		return nil, err
+	}
+
+	var stderr bytes.Buffer
+	c := exec.Command(bin, r.args(in, out)...)
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %w: %s", r.bin, err, strings.TrimSpace(stderr.String()))
+	}
+	return os.ReadFile(out)
+}
+
+func init() {
+	names := make([]string, len(diagram.Formats))
+	for i, f := range diagram.Formats {
+		names[i] = string(f)
+	}
+	diagramCmd.Flags().StringVar(&diagramFormat, "format", string(diagram.FormatMermaid), "Diagram format: "+strings.Join(names, ", "))
+	diagramCmd.Flags().StringVar(&diagramOut, "out", "", "Write to file instead of stdout")
+	diagramCmd.Flags().BoolVar(&diagramSVG, "svg", false, "Render to SVG using the format's CLI (mmdc, d2, plantuml)")
+
+	rootCmd.AddCommand(diagramCmd)
+}
@@ -1,23 +1,34 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"time"
 
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
 	ktesting "github.com/ormasoftchile/gert/pkg/kernel/testing"
 	"github.com/spf13/cobra"
 )
 
+var diffJSON bool
+
 var diffCmd = &cobra.Command{
-	Use:   "diff [runbook.yaml]",
-	Short: "Compare scenario test outcomes for change detection",
-	Args:  cobra.ExactArgs(1),
-	RunE:  runDiff,
+	Use:   "diff [runbook.yaml] [other.yaml]",
+	Short: "Compare scenario test outcomes, or the structure of two runbooks",
+	Long: `With one runbook, re-run its scenarios and report outcome changes.
+With two runbooks, compare their steps by ID and report added, removed and
+changed steps.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runDiff,
 }
 
 func runDiff(cmd *cobra.Command, args []string) error {
+	if len(args) == 2 {
+		return runRunbookDiff(args[0], args[1])
+	}
 	filePath := args[0]
 
 	runner := &ktesting.Runner{
@@ -47,7 +58,67 @@ func runDiff(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runRunbookDiff(beforePath, afterPath string) error {
+	before, err := kschema.LoadFile(beforePath)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", beforePath, err)
+	}
+	after, err := kschema.LoadFile(afterPath)
+	if err != nil {
+		return fmt.Errorf("load %s: %w", afterPath, err)
+	}
+
+	entries := kschema.Diff(before, after)
+	if diffJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+
+	fmt.Printf("--- %s\n+++ %s\n", beforePath, afterPath)
+	printRunbookDiff(os.Stdout, entries)
+	if len(entries) > 0 {
+		return fmt.Errorf("%d difference(s)", len(entries))
+	}
+	return nil
+}
+
+// printRunbookDiff renders entries as -/+ lines, one value per line.
+func printRunbookDiff(w io.Writer, entries []kschema.DiffEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "  no differences")
+		return
+	}
+	for _, e := range entries {
+		switch e.Kind {
+		case kschema.DiffAdded:
+			fmt.Fprintf(w, "+ %s (added)\n", e.Path)
+		case kschema.DiffRemoved:
+			fmt.Fprintf(w, "- %s (removed)\n", e.Path)
+		default:
+			fmt.Fprintf(w, "~ %s\n", e.Path)
+			fmt.Fprintf(w, "    - %s\n", diffValue(e.Before))
+			fmt.Fprintf(w, "    + %s\n", diffValue(e.After))
+		}
+	}
+}
+
+func diffValue(v any) string {
+	if v == nil {
+		return "(unset)"
+	}
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
 func init() {
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "Output runbook differences as JSON")
 	rootCmd.AddCommand(diffCmd)
 }
 
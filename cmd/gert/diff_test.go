@@ -1,6 +1,11 @@
 package main
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+)
 
 // T133a: gert diff detects outcome changes
 func TestExtractField(t *testing.T) {
@@ -37,3 +42,23 @@ func TestContains(t *testing.T) {
 		t.Error("expected false")
 	}
 }
+
+func TestPrintRunbookDiff(t *testing.T) {
+	var buf bytes.Buffer
+	printRunbookDiff(&buf, []kschema.DiffEntry{
+		{Path: "steps.check.action", Kind: kschema.DiffChanged, Before: "get", After: "post"},
+		{Path: "steps.check.inputs", Kind: kschema.DiffChanged, Before: nil, After: map[string]any{"url": "http://localhost"}},
+		{Path: "steps.extra", Kind: kschema.DiffAdded},
+	})
+	want := `~ steps.check.action
+    - "get"
+    + "post"
+~ steps.check.inputs
+    - (unset)
+    + {"url":"http://localhost"}
++ steps.extra (added)
+`
+	if buf.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultEnvFile is loaded from the working directory when --env-file is
+// not given.
+const defaultEnvFile = ".env"
+
+var envFile string
+
+// loadEnv runs before every command. It loads --env-file when set, and
+// .env otherwise. A missing .env is ignored; any other failure is only a
+// warning, so a broken env file never stops a command.
+func loadEnv(cmd *cobra.Command, args []string) {
+	path := envFile
+	if path == "" {
+		path = defaultEnvFile
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			return
+		}
+	}
+	if err := loadDotEnvFile(path); err != nil {
+		fmt.Fprintf(os.Stderr, "  ⚠ env file: %v\n", err)
+	}
+}
+
+// loadDotEnvFile sets environment variables from a KEY=value file. Blank
+// lines and # comments are skipped, an "export " prefix is allowed and
+// values may be single- or double-quoted. Variables already set in the
+// environment are left alone. A leading ~ in path is the home directory.
+func loadDotEnvFile(path string) error {
+	path, err := expandHome(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return fmt.Errorf("%s:%d: expected KEY=value", path, n)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// expandHome replaces a leading ~ with the user's home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", "Load environment variables from this file instead of .env")
+	rootCmd.PersistentPreRun = loadEnv
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDotEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prod.env")
+	content := `# production
+GERT_TEST_REGION=westeurope
+export GERT_TEST_TOKEN="s3cr3t"
+GERT_TEST_QUOTED='a b'
+GERT_TEST_PRESET=from-file
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"GERT_TEST_REGION", "GERT_TEST_TOKEN", "GERT_TEST_QUOTED"} {
+		t.Setenv(k, "")
+		os.Unsetenv(k)
+	}
+	t.Setenv("GERT_TEST_PRESET", "from-env")
+
+	if err := loadDotEnvFile(path); err != nil {
+		t.Fatalf("loadDotEnvFile: %v", err)
+	}
+	want := map[string]string{
+		"GERT_TEST_REGION": "westeurope",
+		"GERT_TEST_TOKEN":  "s3cr3t",
+		"GERT_TEST_QUOTED": "a b",
+		"GERT_TEST_PRESET": "from-env", // the environment wins
+	}
+	for k, v := range want {
+		if got := os.Getenv(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+
+	if err := loadDotEnvFile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	t.Setenv("HOME", "/home/op")
+	tests := map[string]string{
+		"~/envs/prod.env": "/home/op/envs/prod.env",
+		"~":               "/home/op",
+		"/etc/gert.env":   "/etc/gert.env",
+		"staging.env":     "staging.env",
+	}
+	for in, want := range tests {
+		if got, err := expandHome(in); err != nil || got != want {
+			t.Errorf("expandHome(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	formatWrite bool
+	formatCheck bool
+)
+
+var formatCmd = &cobra.Command{
+	Use:   "format [runbook.yaml...]",
+	Short: "Rewrite runbook YAML in canonical form",
+	Long: `Rewrites kernel/v0 runbooks with keys in schema order (apiVersion, meta,
+tools, steps, ...), block style, two-space indentation and quotes only where
+YAML needs them. Values and comments are kept; blank lines are not.
+
+Without flags a unified diff is printed for every file that would change.
+--write rewrites the files in place; --check prints the files that would
+change and exits non-zero if there are any.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runFormat,
+}
+
+func runFormat(cmd *cobra.Command, args []string) error {
+	var changed []string
+	for _, path := range args {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read runbook: %w", err)
+		}
+		out, err := kschema.Format(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if bytes.Equal(src, out) {
+			continue
+		}
+		changed = append(changed, path)
+
+		switch {
+		case formatCheck:
+			fmt.Println(path)
+		case formatWrite:
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("write runbook: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "  ✓ %s\n", path)
+		default:
+			fmt.Print(unifiedDiff(path, src, out))
+		}
+	}
+	if formatCheck && len(changed) > 0 {
+		return fmt.Errorf("%d file(s) not formatted", len(changed))
+	}
+	return nil
+}
+
+// unifiedDiff returns a line diff of a and b with three lines of context.
+func unifiedDiff(path string, a, b []byte) string {
+	const context = 3
+	al, bl := splitLines(a), splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of al[i:] and bl[j:]
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type edit struct {
+		op   byte // ' ', '-', '+'
+		line string
+		ai   int // lines of a and b consumed before this edit
+		bi   int
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			edits = append(edits, edit{' ', al[i], i, j})
+			i, j = i+1, j+1
+		case i < len(al) && (j == len(bl) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', al[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', bl[j], i, j})
+			j++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s (formatted)\n", path, path)
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		// Grow the hunk until the next change is more than 2*context lines away
+		start := max(k-context, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = next
+		}
+
+		var na, nb int
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				na++
+			}
+			if e.op != '-' {
+				nb++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", edits[start].ai+1, na, edits[start].bi+1, nb)
+		for _, e := range edits[start:end] {
+			sb.WriteByte(e.op)
+			sb.WriteString(e.line)
+			sb.WriteByte('\n')
+		}
+		k = end
+	}
+	return sb.String()
+}
+
+func init() {
+	formatCmd.Flags().BoolVarP(&formatWrite, "write", "w", false, "rewrite files in place")
+	formatCmd.Flags().BoolVar(&formatCheck, "check", false, "list files that would change and exit non-zero if any")
+	rootCmd.AddCommand(formatCmd)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatCmd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rb.yaml")
+	os.WriteFile(path, []byte(`meta: {name: fmt}
+apiVersion: kernel/v0
+steps:
+  - type: end
+    id: done
+    outcome: {category: no_action, code: ok}
+`), 0o644)
+	defer func() { formatWrite, formatCheck = false, false }()
+
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"format", path})
+		return rootCmd.Execute()
+	})
+	if !strings.Contains(out, "+  name: fmt") || !strings.Contains(out, "-meta: {name: fmt}") {
+		t.Errorf("diff output:\n%s", out)
+	}
+
+	var checkErr error
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"format", "--check", path})
+		checkErr = rootCmd.Execute()
+		return nil
+	})
+	if checkErr == nil {
+		t.Error("expected --check to fail on an unformatted file")
+	}
+	formatCheck = false
+
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"format", "--write", path})
+		return rootCmd.Execute()
+	})
+	formatWrite = false
+	out = captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"format", "--check", path})
+		return rootCmd.Execute()
+	})
+	if out != "" {
+		t.Errorf("formatted file still reported:\n%s", out)
+	}
+}
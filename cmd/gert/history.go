@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/engine"
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	historyLast    int
+	historyJSON    bool
+	historyRunbook string
+	historyDir     = engine.DefaultRunsDir
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List previous runs with outcome and duration",
+	Args:  cobra.NoArgs,
+	RunE:  runHistory,
+}
+
+// historyEntry is one row of gert history output, read from a run's
+// engine.RunManifest.
+type historyEntry struct {
+	RunID      string           `json:"run_id"`
+	Runbook    string           `json:"runbook"`
+	Actor      string           `json:"actor,omitempty"`
+	Mode       string           `json:"mode"`
+	Status     string           `json:"status,omitempty"`
+	StartedAt  string           `json:"started_at"`
+	EndedAt    string           `json:"ended_at"`
+	Outcome    *kschema.Outcome `json:"outcome,omitempty"`
+	DurationMs int64            `json:"duration_ms"`
+
+	started time.Time
+}
+
+// outcome shows the outcome category and code, or the run status for runs
+// that ended without reaching an end step.
+func (h historyEntry) outcome() string {
+	switch {
+	case h.Outcome != nil && h.Outcome.Category != "":
+		if h.Outcome.Code == "" {
+			return string(h.Outcome.Category)
+		}
+		return fmt.Sprintf("%s (%s)", h.Outcome.Category, h.Outcome.Code)
+	case h.Status != "":
+		return h.Status
+	default:
+		return "-"
+	}
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	entries, err := loadHistory(historyDir, historyRunbook)
+	if err != nil {
+		return err
+	}
+	if historyLast > 0 && len(entries) > historyLast {
+		entries = entries[:historyLast]
+	}
+
+	if historyJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No runs found in %s/\n", historyDir)
+		return nil
+	}
+	printHistory(os.Stdout, entries)
+	return nil
+}
+
+// loadHistory reads <dir>/*/run.yaml, keeps runs whose runbook path contains
+// filter, and returns them newest first. Unreadable manifests are skipped.
+func loadHistory(dir, filter string) ([]historyEntry, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*", "run.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("glob runs: %w", err)
+	}
+
+	entries := []historyEntry{}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		var m engine.RunManifest
+		if err := yaml.Unmarshal(data, &m); err != nil {
+			fmt.Fprintf(os.Stderr, "  ⚠ skipping %s: %v\n", f, err)
+			continue
+		}
+		h := historyEntry{
+			RunID:     m.RunID,
+			Runbook:   m.Runbook,
+			Actor:     m.Actor,
+			Mode:      m.Mode,
+			Status:    m.Status,
+			StartedAt: m.StartedAt,
+			EndedAt:   m.EndedAt,
+			Outcome:   m.Outcome,
+		}
+		if filter != "" && !strings.Contains(h.Runbook, filter) {
+			continue
+		}
+		if h.RunID == "" {
+			h.RunID = filepath.Base(filepath.Dir(f))
+		}
+		h.started, _ = time.Parse(time.RFC3339, h.StartedAt)
+		if ended, err := time.Parse(time.RFC3339, h.EndedAt); err == nil && !h.started.IsZero() {
+			h.DurationMs = ended.Sub(h.started).Milliseconds()
+		}
+		entries = append(entries, h)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].started.After(entries[j].started)
+	})
+	return entries, nil
+}
+
+func printHistory(w io.Writer, entries []historyEntry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RUN ID\tRUNBOOK\tMODE\tOUTCOME\tACTOR\tDURATION")
+	for _, h := range entries {
+		actor := h.Actor
+		if actor == "" {
+			actor = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			h.RunID, h.Runbook, h.Mode, h.outcome(), actor,
+			time.Duration(h.DurationMs)*time.Millisecond)
+	}
+	tw.Flush()
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyLast, "last", 0, "Show only the N most recent runs")
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Output runs as a JSON array")
+	historyCmd.Flags().StringVar(&historyRunbook, "runbook", "", "Only show runs whose runbook path contains this string")
+	historyCmd.Flags().StringVar(&historyDir, "output-dir", engine.DefaultRunsDir, "Base directory of run artifacts (as given to exec --output-dir)")
+	rootCmd.AddCommand(historyCmd)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeRunManifest(t *testing.T, dir, runID, body string) {
+	t.Helper()
+	runDir := filepath.Join(dir, "runs", runID)
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, "run.yaml"), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// captureStdout runs fn with os.Stdout redirected and returns what it wrote.
+func captureStdout(t *testing.T, fn func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orig := os.Stdout
+	os.Stdout = w
+	runErr := fn()
+	os.Stdout = orig
+	w.Close()
+	out, _ := io.ReadAll(r)
+	if runErr != nil {
+		t.Fatalf("command failed: %v", runErr)
+	}
+	return string(out)
+}
+
+func TestHistoryCmd_ListsRuns(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	writeRunManifest(t, dir, "run-old", `run_id: run-old
+runbook: runbooks/disk-cleanup.yaml
+actor: alice
+mode: real
+started_at: "2026-01-10T08:00:00Z"
+ended_at: "2026-01-10T08:02:30Z"
+status: completed
+outcome:
+  category: resolved
+  code: disk_freed
+steps_executed: 3
+`)
+	writeRunManifest(t, dir, "run-new", `run_id: run-new
+runbook: runbooks/service-health.yaml
+mode: dry-run
+started_at: "2026-01-11T09:00:00Z"
+ended_at: "2026-01-11T09:00:05Z"
+status: failed
+error: step check failed
+`)
+
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"history"})
+		return rootCmd.Execute()
+	})
+	newIdx, oldIdx := strings.Index(out, "run-new"), strings.Index(out, "run-old")
+	if newIdx < 0 || oldIdx < 0 {
+		t.Fatalf("expected both runs in output:\n%s", out)
+	}
+	if newIdx > oldIdx {
+		t.Errorf("expected newest run first:\n%s", out)
+	}
+	if !strings.Contains(out, "resolved (disk_freed)") || !strings.Contains(out, "2m30s") || !strings.Contains(out, "alice") {
+		t.Errorf("missing outcome, duration or actor:\n%s", out)
+	}
+	if !strings.Contains(out, "failed") {
+		t.Errorf("expected the status of a run without outcome:\n%s", out)
+	}
+
+	out = captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"history", "--json", "--runbook", "disk"})
+		defer func() { historyJSON, historyRunbook = false, "" }()
+		return rootCmd.Execute()
+	})
+	var entries []map[string]any
+	if err := json.NewDecoder(bytes.NewBufferString(out)).Decode(&entries); err != nil {
+		t.Fatalf("decode JSON: %v\n%s", err, out)
+	}
+	if len(entries) != 1 || entries[0]["run_id"] != "run-old" || entries[0]["duration_ms"] != float64(150000) {
+		t.Errorf("filtered entries = %v", entries)
+	}
+	if o, _ := entries[0]["outcome"].(map[string]any); o["category"] != "resolved" || o["code"] != "disk_freed" {
+		t.Errorf("outcome = %v", entries[0]["outcome"])
+	}
+}
+
+func TestLoadHistory_NewestFirst(t *testing.T) {
+	dir := t.TempDir()
+	started := map[string]string{"a": "2026-01-01", "b": "2026-01-03", "c": "2026-01-02"}
+	for id, day := range started {
+		writeRunManifest(t, dir, id, "run_id: "+id+"\nrunbook: r.yaml\nmode: real\nstarted_at: \""+day+"T00:00:00Z\"\n")
+	}
+
+	entries, err := loadHistory(filepath.Join(dir, "runs"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.RunID)
+	}
+	if strings.Join(ids, ",") != "b,c,a" {
+		t.Errorf("order = %v, want b,c,a", ids)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+	"github.com/spf13/cobra"
+)
+
+var (
+	initKind  string
+	initSteps int
+	initTool  string
+	initForce bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [name]",
+	Short: "Scaffold a new runbook from a template",
+	Long: `Prompts for the runbook name, kind and first steps, then writes <name>.yaml
+and a scenarios/<name>/dry-run/inputs.yaml stub in the current directory.
+Prompts are skipped for values given as arguments or flags.
+
+Tool steps call the "run" action of --tool (default: ` + kschema.DefaultTemplateTool + `); add the
+tool definition before executing the runbook.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInit,
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	opts, err := promptInit(cmd, args, bufio.NewScanner(os.Stdin), os.Stdout)
+	if err != nil {
+		return err
+	}
+	return writeInit(".", opts)
+}
+
+// promptInit asks for every option not already set by args or flags. An
+// empty answer (or closed stdin) takes the default shown in brackets.
+func promptInit(cmd *cobra.Command, args []string, in *bufio.Scanner, out io.Writer) (kschema.TemplateOptions, error) {
+	ask := func(prompt, def string) string {
+		if def != "" {
+			fmt.Fprintf(out, "%s [%s]: ", prompt, def)
+		} else {
+			fmt.Fprintf(out, "%s: ", prompt)
+		}
+		if !in.Scan() {
+			fmt.Fprintln(out)
+			return def
+		}
+		if v := strings.TrimSpace(in.Text()); v != "" {
+			return v
+		}
+		return def
+	}
+
+	opts := kschema.TemplateOptions{Kind: initKind, Tool: initTool}
+	if len(args) > 0 {
+		opts.Name = args[0]
+	} else {
+		opts.Name = ask("Runbook name", "my-runbook")
+	}
+	opts.Description = ask("Description", "")
+	if !cmd.Flags().Changed("kind") {
+		opts.Kind = ask("Kind (cli, manual, mixed)", initKind)
+	}
+
+	if cmd.Flags().Changed("steps") {
+		if initSteps < 1 {
+			return opts, fmt.Errorf("--steps must be at least 1, got %d", initSteps)
+		}
+		for i := range initSteps {
+			opts.Steps = append(opts.Steps, fmt.Sprintf("step %d", i+1))
+		}
+		return opts, nil
+	}
+
+	n, err := strconv.Atoi(ask("Number of steps", strconv.Itoa(initSteps)))
+	if err != nil || n < 1 {
+		return opts, fmt.Errorf("number of steps must be a positive integer")
+	}
+	for i := range n {
+		def := fmt.Sprintf("step %d", i+1)
+		opts.Steps = append(opts.Steps, ask(fmt.Sprintf("Step %d name", i+1), def))
+	}
+	return opts, nil
+}
+
+// writeInit writes the runbook and its dry-run scenario stub under dir,
+// then validates the runbook it wrote.
+func writeInit(dir string, opts kschema.TemplateOptions) error {
+	rb, err := kschema.NewRunbookTemplate(opts)
+	if err != nil {
+		return err
+	}
+
+	rbPath := filepath.Join(dir, opts.Name+".yaml")
+	if _, err := os.Stat(rbPath); err == nil && !initForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", rbPath)
+	}
+	if err := writeYAML(rbPath, rb); err != nil {
+		return err
+	}
+
+	scenarioDir := filepath.Join(dir, "scenarios", opts.Name, "dry-run")
+	if err := os.MkdirAll(scenarioDir, 0755); err != nil {
+		return fmt.Errorf("create scenario dir: %w", err)
+	}
+	stub := make(map[string]string, len(rb.Meta.Inputs))
+	for name := range rb.Meta.Inputs {
+		stub[name] = mockPlaceholder
+	}
+	if err := writeYAML(filepath.Join(scenarioDir, "inputs.yaml"), stub); err != nil {
+		return err
+	}
+
+	_, errs := kvalidate.ValidateFile(rbPath)
+	for _, e := range errs {
+		if e.Severity == "error" {
+			return fmt.Errorf("generated runbook is invalid: %s", e)
+		}
+	}
+
+	fmt.Printf("  ✓ %s (%d steps)\n", rbPath, len(rb.Steps))
+	fmt.Printf("  ✓ %s\n", filepath.Join(scenarioDir, "inputs.yaml"))
+	for _, tool := range rb.Tools {
+		if kvalidate.ResolveToolPath(tool, dir, "") == "" {
+			fmt.Fprintf(os.Stderr, "  ⚠ tool %q: definition not found — add tools/%s.tool.yaml before running\n", tool, tool)
+		}
+	}
+	return nil
+}
+
+func init() {
+	initCmd.Flags().StringVar(&initKind, "kind", kschema.TemplateMixed, "Step kind: cli, manual, or mixed")
+	initCmd.Flags().IntVar(&initSteps, "steps", 3, "Number of placeholder steps (skips the step prompts)")
+	initCmd.Flags().StringVar(&initTool, "tool", "", "Tool referenced by tool steps (default: "+kschema.DefaultTemplateTool+")")
+	initCmd.Flags().BoolVar(&initForce, "force", false, "Overwrite an existing runbook")
+	rootCmd.AddCommand(initCmd)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+)
+
+func TestInitCmd_PromptsAndWritesValidRunbook(t *testing.T) {
+	dir := t.TempDir()
+	in := bufio.NewScanner(strings.NewReader("disk-cleanup\nFree disk space\nmixed\n2\nCheck usage\n\n"))
+	var out bytes.Buffer
+
+	opts, err := promptInit(initCmd, nil, in, &out)
+	if err != nil {
+		t.Fatalf("promptInit: %v", err)
+	}
+	if opts.Name != "disk-cleanup" || opts.Kind != kschema.TemplateMixed {
+		t.Fatalf("opts = %+v", opts)
+	}
+	if strings.Join(opts.Steps, ",") != "Check usage,step 2" {
+		t.Errorf("steps = %v, want the default for the blank answer", opts.Steps)
+	}
+	if !strings.Contains(out.String(), "Step 2 name [step 2]: ") {
+		t.Errorf("prompts = %q", out.String())
+	}
+
+	if err := writeInit(dir, opts); err != nil {
+		t.Fatalf("writeInit: %v", err)
+	}
+	rb, errs := kvalidate.ValidateFile(filepath.Join(dir, "disk-cleanup.yaml"))
+	for _, e := range errs {
+		t.Errorf("generated runbook: %s", e)
+	}
+	if rb.Meta.Description != "Free disk space" || len(rb.Steps) != 3 {
+		t.Errorf("runbook = %+v", rb)
+	}
+	stub, err := os.ReadFile(filepath.Join(dir, "scenarios", "disk-cleanup", "dry-run", "inputs.yaml"))
+	if err != nil {
+		t.Fatalf("read inputs stub: %v", err)
+	}
+	if string(stub) != "target: "+mockPlaceholder+"\n" {
+		t.Errorf("inputs.yaml = %q", stub)
+	}
+
+	if err := writeInit(dir, opts); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected overwrite error, got %v", err)
+	}
+}
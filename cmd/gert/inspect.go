@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+	"github.com/spf13/cobra"
+)
+
+var inspectJSON bool
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [runbook.yaml]",
+	Short: "Show a runbook's inputs, constants, tools and variable flow",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runInspect,
+}
+
+func runInspect(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	rb, errs := kvalidate.ValidateFile(filePath)
+	for _, e := range errs {
+		if e.Severity == "error" {
+			return fmt.Errorf("validation failed for %s: %s", filePath, e)
+		}
+	}
+
+	ins := kvalidate.Inspect(rb, filepath.Dir(filePath))
+	if inspectJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(ins)
+	}
+	printInspection(os.Stdout, ins)
+	return nil
+}
+
+func printInspection(w io.Writer, ins *kvalidate.Inspection) {
+	fmt.Fprintf(w, "  %s\n", ins.Name)
+	if ins.Description != "" {
+		fmt.Fprintf(w, "  %s\n", ins.Description)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "\n  Inputs (%d)\n", len(ins.Inputs))
+	for _, in := range ins.Inputs {
+		var notes []string
+		if in.Required {
+			notes = append(notes, "required")
+		}
+		if in.Default != nil {
+			notes = append(notes, fmt.Sprintf("default: %v", in.Default))
+		}
+		if in.From != "" {
+			notes = append(notes, "from: "+in.From)
+		}
+		fmt.Fprintf(tw, "    %s\t%s\t%s\n", in.Name, in.Type, strings.Join(notes, ", "))
+	}
+
+	fmt.Fprintf(tw, "\n  Constants (%d)\n", len(ins.Constants))
+	for _, c := range ins.Constants {
+		fmt.Fprintf(tw, "    %s\t%v\t\n", c.Name, c.Value)
+	}
+
+	fmt.Fprintf(tw, "\n  Tools (%d)\n", len(ins.Tools))
+	for _, t := range ins.Tools {
+		if t.Error != "" {
+			fmt.Fprintf(tw, "    %s\t⚠ %s\t\n", t.Name, t.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "    %s\t%s\t\n", t.Name, t.Path)
+		for _, a := range t.Actions {
+			fmt.Fprintf(tw, "      %s\teffects: %s\t\n", a.Name, listOrDash(a.Effects))
+		}
+	}
+
+	fmt.Fprintf(tw, "\n  Variable flow (%d)\n", len(ins.Variables))
+	fmt.Fprintf(tw, "    VARIABLE\tSOURCE\tPRODUCED BY\tCONSUMED BY\n")
+	for _, v := range ins.Variables {
+		fmt.Fprintf(tw, "    %s\t%s\t%s\t%s\n", v.Name, v.Source, listOrDash(v.Producers), listOrDash(v.Consumers))
+	}
+	tw.Flush()
+}
+
+func listOrDash(items []string) string {
+	if len(items) == 0 {
+		return "-"
+	}
+	return strings.Join(items, ", ")
+}
+
+func init() {
+	inspectCmd.Flags().BoolVar(&inspectJSON, "json", false, "Output as JSON")
+	rootCmd.AddCommand(inspectCmd)
+}
@@ -13,14 +13,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"text/tabwriter"
 	"time"
 
+	"github.com/ormasoftchile/gert/pkg/inputs"
 	"github.com/ormasoftchile/gert/pkg/kernel/engine"
 	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
 	ktesting "github.com/ormasoftchile/gert/pkg/kernel/testing"
 	"github.com/ormasoftchile/gert/pkg/kernel/trace"
 	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+	"github.com/ormasoftchile/gert/pkg/schema"
 	"github.com/spf13/cobra"
 )
 
@@ -42,6 +46,13 @@ var rootCmd = &cobra.Command{
 
 // --- validate ---
 
+var (
+	validateStrict        bool
+	validateCheckTools    bool
+	validateProfile       bool
+	validateStrictRunRefs bool
+)
+
 var validateCmd = &cobra.Command{
 	Use:   "validate [runbook.yaml]",
 	Short: "Validate a kernel/v0 runbook YAML (3-phase pipeline)",
@@ -51,6 +62,9 @@ var validateCmd = &cobra.Command{
 
 func runValidate(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
+	if validateSARIFOut != "" && !validateSARIF {
+		return fmt.Errorf("--sarif-out requires --sarif")
+	}
 
 	ext := strings.ToLower(filepath.Ext(filePath))
 	if ext == ".md" || ext == ".markdown" {
@@ -62,14 +76,36 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		return runValidateTool(filePath)
 	}
 
-	rb, errs := kvalidate.ValidateFile(filePath)
+	rb, errs, profile := kvalidate.ValidateFileProfiled(filePath)
+	if validateProfile {
+		fmt.Fprintf(os.Stderr, "  Validation profile: parse %dms, schema %dms, domain %dms\n",
+			profile.ParseMs, profile.SchemaMs, profile.DomainMs)
+	}
+	if rb != nil {
+		toolErrs := kvalidate.CheckToolFiles(rb, filepath.Dir(filePath))
+		if validateCheckTools {
+			errs = append(errs, toolErrs...)
+		} else if len(toolErrs) > 0 {
+			fmt.Fprintf(os.Stderr, "  ⚠ %d tool definition(s) missing or unreadable — add --check-tools to verify tool files\n", len(toolErrs))
+		}
+		if validateStrictRunRefs {
+			errs = append(errs, checkRunRefs(rb, engine.DefaultRunsDir)...)
+		}
+	}
+	if validateSARIF {
+		return writeValidateSARIF(filePath, errs)
+	}
 	if len(errs) > 0 {
 		var errors []*kvalidate.ValidationError
 		var warnings []*kvalidate.ValidationError
+		var hints []*kvalidate.ValidationError
 		for _, e := range errs {
-			if e.Severity == "warning" {
+			switch {
+			case e.Severity == "hint":
+				hints = append(hints, e)
+			case e.Severity == "warning" && !validateStrict:
 				warnings = append(warnings, e)
-			} else {
+			default:
 				errors = append(errors, e)
 			}
 		}
@@ -79,6 +115,12 @@ func runValidate(cmd *cobra.Command, args []string) error {
 				fmt.Fprintf(os.Stderr, "    at: %s\n", w.Path)
 			}
 		}
+		for _, h := range hints {
+			fmt.Fprintf(os.Stderr, "  ℹ [%s] %s\n", h.Phase, h.Message)
+			if h.Path != "" {
+				fmt.Fprintf(os.Stderr, "    at: %s\n", h.Path)
+			}
+		}
 		if len(errors) > 0 {
 			fmt.Fprintf(os.Stderr, "Validation failed: %d error(s)\n\n", len(errors))
 			for i, e := range errors {
@@ -96,10 +138,13 @@ func runValidate(cmd *cobra.Command, args []string) error {
 
 func runValidateTool(filePath string) error {
 	td, errs := kvalidate.ValidateToolFile(filePath)
+	if validateSARIF {
+		return writeValidateSARIF(filePath, errs)
+	}
 	if len(errs) > 0 {
 		var errors []*kvalidate.ValidationError
 		for _, e := range errs {
-			if e.Severity == "error" {
+			if e.Severity == "error" || validateStrict {
 				errors = append(errors, e)
 			}
 		}
@@ -132,6 +177,26 @@ func runValidateTool(filePath string) error {
 	return nil
 }
 
+// checkRunRefs reports run.<run-id>.<name> inputs whose run has no
+// manifest under runsDir (ROP1). Such inputs only warn at resolve time, so
+// the check is opt-in via --strict-run-refs.
+func checkRunRefs(rb *kschema.Runbook, runsDir string) []*kvalidate.ValidationError {
+	defs := make(map[string]*schema.InputDef, len(rb.Meta.Inputs))
+	for name, p := range rb.Meta.Inputs {
+		defs[name] = &schema.InputDef{From: p.From}
+	}
+	var errs []*kvalidate.ValidationError
+	for _, e := range inputs.ValidateRunRefs(defs, runsDir) {
+		errs = append(errs, &kvalidate.ValidationError{
+			Phase:    e.Phase,
+			Path:     e.Path,
+			Message:  e.Message,
+			Severity: e.Severity,
+		})
+	}
+	return errs
+}
+
 // isToolFile peeks at the file to check if apiVersion starts with "tool/".
 func isToolFile(path string) bool {
 	f, err := os.Open(path)
@@ -157,10 +222,20 @@ var versionCmd = &cobra.Command{
 // --- exec ---
 
 var (
-	execMode  string
-	execVars  []string
-	execTrace string
-	execActor string
+	execMode         string
+	execVars         []string
+	execVarFiles     []string
+	execTrace        string
+	execActor        string
+	execActorGroup   []string
+	execSkipHooks    bool
+	execEnvInherit   bool
+	execNoCache      bool
+	execAllowEffects []string
+	execTimeout      string
+	execOutputDir    string
+	execStrict       bool
+	execStrictRefs   bool
 )
 
 var execCmd = &cobra.Command{
@@ -173,8 +248,36 @@ var execCmd = &cobra.Command{
 func runExec(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 
+	switch execMode {
+	case "real", "dry-run", "probe":
+	default:
+		return fmt.Errorf("invalid --mode %q: expected real, dry-run, or probe", execMode)
+	}
+	if execActor != "" && len(execActorGroup) > 0 {
+		return fmt.Errorf("--as and --as-group are mutually exclusive")
+	}
+	var runTimeout time.Duration
+	if execTimeout != "" {
+		d, err := time.ParseDuration(execTimeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("invalid --timeout %q: expected a positive duration like 30m", execTimeout)
+		}
+		runTimeout = d
+	}
+
+	runsDir := engine.DefaultRunsDir
+	if execOutputDir != "" {
+		if err := engine.CheckWritable(execOutputDir); err != nil {
+			return err
+		}
+		runsDir = execOutputDir
+	}
+
 	// Validate first
 	rb, errs := kvalidate.ValidateFile(filePath)
+	if rb != nil && execStrictRefs {
+		errs = append(errs, checkRunRefs(rb, runsDir)...)
+	}
 	if errs != nil {
 		for _, e := range errs {
 			if e.Severity == "error" {
@@ -188,8 +291,14 @@ func runExec(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Parse --var flags
-	vars := make(map[string]string)
+	// Load --var-file files, then apply --var flags on top
+	vars, err := engine.LoadVarFiles(execVarFiles)
+	if err != nil {
+		return err
+	}
+	for _, w := range engine.SecretVarWarnings(rb, vars) {
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", w)
+	}
 	for _, v := range execVars {
 		parts := strings.SplitN(v, "=", 2)
 		if len(parts) != 2 {
@@ -205,33 +314,86 @@ func runExec(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("input resolution: %w", err)
 	}
 
-	// Set up trace writer
-	var tw *trace.Writer
-	if execTrace != "" {
-		var err error
-		tw, err = trace.NewFileWriter(execTrace, "run-1")
-		if err != nil {
-			return fmt.Errorf("trace: %w", err)
+	// Set up trace writer: --trace, or the run's directory under runsDir
+	runID := engine.NewRunID()
+	tracePath := execTrace
+	if tracePath == "" {
+		runDir := filepath.Join(runsDir, runID)
+		if err := os.MkdirAll(runDir, 0o755); err != nil {
+			return fmt.Errorf("create run dir: %w", err)
 		}
+		tracePath = filepath.Join(runDir, engine.TraceFile)
+	}
+	tw, err := trace.NewFileWriter(tracePath, runID)
+	if err != nil {
+		return fmt.Errorf("trace: %w", err)
 	}
 
 	// Build run config
 	baseDir := filepath.Dir(filePath)
 	hostname, _ := os.Hostname()
 	cfg := engine.RunConfig{
-		RunID:       "run-1",
-		Mode:        execMode,
-		Vars:        resolved.Vars,
-		BaseDir:     baseDir,
-		Trace:       tw,
-		Actor:       execActor,
-		Host:        hostname,
-		Version:     version,
-		RunbookPath: filePath,
+		RunID:           runID,
+		Mode:            execMode,
+		Vars:            resolved.Vars,
+		BaseDir:         baseDir,
+		Trace:           tw,
+		Actor:           execActor,
+		ActorGroup:      execActorGroup,
+		Host:            hostname,
+		Version:         version,
+		RunbookPath:     filePath,
+		SkipHooks:       execSkipHooks,
+		CleanEnv:        !execEnvInherit,
+		NoCache:         execNoCache,
+		StrictContracts: execStrict,
+	}
+	// --allow-effects limits the effects tool steps may declare
+	if cmd.Flags().Changed("allow-effects") {
+		cfg.GovernancePolicy = &kschema.GovernancePolicy{AllowedEffects: execAllowEffects}
+	}
+
+	banner := fmt.Sprintf("▶ %s (mode: %s", rb.Meta.Name, execMode)
+	if execActor != "" {
+		banner += ", actor: " + execActor
+	}
+	if len(execActorGroup) > 0 {
+		banner += ", actors: " + strings.Join(execActorGroup, ",")
+	}
+	if !execEnvInherit {
+		banner += ", clean env"
 	}
+	if runTimeout > 0 {
+		banner += ", timeout: " + runTimeout.String()
+	}
+	fmt.Println(banner + ")")
 
+	runCtx := ctx
+	if runTimeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, runTimeout)
+		defer cancel()
+	}
 	eng := engine.New(rb, cfg)
-	result := eng.Run(ctx)
+	for _, w := range eng.Warnings() {
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", w)
+	}
+	result := eng.Run(runCtx)
+
+	// Record the run for gert history and gert clean; on timeout this keeps
+	// the partial run for inspection
+	dir, manifestErr := engine.WriteManifestIn(runsDir, eng.Manifest(result))
+	if result.Outcome != nil && result.Outcome.Category == kschema.OutcomeTimedOut {
+		if manifestErr != nil {
+			return fmt.Errorf("run timed out after %s: %w", runTimeout, manifestErr)
+		}
+		fmt.Printf("\n✗ Timed out after %s\n  Artifacts: %s\n", runTimeout, dir)
+		return fmt.Errorf("run timed out after %s", runTimeout)
+	}
+	if manifestErr != nil {
+		fmt.Fprintf(os.Stderr, "  ⚠ %v\n", manifestErr)
+		dir = filepath.Join(runsDir, runID)
+	}
 
 	if result.Outcome != nil {
 		fmt.Printf("\n✓ Outcome: %s (%s)\n", result.Outcome.Category, result.Outcome.Code)
@@ -246,20 +408,46 @@ func runExec(cmd *cobra.Command, args []string) error {
 		return result.Error
 	}
 
-	fmt.Printf("  Duration: %s\n", result.Duration)
+	fmt.Printf("  Duration: %s\n  Artifacts: %s\n", result.Duration, dir)
 	return nil
 }
 
 func init() {
-	execCmd.Flags().StringVar(&execMode, "mode", "real", "Execution mode: real or dry-run")
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Treat warnings as errors (non-zero exit on any warning)")
+	validateCmd.Flags().BoolVar(&validateCheckTools, "check-tools", false, "Fail when a declared tool's definition file is missing or malformed")
+	validateCmd.Flags().BoolVar(&validateStrictRunRefs, "strict-run-refs", false, "Fail when a run.<run-id>.<name> input refers to a run with no manifest under runs/")
+	validateCmd.Flags().BoolVar(&validateProfile, "profile", false, "Print how long each validation phase took")
+	validateCmd.Flags().BoolVar(&validateSARIF, "sarif", false, "Print findings as a SARIF 2.1.0 log (for GitHub code scanning)")
+	validateCmd.Flags().StringVar(&validateSARIFOut, "sarif-out", "", "Write the SARIF log to this file instead of stdout")
+
+	execCmd.Flags().StringVar(&execMode, "mode", "real", "Execution mode: real, dry-run, or probe (runs read-only steps, skips write-effect steps)")
 	execCmd.Flags().StringArrayVar(&execVars, "var", nil, "Set a variable (key=value), repeatable")
+	execCmd.Flags().StringArrayVar(&execVarFiles, "var-file", nil, "Load variables from a YAML file, repeatable (--var overrides)")
 	execCmd.Flags().StringVar(&execTrace, "trace", "", "Write trace to JSONL file")
 	execCmd.Flags().StringVar(&execActor, "as", "", "Actor identity for trace and approval requests")
+	execCmd.Flags().StringSliceVar(&execActorGroup, "as-group", nil, "Comma-separated approvers; approves gated steps without prompting when enough are listed")
+	execCmd.Flags().BoolVar(&execSkipHooks, "skip-hooks", false, "Don't invoke step pre_hook/post_hook (offline or replay runs)")
+	execCmd.Flags().BoolVar(&execEnvInherit, "env-inherit", true, "Pass the parent environment to tools; false passes only step env, declared secrets and PATH, HOME, USER")
+	execCmd.Flags().BoolVar(&execNoCache, "no-cache", false, "Ignore step cache: settings and always execute tool steps")
+	execCmd.Flags().StringVar(&execTimeout, "timeout", "", "Stop the run after this long (e.g. 30m) with a timed_out outcome")
+	execCmd.Flags().BoolVar(&execStrict, "strict-contracts", false, "Fail tool steps whose inputs don't match the tool's contract.inputs (default: warn)")
+	execCmd.Flags().BoolVar(&execStrictRefs, "strict-run-refs", false, "Fail before running when a run.<run-id>.<name> input refers to a run with no manifest in the output dir")
+	execCmd.Flags().StringVar(&execOutputDir, "output-dir", "", "Base directory for run artifacts (default: runs); must be writable")
+	execCmd.Flags().StringSliceVar(&execAllowEffects, "allow-effects", nil, "Only run tool steps whose contract effects are in this list (e.g. reads,network)")
 
 	testCmd.Flags().StringVar(&testScenario, "scenario", "", "Run only the named scenario (default: all)")
-	testCmd.Flags().BoolVar(&testJSON, "json", false, "Output results as JSON")
+	testCmd.Flags().BoolVar(&testJSON, "json", false, "Output results as JSON (same as --output json)")
+	testCmd.Flags().StringVar(&testOutput, "output", "text", "Output format: text, json or junit (JUnit XML for CI)")
 	testCmd.Flags().BoolVar(&testFailFast, "fail-fast", false, "Stop after first failure")
 	testCmd.Flags().StringVar(&testTimeout, "timeout", "30s", "Per-scenario timeout")
+	testCmd.Flags().IntVar(&testParallel, "parallel", 1, "Number of scenarios to replay concurrently")
+	testCmd.Flags().BoolVar(&testCoverage, "coverage", false, "Report which steps the scenarios exercised")
+	testCmd.Flags().StringVar(&testCoverageOut, "coverage-out", "", "Write the coverage report as JSON to this file")
+	testCmd.Flags().StringVar(&testMinCoverage, "min-coverage", "", "Fail when step coverage is below this percentage (e.g. 80%)")
+	testCmd.Flags().BoolVar(&testWatch, "watch", false, "Re-run failing scenarios when the runbook, its tools or its scenarios change")
+	testCmd.Flags().BoolVar(&testWatchAll, "watch-all", false, "With --watch, re-run every scenario on each change")
+	testCmd.Flags().BoolVar(&testFuzz, "fuzz", false, "Mutate scenario responses: numbers within ±20%, shuffled arrays, omitted optional strings")
+	testCmd.Flags().Int64Var(&testFuzzSeed, "fuzz-seed", 0, "Seed for --fuzz (default: random, printed so the run can be reproduced)")
 
 	rootCmd.AddCommand(validateCmd)
 	rootCmd.AddCommand(execCmd)
@@ -276,8 +464,9 @@ var schemaCmd = &cobra.Command{
 }
 
 var schemaRunbookCmd = &cobra.Command{
-	Use:   "runbook",
-	Short: "Export kernel/v0 runbook JSON Schema",
+	Use:     "runbook",
+	Aliases: []string{"export"},
+	Short:   "Export kernel/v0 runbook JSON Schema",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		data, err := kschema.GenerateRunbookJSONSchema()
 		if err != nil {
@@ -311,8 +500,17 @@ func init() {
 var (
 	testScenario string
 	testJSON     bool
+	testOutput   string
 	testFailFast bool
 	testTimeout  string
+	testParallel int
+
+	testCoverage    bool
+	testCoverageOut string
+	testMinCoverage string
+
+	testFuzz     bool
+	testFuzzSeed int64
 )
 
 var testCmd = &cobra.Command{
@@ -327,13 +525,57 @@ func runTest(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("invalid --timeout: %w", err)
 	}
+	if testParallel < 1 {
+		return fmt.Errorf("invalid --parallel %d: must be at least 1", testParallel)
+	}
+	format := testOutput
+	if testJSON {
+		format = "json"
+	}
+	switch format {
+	case "text", "json", "junit":
+	default:
+		return fmt.Errorf("invalid --output %q: expected text, json or junit", testOutput)
+	}
+	minCoverage := -1.0
+	if testMinCoverage != "" {
+		minCoverage, err = strconv.ParseFloat(strings.TrimSuffix(testMinCoverage, "%"), 64)
+		if err != nil || minCoverage < 0 || minCoverage > 100 {
+			return fmt.Errorf("invalid --min-coverage %q: expected a percentage such as 80%%", testMinCoverage)
+		}
+	}
+
+	if cmd.Flags().Changed("fuzz-seed") && !testFuzz {
+		return fmt.Errorf("--fuzz-seed requires --fuzz")
+	}
+	seed := testFuzzSeed
+	if testFuzz && !cmd.Flags().Changed("fuzz-seed") {
+		seed = time.Now().UnixNano()
+		fmt.Fprintf(os.Stderr, "fuzz seed: %d\n", seed)
+	}
+
+	if testWatchAll && !testWatch {
+		return fmt.Errorf("--watch-all requires --watch")
+	}
+	if testWatch {
+		if format != "text" || testScenario != "" || testCoverage || testCoverageOut != "" || minCoverage >= 0 {
+			return fmt.Errorf("--watch cannot be combined with --output, --scenario or coverage flags")
+		}
+		return runTestWatch(args, func() *ktesting.Runner {
+			return &ktesting.Runner{Timeout: timeout, FailFast: testFailFast, Concurrency: testParallel, Fuzz: testFuzz, FuzzSeed: seed}
+		})
+	}
 
 	runner := &ktesting.Runner{
-		Timeout:  timeout,
-		FailFast: testFailFast,
+		Timeout:     timeout,
+		FailFast:    testFailFast,
+		Concurrency: testParallel,
+		Fuzz:        testFuzz,
+		FuzzSeed:    seed,
 	}
 
 	allPassed := true
+	junit := &ktesting.JUnitWriter{}
 
 	for _, filePath := range args {
 		var output *ktesting.TestOutput
@@ -368,11 +610,14 @@ func runTest(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		if testJSON {
+		switch format {
+		case "json":
 			enc := json.NewEncoder(os.Stdout)
 			enc.SetIndent("", "  ")
 			enc.Encode(output)
-		} else {
+		case "junit":
+			junit.Add(output)
+		default:
 			printTestOutput(output)
 		}
 
@@ -381,12 +626,63 @@ func runTest(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if format == "junit" {
+		if err := junit.Write(os.Stdout); err != nil {
+			return err
+		}
+	}
+
+	if testCoverage || testCoverageOut != "" || minCoverage >= 0 {
+		cov := runner.Coverage()
+		if testCoverage && format != "junit" {
+			if format == "json" {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				enc.Encode(cov)
+			} else {
+				printCoverage(cov)
+			}
+		}
+		if testCoverageOut != "" {
+			data, err := json.MarshalIndent(cov, "", "  ")
+			if err != nil {
+				return fmt.Errorf("marshal coverage: %w", err)
+			}
+			if err := os.WriteFile(testCoverageOut, append(data, '\n'), 0644); err != nil {
+				return fmt.Errorf("write coverage: %w", err)
+			}
+		}
+		if allPassed && cov.Percent < minCoverage {
+			return fmt.Errorf("step coverage %.1f%% is below --min-coverage %s", cov.Percent, testMinCoverage)
+		}
+	}
+
 	if !allPassed {
 		return fmt.Errorf("tests failed")
 	}
 	return nil
 }
 
+func printCoverage(cov *ktesting.CoverageSummary) {
+	for _, rb := range cov.Runbooks {
+		fmt.Printf("\n  Coverage: %s\n", rb.Runbook)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "    STEP\tTYPE\tSTATUS\tSCENARIOS")
+		for _, s := range rb.Steps {
+			status, scenarios := "uncovered", "-"
+			if s.Covered {
+				status, scenarios = "covered", strings.Join(s.Scenarios, ", ")
+			}
+			fmt.Fprintf(w, "    %s\t%s\t%s\t%s\n", s.ID, s.Type, status, scenarios)
+		}
+		w.Flush()
+		fmt.Printf("\n  %d/%d steps covered (%.1f%%)\n", rb.Covered, rb.Total, rb.Percent)
+	}
+	if len(cov.Runbooks) > 1 {
+		fmt.Printf("\n  total: %d/%d steps covered (%.1f%%)\n", cov.Covered, cov.Total, cov.Percent)
+	}
+}
+
 func printTestOutput(output *ktesting.TestOutput) {
 	fmt.Printf("\n  %s\n", output.Runbook)
 	for _, s := range output.Scenarios {
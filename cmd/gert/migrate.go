@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateWrite bool
+	migrateCheck bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate [runbook.yaml...]",
+	Short: "Rewrite runbooks to current idioms",
+	Long: `Rewrites kernel/v0 runbooks to current idioms. Branch steps with exactly
+two unlabeled branches, a condition and a default, become the shorthand
+
+  branch:
+    if: <condition>
+    steps: [...]
+    else: [...]
+
+Output is in the canonical form of gert format. Without flags a unified
+diff is printed for every file that would change. --write rewrites the
+files in place; --check prints the files that would change and exits
+non-zero if there are any.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runMigrate,
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	var changed []string
+	for _, path := range args {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read runbook: %w", err)
+		}
+		out, n, err := kschema.Migrate(src)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if n == 0 || bytes.Equal(src, out) {
+			continue
+		}
+		changed = append(changed, path)
+
+		switch {
+		case migrateCheck:
+			fmt.Println(path)
+		case migrateWrite:
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+				return fmt.Errorf("write runbook: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "  ✓ %s (%d rewrite(s))\n", path, n)
+		default:
+			fmt.Print(unifiedDiff(path, src, out))
+		}
+	}
+	if migrateCheck && len(changed) > 0 {
+		return fmt.Errorf("%d file(s) need migration", len(changed))
+	}
+	return nil
+}
+
+func init() {
+	migrateCmd.Flags().BoolVarP(&migrateWrite, "write", "w", false, "rewrite files in place")
+	migrateCmd.Flags().BoolVar(&migrateCheck, "check", false, "list files that would change and exit non-zero if any")
+	rootCmd.AddCommand(migrateCmd)
+}
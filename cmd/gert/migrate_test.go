@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateCmd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rb.yaml")
+	os.WriteFile(path, []byte(`apiVersion: kernel/v0
+meta:
+  name: migrate
+steps:
+  - id: decide
+    type: branch
+    branches:
+      - condition: "{{ .degraded }}"
+        steps:
+          - type: end
+            outcome: {category: resolved, code: fixed}
+      - condition: default
+        steps:
+          - type: end
+            outcome: {category: no_action, code: ok}
+`), 0o644)
+	defer func() { migrateWrite, migrateCheck = false, false }()
+
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"migrate", path})
+		return rootCmd.Execute()
+	})
+	if !strings.Contains(out, "+    branch:") || !strings.Contains(out, "-    branches:") {
+		t.Errorf("diff output:\n%s", out)
+	}
+
+	var checkErr error
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"migrate", "--check", path})
+		checkErr = rootCmd.Execute()
+		return nil
+	})
+	if checkErr == nil {
+		t.Error("expected --check to fail on a file that needs migration")
+	}
+	migrateCheck = false
+
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"migrate", "--write", path})
+		return rootCmd.Execute()
+	})
+	migrateWrite = false
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "else:") {
+		t.Errorf("file not migrated:\n%s", data)
+	}
+	out = captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"migrate", "--check", path})
+		return rootCmd.Execute()
+	})
+	if out != "" {
+		t.Errorf("migrated file still reported:\n%s", out)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/contract"
+	"github.com/ormasoftchile/gert/pkg/kernel/replay"
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+	ktesting "github.com/ormasoftchile/gert/pkg/kernel/testing"
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// mockPlaceholder marks values the author still has to fill in.
+const mockPlaceholder = "<fill-me>"
+
+var (
+	mockOut   string
+	mockName  string
+	mockForce bool
+)
+
+var mockCmd = &cobra.Command{
+	Use:   "mock [runbook.yaml]",
+	Short: "Generate a skeleton replay scenario for a runbook",
+	Long: `Walks the runbook and writes <out>/<runbook>/<name>/scenario.yaml with
+every input, a canned response per tool step (outputs taken from the tool
+contract), and evidence for manual steps, plus a test.yaml expecting the
+run to complete. Values without a default are set to "` + mockPlaceholder + `".
+
+By default the scenario is written next to the runbook, where gert test
+discovers it.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMock,
+}
+
+func runMock(cmd *cobra.Command, args []string) error {
+	filePath := args[0]
+
+	rb, errs := kvalidate.ValidateFile(filePath)
+	for _, e := range errs {
+		if e.Severity == "error" {
+			return fmt.Errorf("validation failed for %s: %s", filePath, e)
+		}
+	}
+
+	out := mockOut
+	if out == "" {
+		out = filepath.Join(filepath.Dir(filePath), "scenarios")
+	}
+	base := strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath))
+	dir := filepath.Join(out, base, mockName)
+
+	scenarioPath := filepath.Join(dir, "scenario.yaml")
+	if _, err := os.Stat(scenarioPath); err == nil && !mockForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", scenarioPath)
+	}
+
+	scenario, warnings := mockScenario(rb, filepath.Dir(filePath))
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "  ⚠ %s\n", w)
+	}
+	spec := &ktesting.TestSpec{
+		Description:    fmt.Sprintf("Generated by gert mock for %s", rb.Meta.Name),
+		ExpectedStatus: "completed",
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create scenario dir: %w", err)
+	}
+	if err := writeYAML(scenarioPath, scenario); err != nil {
+		return err
+	}
+	if err := writeYAML(filepath.Join(dir, "test.yaml"), spec); err != nil {
+		return err
+	}
+
+	fmt.Printf("  ✓ %s\n", dir)
+	fmt.Printf("    %d inputs, %d tool responses, %d manual steps\n",
+		len(scenario.Inputs), countResponses(scenario), len(scenario.Evidence))
+	return nil
+}
+
+// mockScenario builds a scenario that satisfies every input, tool step and
+// manual step of rb. Tools are resolved relative to baseDir; tools that fail
+// to load still get a response, without outputs, and a warning.
+func mockScenario(rb *kschema.Runbook, baseDir string) (*replay.Scenario, []string) {
+	s := &replay.Scenario{
+		Inputs:        make(map[string]string),
+		ToolResponses: make(map[string][]replay.ToolResponse),
+		Evidence:      make(map[string]map[string]string),
+	}
+	var warnings []string
+
+	for name, in := range rb.Meta.Inputs {
+		if in.Default != nil {
+			s.Inputs[name] = fmt.Sprint(in.Default)
+		} else {
+			s.Inputs[name] = mockPlaceholder
+		}
+	}
+
+	tools := make(map[string]*kschema.ToolDefinition)
+	for _, name := range rb.Tools {
+		path := kvalidate.ResolveToolPath(name, baseDir, "")
+		if path == "" {
+			warnings = append(warnings, fmt.Sprintf("tool %q: definition not found", name))
+			continue
+		}
+		td, err := kschema.LoadToolFile(path)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("tool %q: %v", name, err))
+			continue
+		}
+		tools[name] = td
+	}
+
+	var walk func(steps []kschema.Step, times int)
+	walk = func(steps []kschema.Step, times int) {
+		for _, step := range steps {
+			switch step.Type {
+			case kschema.StepTool:
+				key, resp := mockToolResponse(step, tools[step.Tool])
+				for range times {
+					s.ToolResponses[key] = append(s.ToolResponses[key], resp)
+				}
+			case kschema.StepManual:
+				if step.ID == "" {
+					continue
+				}
+				evidence := make(map[string]string)
+				for _, ev := range step.RequiredEvidence {
+					evidence[ev.Name] = mockPlaceholder
+				}
+				s.Evidence[step.ID] = evidence
+			}
+			for _, br := range step.Branches {
+				walk(br.Steps, times)
+			}
+			if step.Repeat != nil {
+				// Every iteration consumes its own responses
+				walk(step.Repeat.Steps, times*max(step.Repeat.Max, 1))
+			}
+		}
+	}
+	walk(rb.Steps, 1)
+
+	return s, warnings
+}
+
+// mockToolResponse returns the replay key and a successful response for a
+// tool step, with a placeholder for every output its contract declares.
+func mockToolResponse(step kschema.Step, td *kschema.ToolDefinition) (string, replay.ToolResponse) {
+	resp := replay.ToolResponse{ExitCode: 0}
+	if td == nil {
+		return step.Tool + ":" + step.Action, resp
+	}
+
+	c := td.Contract
+	if action, ok := td.Actions[step.Action]; ok && action.Contract != nil {
+		c = contract.Merge(&td.Contract, action.Contract)
+	}
+	if step.Contract != nil {
+		c = contract.Merge(&c, step.Contract)
+	}
+	if len(c.Outputs) > 0 {
+		resp.Outputs = make(map[string]any, len(c.Outputs))
+		for name, p := range c.Outputs {
+			resp.Outputs[name] = mockValue(p)
+		}
+	}
+	return td.Meta.Name + ":" + step.Action, resp
+}
+
+// mockValue returns a placeholder of the parameter's declared type.
+func mockValue(p contract.ParamDef) any {
+	if p.Default != nil {
+		return p.Default
+	}
+	switch p.Type {
+	case "int", "integer", "number":
+		return 0
+	case "bool", "boolean":
+		return false
+	case "array", "list":
+		return []any{}
+	case "object", "map":
+		return map[string]any{}
+	default:
+		return mockPlaceholder
+	}
+}
+
+func countResponses(s *replay.Scenario) int {
+	n := 0
+	for _, rs := range s.ToolResponses {
+		n += len(rs)
+	}
+	return n
+}
+
+func writeYAML(path string, v any) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("marshal %s: %w", filepath.Base(path), err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+func init() {
+	mockCmd.Flags().StringVar(&mockOut, "out", "", "Scenarios root directory (default: scenarios/ next to the runbook)")
+	mockCmd.Flags().StringVar(&mockName, "name", "skeleton", "Scenario name")
+	mockCmd.Flags().BoolVar(&mockForce, "force", false, "Overwrite an existing scenario")
+	rootCmd.AddCommand(mockCmd)
+}
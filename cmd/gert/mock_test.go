@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/replay"
+)
+
+const mockTestRunbook = `apiVersion: kernel/v0
+meta:
+  name: mock-test
+  inputs:
+    host:
+      type: string
+    port:
+      type: string
+      default: "443"
+tools:
+  - probe
+steps:
+  - id: check
+    type: tool
+    tool: probe
+    action: ping
+    inputs:
+      target: "{{ .host }}:{{ .port }}"
+  - id: confirm
+    type: manual
+    instructions: Confirm the probe result
+    required_evidence:
+      - kind: text
+        name: ticket
+  - id: done
+    type: end
+    outcome:
+      category: no_action
+      code: healthy
+`
+
+const mockTestTool = `apiVersion: tool/v0
+meta:
+  name: probe
+  binary: probe
+contract:
+  outputs:
+    latency:
+      type: int
+actions:
+  ping:
+    argv: ["ping", "{{ .target }}"]
+    contract:
+      outputs:
+        reachable:
+          type: bool
+`
+
+func TestMockCmd_GeneratesRunnableScenario(t *testing.T) {
+	dir := t.TempDir()
+	rbPath := filepath.Join(dir, "mock-test.yaml")
+	os.WriteFile(rbPath, []byte(mockTestRunbook), 0o644)
+	os.MkdirAll(filepath.Join(dir, "tools"), 0o755)
+	os.WriteFile(filepath.Join(dir, "tools", "probe.tool.yaml"), []byte(mockTestTool), 0o644)
+
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"mock", rbPath})
+		return rootCmd.Execute()
+	})
+
+	scenarioDir := filepath.Join(dir, "scenarios", "mock-test", "skeleton")
+	s, err := replay.LoadScenarioDir(scenarioDir)
+	if err != nil {
+		t.Fatalf("LoadScenarioDir: %v", err)
+	}
+	if s.Inputs["host"] != mockPlaceholder || s.Inputs["port"] != "443" {
+		t.Errorf("inputs = %v", s.Inputs)
+	}
+	responses := s.ToolResponses["probe:ping"]
+	if len(responses) != 1 {
+		t.Fatalf("tool_responses = %v, want one for probe:ping", s.ToolResponses)
+	}
+	if out := responses[0].Outputs; out["latency"] != 0 || out["reachable"] != false {
+		t.Errorf("outputs = %v, want typed placeholders for latency and reachable", out)
+	}
+	if s.Evidence["confirm"]["ticket"] != mockPlaceholder {
+		t.Errorf("evidence = %v", s.Evidence)
+	}
+
+	// Refuses to overwrite without --force
+	rootCmd.SetArgs([]string{"mock", rbPath})
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("expected already-exists error, got %v", err)
+	}
+
+	// The skeleton replays as-is
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"test", rbPath})
+		return rootCmd.Execute()
+	})
+	if !strings.Contains(out, "1 passed") {
+		t.Errorf("gert test output:\n%s", out)
+	}
+}
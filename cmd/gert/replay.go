@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ormasoftchile/gert/pkg/replay"
+	"github.com/spf13/cobra"
+)
+
+var replayDiffJSON bool
+
+var replayDiffCmd = &cobra.Command{
+	Use:   "diff [scenario-a] [scenario-b]",
+	Short: "Compare the outcome and captured step outputs of two scenario runs",
+	Long: `Loads two scenario directories — kernel scenario.yaml files, or run.yaml
+(or inputs.yaml) and steps/*.json from legacy runs — and reports outcome
+changes, changed inputs, added and removed steps, and the captured values
+that differ for each step present in both. Exits non-zero when the runs
+differ.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runReplayDiff,
+}
+
+func runReplayDiff(cmd *cobra.Command, args []string) error {
+	a, stepsA, err := replay.LoadRunDir(args[0])
+	if err != nil {
+		return fmt.Errorf("load %s: %w", args[0], err)
+	}
+	b, stepsB, err := replay.LoadRunDir(args[1])
+	if err != nil {
+		return fmt.Errorf("load %s: %w", args[1], err)
+	}
+
+	report, err := replay.DiffScenarios(a, b, stepsA, stepsB)
+	if err != nil {
+		return err
+	}
+	if replayDiffJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("--- %s (%s)\n+++ %s (%s)\n", args[0], a.RunID, args[1], b.RunID)
+		printScenarioDiff(os.Stdout, report)
+	}
+	if !report.Empty() {
+		return fmt.Errorf("scenario runs differ")
+	}
+	return nil
+}
+
+// printScenarioDiff renders the report with old and new values side by side.
+func printScenarioDiff(w io.Writer, r *replay.DiffReport) {
+	if r.Empty() {
+		fmt.Fprintln(w, "  no differences")
+		return
+	}
+	if r.OutcomeChanged {
+		fmt.Fprintf(w, "~ outcome: %s → %s\n", outcomeLabel(r.OutcomeA), outcomeLabel(r.OutcomeB))
+	}
+	if len(r.Inputs) > 0 {
+		fmt.Fprintln(w, "~ inputs")
+		printChanges(w, r.Inputs)
+	}
+	for _, id := range r.RemovedSteps {
+		fmt.Fprintf(w, "- %s (removed)\n", id)
+	}
+	for _, id := range r.AddedSteps {
+		fmt.Fprintf(w, "+ %s (added)\n", id)
+	}
+	for _, s := range r.ChangedSteps {
+		fmt.Fprintf(w, "~ %s\n", s.StepID)
+		printChanges(w, s.Changes)
+	}
+}
+
+func printChanges(w io.Writer, changes []replay.ValueChange) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range changes {
+		fmt.Fprintf(tw, "    %s\t%s\t→ %s\n", c.Path, changeValue(c.Old), changeValue(c.New))
+	}
+	tw.Flush()
+}
+
+func changeValue(v string) string {
+	if v == "" {
+		return "(unset)"
+	}
+	return v
+}
+
+func outcomeLabel(o *replay.ManifestOutcome) string {
+	if o == nil {
+		return "(none)"
+	}
+	return fmt.Sprintf("%s at %s", o.State, o.StepID)
+}
+
+func init() {
+	replayDiffCmd.Flags().BoolVar(&replayDiffJSON, "json", false, "Output the report as JSON")
+
+	replayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Inspect recorded scenario runs",
+	}
+	replayCmd.AddCommand(replayDiffCmd)
+	rootCmd.AddCommand(replayCmd)
+}
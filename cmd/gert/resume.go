@@ -7,11 +7,15 @@ import (
 	"path/filepath"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/engine"
+	"github.com/ormasoftchile/gert/pkg/kernel/trace"
 	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
 	"github.com/spf13/cobra"
 )
 
-var resumeRunID string
+var (
+	resumeRunID     string
+	resumeOutputDir string
+)
 
 var resumeCmd = &cobra.Command{
 	Use:   "resume",
@@ -24,7 +28,7 @@ func runResume(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("--run is required")
 	}
 
-	state, err := engine.LoadState(resumeRunID)
+	state, err := engine.LoadStateFrom(resumeOutputDir, resumeRunID)
 	if err != nil {
 		return fmt.Errorf("load state: %w", err)
 	}
@@ -46,14 +50,26 @@ func runResume(cmd *cobra.Command, args []string) error {
 	}
 
 	cfg := engine.RunConfig{
-		RunID:   state.RunID,
-		Mode:    "real",
-		Vars:    vars,
-		BaseDir: filepath.Dir(state.RunbookPath),
+		RunID:       state.RunID,
+		Mode:        "real",
+		Vars:        vars,
+		BaseDir:     filepath.Dir(state.RunbookPath),
+		RunbookPath: state.RunbookPath,
+	}
+	// Continue the run's trace where the paused run left it
+	if state.TracePath != "" {
+		tw, err := trace.NewFileWriter(state.TracePath, state.RunID)
+		if err != nil {
+			return fmt.Errorf("trace: %w", err)
+		}
+		cfg.Trace = tw
 	}
 
 	eng := engine.New(rb, cfg)
 	result := eng.Run(context.Background())
+	if _, err := engine.WriteManifestIn(resumeOutputDir, eng.Manifest(result)); err != nil {
+		fmt.Fprintf(os.Stderr, "  ⚠ %v\n", err)
+	}
 
 	if result.Outcome != nil {
 		fmt.Printf("\n✓ Outcome: %s (%s)\n", result.Outcome.Category, result.Outcome.Code)
@@ -65,14 +81,14 @@ func runResume(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("  Duration: %s\n", result.Duration)
 
-	// Clean up state file on success
-	stateDir := filepath.Join("runs", resumeRunID)
-	os.RemoveAll(stateDir)
+	// The run is no longer resumable; its manifest and trace stay
+	os.Remove(filepath.Join(resumeOutputDir, resumeRunID, engine.StateFile))
 
 	return nil
 }
 
 func init() {
 	resumeCmd.Flags().StringVar(&resumeRunID, "run", "", "Run ID to resume")
+	resumeCmd.Flags().StringVar(&resumeOutputDir, "output-dir", engine.DefaultRunsDir, "Base directory of run artifacts (as given to exec --output-dir)")
 	rootCmd.AddCommand(resumeCmd)
 }
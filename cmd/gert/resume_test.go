@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/engine"
@@ -58,3 +59,47 @@ steps:
 		t.Errorf("RunbookPath = %q, want %q", loaded.RunbookPath, rbPath)
 	}
 }
+
+func TestResumeCmd_OutputDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Chdir(tmpDir)
+	defer func() { resumeRunID, resumeOutputDir = "", engine.DefaultRunsDir }()
+
+	rbPath := filepath.Join(tmpDir, "test.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: test-resume
+steps:
+  - id: done
+    type: end
+    outcome:
+      category: no_action
+      code: resumed_ok
+`), 0o644)
+
+	runsDir := filepath.Join(tmpDir, "artifacts")
+	tracePath := filepath.Join(runsDir, "paused-run", engine.TraceFile)
+	if err := engine.SaveStateIn(runsDir, &engine.RunState{RunID: "paused-run", RunbookPath: rbPath, TracePath: tracePath}); err != nil {
+		t.Fatal(err)
+	}
+
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"resume", "--run", "paused-run", "--output-dir", runsDir})
+		return rootCmd.Execute()
+	})
+
+	runDir := filepath.Join(runsDir, "paused-run")
+	if _, err := os.Stat(filepath.Join(runDir, engine.StateFile)); !os.IsNotExist(err) {
+		t.Error("state.json should be removed once the run completes")
+	}
+	manifest, err := os.ReadFile(filepath.Join(runDir, engine.ManifestFile))
+	if err != nil || !strings.Contains(string(manifest), "code: resumed_ok") {
+		t.Errorf("manifest = %s, err = %v", manifest, err)
+	}
+	if data, err := os.ReadFile(tracePath); err != nil || !strings.Contains(string(data), "run_complete") {
+		t.Errorf("trace not continued at %s: %v", tracePath, err)
+	}
+	if _, err := os.Stat(engine.DefaultRunsDir); !os.IsNotExist(err) {
+		t.Error("resume wrote to the default runs directory")
+	}
+}
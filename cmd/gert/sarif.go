@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+)
+
+var (
+	validateSARIF    bool
+	validateSARIFOut string
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// sarifLog is the subset of a SARIF 2.1.0 log that validate --sarif emits.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri,omitempty"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifLogicalLocation struct {
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+}
+
+// buildSARIF converts validation findings for one file into a SARIF log.
+// With strict set, warnings are reported at error level, matching the exit
+// status of validate --strict.
+func buildSARIF(filePath string, errs []*kvalidate.ValidationError, strict bool) *sarifLog {
+	driver := sarifDriver{
+		Name:           "gert",
+		Version:        version,
+		InformationURI: "https://github.com/ormasoftchile/gert",
+		Rules:          []sarifRule{},
+	}
+	results := []sarifResult{}
+	seen := make(map[string]bool)
+	uri := filepath.ToSlash(filePath)
+	for _, e := range errs {
+		id := sarifRuleID(e)
+		if !seen[id] {
+			seen[id] = true
+			driver.Rules = append(driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: e.Message}})
+		}
+		loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: uri}}}
+		if e.Path != "" {
+			loc.LogicalLocations = []sarifLogicalLocation{{FullyQualifiedName: e.Path}}
+		}
+		results = append(results, sarifResult{
+			RuleID:    id,
+			Level:     sarifLevel(e.Severity, strict),
+			Message:   sarifMessage{Text: e.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+	return &sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+}
+
+// sarifRuleID derives a stable rule ID from the phase and message, e.g.
+// "semantic/step-id-is-required".
+func sarifRuleID(e *kvalidate.ValidationError) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(e.Message) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > 64 {
+		slug = strings.TrimRight(slug[:64], "-")
+	}
+	phase := e.Phase
+	if phase == "" {
+		phase = "validate"
+	}
+	return phase + "/" + slug
+}
+
+// sarifLevel maps a validation severity to a SARIF result level. Hints are
+// notes and never escalate.
+func sarifLevel(severity string, strict bool) string {
+	switch {
+	case severity == "hint":
+		return "note"
+	case severity == "warning" && !strict:
+		return "warning"
+	}
+	return "error"
+}
+
+// writeValidateSARIF writes the findings as SARIF to --sarif-out (stdout
+// when unset) and fails when any finding counts as an error.
+func writeValidateSARIF(filePath string, errs []*kvalidate.ValidationError) error {
+	var out io.Writer = os.Stdout
+	if validateSARIFOut != "" {
+		f, err := os.Create(validateSARIFOut)
+		if err != nil {
+			return fmt.Errorf("create SARIF output: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(buildSARIF(filePath, errs, validateStrict)); err != nil {
+		return fmt.Errorf("write SARIF output: %w", err)
+	}
+
+	n := 0
+	for _, e := range errs {
+		if sarifLevel(e.Severity, validateStrict) == "error" {
+			n++
+		}
+	}
+	if n > 0 {
+		return fmt.Errorf("validation failed with %d error(s)", n)
+	}
+	return nil
+}
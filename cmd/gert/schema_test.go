@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSchemaCmd_ExportAliasesRunbook(t *testing.T) {
+	runbook := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"schema", "runbook"})
+		return rootCmd.Execute()
+	})
+	export := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"schema", "export"})
+		return rootCmd.Execute()
+	})
+	if runbook == "" || runbook != export {
+		t.Error("schema export should print the same schema as schema runbook")
+	}
+}
+
+func TestSchemaCmd_Tool(t *testing.T) {
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"schema", "tool"})
+		return rootCmd.Execute()
+	})
+	var doc struct {
+		Defs map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("tool schema is not JSON: %v", err)
+	}
+	for def, keys := range map[string][]string{
+		"ToolDefinition": {"apiVersion", "meta", "contract", "actions"},
+		"ToolMeta":       {"secrets", "platform"},
+		"Contract":       {"effects"},
+	} {
+		for _, key := range keys {
+			if _, ok := doc.Defs[def].Properties[key]; !ok {
+				t.Errorf("%s schema missing property %q", def, key)
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ormasoftchile/gert/pkg/evidence"
+	"github.com/spf13/cobra"
+)
+
+var (
+	signKey   string
+	verifyKey string
+)
+
+var signCmd = &cobra.Command{
+	Use:   "sign [run-dir]",
+	Short: "Sign a run manifest (run.yaml) with an Ed25519 key",
+	Long: `Signs the canonical bytes of <run-dir>/run.yaml and writes run.yaml.sig
+next to it, recording the manifest's SHA256 digest and the Ed25519 signature.
+
+The private key is read from --key or ` + evidence.EnvSigningKey + `: a base64-encoded
+32-byte seed or 64-byte private key, or the path to a file containing one.
+Runs written while ` + evidence.EnvSigningKey + ` is set are signed automatically.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSign,
+}
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [run-dir]",
+	Short: "Verify a signed run manifest against an Ed25519 public key",
+	Long: `Checks that <run-dir>/run.yaml still matches the digest in run.yaml.sig and
+that the signature was made by the key matching --pub-key or ` + evidence.EnvVerifyKey + `
+(a base64-encoded 32-byte public key, or the path to a file containing one).`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVerify,
+}
+
+func runSign(cmd *cobra.Command, args []string) error {
+	dir := runDirArg(args)
+	raw := signKey
+	if raw == "" {
+		raw = os.Getenv(evidence.EnvSigningKey)
+	}
+	if raw == "" {
+		return fmt.Errorf("no signing key: set --key or %s", evidence.EnvSigningKey)
+	}
+	key, err := evidence.ParsePrivateKey(raw)
+	if err != nil {
+		return err
+	}
+
+	sig, err := evidence.SignManifest(dir, key)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Signed %s/%s (sha256 %s)\n", dir, evidence.ManifestFile, sig.SHA256)
+	return nil
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	dir := runDirArg(args)
+	raw := verifyKey
+	if raw == "" {
+		raw = os.Getenv(evidence.EnvVerifyKey)
+	}
+	if raw == "" {
+		return fmt.Errorf("no verification key: set --pub-key or %s", evidence.EnvVerifyKey)
+	}
+	pub, err := evidence.ParsePublicKey(raw)
+	if err != nil {
+		return err
+	}
+
+	sig, err := evidence.VerifyManifest(dir, pub)
+	switch {
+	case errors.Is(err, evidence.ErrManifestModified):
+		fmt.Printf("✗ %s/%s was modified after signing\n", dir, evidence.ManifestFile)
+		return fmt.Errorf("manifest verification failed")
+	case err != nil && sig != nil:
+		fmt.Printf("✗ Signature invalid\n")
+		return fmt.Errorf("manifest verification failed: %w", err)
+	case err != nil:
+		return err
+	}
+	fmt.Printf("✓ Manifest integrity: sha256 %s\n", sig.SHA256)
+	fmt.Printf("✓ Signature valid\n")
+	return nil
+}
+
+func runDirArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return "."
+}
+
+func init() {
+	signCmd.Flags().StringVar(&signKey, "key", "", "Ed25519 private key, base64 or key file (default: $"+evidence.EnvSigningKey+")")
+	verifyCmd.Flags().StringVar(&verifyKey, "pub-key", "", "Ed25519 public key, base64 or key file (default: $"+evidence.EnvVerifyKey+")")
+	rootCmd.AddCommand(signCmd)
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSignVerifyCmd(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "run.yaml")
+	os.WriteFile(manifest, []byte("run_id: r1\nrunbook: rb.yaml\nmode: real\n"), 0o644)
+
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	t.Setenv("GERT_SIGNING_KEY", base64.StdEncoding.EncodeToString(priv))
+	defer func() { verifyKey = "" }()
+
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"sign", dir})
+		return rootCmd.Execute()
+	})
+	if _, err := os.Stat(manifest + ".sig"); err != nil {
+		t.Fatalf("run.yaml.sig not written: %v", err)
+	}
+
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"verify", "--pub-key", base64.StdEncoding.EncodeToString(pub), dir})
+		return rootCmd.Execute()
+	})
+	if !strings.Contains(out, "Signature valid") {
+		t.Errorf("verify output:\n%s", out)
+	}
+
+	os.WriteFile(manifest, []byte("run_id: r1\nrunbook: rb.yaml\nmode: dry-run\n"), 0o644)
+	var verifyErr error
+	out = captureStdout(t, func() error {
+		verifyErr = rootCmd.Execute()
+		return nil
+	})
+	if verifyErr == nil || !strings.Contains(out, "modified after signing") {
+		t.Errorf("expected tampering to be reported, got %v:\n%s", verifyErr, out)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ktesting "github.com/ormasoftchile/gert/pkg/kernel/testing"
+)
+
+func TestTestCmd_Coverage(t *testing.T) {
+	dir := t.TempDir()
+	rbPath := filepath.Join(dir, "cov.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: cov
+  inputs:
+    x:
+      type: string
+steps:
+  - id: check
+    type: assert
+    assert:
+      - type: equals
+        value: "ok"
+        expected: "{{ .x }}"
+  - id: done
+    type: end
+    outcome:
+      category: no_action
+      code: checked
+`), 0o644)
+	sdir := filepath.Join(dir, "scenarios", "cov", "fails")
+	os.MkdirAll(sdir, 0o755)
+	os.WriteFile(filepath.Join(sdir, "scenario.yaml"), []byte("inputs:\n  x: nope\n"), 0o644)
+	os.WriteFile(filepath.Join(sdir, "test.yaml"), []byte("expected_status: failed\n"), 0o644)
+	outPath := filepath.Join(dir, "coverage.json")
+
+	defer func() { testCoverage, testCoverageOut, testMinCoverage = false, "", "" }()
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"test", "--coverage", "--coverage-out", outPath, rbPath})
+		return rootCmd.Execute()
+	})
+	if !strings.Contains(out, "1/2 steps covered (50.0%)") {
+		t.Errorf("output missing coverage total:\n%s", out)
+	}
+
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cov ktesting.CoverageSummary
+	if err := json.Unmarshal(data, &cov); err != nil {
+		t.Fatal(err)
+	}
+	if cov.Covered != 1 || cov.Total != 2 || cov.Runbooks[0].Steps[1].Covered {
+		t.Errorf("coverage.json = %s", data)
+	}
+
+	// The scenario passes, but coverage is below the threshold
+	testCoverage, testCoverageOut = false, ""
+	rootCmd.SetArgs([]string{"test", "--min-coverage", "80%", rbPath})
+	var runErr error
+	captureStdout(t, func() error {
+		runErr = rootCmd.Execute()
+		return nil
+	})
+	if runErr == nil || !strings.Contains(runErr.Error(), "below --min-coverage 80%") {
+		t.Errorf("expected --min-coverage to fail, got %v", runErr)
+	}
+}
+
+func TestTestCmd_Fuzz(t *testing.T) {
+	dir := t.TempDir()
+	rbPath := filepath.Join(dir, "fuzz.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: fuzz
+tools:
+  - probe
+steps:
+  - id: check
+    type: tool
+    tool: probe
+    action: ping
+  - id: done
+    type: end
+    outcome:
+      category: no_action
+      code: healthy
+`), 0o644)
+	os.MkdirAll(filepath.Join(dir, "tools"), 0o755)
+	os.WriteFile(filepath.Join(dir, "tools", "probe.tool.yaml"), []byte(`apiVersion: tool/v0
+meta:
+  name: probe
+  binary: probe
+actions:
+  ping:
+    argv: ["ping"]
+    contract:
+      outputs:
+        latency:
+          type: int
+          required: true
+`), 0o644)
+	sdir := filepath.Join(dir, "scenarios", "fuzz", "slow")
+	os.MkdirAll(sdir, 0o755)
+	os.WriteFile(filepath.Join(sdir, "scenario.yaml"), []byte(`tool_responses:
+  "probe:ping":
+    - exit_code: 0
+      outputs:
+        latency: 1000
+`), 0o644)
+	os.WriteFile(filepath.Join(sdir, "test.yaml"), []byte("expected_status: completed\nexpected_outputs:\n  latency: /^(8|9|10|11|12)[0-9][0-9]$/\n"), 0o644)
+
+	defer func() {
+		testFuzz, testFuzzSeed, testOutput = false, 0, "text"
+		testCmd.Flags().Lookup("fuzz-seed").Changed = false
+	}()
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"test", "--fuzz", "--fuzz-seed", "42", "--output", "json", rbPath})
+		return rootCmd.Execute()
+	})
+	var output ktesting.TestOutput
+	if err := json.Unmarshal([]byte(out), &output); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, out)
+	}
+	if output.Summary.Passed != 1 {
+		t.Errorf("fuzzed scenario did not pass:\n%s", out)
+	}
+
+	testFuzz = false
+	rootCmd.SetArgs([]string{"test", "--fuzz-seed", "42", rbPath})
+	var runErr error
+	captureStdout(t, func() error {
+		runErr = rootCmd.Execute()
+		return nil
+	})
+	if runErr == nil || !strings.Contains(runErr.Error(), "--fuzz-seed requires --fuzz") {
+		t.Errorf("expected --fuzz-seed without --fuzz to fail, got %v", runErr)
+	}
+}
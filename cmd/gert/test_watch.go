@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	ktesting "github.com/ormasoftchile/gert/pkg/kernel/testing"
+)
+
+// watchDebounce is how long test --watch waits after the last file event
+// before re-running, so a burst of partial writes triggers a single run.
+const watchDebounce = 500 * time.Millisecond
+
+var (
+	testWatch    bool
+	testWatchAll bool
+)
+
+// testWatcher re-runs scenarios for test --watch. After the first full run
+// only the scenarios that failed are replayed, until they all pass again.
+type testWatcher struct {
+	runbooks  []string
+	all       bool // --watch-all: always replay every scenario
+	newRunner func() *ktesting.Runner
+	failing   map[string][]string // runbook path → failing scenario names
+}
+
+// runTestWatch runs every scenario once, then watches the runbooks, their
+// tools and their scenario directories and re-runs on change until q is
+// entered or stdin closes.
+func runTestWatch(runbooks []string, newRunner func() *ktesting.Runner) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watch: %w", err)
+	}
+	defer watcher.Close()
+	for _, dir := range watchDirs(runbooks) {
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("watch %s: %w", dir, err)
+		}
+	}
+
+	tw := &testWatcher{
+		runbooks:  runbooks,
+		all:       testWatchAll,
+		newRunner: newRunner,
+		failing:   make(map[string][]string),
+	}
+	tw.cycle(true)
+	fmt.Println("\n  Watching for changes — press q and Enter to quit")
+
+	quit := make(chan struct{})
+	go func() {
+		defer close(quit)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if strings.TrimSpace(scanner.Text()) == "q" {
+				return
+			}
+		}
+	}()
+
+	debounce := time.NewTimer(watchDebounce)
+	debounce.Stop()
+	for {
+		select {
+		case <-quit:
+			return nil
+		case evt, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			// New scenario directories are watched as they appear
+			if evt.Has(fsnotify.Create) {
+				if info, err := os.Stat(evt.Name); err == nil && info.IsDir() {
+					for _, dir := range walkDirs(evt.Name) {
+						watcher.Add(dir)
+					}
+				}
+			}
+			if evt.Op == fsnotify.Chmod || !tw.relevant(evt.Name) {
+				continue
+			}
+			debounce.Reset(watchDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "  ⚠ watch: %v\n", err)
+		case <-debounce.C:
+			tw.cycle(false)
+		}
+	}
+}
+
+// cycle runs the scenarios and prints the results. The first run prints the
+// full report; later runs print failures and a one-line status.
+func (w *testWatcher) cycle(first bool) {
+	runner := w.newRunner()
+	var summary ktesting.TestSummary
+	for _, path := range w.runbooks {
+		output, err := w.runRunbook(runner, path)
+		if err != nil {
+			fmt.Printf("\n  ! %s: %v\n", path, err)
+			delete(w.failing, path)
+			summary.Errors++
+			continue
+		}
+
+		var failing []string
+		for _, s := range output.Scenarios {
+			if s.Status == "failed" || s.Status == "error" {
+				failing = append(failing, s.ScenarioName)
+			}
+		}
+		w.failing[path] = failing
+
+		summary.Total += output.Summary.Total
+		summary.Passed += output.Summary.Passed
+		summary.Failed += output.Summary.Failed
+		summary.Skipped += output.Summary.Skipped
+		summary.Errors += output.Summary.Errors
+
+		if first {
+			printTestOutput(output)
+			continue
+		}
+		for _, s := range output.Scenarios {
+			if s.Status == "failed" || s.Status == "error" {
+				fmt.Printf("    %s %s/%s", statusIcon(s.Status), output.Runbook, s.ScenarioName)
+				if s.Error != "" {
+					fmt.Printf(": %s", s.Error)
+				}
+				fmt.Println()
+				for _, a := range s.Assertions {
+					if !a.Passed {
+						fmt.Printf("      ✗ %s: %s\n", a.Type, a.Message)
+					}
+				}
+			}
+		}
+	}
+	if first {
+		return
+	}
+
+	status := "completed"
+	if summary.Failed > 0 || summary.Errors > 0 {
+		status = "failed"
+	}
+	fmt.Printf("%s  %s %d passed, %d failed, %d errors (%d run)\n",
+		time.Now().Format("15:04:05"), statusIcon(status), summary.Passed, summary.Failed, summary.Errors, summary.Total)
+}
+
+// runRunbook replays the failing scenarios of a runbook, or all of them
+// when none are failing or --watch-all is set.
+func (w *testWatcher) runRunbook(runner *ktesting.Runner, path string) (*ktesting.TestOutput, error) {
+	failing := w.failing[path]
+	if w.all || len(failing) == 0 {
+		return runner.RunAll(path)
+	}
+
+	output := &ktesting.TestOutput{Runbook: filepath.Base(path)}
+	for _, name := range failing {
+		result, err := runner.RunScenario(path, name)
+		if err != nil {
+			return nil, err
+		}
+		output.Scenarios = append(output.Scenarios, *result)
+		output.Summary.Total++
+		switch result.Status {
+		case "passed":
+			output.Summary.Passed++
+		case "failed":
+			output.Summary.Failed++
+		case "skipped":
+			output.Summary.Skipped++
+		case "error":
+			output.Summary.Errors++
+		}
+	}
+	return output, nil
+}
+
+// relevant reports whether a change to path should trigger a re-run: the
+// runbook itself, or anything under its tools or scenario directories.
+func (w *testWatcher) relevant(path string) bool {
+	for _, rb := range w.runbooks {
+		if filepath.Clean(path) == filepath.Clean(rb) {
+			return true
+		}
+		for _, root := range watchRoots(rb) {
+			if rel, err := filepath.Rel(root, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// watchDirs lists the directories to watch for the given runbooks: each
+// runbook's directory (editors often replace files rather than write them)
+// and every directory under its watch roots.
+func watchDirs(runbooks []string) []string {
+	seen := make(map[string]bool)
+	var dirs []string
+	add := func(list []string) {
+		for _, d := range list {
+			if !seen[d] {
+				seen[d] = true
+				dirs = append(dirs, d)
+			}
+		}
+	}
+	for _, rb := range runbooks {
+		add([]string{filepath.Dir(rb)})
+		for _, root := range watchRoots(rb) {
+			add(walkDirs(root))
+		}
+	}
+	return dirs
+}
+
+// watchRoots returns the directories whose contents affect a runbook's
+// tests: the tools directories it resolves tools from, and its scenarios.
+func watchRoots(rb string) []string {
+	dir := filepath.Dir(rb)
+	base := strings.TrimSuffix(filepath.Base(rb), filepath.Ext(rb))
+	return []string{
+		filepath.Join(dir, "tools"),
+		"tools",
+		filepath.Join(dir, "scenarios", base),
+	}
+}
+
+// walkDirs returns root and every directory below it. A missing root
+// yields nothing.
+func walkDirs(root string) []string {
+	var dirs []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs
+}
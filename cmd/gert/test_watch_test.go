@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ktesting "github.com/ormasoftchile/gert/pkg/kernel/testing"
+)
+
+func writeWatchFixture(t *testing.T) (dir, rbPath string) {
+	t.Helper()
+	dir = t.TempDir()
+	rbPath = filepath.Join(dir, "svc.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: svc
+  inputs:
+    x:
+      type: string
+steps:
+  - id: check
+    type: assert
+    assert:
+      - type: equals
+        value: "ok"
+        expected: "{{ .x }}"
+  - id: done
+    type: end
+    outcome:
+      category: no_action
+      code: checked
+`), 0o644)
+	for name, x := range map[string]string{"good": "ok", "bad": "nope"} {
+		sdir := filepath.Join(dir, "scenarios", "svc", name)
+		os.MkdirAll(sdir, 0o755)
+		os.WriteFile(filepath.Join(sdir, "scenario.yaml"), []byte("inputs:\n  x: "+x+"\n"), 0o644)
+		os.WriteFile(filepath.Join(sdir, "test.yaml"), []byte("expected_status: completed\n"), 0o644)
+	}
+	return dir, rbPath
+}
+
+func TestTestWatcher_RerunsFailing(t *testing.T) {
+	dir, rbPath := writeWatchFixture(t)
+	tw := &testWatcher{
+		runbooks:  []string{rbPath},
+		newRunner: func() *ktesting.Runner { return &ktesting.Runner{} },
+		failing:   make(map[string][]string),
+	}
+
+	captureStdout(t, func() error { tw.cycle(true); return nil })
+	if got := tw.failing[rbPath]; len(got) != 1 || got[0] != "bad" {
+		t.Fatalf("failing = %v, want [bad]", got)
+	}
+
+	// Only the failing scenario is replayed
+	out := captureStdout(t, func() error { tw.cycle(false); return nil })
+	if !strings.Contains(out, "0 passed, 1 failed, 0 errors (1 run)") {
+		t.Errorf("status line:\n%s", out)
+	}
+
+	// Fix it: the re-run passes, and the next change replays everything again
+	os.WriteFile(filepath.Join(dir, "scenarios", "svc", "bad", "scenario.yaml"), []byte("inputs:\n  x: ok\n"), 0o644)
+	out = captureStdout(t, func() error { tw.cycle(false); return nil })
+	if !strings.Contains(out, "✓ 1 passed, 0 failed, 0 errors (1 run)") {
+		t.Errorf("status line after fix:\n%s", out)
+	}
+	out = captureStdout(t, func() error { tw.cycle(false); return nil })
+	if !strings.Contains(out, "2 passed, 0 failed, 0 errors (2 run)") {
+		t.Errorf("status line after all pass:\n%s", out)
+	}
+}
+
+func TestTestWatcher_WatchAll(t *testing.T) {
+	_, rbPath := writeWatchFixture(t)
+	tw := &testWatcher{
+		runbooks:  []string{rbPath},
+		all:       true,
+		newRunner: func() *ktesting.Runner { return &ktesting.Runner{} },
+		failing:   map[string][]string{rbPath: {"bad"}},
+	}
+	out := captureStdout(t, func() error { tw.cycle(false); return nil })
+	if !strings.Contains(out, "1 passed, 1 failed, 0 errors (2 run)") {
+		t.Errorf("status line:\n%s", out)
+	}
+}
+
+func TestTestWatcher_Relevant(t *testing.T) {
+	dir, rbPath := writeWatchFixture(t)
+	tw := &testWatcher{runbooks: []string{rbPath}}
+	tests := map[string]bool{
+		rbPath: true,
+		filepath.Join(dir, "tools", "curl.tool.yaml"):                  true,
+		filepath.Join(dir, "scenarios", "svc", "bad", "scenario.yaml"): true,
+		filepath.Join(dir, "scenarios", "other", "x", "scenario.yaml"): false,
+		filepath.Join(dir, "notes.md"):                                 false,
+		filepath.Join(dir, "svc.yaml.swp"):                             false,
+	}
+	for path, want := range tests {
+		if got := tw.relevant(path); got != want {
+			t.Errorf("relevant(%s) = %v, want %v", path, got, want)
+		}
+	}
+
+	dirs := watchDirs([]string{rbPath})
+	want := []string{dir, filepath.Join(dir, "scenarios", "svc"), filepath.Join(dir, "scenarios", "svc", "bad"), filepath.Join(dir, "scenarios", "svc", "good")}
+	if strings.Join(dirs, "\n") != strings.Join(want, "\n") {
+		t.Errorf("watchDirs = %v, want %v", dirs, want)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// noUpdateCheckEnv disables --version-check and gert upgrade when set to 1.
+const noUpdateCheckEnv = "GERT_NO_UPDATE_CHECK"
+
+// versionCheckTimeout bounds the release query so --version-check never
+// holds up a command for long when offline.
+const versionCheckTimeout = 2 * time.Second
+
+// upgradeTimeout bounds the download in gert upgrade. It is separate from
+// versionCheckTimeout, which is far too short for a binary.
+const upgradeTimeout = 5 * time.Minute
+
+// checksumsAsset lists "<sha256>  <asset>" lines (sha256sum format) for the
+// release binaries; gert upgrade refuses assets it cannot verify.
+const checksumsAsset = "checksums.txt"
+
+// Download caps for the checksums file and the binary itself.
+const (
+	maxChecksumsSize = 1 << 20
+	maxBinarySize    = 256 << 20
+)
+
+// releaseAPI is the GitHub endpoint describing the latest release.
+var releaseAPI = "https://api.github.com/repos/ormasoftchile/gert/releases/latest"
+
+var (
+	versionCheck bool
+	upgradeForce bool
+)
+
+// release is the subset of the GitHub release response gert reads.
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Replace this binary with the latest release",
+	Args:  cobra.NoArgs,
+	RunE:  runUpgrade,
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) error {
+	if updateChecksDisabled() {
+		return fmt.Errorf("upgrade disabled by %s", noUpdateCheckEnv)
+	}
+	rel, err := latestRelease(cmd.Context())
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == "dev" && !upgradeForce:
+		return fmt.Errorf("gert is a dev build; pass --force to replace it with %s", rel.TagName)
+	case !newerVersion(version, rel.TagName) && !upgradeForce:
+		fmt.Printf("gert %s is up to date\n", version)
+		return nil
+	}
+	target, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate gert binary: %w", err)
+	}
+	if target, err = filepath.EvalSymlinks(target); err != nil {
+		return fmt.Errorf("locate gert binary: %w", err)
+	}
+	if err := upgradeBinary(cmd.Context(), rel, target); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Upgraded gert %s → %s\n", version, rel.TagName)
+	return nil
+}
+
+// checkVersion prints a notice when --version-check is set and a newer
+// release exists. Any failure, e.g. no network, is silently ignored.
+func checkVersion(cmd *cobra.Command, args []string) {
+	if !versionCheck || updateChecksDisabled() || version == "dev" {
+		return
+	}
+	rel, err := latestRelease(cmd.Context())
+	if err != nil || !newerVersion(version, rel.TagName) {
+		return
+	}
+	fmt.Fprintf(cmd.ErrOrStderr(), "A newer version (%s) is available: gert upgrade\n", rel.TagName)
+}
+
+func updateChecksDisabled() bool {
+	return os.Getenv(noUpdateCheckEnv) == "1"
+}
+
+// latestRelease queries releaseAPI, giving up after versionCheckTimeout.
+// ctx may be nil for a command run without a context.
+func latestRelease(ctx context.Context) (*release, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, versionCheckTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releaseAPI, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query latest release: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("query latest release: %s", resp.Status)
+	}
+	var rel release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return nil, fmt.Errorf("decode latest release: %w", err)
+	}
+	if rel.TagName == "" {
+		return nil, fmt.Errorf("latest release has no tag")
+	}
+	return &rel, nil
+}
+
+// upgradeBinary downloads the release asset for this platform
+// (gert_<os>_<arch>, .exe on Windows), verifies it against the release's
+// checksums.txt and atomically replaces target.
+//
+// No release workflow in this repository publishes these assets yet; the
+// naming above is the contract a release must follow for upgrade to work.
+func upgradeBinary(ctx context.Context, rel *release, target string) error {
+	name := fmt.Sprintf("gert_%s_%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	url := rel.assetURL(name)
+	if url == "" {
+		return fmt.Errorf("release %s has no %s asset", rel.TagName, name)
+	}
+	sumsURL := rel.assetURL(checksumsAsset)
+	if sumsURL == "" {
+		return fmt.Errorf("release %s has no %s; refusing an unverified binary", rel.TagName, checksumsAsset)
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, upgradeTimeout)
+	defer cancel()
+
+	var sums bytes.Buffer
+	if err := download(ctx, sumsURL, maxChecksumsSize, &sums); err != nil {
+		return fmt.Errorf("download %s: %w", checksumsAsset, err)
+	}
+	want, ok := lookupChecksum(sums.Bytes(), name)
+	if !ok {
+		return fmt.Errorf("%s has no entry for %s", checksumsAsset, name)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".gert-upgrade-*")
+	if err != nil {
+		return fmt.Errorf("write %s: %w", target, err)
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	if err := download(ctx, url, maxBinarySize, io.MultiWriter(tmp, h)); err != nil {
+		tmp.Close()
+		return fmt.Errorf("download %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", target, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("download %s: checksum mismatch (got %s, want %s)", name, got, want)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("write %s: %w", target, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("replace %s: %w", target, err)
+	}
+	return nil
+}
+
+func (r *release) assetURL(name string) string {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// download copies url into w, failing if the body exceeds limit bytes.
+func download(ctx context.Context, url string, limit int64, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	n, err := io.Copy(w, io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return fmt.Errorf("larger than %d bytes", limit)
+	}
+	return nil
+}
+
+// lookupChecksum finds name in sha256sum output ("<hex>  <name>", with an
+// optional * before binary-mode names).
+func lookupChecksum(sums []byte, name string) (string, bool) {
+	sc := bufio.NewScanner(bytes.NewReader(sums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), true
+		}
+	}
+	return "", false
+}
+
+// newerVersion reports whether latest is a higher vMAJOR.MINOR.PATCH than
+// current. Versions that don't parse never compare as newer.
+func newerVersion(current, latest string) bool {
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	for i := range c {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses v1.2.3 (the v and any -suffix are optional).
+func parseVersion(s string) ([3]int, bool) {
+	var v [3]int
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "-")
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, true
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&versionCheck, "version-check", false, "Check for a newer release after the command (2s timeout; "+noUpdateCheckEnv+"=1 disables)")
+	rootCmd.PersistentPostRun = checkVersion
+	upgradeCmd.Flags().BoolVar(&upgradeForce, "force", false, "Replace a dev build, or reinstall when already up to date")
+	rootCmd.AddCommand(upgradeCmd)
+}
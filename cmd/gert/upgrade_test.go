@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"v1.2.3", "v1.2.4", true},
+		{"1.2.3", "v1.10.0", true},
+		{"v2.0.0", "v1.9.9", false},
+		{"v1.2.3", "v1.2.3", false},
+		{"v1.2.3-rc1", "v1.2.3", false},
+		{"dev", "v1.0.0", false},
+		{"v1.0.0", "nightly", false},
+	}
+	for _, tt := range tests {
+		if got := newerVersion(tt.current, tt.latest); got != tt.want {
+			t.Errorf("newerVersion(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+// fakeReleases serves a latest release with the binary for this platform
+// and a checksums.txt listing its sha256.
+func fakeReleases(t *testing.T, tag string, binary []byte) {
+	t.Helper()
+	asset := "gert_" + runtime.GOOS + "_" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		asset += ".exe"
+	}
+	sum := sha256.Sum256(binary)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			json.NewEncoder(w).Encode(release{
+				TagName: tag,
+				Assets: []releaseAsset{
+					{Name: asset, URL: srv.URL + "/download"},
+					{Name: checksumsAsset, URL: srv.URL + "/checksums"},
+				},
+			})
+		case "/download":
+			w.Write(binary)
+		case "/checksums":
+			fmt.Fprintf(w, "%s  other_asset\n%s  %s\n", strings.Repeat("0", 64), hex.EncodeToString(sum[:]), asset)
+		case "/badsums":
+			fmt.Fprintf(w, "%s  %s\n", strings.Repeat("0", 64), asset)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	api := releaseAPI
+	t.Cleanup(func() { releaseAPI = api })
+	releaseAPI = srv.URL + "/latest"
+}
+
+func TestCheckVersion(t *testing.T) {
+	fakeReleases(t, "v1.3.0", nil)
+	defer func(v string) { version = v }(version)
+	defer func() { versionCheck = false }()
+
+	check := func() string {
+		var buf bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetErr(&buf)
+		checkVersion(cmd, nil)
+		return buf.String()
+	}
+
+	version, versionCheck = "v1.2.0", true
+	if got := check(); got != "A newer version (v1.3.0) is available: gert upgrade\n" {
+		t.Errorf("notice = %q", got)
+	}
+	t.Setenv(noUpdateCheckEnv, "1")
+	if got := check(); got != "" {
+		t.Errorf("%s=1 should skip the check, got %q", noUpdateCheckEnv, got)
+	}
+	os.Unsetenv(noUpdateCheckEnv)
+
+	version = "v1.3.0"
+	if got := check(); got != "" {
+		t.Errorf("up-to-date build printed %q", got)
+	}
+
+	releaseAPI = "http://127.0.0.1:1/unreachable"
+	version = "v1.0.0"
+	if got := check(); got != "" {
+		t.Errorf("failed check must be silent, got %q", got)
+	}
+}
+
+func TestUpgradeBinary(t *testing.T) {
+	fakeReleases(t, "v1.3.0", []byte("new binary"))
+	target := filepath.Join(t.TempDir(), "gert")
+	os.WriteFile(target, []byte("old binary"), 0o755)
+
+	rel, err := latestRelease(context.Background())
+	if err != nil {
+		t.Fatalf("latestRelease: %v", err)
+	}
+	if err := upgradeBinary(context.Background(), rel, target); err != nil {
+		t.Fatalf("upgradeBinary: %v", err)
+	}
+	data, _ := os.ReadFile(target)
+	if string(data) != "new binary" {
+		t.Errorf("target = %q, want the downloaded binary", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(target))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+
+	os.WriteFile(target, []byte("old binary"), 0o755)
+	rel.Assets[1].URL = strings.Replace(rel.Assets[1].URL, "/checksums", "/badsums", 1)
+	if err := upgradeBinary(context.Background(), rel, target); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "old binary" {
+		t.Errorf("target replaced by an unverified download: %q", data)
+	}
+
+	rel.Assets = rel.Assets[:1]
+	if err := upgradeBinary(context.Background(), rel, target); err == nil || !strings.Contains(err.Error(), "has no "+checksumsAsset) {
+		t.Errorf("expected missing checksums error, got %v", err)
+	}
+
+	rel.Assets = nil
+	if err := upgradeBinary(context.Background(), rel, target); err == nil || !strings.Contains(err.Error(), "has no gert_") {
+		t.Errorf("expected missing asset error, got %v", err)
+	}
+	entries, _ = os.ReadDir(filepath.Dir(target))
+	if len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestLookupChecksum(t *testing.T) {
+	sums := []byte("ABCDEF  gert_linux_amd64\n0123 *gert_windows_amd64.exe\n")
+	if got, ok := lookupChecksum(sums, "gert_linux_amd64"); !ok || got != "abcdef" {
+		t.Errorf("linux = %q, %v", got, ok)
+	}
+	if got, ok := lookupChecksum(sums, "gert_windows_amd64.exe"); !ok || got != "0123" {
+		t.Errorf("windows = %q, %v", got, ok)
+	}
+	if _, ok := lookupChecksum(sums, "gert_darwin_arm64"); ok {
+		t.Error("unlisted asset found")
+	}
+}
+
+func TestRunUpgrade_DevBuildNeedsForce(t *testing.T) {
+	fakeReleases(t, "v1.3.0", []byte("new binary"))
+	defer func(v string) { version = v }(version)
+	version = "dev"
+	err := runUpgrade(&cobra.Command{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected dev build to require --force, got %v", err)
+	}
+}
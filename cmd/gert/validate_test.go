@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+)
+
+func TestValidateCmd_StrictPlatformMismatch(t *testing.T) {
+	other := "windows"
+	if runtime.GOOS == "windows" {
+		other = "linux"
+	}
+	toolPath := filepath.Join(t.TempDir(), "winonly.tool.yaml")
+	os.WriteFile(toolPath, []byte(`apiVersion: tool/v0
+meta:
+  name: winonly
+  binary: winonly
+  platform: [`+other+`]
+actions:
+  run:
+    argv: ["--run"]
+`), 0o644)
+
+	// Without --strict the platform mismatch is only a warning
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"validate", toolPath})
+		return rootCmd.Execute()
+	})
+
+	rootCmd.SetArgs([]string{"validate", "--strict", toolPath})
+	defer func() { validateStrict = false }()
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 error(s)") {
+		t.Fatalf("expected --strict to fail on the platform warning, got %v", err)
+	}
+}
+
+func TestValidateCmd_CheckTools(t *testing.T) {
+	rbPath := filepath.Join(t.TempDir(), "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: check-tools
+tools:
+  - absent
+steps:
+  - id: run
+    type: tool
+    tool: absent
+    action: run
+  - type: end
+    outcome:
+      category: resolved
+      code: done
+`), 0o644)
+
+	// Without --check-tools a missing tool file does not fail validation
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"validate", rbPath})
+		return rootCmd.Execute()
+	})
+
+	rootCmd.SetArgs([]string{"validate", "--check-tools", rbPath})
+	defer func() { validateCheckTools = false }()
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 error(s)") {
+		t.Fatalf("expected --check-tools to fail on the missing tool, got %v", err)
+	}
+}
+
+func TestValidateCmd_SARIF(t *testing.T) {
+	dir := t.TempDir()
+	rbPath := filepath.Join(dir, "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: sarif
+steps:
+  - id: ask
+    type: manual
+    instructions: Check the dashboard
+  - id: ask
+    type: end
+    outcome:
+      category: resolved
+      code: done
+`), 0o644)
+	sarifPath := filepath.Join(dir, "out.sarif")
+
+	rootCmd.SetArgs([]string{"validate", "--sarif", "--sarif-out", sarifPath, rbPath})
+	defer func() { validateSARIF, validateSARIFOut = false, "" }()
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "error(s)") {
+		t.Fatalf("expected validation to fail, got %v", err)
+	}
+
+	data, err := os.ReadFile(sarifPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("invalid SARIF JSON: %v\n%s", err, data)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF envelope: %s", data)
+	}
+	results := log.Runs[0].Results
+	if len(results) == 0 {
+		t.Fatalf("expected results, got none:\n%s", data)
+	}
+	for _, r := range results {
+		if r.Level != "error" && r.Level != "warning" {
+			t.Errorf("level = %q", r.Level)
+		}
+		if !strings.Contains(r.RuleID, "/") || strings.ContainsAny(r.RuleID, " :\"") {
+			t.Errorf("ruleId = %q, want phase/slug", r.RuleID)
+		}
+		if uri := r.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != filepath.ToSlash(rbPath) {
+			t.Errorf("uri = %q, want %q", uri, rbPath)
+		}
+	}
+
+	// A valid runbook yields an empty result list on stdout
+	okPath := filepath.Join(dir, "ok.yaml")
+	os.WriteFile(okPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: ok
+steps:
+  - type: end
+    outcome:
+      category: resolved
+      code: done
+`), 0o644)
+	validateSARIFOut = ""
+	out := captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"validate", "--sarif", okPath})
+		return rootCmd.Execute()
+	})
+	if !strings.Contains(out, `"results": []`) {
+		t.Errorf("expected empty results on stdout, got:\n%s", out)
+	}
+}
+
+func TestSARIFRuleID(t *testing.T) {
+	got := sarifRuleID(&kvalidate.ValidationError{Phase: "domain", Message: `duplicate step ID "ask"`})
+	if got != "domain/duplicate-step-id-ask" {
+		t.Errorf("ruleId = %q", got)
+	}
+}
+
+func TestValidateCmd_StrictRunRefs(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	rbPath := filepath.Join(dir, "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: run-refs
+  inputs:
+    cluster:
+      type: string
+      from: run.gone.cluster
+steps:
+  - type: end
+    outcome:
+      category: resolved
+      code: done
+`), 0o644)
+
+	// Without --strict-run-refs a missing run does not fail validation
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"validate", rbPath})
+		return rootCmd.Execute()
+	})
+
+	rootCmd.SetArgs([]string{"validate", "--strict-run-refs", rbPath})
+	defer func() { validateStrictRunRefs = false }()
+	err := rootCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "1 error(s)") {
+		t.Fatalf("expected --strict-run-refs to fail on the missing run, got %v", err)
+	}
+
+	runsDir := filepath.Join(dir, "runs")
+	rootCmd.SetArgs([]string{"exec", "--strict-run-refs", "--output-dir", runsDir, rbPath})
+	defer func() { execStrictRefs, execOutputDir = false, "" }()
+	if err := rootCmd.Execute(); err == nil || !strings.Contains(err.Error(), "validation failed") {
+		t.Fatalf("expected exec --strict-run-refs to fail on the missing run, got %v", err)
+	}
+
+	// Once the run's manifest exists the reference validates
+	writeRunManifest(t, dir, "gone", "run_id: gone\n")
+	captureStdout(t, func() error {
+		rootCmd.SetArgs([]string{"validate", "--strict-run-refs", rbPath})
+		return rootCmd.Execute()
+	})
+}
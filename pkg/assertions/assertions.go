@@ -1,4 +1,4 @@
-// Package assertions implements the 7 assertion types for post-execution checks.
+// Package assertions implements the 10 assertion types for post-execution checks.
 package assertions
 
 import (
@@ -6,13 +6,34 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/ormasoftchile/gert/pkg/kernel/eval"
 	"github.com/ormasoftchile/gert/pkg/providers"
 	"github.com/ormasoftchile/gert/pkg/schema"
 )
 
-// Evaluate runs a single assertion against the given output and exit code.
-func Evaluate(a schema.Assertion, output string, exitCode int) *providers.AssertionResult {
+// Evaluate runs a single assertion against the given output, exit code and
+// the time the step has taken so far. With Not set the result is inverted,
+// unless the assertion could not be evaluated at all.
+func Evaluate(a schema.Assertion, output string, exitCode int, elapsed time.Duration) *providers.AssertionResult {
+	r := evaluate(a, output, exitCode, elapsed)
+	if a.Not && !unevaluable(r) {
+		r.Passed = !r.Passed
+		r.Message = "not: " + r.Message
+	}
+	return r
+}
+
+// unevaluable reports whether a result records an assertion that could not
+// be evaluated — no field set, or an invalid pattern, JSON document or
+// duration — rather than a check that failed. Not never turns these into
+// passes.
+func unevaluable(r *providers.AssertionResult) bool {
+	return r.Type == "unknown" || strings.HasPrefix(r.Message, "invalid ")
+}
+
+func evaluate(a schema.Assertion, output string, exitCode int, elapsed time.Duration) *providers.AssertionResult {
 	if a.Contains != "" {
 		return EvalContains(output, a.Contains)
 	}
@@ -34,6 +55,15 @@ func Evaluate(a schema.Assertion, output string, exitCode int) *providers.Assert
 	if a.JSONPath != nil {
 		return EvalJSONPath(output, a.JSONPath.Path, a.JSONPath.Equals)
 	}
+	if a.DurationLessThan != "" {
+		return EvalDurationLessThan(elapsed, a.DurationLessThan)
+	}
+	if a.ContainsAll != nil {
+		return EvalContainsAll(output, a.ContainsAll)
+	}
+	if a.ContainsNone != nil {
+		return EvalContainsNone(output, a.ContainsNone)
+	}
 	return &providers.AssertionResult{
 		Type:    "unknown",
 		Passed:  false,
@@ -73,6 +103,50 @@ func EvalNotContains(output, expected string) *providers.AssertionResult {
 	}
 }
 
+// EvalContainsAll checks that output contains every expected substring.
+func EvalContainsAll(output string, expected []string) *providers.AssertionResult {
+	var missing []string
+	for _, e := range expected {
+		if !strings.Contains(output, e) {
+			missing = append(missing, e)
+		}
+	}
+	passed := len(missing) == 0
+	msg := fmt.Sprintf("output contains all of [%s]", strings.Join(expected, ", "))
+	if !passed {
+		msg = fmt.Sprintf("expected 'output' to contain all of [%s] but missing: [%s]", strings.Join(expected, ", "), strings.Join(missing, ", "))
+	}
+	return &providers.AssertionResult{
+		Type:     "contains_all",
+		Expected: strings.Join(expected, ", "),
+		Actual:   truncate(output, 200),
+		Passed:   passed,
+		Message:  msg,
+	}
+}
+
+// EvalContainsNone checks that output contains none of the substrings.
+func EvalContainsNone(output string, unexpected []string) *providers.AssertionResult {
+	var found []string
+	for _, u := range unexpected {
+		if strings.Contains(output, u) {
+			found = append(found, u)
+		}
+	}
+	passed := len(found) == 0
+	msg := fmt.Sprintf("output contains none of [%s]", strings.Join(unexpected, ", "))
+	if !passed {
+		msg = fmt.Sprintf("expected 'output' to contain none of [%s] but found: [%s]", strings.Join(unexpected, ", "), strings.Join(found, ", "))
+	}
+	return &providers.AssertionResult{
+		Type:     "contains_none",
+		Expected: strings.Join(unexpected, ", "),
+		Actual:   truncate(output, 200),
+		Passed:   passed,
+		Message:  msg,
+	}
+}
+
 // EvalMatches checks if output matches the regex pattern.
 func EvalMatches(output, pattern string) *providers.AssertionResult {
 	re, err := regexp.Compile(pattern)
@@ -148,7 +222,7 @@ func EvalNotEquals(output, expected string) *providers.AssertionResult {
 }
 
 // EvalJSONPath extracts a value at a JSON path and compares to expected.
-// Supports simple dot-notation paths like $.status.phase.
+// Supports dot keys, bracketed keys and array indexes like $.items[0].name.
 func EvalJSONPath(jsonOutput, path, expected string) *providers.AssertionResult {
 	// Parse the JSON
 	var data interface{}
@@ -189,29 +263,41 @@ func EvalJSONPath(jsonOutput, path, expected string) *providers.AssertionResult
 	}
 }
 
-// navigateJSONPath navigates a simple JSON path ($.key1.key2).
-func navigateJSONPath(data interface{}, path string) (interface{}, error) {
-	// Strip leading $. or $
-	path = strings.TrimPrefix(path, "$.")
-	path = strings.TrimPrefix(path, "$")
-	if path == "" {
-		return data, nil
-	}
-
-	parts := strings.Split(path, ".")
-	current := data
-	for _, part := range parts {
-		m, ok := current.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("expected object at %q, got %T", part, current)
-		}
-		val, exists := m[part]
-		if !exists {
-			return nil, fmt.Errorf("key %q not found", part)
+// EvalDurationLessThan checks that the step finished within the expected
+// duration (e.g. "5s").
+func EvalDurationLessThan(elapsed time.Duration, expected string) *providers.AssertionResult {
+	limit, err := time.ParseDuration(expected)
+	if err != nil {
+		return &providers.AssertionResult{
+			Type:     "duration_less_than",
+			Expected: expected,
+			Actual:   elapsed.String(),
+			Passed:   false,
+			Message:  fmt.Sprintf("invalid duration: %v", err),
 		}
-		current = val
 	}
-	return current, nil
+	passed := elapsed <= limit
+	msg := fmt.Sprintf("took %s <= %s", elapsed, limit)
+	if !passed {
+		msg = fmt.Sprintf("took %s, want <= %s", elapsed, limit)
+	}
+	return &providers.AssertionResult{
+		Type:     "duration_less_than",
+		Expected: expected,
+		Actual:   elapsed.String(),
+		Passed:   passed,
+		Message:  msg,
+	}
+}
+
+// navigateJSONPath evaluates a JSONPath ($.key1.key2, $.items[0].name)
+// with the kernel evaluator, so both stacks accept the same paths. A path
+// without the leading $ is taken relative to the root.
+func navigateJSONPath(data interface{}, path string) (interface{}, error) {
+	if !strings.HasPrefix(path, "$") {
+		path = "$." + path
+	}
+	return eval.JSONPath(data, path)
 }
 
 func truncate(s string, maxLen int) string {
@@ -1,7 +1,11 @@
 package assertions
 
 import (
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
 )
 
 func TestContainsAssertion(t *testing.T) {
@@ -26,6 +30,36 @@ func TestNotContainsAssertion(t *testing.T) {
 	}
 }
 
+func TestContainsAllAssertion(t *testing.T) {
+	r := EvalContainsAll("started ok; ready", []string{"started", "ready"})
+	if !r.Passed {
+		t.Errorf("expected pass, got: %s", r.Message)
+	}
+	r = EvalContainsAll("started ok", []string{"started", "ready"})
+	if r.Passed || r.Message != "expected 'output' to contain all of [started, ready] but missing: [ready]" {
+		t.Errorf("expected fail, got: %s", r.Message)
+	}
+	r = Evaluate(schema.Assertion{ContainsAll: []string{"a"}}, "abc", 0, 0)
+	if !r.Passed || r.Type != "contains_all" {
+		t.Errorf("Evaluate: got %+v", r)
+	}
+}
+
+func TestContainsNoneAssertion(t *testing.T) {
+	r := EvalContainsNone("all good", []string{"ERROR", "FATAL"})
+	if !r.Passed {
+		t.Errorf("expected pass, got: %s", r.Message)
+	}
+	r = EvalContainsNone("ERROR: disk full", []string{"ERROR", "FATAL"})
+	if r.Passed || r.Message != "expected 'output' to contain none of [ERROR, FATAL] but found: [ERROR]" {
+		t.Errorf("expected fail, got: %s", r.Message)
+	}
+	r = Evaluate(schema.Assertion{ContainsNone: []string{"panic"}}, "panic: nil map", 0, 0)
+	if r.Passed || r.Type != "contains_none" {
+		t.Errorf("Evaluate: got %+v", r)
+	}
+}
+
 func TestMatchesAssertion(t *testing.T) {
 	r := EvalMatches("status: ok", "status.*ok")
 	if !r.Passed {
@@ -102,3 +136,53 @@ func TestJSONPathMissingPath(t *testing.T) {
 		t.Error("expected fail for missing JSON path")
 	}
 }
+
+func TestJSONPathArrayIndex(t *testing.T) {
+	jsonData := `{"items":[{"name":"a"},{"name":"b","ports":[80,443]}]}`
+	r := EvalJSONPath(jsonData, "$.items[1].ports[0]", "80")
+	if !r.Passed {
+		t.Errorf("expected pass for $.items[1].ports[0]=80, got: %s", r.Message)
+	}
+	r = EvalJSONPath(jsonData, "$.items[-1].name", "b")
+	if !r.Passed {
+		t.Errorf("expected pass for $.items[-1].name=b, got: %s", r.Message)
+	}
+	r = EvalJSONPath(jsonData, "$.items[2].name", "c")
+	if r.Passed || !strings.Contains(r.Message, "out of range") {
+		t.Errorf("expected out of range failure, got: %s", r.Message)
+	}
+}
+
+func TestDurationLessThanAssertion(t *testing.T) {
+	r := EvalDurationLessThan(1200*time.Millisecond, "5s")
+	if !r.Passed {
+		t.Errorf("expected pass, got: %s", r.Message)
+	}
+	r = EvalDurationLessThan(6*time.Second, "5s")
+	if r.Passed || r.Message != "took 6s, want <= 5s" {
+		t.Errorf("expected fail, got: %s", r.Message)
+	}
+	r = EvalDurationLessThan(time.Second, "soon")
+	if r.Passed || !strings.Contains(r.Message, "invalid duration") {
+		t.Errorf("expected invalid duration failure, got: %s", r.Message)
+	}
+	r = Evaluate(schema.Assertion{DurationLessThan: "1s"}, "", 0, 2*time.Second)
+	if r.Passed || r.Type != "duration_less_than" {
+		t.Errorf("Evaluate: got %+v", r)
+	}
+}
+
+func TestNotAssertion(t *testing.T) {
+	r := Evaluate(schema.Assertion{Equals: "error", Not: true}, "ok", 0, 0)
+	if !r.Passed || r.Message != `not: output "ok" != "error"` {
+		t.Errorf("expected pass, got %+v", r)
+	}
+	r = Evaluate(schema.Assertion{Contains: "error", Not: true}, "an error occurred", 0, 0)
+	if r.Passed || r.Message != `not: output contains "error"` {
+		t.Errorf("expected fail, got %+v", r)
+	}
+	r = Evaluate(schema.Assertion{Matches: "([", Not: true}, "x", 0, 0)
+	if r.Passed {
+		t.Errorf("invalid regex must not pass under not, got %+v", r)
+	}
+}
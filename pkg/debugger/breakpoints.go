@@ -0,0 +1,143 @@
+package debugger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Breakpoint pauses continue before a step runs. A Condition, when set, is
+// evaluated with the engine's condition logic and the breakpoint only fires
+// when it holds. OnError breakpoints match no step; they pause continue
+// after any step fails.
+type Breakpoint struct {
+	StepID    string
+	Condition string
+	OnError   bool
+}
+
+// String formats the breakpoint for list breakpoints.
+func (b Breakpoint) String() string {
+	if b.OnError {
+		return "on error"
+	}
+	if b.Condition != "" {
+		return fmt.Sprintf("%s if %s", b.StepID, b.Condition)
+	}
+	return b.StepID
+}
+
+// SetBreakOnError adds (or removes) the breakpoint that fires when any step
+// fails. It backs the --break-on-error flag.
+func (d *Debugger) SetBreakOnError(on bool) {
+	d.removeBreakpoints(func(b Breakpoint) bool { return b.OnError })
+	if on {
+		d.breakpoints = append(d.breakpoints, Breakpoint{OnError: true})
+	}
+}
+
+// handleBreak sets a breakpoint: break <step_id> [if <condition>].
+func (d *Debugger) handleBreak(parts []string) {
+	if len(parts) < 2 {
+		fmt.Fprintln(d.output, "Usage: break <step_id> [if <condition>]")
+		return
+	}
+	bp := Breakpoint{StepID: parts[1]}
+	if len(parts) > 2 {
+		if parts[2] != "if" || len(parts) < 4 {
+			fmt.Fprintln(d.output, "Usage: break <step_id> [if <condition>]")
+			return
+		}
+		bp.Condition = strings.Join(parts[3:], " ")
+	}
+	if d.stepIndex(bp.StepID) < 0 {
+		fmt.Fprintf(d.output, "  Unknown step: %q\n", bp.StepID)
+		return
+	}
+
+	// One breakpoint per step; setting it again replaces the condition
+	d.removeBreakpoints(func(b Breakpoint) bool { return !b.OnError && b.StepID == bp.StepID })
+	d.breakpoints = append(d.breakpoints, bp)
+	fmt.Fprintf(d.output, "  Breakpoint set: %s\n", bp)
+}
+
+// handleDelete removes the breakpoint on a step, or all breakpoints when no
+// step is given.
+func (d *Debugger) handleDelete(parts []string) {
+	if len(parts) < 2 {
+		n := len(d.breakpoints)
+		d.breakpoints = nil
+		fmt.Fprintf(d.output, "  Deleted %d breakpoint(s)\n", n)
+		return
+	}
+	stepID := parts[1]
+	if !d.removeBreakpoints(func(b Breakpoint) bool { return !b.OnError && b.StepID == stepID }) {
+		fmt.Fprintf(d.output, "  No breakpoint on %q\n", stepID)
+		return
+	}
+	fmt.Fprintf(d.output, "  Breakpoint deleted: %s\n", stepID)
+}
+
+// handleList displays active breakpoints.
+func (d *Debugger) handleList(parts []string) {
+	if len(parts) > 1 && parts[1] != "breakpoints" {
+		fmt.Fprintln(d.output, "Usage: list breakpoints")
+		return
+	}
+	if len(d.breakpoints) == 0 {
+		fmt.Fprintln(d.output, "  (no breakpoints)")
+		return
+	}
+	for i, bp := range d.breakpoints {
+		fmt.Fprintf(d.output, "  %d. %s\n", i+1, bp)
+	}
+}
+
+// breakpointAt returns the breakpoint that fires before the step at index,
+// or nil.
+func (d *Debugger) breakpointAt(index int) *Breakpoint {
+	stepID := d.runbook.Steps[index].ID
+	for i := range d.breakpoints {
+		bp := &d.breakpoints[i]
+		if bp.OnError || bp.StepID != stepID {
+			continue
+		}
+		if bp.Condition == "" || d.engine.EvalConditionPublic(bp.Condition) {
+			return bp
+		}
+	}
+	return nil
+}
+
+// breakOnError reports whether an on-error breakpoint is set.
+func (d *Debugger) breakOnError() bool {
+	for _, bp := range d.breakpoints {
+		if bp.OnError {
+			return true
+		}
+	}
+	return false
+}
+
+// removeBreakpoints drops the breakpoints matching fn and reports whether
+// any were removed.
+func (d *Debugger) removeBreakpoints(fn func(Breakpoint) bool) bool {
+	kept := d.breakpoints[:0]
+	for _, bp := range d.breakpoints {
+		if !fn(bp) {
+			kept = append(kept, bp)
+		}
+	}
+	removed := len(kept) != len(d.breakpoints)
+	d.breakpoints = kept
+	return removed
+}
+
+// stepIndex returns the index of the step with the given ID, or -1.
+func (d *Debugger) stepIndex(stepID string) int {
+	for i, step := range d.runbook.Steps {
+		if step.ID == stepID {
+			return i
+		}
+	}
+	return -1
+}
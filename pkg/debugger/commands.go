@@ -39,17 +39,24 @@ func (d *Debugger) handleNext(ctx context.Context) error {
 	return nil
 }
 
-// handleContinue executes all remaining steps.
+// handleContinue executes steps until a breakpoint fires or the runbook
+// ends. The current step always runs, so continue moves past the
+// breakpoint it stopped at.
 func (d *Debugger) handleContinue(ctx context.Context) error {
-	for d.state.CurrentStepIndex < len(d.runbook.Steps) {
+	for first := true; d.state.CurrentStepIndex < len(d.runbook.Steps); first = false {
+		if !first {
+			if bp := d.breakpointAt(d.state.CurrentStepIndex); bp != nil {
+				fmt.Fprintf(d.output, "Breakpoint hit: %s\n", bp)
+				return nil
+			}
+		}
 		if err := d.handleNext(ctx); err != nil {
 			return err
 		}
-		// Stop on failure
-		if len(d.state.History) > 0 {
+		if d.breakOnError() && len(d.state.History) > 0 {
 			last := d.state.History[len(d.state.History)-1]
 			if last.Status == "failed" {
-				fmt.Fprintf(d.output, "Halted on failure.\n")
+				fmt.Fprintf(d.output, "Breakpoint hit: on error (%s failed)\n", last.StepID)
 				return nil
 			}
 		}
@@ -216,7 +223,11 @@ func (d *Debugger) handleDump() {
 func (d *Debugger) handleHelp() {
 	fmt.Fprintln(d.output, "Available commands:")
 	fmt.Fprintln(d.output, "  next (n)         Execute the next step")
-	fmt.Fprintln(d.output, "  continue (c)     Execute all remaining steps")
+	fmt.Fprintln(d.output, "  step (s)         Execute the next step (alias of next)")
+	fmt.Fprintln(d.output, "  continue (c)     Run until the next breakpoint or the end")
+	fmt.Fprintln(d.output, "  break (b)        Set breakpoint: break <step_id> [if <condition>]")
+	fmt.Fprintln(d.output, "  delete (d)       Remove breakpoint: delete [step_id] (all if omitted)")
+	fmt.Fprintln(d.output, "  list breakpoints Show active breakpoints")
 	fmt.Fprintln(d.output, "  print vars       Show current variables")
 	fmt.Fprintln(d.output, "  print captures   Show captured values")
 	fmt.Fprintln(d.output, "  history          Show executed step results")
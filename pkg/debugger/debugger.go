@@ -25,6 +25,8 @@ type Debugger struct {
 	collector providers.EvidenceCollector
 	mode      string
 	actor     string
+
+	breakpoints []Breakpoint
 }
 
 // New creates a new debugger for the given runbook.
@@ -54,7 +56,7 @@ func (d *Debugger) Engine() *runtime.Engine {
 
 // Run starts the interactive REPL loop.
 func (d *Debugger) Run(ctx context.Context) error {
-	commands := []string{"next", "continue", "dump", "print vars", "print captures",
+	commands := []string{"next", "step", "continue", "break", "delete", "list breakpoints", "dump", "print vars", "print captures",
 		"history", "evidence set", "evidence check", "evidence attach",
 		"approve", "snapshot", "help", "quit"}
 
@@ -98,7 +100,7 @@ func (d *Debugger) Run(ctx context.Context) error {
 		cmd := parts[0]
 
 		switch cmd {
-		case "next", "n":
+		case "next", "n", "step", "s":
 			if err := d.handleNext(ctx); err != nil {
 				fmt.Fprintf(d.output, "Error: %v\n", err)
 			}
@@ -106,6 +108,12 @@ func (d *Debugger) Run(ctx context.Context) error {
 			if err := d.handleContinue(ctx); err != nil {
 				fmt.Fprintf(d.output, "Error: %v\n", err)
 			}
+		case "break", "b":
+			d.handleBreak(parts)
+		case "delete", "d":
+			d.handleDelete(parts)
+		case "list", "l":
+			d.handleList(parts)
 		case "print", "p":
 			d.handlePrint(parts)
 		case "history", "h":
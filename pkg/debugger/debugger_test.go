@@ -2,6 +2,7 @@ package debugger
 
 import (
 	"bytes"
+	"context"
 	"strings"
 	"testing"
 
@@ -28,7 +29,7 @@ func TestDebuggerCommandHelp(t *testing.T) {
 	}
 	d.handleHelp()
 	out := buf.String()
-	cmds := []string{"next", "continue", "print", "history", "evidence", "approve", "snapshot", "dump", "help", "quit"}
+	cmds := []string{"next", "step", "continue", "break", "delete", "list breakpoints", "print", "history", "evidence", "approve", "snapshot", "dump", "help", "quit"}
 	for _, cmd := range cmds {
 		if !strings.Contains(out, cmd) {
 			t.Errorf("help output missing command %q", cmd)
@@ -106,3 +107,113 @@ func TestDebuggerPromptFormat(t *testing.T) {
 		t.Errorf("prompt format unexpected: %q", prompt)
 	}
 }
+
+// okExecutor returns "ok" for every command.
+type okExecutor struct{}
+
+func (okExecutor) Execute(ctx context.Context, command string, args []string, env []string) (*providers.CommandResult, error) {
+	return &providers.CommandResult{Stdout: []byte("ok")}, nil
+}
+
+// newTestDebugger builds a debugger over cli steps, running in a temp dir.
+func newTestDebugger(t *testing.T, steps ...schema.Step) (*Debugger, *bytes.Buffer) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	for i := range steps {
+		steps[i].Type = "cli"
+		steps[i].With = &schema.CLIStepConfig{Argv: []string{"echo", steps[i].ID}}
+	}
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "bp", Vars: map[string]string{"env": "prod"}},
+		Steps:      steps,
+	}
+	d, err := New(rb, okExecutor{}, &providers.DryRunCollector{}, "real", "tester")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	var buf bytes.Buffer
+	d.output = &buf
+	return d, &buf
+}
+
+// TestDebuggerBreakpoints verifies break, delete and list breakpoints.
+func TestDebuggerBreakpoints(t *testing.T) {
+	d, buf := newTestDebugger(t, schema.Step{ID: "a"}, schema.Step{ID: "b"})
+
+	d.handleBreak([]string{"break", "missing"})
+	d.handleBreak([]string{"break", "a"})
+	d.handleBreak([]string{"break", "b", "if", `env`, "==", `"prod"`})
+	d.handleBreak([]string{"break", "a", "if", "true"}) // replaces the first
+	d.SetBreakOnError(true)
+	if len(d.breakpoints) != 3 {
+		t.Fatalf("breakpoints = %+v, want 3", d.breakpoints)
+	}
+	if !strings.Contains(buf.String(), `Unknown step: "missing"`) {
+		t.Errorf("missing unknown step error: %s", buf.String())
+	}
+
+	buf.Reset()
+	d.handleList([]string{"list", "breakpoints"})
+	for _, want := range []string{`b if env == "prod"`, "a if true", "on error"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("list missing %q: %s", want, buf.String())
+		}
+	}
+
+	d.handleDelete([]string{"delete", "a"})
+	if len(d.breakpoints) != 2 || d.breakpoints[0].StepID != "b" {
+		t.Errorf("after delete a: %+v", d.breakpoints)
+	}
+	d.handleDelete([]string{"delete"})
+	if len(d.breakpoints) != 0 {
+		t.Errorf("after delete: %+v", d.breakpoints)
+	}
+}
+
+// TestDebuggerContinueStopsAtBreakpoint verifies continue pauses before a
+// breakpointed step, skips false conditions and resumes past the stop.
+func TestDebuggerContinueStopsAtBreakpoint(t *testing.T) {
+	d, buf := newTestDebugger(t, schema.Step{ID: "a"}, schema.Step{ID: "b"}, schema.Step{ID: "c"}, schema.Step{ID: "d"})
+	d.handleBreak([]string{"break", "b", "if", `env == "staging"`})
+	d.handleBreak([]string{"break", "c"})
+	ctx := context.Background()
+
+	if err := d.handleContinue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d.state.CurrentStepIndex != 2 || !strings.Contains(buf.String(), "Breakpoint hit: c") {
+		t.Fatalf("stopped at index %d: %s", d.state.CurrentStepIndex, buf.String())
+	}
+
+	if err := d.handleContinue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d.state.CurrentStepIndex != 4 || !strings.Contains(buf.String(), "All steps completed") {
+		t.Errorf("second continue stopped at index %d: %s", d.state.CurrentStepIndex, buf.String())
+	}
+}
+
+// TestDebuggerBreakOnError verifies continue runs past failures unless
+// --break-on-error is set.
+func TestDebuggerBreakOnError(t *testing.T) {
+	fail := schema.Step{ID: "b", Assertions: []schema.Assertion{{Contains: "never"}}}
+	ctx := context.Background()
+
+	d, _ := newTestDebugger(t, schema.Step{ID: "a"}, fail, schema.Step{ID: "c"})
+	if err := d.handleContinue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d.state.CurrentStepIndex != 3 {
+		t.Errorf("without break-on-error stopped at index %d, want 3", d.state.CurrentStepIndex)
+	}
+
+	d, buf := newTestDebugger(t, schema.Step{ID: "a"}, fail, schema.Step{ID: "c"})
+	d.SetBreakOnError(true)
+	if err := d.handleContinue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d.state.CurrentStepIndex != 2 || !strings.Contains(buf.String(), "on error (b failed)") {
+		t.Errorf("with break-on-error stopped at index %d: %s", d.state.CurrentStepIndex, buf.String())
+	}
+}
@@ -0,0 +1,183 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// --- D2 ---
+
+// d2Writer accumulates D2 shape declarations (nested inside iterate
+// containers) and edges (always written at the root with full paths).
+type d2Writer struct {
+	shapes strings.Builder
+	edges  []string
+	styles []string
+	loops  int
+}
+
+func generateD2(rb *schema.Runbook) string {
+	w := &d2Writer{}
+	w.shapes.WriteString("direction: down\n\n")
+
+	nodes := rootNodes(rb)
+	if name := rb.Meta.Name; name != "" {
+		w.shapes.WriteString(fmt.Sprintf("title: %s {\n  shape: text\n  near: top-center\n}\n\n", d2Quote(name)))
+	}
+	w.shapes.WriteString("start: Start {\n  shape: oval\n}\n")
+
+	entry, _ := w.sequence(nodes, "", 0, []string{"start"})
+	if entry == "" {
+		return w.shapes.String()
+	}
+
+	var b strings.Builder
+	b.WriteString(w.shapes.String())
+	b.WriteString("\n")
+	for _, e := range w.edges {
+		b.WriteString(e + "\n")
+	}
+	for _, s := range w.styles {
+		b.WriteString(s + "\n")
+	}
+	return b.String()
+}
+
+// sequence declares nodes in order under container and chains them with
+// edges starting from prev. It returns the first node key and the keys the
+// sequence exits through.
+func (w *d2Writer) sequence(nodes []schema.TreeNode, container string, depth int, prev []string) (string, []string) {
+	entry := ""
+	for _, n := range nodes {
+		var key string
+		switch {
+		case n.Step.ID == "" && n.Iterate != nil:
+			key = w.iterate(n.Iterate, container, depth)
+		case n.Step.ID != "":
+			key = w.step(n.Step, container, depth)
+		default:
+			continue
+		}
+		if entry == "" {
+			entry = key
+		}
+		w.link(prev, key)
+		prev = []string{key}
+
+		// Branches fan out from this step and rejoin at the next node.
+		if len(n.Branches) > 0 {
+			exits := []string{key + ` -> %s: continue`}
+			for _, br := range n.Branches {
+				label := br.Label
+				if label == "" {
+					label = truncate(br.Condition, 30)
+				}
+				brEntry, brExits := w.sequence(br.Steps, container, depth, nil)
+				if brEntry == "" {
+					continue
+				}
+				w.edges = append(w.edges, fmt.Sprintf("%s -> %s: %s", key, brEntry, d2Quote(label)))
+				for _, x := range brExits {
+					if !strings.Contains(x, "%s") {
+						x += " -> %s"
+					}
+					exits = append(exits, x)
+				}
+			}
+			prev = exits
+		}
+
+		if n.Step.ID != "" {
+			w.outcomes(n.Step, key, container, depth)
+		}
+	}
+	return entry, prev
+}
+
+// link connects each pending exit to key. Exits are either bare keys or
+// edge templates ("a -> %s: label") produced by branch fan-out.
+func (w *d2Writer) link(prev []string, key string) {
+	for _, p := range prev {
+		if strings.Contains(p, "%s") {
+			w.edges = append(w.edges, fmt.Sprintf(p, key))
+		} else {
+			w.edges = append(w.edges, p+" -> "+key)
+		}
+	}
+}
+
+func (w *d2Writer) step(s schema.Step, container string, depth int) string {
+	id := safeID(s.ID)
+	title := s.Title
+	if title == "" {
+		title = s.ID
+	}
+	label := stepIcon(s.Type) + " " + title
+	if caps := captureNames(s); caps != "" {
+		label += "\\n→ " + caps
+	}
+
+	indent := strings.Repeat("  ", depth)
+	w.shapes.WriteString(fmt.Sprintf("%s%s: %s {\n", indent, id, d2Quote(label)))
+	w.shapes.WriteString(fmt.Sprintf("%s  shape: %s\n", indent, d2Shape(s.Type)))
+	if s.Type == "cli" {
+		w.shapes.WriteString(fmt.Sprintf("%s  style.fill: \"#1a3a4a\"\n%s  style.stroke: \"#0af\"\n", indent, indent))
+	}
+	w.shapes.WriteString(indent + "}\n")
+	return qualify(container, id)
+}
+
+func (w *d2Writer) iterate(it *schema.IterateBlock, container string, depth int) string {
+	w.loops++
+	id := fmt.Sprintf("iterate_%d", w.loops)
+	indent := strings.Repeat("  ", depth)
+	w.shapes.WriteString(fmt.Sprintf("%s%s: %s {\n", indent, id, d2Quote("🔁 "+iterateLabel(it))))
+	key := qualify(container, id)
+	w.sequence(it.Steps, key, depth+1, nil)
+	w.shapes.WriteString(indent + "}\n")
+	return key
+}
+
+func (w *d2Writer) outcomes(s schema.Step, key, container string, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, o := range s.Outcomes {
+		id := safeID(s.ID + "_" + o.State)
+		w.shapes.WriteString(fmt.Sprintf("%s%s: %s {\n%s  shape: oval\n%s}\n",
+			indent, id, d2Quote(outcomeLabel(o.State)), indent, indent))
+		label := truncate(o.When, 30)
+		if label == "" {
+			label = o.State
+		}
+		outKey := qualify(container, id)
+		w.edges = append(w.edges, fmt.Sprintf("%s -> %s: %s", key, outKey, d2Quote(label)))
+		if fill := outcomeFill(o.State); fill != "" {
+			w.styles = append(w.styles, fmt.Sprintf("%s.style.fill: %q", outKey, fill))
+		}
+	}
+}
+
+func d2Shape(stepType string) string {
+	switch stepType {
+	case "manual":
+		return "hexagon"
+	case "tool":
+		return "parallelogram"
+	case "invoke":
+		return "page"
+	default:
+		return "rectangle"
+	}
+}
+
+func d2Quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func qualify(container, id string) string {
+	if container == "" {
+		return id
+	}
+	return container + "." + id
+}
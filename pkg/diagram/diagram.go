@@ -1,9 +1,11 @@
 // Package diagram generates visual diagrams from parsed runbooks.
-// Supports Mermaid flowchart and ASCII formats.
+// Supports Mermaid flowchart, D2, PlantUML sequence and ASCII formats, and
+// HTML pages that render the Mermaid flowchart with a step status overlay.
 package diagram
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/mattn/go-runewidth"
@@ -14,18 +16,46 @@ import (
 type Format string
 
 const (
-	FormatMermaid Format = "mermaid"
-	FormatASCII   Format = "ascii"
+	FormatMermaid  Format = "mermaid"
+	FormatD2       Format = "d2"
+	FormatPlantUML Format = "plantuml"
+	FormatASCII    Format = "ascii"
+	FormatHTML     Format = "html"
 )
 
+// Formats lists the supported diagram formats.
+var Formats = []Format{FormatMermaid, FormatD2, FormatPlantUML, FormatASCII, FormatHTML}
+
+// Options configures GenerateWithOptions.
+type Options struct {
+	// Statuses colour step nodes in Mermaid and HTML output. When set, steps
+	// without an entry are shown as pending.
+	Statuses []StepStatus
+
+	// MermaidURL is the Mermaid ES module loaded by HTML output
+	// (default: DefaultMermaidURL). Point it at a local copy for offline use.
+	MermaidURL string
+}
+
 // Generate produces a diagram string from a parsed runbook.
 func Generate(rb *schema.Runbook, format Format) (string, error) {
+	return GenerateWithOptions(rb, format, Options{})
+}
+
+// GenerateWithOptions is Generate with a status overlay and HTML settings.
+func GenerateWithOptions(rb *schema.Runbook, format Format, opts Options) (string, error) {
 	if rb == nil {
 		return "", fmt.Errorf("nil runbook")
 	}
 	switch format {
 	case FormatMermaid:
-		return generateMermaid(rb), nil
+		return mermaidFlowchart(rb, statusMap(opts.Statuses)), nil
+	case FormatHTML:
+		return generateHTML(rb, opts)
+	case FormatD2:
+		return generateD2(rb), nil
+	case FormatPlantUML:
+		return generatePlantUML(rb), nil
 	case FormatASCII:
 		return generateASCII(rb), nil
 	default:
@@ -35,7 +65,9 @@ func Generate(rb *schema.Runbook, format Format) (string, error) {
 
 // --- Mermaid flowchart ---
 
-func generateMermaid(rb *schema.Runbook) string {
+// mermaidFlowchart renders the flowchart. With a non-nil status map, step
+// nodes are coloured by status instead of by type.
+func mermaidFlowchart(rb *schema.Runbook, status map[string]string) string {
 	var b strings.Builder
 	b.WriteString("flowchart TD\n")
 
@@ -127,6 +159,11 @@ func generateMermaid(rb *schema.Runbook) string {
 		}
 	}
 
+	if status != nil {
+		writeStatusClasses(&b, steps, status)
+		return b.String()
+	}
+
 	// Style CLI steps
 	for _, s := range steps {
 		if s.stepType == "cli" {
@@ -138,17 +175,22 @@ func generateMermaid(rb *schema.Runbook) string {
 }
 
 func outcomeShape(state string) string {
+	return "([" + outcomeLabel(state) + "])"
+}
+
+// outcomeLabel returns the display label for an outcome state.
+func outcomeLabel(state string) string {
 	switch state {
 	case "resolved":
-		return "([✅ Resolved])"
+		return "✅ Resolved"
 	case "escalated":
-		return "([⚠️ Request Assistance])"
+		return "⚠️ Request Assistance"
 	case "no_action":
-		return "([ℹ️ No Action Needed])"
+		return "ℹ️ No Action Needed"
 	case "needs_rca":
-		return "([🔍 Needs RCA])"
+		return "🔍 Needs RCA"
 	default:
-		return fmt.Sprintf("([%s])", state)
+		return state
 	}
 }
 
@@ -167,6 +209,12 @@ func outcomeStyle(state string) string {
 	}
 }
 
+// outcomeFill returns the fill colour from outcomeStyle, or "" if unstyled.
+func outcomeFill(state string) string {
+	fill, _, _ := strings.Cut(outcomeStyle(state), ",")
+	return strings.TrimPrefix(fill, "fill:")
+}
+
 // --- ASCII ---
 
 func generateASCII(rb *schema.Runbook) string {
@@ -412,14 +460,7 @@ func flattenTree(entries []schema.TreeNode) []diagramStep {
 			stepType: s.Type,
 		}
 
-		// Capture summary
-		if s.Capture != nil {
-			caps := make([]string, 0, len(s.Capture))
-			for k := range s.Capture {
-				caps = append(caps, k)
-			}
-			ds.capture = strings.Join(caps, ", ")
-		}
+		ds.capture = captureNames(s)
 
 		// Branches
 		for _, br := range e.Branches {
@@ -444,6 +485,53 @@ func flattenTree(entries []schema.TreeNode) []diagramStep {
 	return result
 }
 
+// rootNodes returns the runbook tree, falling back to flat steps.
+func rootNodes(rb *schema.Runbook) []schema.TreeNode {
+	if len(rb.Tree) > 0 {
+		return rb.Tree
+	}
+	nodes := make([]schema.TreeNode, 0, len(rb.Steps))
+	for _, s := range rb.Steps {
+		nodes = append(nodes, schema.TreeNode{Step: s})
+	}
+	return nodes
+}
+
+// captureNames returns the step's capture variable names, sorted and joined.
+func captureNames(s schema.Step) string {
+	if len(s.Capture) == 0 {
+		return ""
+	}
+	caps := make([]string, 0, len(s.Capture))
+	for k := range s.Capture {
+		caps = append(caps, k)
+	}
+	sort.Strings(caps)
+	return strings.Join(caps, ", ")
+}
+
+// iterateLabel summarises an iterate block's loop control.
+func iterateLabel(it *schema.IterateBlock) string {
+	var parts []string
+	if it.Over != "" {
+		as := it.As
+		if as == "" {
+			as = "item"
+		}
+		parts = append(parts, fmt.Sprintf("for %s in %s", as, it.Over))
+	}
+	if it.Until != "" {
+		parts = append(parts, "until "+truncate(it.Until, 30))
+	}
+	if it.Max > 0 {
+		parts = append(parts, fmt.Sprintf("max %d", it.Max))
+	}
+	if len(parts) == 0 {
+		return "iterate"
+	}
+	return "iterate: " + strings.Join(parts, ", ")
+}
+
 // --- string helpers ---
 
 func nodeDefinition(s diagramStep) string {
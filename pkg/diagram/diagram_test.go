@@ -1,9 +1,11 @@
 package diagram
 
 import (
+	"path/filepath"
 	"strings"
 	"testing"
 
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
 	"github.com/ormasoftchile/gert/pkg/schema"
 )
 
@@ -209,3 +211,248 @@ func TestFlattenTree_NestedSteps(t *testing.T) {
 		t.Errorf("expected iterate step b, got %s", result[1].id)
 	}
 }
+
+// treeWithBranchesAndIterate builds a tree runbook with a branch fan-out,
+// an iterate block and an outcome, shared by the D2 and PlantUML tests.
+func treeWithBranchesAndIterate() *schema.Runbook {
+	return &schema.Runbook{
+		Meta: schema.Meta{Name: "tree-test"},
+		Tree: []schema.TreeNode{
+			{
+				Step: schema.Step{ID: "check", Type: "cli", Title: "Check status",
+					Capture: map[string]string{"status": "stdout"}},
+				Branches: []schema.Branch{
+					{
+						Condition: `status == "error"`,
+						Label:     "Error path",
+						Steps: []schema.TreeNode{
+							{Step: schema.Step{ID: "fix", Type: "tool", Title: "Apply fix",
+								Tool: &schema.ToolStepConfig{Name: "kubectl"}}},
+						},
+					},
+					{
+						Condition: `status == "degraded"`,
+						Steps: []schema.TreeNode{
+							{Step: schema.Step{ID: "inspect", Type: "manual", Title: "Inspect"}},
+						},
+					},
+				},
+			},
+			{
+				Iterate: &schema.IterateBlock{
+					Over: "{{ .nodes }}",
+					As:   "node",
+					Max:  5,
+					Steps: []schema.TreeNode{
+						{Step: schema.Step{ID: "drain-node", Type: "cli", Title: "Drain node"}},
+						{Step: schema.Step{ID: "verify-node", Type: "manual", Title: "Verify node"}},
+					},
+				},
+			},
+			{Step: schema.Step{
+				ID:    "done",
+				Type:  "manual",
+				Title: "Confirm",
+				Outcomes: []schema.Outcome{
+					{When: "healthy", State: "resolved"},
+				},
+			}},
+		},
+	}
+}
+
+func TestGenerateD2_Tree(t *testing.T) {
+	out, err := Generate(treeWithBranchesAndIterate(), FormatD2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"direction: down",
+		"start -> check",
+		`check -> fix: "Error path"`,
+		`check -> inspect: "status == \"degraded\""`,
+		"check -> iterate_1: continue",
+		"fix -> iterate_1",
+		"inspect -> iterate_1",
+		"iterate_1.drain_node -> iterate_1.verify_node",
+		"iterate_1 -> done",
+		`done -> done_resolved: "healthy"`,
+		`done_resolved.style.fill: "#0d6"`,
+		"shape: hexagon",
+		"shape: parallelogram",
+		"→ status",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q, got:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, `iterate_1: "🔁 iterate: for node in {{ .nodes }}, max 5" {`) {
+		t.Errorf("missing iterate container, got:\n%s", out)
+	}
+}
+
+func TestGenerateD2_FlatSteps(t *testing.T) {
+	rb := &schema.Runbook{
+		Steps: []schema.Step{
+			{ID: "a", Type: "cli", Title: "A"},
+			{ID: "b", Type: "invoke", Title: "B"},
+		},
+	}
+	out, err := Generate(rb, FormatD2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "start -> a\na -> b\n") {
+		t.Errorf("missing sequential edges, got:\n%s", out)
+	}
+	if !strings.Contains(out, "shape: page") {
+		t.Errorf("missing invoke shape, got:\n%s", out)
+	}
+}
+
+func TestGeneratePlantUML_Tree(t *testing.T) {
+	out, err := Generate(treeWithBranchesAndIterate(), FormatPlantUML)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{
+		"@startuml\n",
+		"title tree-test\n",
+		"actor Operator\n",
+		"Engine -> Shell: ⚡ Check status\n",
+		"Shell --> Engine: status\n",
+		"alt Error path\n",
+		"  Engine -> Tool: 🔧 Apply fix (kubectl)\n",
+		"else status == \"degraded\"\n",
+		"  Engine -> Operator: 🧑 Inspect\n",
+		"loop iterate: for node in {{ .nodes }}, max 5\n",
+		"  Engine -> Shell: ⚡ Drain node\n",
+		"note over Engine #0d6: ✅ Resolved\\nwhen: healthy\n",
+		"@enduml\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q, got:\n%s", want, out)
+		}
+	}
+	if got := strings.Count(out, "\nend\n"); got != 2 {
+		t.Errorf("expected 2 top-level end markers (alt, loop), got %d:\n%s", got, out)
+	}
+}
+
+func TestIterateLabel(t *testing.T) {
+	tests := []struct {
+		it   schema.IterateBlock
+		want string
+	}{
+		{schema.IterateBlock{}, "iterate"},
+		{schema.IterateBlock{Max: 3}, "iterate: max 3"},
+		{schema.IterateBlock{Until: "done == true", Max: 10}, "iterate: until done == true, max 10"},
+		{schema.IterateBlock{Over: "{{ .items }}"}, "iterate: for item in {{ .items }}"},
+	}
+	for _, tt := range tests {
+		if got := iterateLabel(&tt.it); got != tt.want {
+			t.Errorf("iterateLabel(%+v) = %q, want %q", tt.it, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateHTML_StatusOverlay(t *testing.T) {
+	rb := &schema.Runbook{
+		Meta: schema.Meta{Name: "overlay-test"},
+		Tree: []schema.TreeNode{
+			{
+				Step: schema.Step{ID: "check", Type: "cli", Title: "Check <status>"},
+				Branches: []schema.Branch{{
+					Label: "broken",
+					Steps: []schema.TreeNode{{Step: schema.Step{ID: "fix", Type: "cli", Title: "Fix"}}},
+				}},
+			},
+			{Step: schema.Step{ID: "confirm", Type: "manual", Title: "Confirm"}},
+		},
+	}
+	opts := Options{
+		Statuses: []StepStatus{
+			{StepID: "check", Status: "failed"},
+			{StepID: "check", Status: "passed"}, // a retry: the last result wins
+			{StepID: "fix", Status: "skipped"},
+		},
+		MermaidURL: "./mermaid.esm.min.mjs",
+	}
+
+	out, err := GenerateWithOptions(rb, FormatHTML, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"<title>overlay-test</title>",
+		"    class check passed\n",
+		"    class fix skipped\n",
+		"    class confirm pending\n",
+		"Check &lt;status&gt;",
+		`import mermaid from "./mermaid.esm.min.mjs"`,
+		"</span>Failed</li>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "style check fill:#1a3a4a") {
+		t.Error("type styling should give way to the status overlay")
+	}
+
+	// Plain Mermaid output is unchanged without statuses
+	plain, _ := Generate(rb, FormatMermaid)
+	if strings.Contains(plain, "classDef") || !strings.Contains(plain, "style check fill:#1a3a4a") {
+		t.Errorf("mermaid output changed without statuses:\n%s", plain)
+	}
+}
+
+func TestGenerateKernel_Runbooks(t *testing.T) {
+	files, err := filepath.Glob("../../runbooks/*.yaml")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no runbooks found: %v", err)
+	}
+	for _, f := range files {
+		rb, err := kschema.LoadFile(f)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		for _, format := range []Format{FormatMermaid, FormatD2, FormatPlantUML, FormatASCII} {
+			if _, err := GenerateKernel(rb, format); err != nil {
+				t.Errorf("%s (%s): %v", f, format, err)
+			}
+		}
+	}
+}
+
+func TestGenerateKernel_Branches(t *testing.T) {
+	rb := &kschema.Runbook{
+		Meta: kschema.Meta{Name: "kernel-test"},
+		Steps: []kschema.Step{
+			{ID: "probe", Type: kschema.StepTool, Tool: "curl", Action: "get"},
+			{ID: "route", Type: kschema.StepBranch, Branches: []kschema.Branch{
+				{Condition: "probe.status != 200", Label: "down", Steps: []kschema.Step{
+					{ID: "page", Type: kschema.StepManual},
+				}},
+			}},
+			{ID: "done", Type: kschema.StepEnd, Outcome: &kschema.Outcome{Category: kschema.OutcomeResolved, Code: "healthy"}},
+		},
+	}
+
+	out, err := GenerateKernel(rb, FormatMermaid)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`probe[/"🔧 probe (curl.get)"/]`,
+		`route -->|"down"| page`,
+		"page --> done",
+		"done_resolved",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q, got:\n%s", want, out)
+		}
+	}
+}
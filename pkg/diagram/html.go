@@ -0,0 +1,159 @@
+package diagram
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// Step statuses understood by the overlay.
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+	StatusPending = "pending"
+)
+
+// DefaultMermaidURL is the Mermaid ES module HTML output loads by default.
+const DefaultMermaidURL = "https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.esm.min.mjs"
+
+// StepStatus is the execution status of one step, for the overlay.
+type StepStatus struct {
+	StepID string `json:"stepId"`
+	Status string `json:"status"` // passed, failed, skipped, pending
+}
+
+// statusStyles lists the overlay classes in legend order.
+var statusStyles = []struct {
+	status, label, style string
+}{
+	{StatusPassed, "Passed", "fill:#2da44e,stroke:#1a7f37,color:#fff"},
+	{StatusFailed, "Failed", "fill:#cf222e,stroke:#a40e26,color:#fff"},
+	{StatusSkipped, "Skipped", "fill:#d4a72c,stroke:#9a6700,color:#000"},
+	{StatusPending, "Pending", "fill:#8c959f,stroke:#6e7781,color:#fff"},
+}
+
+// statusMap indexes statuses by step ID; a later entry for the same step
+// (a retry or another iteration) wins. It returns nil for no statuses.
+func statusMap(statuses []StepStatus) map[string]string {
+	if len(statuses) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(statuses))
+	for _, st := range statuses {
+		m[st.StepID] = statusClass(st.Status)
+	}
+	return m
+}
+
+// statusClass maps a step result status onto an overlay class.
+func statusClass(status string) string {
+	switch status {
+	case StatusPassed, StatusFailed, StatusSkipped:
+		return status
+	case "error":
+		return StatusFailed
+	default:
+		return StatusPending
+	}
+}
+
+// writeStatusClasses assigns every step node, including branch steps, its
+// status class. Steps missing from status are pending.
+func writeStatusClasses(b *strings.Builder, steps []diagramStep, status map[string]string) {
+	byClass := make(map[string][]string)
+	var walk func(steps []diagramStep)
+	walk = func(steps []diagramStep) {
+		for _, s := range steps {
+			class, ok := status[s.id]
+			if !ok {
+				class = StatusPending
+			}
+			byClass[class] = append(byClass[class], safeID(s.id))
+			for _, br := range s.branches {
+				walk(flattenTree(br.steps))
+			}
+		}
+	}
+	walk(steps)
+
+	for _, st := range statusStyles {
+		b.WriteString("    classDef " + st.status + " " + st.style + "\n")
+	}
+	for _, st := range statusStyles {
+		if ids := byClass[st.status]; len(ids) > 0 {
+			b.WriteString("    class " + strings.Join(ids, ",") + " " + st.status + "\n")
+		}
+	}
+}
+
+var htmlTemplate = template.Must(template.New("diagram").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+  body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2rem; color: #1f2328; }
+  h1 { font-size: 1.4rem; margin-bottom: 0.25rem; }
+  .legend { display: flex; gap: 1.25rem; margin: 1rem 0 1.5rem; padding: 0; list-style: none; }
+  .legend li { display: flex; align-items: center; gap: 0.4rem; font-size: 0.9rem; }
+  .swatch { width: 0.9rem; height: 0.9rem; border-radius: 3px; display: inline-block; }
+</style>
+</head>
+<body>
+<h1>{{ .Title }}</h1>
+<ul class="legend">
+{{- range .Legend }}
+  <li><span class="swatch" style="background:{{ .Fill }}"></span>{{ .Label }}</li>
+{{- end }}
+</ul>
+<pre class="mermaid">
+{{ .Source }}</pre>
+<script type="module">
+  import mermaid from {{ .MermaidURL }};
+  mermaid.initialize({ startOnLoad: true, securityLevel: "strict", flowchart: { htmlLabels: true } });
+</script>
+</body>
+</html>
+`))
+
+type legendEntry struct {
+	Label string
+	Fill  template.CSS
+}
+
+// generateHTML renders a standalone page with the Mermaid flowchart, every
+// step coloured by status, and a legend.
+func generateHTML(rb *schema.Runbook, opts Options) (string, error) {
+	status := statusMap(opts.Statuses)
+	if status == nil {
+		status = map[string]string{}
+	}
+	mermaidURL := opts.MermaidURL
+	if mermaidURL == "" {
+		mermaidURL = DefaultMermaidURL
+	}
+	title := rb.Meta.Name
+	if title == "" {
+		title = "Runbook"
+	}
+
+	var legend []legendEntry
+	for _, st := range statusStyles {
+		fill, _, _ := strings.Cut(strings.TrimPrefix(st.style, "fill:"), ",")
+		legend = append(legend, legendEntry{Label: st.label, Fill: template.CSS(fill)})
+	}
+
+	var b strings.Builder
+	err := htmlTemplate.Execute(&b, map[string]any{
+		"Title":      title,
+		"Legend":     legend,
+		"Source":     mermaidFlowchart(rb, status),
+		"MermaidURL": mermaidURL,
+	})
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
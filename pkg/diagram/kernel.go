@@ -0,0 +1,97 @@
+package diagram
+
+import (
+	"fmt"
+
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// GenerateKernel produces a diagram string from a kernel/v0 runbook.
+func GenerateKernel(rb *kschema.Runbook, format Format) (string, error) {
+	if rb == nil {
+		return "", fmt.Errorf("nil runbook")
+	}
+	return Generate(fromKernel(rb), format)
+}
+
+// fromKernel maps a kernel runbook onto the tree the renderers draw:
+// branch and parallel arms become branches, repeat blocks become iterate
+// blocks and end steps carry their outcome.
+func fromKernel(rb *kschema.Runbook) *schema.Runbook {
+	return &schema.Runbook{
+		Meta: schema.Meta{Name: rb.Meta.Name, Description: rb.Meta.Description},
+		Tree: kernelNodes(rb.Steps),
+	}
+}
+
+func kernelNodes(steps []kschema.Step) []schema.TreeNode {
+	var nodes []schema.TreeNode
+	for i, s := range steps {
+		id := s.ID
+		if id == "" {
+			id = fmt.Sprintf("%s_%d", s.Type, i+1)
+		}
+		node := schema.TreeNode{Step: schema.Step{
+			ID:    id,
+			Title: kernelTitle(s, id),
+			Type:  string(s.Type),
+		}}
+
+		for _, br := range s.Branches {
+			cond := br.Condition
+			if s.Type == kschema.StepParallel && cond == "" {
+				cond = "parallel"
+			}
+			node.Branches = append(node.Branches, schema.Branch{
+				Condition: cond,
+				Label:     br.Label,
+				Steps:     kernelNodes(br.Steps),
+			})
+		}
+
+		if s.Outcome != nil {
+			node.Step.Outcomes = []schema.Outcome{{
+				State:          string(s.Outcome.Category),
+				Recommendation: s.Outcome.Code,
+			}}
+		}
+
+		nodes = append(nodes, node)
+
+		if s.Repeat != nil {
+			nodes = append(nodes, schema.TreeNode{Iterate: &schema.IterateBlock{
+				Max:   s.Repeat.Max,
+				Until: s.Repeat.Until,
+				Steps: kernelNodes(s.Repeat.Steps),
+			}})
+		}
+	}
+	return nodes
+}
+
+// kernelTitle labels a node with the step ID and, for tool and extension
+// steps, what they call, or for end steps the outcome they reach.
+func kernelTitle(s kschema.Step, id string) string {
+	title := id
+	switch s.Type {
+	case kschema.StepTool:
+		if s.Action != "" {
+			title += " (" + s.Tool + "." + s.Action + ")"
+		} else if s.Tool != "" {
+			title += " (" + s.Tool + ")"
+		}
+	case kschema.StepExtension:
+		if s.Extension != "" {
+			title += " (" + s.Extension + ")"
+		}
+	case kschema.StepEnd:
+		if s.Outcome != nil {
+			title += ": " + outcomeLabel(string(s.Outcome.Category))
+		}
+	}
+	if s.ForEach != nil {
+		title += fmt.Sprintf(" [for %s in %s]", s.ForEach.As, s.ForEach.Over)
+	}
+	return title
+}
@@ -0,0 +1,137 @@
+package diagram
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// --- PlantUML sequence ---
+
+// Sequence diagram participants. The engine drives every step; each step
+// type talks to the participant that actually does the work.
+const (
+	pumlOperator = "Operator"
+	pumlEngine   = "Engine"
+	pumlShell    = "Shell"
+	pumlTool     = "Tool"
+	pumlRunbook  = "Runbook"
+)
+
+func generatePlantUML(rb *schema.Runbook) string {
+	var b strings.Builder
+	b.WriteString("@startuml\n")
+	if rb.Meta.Name != "" {
+		b.WriteString("title " + rb.Meta.Name + "\n")
+	}
+	b.WriteString("actor " + pumlOperator + "\n")
+	b.WriteString("participant " + pumlEngine + "\n")
+	b.WriteString("participant " + pumlShell + "\n")
+	b.WriteString("participant " + pumlTool + "\n")
+	b.WriteString("participant \"Child Runbook\" as " + pumlRunbook + "\n")
+	b.WriteString("\n")
+
+	writePlantUMLNodes(&b, rootNodes(rb), 0)
+
+	b.WriteString("@enduml\n")
+	return b.String()
+}
+
+func writePlantUMLNodes(b *strings.Builder, nodes []schema.TreeNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	for _, n := range nodes {
+		if n.Step.ID == "" {
+			if n.Iterate != nil {
+				b.WriteString(indent + "loop " + iterateLabel(n.Iterate) + "\n")
+				writePlantUMLNodes(b, n.Iterate.Steps, depth+1)
+				b.WriteString(indent + "end\n")
+			}
+			continue
+		}
+
+		writePlantUMLStep(b, n.Step, indent)
+
+		// Only the first matching branch runs, so branches render as alt/else.
+		first := true
+		for _, br := range n.Branches {
+			if len(br.Steps) == 0 {
+				continue
+			}
+			label := br.Label
+			if label == "" {
+				label = truncate(br.Condition, 30)
+			}
+			if first {
+				b.WriteString(indent + "alt " + label + "\n")
+				first = false
+			} else {
+				b.WriteString(indent + "else " + label + "\n")
+			}
+			writePlantUMLNodes(b, br.Steps, depth+1)
+		}
+		if !first {
+			b.WriteString(indent + "end\n")
+		}
+
+		for _, o := range n.Step.Outcomes {
+			note := outcomeLabel(o.State)
+			if o.When != "" {
+				note += "\\nwhen: " + truncate(o.When, 30)
+			}
+			if o.Recommendation != "" {
+				note += "\\n" + truncate(o.Recommendation, 40)
+			}
+			color := ""
+			if fill := outcomeFill(o.State); fill != "" {
+				color = " " + fill
+			}
+			b.WriteString(fmt.Sprintf("%snote over %s%s: %s\n", indent, pumlEngine, color, note))
+		}
+	}
+}
+
+func writePlantUMLStep(b *strings.Builder, s schema.Step, indent string) {
+	title := s.Title
+	if title == "" {
+		title = s.ID
+	}
+	target := plantUMLParticipant(s.Type)
+	label := stepIcon(s.Type) + " " + title
+	if s.Type == "tool" && s.Tool != nil && s.Tool.Name != "" {
+		label += " (" + s.Tool.Name + ")"
+	}
+
+	if target == pumlOperator {
+		// Manual steps hand control to the operator and wait for a reply.
+		b.WriteString(fmt.Sprintf("%s%s -> %s: %s\n", indent, pumlEngine, target, label))
+		reply := "done"
+		if caps := captureNames(s); caps != "" {
+			reply = caps
+		}
+		b.WriteString(fmt.Sprintf("%s%s --> %s: %s\n", indent, target, pumlEngine, reply))
+		return
+	}
+
+	b.WriteString(fmt.Sprintf("%s%s -> %s: %s\n", indent, pumlEngine, target, label))
+	if target != pumlEngine {
+		if caps := captureNames(s); caps != "" {
+			b.WriteString(fmt.Sprintf("%s%s --> %s: %s\n", indent, target, pumlEngine, caps))
+		}
+	}
+}
+
+func plantUMLParticipant(stepType string) string {
+	switch stepType {
+	case "manual":
+		return pumlOperator
+	case "cli":
+		return pumlShell
+	case "tool":
+		return pumlTool
+	case "invoke":
+		return pumlRunbook
+	default:
+		return pumlEngine
+	}
+}
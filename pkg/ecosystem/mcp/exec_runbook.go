@@ -0,0 +1,191 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/ormasoftchile/gert/pkg/kernel/engine"
+	"github.com/ormasoftchile/gert/pkg/kernel/trace"
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+)
+
+// StepSummary is one executed step in a gert/exec_runbook result.
+type StepSummary struct {
+	ID       string `json:"id"`
+	Type     string `json:"type,omitempty"`
+	Status   string `json:"status"`
+	Duration string `json:"duration,omitempty"`
+	Failure  string `json:"failure,omitempty"`
+}
+
+// AssertionFailure is a failed assert step in a gert/exec_runbook result.
+type AssertionFailure struct {
+	StepID  string `json:"step_id"`
+	Message string `json:"message"`
+}
+
+// HandleExecRunbook implements the gert/exec_runbook MCP tool. It runs in
+// dry-run mode unless the caller asks for mode "real" and also passes
+// confirm: true. Step events are sent as progress notifications when the
+// request carries a progress token.
+func HandleExecRunbook(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := req.GetArguments()
+	path, _ := args["runbook_path"].(string)
+	if path == "" {
+		return errorResult("runbook_path argument is required"), nil
+	}
+	mode, _ := args["mode"].(string)
+	if mode == "" {
+		mode = "dry-run"
+	}
+	switch mode {
+	case "dry-run":
+	case "real":
+		if confirm, _ := args["confirm"].(bool); !confirm {
+			return errorResult("mode real executes commands for real — pass confirm: true to proceed"), nil
+		}
+	default:
+		return errorResult(fmt.Sprintf("unknown mode %q — use 'dry-run' or 'real'", mode)), nil
+	}
+
+	rb, errs := kvalidate.ValidateFile(path)
+	if hasErrors(errs) {
+		return errorResult(formatErrors(errs)), nil
+	}
+
+	vars := make(map[string]string)
+	if rawVars, ok := args["vars"].(map[string]any); ok {
+		for k, v := range rawVars {
+			vars[k] = fmt.Sprint(v)
+		}
+	}
+	resolved, err := engine.ResolveInputs(ctx, rb, vars, nil)
+	if err != nil {
+		return errorResult(fmt.Sprintf("input resolution: %s", err)), nil
+	}
+
+	runID := "mcp-run-1"
+	collector := newStepCollector(ctx, req)
+	tw := trace.NewWriter(io.Discard, runID)
+	tw.AddSink(collector)
+
+	var out bytes.Buffer
+	eng := engine.New(rb, engine.RunConfig{
+		RunID:   runID,
+		Mode:    mode,
+		Vars:    resolved.Vars,
+		BaseDir: filepath.Dir(path),
+		Trace:   tw,
+		Stdout:  &out,
+	})
+	result := eng.Run(ctx)
+
+	response := map[string]any{
+		"status":   result.Status,
+		"duration": result.Duration.String(),
+		"mode":     mode,
+		"steps":    collector.steps,
+	}
+	if result.Outcome != nil {
+		response["outcome"] = map[string]any{
+			"category": string(result.Outcome.Category),
+			"code":     result.Outcome.Code,
+		}
+	}
+	if len(collector.assertions) > 0 {
+		response["assertion_failures"] = collector.assertions
+	}
+	if w := eng.Warnings(); len(w) > 0 {
+		response["warnings"] = w
+	}
+	if result.Error != nil {
+		response["error"] = result.Error.Error()
+	}
+	if out.Len() > 0 {
+		response["output"] = out.String()
+	}
+
+	data, _ := json.MarshalIndent(response, "", "  ")
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.NewTextContent(string(data))},
+		IsError: result.Status == "failed" || result.Status == "error",
+	}, nil
+}
+
+// stepCollector is a trace sink that builds the step summary and, when the
+// client asked for progress, forwards each step event as a notification.
+type stepCollector struct {
+	mu         sync.Mutex
+	ctx        context.Context
+	srv        *server.MCPServer
+	token      mcp.ProgressToken
+	progress   float64
+	steps      []StepSummary
+	assertions []AssertionFailure
+	types      map[string]string // step ID → type, from step_start
+}
+
+func newStepCollector(ctx context.Context, req mcp.CallToolRequest) *stepCollector {
+	c := &stepCollector{
+		ctx:   ctx,
+		steps: []StepSummary{},
+		types: make(map[string]string),
+	}
+	if req.Params.Meta != nil && req.Params.Meta.ProgressToken != nil {
+		c.srv = server.ServerFromContext(ctx)
+		c.token = req.Params.Meta.ProgressToken
+	}
+	return c
+}
+
+// Export implements trace.Sink.
+func (c *stepCollector) Export(evt trace.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stepID, _ := evt.Data["step_id"].(string)
+	var message string
+	switch evt.Type {
+	case trace.EventStepStart:
+		stepType, _ := evt.Data["type"].(string)
+		c.types[stepID] = stepType
+		message = fmt.Sprintf("step %s started", stepID)
+	case trace.EventStepComplete:
+		step := StepSummary{ID: stepID, Type: c.types[stepID]}
+		step.Status, _ = evt.Data["status"].(string)
+		step.Duration, _ = evt.Data["duration"].(string)
+		if failure, ok := evt.Data["failure"].(map[string]any); ok {
+			kind, _ := failure["kind"].(string)
+			step.Failure, _ = failure["message"].(string)
+			if kind == "assertion" {
+				c.assertions = append(c.assertions, AssertionFailure{StepID: stepID, Message: step.Failure})
+			}
+		}
+		c.steps = append(c.steps, step)
+		message = fmt.Sprintf("step %s %s", stepID, step.Status)
+	default:
+		return
+	}
+	c.notify(message)
+}
+
+// notify sends a progress notification. Failures are ignored: progress is
+// best effort and must never fail the run.
+func (c *stepCollector) notify(message string) {
+	if c.srv == nil {
+		return
+	}
+	c.progress++
+	_ = c.srv.SendNotificationToClient(c.ctx, "notifications/progress", map[string]any{
+		"progressToken": c.token,
+		"progress":      c.progress,
+		"message":       message,
+	})
+}
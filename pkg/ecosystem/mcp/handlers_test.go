@@ -2,6 +2,10 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -50,3 +54,126 @@ func TestHandleSchema_UnknownType(t *testing.T) {
 		t.Error("expected error for unknown schema type")
 	}
 }
+
+const execRunbookYAML = `apiVersion: kernel/v0
+meta:
+  name: exec-runbook-test
+  inputs:
+    status:
+      type: string
+      default: "200"
+steps:
+  - id: check
+    type: assert
+    assert:
+      - type: equals
+        value: "{{ .status }}"
+        expected: "200"
+  - id: done
+    type: end
+    outcome:
+      category: resolved
+      code: healthy
+`
+
+func writeRunbook(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "runbook.yaml")
+	if err := os.WriteFile(path, []byte(execRunbookYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func callExecRunbook(t *testing.T, args map[string]any) (*mcp.CallToolResult, map[string]any) {
+	t.Helper()
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = args
+	result, err := HandleExecRunbook(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := result.Content[0].(mcp.TextContent).Text
+	var response map[string]any
+	if err := json.Unmarshal([]byte(text), &response); err != nil {
+		return result, nil
+	}
+	return result, response
+}
+
+// T112: exec_runbook refuses real mode without confirm
+func TestHandleExecRunbook_RealRequiresConfirm(t *testing.T) {
+	path := writeRunbook(t)
+
+	result, _ := callExecRunbook(t, map[string]any{"runbook_path": path, "mode": "real"})
+	if !result.IsError {
+		t.Fatal("expected error for real mode without confirm")
+	}
+	if text := result.Content[0].(mcp.TextContent).Text; !strings.Contains(text, "confirm: true") {
+		t.Errorf("error = %q, want a hint about confirm", text)
+	}
+
+	result, response := callExecRunbook(t, map[string]any{"runbook_path": path, "mode": "real", "confirm": true})
+	if result.IsError || response["mode"] != "real" {
+		t.Errorf("confirmed real run: isError=%v response=%v", result.IsError, response)
+	}
+}
+
+// T113: exec_runbook defaults to dry-run and summarizes steps
+func TestHandleExecRunbook_DryRunSummary(t *testing.T) {
+	path := writeRunbook(t)
+
+	result, response := callExecRunbook(t, map[string]any{"runbook_path": path})
+	if result.IsError {
+		t.Fatalf("unexpected error: %v", response)
+	}
+	if response["mode"] != "dry-run" || response["status"] != "completed" {
+		t.Errorf("mode/status = %v/%v, want dry-run/completed", response["mode"], response["status"])
+	}
+	outcome, _ := response["outcome"].(map[string]any)
+	if outcome["code"] != "healthy" {
+		t.Errorf("outcome = %v, want healthy", outcome)
+	}
+	steps, _ := response["steps"].([]any)
+	if len(steps) == 0 {
+		t.Fatal("expected a step summary")
+	}
+	first, _ := steps[0].(map[string]any)
+	if first["id"] != "check" || first["type"] != "assert" || first["status"] != "success" {
+		t.Errorf("first step = %v", first)
+	}
+}
+
+// T114: exec_runbook reports assertion failures
+func TestHandleExecRunbook_AssertionFailure(t *testing.T) {
+	path := writeRunbook(t)
+
+	result, response := callExecRunbook(t, map[string]any{
+		"runbook_path": path,
+		"vars":         map[string]any{"status": 503},
+	})
+	if !result.IsError {
+		t.Error("expected error result for a failed assertion")
+	}
+	failures, _ := response["assertion_failures"].([]any)
+	if len(failures) != 1 {
+		t.Fatalf("assertion_failures = %v, want 1", response["assertion_failures"])
+	}
+	if f := failures[0].(map[string]any); f["step_id"] != "check" {
+		t.Errorf("failure = %v, want step check", f)
+	}
+}
+
+func TestHandleExecRunbook_Errors(t *testing.T) {
+	for name, args := range map[string]map[string]any{
+		"missing path": {},
+		"unknown mode": {"runbook_path": "x.yaml", "mode": "probe"},
+		"missing file": {"runbook_path": filepath.Join(t.TempDir(), "nope.yaml")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if result, _ := callExecRunbook(t, args); !result.IsError {
+				t.Error("expected error result")
+			}
+		})
+	}
+}
@@ -31,6 +31,17 @@ func NewServer(version string) *server.MCPServer {
 		HandleExec,
 	)
 
+	s.AddTool(
+		mcp.NewTool("gert/exec_runbook",
+			mcp.WithDescription("Run a gert runbook and return its outcome, step summary, and assertion failures. Runs in dry-run mode unless mode is real and confirm is true; step events are sent as progress notifications"),
+			mcp.WithString("runbook_path", mcp.Required(), mcp.Description("Path to the runbook YAML file")),
+			mcp.WithString("mode", mcp.Description("Execution mode: dry-run (default) or real")),
+			mcp.WithObject("vars", mcp.Description("Input values, keyed by input name")),
+			mcp.WithBoolean("confirm", mcp.Description("Must be true to run in real mode")),
+		),
+		HandleExecRunbook,
+	)
+
 	s.AddTool(
 		mcp.NewTool("gert/test",
 			mcp.WithDescription("Run scenario replay tests for a gert runbook"),
@@ -0,0 +1,157 @@
+package tui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// VarInjectionModel is an inline key=value input used to inject variables
+// into a paused run. Each submitted pair is passed to SetVar; the input stays
+// open so several variables can be set before the operator proceeds.
+type VarInjectionModel struct {
+	input     textinput.Model
+	active    bool
+	known     []string                      // existing variable names for Tab-completion
+	setVar    func(key, value string) error // applies the injected variable
+	confirmed []string                      // confirmation lines for injected variables
+	err       string
+}
+
+// NewVarInjectionModel creates an injection input. known lists the variable
+// names offered for Tab-completion; setVar is called on every submitted pair.
+func NewVarInjectionModel(known []string, setVar func(key, value string) error) VarInjectionModel {
+	ti := textinput.New()
+	ti.Placeholder = "key=value"
+	ti.CharLimit = 512
+	ti.Width = 40
+	ti.Prompt = "set "
+	ti.PromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("51")).Bold(true)
+
+	names := append([]string(nil), known...)
+	sort.Strings(names)
+	return VarInjectionModel{input: ti, known: names, setVar: setVar}
+}
+
+// Open activates the input and focuses it.
+func (v *VarInjectionModel) Open() {
+	v.active = true
+	v.err = ""
+	v.input.Reset()
+	v.input.Focus()
+}
+
+// Close deactivates the input, discarding any uncommitted text.
+func (v *VarInjectionModel) Close() {
+	v.active = false
+	v.input.Reset()
+	v.input.Blur()
+}
+
+// IsActive returns whether the input is accepting keys.
+func (v VarInjectionModel) IsActive() bool {
+	return v.active
+}
+
+// Confirmed returns the confirmation lines for variables injected so far.
+func (v VarInjectionModel) Confirmed() []string {
+	return v.confirmed
+}
+
+// Update handles key events while the input is active.
+// Enter submits key=value, Tab completes a variable name, Esc closes.
+func (v VarInjectionModel) Update(msg tea.Msg) (VarInjectionModel, tea.Cmd) {
+	if !v.active {
+		return v, nil
+	}
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	switch key.String() {
+	case "esc":
+		v.Close()
+		return v, nil
+	case "enter":
+		v.submit()
+		return v, nil
+	case "tab":
+		v.complete()
+		return v, nil
+	}
+
+	var cmd tea.Cmd
+	v.input, cmd = v.input.Update(msg)
+	return v, cmd
+}
+
+// submit parses the current input and applies it through setVar.
+func (v *VarInjectionModel) submit() {
+	name, value, ok := strings.Cut(v.input.Value(), "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		v.err = "expected key=value"
+		return
+	}
+	value = strings.TrimSpace(value)
+	if v.setVar != nil {
+		if err := v.setVar(name, value); err != nil {
+			v.err = err.Error()
+			return
+		}
+	}
+	v.err = ""
+	v.confirmed = append(v.confirmed, fmt.Sprintf("✓ Set %s = %s", name, value))
+	if !v.isKnown(name) {
+		v.known = append(v.known, name)
+		sort.Strings(v.known)
+	}
+	v.input.Reset()
+}
+
+// complete replaces a partial variable name with the first known match.
+func (v *VarInjectionModel) complete() {
+	text := v.input.Value()
+	if text == "" || strings.Contains(text, "=") {
+		return
+	}
+	for _, name := range v.known {
+		if strings.HasPrefix(name, text) {
+			v.input.SetValue(name + "=")
+			v.input.CursorEnd()
+			return
+		}
+	}
+}
+
+func (v VarInjectionModel) isKnown(name string) bool {
+	for _, n := range v.known {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// View renders confirmations followed by the input line.
+func (v VarInjectionModel) View() string {
+	var b strings.Builder
+	okStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("40"))
+	for _, line := range v.confirmed {
+		b.WriteString("  " + okStyle.Render(line) + "\n")
+	}
+	if !v.active {
+		return b.String()
+	}
+	b.WriteString("  " + v.input.View())
+	if v.err != "" {
+		errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+		b.WriteString("  " + errStyle.Render(v.err))
+	}
+	return b.String()
+}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func typeText(m VarInjectionModel, text string) VarInjectionModel {
+	for _, r := range text {
+		m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	return m
+}
+
+// T102: Enter submits key=value through SetVar
+func TestVarInjection_EnterSetsVar(t *testing.T) {
+	got := map[string]string{}
+	m := NewVarInjectionModel(nil, func(key, value string) error {
+		got[key] = value
+		return nil
+	})
+	m.Open()
+
+	m = typeText(m, "cluster=prod")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	if got["cluster"] != "prod" {
+		t.Fatalf("SetVar calls = %v, want cluster=prod", got)
+	}
+	if !m.IsActive() {
+		t.Error("input should stay open for further injections")
+	}
+	if c := m.Confirmed(); len(c) != 1 || c[0] != "✓ Set cluster = prod" {
+		t.Errorf("confirmed = %v", c)
+	}
+}
+
+// T103: Escape closes without calling SetVar
+func TestVarInjection_EscapeDiscards(t *testing.T) {
+	called := false
+	m := NewVarInjectionModel(nil, func(key, value string) error {
+		called = true
+		return nil
+	})
+	m.Open()
+
+	m = typeText(m, "cluster=prod")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	if called {
+		t.Error("SetVar should not be called on escape")
+	}
+	if m.IsActive() {
+		t.Error("input should be closed after escape")
+	}
+}
+
+// T104: Tab completes a known variable name
+func TestVarInjection_TabCompletes(t *testing.T) {
+	m := NewVarInjectionModel([]string{"environment", "cluster"}, nil)
+	m.Open()
+
+	m = typeText(m, "clu")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+
+	if v := m.input.Value(); v != "cluster=" {
+		t.Errorf("value after tab = %q, want %q", v, "cluster=")
+	}
+}
@@ -35,7 +35,7 @@ type Model struct {
 	traceEvents []trace.Event
 	outcome     string
 	outcomeCode string
-	status      string // "idle", "running", "completed", "failed"
+	status      string // "idle", "running", "awaiting_user", "completed", "failed"
 	width       int
 	height      int
 	err         error
@@ -43,6 +43,8 @@ type Model struct {
 	cancel      context.CancelFunc
 	eventCh     chan tea.Msg // channel for streaming events from engine goroutine
 	runCfg      *RunConfig   // set before Run() to auto-start engine
+	eng         *engine.Engine
+	inject      VarInjectionModel
 }
 
 // NewModel creates a TUI model from a runbook.
@@ -72,6 +74,11 @@ type traceEventMsg struct {
 	Event trace.Event
 }
 
+// engineReadyMsg hands the running engine to the model for variable injection.
+type engineReadyMsg struct {
+	Engine *engine.Engine
+}
+
 // runCompleteMsg signals run completion.
 type runCompleteMsg struct {
 	Outcome     string
@@ -107,10 +114,24 @@ func waitForEvent(ch chan tea.Msg) tea.Cmd {
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.inject.IsActive() {
+			var cmd tea.Cmd
+			m.inject, cmd = m.inject.Update(msg)
+			return m, cmd
+		}
 		switch msg.String() {
 		case "q", "ctrl+c":
 			m.cancel()
 			return m, tea.Quit
+		case "v":
+			if m.status == "awaiting_user" && m.eng != nil {
+				eng := m.eng
+				m.inject = NewVarInjectionModel(m.varNames(), func(key, value string) error {
+					eng.SetVar(key, value)
+					return nil
+				})
+				m.inject.Open()
+			}
 		case "up", "k":
 			if m.selected > 0 {
 				m.selected--
@@ -125,10 +146,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.width = msg.Width
 		m.height = msg.Height
 
+	case engineReadyMsg:
+		m.eng = msg.Engine
+		return m, waitForEvent(m.eventCh)
+
 	case traceEventMsg:
 		m.traceEvents = append(m.traceEvents, msg.Event)
-		m.applyTraceEvent(msg.Event)
 		m.status = "running"
+		m.applyTraceEvent(msg.Event)
 		// Keep listening for more events
 		return m, waitForEvent(m.eventCh)
 
@@ -160,6 +185,9 @@ func (m *Model) applyTraceEvent(evt trace.Event) {
 		case trace.EventStepStart:
 			m.steps[i].Status = "running"
 			m.status = "running"
+			if t, _ := evt.Data["type"].(string); t == string(schema.StepManual) {
+				m.status = "awaiting_user"
+			}
 		case trace.EventStepComplete:
 			status, _ := evt.Data["status"].(string)
 			switch status {
@@ -219,6 +247,8 @@ func (m Model) View() string {
 		b.WriteString(statusStyle.Render("  Ready"))
 	case "running":
 		b.WriteString(statusStyle.Render("  Running..."))
+	case "awaiting_user":
+		b.WriteString(statusStyle.Render("  Awaiting input..."))
 	case "completed":
 		outcomeStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("40"))
 		b.WriteString(outcomeStyle.Render(fmt.Sprintf("  ✓ %s (%s)", m.outcome, m.outcomeCode)))
@@ -241,12 +271,46 @@ func (m Model) View() string {
 		}
 	}
 
+	// Variable injection
+	if v := m.inject.View(); v != "" {
+		b.WriteString("\n\n")
+		b.WriteString(v)
+	}
+
 	b.WriteString("\n\n")
-	b.WriteString(statusStyle.Render("  q: quit  ↑/↓: navigate"))
+	help := "  q: quit  ↑/↓: navigate"
+	if m.status == "awaiting_user" {
+		help += "  v: set variable"
+	}
+	b.WriteString(statusStyle.Render(help))
 
 	return b.String()
 }
 
+// varNames lists variable names offered for Tab-completion during injection.
+func (m Model) varNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range m.runbook.Meta.Inputs {
+		add(name)
+	}
+	for name := range m.runbook.Meta.Constants {
+		add(name)
+	}
+	if m.runCfg != nil {
+		for name := range m.runCfg.Vars {
+			add(name)
+		}
+	}
+	return names
+}
+
 func stepIcon(status string) string {
 	switch status {
 	case "pending":
@@ -273,6 +337,7 @@ type RunConfig struct {
 	RunbookPath string
 	Scenario    string              // for replay mode
 	ToolExec    engine.ToolExecutor // optional (replay executor)
+	Stdin       io.Reader           // manual step input (default: os.Stdin)
 }
 
 // SetRunConfig configures the engine to start on Init().
@@ -298,6 +363,7 @@ func (m Model) runEngine(cfg RunConfig) {
 		Trace:       tw,
 		Stdout:      &stdout,
 		RunbookPath: cfg.RunbookPath,
+		Stdin:       cfg.Stdin,
 	}
 	if cfg.ToolExec != nil {
 		eCfg.ToolExec = cfg.ToolExec
@@ -319,6 +385,7 @@ func (m Model) runEngine(cfg RunConfig) {
 
 	// Run engine synchronously
 	eng := engine.New(m.runbook, eCfg)
+	m.eventCh <- engineReadyMsg{Engine: eng}
 	result := eng.Run(m.ctx)
 
 	// Close pipe writer so reader finishes
@@ -1,8 +1,11 @@
 package tui
 
 import (
+	"io"
 	"testing"
+	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/ormasoftchile/gert/pkg/kernel/schema"
 	"github.com/ormasoftchile/gert/pkg/kernel/trace"
 )
@@ -63,3 +66,66 @@ func TestModel_TracksStepStatus(t *testing.T) {
 		t.Errorf("duration = %v, want 100ms", m.steps[0].Duration)
 	}
 }
+
+// T105: a variable injected with 'v' while a manual step waits reaches the
+// engine without racing the run goroutine (run with -race)
+func TestModel_InjectVarDuringManualStep(t *testing.T) {
+	rb := &schema.Runbook{
+		Meta: schema.Meta{Name: "inject-rb"},
+		Steps: []schema.Step{
+			{ID: "confirm", Type: schema.StepManual, Instructions: "Set the cluster"},
+			{ID: "check", Type: schema.StepAssert, Assert: []schema.Assertion{
+				{Type: "equals", Value: "{{ .cluster }}", Expected: "prod"},
+			}},
+			{ID: "done", Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "ok"}},
+		},
+	}
+
+	stdin, operator := io.Pipe()
+	defer operator.Close()
+
+	m := NewModel(rb)
+	m.SetRunConfig(RunConfig{Mode: "real", Stdin: stdin})
+	m.Init()
+
+	next := func() tea.Msg {
+		t.Helper()
+		select {
+		case msg := <-m.eventCh:
+			return msg
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for engine event")
+			return nil
+		}
+	}
+	update := func(msg tea.Msg) {
+		updated, _ := m.Update(msg)
+		m = updated.(Model)
+	}
+
+	for m.status != "awaiting_user" {
+		update(next())
+	}
+
+	update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'v'}})
+	for _, r := range "cluster=prod" {
+		update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	update(tea.KeyMsg{Type: tea.KeyEnter})
+	update(tea.KeyMsg{Type: tea.KeyEsc})
+
+	if _, err := io.WriteString(operator, "\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	for {
+		msg := next()
+		if done, ok := msg.(runCompleteMsg); ok {
+			if done.Err != nil || done.Outcome != "resolved" {
+				t.Fatalf("run = %+v, want resolved", done)
+			}
+			return
+		}
+		update(msg)
+	}
+}
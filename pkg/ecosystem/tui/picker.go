@@ -0,0 +1,173 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// RunbookItem is a runbook offered by the picker.
+type RunbookItem struct {
+	Path string
+	Name string // meta.name, or the file name when unset
+}
+
+// Title implements list.DefaultItem.
+func (i RunbookItem) Title() string { return i.Name }
+
+// Description implements list.DefaultItem.
+func (i RunbookItem) Description() string { return i.Path }
+
+// FilterValue implements list.Item; "/" filters by runbook name.
+func (i RunbookItem) FilterValue() string { return i.Name }
+
+// FindRunbooks recursively scans dir for kernel runbooks, skipping .git and
+// .runbook directories, and returns them sorted by path. Like runbook/list,
+// a file is a runbook when its first non-comment line is
+// "apiVersion: kernel/...".
+func FindRunbooks(dir string) ([]RunbookItem, error) {
+	var items []RunbookItem
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil // unreadable entries are skipped
+		}
+		if d.IsDir() {
+			if path != dir && (d.Name() == ".git" || d.Name() == ".runbook") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".yaml") && !strings.HasSuffix(d.Name(), ".yml") {
+			return nil
+		}
+		if item, ok := readRunbookItem(path); ok {
+			items = append(items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+	return items, nil
+}
+
+// readRunbookItem reports whether path is a kernel runbook and reads its name.
+func readRunbookItem(path string) (RunbookItem, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RunbookItem{}, false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		version := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "apiVersion:")), `"'`)
+		if !strings.HasPrefix(line, "apiVersion:") || !strings.HasPrefix(version, "kernel/") {
+			return RunbookItem{}, false
+		}
+		break
+	}
+
+	// Decoded loosely so runbooks with validation errors are still listed
+	var header struct {
+		Meta struct {
+			Name string `yaml:"name"`
+		} `yaml:"meta"`
+	}
+	_ = yaml.Unmarshal(data, &header)
+	item := RunbookItem{Path: path, Name: header.Meta.Name}
+	if item.Name == "" {
+		item.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return item, true
+}
+
+// PickerModel lists runbooks to choose from when gert-tui starts without a
+// file. Enter opens the selected runbook and hands control to the model
+// open returns; the picker stays up with an error if the runbook can't run.
+type PickerModel struct {
+	list list.Model
+	open func(path string) (tea.Model, error)
+	size *tea.WindowSizeMsg
+	err  string
+}
+
+// NewPickerModel creates a picker over items. open loads the chosen
+// runbook and returns the model for the step execution view.
+func NewPickerModel(items []RunbookItem, open func(path string) (tea.Model, error)) PickerModel {
+	listItems := make([]list.Item, len(items))
+	for i, item := range items {
+		listItems[i] = item
+	}
+	l := list.New(listItems, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Select a runbook"
+	l.Styles.Title = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39"))
+	return PickerModel{list: l, open: open}
+}
+
+// Init implements tea.Model.
+func (p PickerModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model. Arrow keys navigate, "/" filters by name and
+// Enter selects.
+func (p PickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.size = &msg
+		p.list.SetSize(msg.Width, msg.Height-1)
+	case tea.KeyMsg:
+		if msg.String() == "enter" && p.list.FilterState() != list.Filtering {
+			return p.selectRunbook()
+		}
+	}
+	var cmd tea.Cmd
+	p.list, cmd = p.list.Update(msg)
+	return p, cmd
+}
+
+// selectRunbook opens the highlighted runbook and switches to its model,
+// replaying the last window size so it lays out immediately.
+func (p PickerModel) selectRunbook() (tea.Model, tea.Cmd) {
+	item, ok := p.list.SelectedItem().(RunbookItem)
+	if !ok {
+		return p, nil
+	}
+	next, err := p.open(item.Path)
+	if err != nil {
+		p.err = err.Error()
+		return p, nil
+	}
+	cmds := []tea.Cmd{next.Init()}
+	if p.size != nil {
+		size := *p.size
+		cmds = append(cmds, func() tea.Msg { return size })
+	}
+	return next, tea.Batch(cmds...)
+}
+
+// View implements tea.Model.
+func (p PickerModel) View() string {
+	view := p.list.View()
+	if p.err != "" {
+		errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+		view += "\n  " + errStyle.Render(p.err)
+	}
+	return view
+}
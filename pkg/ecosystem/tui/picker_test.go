@@ -0,0 +1,75 @@
+package tui
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
+)
+
+// T105: FindRunbooks recursively lists kernel runbooks only
+func TestFindRunbooks(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml":            "# header\napiVersion: kernel/v0\nmeta:\n  name: restart-pods\n",
+		"nested/b.yaml":     "apiVersion: kernel/v0\nmeta: {}\n",
+		"tools/curl.yaml":   "apiVersion: tool/v0\nmeta:\n  name: curl\n",
+		"notes.yaml":        "title: not a runbook\n",
+		".runbook/old.yaml": "apiVersion: kernel/v0\n",
+		"nested/readme.md":  "apiVersion: kernel/v0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, err := FindRunbooks(dir)
+	if err != nil {
+		t.Fatalf("FindRunbooks: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("found %d runbooks, want 2: %+v", len(items), items)
+	}
+	if items[0].Name != "restart-pods" || items[0].Path != filepath.Join(dir, "a.yaml") {
+		t.Errorf("items[0] = %+v", items[0])
+	}
+	if items[1].Name != "b" {
+		t.Errorf("items[1].Name = %q, want file name fallback b", items[1].Name)
+	}
+}
+
+// T106: Enter hands control to the selected runbook's model
+func TestPicker_EnterOpensRunbook(t *testing.T) {
+	items := []RunbookItem{{Path: "a.yaml", Name: "alpha"}, {Path: "b.yaml", Name: "beta"}}
+	var opened string
+	p := NewPickerModel(items, func(path string) (tea.Model, error) {
+		opened = path
+		if path == "a.yaml" {
+			return nil, errors.New("validation failed")
+		}
+		return NewModel(&schema.Runbook{Meta: schema.Meta{Name: "beta"}}), nil
+	})
+	var m tea.Model = p
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	// A runbook that fails to load keeps the picker up with the error
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if _, ok := m.(PickerModel); !ok || opened != "a.yaml" {
+		t.Fatalf("after failed open: model %T, opened %q", m, opened)
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if _, ok := m.(Model); !ok {
+		t.Fatalf("model = %T, want the step execution Model", m)
+	}
+	if opened != "b.yaml" || cmd == nil {
+		t.Errorf("opened %q, cmd %v", opened, cmd)
+	}
+}
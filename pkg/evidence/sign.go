@@ -0,0 +1,171 @@
+package evidence
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest signing files and environment variables.
+const (
+	ManifestFile    = "run.yaml"
+	SignatureFile   = ManifestFile + ".sig"
+	EnvSigningKey   = "GERT_SIGNING_KEY" // base64 Ed25519 private key (or seed), or a file containing it
+	EnvVerifyKey    = "GERT_VERIFY_KEY"  // base64 Ed25519 public key, or a file containing it
+	SignatureScheme = "ed25519"
+)
+
+// ErrManifestModified reports a run.yaml whose content no longer matches
+// the digest recorded when it was signed.
+var ErrManifestModified = errors.New("run.yaml was modified after signing")
+
+// ManifestSignature is the content of run.yaml.sig. SHA256 covers the
+// canonical manifest bytes; Signature is the Ed25519 signature of the same
+// bytes, base64-encoded.
+type ManifestSignature struct {
+	Algorithm string `yaml:"algorithm"`
+	SHA256    string `yaml:"sha256"`
+	Signature string `yaml:"signature"`
+}
+
+// HashBytes computes the SHA256 hash of data, hex-encoded like HashFile.
+func HashBytes(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+// CanonicalManifest re-encodes manifest YAML with sorted keys and fixed
+// indentation, so formatting-only edits do not change what is signed.
+func CanonicalManifest(data []byte) ([]byte, error) {
+	var v any
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("canonicalize manifest: %w", err)
+	}
+	return out, nil
+}
+
+// SignManifest signs <runDir>/run.yaml and writes <runDir>/run.yaml.sig.
+func SignManifest(runDir string, key ed25519.PrivateKey) (*ManifestSignature, error) {
+	canonical, err := readCanonicalManifest(runDir)
+	if err != nil {
+		return nil, err
+	}
+	sig := &ManifestSignature{
+		Algorithm: SignatureScheme,
+		SHA256:    HashBytes(canonical),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, canonical)),
+	}
+	data, err := yaml.Marshal(sig)
+	if err != nil {
+		return nil, fmt.Errorf("marshal signature: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(runDir, SignatureFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("write signature: %w", err)
+	}
+	return sig, nil
+}
+
+// VerifyManifest checks <runDir>/run.yaml against <runDir>/run.yaml.sig.
+// It returns ErrManifestModified when the manifest digest has changed, and
+// an error when the signature was not made by the key matching pub.
+func VerifyManifest(runDir string, pub ed25519.PublicKey) (*ManifestSignature, error) {
+	data, err := os.ReadFile(filepath.Join(runDir, SignatureFile))
+	if err != nil {
+		return nil, fmt.Errorf("read signature: %w", err)
+	}
+	var sig ManifestSignature
+	if err := yaml.Unmarshal(data, &sig); err != nil {
+		return nil, fmt.Errorf("parse signature: %w", err)
+	}
+	if sig.Algorithm != SignatureScheme {
+		return nil, fmt.Errorf("unsupported signature algorithm %q", sig.Algorithm)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("decode signature: %w", err)
+	}
+
+	canonical, err := readCanonicalManifest(runDir)
+	if err != nil {
+		return nil, err
+	}
+	if HashBytes(canonical) != sig.SHA256 {
+		return &sig, ErrManifestModified
+	}
+	if !ed25519.Verify(pub, canonical, raw) {
+		return &sig, errors.New("signature does not match the verification key")
+	}
+	return &sig, nil
+}
+
+func readCanonicalManifest(runDir string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(runDir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("read manifest: %w", err)
+	}
+	return CanonicalManifest(data)
+}
+
+// ParsePrivateKey decodes a base64 Ed25519 private key: the 32-byte seed
+// or the 64-byte expanded key. s may also be the path to a file holding it.
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	raw, err := decodeKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("signing key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("signing key: got %d bytes, want %d (seed) or %d", len(raw), ed25519.SeedSize, ed25519.PrivateKeySize)
+	}
+}
+
+// ParsePublicKey decodes a base64 Ed25519 public key, or reads it from the
+// file at path s.
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	raw, err := decodeKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("verify key: %w", err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("verify key: got %d bytes, want %d", len(raw), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// SigningKeyFromEnv returns the key in GERT_SIGNING_KEY, or nil when unset.
+func SigningKeyFromEnv() (ed25519.PrivateKey, error) {
+	v := os.Getenv(EnvSigningKey)
+	if v == "" {
+		return nil, nil
+	}
+	return ParsePrivateKey(v)
+}
+
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("no key given")
+	}
+	if data, err := os.ReadFile(s); err == nil {
+		s = strings.TrimSpace(string(data))
+	}
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("not a base64 key or readable key file: %w", err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,74 @@
+package evidence
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testManifest = "run_id: r1\nrunbook: rb.yaml\nmode: real\noutcome:\n  state: resolved\n"
+
+func TestSignAndVerifyManifest(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, ManifestFile)
+	if err := os.WriteFile(manifest, []byte(testManifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 1
+	key, err := ParsePrivateKey(base64.StdEncoding.EncodeToString(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := key.Public().(ed25519.PublicKey)
+
+	if _, err := SignManifest(dir, key); err != nil {
+		t.Fatalf("SignManifest: %v", err)
+	}
+	if _, err := VerifyManifest(dir, pub); err != nil {
+		t.Fatalf("VerifyManifest: %v", err)
+	}
+
+	// Reformatting does not change the canonical bytes
+	reformatted := "mode: real\nrunbook: rb.yaml\nrun_id: r1\noutcome: {state: resolved}\n"
+	os.WriteFile(manifest, []byte(reformatted), 0o644)
+	if _, err := VerifyManifest(dir, pub); err != nil {
+		t.Errorf("reformatted manifest: %v", err)
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(nil)
+	if _, err := VerifyManifest(dir, otherPub); err == nil || errors.Is(err, ErrManifestModified) {
+		t.Errorf("expected a signature mismatch for another key, got %v", err)
+	}
+
+	os.WriteFile(manifest, []byte("run_id: r1\nrunbook: rb.yaml\nmode: real\noutcome:\n  state: failed\n"), 0o644)
+	if _, err := VerifyManifest(dir, pub); !errors.Is(err, ErrManifestModified) {
+		t.Errorf("expected ErrManifestModified, got %v", err)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+
+	// The expanded key, read from a file
+	keyFile := filepath.Join(t.TempDir(), "signing.key")
+	os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0o600)
+	got, err := ParsePrivateKey(keyFile)
+	if err != nil || !got.Equal(priv) {
+		t.Errorf("ParsePrivateKey(file) = %v", err)
+	}
+
+	gotPub, err := ParsePublicKey(base64.StdEncoding.EncodeToString(pub))
+	if err != nil || !gotPub.Equal(pub) {
+		t.Errorf("ParsePublicKey = %v", err)
+	}
+	if _, err := ParsePublicKey(base64.StdEncoding.EncodeToString(priv)); err == nil {
+		t.Error("expected a size error for a private key passed as public key")
+	}
+	if _, err := ParsePrivateKey("not base64!"); err == nil {
+		t.Error("expected a decode error")
+	}
+}
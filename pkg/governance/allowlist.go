@@ -3,8 +3,10 @@
 package governance
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 
 	"github.com/ormasoftchile/gert/pkg/schema"
 )
@@ -14,6 +16,7 @@ type GovernanceEngine struct {
 	AllowedCommands []string
 	DeniedCommands  []string
 	DenyEnvVars     []string
+	Rules           []schema.CommandRule
 }
 
 // NewGovernanceEngine creates a GovernanceEngine from a GovernancePolicy.
@@ -26,30 +29,97 @@ func NewGovernanceEngine(policy *schema.GovernancePolicy) *GovernanceEngine {
 		AllowedCommands: policy.AllowedCommands,
 		DeniedCommands:  policy.DeniedCommands,
 		DenyEnvVars:     policy.DenyEnvVars,
+		Rules:           policy.Rules,
 	}
 }
 
-// CheckCommand validates argv[0] against the allowlist/denylist.
+// CommandDecision is the outcome of evaluating a command line.
+type CommandDecision struct {
+	Allowed bool
+	Rule    string // the entry that decided, e.g. `rules[0] "kubectl delete *"`; empty when no policy applies
+	Reason  string // why the command was denied
+}
+
+// CheckCommand validates a single command name against the allowlist/denylist.
 // Deny takes precedence over allow.
 func (g *GovernanceEngine) CheckCommand(command string) error {
-	// Check denylist first (deny takes precedence)
-	for _, denied := range g.DeniedCommands {
-		if command == denied {
-			return fmt.Errorf("command %q is denied by governance policy", command)
+	return g.CheckArgv([]string{command})
+}
+
+// CheckArgv validates a command line against the allowlist/denylist and
+// command rules. Deny takes precedence over allow.
+func (g *GovernanceEngine) CheckArgv(argv []string) error {
+	if d := g.EvaluateArgv(argv); !d.Allowed {
+		return errors.New(d.Reason)
+	}
+	return nil
+}
+
+// EvaluateArgv decides whether a command line may run. Entries in
+// denied_commands and allowed_commands are globs (see MatchCommand);
+// rules add exact argv[0] matches (command) and command line globs
+// (pattern). Any matching deny entry blocks the command. When allow
+// entries exist, the command must match one of them.
+func (g *GovernanceEngine) EvaluateArgv(argv []string) CommandDecision {
+	command := strings.Join(argv, " ")
+	subj := newSubject(argv)
+	deny := func(rule string) CommandDecision {
+		return CommandDecision{Rule: rule, Reason: fmt.Sprintf("command %q is denied by governance policy", command)}
+	}
+	invalid := func(rule, pattern string, err error) CommandDecision {
+		return CommandDecision{Rule: rule, Reason: fmt.Sprintf("invalid command deny pattern %q: %v", pattern, err)}
+	}
+
+	// Check denylist first (deny takes precedence). An invalid deny
+	// pattern blocks the command for safety.
+	for i, denied := range g.DeniedCommands {
+		matched, err := subj.match(denied)
+		if err != nil {
+			return invalid(listRule("denied_commands", i, denied), denied, err)
+		}
+		if matched {
+			return deny(listRule("denied_commands", i, denied))
+		}
+	}
+	for i, r := range g.Rules {
+		if r.Action != "deny" {
+			continue
+		}
+		matched, err := subj.matchRule(r)
+		if err != nil {
+			return invalid(listRule("rules", i, r.Pattern), r.Pattern, err)
+		}
+		if matched {
+			return deny(listRule("rules", i, r.Command+r.Pattern))
 		}
 	}
 
-	// If allowlist is set, command must be in it
-	if len(g.AllowedCommands) > 0 {
-		for _, allowed := range g.AllowedCommands {
-			if command == allowed {
-				return nil
-			}
+	// If allow entries are set, the command must match one of them
+	hasAllow := len(g.AllowedCommands) > 0
+	for i, allowed := range g.AllowedCommands {
+		if ok, _ := subj.match(allowed); ok {
+			return CommandDecision{Allowed: true, Rule: listRule("allowed_commands", i, allowed)}
+		}
+	}
+	for i, r := range g.Rules {
+		if r.Action != "allow" {
+			continue
 		}
-		return fmt.Errorf("command %q is not in the governance allowlist", command)
+		hasAllow = true
+		if ok, _ := subj.matchRule(r); ok {
+			return CommandDecision{Allowed: true, Rule: listRule("rules", i, r.Command+r.Pattern)}
+		}
+	}
+	if hasAllow {
+		return CommandDecision{Reason: fmt.Sprintf("command %q is not in the governance allowlist", command)}
 	}
 
-	return nil
+	return CommandDecision{Allowed: true}
+}
+
+// listRule names a policy entry for CommandDecision.Rule.
+func listRule(list string, i int, entry string) string {
+	return fmt.Sprintf("%s[%d] %q", list, i, entry)
 }
 
 // CheckEnvVar validates an environment variable name against deny_env_vars patterns.
@@ -66,3 +136,57 @@ func (g *GovernanceEngine) CheckEnvVar(name string) error {
 	}
 	return nil
 }
+
+// MatchCommand reports whether a governance glob matches a command line.
+// A pattern without spaces matches argv[0]; one with spaces matches the
+// arguments joined by single spaces, so "kubectl delete *" matches any
+// kubectl delete. Patterns use filepath.Match syntax, except that * and ?
+// also match '/' so path arguments cannot slip past a deny rule.
+func MatchCommand(pattern string, argv []string) (bool, error) {
+	return newSubject(argv).match(pattern)
+}
+
+// commandSubject holds a command line prepared once for matching against
+// every rule in a policy.
+type commandSubject struct {
+	command string // argv[0]
+	head    string // argv[0] with slashes hidden
+	line    string // the joined command line with slashes hidden
+}
+
+func newSubject(argv []string) commandSubject {
+	if len(argv) == 0 {
+		return commandSubject{}
+	}
+	return commandSubject{
+		command: argv[0],
+		head:    hideSlash(argv[0]),
+		line:    hideSlash(strings.Join(argv, " ")),
+	}
+}
+
+// match applies a MatchCommand glob.
+func (c commandSubject) match(pattern string) (bool, error) {
+	if c.command == "" {
+		return false, nil
+	}
+	subject := c.head
+	if strings.Contains(pattern, " ") {
+		subject = c.line
+	}
+	return filepath.Match(hideSlash(pattern), subject)
+}
+
+// matchRule applies a command rule: command matches argv[0] exactly,
+// pattern is a MatchCommand glob.
+func (c commandSubject) matchRule(r schema.CommandRule) (bool, error) {
+	if r.Command != "" {
+		return c.command != "" && c.command == r.Command, nil
+	}
+	return c.match(r.Pattern)
+}
+
+// hideSlash swaps '/' for a byte filepath.Match treats as ordinary.
+func hideSlash(s string) string {
+	return strings.ReplaceAll(s, "/", "\x00")
+}
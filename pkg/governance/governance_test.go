@@ -1,7 +1,10 @@
 package governance
 
 import (
+	"fmt"
 	"testing"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
 )
 
 // TestAllowlistAcceptsAllowedCommand verifies allowed commands pass.
@@ -99,3 +102,71 @@ func TestEnvVarPatternMatching(t *testing.T) {
 		}
 	}
 }
+
+// TestCommandGlobs verifies glob entries in the lists and in rules.
+func TestCommandGlobs(t *testing.T) {
+	g := &GovernanceEngine{
+		DeniedCommands: []string{"az vm delete *"},
+		Rules: []schema.CommandRule{
+			{Action: "deny", Pattern: "kubectl delete *"},
+			{Action: "allow", Command: "kubectl"},
+			{Action: "allow", Pattern: "az *"},
+		},
+	}
+	tests := []struct {
+		argv    []string
+		allowed bool
+	}{
+		{[]string{"kubectl", "get", "pods"}, true},
+		{[]string{"kubectl", "delete", "pod", "api-0"}, false},
+		{[]string{"kubectl", "delete", "pod/api-0"}, false}, // * crosses '/'
+		{[]string{"az", "vm", "list"}, true},
+		{[]string{"az", "vm", "delete", "--ids", "/subscriptions/x/vm/y"}, false},
+		{[]string{"rm", "-rf", "/"}, false}, // not allowed by any rule
+	}
+	for _, tt := range tests {
+		err := g.CheckArgv(tt.argv)
+		if (err == nil) != tt.allowed {
+			t.Errorf("CheckArgv(%q) = %v, want allowed=%v", tt.argv, err, tt.allowed)
+		}
+	}
+
+	// Single-word globs match argv[0]
+	g = &GovernanceEngine{AllowedCommands: []string{"kube*"}}
+	if err := g.CheckArgv([]string{"kubectl", "delete", "x"}); err != nil {
+		t.Errorf("kube* should allow kubectl: %v", err)
+	}
+
+	d := (&GovernanceEngine{Rules: []schema.CommandRule{{Action: "deny", Pattern: "kubectl delete *"}}}).
+		EvaluateArgv([]string{"kubectl", "delete", "ns", "prod"})
+	if d.Allowed || d.Rule != `rules[0] "kubectl delete *"` {
+		t.Errorf("decision = %+v", d)
+	}
+}
+
+// TestInvalidDenyPatternBlocks verifies a malformed deny glob fails closed.
+func TestInvalidDenyPatternBlocks(t *testing.T) {
+	g := &GovernanceEngine{DeniedCommands: []string{"kubectl [delete"}}
+	if err := g.CheckArgv([]string{"kubectl", "get"}); err == nil {
+		t.Error("expected invalid deny pattern to block")
+	}
+}
+
+// BenchmarkCheckArgv_ManyRules measures a full scan of 150 rules, the
+// worst case for a command that matches only the last allow rule.
+func BenchmarkCheckArgv_ManyRules(b *testing.B) {
+	g := &GovernanceEngine{}
+	for i := 0; i < 100; i++ {
+		g.Rules = append(g.Rules, schema.CommandRule{Action: "deny", Pattern: fmt.Sprintf("tool%d delete *", i)})
+	}
+	for i := 0; i < 50; i++ {
+		g.Rules = append(g.Rules, schema.CommandRule{Action: "allow", Pattern: fmt.Sprintf("tool%d get *", i)})
+	}
+	argv := []string{"tool49", "get", "pods", "--namespace", "prod/eu"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := g.CheckArgv(argv); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
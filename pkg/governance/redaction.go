@@ -36,3 +36,19 @@ func RedactOutput(output string, rules []*CompiledRedaction) string {
 	}
 	return result
 }
+
+// SecretRedactions builds rules that mask each literal value (e.g. secrets
+// resolved from a vault) as "***". Empty values are ignored.
+func SecretRedactions(values []string) []*CompiledRedaction {
+	var compiled []*CompiledRedaction
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		compiled = append(compiled, &CompiledRedaction{
+			Pattern: regexp.MustCompile(regexp.QuoteMeta(v)),
+			Replace: "***",
+		})
+	}
+	return compiled
+}
@@ -0,0 +1,252 @@
+// Package akv provides an input provider that resolves runbook inputs from
+// Azure Key Vault secrets: `from: akv.<vault>/<secret>`.
+package akv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/ormasoftchile/gert/pkg/governance"
+	"github.com/ormasoftchile/gert/pkg/inputs"
+)
+
+// Prefix is the from: prefix for Key Vault bindings: "akv.<vault>/<secret>".
+const Prefix = "akv."
+
+// EnvVaultURL enables the provider in hosts when set. Its host suffix also
+// selects the cloud, so bindings to vaults in sovereign clouds resolve to
+// the right FQDN.
+const EnvVaultURL = "AZURE_KEYVAULT_URL"
+
+// DefaultVaultSuffix is the DNS suffix of vaults in the public cloud.
+const DefaultVaultSuffix = "vault.azure.net"
+
+// SecretClient reads the current version of a secret from one vault.
+type SecretClient interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// ClientFactory creates a SecretClient for a vault URL.
+type ClientFactory func(vaultURL string) (SecretClient, error)
+
+// Provider resolves akv.* bindings. One client is created per vault and
+// reused across Resolve calls. Every value it resolves is recorded so hosts
+// can redact it from output (see Redactions).
+type Provider struct {
+	Suffix    string // vault DNS suffix, e.g. "vault.azure.net"
+	NewClient ClientFactory
+
+	mu      sync.Mutex
+	clients map[string]SecretClient
+	secrets map[string]bool
+}
+
+// NewProvider creates a provider that authenticates with
+// DefaultAzureCredential. The credential is created lazily, on the first
+// vault lookup.
+func NewProvider(suffix string) *Provider {
+	var (
+		once    sync.Once
+		cred    azcore.TokenCredential
+		credErr error
+	)
+	return NewProviderWithClients(suffix, func(vaultURL string) (SecretClient, error) {
+		once.Do(func() { cred, credErr = azidentity.NewDefaultAzureCredential(nil) })
+		if credErr != nil {
+			return nil, fmt.Errorf("azure credential: %w", credErr)
+		}
+		c, err := azsecrets.NewClient(vaultURL, cred, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &azureSecretClient{client: c}, nil
+	})
+}
+
+// NewProviderWithClients creates a provider using factory to reach vaults.
+func NewProviderWithClients(suffix string, factory ClientFactory) *Provider {
+	if suffix == "" {
+		suffix = DefaultVaultSuffix
+	}
+	return &Provider{
+		Suffix:    suffix,
+		NewClient: factory,
+		clients:   make(map[string]SecretClient),
+		secrets:   make(map[string]bool),
+	}
+}
+
+// NewProviderFromEnv returns a DefaultAzureCredential provider when
+// AZURE_KEYVAULT_URL is set, or nil when it is not.
+func NewProviderFromEnv() (*Provider, error) {
+	raw := os.Getenv(EnvVaultURL)
+	if raw == "" {
+		return nil, nil
+	}
+	suffix, err := VaultSuffix(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", EnvVaultURL, err)
+	}
+	return NewProvider(suffix), nil
+}
+
+// Prefixes returns the from: prefixes this provider handles.
+func (p *Provider) Prefixes() []string {
+	return []string{Prefix}
+}
+
+// Resolve fetches each referenced secret. Malformed bindings and failed
+// lookups produce warnings, not errors.
+func (p *Provider) Resolve(ctx context.Context, req *inputs.ResolveRequest) (*inputs.ResolveResult, error) {
+	result := &inputs.ResolveResult{Resolved: make(map[string]string)}
+
+	for name, binding := range req.Bindings {
+		vault, secret, err := ParseRef(binding.From)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: %v", name, err))
+			continue
+		}
+		client, err := p.client(vault)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: vault %q: %v", name, vault, err))
+			continue
+		}
+		val, err := client.GetSecret(ctx, secret)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: secret %q in vault %q: %v", name, secret, vault, err))
+			continue
+		}
+		p.remember(val)
+
+		if binding.Pattern != "" {
+			re, err := regexp.Compile(binding.Pattern)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: invalid pattern: %v", name, err))
+				continue
+			}
+			m := re.FindStringSubmatch(val)
+			switch {
+			case m == nil:
+				result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: pattern %q did not match", name, binding.Pattern))
+				continue
+			case len(m) > 1:
+				val = m[1]
+			default:
+				val = m[0]
+			}
+			p.remember(val)
+		}
+		result.Resolved[name] = val
+	}
+
+	return result, nil
+}
+
+// Shutdown drops cached clients; the SDK clients hold no open resources.
+func (p *Provider) Shutdown() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clients = make(map[string]SecretClient)
+	return nil
+}
+
+// Secrets returns every value resolved so far.
+func (p *Provider) Secrets() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	values := make([]string, 0, len(p.secrets))
+	for v := range p.secrets {
+		values = append(values, v)
+	}
+	return values
+}
+
+// Redactions returns rules masking every value resolved so far, for use
+// alongside the runbook's governance.redact rules.
+func (p *Provider) Redactions() []*governance.CompiledRedaction {
+	return governance.SecretRedactions(p.Secrets())
+}
+
+// VaultURL returns the URL of the named vault.
+func (p *Provider) VaultURL(vault string) string {
+	return "https://" + vault + "." + p.Suffix + "/"
+}
+
+func (p *Provider) client(vault string) (SecretClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[vault]; ok {
+		return c, nil
+	}
+	c, err := p.NewClient(p.VaultURL(vault))
+	if err != nil {
+		return nil, err
+	}
+	p.clients[vault] = c
+	return c, nil
+}
+
+func (p *Provider) remember(val string) {
+	if val == "" {
+		return
+	}
+	p.mu.Lock()
+	p.secrets[val] = true
+	p.mu.Unlock()
+}
+
+// vaultNameRe matches Key Vault naming rules: 3-24 alphanumerics and dashes,
+// starting with a letter.
+var vaultNameRe = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9-]{2,23}$`)
+
+// ParseRef splits "akv.<vault>/<secret>" into its vault and secret names.
+func ParseRef(from string) (vault, secret string, err error) {
+	rest, ok := strings.CutPrefix(from, Prefix)
+	if !ok {
+		return "", "", fmt.Errorf("binding %q does not start with %q", from, Prefix)
+	}
+	vault, secret, ok = strings.Cut(rest, "/")
+	if !ok || vault == "" || secret == "" {
+		return "", "", fmt.Errorf("binding %q must have the form akv.<vault>/<secret>", from)
+	}
+	if !vaultNameRe.MatchString(vault) {
+		return "", "", fmt.Errorf("binding %q: invalid vault name %q", from, vault)
+	}
+	return vault, secret, nil
+}
+
+// VaultSuffix extracts the DNS suffix from a vault URL such as
+// "https://my-vault.vault.azure.net/".
+func VaultSuffix(vaultURL string) (string, error) {
+	host := strings.TrimPrefix(strings.TrimPrefix(vaultURL, "https://"), "http://")
+	host, _, _ = strings.Cut(host, "/")
+	_, suffix, ok := strings.Cut(host, ".")
+	if !ok || suffix == "" {
+		return "", fmt.Errorf("%q is not a vault URL", vaultURL)
+	}
+	return suffix, nil
+}
+
+// azureSecretClient adapts azsecrets.Client to SecretClient.
+type azureSecretClient struct {
+	client *azsecrets.Client
+}
+
+func (c *azureSecretClient) GetSecret(ctx context.Context, name string) (string, error) {
+	// An empty version reads the latest
+	resp, err := c.client.GetSecret(ctx, name, "", nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.Value == nil {
+		return "", fmt.Errorf("secret %q has no value", name)
+	}
+	return *resp.Value, nil
+}
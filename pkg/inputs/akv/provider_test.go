@@ -0,0 +1,132 @@
+package akv
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ormasoftchile/gert/pkg/governance"
+	"github.com/ormasoftchile/gert/pkg/inputs"
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// mockVault serves secrets from a map and counts lookups.
+type mockVault struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (m *mockVault) GetSecret(ctx context.Context, name string) (string, error) {
+	m.calls++
+	v, ok := m.secrets[name]
+	if !ok {
+		return "", errors.New("SecretNotFound")
+	}
+	return v, nil
+}
+
+func newMockProvider(t *testing.T, vaults map[string]*mockVault) (*Provider, *[]string) {
+	t.Helper()
+	var urls []string
+	p := NewProviderWithClients("", func(vaultURL string) (SecretClient, error) {
+		urls = append(urls, vaultURL)
+		for name, v := range vaults {
+			if vaultURL == "https://"+name+".vault.azure.net/" {
+				return v, nil
+			}
+		}
+		return nil, errors.New("no such vault")
+	})
+	return p, &urls
+}
+
+func TestProvider_ResolvesSecrets(t *testing.T) {
+	ops := &mockVault{secrets: map[string]string{"db-password": "s3cr3t", "conn": "Server=x;Password=hunter2;"}}
+	p, urls := newMockProvider(t, map[string]*mockVault{"ops-vault": ops})
+
+	mgr := inputs.NewManager()
+	mgr.Register(p)
+
+	resolved, warnings, err := mgr.Resolve(context.Background(), map[string]*schema.InputDef{
+		"password": {From: "akv.ops-vault/db-password"},
+		"db_pass":  {From: "akv.ops-vault/conn", Pattern: `Password=([^;]+)`},
+		"missing":  {From: "akv.ops-vault/nope"},
+		"other":    {From: "akv.other-vault/x"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if resolved["password"] != "s3cr3t" {
+		t.Errorf("password = %q", resolved["password"])
+	}
+	if resolved["db_pass"] != "hunter2" {
+		t.Errorf("db_pass = %q", resolved["db_pass"])
+	}
+	if _, ok := resolved["missing"]; ok {
+		t.Error("missing secret should not be resolved")
+	}
+	if len(warnings) != 2 {
+		t.Errorf("expected 2 warnings, got %v", warnings)
+	}
+	if ops.calls != 3 {
+		t.Errorf("ops-vault lookups = %d, want 3", ops.calls)
+	}
+	// One client per vault, reused across bindings
+	if len(*urls) != 2 {
+		t.Errorf("clients created for %v, want one per vault", *urls)
+	}
+}
+
+func TestProvider_Redactions(t *testing.T) {
+	p, _ := newMockProvider(t, map[string]*mockVault{
+		"ops-vault": {secrets: map[string]string{"token": "abc.def+1"}},
+	})
+	_, err := p.Resolve(context.Background(), &inputs.ResolveRequest{
+		Bindings: map[string]inputs.InputBinding{"token": {From: "akv.ops-vault/token"}},
+	})
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	out := governance.RedactOutput("token=abc.def+1 other=abcXdef+1", p.Redactions())
+	if out != "token=*** other=abcXdef+1" {
+		t.Errorf("redacted = %q", out)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	vault, secret, err := ParseRef("akv.my-vault/my-secret")
+	if err != nil {
+		t.Fatalf("ParseRef: %v", err)
+	}
+	if vault != "my-vault" || secret != "my-secret" {
+		t.Errorf("got (%q, %q)", vault, secret)
+	}
+	for _, bad := range []string{"akv.my-vault", "akv./x", "akv.v/x", "kv.my-vault/x"} {
+		if _, _, err := ParseRef(bad); err == nil {
+			t.Errorf("ParseRef(%q): expected error", bad)
+		}
+	}
+}
+
+func TestNewProviderFromEnv(t *testing.T) {
+	t.Setenv(EnvVaultURL, "")
+	if p, err := NewProviderFromEnv(); p != nil || err != nil {
+		t.Fatalf("unset: got (%v, %v), want (nil, nil)", p, err)
+	}
+
+	t.Setenv(EnvVaultURL, "https://ops.vault.usgovcloudapi.net/")
+	p, err := NewProviderFromEnv()
+	if err != nil {
+		t.Fatalf("NewProviderFromEnv: %v", err)
+	}
+	if got := p.VaultURL("ops-vault"); got != "https://ops-vault.vault.usgovcloudapi.net/" {
+		t.Errorf("VaultURL = %q", got)
+	}
+
+	t.Setenv(EnvVaultURL, "not-a-url")
+	if _, err := NewProviderFromEnv(); err == nil || !strings.Contains(err.Error(), EnvVaultURL) {
+		t.Errorf("expected %s error, got %v", EnvVaultURL, err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/ormasoftchile/gert/pkg/schema"
@@ -14,13 +15,17 @@ import (
 type Manager struct {
 	providers []InputProvider
 	prefixMap map[string]InputProvider // prefix → provider for fast lookup
+	prompt    PromptFunc               // collects `from: prompt` inputs; nil skips them
 }
 
-// NewManager creates an empty input manager.
+// NewManager creates an input manager with the built-in providers
+// (run.<run-id>.<name> cross-run bindings) registered.
 func NewManager() *Manager {
-	return &Manager{
+	m := &Manager{
 		prefixMap: make(map[string]InputProvider),
 	}
+	m.Register(NewRunOutputProvider(DefaultRunsDir))
+	return m
 }
 
 // Register adds an input provider. Its prefixes are indexed for dispatch.
@@ -31,12 +36,85 @@ func (m *Manager) Register(provider InputProvider) {
 	}
 }
 
+// SetPrompt enables interactive collection of `from: prompt` inputs.
+func (m *Manager) SetPrompt(fn PromptFunc) {
+	m.prompt = fn
+}
+
 // Resolve dispatches input bindings to the appropriate providers and merges results.
-// Inputs with `from: prompt` or unmatched prefixes are skipped (not an error).
-// The context map provides execution metadata that providers may need.
+// Inputs with unmatched prefixes are skipped (not an error), as are `from:
+// prompt` inputs unless a prompt is set. Values are checked against each
+// input's validation: prompts re-ask, and invalid provider values are
+// dropped with a warning. Inputs whose when: condition is false are not
+// resolved. The context map provides execution metadata that providers may need.
 func (m *Manager) Resolve(ctx context.Context, inputs map[string]*schema.InputDef, execCtx map[string]string) (map[string]string, []string, error) {
+	resolved, _, warnings, err := m.ResolveMeta(ctx, &schema.Meta{Inputs: inputs}, nil, execCtx)
+	return resolved, warnings, err
+}
+
+// ResolveMeta resolves meta.inputs like Resolve, for a host that already
+// holds some values (vars passed in, defaults): inputs in known are not
+// resolved again, and when: conditions see them. Unconditional inputs are
+// resolved first; conditional ones follow in declaration order, each seeing
+// the values before it. skipped lists the inputs whose when: was false.
+func (m *Manager) ResolveMeta(ctx context.Context, meta *schema.Meta, known, execCtx map[string]string) (resolved map[string]string, skipped, warnings []string, err error) {
+	if len(meta.Inputs) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	unconditional := make(map[string]*schema.InputDef)
+	for name, input := range meta.Inputs {
+		if _, ok := known[name]; !ok && input.When == "" {
+			unconditional[name] = input
+		}
+	}
+	resolved, warnings, err = m.resolve(ctx, unconditional, execCtx)
+	if err != nil {
+		return resolved, nil, warnings, err
+	}
+
+	values := make(map[string]string, len(known)+len(resolved))
+	for k, v := range known {
+		values[k] = v
+	}
+	for k, v := range resolved {
+		values[k] = v
+	}
+	for _, name := range meta.OrderedInputs() {
+		input := meta.Inputs[name]
+		if input.When == "" {
+			continue
+		}
+		ok, err := EvalWhen(input.When, meta.Inputs, values)
+		if err != nil {
+			return resolved, skipped, warnings, fmt.Errorf("input %q: %w", name, err)
+		}
+		if !ok {
+			skipped = append(skipped, name)
+			delete(values, name)
+			continue
+		}
+		if _, ok := known[name]; ok {
+			continue
+		}
+		r, w, err := m.resolve(ctx, map[string]*schema.InputDef{name: input}, execCtx)
+		warnings = append(warnings, w...)
+		for k, v := range r {
+			resolved[k] = v
+			values[k] = v
+		}
+		if err != nil {
+			return resolved, skipped, warnings, err
+		}
+	}
+	return resolved, skipped, warnings, nil
+}
+
+// resolve dispatches one set of inputs to their providers and the prompt.
+func (m *Manager) resolve(ctx context.Context, inputs map[string]*schema.InputDef, execCtx map[string]string) (map[string]string, []string, error) {
+	allResolved := make(map[string]string)
 	if len(inputs) == 0 {
-		return nil, nil, nil
+		return allResolved, nil, nil
 	}
 
 	// Group bindings by provider
@@ -46,7 +124,12 @@ func (m *Manager) Resolve(ctx context.Context, inputs map[string]*schema.InputDe
 	}
 	batches := make(map[string]*providerBatch) // keyed by first prefix
 
+	var prompted []string
 	for name, input := range inputs {
+		if input.From == "prompt" && m.prompt != nil {
+			prompted = append(prompted, name)
+			continue
+		}
 		if input.From == "" || input.From == "prompt" || input.From == "enrichment" {
 			continue
 		}
@@ -81,7 +164,6 @@ func (m *Manager) Resolve(ctx context.Context, inputs map[string]*schema.InputDe
 	}
 
 	// Dispatch to each provider
-	allResolved := make(map[string]string)
 	var allWarnings []string
 
 	for prefix, batch := range batches {
@@ -98,11 +180,26 @@ func (m *Manager) Resolve(ctx context.Context, inputs map[string]*schema.InputDe
 		}
 
 		for k, v := range result.Resolved {
+			if in := inputs[k]; in != nil {
+				if err := Validate(in.Validation, v); err != nil {
+					allWarnings = append(allWarnings, fmt.Sprintf("input %q: %v", k, err))
+					continue
+				}
+			}
 			allResolved[k] = v
 		}
 		allWarnings = append(allWarnings, result.Warnings...)
 	}
 
+	sort.Strings(prompted)
+	for _, name := range prompted {
+		v, err := m.prompt(name, inputs[name])
+		if err != nil {
+			return allResolved, allWarnings, err
+		}
+		allResolved[name] = v
+	}
+
 	return allResolved, allWarnings, nil
 }
 
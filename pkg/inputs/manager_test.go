@@ -3,6 +3,7 @@ package inputs
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/ormasoftchile/gert/pkg/schema"
@@ -135,6 +136,38 @@ func TestManagerResolve_NoMatchingProvider(t *testing.T) {
 	}
 }
 
+func TestManagerResolve_Validation(t *testing.T) {
+	mgr := NewManager()
+	mgr.Register(&mockProvider{
+		prefixes: []string{"svc."},
+		resolved: map[string]string{"svc.severity": "7"},
+	})
+	var asked []string
+	mgr.SetPrompt(func(name string, input *schema.InputDef) (string, error) {
+		asked = append(asked, name)
+		return "prod", nil
+	})
+
+	inputs := map[string]*schema.InputDef{
+		"sev": {From: "svc.severity", Validation: &schema.InputValidation{Type: "enum", Options: []string{"1", "2", "3", "4"}}},
+		"env": {From: "prompt"},
+	}
+
+	resolved, warnings, err := mgr.Resolve(context.Background(), inputs, nil)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if _, ok := resolved["sev"]; ok {
+		t.Errorf("invalid provider value kept: %v", resolved)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "must be one of 1, 2, 3, 4") {
+		t.Errorf("warnings = %v", warnings)
+	}
+	if resolved["env"] != "prod" || len(asked) != 1 {
+		t.Errorf("prompted %v, resolved %v", asked, resolved)
+	}
+}
+
 func TestManagerResolve_EmptyInputs(t *testing.T) {
 	mgr := NewManager()
 	resolved, _, err := mgr.Resolve(context.Background(), nil, nil)
@@ -146,6 +179,60 @@ func TestManagerResolve_EmptyInputs(t *testing.T) {
 	}
 }
 
+func TestManagerResolveMeta_When(t *testing.T) {
+	mgr := NewManager()
+	mgr.Register(&mockProvider{
+		prefixes: []string{"svc."},
+		resolved: map[string]string{"svc.cloud": "aws", "svc.zone": "z1"},
+	})
+	var asked []string
+	mgr.SetPrompt(func(name string, input *schema.InputDef) (string, error) {
+		asked = append(asked, name)
+		return "eu-west-1", nil
+	})
+
+	meta := &schema.Meta{
+		Inputs: map[string]*schema.InputDef{
+			"cloud":   {From: "svc.cloud"},
+			"region":  {From: "prompt", When: `cloud == "aws"`},
+			"project": {From: "prompt", When: `cloud == "gcp"`},
+			"zone":    {From: "svc.zone", When: `project != ""`},
+			"tier":    {From: "prompt", When: `env == "prod"`},
+		},
+		InputOrder: []string{"cloud", "region", "project", "zone", "tier"},
+	}
+
+	resolved, skipped, _, err := mgr.ResolveMeta(context.Background(), meta, map[string]string{"env": "prod", "tier": "gold"}, nil)
+	if err != nil {
+		t.Fatalf("ResolveMeta: %v", err)
+	}
+	if resolved["cloud"] != "aws" || resolved["region"] != "eu-west-1" {
+		t.Errorf("resolved = %v", resolved)
+	}
+	if fmt.Sprint(asked) != "[region]" {
+		t.Errorf("prompted %v, want only region (tier is known)", asked)
+	}
+	// zone depends on the skipped project, which reads as empty
+	if fmt.Sprint(skipped) != "[project zone]" {
+		t.Errorf("skipped = %v, want [project zone]", skipped)
+	}
+	if _, ok := resolved["zone"]; ok {
+		t.Errorf("skipped input resolved: %v", resolved)
+	}
+
+	skipped, err = Skipped(meta, map[string]string{"cloud": "gcp", "project": "p1", "env": "dev"})
+	if err != nil {
+		t.Fatalf("Skipped: %v", err)
+	}
+	if fmt.Sprint(skipped) != "[region tier]" {
+		t.Errorf("Skipped = %v, want [region tier]", skipped)
+	}
+
+	if _, err := EvalWhen(`cloud + 1`, meta.Inputs, nil); err == nil {
+		t.Error("expected error for a non-bool when")
+	}
+}
+
 func TestBindingsFromInputs(t *testing.T) {
 	inputs := map[string]*schema.InputDef{
 		"server": {From: "svc.fields.ServerName", Pattern: ".*"},
@@ -0,0 +1,162 @@
+package inputs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// RunOutputPrefix is the from: prefix for cross-run bindings:
+// "run.<run-id>.<capture-name>".
+const RunOutputPrefix = "run."
+
+// DefaultRunsDir is where run artifacts (and run.yaml manifests) are written.
+var DefaultRunsDir = filepath.Join(".runbook", "runs")
+
+// RunOutputProvider resolves inputs from the manifest of a prior run, so a
+// "gather" runbook's results can feed a "remediate" runbook.
+type RunOutputProvider struct {
+	RunsDir string // directory containing <run-id>/run.yaml
+}
+
+// NewRunOutputProvider creates a provider reading manifests under runsDir.
+func NewRunOutputProvider(runsDir string) *RunOutputProvider {
+	return &RunOutputProvider{RunsDir: runsDir}
+}
+
+// runManifestInputs is the subset of run.yaml read by the provider.
+type runManifestInputs struct {
+	InputsResolved map[string]string `yaml:"inputs_resolved"`
+}
+
+// Prefixes returns the from: prefixes this provider handles.
+func (p *RunOutputProvider) Prefixes() []string {
+	return []string{RunOutputPrefix}
+}
+
+// Resolve reads each referenced run's manifest and extracts the named value.
+// Missing runs or values produce warnings, not errors.
+func (p *RunOutputProvider) Resolve(ctx context.Context, req *ResolveRequest) (*ResolveResult, error) {
+	result := &ResolveResult{Resolved: make(map[string]string)}
+	manifests := make(map[string]map[string]string)
+
+	for name, binding := range req.Bindings {
+		runID, key, err := ParseRunRef(binding.From)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: %v", name, err))
+			continue
+		}
+
+		values, ok := manifests[runID]
+		if !ok {
+			values, err = p.loadInputs(runID)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: %v", name, err))
+				continue
+			}
+			manifests[runID] = values
+		}
+
+		val, ok := values[key]
+		if !ok {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: run %q has no value %q", name, runID, key))
+			continue
+		}
+		if binding.Pattern != "" {
+			re, err := regexp.Compile(binding.Pattern)
+			if err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: invalid pattern: %v", name, err))
+				continue
+			}
+			m := re.FindStringSubmatch(val)
+			switch {
+			case m == nil:
+				result.Warnings = append(result.Warnings, fmt.Sprintf("input %q: pattern %q did not match", name, binding.Pattern))
+				continue
+			case len(m) > 1:
+				val = m[1]
+			default:
+				val = m[0]
+			}
+		}
+		result.Resolved[name] = val
+	}
+
+	return result, nil
+}
+
+// Shutdown is a no-op; the provider holds no resources.
+func (p *RunOutputProvider) Shutdown() error {
+	return nil
+}
+
+// loadInputs reads InputsResolved from <RunsDir>/<runID>/run.yaml.
+func (p *RunOutputProvider) loadInputs(runID string) (map[string]string, error) {
+	data, err := os.ReadFile(p.manifestPath(runID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("run %q not found", runID)
+		}
+		return nil, fmt.Errorf("read run %q manifest: %w", runID, err)
+	}
+	var m runManifestInputs
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse run %q manifest: %w", runID, err)
+	}
+	return m.InputsResolved, nil
+}
+
+func (p *RunOutputProvider) manifestPath(runID string) string {
+	return filepath.Join(p.RunsDir, runID, "run.yaml")
+}
+
+// ParseRunRef splits "run.<run-id>.<capture-name>" into its run ID and name.
+// The run ID names a directory under the runs dir, so path separators and
+// ".." are rejected.
+func ParseRunRef(from string) (runID, name string, err error) {
+	rest, ok := strings.CutPrefix(from, RunOutputPrefix)
+	if !ok {
+		return "", "", fmt.Errorf("binding %q does not start with %q", from, RunOutputPrefix)
+	}
+	runID, name, ok = strings.Cut(rest, ".")
+	if !ok || runID == "" || name == "" {
+		return "", "", fmt.Errorf("binding %q must have the form run.<run-id>.<name>", from)
+	}
+	if strings.ContainsAny(runID, `/\`) || strings.Contains(runID, "..") {
+		return "", "", fmt.Errorf("binding %q: run ID %q must not contain path separators or \"..\"", from, runID)
+	}
+	return runID, name, nil
+}
+
+// ValidateRunRefs implements ROP1: every run.<run-id>.<name> input must refer
+// to an existing run manifest. Hosts call it at startup under --strict-run-refs.
+func ValidateRunRefs(inputs map[string]*schema.InputDef, runsDir string) []*schema.ValidationError {
+	p := NewRunOutputProvider(runsDir)
+	var errs []*schema.ValidationError
+	for name, input := range inputs {
+		if !strings.HasPrefix(input.From, RunOutputPrefix) {
+			continue
+		}
+		path := fmt.Sprintf("meta.inputs.%s.from", name)
+		runID, _, err := ParseRunRef(input.From)
+		if err != nil {
+			errs = append(errs, &schema.ValidationError{Phase: "domain", Path: path, Message: err.Error(), Severity: "error"})
+			continue
+		}
+		if _, err := os.Stat(p.manifestPath(runID)); err != nil {
+			errs = append(errs, &schema.ValidationError{
+				Phase:    "domain",
+				Path:     path,
+				Message:  fmt.Sprintf("input %q references run %q which does not exist in %s", name, runID, runsDir),
+				Severity: "error",
+			})
+		}
+	}
+	return errs
+}
@@ -0,0 +1,84 @@
+package inputs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+func writeRunManifest(t *testing.T, runsDir, runID, content string) {
+	t.Helper()
+	dir := filepath.Join(runsDir, runID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "run.yaml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunOutputProvider_ResolvesFromManifest(t *testing.T) {
+	runsDir := t.TempDir()
+	writeRunManifest(t, runsDir, "test-run-id", "run_id: test-run-id\ninputs_resolved:\n  cluster: prod\n")
+
+	mgr := NewManager()
+	mgr.Register(NewRunOutputProvider(runsDir))
+
+	inputs := map[string]*schema.InputDef{
+		"cluster": {From: "run.test-run-id.cluster"},
+		"missing": {From: "run.test-run-id.region"},
+	}
+
+	resolved, warnings, err := mgr.Resolve(context.Background(), inputs, nil)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if resolved["cluster"] != "prod" {
+		t.Errorf("cluster = %q, want %q", resolved["cluster"], "prod")
+	}
+	if _, ok := resolved["missing"]; ok {
+		t.Error("missing value should not be resolved")
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected 1 warning, got %v", warnings)
+	}
+}
+
+func TestParseRunRef(t *testing.T) {
+	runID, name, err := ParseRunRef("run.20240315T101500-abc1.cluster_list")
+	if err != nil {
+		t.Fatalf("ParseRunRef: %v", err)
+	}
+	if runID != "20240315T101500-abc1" || name != "cluster_list" {
+		t.Errorf("got (%q, %q)", runID, name)
+	}
+	if _, _, err := ParseRunRef("run.only-id"); err == nil {
+		t.Error("expected error for missing capture name")
+	}
+	for _, from := range []string{"run.../etc.passwd", "run.a/b.cluster", `run.a\b.cluster`} {
+		if _, _, err := ParseRunRef(from); err == nil {
+			t.Errorf("expected error for run ID escaping the runs dir in %q", from)
+		}
+	}
+}
+
+func TestValidateRunRefs(t *testing.T) {
+	runsDir := t.TempDir()
+	writeRunManifest(t, runsDir, "exists", "inputs_resolved: {}\n")
+
+	inputs := map[string]*schema.InputDef{
+		"a": {From: "run.exists.cluster"},
+		"b": {From: "run.gone.cluster"},
+		"c": {From: "prompt"},
+	}
+	errs := ValidateRunRefs(inputs, runsDir)
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+	if errs[0].Path != "meta.inputs.b.from" {
+		t.Errorf("path = %q", errs[0].Path)
+	}
+}
@@ -0,0 +1,100 @@
+package inputs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// Validate checks value against an input's validation spec. A nil spec
+// accepts anything. The error reads as a message for the person typing,
+// e.g. "invalid input: must match pattern ^\d{6}$".
+func Validate(v *schema.InputValidation, value string) error {
+	if v == nil {
+		return nil
+	}
+	switch v.Type {
+	case "regex":
+		re, err := regexp.Compile(v.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid validation pattern %q: %w", v.Pattern, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("invalid input: must match pattern %s", v.Pattern)
+		}
+	case "range":
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return fmt.Errorf("invalid input: must be a number%s", rangeText(v))
+		}
+		if (v.Min != nil && n < *v.Min) || (v.Max != nil && n > *v.Max) {
+			return fmt.Errorf("invalid input: must be%s", rangeText(v))
+		}
+	case "enum":
+		if !slices.Contains(v.Options, value) {
+			return fmt.Errorf("invalid input: must be one of %s", strings.Join(v.Options, ", "))
+		}
+	default:
+		return fmt.Errorf("unknown validation type %q", v.Type)
+	}
+	return nil
+}
+
+// rangeText describes the bounds of a range validation, e.g.
+// " between 0 and 100".
+func rangeText(v *schema.InputValidation) string {
+	switch {
+	case v.Min != nil && v.Max != nil:
+		return fmt.Sprintf(" between %v and %v", *v.Min, *v.Max)
+	case v.Min != nil:
+		return fmt.Sprintf(" at least %v", *v.Min)
+	case v.Max != nil:
+		return fmt.Sprintf(" at most %v", *v.Max)
+	}
+	return ""
+}
+
+// PromptFunc collects the value of a `from: prompt` input.
+type PromptFunc func(name string, input *schema.InputDef) (string, error)
+
+// NewLinePrompt returns a PromptFunc that asks for each input on out and
+// reads a line from in. An empty response takes the default. Responses
+// that fail validation are reported and asked again.
+func NewLinePrompt(in io.Reader, out io.Writer) PromptFunc {
+	reader := bufio.NewReader(in)
+	return func(name string, input *schema.InputDef) (string, error) {
+		label := name
+		if input.Description != "" {
+			label = fmt.Sprintf("%s (%s)", name, input.Description)
+		}
+		for {
+			if input.Default != "" {
+				fmt.Fprintf(out, "%s [%s]: ", label, input.Default)
+			} else {
+				fmt.Fprintf(out, "%s: ", label)
+			}
+			line, err := reader.ReadString('\n')
+			if err != nil && (err != io.EOF || line == "") {
+				return "", fmt.Errorf("read input %q: %w", name, err)
+			}
+			value := strings.TrimSpace(line)
+			if value == "" {
+				value = input.Default
+			}
+			verr := Validate(input.Validation, value)
+			if verr == nil {
+				return value, nil
+			}
+			fmt.Fprintf(out, "  %v\n", verr)
+			if err == io.EOF {
+				return "", verr
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package inputs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+func TestValidate(t *testing.T) {
+	regex := &schema.InputValidation{Type: "regex", Pattern: `^\d{6}$`}
+	rng := &schema.InputValidation{Type: "range", Min: ptr(0), Max: ptr(100)}
+	minOnly := &schema.InputValidation{Type: "range", Min: ptr(1)}
+	enum := &schema.InputValidation{Type: "enum", Options: []string{"eastus", "westeurope"}}
+
+	tests := []struct {
+		name    string
+		v       *schema.InputValidation
+		value   string
+		wantErr string
+	}{
+		{"no spec", nil, "anything", ""},
+		{"regex match", regex, "123456", ""},
+		{"regex too short", regex, "12345", `must match pattern ^\d{6}$`},
+		{"regex too long", regex, "1234567", `must match pattern ^\d{6}$`},
+		{"regex empty", regex, "", `must match pattern`},
+		{"range lower bound", rng, "0", ""},
+		{"range upper bound", rng, "100", ""},
+		{"range decimal", rng, "99.5", ""},
+		{"range below", rng, "-0.1", "must be between 0 and 100"},
+		{"range above", rng, "100.01", "must be between 0 and 100"},
+		{"range not a number", rng, "ten", "must be a number between 0 and 100"},
+		{"range min only", minOnly, "1", ""},
+		{"range min only below", minOnly, "0", "must be at least 1"},
+		{"enum member", enum, "westeurope", ""},
+		{"enum is case sensitive", enum, "EastUS", "must be one of eastus, westeurope"},
+		{"enum empty", enum, "", "must be one of"},
+		{"unknown type", &schema.InputValidation{Type: "length"}, "x", `unknown validation type "length"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.v, tt.value)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate(%q) = %v, want nil", tt.value, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate(%q) = %v, want error containing %q", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLinePrompt_RepromptsUntilValid(t *testing.T) {
+	in := strings.NewReader("12ab\n\n654321\n")
+	var out bytes.Buffer
+	prompt := NewLinePrompt(in, &out)
+
+	input := &schema.InputDef{
+		From:        "prompt",
+		Description: "ticket number",
+		Validation:  &schema.InputValidation{Type: "regex", Pattern: `^\d{6}$`},
+	}
+	got, err := prompt("ticket", input)
+	if err != nil {
+		t.Fatalf("prompt: %v", err)
+	}
+	if got != "654321" {
+		t.Errorf("value = %q, want 654321", got)
+	}
+	if n := strings.Count(out.String(), "invalid input: must match pattern ^\\d{6}$"); n != 2 {
+		t.Errorf("got %d validation errors, want 2:\n%s", n, out.String())
+	}
+	if n := strings.Count(out.String(), "ticket (ticket number): "); n != 3 {
+		t.Errorf("asked %d times, want 3:\n%s", n, out.String())
+	}
+}
+
+func TestLinePrompt_DefaultAndEOF(t *testing.T) {
+	var out bytes.Buffer
+	prompt := NewLinePrompt(strings.NewReader("\n"), &out)
+	got, err := prompt("region", &schema.InputDef{From: "prompt", Default: "eastus"})
+	if err != nil || got != "eastus" {
+		t.Errorf("prompt = %q, %v; want default eastus", got, err)
+	}
+
+	// Input ends before a valid value arrives
+	prompt = NewLinePrompt(strings.NewReader("7"), &out)
+	_, err = prompt("pct", &schema.InputDef{
+		From:       "prompt",
+		Validation: &schema.InputValidation{Type: "range", Max: ptr(5)},
+	})
+	if err == nil || !strings.Contains(err.Error(), "must be at most 5") {
+		t.Errorf("err = %v, want range error", err)
+	}
+}
@@ -0,0 +1,56 @@
+package inputs
+
+import (
+	"fmt"
+
+	"github.com/expr-lang/expr"
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// EvalWhen evaluates an input's when: expression against the values
+// resolved so far. Every declared input is visible to the expression;
+// inputs without a value (skipped or not yet resolved) read as "". An
+// empty expression is true.
+func EvalWhen(when string, inputs map[string]*schema.InputDef, values map[string]string) (bool, error) {
+	if when == "" {
+		return true, nil
+	}
+	env := make(map[string]any, len(inputs)+len(values))
+	for name := range inputs {
+		env[name] = ""
+	}
+	for name, v := range values {
+		env[name] = v
+	}
+	out, err := expr.Eval(when, env)
+	if err != nil {
+		return false, fmt.Errorf("when %q: %w", when, err)
+	}
+	ok, isBool := out.(bool)
+	if !isBool {
+		return false, fmt.Errorf("when %q: got %T, want bool", when, out)
+	}
+	return ok, nil
+}
+
+// Skipped evaluates the when: conditions of meta.inputs in declaration
+// order and returns the inputs whose condition is false. A skipped input
+// reads as "" in the conditions that follow it, whatever values holds.
+func Skipped(meta *schema.Meta, values map[string]string) ([]string, error) {
+	current := make(map[string]string, len(values))
+	for k, v := range values {
+		current[k] = v
+	}
+	var skipped []string
+	for _, name := range meta.OrderedInputs() {
+		ok, err := EvalWhen(meta.Inputs[name].When, meta.Inputs, current)
+		if err != nil {
+			return skipped, fmt.Errorf("input %q: %w", name, err)
+		}
+		if !ok {
+			skipped = append(skipped, name)
+			delete(current, name)
+		}
+	}
+	return skipped, nil
+}
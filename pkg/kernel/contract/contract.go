@@ -12,6 +12,11 @@ type Contract struct {
 	Idempotent    *bool               `yaml:"idempotent,omitempty"  json:"idempotent,omitempty"`
 	Reads         []string            `yaml:"reads,omitempty"       json:"reads,omitempty"`
 	Writes        []string            `yaml:"writes,omitempty"      json:"writes,omitempty"`
+
+	// Extension steps only: the plugin binary spawned to execute the step,
+	// spoken to over newline-delimited JSON-RPC 2.0 on stdio.
+	Binary string   `yaml:"binary,omitempty" json:"binary,omitempty"`
+	Args   []string `yaml:"args,omitempty"   json:"args,omitempty"`
 }
 
 // ParamDef describes a single input or output parameter.
@@ -23,6 +28,43 @@ type ParamDef struct {
 	From        string `yaml:"from,omitempty"         json:"from,omitempty"`
 }
 
+// CheckType reports whether v, as decoded from JSON or YAML, matches a
+// declared parameter type. An empty or "any" type matches everything.
+func CheckType(typ string, v any) bool {
+	switch typ {
+	case "", "any":
+		return true
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "bool", "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number", "float":
+		switch v.(type) {
+		case float64, float32, int, int64:
+			return true
+		}
+		return false
+	case "int", "integer":
+		switch n := v.(type) {
+		case int, int64:
+			return true
+		case float64:
+			return n == float64(int64(n))
+		}
+		return false
+	case "object", "map":
+		_, ok := v.(map[string]any)
+		return ok
+	case "array", "list":
+		_, ok := v.([]any)
+		return ok
+	default:
+		return true
+	}
+}
+
 // RiskLevel classifies a contract's risk based on its behavioural properties.
 type RiskLevel string
 
@@ -70,6 +112,21 @@ func (c *Contract) Risk() RiskLevel {
 	return RiskCritical
 }
 
+// HasWriteEffects reports whether the contract declares writes, any effect
+// other than "reads", or (with no effects) explicit side_effects. Probe mode
+// skips steps whose contract has write effects.
+func (c *Contract) HasWriteEffects() bool {
+	if len(c.Writes) > 0 {
+		return true
+	}
+	for _, eff := range c.Effects {
+		if eff != "reads" {
+			return true
+		}
+	}
+	return len(c.Effects) == 0 && c.getBool(c.SideEffects, false)
+}
+
 // Resolved returns a copy of this contract with all nil fields replaced by
 // their defaults (side_effects=true, deterministic=false, idempotent=false).
 // If side_effects is set but effects is nil, auto-migrates to effects: [unknown].
@@ -140,6 +197,9 @@ func Merge(parent, child *Contract) Contract {
 			out.Outputs[k] = v
 		}
 	}
+	if child.Effects != nil {
+		out.Effects = setUnion(parent.Effects, child.Effects)
+	}
 	if child.SideEffects != nil {
 		out.SideEffects = child.SideEffects
 	}
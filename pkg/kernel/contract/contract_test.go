@@ -1,6 +1,7 @@
 package contract
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -213,6 +214,12 @@ func TestMerge(t *testing.T) {
 	if len(merged.Reads) != 1 || merged.Reads[0] != "network" {
 		t.Errorf("Reads should be union: got %v", merged.Reads)
 	}
+
+	// Effects are a union too
+	merged = Merge(&Contract{Effects: []string{"reads"}}, &Contract{Effects: []string{"reads", "filesystem"}})
+	if len(merged.Effects) != 2 {
+		t.Errorf("Effects should be union: got %v", merged.Effects)
+	}
 }
 
 func TestAssertContract(t *testing.T) {
@@ -292,3 +299,72 @@ func TestResolved_IncludesEffects(t *testing.T) {
 		t.Errorf("resolved effects = %v, want [network]", r.Effects)
 	}
 }
+
+func TestHasWriteEffects(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Contract
+		want bool
+	}{
+		{name: "reads only", c: Contract{Effects: []string{"reads"}}, want: false},
+		{name: "network effect", c: Contract{Effects: []string{"network"}}, want: true},
+		{name: "writes declared", c: Contract{Writes: []string{"pods"}}, want: true},
+		{name: "no effects, side effects", c: Contract{SideEffects: boolPtr(true)}, want: true},
+		{name: "no effects, no side effects", c: Contract{SideEffects: boolPtr(false)}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.c.HasWriteEffects(); got != tt.want {
+				t.Errorf("HasWriteEffects() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		v    any
+		want bool
+	}{
+		{"string", "x", true},
+		{"string", 1.0, false},
+		{"int", 3.0, true},
+		{"int", 3.5, false},
+		{"number", 3.5, true},
+		{"bool", true, true},
+		{"boolean", "true", false},
+		{"object", map[string]any{}, true},
+		{"array", []any{1.0}, true},
+		{"", nil, true},
+	}
+	for _, tt := range tests {
+		if got := CheckType(tt.typ, tt.v); got != tt.want {
+			t.Errorf("CheckType(%q, %#v) = %v, want %v", tt.typ, tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestEnforcer_Violations(t *testing.T) {
+	en := NewEnforcer([]string{"reads", "network"})
+	tests := []struct {
+		name string
+		c    Contract
+		want []string
+	}{
+		{name: "allowed effects", c: Contract{Effects: []string{"reads", "network"}}, want: nil},
+		{name: "disallowed effect", c: Contract{Effects: []string{"reads", "filesystem"}}, want: []string{"filesystem"}},
+		{name: "explicitly no effects", c: Contract{Effects: []string{}}, want: nil},
+		{name: "no side effects", c: Contract{SideEffects: boolPtr(false)}, want: nil},
+		{name: "undeclared effects", c: Contract{}, want: []string{EffectUnknown}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := en.Violations(&tt.c)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Violations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
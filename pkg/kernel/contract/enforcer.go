@@ -0,0 +1,45 @@
+package contract
+
+// EffectUnknown stands in for the effects of a contract that declares no
+// effects but may have side effects (side_effects unset or true).
+const EffectUnknown = "unknown"
+
+// Enforcer checks a contract's declared effects against the set of
+// effects a governance policy allows.
+type Enforcer struct {
+	allowed map[string]bool
+}
+
+// NewEnforcer returns an enforcer permitting only the given effects.
+// With no allowed effects, only contracts without effects pass.
+func NewEnforcer(allowed []string) *Enforcer {
+	en := &Enforcer{allowed: make(map[string]bool, len(allowed))}
+	for _, eff := range allowed {
+		en.allowed[eff] = true
+	}
+	return en
+}
+
+// Violations returns the effects of c that are not allowed, in the
+// order c lists them. An empty result means the contract may execute.
+func (en *Enforcer) Violations(c *Contract) []string {
+	var violations []string
+	for _, eff := range c.DeclaredEffects() {
+		if !en.allowed[eff] {
+			violations = append(violations, eff)
+		}
+	}
+	return violations
+}
+
+// DeclaredEffects returns the contract's effects. A contract without
+// effects reports [unknown] unless side_effects is explicitly false.
+func (c *Contract) DeclaredEffects() []string {
+	if len(c.Effects) > 0 {
+		return c.Effects
+	}
+	if c.Effects == nil && c.getBool(c.SideEffects, true) {
+		return []string{EffectUnknown}
+	}
+	return nil
+}
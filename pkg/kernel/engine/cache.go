@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/eval"
+	"github.com/ormasoftchile/gert/pkg/kernel/executor"
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
+	"github.com/ormasoftchile/gert/pkg/kernel/trace"
+)
+
+// toolCacheDir holds cached tool step results, one <sha256>.json file per
+// key, relative to the working directory like runs/.
+const toolCacheDir = ".gert-cache/tool-results"
+
+// cacheEntry is a cached tool result.
+type cacheEntry struct {
+	Tool     string         `json:"tool"`
+	Action   string         `json:"action"`
+	StoredAt time.Time      `json:"stored_at"`
+	Outputs  map[string]any `json:"outputs"`
+}
+
+// useCache reports whether a step's result may be read from and written to
+// the cache. Only real runs use it, so dry-run, probe and replay always see
+// what the tool (or scenario) returns.
+func (e *Engine) useCache(step schema.Step) bool {
+	return step.Cache != nil && e.cfg.Mode == "real" && !e.cfg.NoCache
+}
+
+// cacheKey resolves the step's cache key and hashes it with the tool and
+// action, so two steps sharing a key template never share results.
+func (e *Engine) cacheKey(step schema.Step) (string, error) {
+	key, err := eval.Resolve(step.Cache.Key, e.vars)
+	if err != nil {
+		return "", fmt.Errorf("cache key: %w", err)
+	}
+	sum := sha256.Sum256([]byte(step.Tool + "\x00" + step.Action + "\x00" + key))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// cachedResult returns the stored result for hash when it is younger than
+// the step's ttl, emitting tool_cache_hit or tool_cache_miss.
+func (e *Engine) cachedResult(step schema.Step, stepID, hash string) *executor.Result {
+	ttl, _ := time.ParseDuration(step.Cache.TTL)
+	reason := "missing"
+	data, err := os.ReadFile(filepath.Join(toolCacheDir, hash+".json"))
+	if err == nil {
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			reason = "corrupt"
+		} else if age := time.Since(entry.StoredAt); age > ttl {
+			reason = "expired"
+		} else {
+			if e.trace != nil {
+				e.trace.Emit(trace.EventToolCacheHit, map[string]any{
+					"step_id": stepID,
+					"key":     hash,
+					"age":     age.Round(time.Second).String(),
+				})
+			}
+			return &executor.Result{Outputs: entry.Outputs}
+		}
+	}
+	if e.trace != nil {
+		e.trace.Emit(trace.EventToolCacheMiss, map[string]any{
+			"step_id": stepID,
+			"key":     hash,
+			"reason":  reason,
+		})
+	}
+	return nil
+}
+
+// storeCachedResult writes a successful result to the cache. A write
+// failure only costs a later cache miss, so it is reported and ignored.
+func (e *Engine) storeCachedResult(step schema.Step, stepID, hash string, result *executor.Result) {
+	data, err := json.Marshal(cacheEntry{
+		Tool:     step.Tool,
+		Action:   step.Action,
+		StoredAt: time.Now().UTC(),
+		Outputs:  result.Outputs,
+	})
+	if err == nil {
+		if err = os.MkdirAll(toolCacheDir, 0o755); err == nil {
+			err = os.WriteFile(filepath.Join(toolCacheDir, hash+".json"), data, 0o600)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(e.cfg.Stdout, "  ⚠ %s cache: %v\n", stepID, err)
+	}
+}
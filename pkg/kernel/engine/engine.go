@@ -9,8 +9,13 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -69,17 +74,20 @@ type ApprovalResponse struct {
 	KeyID        string    `json:"key_id,omitempty"`
 }
 
+// ProbeSkipReason is recorded on steps skipped in probe mode.
+const ProbeSkipReason = "probe: write-effect step"
+
 // defaultExecutor delegates to executor.RunTool.
 type defaultExecutor struct{}
 
 func (d *defaultExecutor) Execute(ctx context.Context, td *schema.ToolDefinition, action string, inputs map[string]any, vars map[string]any) (*executor.Result, error) {
-	return executor.RunTool(td, action, inputs, vars)
+	return executor.RunTool(ctx, td, action, inputs, vars, executor.EnvFromContext(ctx))
 }
 
 // RunConfig configures a runbook execution.
 type RunConfig struct {
 	RunID       string
-	Mode        string // "real", "dry-run", "replay"
+	Mode        string // "real", "dry-run", "probe", "replay"
 	Vars        map[string]string
 	BaseDir     string
 	ProjectRoot string
@@ -89,9 +97,21 @@ type RunConfig struct {
 	ToolExec    ToolExecutor     // custom tool executor (e.g., replay); nil uses default
 	Approval    ApprovalProvider // custom approval provider; nil uses stdin
 	Actor       string           // actor identity for trace attribution
+	ActorGroup  []string         // approvers standing in for approval prompts; exclusive with Actor
 	Host        string           // host identifier for trace
 	Version     string           // gert version for trace
 	RunbookPath string           // path to runbook file (for hashing)
+	SkipHooks   bool             // don't invoke step pre_hook/post_hook (offline or replay runs)
+	CleanEnv    bool             // tool processes get only step env, declared secrets and PATH/HOME/USER
+	NoCache     bool             // ignore step cache: settings; always execute tool steps
+
+	// StrictContracts fails tool steps whose inputs do not match the
+	// tool's contract.inputs; otherwise mismatches are warnings.
+	StrictContracts bool
+
+	// GovernancePolicy is the host's policy. When set, tool steps whose
+	// contract declares effects outside AllowedEffects are blocked.
+	GovernancePolicy *schema.GovernancePolicy
 }
 
 // RunResult is the outcome of executing a runbook.
@@ -112,7 +132,15 @@ type Engine struct {
 	startTime    time.Time
 	toolExec     ToolExecutor
 	approval     ApprovalProvider
-	VisitedSteps []string // ordered list of step IDs executed (for test harness)
+	stdin        *lineReader              // cfg.Stdin, shared by every prompt in the run
+	scoped       *sync.Map                // keys of scope-prefixed outputs, shared with forked engines
+	warnings     []string                 // setup problems reported by New
+	durations    map[string]time.Duration // how long each finished step took, for duration_less_than
+	lastStep     string                   // the most recently finished step
+	VisitedSteps []string                 // ordered list of step IDs executed (for test harness)
+
+	injectMu sync.Mutex     // guards injected
+	injected map[string]any // SetVar values waiting for the run goroutine to apply
 }
 
 // New creates an engine for the given runbook.
@@ -130,7 +158,8 @@ func New(rb *schema.Runbook, cfg RunConfig) *Engine {
 			vars[name] = paramDef.Default
 		}
 	}
-	for k, v := range cfg.Vars {
+	hostVars, warnings := CoerceVars(rb, cfg.Vars)
+	for k, v := range hostVars {
 		vars[k] = v
 	}
 
@@ -146,22 +175,33 @@ func New(rb *schema.Runbook, cfg RunConfig) *Engine {
 		te = &defaultExecutor{}
 	}
 
+	stdin := newLineReader(cfg.Stdin)
 	ap := cfg.Approval
 	if ap == nil {
-		ap = &stdinApprovalProvider{stdin: cfg.Stdin, stdout: cfg.Stdout}
+		ap = &stdinApprovalProvider{stdin: stdin, stdout: cfg.Stdout}
 	}
 
 	return &Engine{
-		cfg:      cfg,
-		rb:       rb,
-		vars:     vars,
-		trace:    cfg.Trace,
-		toolExec: te,
-		approval: ap,
-		tools:    make(map[string]*schema.ToolDefinition),
+		cfg:       cfg,
+		rb:        rb,
+		vars:      vars,
+		trace:     cfg.Trace,
+		toolExec:  te,
+		approval:  ap,
+		stdin:     stdin,
+		tools:     make(map[string]*schema.ToolDefinition),
+		scoped:    &sync.Map{},
+		warnings:  warnings,
+		durations: make(map[string]time.Duration),
 	}
 }
 
+// Warnings returns problems found while setting up the engine, such as
+// host vars that do not parse as their declared input type.
+func (e *Engine) Warnings() []string {
+	return e.warnings
+}
+
 // Run executes the runbook sequentially.
 func (e *Engine) Run(ctx context.Context) *RunResult {
 	e.startTime = time.Now()
@@ -188,8 +228,11 @@ func (e *Engine) Run(ctx context.Context) *RunResult {
 		if len(constantsAny) > 0 {
 			runStartData["constants"] = constantsAny
 		}
-		if e.cfg.Actor != "" {
-			runStartData["actor"] = e.cfg.Actor
+		if actor := e.actor(); actor != "" {
+			runStartData["actor"] = actor
+		}
+		if e.cfg.CleanEnv {
+			runStartData["env_inherit"] = false
 		}
 		if e.cfg.Host != "" {
 			runStartData["host"] = e.cfg.Host
@@ -221,28 +264,20 @@ func (e *Engine) Run(ctx context.Context) *RunResult {
 
 	// Configure secret redaction on trace writer
 	if e.trace != nil {
-		var secretEnvVars []string
-		// Collect from runbook meta
-		for _, s := range e.rb.Meta.Secrets {
-			if s.Env != "" {
-				secretEnvVars = append(secretEnvVars, s.Env)
-			}
-		}
-		// Collect from loaded tool definitions
-		for _, td := range e.tools {
-			for _, s := range td.Meta.Secrets {
-				if s.Env != "" {
-					secretEnvVars = append(secretEnvVars, s.Env)
-				}
-			}
-		}
-		if len(secretEnvVars) > 0 {
+		if secretEnvVars := e.secretEnvVars(); len(secretEnvVars) > 0 {
 			e.trace.SetSecrets(secretEnvVars)
 		}
 	}
 
 	// Execute steps
 	result := e.executeSteps(ctx, e.rb.Steps, true)
+	if ctx.Err() == context.DeadlineExceeded && result.Status != "completed" {
+		result = &RunResult{
+			Outcome: &schema.Outcome{Category: schema.OutcomeTimedOut, Code: "run_timeout"},
+			Status:  "error",
+			Error:   fmt.Errorf("run timed out: %w", ctx.Err()),
+		}
+	}
 
 	duration := time.Since(e.startTime)
 	result.Duration = duration
@@ -271,8 +306,16 @@ func (e *Engine) Run(ctx context.Context) *RunResult {
 func (e *Engine) executeSteps(ctx context.Context, steps []schema.Step, requireEnd bool) *RunResult {
 	// retryCounts tracks how many times a backward next has jumped to each target
 	retryCounts := make(map[string]int)
+	// routed records steps whose on_failure has fired in this block
+	routed := make(map[string]bool)
 
 	for i := 0; i < len(steps); i++ {
+		// Stop at the next step once the run is cancelled or past its deadline
+		if err := ctx.Err(); err != nil {
+			return &RunResult{Status: "error", Error: err}
+		}
+		e.applyInjected()
+
 		step := steps[i]
 		stepID := step.ID
 		if stepID == "" {
@@ -302,6 +345,10 @@ func (e *Engine) executeSteps(ctx context.Context, steps []schema.Step, requireE
 		if step.ForEach != nil {
 			result := e.executeForEach(ctx, step, stepID)
 			if result != nil {
+				if j := e.routeFailure(ctx, steps, step, stepID, result, routed); j >= 0 {
+					i = j - 1
+					continue
+				}
 				return result
 			}
 			continue // for_each handled the step; proceed to next
@@ -310,12 +357,16 @@ func (e *Engine) executeSteps(ctx context.Context, steps []schema.Step, requireE
 		// Execute the step
 		result := e.executeStep(ctx, step, stepID)
 		if result != nil {
+			if j := e.routeFailure(ctx, steps, step, stepID, result, routed); j >= 0 {
+				i = j - 1 // -1 because loop will i++
+				continue
+			}
 			return result
 		}
 
 		// Handle `next` — jump to a different step
 		if step.Next != nil {
-			target, max, _, err := schema.ParseNext(step.Next)
+			target, max, err := e.selectNext(step.Next)
 			if err != nil {
 				return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
 			}
@@ -360,10 +411,91 @@ func (e *Engine) executeSteps(ctx context.Context, steps []schema.Step, requireE
 	return nil
 }
 
-// executeStep dispatches a single step by type.
+// selectNext picks the jump target of a step's next: the first entry whose
+// if holds or that has none. No match ("") continues to the following step.
+func (e *Engine) selectNext(raw any) (target string, max int, err error) {
+	targets, err := schema.ParseNextTargets(raw)
+	if err != nil {
+		return "", 0, err
+	}
+	for _, t := range targets {
+		if t.If != "" {
+			matched, err := eval.EvalBool(t.If, e.vars)
+			if err != nil {
+				return "", 0, fmt.Errorf("next condition %q: %w", t.If, err)
+			}
+			if !matched {
+				continue
+			}
+		}
+		return t.Step, t.Max, nil
+	}
+	return "", 0, nil
+}
+
+// executeStep runs a single step between its pre and post hooks, under the
+// step's timeout when it has one.
 // Returns nil to continue to next step, or a RunResult to terminate.
 func (e *Engine) executeStep(ctx context.Context, step schema.Step, stepID string) *RunResult {
+	pre, post := e.stepHooks(step)
+	if pre != "" {
+		e.runHook(ctx, "pre", pre, stepID, "running")
+	}
+	result := e.dispatchWithTimeout(ctx, step, stepID)
+	if post != "" {
+		e.runHook(ctx, "post", post, stepID, hookStatus(result))
+	}
+	return result
+}
+
+// dispatchWithTimeout runs dispatchStep with a deadline of the step's
+// timeout (or meta.defaults.timeout). A step cut short by the deadline fails
+// with a timeout error after emitting step_timeout.
+func (e *Engine) dispatchWithTimeout(ctx context.Context, step schema.Step, stepID string) *RunResult {
+	timeout := e.stepTimeout(step)
+	if timeout <= 0 {
+		return e.dispatchStep(ctx, step, stepID)
+	}
+	stepCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := e.dispatchStep(stepCtx, step, stepID)
+	if result == nil || result.Error == nil || stepCtx.Err() != context.DeadlineExceeded || ctx.Err() != nil {
+		return result
+	}
+	if e.trace != nil {
+		e.trace.Emit(trace.EventStepTimeout, map[string]any{
+			"step_id": stepID,
+			"timeout": timeout.String(),
+			"error":   result.Error.Error(),
+		})
+	}
+	return &RunResult{Status: "error", Error: fmt.Errorf("step %s: timed out after %s", stepID, timeout)}
+}
+
+// stepTimeout returns the step's timeout, falling back to
+// meta.defaults.timeout; zero means no deadline.
+func (e *Engine) stepTimeout(step schema.Step) time.Duration {
+	if step.Timeout != "" {
+		if d, err := time.ParseDuration(step.Timeout); err == nil {
+			return d
+		}
+	}
+	if e.rb.Meta.Defaults != nil && e.rb.Meta.Defaults.Timeout != "" {
+		if d, err := time.ParseDuration(e.rb.Meta.Defaults.Timeout); err == nil {
+			return d
+		}
+	}
+	return 0
+}
+
+// dispatchStep dispatches a single step by type.
+func (e *Engine) dispatchStep(ctx context.Context, step schema.Step, stepID string) *RunResult {
 	start := time.Now()
+	defer func() {
+		e.durations[stepID] = time.Since(start)
+		e.lastStep = stepID
+	}()
 
 	// Track visited steps for test harness
 	e.VisitedSteps = append(e.VisitedSteps, stepID)
@@ -381,20 +513,56 @@ func (e *Engine) executeStep(ctx context.Context, step schema.Step, stepID strin
 		e.vars["_scope"] = step.Scope
 	}
 
-	// --- Visibility: emit trace event if visibility is declared ---
-	if step.Visibility != nil && e.trace != nil {
-		visData := map[string]any{"step_id": stepID}
-		if len(step.Visibility.Allow) > 0 {
-			visData["allow"] = step.Visibility.Allow
+	// --- Visibility: hide filtered variables from this step and its sub-steps ---
+	var visView *visibilityView
+	if step.Visibility != nil {
+		var denied []string
+		visView, denied = e.applyVisibility(step.Visibility)
+		if e.trace != nil {
+			visData := map[string]any{"step_id": stepID, "denied": denied}
+			if len(step.Visibility.Allow) > 0 {
+				visData["allow"] = step.Visibility.Allow
+			}
+			if len(step.Visibility.Deny) > 0 {
+				visData["deny"] = step.Visibility.Deny
+			}
+			e.trace.Emit(trace.EventVisibilityApplied, visData)
 		}
-		if len(step.Visibility.Deny) > 0 {
-			visData["deny"] = step.Visibility.Deny
+	}
+	postStep := func() {
+		// Restore hidden variables first so export can promote them
+		if visView != nil {
+			e.restoreVisibility(visView)
 		}
-		e.trace.Emit(trace.EventVisibilityApplied, visData)
+		e.handlePostStep(step, stepID, scopeSnapshot)
 	}
 
 	// Resolve contract and evaluate governance for executable steps
 	resolvedContract := e.resolveContract(step)
+
+	// Probe mode: skip write-effect steps before governance so they never
+	// prompt for approval; read-only steps run for real.
+	if e.cfg.Mode == "probe" && resolvedContract != nil && resolvedContract.HasWriteEffects() &&
+		(step.Type == schema.StepTool || step.Type == schema.StepExtension) {
+		fmt.Fprintf(e.cfg.Stdout, "  [probe] SKIP %s (write-effect step)\n", stepID)
+		if e.trace != nil {
+			e.trace.EmitStepStart(stepID, string(step.Type), nil)
+			e.trace.EmitStepComplete(stepID, trace.StatusSkipped, nil, time.Since(start), &trace.Failure{
+				Kind: "probe", Message: ProbeSkipReason,
+			})
+		}
+		postStep()
+		return nil
+	}
+
+	// Host policy: block tool steps declaring effects it does not allow
+	if step.Type == schema.StepTool && resolvedContract != nil && e.cfg.GovernancePolicy != nil {
+		if blocked := e.enforceEffects(step, stepID, resolvedContract, start); blocked != nil {
+			postStep()
+			return blocked
+		}
+	}
+
 	if resolvedContract != nil {
 		// Emit contract_evaluated
 		if e.trace != nil {
@@ -402,7 +570,10 @@ func (e *Engine) executeStep(ctx context.Context, step schema.Step, stepID strin
 		}
 
 		// Evaluate governance
-		decision := governance.Evaluate(resolvedContract, e.rb.Meta.Governance)
+		decision := governance.EvaluateStep(step.Type, resolvedContract, e.rb.Meta.Governance)
+		if len(step.Env) > 0 {
+			decision = governance.EvaluateEnv(decision, envNames(step.Env), e.rb.Meta.Governance)
+		}
 		if e.trace != nil {
 			e.trace.EmitGovernanceDecision(stepID, string(decision.RiskLevel), string(decision.Action), decision.MinApprovers)
 		}
@@ -415,6 +586,7 @@ func (e *Engine) executeStep(ctx context.Context, step schema.Step, stepID strin
 					Kind: "denied", Message: "governance denied execution",
 				})
 			}
+			postStep()
 			return &RunResult{
 				Status: "failed",
 				Error:  fmt.Errorf("step %s: governance denied", stepID),
@@ -429,6 +601,7 @@ func (e *Engine) executeStep(ctx context.Context, step schema.Step, stepID strin
 						Kind: "denied", Message: "approval rejected",
 					})
 				}
+				postStep()
 				return &RunResult{
 					Status: "failed",
 					Error:  fmt.Errorf("step %s: approval rejected", stepID),
@@ -439,30 +612,40 @@ func (e *Engine) executeStep(ctx context.Context, step schema.Step, stepID strin
 
 	switch step.Type {
 	case schema.StepTool:
-		result := e.executeTool(ctx, step, stepID, start)
-		e.handlePostStep(step, stepID, scopeSnapshot)
+		result := e.executeWithRetry(ctx, step, stepID, func() *RunResult {
+			return e.executeTool(ctx, step, stepID, time.Now())
+		})
+		postStep()
 		return result
 	case schema.StepManual:
 		result := e.executeManual(ctx, step, stepID, start)
-		e.handlePostStep(step, stepID, scopeSnapshot)
+		postStep()
 		return result
 	case schema.StepAssert:
-		result := e.executeAssert(ctx, step, stepID, start)
-		e.handlePostStep(step, stepID, scopeSnapshot)
+		result := e.executeWithRetry(ctx, step, stepID, func() *RunResult {
+			return e.executeAssert(ctx, step, stepID, time.Now())
+		})
+		postStep()
 		return result
 	case schema.StepBranch:
-		result := e.executeBranch(ctx, step, stepID)
-		e.handlePostStep(step, stepID, scopeSnapshot)
+		result := e.executeWithRetry(ctx, step, stepID, func() *RunResult {
+			return e.executeBranch(ctx, step, stepID)
+		})
+		postStep()
 		return result
 	case schema.StepParallel:
-		result := e.executeParallel(ctx, step, stepID)
-		e.handlePostStep(step, stepID, scopeSnapshot)
+		result := e.executeWithRetry(ctx, step, stepID, func() *RunResult {
+			return e.executeParallel(ctx, step, stepID)
+		})
+		postStep()
 		return result
 	case schema.StepEnd:
 		return e.executeEnd(ctx, step, stepID, start)
 	case schema.StepExtension:
-		result := e.executeExtension(ctx, step, stepID, start)
-		e.handlePostStep(step, stepID, scopeSnapshot)
+		result := e.executeWithRetry(ctx, step, stepID, func() *RunResult {
+			return e.executeExtension(ctx, step, stepID, time.Now())
+		})
+		postStep()
 		return result
 	default:
 		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: unsupported type %q", stepID, step.Type)}
@@ -510,8 +693,21 @@ func (e *Engine) handlePostStep(step schema.Step, stepID string, scopeSnapshot m
 				exports[name] = val
 			}
 		}
+		stepOutputs, _ := e.vars[stepID].(map[string]any)
+		scope, err := eval.Resolve(step.Scope, e.vars)
+		if err != nil {
+			scope = step.Scope
+		}
 		// Restore pre-scope state
 		e.vars = scopeSnapshot
+		// Keep the step's outputs under <scope>.<step_id>.<name>
+		if stepID != "" {
+			for k, v := range stepOutputs {
+				key := strings.ReplaceAll(scope, "/", ".") + "." + stepID + "." + k
+				e.vars[key] = v
+				e.scoped.Store(key, struct{}{})
+			}
+		}
 		// Apply exports
 		for k, v := range exports {
 			e.vars[k] = v
@@ -535,6 +731,11 @@ func (e *Engine) executeTool(ctx context.Context, step schema.Step, stepID strin
 		e.emitStepError(stepID, start, "template", err.Error())
 		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
 	}
+	env, err := e.resolveEnv(step)
+	if err != nil {
+		e.emitStepError(stepID, start, "template", err.Error())
+		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+	}
 
 	// Dry-run and probe modes
 	if e.cfg.Mode == "dry-run" || e.cfg.Mode == "probe" {
@@ -542,21 +743,18 @@ func (e *Engine) executeTool(ctx context.Context, step schema.Step, stepID strin
 
 		c := e.resolveContract(step)
 
-		// In probe mode, skip steps with writes (non-read-only)
-		if isProbe && c != nil && len(c.Writes) > 0 {
-			fmt.Fprintf(e.cfg.Stdout, "  [probe] SKIP %s:%s (has writes)\n", step.Tool, step.Action)
-			if e.trace != nil {
-				e.trace.EmitStepStart(stepID, "tool", nil)
-				e.trace.EmitStepComplete(stepID, trace.StatusSkipped, nil, time.Since(start), nil)
-			}
-			return nil
-		}
-
 		// In probe mode, read-only tools fall through to actual execution below
+		// (write-effect steps were already skipped in executeStep)
 		if !isProbe {
 			fmt.Fprintf(e.cfg.Stdout, "  [dry-run] tool %s:%s\n", step.Tool, step.Action)
 			fmt.Fprintf(e.cfg.Stdout, "    inputs: %v\n", resolvedInputs)
+			if len(env) > 0 {
+				fmt.Fprintf(e.cfg.Stdout, "    env: %v\n", envNames(step.Env))
+			}
 			td := e.tools[step.Tool]
+			if e.cfg.CleanEnv {
+				fmt.Fprintf(e.cfg.Stdout, "    inherited env: %v\n", append(slices.Clone(executor.MinimalEnv), secretEnvNames(td)...))
+			}
 			if td != nil && len(td.Meta.Platform) > 0 {
 				fmt.Fprintf(e.cfg.Stdout, "    platform: %v\n", td.Meta.Platform)
 			}
@@ -605,11 +803,43 @@ func (e *Engine) executeTool(ctx context.Context, step schema.Step, stepID strin
 		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: tool %q not found", stepID, step.Tool)}
 	}
 
-	// Execute via tool executor (default or replay)
-	result, err := e.toolExec.Execute(ctx, td, step.Action, resolvedInputs, e.vars)
-	if err != nil {
-		e.emitStepError(stepID, start, "exec", err.Error())
-		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+	if r := e.checkInputs(step, stepID, resolvedInputs, start); r != nil {
+		return r
+	}
+
+	// Execute via tool executor (default or replay). A clean environment
+	// still passes the tool's declared secrets through.
+	if e.cfg.CleanEnv {
+		ctx = executor.WithCleanEnv(ctx)
+		for _, name := range secretEnvNames(td) {
+			if val, ok := os.LookupEnv(name); ok {
+				env = append(env, name+"="+val)
+			}
+		}
+	}
+	if len(env) > 0 {
+		ctx = executor.WithEnv(ctx, env)
+	}
+
+	// A cached result from an earlier real run stands in for execution
+	var result *executor.Result
+	cacheHash := ""
+	if e.useCache(step) {
+		if cacheHash, err = e.cacheKey(step); err != nil {
+			e.emitStepError(stepID, start, "template", err.Error())
+			return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+		}
+		result = e.cachedResult(step, stepID, cacheHash)
+	}
+	if result == nil {
+		result, err = e.toolExec.Execute(ctx, td, step.Action, resolvedInputs, e.vars)
+		if err != nil {
+			e.emitStepError(stepID, start, "exec", err.Error())
+			return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+		}
+		if cacheHash != "" && result.ExitCode == 0 {
+			e.storeCachedResult(step, stepID, cacheHash, result)
+		}
 	}
 
 	// Store outputs
@@ -688,31 +918,68 @@ func (e *Engine) executeManual(ctx context.Context, step schema.Step, stepID str
 		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
 	}
 
-	fmt.Fprintf(e.cfg.Stdout, "\n  [manual] %s\n", instructions)
-
-	if e.cfg.Mode == "dry-run" {
-		fmt.Fprintf(e.cfg.Stdout, "  (dry-run: skipping manual input)\n")
-		if e.trace != nil {
-			e.trace.EmitStepComplete(stepID, trace.StatusSuccess, nil, time.Since(start), nil)
+	if e.trace != nil {
+		evidence := make([]string, len(step.RequiredEvidence))
+		for i, ev := range step.RequiredEvidence {
+			evidence[i] = ev.Name
 		}
-		return nil
+		e.trace.Emit(trace.EventManualPrompt, map[string]any{
+			"step_id":      stepID,
+			"instructions": instructions,
+			"evidence":     evidence,
+		})
 	}
 
-	// Collect evidence
+	fmt.Fprintf(e.cfg.Stdout, "\n  [manual] %s\n", instructions)
+
+	// Collect evidence. dry-run and probe never wait for a person; each
+	// evidence entry gets a placeholder such as "<dry-run>" instead.
 	outputs := make(map[string]any)
-	for _, ev := range step.RequiredEvidence {
-		fmt.Fprintf(e.cfg.Stdout, "  [evidence] %s (%s): ", ev.Name, ev.Kind)
-		scanner := bufio.NewScanner(e.cfg.Stdin)
-		if scanner.Scan() {
-			outputs[ev.Name] = scanner.Text()
+	if e.cfg.Mode == "dry-run" || e.cfg.Mode == "probe" {
+		fmt.Fprintf(e.cfg.Stdout, "  (%s: skipping manual input)\n", e.cfg.Mode)
+		for _, ev := range step.RequiredEvidence {
+			outputs[ev.Name] = "<" + e.cfg.Mode + ">"
+		}
+	} else {
+		for _, ev := range step.RequiredEvidence {
+			fmt.Fprintf(e.cfg.Stdout, "  [evidence] %s (%s): ", ev.Name, ev.Kind)
+			line, ok, err := e.stdin.readLine(ctx)
+			if err != nil {
+				e.emitStepError(stepID, start, "timeout", err.Error())
+				return &RunResult{Status: "error", Error: fmt.Errorf("step %s: evidence %s: %w", stepID, ev.Name, err)}
+			}
+			if ok {
+				outputs[ev.Name] = line
+			}
+		}
+
+		// If no evidence or choice required, ask for confirmation
+		if len(step.RequiredEvidence) == 0 && step.Choices == nil {
+			fmt.Fprintf(e.cfg.Stdout, "  Press Enter to continue...")
+			if _, _, err := e.stdin.readLine(ctx); err != nil {
+				e.emitStepError(stepID, start, "timeout", err.Error())
+				return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+			}
 		}
 	}
 
-	// If no evidence required, ask for confirmation
-	if len(step.RequiredEvidence) == 0 {
-		fmt.Fprintf(e.cfg.Stdout, "  Press Enter to continue...")
-		scanner := bufio.NewScanner(e.cfg.Stdin)
-		scanner.Scan()
+	e.applyInjected()
+
+	if step.Choices != nil {
+		value, err := e.chooseOption(ctx, step.Choices)
+		if err != nil {
+			e.emitStepError(stepID, start, "choice", err.Error())
+			return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+		}
+		e.vars[step.Choices.Variable] = value
+		outputs[step.Choices.Variable] = value
+		if e.trace != nil {
+			e.trace.Emit(trace.EventManualChoice, map[string]any{
+				"step_id":  stepID,
+				"variable": step.Choices.Variable,
+				"value":    value,
+			})
+		}
 	}
 
 	if stepID != "" {
@@ -720,6 +987,10 @@ func (e *Engine) executeManual(ctx context.Context, step schema.Step, stepID str
 	}
 
 	if e.trace != nil {
+		e.trace.Emit(trace.EventManualComplete, map[string]any{
+			"step_id":  stepID,
+			"evidence": outputs,
+		})
 		e.trace.EmitStepComplete(stepID, trace.StatusSuccess, outputs, time.Since(start), nil)
 	}
 	return nil
@@ -795,93 +1066,60 @@ func (e *Engine) executeBranch(ctx context.Context, step schema.Step, stepID str
 // ---------------------------------------------------------------------------
 
 // executeParallel runs parallel branches concurrently with state isolation.
-// Contract conflicts cause serialization (sequential fallback).
+// Branches whose contracts conflict run one after another; the rest still
+// run concurrently.
 func (e *Engine) executeParallel(ctx context.Context, step schema.Step, stepID string) *RunResult {
 	if len(step.Branches) < 2 {
 		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: parallel requires at least 2 branches", stepID)}
 	}
 
 	// Compute per-branch aggregate contracts for conflict detection
-	type branchInfo struct {
-		index    int
-		label    string
-		contract contract.Contract
-	}
-
-	var branchInfos []branchInfo
+	contracts := make([]contract.Contract, len(step.Branches))
 	for i, br := range step.Branches {
 		var allReads, allWrites []string
 		walkBranchContracts(br.Steps, e, &allReads, &allWrites)
-		branchInfos = append(branchInfos, branchInfo{
-			index: i,
-			label: br.Label,
-			contract: contract.Contract{
-				Reads:  allReads,
-				Writes: allWrites,
-			},
-		})
-	}
-
-	// Build conflict graph — which pairs conflict?
-	conflictsWith := make(map[int]map[int]bool)
-	for i := 0; i < len(branchInfos); i++ {
-		for j := i + 1; j < len(branchInfos); j++ {
-			if contract.HasConflict(&branchInfos[i].contract, &branchInfos[j].contract) {
-				if conflictsWith[i] == nil {
-					conflictsWith[i] = make(map[int]bool)
-				}
-				if conflictsWith[j] == nil {
-					conflictsWith[j] = make(map[int]bool)
-				}
-				conflictsWith[i][j] = true
-				conflictsWith[j][i] = true
-			}
-		}
+		contracts[i] = contract.Contract{Reads: allReads, Writes: allWrites}
 	}
 
-	// If any conflicts exist, serialize all branches (simple strategy per §8)
-	hasConflicts := len(conflictsWith) > 0
+	// Group branches connected by conflicts — each group runs sequentially
+	groups := conflictGroups(contracts)
+	serialized := len(groups) < len(step.Branches)
 
 	// Emit parallel_fork
 	if e.trace != nil {
-		labels := make([]any, len(step.Branches))
+		labels := make([]string, len(step.Branches))
 		for i, br := range step.Branches {
 			labels[i] = br.Label
 		}
-		e.trace.Emit(trace.EventParallelFork, map[string]any{
-			"step_id":      stepID,
-			"branch_count": len(step.Branches),
-			"branches":     labels,
-			"serialized":   hasConflicts,
-		})
-	}
-
-	if hasConflicts {
-		// Serialized execution — run branches sequentially
-		return e.executeParallelSerialized(ctx, step, stepID)
+		e.trace.EmitParallelFork(stepID, labels, serialized)
 	}
 
-	// Concurrent execution — fork state per branch, run in goroutines
+	// Fork state per branch; groups run in goroutines, branches within a
+	// group in declaration order
 	results := make([]branchResult, len(step.Branches))
 	var wg sync.WaitGroup
 
-	for i, br := range step.Branches {
+	for _, group := range groups {
 		wg.Add(1)
-		go func(idx int, branch schema.Branch) {
+		go func(indexes []int) {
 			defer wg.Done()
-
-			// Fork state — each branch gets a snapshot
-			forkedVars := e.forkVars()
-			branchEngine := e.forkEngine(forkedVars)
-
-			res := branchEngine.executeSteps(ctx, branch.Steps, false)
-			results[idx] = branchResult{
-				index:   idx,
-				label:   branch.Label,
-				result:  res,
-				outputs: branchEngine.collectNewVars(e.vars),
+			for _, idx := range indexes {
+				branch := step.Branches[idx]
+
+				// Fork state — each branch gets a snapshot
+				forkedVars := e.forkVars()
+				branchEngine := e.forkEngine(forkedVars)
+
+				res := branchEngine.executeSteps(ctx, branch.Steps, false)
+				results[idx] = branchResult{
+					index:   idx,
+					label:   branch.Label,
+					result:  res,
+					outputs: branchEngine.collectNewVars(e.vars),
+					visited: branchEngine.VisitedSteps,
+				}
 			}
-		}(i, br)
+		}(group)
 	}
 
 	wg.Wait()
@@ -889,23 +1127,42 @@ func (e *Engine) executeParallel(ctx context.Context, step schema.Step, stepID s
 	return e.mergeParallelResults(stepID, results)
 }
 
-// executeParallelSerialized runs parallel branches sequentially due to conflicts.
-func (e *Engine) executeParallelSerialized(ctx context.Context, step schema.Step, stepID string) *RunResult {
-	results := make([]branchResult, len(step.Branches))
-	for i, br := range step.Branches {
-		forkedVars := e.forkVars()
-		branchEngine := e.forkEngine(forkedVars)
-
-		res := branchEngine.executeSteps(ctx, br.Steps, false)
-		results[i] = branchResult{
-			index:   i,
-			label:   br.Label,
-			result:  res,
-			outputs: branchEngine.collectNewVars(e.vars),
+// conflictGroups partitions branches into groups connected by read/write
+// conflicts, each listed in declaration order. Branches with no conflicts
+// form groups of one.
+func conflictGroups(contracts []contract.Contract) [][]int {
+	parent := make([]int, len(contracts))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	for i := 0; i < len(contracts); i++ {
+		for j := i + 1; j < len(contracts); j++ {
+			if contract.HasConflict(&contracts[i], &contracts[j]) {
+				parent[find(j)] = find(i)
+			}
 		}
 	}
 
-	return e.mergeParallelResults(stepID, results)
+	var groups [][]int
+	byRoot := make(map[int]int) // root → index into groups
+	for i := range contracts {
+		root := find(i)
+		g, ok := byRoot[root]
+		if !ok {
+			g = len(groups)
+			byRoot[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
 }
 
 type branchResult struct {
@@ -913,6 +1170,7 @@ type branchResult struct {
 	label   string
 	result  *RunResult
 	outputs map[string]any
+	visited []string
 }
 
 // mergeParallelResults merges branch results in declaration order.
@@ -922,6 +1180,8 @@ func (e *Engine) mergeParallelResults(stepID string, results []branchResult) *Ru
 	branchOutcomes := make([]map[string]any, len(results))
 
 	for i, br := range results {
+		e.VisitedSteps = append(e.VisitedSteps, br.visited...)
+
 		status := "success"
 		if br.result != nil {
 			status = br.result.Status
@@ -987,7 +1247,11 @@ func (e *Engine) forkEngine(vars map[string]any) *Engine {
 		trace:     e.trace,
 		tools:     e.tools,
 		toolExec:  e.toolExec,
+		stdin:     e.stdin,
 		startTime: e.startTime,
+		scoped:    e.scoped,
+		durations: maps.Clone(e.durations),
+		lastStep:  e.lastStep,
 	}
 }
 
@@ -1007,7 +1271,10 @@ func (e *Engine) executeRepeat(ctx context.Context, step schema.Step, stepID str
 		})
 	}
 
+	iterations, stoppedBy := 0, "max"
 	for i := 0; i < rep.Max; i++ {
+		iterations++
+
 		// Set iteration vars
 		e.vars["repeat"] = map[string]any{
 			"index": i,
@@ -1024,25 +1291,46 @@ func (e *Engine) executeRepeat(ctx context.Context, step schema.Step, stepID str
 		// Execute the repeat's inner steps
 		result := e.executeSteps(ctx, rep.Steps, false)
 		if result != nil && (result.Status == "failed" || result.Status == "error") {
+			e.emitRepeatComplete(stepID, iterations, result.Status)
 			return result
 		}
 		// If inner steps produced an outcome (end step), propagate it
 		if result != nil && result.Outcome != nil {
+			e.emitRepeatComplete(stepID, iterations, "outcome")
 			return result
 		}
 
 		// Check until condition
 		if rep.Until != "" {
 			val, err := eval.EvalBool(rep.Until, e.vars)
-			if err == nil && val {
+			if err != nil {
+				e.emitRepeatComplete(stepID, iterations, "error")
+				return &RunResult{Status: "error", Error: fmt.Errorf("step %s: repeat.until: %w", stepID, err)}
+			}
+			if val {
+				stoppedBy = "until"
 				break
 			}
 		}
 	}
 
+	e.emitRepeatComplete(stepID, iterations, stoppedBy)
 	return nil
 }
 
+// emitRepeatComplete records how many iterations a repeat block ran and
+// why it stopped: "until", "max", "outcome", "failed" or "error".
+func (e *Engine) emitRepeatComplete(stepID string, iterations int, stoppedBy string) {
+	if e.trace == nil {
+		return
+	}
+	e.trace.Emit(trace.EventRepeatComplete, map[string]any{
+		"step_id":    stepID,
+		"iterations": iterations,
+		"stopped_by": stoppedBy,
+	})
+}
+
 // collectNewVars returns variables that were added or changed relative to parentVars.
 func (e *Engine) collectNewVars(parentVars map[string]any) map[string]any {
 	new_ := make(map[string]any)
@@ -1131,7 +1419,7 @@ func (e *Engine) executeForEach(ctx context.Context, step schema.Step, stepID st
 	innerStep.ForEach = nil
 
 	if fe.Parallel {
-		return e.executeForEachParallel(ctx, innerStep, stepID, fe.As, fe.Key, items)
+		return e.executeForEachParallel(ctx, innerStep, stepID, fe.As, fe.Key, fe.MaxParallel, items)
 	}
 	return e.executeForEachSequential(ctx, innerStep, stepID, fe.As, fe.Key, items)
 }
@@ -1165,6 +1453,8 @@ func (e *Engine) executeForEachSequential(ctx context.Context, step schema.Step,
 					return &RunResult{Status: "error", Error: fmt.Errorf("step %s: for_each key: %w", stepID, err)}
 				}
 				if _, exists := accumulatedMap[key]; exists {
+					e.emitKeyCollision(stepID, key, i)
+					e.vars[stepID] = accumulatedMap
 					return &RunResult{Status: "error", Error: fmt.Errorf("step %s: for_each key %q duplicated", stepID, key)}
 				}
 				accumulatedMap[key] = outputs
@@ -1193,20 +1483,51 @@ func (e *Engine) executeForEachSequential(ctx context.Context, step schema.Step,
 }
 
 // executeForEachParallel runs the step once per item, concurrently.
-func (e *Engine) executeForEachParallel(ctx context.Context, step schema.Step, stepID, asVar, keyExpr string, items []any) *RunResult {
+// maxParallel > 0 caps how many iterations run at once. With a key
+// expression, each iteration's key is resolved against its own item and
+// outputs are merged into a map in declaration order. The first iteration
+// to fail cancels the others and iterations not yet started are skipped;
+// its result is the step's result.
+func (e *Engine) executeForEachParallel(ctx context.Context, step schema.Step, stepID, asVar, keyExpr string, maxParallel int, items []any) *RunResult {
 	type iterResult struct {
 		index   int
 		result  *RunResult
 		outputs any
+		key     string
+		keyErr  error
+		visited []string
 	}
+	useMap := keyExpr != ""
 
 	results := make([]iterResult, len(items))
 	var wg sync.WaitGroup
 
+	iterCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var failOnce sync.Once
+	var firstFailure *RunResult
+
+	var sem chan struct{}
+	if maxParallel > 0 {
+		sem = make(chan struct{}, maxParallel)
+	}
+
 	for i, item := range items {
+		if sem != nil {
+			select {
+			case sem <- struct{}{}:
+			case <-iterCtx.Done():
+			}
+		}
+		if iterCtx.Err() != nil {
+			break
+		}
 		wg.Add(1)
 		go func(idx int, itemVal any) {
 			defer wg.Done()
+			if sem != nil {
+				defer func() { <-sem }()
+			}
 
 			// Fork state for isolation
 			forkedVars := e.forkVars()
@@ -1224,41 +1545,88 @@ func (e *Engine) executeForEachParallel(ctx context.Context, step schema.Step, s
 				})
 			}
 
-			res := iterEngine.executeStep(ctx, step, iterID)
-			var outputs any
+			res := iterEngine.executeStep(iterCtx, step, iterID)
+			if res != nil && (res.Status == "failed" || res.Status == "error") {
+				failOnce.Do(func() {
+					firstFailure = res
+					cancel()
+				})
+			}
+			ir := iterResult{index: idx, result: res, visited: iterEngine.VisitedSteps}
 			if val, ok := iterEngine.vars[iterID]; ok {
-				outputs = val
+				ir.outputs = val
+				if useMap {
+					ir.key, ir.keyErr = eval.Resolve(keyExpr, iterEngine.vars)
+				}
 			}
 
-			results[idx] = iterResult{index: idx, result: res, outputs: outputs}
+			results[idx] = ir
 		}(i, item)
 	}
 
 	wg.Wait()
 
 	// Merge results in declaration order (deterministic)
+	for _, ir := range results {
+		e.VisitedSteps = append(e.VisitedSteps, ir.visited...)
+	}
 	accumulated := make([]any, 0, len(items))
+	accumulatedMap := make(map[string]any)
+	store := func() {
+		if useMap {
+			e.vars[stepID] = accumulatedMap
+		} else {
+			e.vars[stepID] = accumulated
+		}
+	}
 	for _, ir := range results {
 		if ir.outputs != nil {
-			accumulated = append(accumulated, ir.outputs)
+			if !useMap {
+				accumulated = append(accumulated, ir.outputs)
+			} else if ir.keyErr != nil {
+				store()
+				return &RunResult{Status: "error", Error: fmt.Errorf("step %s: for_each key: %w", stepID, ir.keyErr)}
+			} else if _, exists := accumulatedMap[ir.key]; exists {
+				e.emitKeyCollision(stepID, ir.key, ir.index)
+				store()
+				return &RunResult{Status: "error", Error: fmt.Errorf("step %s: for_each key %q duplicated", stepID, ir.key)}
+			} else {
+				accumulatedMap[ir.key] = ir.outputs
+			}
 		}
 		if ir.result != nil && (ir.result.Status == "failed" || ir.result.Status == "error") {
-			// Store partial accumulation
-			e.vars[stepID] = accumulated
+			// Store partial accumulation; iterations cancelled by the
+			// first failure report that failure, not the cancellation
+			store()
+			if firstFailure != nil {
+				return firstFailure
+			}
 			return ir.result
 		}
 		// If an iteration returned an outcome (end step), propagate it
 		if ir.result != nil && ir.result.Outcome != nil {
-			e.vars[stepID] = accumulated
+			store()
 			return ir.result
 		}
 	}
 
 	// Store accumulated results
-	e.vars[stepID] = accumulated
+	store()
 	return nil
 }
 
+// emitKeyCollision records a for_each iteration whose key was already
+// produced by an earlier item.
+func (e *Engine) emitKeyCollision(stepID, key string, index int) {
+	if e.trace != nil {
+		e.trace.Emit(trace.EventForEachKeyCollision, map[string]any{
+			"step_id": stepID,
+			"key":     key,
+			"index":   index,
+		})
+	}
+}
+
 func (e *Engine) executeEnd(ctx context.Context, step schema.Step, stepID string, start time.Time) *RunResult {
 	if e.trace != nil {
 		e.trace.EmitStepStart(stepID, "end", nil)
@@ -1292,24 +1660,272 @@ func (e *Engine) executeEnd(ctx context.Context, step schema.Step, stepID string
 }
 
 func (e *Engine) executeExtension(ctx context.Context, step schema.Step, stepID string, start time.Time) *RunResult {
-	// Extensions are dispatched to external executors — out of scope for Phase 3.
-	// For now, emit a trace event and skip.
 	if e.trace != nil {
 		e.trace.EmitStepStart(stepID, "extension", nil)
-		e.trace.EmitStepComplete(stepID, trace.StatusSkipped, nil, time.Since(start), &trace.Failure{
-			Kind: "not_implemented", Message: "extension execution not yet implemented",
-		})
 	}
-	if step.ContinueOnFail {
+
+	c := step.Contract
+	if c == nil || c.Binary == "" {
+		e.emitStepError(stepID, start, "extension", "extension contract declares no binary")
+		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: extension contract declares no binary", stepID)}
+	}
+
+	resolvedInputs, err := e.resolveInputs(step)
+	if err != nil {
+		e.emitStepError(stepID, start, "template", err.Error())
+		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+	}
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		if args[i], err = eval.Resolve(a, e.vars); err != nil {
+			e.emitStepError(stepID, start, "template", err.Error())
+			return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+		}
+	}
+	binary := c.Binary
+	if strings.ContainsAny(binary, `/\`) && !filepath.IsAbs(binary) && e.cfg.BaseDir != "" {
+		binary = filepath.Join(e.cfg.BaseDir, binary)
+	}
+
+	// Dry-run prints the plugin invocation; probe mode only reaches here for
+	// read-only extensions, which run for real.
+	if e.cfg.Mode == "dry-run" {
+		fmt.Fprintf(e.cfg.Stdout, "  [dry-run] extension %s: %s %v\n", step.Extension, binary, args)
+		fmt.Fprintf(e.cfg.Stdout, "    inputs: %v\n", resolvedInputs)
+		if e.trace != nil {
+			e.trace.EmitStepComplete(stepID, trace.StatusSkipped, resolvedInputs, time.Since(start), nil)
+		}
 		return nil
 	}
-	return &RunResult{Status: "error", Error: fmt.Errorf("step %s: extension execution not yet implemented", stepID)}
+
+	if e.trace != nil {
+		e.trace.Emit(trace.EventExtensionStarted, map[string]any{
+			"step_id":   stepID,
+			"extension": step.Extension,
+			"binary":    binary,
+		})
+	}
+	result, err := e.invokeExtension(ctx, step, binary, args, resolvedInputs)
+	if e.trace != nil {
+		data := map[string]any{
+			"step_id":     stepID,
+			"extension":   step.Extension,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}
+		if result != nil {
+			data["exit_code"] = result.ExitCode
+		}
+		if err != nil {
+			data["error"] = err.Error()
+		}
+		e.trace.Emit(trace.EventExtensionCompleted, data)
+	}
+	if err != nil {
+		e.emitStepError(stepID, start, "exec", err.Error())
+		return &RunResult{Status: "error", Error: fmt.Errorf("step %s: %w", stepID, err)}
+	}
+
+	// Keep declared outputs only, and check them against their types
+	outputs := result.Outputs
+	if c.Outputs != nil {
+		outputs = make(map[string]any)
+		for k, v := range result.Outputs {
+			if _, declared := c.Outputs[k]; declared {
+				outputs[k] = v
+			} else if e.trace != nil {
+				e.trace.Emit(trace.EventContractViolation, map[string]any{
+					"step_id": stepID,
+					"kind":    "undeclared_output",
+					"field":   k,
+					"message": fmt.Sprintf("output %q not declared in contract", k),
+				})
+			}
+		}
+		var problems []string
+		for k, param := range c.Outputs {
+			v, present := outputs[k]
+			switch {
+			case !present && param.Required:
+				problems = append(problems, fmt.Sprintf("declared output %q missing from result", k))
+			case present && !contract.CheckType(param.Type, v):
+				problems = append(problems, fmt.Sprintf("output %q is %T, contract declares %s", k, v, param.Type))
+			}
+		}
+		if len(problems) > 0 {
+			sort.Strings(problems)
+			msg := strings.Join(problems, "; ")
+			if e.trace != nil {
+				e.trace.EmitStepComplete(stepID, trace.StatusFailed, outputs, time.Since(start), &trace.Failure{
+					Kind: "contract", Message: msg,
+				})
+			}
+			if step.ContinueOnFail {
+				return nil
+			}
+			return &RunResult{Status: "failed", Error: fmt.Errorf("step %s: %s", stepID, msg)}
+		}
+	}
+
+	for k, v := range outputs {
+		e.vars[k] = v
+	}
+	if stepID != "" {
+		e.vars[stepID] = outputs
+	}
+
+	if result.ExitCode != 0 {
+		if e.trace != nil {
+			e.trace.EmitStepComplete(stepID, trace.StatusFailed, outputs, time.Since(start), &trace.Failure{
+				Kind: "exit_code", Message: fmt.Sprintf("exit code %d", result.ExitCode),
+			})
+		}
+		if step.ContinueOnFail {
+			return nil
+		}
+		return &RunResult{Status: "failed", Error: fmt.Errorf("step %s: extension exited with code %d", stepID, result.ExitCode)}
+	}
+
+	if e.trace != nil {
+		e.trace.EmitStepComplete(stepID, trace.StatusSuccess, outputs, time.Since(start), nil)
+	}
+	return nil
+}
+
+// invokeExtension spawns the extension binary, sends one execute request
+// with the step inputs and shuts the process down again.
+func (e *Engine) invokeExtension(ctx context.Context, step schema.Step, binary string, args []string, inputs map[string]any) (*executor.Result, error) {
+	runner := executor.NewExtensionRunner(binary, args...)
+	if err := runner.Start(ctx); err != nil {
+		return nil, fmt.Errorf("extension %s: %w", step.Extension, err)
+	}
+	result, err := runner.Execute(ctx, inputs, e.vars, map[string]any{
+		"extension": step.Extension,
+		"outputs":   step.Contract.Outputs,
+	})
+	if shutdownErr := runner.Shutdown(ctx); err == nil && shutdownErr != nil {
+		err = fmt.Errorf("shutdown: %w", shutdownErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("extension %s: %w", step.Extension, err)
+	}
+	return result, nil
 }
 
 // ---------------------------------------------------------------------------
 // Helpers
 // ---------------------------------------------------------------------------
 
+// checkInputs validates resolved tool inputs against the contract's
+// declared inputs: unknown keys, missing required inputs without a default,
+// and values of the wrong type. Each mismatch emits contract_violation
+// (kind input_mismatch). With StrictContracts the step fails unexecuted;
+// otherwise the mismatches are printed as warnings and the step runs.
+func (e *Engine) checkInputs(step schema.Step, stepID string, inputs map[string]any, start time.Time) *RunResult {
+	c := e.resolveContract(step)
+	if c == nil || c.Inputs == nil {
+		return nil
+	}
+	problems := make(map[string]string) // input name → mismatch
+	for k, v := range inputs {
+		param, declared := c.Inputs[k]
+		switch {
+		case !declared:
+			problems[k] = fmt.Sprintf("input %q not declared in contract", k)
+		case !inputMatchesType(param.Type, v):
+			problems[k] = fmt.Sprintf("input %q is %T, contract declares %s", k, v, param.Type)
+		}
+	}
+	for k, param := range c.Inputs {
+		if _, present := inputs[k]; !present && param.Required && param.Default == nil {
+			problems[k] = fmt.Sprintf("required input %q not provided", k)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	fields := slices.Sorted(maps.Keys(problems))
+	msgs := make([]string, len(fields))
+	for i, k := range fields {
+		msgs[i] = problems[k]
+		if e.trace != nil {
+			e.trace.Emit(trace.EventContractViolation, map[string]any{
+				"step_id": stepID,
+				"kind":    "input_mismatch",
+				"field":   k,
+				"message": problems[k],
+			})
+		}
+	}
+	msg := strings.Join(msgs, "; ")
+	if !e.cfg.StrictContracts {
+		fmt.Fprintf(e.cfg.Stdout, "  [contract] WARN %s: %s\n", stepID, msg)
+		return nil
+	}
+	if e.trace != nil {
+		e.trace.EmitStepComplete(stepID, trace.StatusFailed, nil, time.Since(start), &trace.Failure{
+			Kind: "contract_violation", Message: msg,
+		})
+	}
+	if step.ContinueOnFail {
+		return nil
+	}
+	return &RunResult{Status: "failed", Error: fmt.Errorf("step %s: %s", stepID, msg)}
+}
+
+// inputMatchesType reports whether a resolved input matches its declared
+// type. Templates always render to strings, so a string that parses as the
+// declared int, number or bool also matches.
+func inputMatchesType(typ string, v any) bool {
+	if contract.CheckType(typ, v) {
+		return true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return false
+	}
+	var err error
+	switch typ {
+	case "int", "integer":
+		_, err = strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	case "number", "float":
+		_, err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+	case "bool", "boolean":
+		_, err = strconv.ParseBool(strings.TrimSpace(s))
+	default:
+		return false
+	}
+	return err == nil
+}
+
+// enforceEffects checks c against the host policy's allowed effects. Each
+// disallowed effect emits contract_violation and the step fails unexecuted.
+func (e *Engine) enforceEffects(step schema.Step, stepID string, c *contract.Contract, start time.Time) *RunResult {
+	violations := contract.NewEnforcer(e.cfg.GovernancePolicy.AllowedEffects).Violations(c)
+	if len(violations) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("effects not allowed by policy: %s", strings.Join(violations, ", "))
+	fmt.Fprintf(e.cfg.Stdout, "  [policy] BLOCK %s (%s)\n", stepID, msg)
+	if e.trace != nil {
+		e.trace.EmitStepStart(stepID, string(step.Type), nil)
+		for _, eff := range violations {
+			e.trace.Emit(trace.EventContractViolation, map[string]any{
+				"step_id": stepID,
+				"kind":    "effect_not_allowed",
+				"field":   eff,
+				"message": fmt.Sprintf("effect %q not allowed by governance policy", eff),
+			})
+		}
+		e.trace.EmitStepComplete(stepID, trace.StatusFailed, nil, time.Since(start), &trace.Failure{
+			Kind: "contract_violation", Message: msg,
+		})
+	}
+	return &RunResult{
+		Status: "failed",
+		Error:  fmt.Errorf("step %s: %s", stepID, msg),
+	}
+}
+
 func (e *Engine) resolveContract(step schema.Step) *contract.Contract {
 	switch step.Type {
 	case schema.StepTool:
@@ -1375,6 +1991,43 @@ func (e *Engine) resolveInputs(step schema.Step) (map[string]any, error) {
 	return eval.ResolveMap(resolved, e.vars)
 }
 
+// resolveEnv resolves the templates in step.env into sorted KEY=value entries.
+func (e *Engine) resolveEnv(step schema.Step) ([]string, error) {
+	env := make([]string, 0, len(step.Env))
+	for _, name := range envNames(step.Env) {
+		val, err := eval.Resolve(step.Env[name], e.vars)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", name, err)
+		}
+		env = append(env, name+"="+val)
+	}
+	return env, nil
+}
+
+// secretEnvNames lists the environment variables a tool declares as
+// secrets. td may be nil.
+func secretEnvNames(td *schema.ToolDefinition) []string {
+	if td == nil {
+		return nil
+	}
+	var names []string
+	for _, s := range td.Meta.Secrets {
+		if s.Env != "" {
+			names = append(names, s.Env)
+		}
+	}
+	return names
+}
+
+func envNames(env map[string]string) []string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func normalizeInputsFrom(raw any) []string {
 	switch v := raw.(type) {
 	case string:
@@ -1393,66 +2046,153 @@ func normalizeInputsFrom(raw any) []string {
 	return nil
 }
 
+// evaluateAssertion reports whether an assertion passes and, when it does
+// not, why. not: inverts the check, but an assertion that cannot be
+// evaluated (a template, pattern or path error) fails either way.
 func (e *Engine) evaluateAssertion(a schema.Assertion) (bool, string) {
-	switch a.Type {
-	case "equals":
-		val, err := eval.Resolve(a.Value, e.vars)
-		if err != nil {
-			return false, fmt.Sprintf("value template: %s", err)
-		}
-		exp, err := eval.Resolve(a.Expected, e.vars)
+	c := e.checkAssertion(a)
+	switch {
+	case c.err != "":
+		return false, c.err
+	case a.Not && c.holds:
+		return false, c.negated
+	case a.Not != c.holds:
+		return true, ""
+	default:
+		return false, c.failure
+	}
+}
+
+// assertionCheck is the outcome of an assertion's check before not: is
+// applied.
+type assertionCheck struct {
+	holds   bool
+	failure string // why the check does not hold
+	negated string // why it fails under not: — the check holds
+	err     string // the assertion could not be evaluated
+}
+
+func (e *Engine) checkAssertion(a schema.Assertion) assertionCheck {
+	resolve := func(field, tmpl string) (string, string) {
+		v, err := eval.Resolve(tmpl, e.vars)
 		if err != nil {
-			return false, fmt.Sprintf("expected template: %s", err)
+			return "", fmt.Sprintf("%s template: %s", field, err)
 		}
-		if val != exp {
-			return false, fmt.Sprintf("expected %q, got %q", exp, val)
+		return v, ""
+	}
+
+	switch a.Type {
+	case "equals", "not_equals", "contains", "json_path":
+		val, errMsg := resolve("value", a.Value)
+		if errMsg != "" {
+			return assertionCheck{err: errMsg}
+		}
+		exp, errMsg := resolve("expected", a.Expected)
+		if errMsg != "" {
+			return assertionCheck{err: errMsg}
+		}
+		switch a.Type {
+		case "equals":
+			return assertionCheck{
+				holds:   val == exp,
+				failure: fmt.Sprintf("expected %q, got %q", exp, val),
+				negated: fmt.Sprintf("expected %q to NOT equal %q, got %q", a.Value, exp, val),
+			}
+		case "not_equals":
+			return assertionCheck{
+				holds:   val != exp,
+				failure: fmt.Sprintf("expected not %q, got %q", exp, val),
+				negated: fmt.Sprintf("expected %q to equal %q, got %q", a.Value, exp, val),
+			}
+		case "contains":
+			return assertionCheck{
+				holds:   strings.Contains(val, exp),
+				failure: fmt.Sprintf("%q does not contain %q", val, exp),
+				negated: fmt.Sprintf("%q contains %q", val, exp),
+			}
+		default: // json_path
+			got, err := eval.JSONPathString(val, a.Path)
+			if err != nil {
+				return assertionCheck{err: fmt.Sprintf("json_path %s: %s", a.Path, err)}
+			}
+			return assertionCheck{
+				holds:   got == exp,
+				failure: fmt.Sprintf("json_path %s: expected %q, got %q", a.Path, exp, got),
+				negated: fmt.Sprintf("json_path %s: expected not %q, got %q", a.Path, exp, got),
+			}
 		}
-		return true, ""
 
-	case "not_equals":
-		val, err := eval.Resolve(a.Value, e.vars)
-		if err != nil {
-			return false, fmt.Sprintf("value template: %s", err)
+	case "contains_all", "contains_none":
+		val, errMsg := resolve("value", a.Value)
+		if errMsg != "" {
+			return assertionCheck{err: errMsg}
 		}
-		exp, err := eval.Resolve(a.Expected, e.vars)
-		if err != nil {
-			return false, fmt.Sprintf("expected template: %s", err)
+		var present, absent []string
+		for i, tmpl := range a.Values {
+			v, errMsg := resolve(fmt.Sprintf("values[%d]", i), tmpl)
+			if errMsg != "" {
+				return assertionCheck{err: errMsg}
+			}
+			if strings.Contains(val, v) {
+				present = append(present, v)
+			} else {
+				absent = append(absent, v)
+			}
 		}
-		if val == exp {
-			return false, fmt.Sprintf("expected not %q, got %q", exp, val)
+		all := strings.Join(a.Values, ", ")
+		if a.Type == "contains_all" {
+			return assertionCheck{
+				holds:   len(absent) == 0,
+				failure: fmt.Sprintf("expected %q to contain all of [%s] but missing: [%s]", val, all, strings.Join(absent, ", ")),
+				negated: fmt.Sprintf("expected %q to NOT contain all of [%s]", val, all),
+			}
+		}
+		return assertionCheck{
+			holds:   len(present) == 0,
+			failure: fmt.Sprintf("expected %q to contain none of [%s] but found: [%s]", val, all, strings.Join(present, ", ")),
+			negated: fmt.Sprintf("expected %q to contain some of [%s]", val, all),
 		}
-		return true, ""
 
-	case "contains":
-		val, err := eval.Resolve(a.Value, e.vars)
-		if err != nil {
-			return false, fmt.Sprintf("value template: %s", err)
+	case "matches":
+		val, errMsg := resolve("value", a.Value)
+		if errMsg != "" {
+			return assertionCheck{err: errMsg}
 		}
-		exp, err := eval.Resolve(a.Expected, e.vars)
+		matched, err := matchPattern(a.Pattern, val)
 		if err != nil {
-			return false, fmt.Sprintf("expected template: %s", err)
+			return assertionCheck{err: fmt.Sprintf("pattern: %s", err)}
 		}
-		if !strings.Contains(val, exp) {
-			return false, fmt.Sprintf("%q does not contain %q", val, exp)
+		return assertionCheck{
+			holds:   matched,
+			failure: fmt.Sprintf("%q does not match pattern %q", val, a.Pattern),
+			negated: fmt.Sprintf("%q matches pattern %q", val, a.Pattern),
 		}
-		return true, ""
 
-	case "matches":
-		val, err := eval.Resolve(a.Value, e.vars)
-		if err != nil {
-			return false, fmt.Sprintf("value template: %s", err)
+	case "duration_less_than":
+		target := a.Step
+		if target == "" {
+			target = e.lastStep
 		}
-		matched, err := matchPattern(a.Pattern, val)
+		took, ok := e.durations[target]
+		if !ok {
+			return assertionCheck{err: fmt.Sprintf("duration_less_than: step %q has not run", target)}
+		}
+		exp, errMsg := resolve("expected", a.Expected)
+		if errMsg != "" {
+			return assertionCheck{err: errMsg}
+		}
+		limit, err := time.ParseDuration(exp)
 		if err != nil {
-			return false, fmt.Sprintf("pattern: %s", err)
+			return assertionCheck{err: fmt.Sprintf("duration_less_than: %s", err)}
 		}
-		if !matched {
-			return false, fmt.Sprintf("%q does not match pattern %q", val, a.Pattern)
+		return assertionCheck{
+			holds:   took <= limit,
+			failure: fmt.Sprintf("step %s took %s, want <= %s", target, took.Round(time.Millisecond), limit),
+			negated: fmt.Sprintf("step %s took %s, want > %s", target, took.Round(time.Millisecond), limit),
 		}
-		return true, ""
 
 	default:
-		return false, fmt.Sprintf("unknown assertion type %q", a.Type)
+		return assertionCheck{err: fmt.Sprintf("unknown assertion type %q", a.Type)}
 	}
 }
 
@@ -1464,11 +2204,96 @@ func matchPattern(pattern, value string) (bool, error) {
 	return re.MatchString(value), nil
 }
 
+// requestApproval asks the approval provider to approve a step that
+// governance gated with require-approval, and reports whether enough
+// approvers said yes. The step-level outcome is traced as approval_requested
+// followed by approval_granted or approval_denied. Replay approves without
+// asking; dry-run denies without asking, since nobody is there to approve.
 func (e *Engine) requestApproval(ctx context.Context, stepID string, decision governance.Decision) bool {
-	if e.cfg.Mode == "dry-run" || e.cfg.Mode == "replay" {
+	if e.cfg.Mode == "replay" {
+		return true
+	}
+
+	minApprovers := decision.MinApprovers
+	if minApprovers < 1 {
+		minApprovers = 1
+	}
+	if e.trace != nil {
+		e.trace.Emit(trace.EventApprovalRequested, map[string]any{
+			"step_id":       stepID,
+			"risk_level":    string(decision.RiskLevel),
+			"min_approvers": minApprovers,
+			"rule":          decision.MatchedRule,
+		})
+	}
+
+	if e.cfg.Mode == "dry-run" {
+		fmt.Fprintf(e.cfg.Stdout, "  [dry-run] %s requires approval (min=%d) — denied\n", stepID, minApprovers)
+		e.emitApprovalResult(stepID, false, 0, "dry-run")
+		return false
+	}
+
+	if len(e.cfg.ActorGroup) >= minApprovers {
+		fmt.Fprintf(e.cfg.Stdout, "  %s approved by %s\n", stepID, strings.Join(e.cfg.ActorGroup, ", "))
+		e.recordGroupApprovals(stepID, minApprovers)
+		e.emitApprovalResult(stepID, true, len(e.cfg.ActorGroup), "")
 		return true
 	}
 
+	approvals, reason := e.collectApprovals(ctx, stepID, decision, minApprovers)
+	approved := approvals >= minApprovers
+	e.emitApprovalResult(stepID, approved, approvals, reason)
+	return approved
+}
+
+// recordGroupApprovals traces one approval_recorded event per member of
+// the --as-group actor group, which approves in place of prompting.
+func (e *Engine) recordGroupApprovals(stepID string, minApprovers int) {
+	if e.trace == nil {
+		return
+	}
+	for i, actor := range e.cfg.ActorGroup {
+		e.trace.Emit(trace.EventApprovalRecorded, map[string]any{
+			"step_id":      stepID,
+			"approver_id":  actor,
+			"approver_num": i + 1,
+			"of_required":  minApprovers,
+		})
+	}
+}
+
+// actor returns the identity the run is attributed to: the actor, or the
+// members of the actor group joined by commas.
+func (e *Engine) actor() string {
+	if e.cfg.Actor != "" {
+		return e.cfg.Actor
+	}
+	return strings.Join(e.cfg.ActorGroup, ",")
+}
+
+// emitApprovalResult traces approval_granted or approval_denied.
+func (e *Engine) emitApprovalResult(stepID string, approved bool, approvals int, reason string) {
+	if e.trace == nil {
+		return
+	}
+	if approved {
+		e.trace.Emit(trace.EventApprovalGranted, map[string]any{
+			"step_id":   stepID,
+			"approvals": approvals,
+		})
+		return
+	}
+	e.trace.Emit(trace.EventApprovalDenied, map[string]any{
+		"step_id":   stepID,
+		"approvals": approvals,
+		"reason":    reason,
+	})
+}
+
+// collectApprovals submits the approval ticket and waits for up to
+// minApprovers responses, stopping at the first rejection. It returns the
+// number of approvals and, when short, why.
+func (e *Engine) collectApprovals(ctx context.Context, stepID string, decision governance.Decision, minApprovers int) (int, string) {
 	req := ApprovalRequest{
 		RunID:        e.cfg.RunID,
 		StepID:       stepID,
@@ -1479,7 +2304,7 @@ func (e *Engine) requestApproval(ctx context.Context, stepID string, decision go
 
 	ticket, err := e.approval.Submit(ctx, req)
 	if err != nil {
-		return false
+		return 0, err.Error()
 	}
 
 	// Emit approval_submitted trace event
@@ -1492,16 +2317,11 @@ func (e *Engine) requestApproval(ctx context.Context, stepID string, decision go
 	}
 
 	// Collect required number of approvals
-	minApprovers := decision.MinApprovers
-	if minApprovers < 1 {
-		minApprovers = 1
-	}
-
 	approvalCount := 0
 	for i := 0; i < minApprovers; i++ {
 		resp, err := e.approval.Wait(ctx, ticket)
 		if err != nil {
-			return false
+			return approvalCount, err.Error()
 		}
 
 		// Verify signature if present and required
@@ -1519,7 +2339,7 @@ func (e *Engine) requestApproval(ctx context.Context, stepID string, decision go
 							"message": "approval response signature verification failed",
 						})
 					}
-					return false
+					return approvalCount, "invalid approval signature"
 				}
 			}
 		}
@@ -1537,12 +2357,12 @@ func (e *Engine) requestApproval(ctx context.Context, stepID string, decision go
 		}
 
 		if !resp.Approved {
-			return false
+			return approvalCount, "rejected by " + resp.ApproverID
 		}
 		approvalCount++
 	}
 
-	return approvalCount >= minApprovers
+	return approvalCount, ""
 }
 
 // verifyApprovalSignature checks the HMAC signature of an approval response.
@@ -1557,7 +2377,7 @@ func verifyApprovalSignature(resp *ApprovalResponse, sigKey string) bool {
 // stdinApprovalProvider implements ApprovalProvider using stdin/stdout.
 // Submit+Wait happen atomically — Submit creates a ticket, Wait prompts and blocks.
 type stdinApprovalProvider struct {
-	stdin  io.Reader
+	stdin  *lineReader
 	stdout io.Writer
 }
 
@@ -1573,10 +2393,13 @@ func (p *stdinApprovalProvider) Wait(ctx context.Context, ticket *ApprovalTicket
 	min := 1
 	fmt.Fprintf(p.stdout, "\n  ⚠ Approval required (ticket: %s)\n", ticket.TicketID)
 	fmt.Fprintf(p.stdout, "  Approve? [y/N]: ")
-	scanner := bufio.NewScanner(p.stdin)
 	approved := false
-	if scanner.Scan() {
-		answer := strings.TrimSpace(strings.ToLower(scanner.Text()))
+	line, ok, err := p.stdin.readLine(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		answer := strings.TrimSpace(strings.ToLower(line))
 		approved = answer == "y" || answer == "yes"
 	}
 	_ = min
@@ -1603,9 +2426,73 @@ func (e *Engine) loadTools() {
 	}
 }
 
-// Vars returns the current variable scope (for test harness inspection).
+// Vars returns the current global variable scope (for test harness
+// inspection). Scope-prefixed step outputs are left out; read them with
+// ScopedVars.
 func (e *Engine) Vars() map[string]any {
-	return e.vars
+	global := make(map[string]any, len(e.vars))
+	for k, v := range e.vars {
+		if _, ok := e.scoped.Load(k); !ok {
+			global[k] = v
+		}
+	}
+	return global
+}
+
+// ScopedVars returns the outputs of scoped steps, keyed
+// <scope>.<step_id>.<output>.
+func (e *Engine) ScopedVars() map[string]any {
+	scoped := make(map[string]any)
+	for k, v := range e.vars {
+		if _, ok := e.scoped.Load(k); ok {
+			scoped[k] = v
+		}
+	}
+	return scoped
+}
+
+// SetVar injects a variable into the run scope. Interactive front-ends use it
+// to patch state while the engine is blocked awaiting operator input. It is
+// safe to call from any goroutine: the value is queued and the run goroutine
+// applies it once the current manual step has its input, or before the next
+// step is considered.
+func (e *Engine) SetVar(name string, value any) {
+	e.injectMu.Lock()
+	defer e.injectMu.Unlock()
+	if e.injected == nil {
+		e.injected = make(map[string]any)
+	}
+	e.injected[name] = value
+}
+
+// applyInjected moves values queued by SetVar into the run scope. Only the
+// run goroutine calls it.
+func (e *Engine) applyInjected() {
+	e.injectMu.Lock()
+	defer e.injectMu.Unlock()
+	for k, v := range e.injected {
+		e.vars[k] = v
+	}
+	e.injected = nil
+}
+
+// secretEnvVars lists the env vars holding secrets declared by the runbook
+// and its loaded tools, whose values are redacted from the trace and hooks.
+func (e *Engine) secretEnvVars() []string {
+	var envVars []string
+	for _, s := range e.rb.Meta.Secrets {
+		if s.Env != "" {
+			envVars = append(envVars, s.Env)
+		}
+	}
+	for _, td := range e.tools {
+		for _, s := range td.Meta.Secrets {
+			if s.Env != "" {
+				envVars = append(envVars, s.Env)
+			}
+		}
+	}
+	return envVars
 }
 
 // SetToolDef injects a tool definition directly (for testing/replay without disk loading).
@@ -1623,6 +2510,93 @@ func fileHash(path string) (string, error) {
 	return hex.EncodeToString(h[:]), nil
 }
 
+// chooseOption lists the options of a manual step's choice and reads the
+// operator's selection by number, asking again until it is in range.
+// dry-run and probe select the first option without waiting.
+func (e *Engine) chooseOption(ctx context.Context, c *schema.ChoiceConfig) (string, error) {
+	if len(c.Options) == 0 {
+		return "", fmt.Errorf("choice %s has no options", c.Variable)
+	}
+	prompt, err := eval.Resolve(c.Prompt, e.vars)
+	if err != nil {
+		return "", fmt.Errorf("choice prompt: %w", err)
+	}
+	if prompt == "" {
+		prompt = "Select " + c.Variable
+	}
+	fmt.Fprintf(e.cfg.Stdout, "  %s:\n", prompt)
+	for i, opt := range c.Options {
+		label := opt.Label
+		if label == "" {
+			label = opt.Value
+		}
+		if opt.Description != "" {
+			label += " — " + opt.Description
+		}
+		fmt.Fprintf(e.cfg.Stdout, "    %d) %s\n", i+1, label)
+	}
+
+	if e.cfg.Mode == "dry-run" || e.cfg.Mode == "probe" {
+		fmt.Fprintf(e.cfg.Stdout, "  (%s: selecting option 1)\n", e.cfg.Mode)
+		return c.Options[0].Value, nil
+	}
+	for {
+		fmt.Fprintf(e.cfg.Stdout, "  [choice] 1-%d: ", len(c.Options))
+		line, ok, err := e.stdin.readLine(ctx)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("choice %s: no selection made", c.Variable)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(line))
+		if err == nil && n >= 1 && n <= len(c.Options) {
+			return c.Options[n-1].Value, nil
+		}
+		fmt.Fprintf(e.cfg.Stdout, "  invalid selection %q\n", strings.TrimSpace(line))
+	}
+}
+
+// lineReader reads manual input a line at a time. A single goroutine,
+// started at the first prompt, reads lines for the whole run and hands them
+// over a channel, so a prompt that gives up when its ctx expires leaves the
+// line being typed for the next prompt instead of stranding a reader on the
+// shared input.
+type lineReader struct {
+	r     *bufio.Reader
+	once  sync.Once
+	lines chan string // closed at end of input
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r), lines: make(chan string)}
+}
+
+func (lr *lineReader) read() {
+	defer close(lr.lines)
+	for {
+		line, err := lr.r.ReadString('\n')
+		if err == nil || line != "" {
+			lr.lines <- strings.TrimRight(line, "\r\n")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readLine returns the next line, giving up when ctx is done. ok is false
+// at end of input.
+func (lr *lineReader) readLine(ctx context.Context) (line string, ok bool, err error) {
+	lr.once.Do(func() { go lr.read() })
+	select {
+	case line, ok := <-lr.lines:
+		return line, ok, nil
+	case <-ctx.Done():
+		return "", false, ctx.Err()
+	}
+}
+
 func (e *Engine) emitStepError(stepID string, start time.Time, kind, msg string) {
 	if e.trace != nil {
 		e.trace.EmitStepComplete(stepID, trace.StatusError, nil, time.Since(start), &trace.Failure{
@@ -1,10 +1,22 @@
 package engine
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/contract"
 	"github.com/ormasoftchile/gert/pkg/kernel/executor"
@@ -466,6 +478,11 @@ func TestEngine_ParallelExecution(t *testing.T) {
 		t.Errorf("code = %q", result.Outcome.Code)
 	}
 
+	// Branch steps run on forked engines but still count as visited
+	if got := strings.Join(eng.VisitedSteps, ","); got != "par,a_check,b_check,_step_1" {
+		t.Errorf("visited = %s", got)
+	}
+
 	traceStr := traceBuf.String()
 	if !strings.Contains(traceStr, "parallel_fork") {
 		t.Error("trace missing parallel_fork")
@@ -905,9 +922,11 @@ func TestEngine_ForEachKey_DuplicateError(t *testing.T) {
 type mockToolExecutor struct {
 	result *executor.Result
 	err    error
+	delay  time.Duration // how long Execute sleeps before returning
 }
 
 func (m *mockToolExecutor) Execute(ctx context.Context, toolDef *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any) (*executor.Result, error) {
+	time.Sleep(m.delay)
 	return m.result, m.err
 }
 
@@ -1342,3 +1361,1990 @@ func TestEngine_RepeatBlock_StopsOnUntil(t *testing.T) {
 		}
 	}
 }
+
+// T135: Repeat block — an inner failure stops the block and the run
+func TestEngine_RepeatBlock_FailurePropagates(t *testing.T) {
+	var traceBuf bytes.Buffer
+	tw := trace.NewWriter(&traceBuf, "r1")
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:   "loop",
+				Type: schema.StepAssert,
+				Repeat: &schema.RepeatBlock{
+					Max: 5,
+					Steps: []schema.Step{
+						{
+							ID:   "inner",
+							Type: schema.StepAssert,
+							Assert: []schema.Assertion{
+								{Type: "equals", Value: "{{ .repeat.index }}", Expected: "0"},
+							},
+						},
+					},
+				},
+			},
+			{
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+			},
+		},
+	}
+
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Trace: tw})
+	result := eng.Run(context.Background())
+	if result.Status != "failed" {
+		t.Fatalf("status = %q, want failed (error = %v)", result.Status, result.Error)
+	}
+	if got := strings.Join(eng.VisitedSteps, ","); got != "loop,inner,inner" {
+		t.Errorf("visited = %s, want the second iteration to be the last", got)
+	}
+	traceStr := traceBuf.String()
+	if n := strings.Count(traceStr, `"repeat_iteration"`); n != 2 {
+		t.Errorf("repeat_iteration events = %d, want 2", n)
+	}
+	if !strings.Contains(traceStr, `"iterations":2,"step_id":"loop","stopped_by":"failed"`) {
+		t.Errorf("trace missing repeat_complete for the failed iteration:\n%s", traceStr)
+	}
+}
+
+// seqToolExecutor returns results in order, repeating the last one.
+type seqToolExecutor struct {
+	results []*executor.Result
+	calls   int
+}
+
+func (m *seqToolExecutor) Execute(ctx context.Context, toolDef *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any) (*executor.Result, error) {
+	r := m.results[min(m.calls, len(m.results)-1)]
+	m.calls++
+	return r, nil
+}
+
+// T129: Retry — step succeeds on the third attempt
+func TestEngine_Retry_ThreeAttempts(t *testing.T) {
+	var traceBuf bytes.Buffer
+	tw := trace.NewWriter(&traceBuf, "r1")
+
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:     "flaky",
+				Type:   schema.StepTool,
+				Tool:   "flaky-tool",
+				Action: "run",
+				Retry:  &schema.RetryPolicy{Max: 3, Delay: "1ms", Backoff: schema.BackoffExponential},
+			},
+			{
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+			},
+		},
+	}
+
+	exec := &seqToolExecutor{results: []*executor.Result{
+		{ExitCode: 1},
+		{ExitCode: 1},
+		{ExitCode: 0, Outputs: map[string]any{"status": "ok"}},
+	}}
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Trace: tw, ToolExec: exec})
+	eng.tools["flaky-tool"] = &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "flaky-tool"},
+		Actions: map[string]schema.ToolAction{"run": {}},
+	}
+
+	result := eng.Run(context.Background())
+	if result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if exec.calls != 3 {
+		t.Errorf("calls = %d, want 3", exec.calls)
+	}
+	if n := strings.Count(traceBuf.String(), `"step_retry"`); n != 2 {
+		t.Errorf("step_retry events = %d, want 2", n)
+	}
+	if eng.Vars()["status"] != "ok" {
+		t.Errorf("status output = %v, want ok", eng.Vars()["status"])
+	}
+}
+
+// T130: Retry — step fails once all attempts are exhausted
+func TestEngine_Retry_Exhausted(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:     "broken",
+				Type:   schema.StepTool,
+				Tool:   "broken-tool",
+				Action: "run",
+				Retry:  &schema.RetryPolicy{Max: 3},
+			},
+			{
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+			},
+		},
+	}
+
+	exec := &seqToolExecutor{results: []*executor.Result{{ExitCode: 1}}}
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", ToolExec: exec})
+	eng.tools["broken-tool"] = &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "broken-tool"},
+		Actions: map[string]schema.ToolAction{"run": {}},
+	}
+
+	result := eng.Run(context.Background())
+	if result.Status != "failed" {
+		t.Errorf("status = %q, want failed", result.Status)
+	}
+	if exec.calls != 3 {
+		t.Errorf("calls = %d, want 3", exec.calls)
+	}
+}
+
+// T131: Retry backoff durations
+func TestRetryDelay_Backoff(t *testing.T) {
+	tests := []struct {
+		backoff string
+		want    []time.Duration
+	}{
+		{"", []time.Duration{5 * time.Second, 5 * time.Second, 5 * time.Second}},
+		{schema.BackoffLinear, []time.Duration{5 * time.Second, 10 * time.Second, 15 * time.Second}},
+		{schema.BackoffExponential, []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second}},
+	}
+	for _, tt := range tests {
+		p := &schema.RetryPolicy{Max: 4, Delay: "5s", Backoff: tt.backoff}
+		for i, want := range tt.want {
+			if got := retryDelay(p, i+1); got != want {
+				t.Errorf("backoff %q attempt %d: delay = %s, want %s", tt.backoff, i+1, got, want)
+			}
+		}
+	}
+}
+
+// concurrencyToolExecutor records the peak number of concurrent Execute calls.
+type concurrencyToolExecutor struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (c *concurrencyToolExecutor) Execute(ctx context.Context, toolDef *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any) (*executor.Result, error) {
+	c.mu.Lock()
+	c.running++
+	if c.running > c.peak {
+		c.peak = c.running
+	}
+	c.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	c.mu.Lock()
+	c.running--
+	c.mu.Unlock()
+	return &executor.Result{ExitCode: 0, Outputs: map[string]any{}}, nil
+}
+
+// T132: for_each.max_parallel bounds concurrent iterations
+func TestEngine_ForEachParallel_MaxParallel(t *testing.T) {
+	for _, tc := range []struct {
+		maxParallel int
+		items       int
+		wantPeak    int
+	}{
+		{maxParallel: 3, items: 10, wantPeak: 3},
+		{maxParallel: 2, items: 5, wantPeak: 2},
+		{maxParallel: 1, items: 3, wantPeak: 1},   // sequential
+		{maxParallel: 0, items: 10, wantPeak: 10}, // unbounded
+	} {
+		rb := &schema.Runbook{
+			APIVersion: "kernel/v0",
+			Meta:       schema.Meta{Name: "test"},
+			Steps: []schema.Step{
+				{
+					ID:     "probe",
+					Type:   schema.StepTool,
+					Tool:   "probe-tool",
+					Action: "run",
+					ForEach: &schema.ForEach{
+						As:          "item",
+						Over:        "{{ .items }}",
+						Parallel:    true,
+						MaxParallel: tc.maxParallel,
+					},
+				},
+				{
+					Type:    schema.StepEnd,
+					Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+				},
+			},
+		}
+
+		exec := &concurrencyToolExecutor{}
+		eng := New(rb, RunConfig{RunID: "r1", Mode: "real", ToolExec: exec})
+		eng.tools["probe-tool"] = &schema.ToolDefinition{
+			Meta:    schema.ToolMeta{Name: "probe-tool"},
+			Actions: map[string]schema.ToolAction{"run": {}},
+		}
+		items := make([]any, tc.items)
+		for i := range items {
+			items[i] = i
+		}
+		eng.vars["items"] = items
+
+		result := eng.Run(context.Background())
+		if result.Status != "completed" {
+			t.Fatalf("max_parallel=%d: status = %q, error = %v", tc.maxParallel, result.Status, result.Error)
+		}
+		if exec.peak != tc.wantPeak {
+			t.Errorf("max_parallel=%d: peak concurrency = %d, want %d", tc.maxParallel, exec.peak, tc.wantPeak)
+		}
+	}
+}
+
+// staggerToolExecutor finishes later items first and fails the item equal
+// to failOn (-1 for none); other items wait for cancellation or 200ms.
+type staggerToolExecutor struct {
+	failOn int
+	mu     sync.Mutex
+	calls  int
+}
+
+func (s *staggerToolExecutor) Execute(ctx context.Context, toolDef *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any) (*executor.Result, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+
+	item := vars["item"].(int)
+	if item == s.failOn {
+		return &executor.Result{ExitCode: 1}, nil
+	}
+	wait := time.Duration(5-item) * 10 * time.Millisecond
+	if s.failOn >= 0 {
+		wait = 200 * time.Millisecond
+	}
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &executor.Result{Outputs: map[string]any{"item": item}}, nil
+}
+
+// T158: parallel for_each collects outputs in item order whatever the
+// completion order, and the first failure cancels the remaining iterations
+func TestEngine_ForEachParallel_OrderAndCancel(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:      "probe",
+				Type:    schema.StepTool,
+				Tool:    "probe-tool",
+				Action:  "run",
+				ForEach: &schema.ForEach{As: "item", Over: "{{ .items }}", Parallel: true, MaxParallel: 2},
+			},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	run := func(exec *staggerToolExecutor) (*Engine, *RunResult) {
+		eng := New(rb, RunConfig{RunID: "r1", Mode: "real", ToolExec: exec, Stdout: io.Discard})
+		eng.tools["probe-tool"] = &schema.ToolDefinition{
+			Meta:    schema.ToolMeta{Name: "probe-tool"},
+			Actions: map[string]schema.ToolAction{"run": {}},
+		}
+		eng.vars["items"] = []any{0, 1, 2, 3, 4}
+		return eng, eng.Run(context.Background())
+	}
+
+	eng, result := run(&staggerToolExecutor{failOn: -1})
+	if result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	outputs, _ := eng.vars["probe"].([]any)
+	if len(outputs) != 5 {
+		t.Fatalf("outputs = %v", eng.vars["probe"])
+	}
+	for i, out := range outputs {
+		if m, _ := out.(map[string]any); m["item"] != i {
+			t.Errorf("outputs[%d] = %v, want item %d", i, out, i)
+		}
+	}
+
+	exec := &staggerToolExecutor{failOn: 1}
+	_, result = run(exec)
+	if result.Status != "failed" || !strings.Contains(result.Error.Error(), "probe[1]") {
+		t.Errorf("status = %q, error = %v; want probe[1] failure", result.Status, result.Error)
+	}
+	if exec.calls != 2 {
+		t.Errorf("tool ran %d times, want 2 (remaining items cancelled)", exec.calls)
+	}
+}
+
+// onFailureRunbook: "drain" fails and routes to "cleanup", which ends the run
+// as escalated; "done" is only reached when drain succeeds.
+func onFailureRunbook(cleanup schema.Step) *schema.Runbook {
+	return &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:        "drain",
+				Type:      schema.StepTool,
+				Tool:      "node-tool",
+				Action:    "run",
+				Retry:     &schema.RetryPolicy{Max: 2},
+				OnFailure: "cleanup",
+			},
+			{
+				ID:      "done",
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "drained"},
+			},
+			cleanup,
+			{
+				ID:      "escalate",
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeEscalated, Code: "drain_failed"},
+			},
+		},
+	}
+}
+
+// T133: on_failure — routes to the named step once retries are exhausted
+func TestEngine_OnFailure_RoutesAfterRetries(t *testing.T) {
+	var traceBuf bytes.Buffer
+	tw := trace.NewWriter(&traceBuf, "r1")
+
+	rb := onFailureRunbook(schema.Step{
+		ID:     "cleanup",
+		Type:   schema.StepAssert,
+		Assert: []schema.Assertion{{Type: "contains", Value: "{{ .drain.error }}", Expected: "drain"}},
+	})
+	exec := &seqToolExecutor{results: []*executor.Result{{ExitCode: 1}}}
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Trace: tw, ToolExec: exec})
+	eng.tools["node-tool"] = &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "node-tool"},
+		Actions: map[string]schema.ToolAction{"run": {}},
+	}
+
+	result := eng.Run(context.Background())
+	if result.Status != "completed" || result.Outcome == nil || result.Outcome.Code != "drain_failed" {
+		t.Fatalf("status = %q, outcome = %+v, error = %v", result.Status, result.Outcome, result.Error)
+	}
+	if exec.calls != 2 {
+		t.Errorf("calls = %d, want 2 (retries exhausted before routing)", exec.calls)
+	}
+	if got := strings.Join(eng.VisitedSteps, ","); got != "drain,cleanup,escalate" {
+		t.Errorf("visited = %s", got)
+	}
+	if n := strings.Count(traceBuf.String(), `"step_on_failure"`); n != 1 {
+		t.Errorf("step_on_failure events = %d, want 1", n)
+	}
+}
+
+// T134: on_failure — a failing target stops the run instead of looping
+func TestEngine_OnFailure_TargetFails(t *testing.T) {
+	rb := onFailureRunbook(schema.Step{
+		ID:        "cleanup",
+		Type:      schema.StepTool,
+		Tool:      "node-tool",
+		Action:    "run",
+		OnFailure: "drain",
+	})
+	exec := &seqToolExecutor{results: []*executor.Result{{ExitCode: 1}}}
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", ToolExec: exec})
+	eng.tools["node-tool"] = &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "node-tool"},
+		Actions: map[string]schema.ToolAction{"run": {}},
+	}
+
+	result := eng.Run(context.Background())
+	if result.Status != "failed" {
+		t.Fatalf("status = %q, want failed", result.Status)
+	}
+	// drain (2 attempts) → cleanup → drain (2 attempts), then drain has
+	// already routed once and the failure ends the run
+	if exec.calls != 5 {
+		t.Errorf("calls = %d, want 5", exec.calls)
+	}
+	if got := strings.Join(eng.VisitedSteps, ","); got != "drain,cleanup,drain" {
+		t.Errorf("visited = %s", got)
+	}
+}
+
+// TestExtensionHelperProcess is the mock extension plugin the extension tests
+// spawn: it answers newline-delimited JSON-RPC on stdio and echoes the inputs
+// of an execute request back as outputs.
+func TestExtensionHelperProcess(t *testing.T) {
+	if os.Getenv("GERT_EXTENSION_HELPER") != "1" {
+		return
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req struct {
+			ID     int64  `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Inputs map[string]any `json:"inputs"`
+			} `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &req)
+		result := map[string]any{}
+		if req.Method == "execute" {
+			result = map[string]any{"outputs": req.Params.Inputs, "exit_code": 0}
+		}
+		data, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		fmt.Println(string(data))
+		if req.Method == "shutdown" {
+			break
+		}
+	}
+	os.Exit(0)
+}
+
+func extensionRunbook(outputType string) *schema.Runbook {
+	return &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:        "ext",
+				Type:      schema.StepExtension,
+				Extension: "echo",
+				Inputs:    map[string]any{"host": "{{ .target }}"},
+				Contract: &contract.Contract{
+					Binary:  os.Args[0],
+					Args:    []string{"-test.run=^TestExtensionHelperProcess$"},
+					Outputs: map[string]contract.ParamDef{"host": {Type: outputType, Required: true}},
+				},
+			},
+			{
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+			},
+		},
+	}
+}
+
+// T136: extension step — spawns the plugin and stores its outputs
+func TestEngine_Extension_Executes(t *testing.T) {
+	t.Setenv("GERT_EXTENSION_HELPER", "1")
+	var traceBuf bytes.Buffer
+	tw := trace.NewWriter(&traceBuf, "r1")
+
+	eng := New(extensionRunbook("string"), RunConfig{
+		RunID: "r1", Mode: "real", Trace: tw, Vars: map[string]string{"target": "db01"},
+	})
+	result := eng.Run(context.Background())
+	if result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	outputs, _ := eng.vars["ext"].(map[string]any)
+	if outputs["host"] != "db01" {
+		t.Errorf("ext outputs = %v, want host=db01", eng.vars["ext"])
+	}
+	for _, event := range []string{`"extension_started"`, `"extension_completed"`} {
+		if !strings.Contains(traceBuf.String(), event) {
+			t.Errorf("trace missing %s:\n%s", event, traceBuf.String())
+		}
+	}
+}
+
+// T137: extension step — an output of the wrong type fails the step
+func TestEngine_Extension_OutputTypeMismatch(t *testing.T) {
+	t.Setenv("GERT_EXTENSION_HELPER", "1")
+	eng := New(extensionRunbook("integer"), RunConfig{
+		RunID: "r1", Mode: "real", Vars: map[string]string{"target": "db01"},
+	})
+	result := eng.Run(context.Background())
+	if result.Status != "failed" || result.Error == nil || !strings.Contains(result.Error.Error(), `output "host" is string, contract declares integer`) {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+}
+
+// envToolExecutor records the environment passed with each call.
+type envToolExecutor struct {
+	env   []string
+	clean bool
+}
+
+func (m *envToolExecutor) Execute(ctx context.Context, toolDef *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any) (*executor.Result, error) {
+	m.env = executor.EnvFromContext(ctx)
+	m.clean = executor.CleanEnvFromContext(ctx)
+	return &executor.Result{Outputs: map[string]any{}}, nil
+}
+
+func envRunbook(policy *schema.GovernancePolicy) *schema.Runbook {
+	return &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test", Governance: policy},
+		Steps: []schema.Step{
+			{
+				ID:     "deploy",
+				Type:   schema.StepTool,
+				Tool:   "test-tool",
+				Action: "run",
+				Env:    map[string]string{"REGION": "{{ .region }}", "AWS_PROFILE": "ops"},
+			},
+			{
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+			},
+		},
+	}
+}
+
+// T138: step env — templates resolved and passed to the tool executor
+func TestEngine_StepEnv(t *testing.T) {
+	exec := &envToolExecutor{}
+	eng := New(envRunbook(nil), RunConfig{
+		RunID: "r1", Mode: "real", ToolExec: exec, Vars: map[string]string{"region": "eu-west-1"},
+	})
+	eng.tools["test-tool"] = &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "test-tool"},
+		Actions: map[string]schema.ToolAction{"run": {}},
+	}
+	result := eng.Run(context.Background())
+	if result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	want := []string{"AWS_PROFILE=ops", "REGION=eu-west-1"}
+	if strings.Join(exec.env, " ") != strings.Join(want, " ") {
+		t.Errorf("env = %v, want %v", exec.env, want)
+	}
+}
+
+// T160: clean env — tools run without the parent environment, but still
+// get the step env and their declared secrets; dry-run lists what they
+// would inherit
+func TestEngine_StepEnv_Clean(t *testing.T) {
+	t.Setenv("GERT_TEST_TOKEN", "s3cret")
+	td := &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "test-tool", Secrets: []schema.SecretRef{{Env: "GERT_TEST_TOKEN"}}},
+		Actions: map[string]schema.ToolAction{"run": {}},
+	}
+
+	exec := &envToolExecutor{}
+	eng := New(envRunbook(nil), RunConfig{
+		RunID: "r1", Mode: "real", ToolExec: exec, CleanEnv: true, Vars: map[string]string{"region": "eu-west-1"},
+	})
+	eng.tools["test-tool"] = td
+	if result := eng.Run(context.Background()); result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	want := []string{"AWS_PROFILE=ops", "REGION=eu-west-1", "GERT_TEST_TOKEN=s3cret"}
+	if !exec.clean || strings.Join(exec.env, " ") != strings.Join(want, " ") {
+		t.Errorf("clean = %v, env = %v, want %v", exec.clean, exec.env, want)
+	}
+
+	var out bytes.Buffer
+	eng = New(envRunbook(nil), RunConfig{
+		RunID: "r1", Mode: "dry-run", Stdout: &out, CleanEnv: true, Vars: map[string]string{"region": "eu-west-1"},
+	})
+	eng.tools["test-tool"] = td
+	eng.Run(context.Background())
+	if !strings.Contains(out.String(), "inherited env: [PATH HOME USER GERT_TEST_TOKEN]") {
+		t.Errorf("dry-run output:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "s3cret") {
+		t.Error("dry-run output leaks a secret value")
+	}
+}
+
+// T161: tool cache — a real run reuses a stored result for the same key
+// until --no-cache or a different key forces execution
+func TestEngine_ToolCache(t *testing.T) {
+	t.Chdir(t.TempDir())
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:     "query",
+				Type:   schema.StepTool,
+				Tool:   "db",
+				Action: "run",
+				Cache:  &schema.CacheConfig{TTL: "5m", Key: "{{ .query }}_{{ .env }}"},
+			},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	exec := &seqToolExecutor{results: []*executor.Result{{Outputs: map[string]any{"rows": "42"}}}}
+	run := func(cfg RunConfig) (*Engine, string) {
+		var traceBuf bytes.Buffer
+		cfg.RunID, cfg.ToolExec, cfg.Stdout = "r1", exec, io.Discard
+		cfg.Trace = trace.NewWriter(&traceBuf, "r1")
+		if cfg.Mode == "" {
+			cfg.Mode = "real"
+		}
+		eng := New(rb, cfg)
+		eng.tools["db"] = &schema.ToolDefinition{Meta: schema.ToolMeta{Name: "db"}, Actions: map[string]schema.ToolAction{"run": {}}}
+		if result := eng.Run(context.Background()); result.Status != "completed" {
+			t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+		}
+		return eng, traceBuf.String()
+	}
+	vars := map[string]string{"query": "select 1", "env": "prod"}
+
+	_, tr := run(RunConfig{Vars: vars})
+	if exec.calls != 1 || !strings.Contains(tr, `"tool_cache_miss"`) {
+		t.Fatalf("first run: calls = %d, trace:\n%s", exec.calls, tr)
+	}
+
+	eng, tr := run(RunConfig{Vars: vars})
+	if exec.calls != 1 || !strings.Contains(tr, `"tool_cache_hit"`) {
+		t.Errorf("second run should hit the cache: calls = %d, trace:\n%s", exec.calls, tr)
+	}
+	if eng.vars["rows"] != "42" {
+		t.Errorf("cached outputs not restored: rows = %v", eng.vars["rows"])
+	}
+
+	run(RunConfig{Vars: map[string]string{"query": "select 2", "env": "prod"}})
+	if exec.calls != 2 {
+		t.Errorf("a different key must execute the tool: calls = %d", exec.calls)
+	}
+
+	_, tr = run(RunConfig{Vars: vars, NoCache: true})
+	if exec.calls != 3 || strings.Contains(tr, "tool_cache") {
+		t.Errorf("NoCache must bypass the cache: calls = %d, trace:\n%s", exec.calls, tr)
+	}
+
+	_, tr = run(RunConfig{Vars: vars, Mode: "replay"})
+	if exec.calls != 4 || strings.Contains(tr, "tool_cache") {
+		t.Errorf("replay must not use the cache: calls = %d, trace:\n%s", exec.calls, tr)
+	}
+}
+
+// T163: tool inputs are checked against contract.inputs — mismatches warn
+// by default and fail the step unexecuted with StrictContracts
+func TestEngine_ToolInputContract(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:     "scale",
+				Type:   schema.StepTool,
+				Tool:   "kube",
+				Action: "scale",
+				Inputs: map[string]any{"replicas": "{{ .count }}", "force": "maybe", "extra": "x"},
+			},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	td := &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "kube"},
+		Actions: map[string]schema.ToolAction{"scale": {}},
+		Contract: contract.Contract{Inputs: map[string]contract.ParamDef{
+			"replicas":   {Type: "int"},
+			"force":      {Type: "bool"},
+			"deployment": {Type: "string", Required: true},
+			"namespace":  {Type: "string", Required: true, Default: "default"},
+		}},
+	}
+	run := func(strict bool) (*RunResult, *seqToolExecutor, string, string) {
+		var traceBuf, out bytes.Buffer
+		exec := &seqToolExecutor{results: []*executor.Result{{}}}
+		eng := New(rb, RunConfig{
+			RunID: "r1", Mode: "real", ToolExec: exec, Stdout: &out,
+			Vars:            map[string]string{"count": "3"},
+			Trace:           trace.NewWriter(&traceBuf, "r1"),
+			StrictContracts: strict,
+		})
+		eng.tools["kube"] = td
+		return eng.Run(context.Background()), exec, traceBuf.String(), out.String()
+	}
+
+	result, exec, tr, out := run(false)
+	if result.Status != "completed" || exec.calls != 1 {
+		t.Fatalf("status = %q, calls = %d, error = %v", result.Status, exec.calls, result.Error)
+	}
+	if n := strings.Count(tr, `"input_mismatch"`); n != 3 {
+		t.Errorf("input_mismatch events = %d, want 3:\n%s", n, tr)
+	}
+	for _, want := range []string{`input "extra" not declared in contract`, `input "force" is string, contract declares bool`, `required input "deployment" not provided`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("warning missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(tr, "maybe") || strings.Contains(out, "maybe") {
+		t.Errorf("input values must not be echoed into the trace or warnings:\n%s\n%s", tr, out)
+	}
+	if strings.Contains(tr, `"replicas"`) || strings.Contains(tr, `"namespace"`) {
+		t.Errorf("templated int and defaulted input must not be flagged:\n%s", tr)
+	}
+
+	result, exec, _, _ = run(true)
+	if result.Status != "failed" || exec.calls != 0 || !strings.Contains(result.Error.Error(), "not declared in contract") {
+		t.Errorf("strict: status = %q, calls = %d, error = %v", result.Status, exec.calls, result.Error)
+	}
+}
+
+// T139: step env — a governance env rule denies the step
+func TestEngine_StepEnv_GovernanceDeny(t *testing.T) {
+	exec := &envToolExecutor{}
+	policy := &schema.GovernancePolicy{Rules: []schema.GovernanceRule{
+		{Env: []string{"AWS_*"}, Action: "deny"},
+		{Default: "allow"},
+	}}
+	eng := New(envRunbook(policy), RunConfig{
+		RunID: "r1", Mode: "real", ToolExec: exec, Vars: map[string]string{"region": "eu-west-1"},
+	})
+	eng.tools["test-tool"] = &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "test-tool"},
+		Actions: map[string]schema.ToolAction{"run": {}},
+	}
+	result := eng.Run(context.Background())
+	if result.Status != "failed" || !strings.Contains(result.Error.Error(), "governance denied") {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if exec.env != nil {
+		t.Error("denied step must not execute")
+	}
+}
+
+// T140: json_path assertions evaluate a JSONPath against JSON text
+func TestEngine_AssertJSONPath(t *testing.T) {
+	body := `{"status": {"phase": "Running"}, "items": [{"name": "a"}, {"name": "b", "ready": true}]}`
+	tests := []struct {
+		name      string
+		assertion schema.Assertion
+		wantErr   string
+	}{
+		{"nested", schema.Assertion{Path: "$.status.phase", Expected: "Running"}, ""},
+		{"array index", schema.Assertion{Path: "$.items[1].ready", Expected: "true"}, ""},
+		{"negative index", schema.Assertion{Path: "$.items[-1].name", Expected: "{{ .want }}"}, ""},
+		{"mismatch", schema.Assertion{Path: "$.items[0].name", Expected: "b"}, `json_path $.items[0].name: expected "b", got "a"`},
+		{"missing key", schema.Assertion{Path: "$.status.reason", Expected: "x"}, `key "reason" not found`},
+		{"out of range", schema.Assertion{Path: "$.items[5].name", Expected: "x"}, "index 5 out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.assertion
+			a.Type = "json_path"
+			a.Value = "{{ .body }}"
+			rb := &schema.Runbook{
+				APIVersion: "kernel/v0",
+				Meta:       schema.Meta{Name: "test"},
+				Steps: []schema.Step{
+					{ID: "check", Type: schema.StepAssert, Assert: []schema.Assertion{a}},
+					{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "ok"}},
+				},
+			}
+			eng := New(rb, RunConfig{
+				RunID: "r1",
+				Mode:  "real",
+				Vars:  map[string]string{"body": body, "want": "b"},
+			})
+			result := eng.Run(context.Background())
+			if tt.wantErr == "" {
+				if result.Status != "completed" {
+					t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+				}
+				return
+			}
+			if result.Status != "failed" || !strings.Contains(result.Error.Error(), tt.wantErr) {
+				t.Fatalf("status = %q, error = %v, want %q", result.Status, result.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+// T162: contains_all and contains_none check a list of substrings
+func TestEngine_AssertContainsAllNone(t *testing.T) {
+	log := "service started\nWARN slow disk\nready"
+	tests := []struct {
+		name      string
+		assertion schema.Assertion
+		wantErr   string
+	}{
+		{"all present", schema.Assertion{Type: "contains_all", Values: []string{"started", "{{ .word }}"}}, ""},
+		{"all missing one", schema.Assertion{Type: "contains_all", Values: []string{"started", "healthy", "ready"}}, "to contain all of [started, healthy, ready] but missing: [healthy]"},
+		{"none present", schema.Assertion{Type: "contains_none", Values: []string{"ERROR", "FATAL"}}, ""},
+		{"none found", schema.Assertion{Type: "contains_none", Values: []string{"ERROR", "WARN"}}, "to contain none of [ERROR, WARN] but found: [WARN]"},
+		{"not none", schema.Assertion{Type: "contains_none", Values: []string{"WARN"}, Not: true}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := tt.assertion
+			a.Value = "{{ .log }}"
+			rb := &schema.Runbook{
+				APIVersion: "kernel/v0",
+				Meta:       schema.Meta{Name: "test"},
+				Steps: []schema.Step{
+					{ID: "check", Type: schema.StepAssert, Assert: []schema.Assertion{a}},
+					{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "ok"}},
+				},
+			}
+			eng := New(rb, RunConfig{
+				RunID: "r1",
+				Mode:  "real",
+				Vars:  map[string]string{"log": log, "word": "ready"},
+			})
+			result := eng.Run(context.Background())
+			if tt.wantErr == "" {
+				if result.Status != "completed" {
+					t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+				}
+				return
+			}
+			if result.Status != "failed" || !strings.Contains(result.Error.Error(), tt.wantErr) {
+				t.Fatalf("status = %q, error = %v, want %q", result.Status, result.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+// T141: visibility hides denied variables from the step and its sub-steps
+func TestEngine_Visibility_HidesVariables(t *testing.T) {
+	var traceBuf bytes.Buffer
+	tw := trace.NewWriter(&traceBuf, "r1")
+
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:         "review",
+				Type:       schema.StepBranch,
+				Visibility: &schema.Visibility{Deny: []string{"secret"}},
+				Branches: []schema.Branch{{
+					Condition: "default",
+					Steps: []schema.Step{{
+						ID:     "blind",
+						Type:   schema.StepAssert,
+						Export: []string{"passed"},
+						Assert: []schema.Assertion{
+							{Type: "equals", Value: "{{ .secret }}", Expected: "<no value>"},
+							{Type: "equals", Value: "{{ .question }}", Expected: "why"},
+						},
+					}},
+				}},
+			},
+			{
+				ID:   "after",
+				Type: schema.StepAssert,
+				Assert: []schema.Assertion{
+					{Type: "equals", Value: "{{ .secret }}", Expected: "s3cr3t"},
+					{Type: "equals", Value: "{{ .passed }}", Expected: "true"},
+				},
+			},
+			{
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+			},
+		},
+	}
+	eng := New(rb, RunConfig{
+		RunID: "r1",
+		Mode:  "real",
+		Trace: tw,
+		Vars:  map[string]string{"secret": "s3cr3t", "question": "why"},
+	})
+	result := eng.Run(context.Background())
+	if result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if !strings.Contains(traceBuf.String(), `"denied":["secret"]`) {
+		t.Errorf("expected denied variables in visibility_applied event, got:\n%s", traceBuf.String())
+	}
+}
+
+// T142: scoped step outputs are stored under <scope>.<step_id>.<output>
+func TestEngine_ScopePrefixesOutputs(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID: "rounds",
+				Repeat: &schema.RepeatBlock{
+					Max: 2,
+					Steps: []schema.Step{{
+						ID:    "check",
+						Type:  schema.StepAssert,
+						Scope: "round.{{ .repeat.index }}",
+						Assert: []schema.Assertion{
+							{Type: "equals", Value: "a", Expected: "a"},
+						},
+					}},
+				},
+			},
+			{
+				ID:   "verify",
+				Type: schema.StepBranch,
+				Branches: []schema.Branch{
+					{
+						Condition: "round.1.check.passed",
+						Steps: []schema.Step{{
+							ID:     "inner",
+							Type:   schema.StepAssert,
+							Assert: []schema.Assertion{{Type: "equals", Value: `{{ lookup . "round.0.check.passed" }}`, Expected: "true"}},
+						}},
+					},
+				},
+			},
+			{
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+			},
+		},
+	}
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real"})
+	result := eng.Run(context.Background())
+	if result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if !slices.Contains(eng.VisitedSteps, "inner") {
+		t.Errorf("visited = %v, want the branch arm to run", eng.VisitedSteps)
+	}
+
+	scoped := eng.ScopedVars()
+	if scoped["round.0.check.passed"] != true || scoped["round.1.check.passed"] != true || len(scoped) != 2 {
+		t.Errorf("scoped vars = %v", scoped)
+	}
+	global := eng.Vars()
+	if _, ok := global["round.0.check.passed"]; ok {
+		t.Error("Vars() should not include scope-prefixed outputs")
+	}
+	if _, ok := global["check"]; ok {
+		t.Error("scoped step outputs should not stay under the bare step ID")
+	}
+}
+
+// T143: pre_hook/post_hook run around each step; meta.defaults applies
+// unless the step sets its own, and a failing hook only warns
+func TestEngine_StepHooks(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("hook script uses sh")
+	}
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "hooks.log")
+	script := filepath.Join(dir, "hook.sh")
+	body := "#!/bin/sh\necho \"$GERT_HOOK $GERT_STEP_ID $GERT_STEP_STATUS $GERT_RUN_ID $GERT_CAPTURES\" >> \"$1\"\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var posted []hookEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var evt hookEvent
+		json.NewDecoder(r.Body).Decode(&evt)
+		mu.Lock()
+		posted = append(posted, evt)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta: schema.Meta{
+			Name:     "test",
+			Defaults: &schema.Defaults{PreHook: srv.URL, PostHook: script + " " + logFile},
+		},
+		Steps: []schema.Step{
+			{ID: "check", Type: schema.StepAssert, Assert: []schema.Assertion{{Type: "equals", Value: "a", Expected: "a"}}},
+			{
+				ID: "flaky", Type: schema.StepAssert, PostHook: filepath.Join(dir, "missing-hook"),
+				Assert: []schema.Assertion{{Type: "equals", Value: "a", Expected: "a"}},
+			},
+			{ID: "done", Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	var traceBuf bytes.Buffer
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Trace: trace.NewWriter(&traceBuf, "r1"), Stdout: io.Discard})
+	result := eng.Run(context.Background())
+	if result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+
+	log, err := os.ReadFile(logFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantLog := "post check success r1 {\"passed\":true}\npost done completed r1 {}\n"
+	if string(log) != wantLog {
+		t.Errorf("post hook log =\n%s\nwant\n%s", log, wantLog)
+	}
+	if len(posted) != 3 || posted[0].StepID != "check" || posted[0].Status != "running" || posted[2].StepID != "done" {
+		t.Errorf("pre hook posts = %+v", posted)
+	}
+	if n := strings.Count(traceBuf.String(), `"hook_warning"`); n != 1 {
+		t.Errorf("hook_warning events = %d, want 1 (flaky post_hook):\n%s", n, traceBuf.String())
+	}
+}
+
+// T144: governance allowed_hooks blocks unlisted hooks; SkipHooks disables them
+func TestEngine_StepHooks_GovernanceAndSkip(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { calls++ }))
+	defer srv.Close()
+
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta: schema.Meta{
+			Name:       "test",
+			Defaults:   &schema.Defaults{PreHook: srv.URL},
+			Governance: &schema.GovernancePolicy{AllowedHooks: []string{"hooks.example.com"}},
+		},
+		Steps: []schema.Step{
+			{ID: "done", Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	var traceBuf bytes.Buffer
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Trace: trace.NewWriter(&traceBuf, "r1"), Stdout: io.Discard})
+	if result := eng.Run(context.Background()); result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if calls != 0 || !strings.Contains(traceBuf.String(), "allowed_hooks") {
+		t.Errorf("calls = %d, want the hook blocked with a warning:\n%s", calls, traceBuf.String())
+	}
+
+	rb.Meta.Governance = nil
+	traceBuf.Reset()
+	eng = New(rb, RunConfig{RunID: "r2", Mode: "real", Trace: trace.NewWriter(&traceBuf, "r2"), Stdout: io.Discard, SkipHooks: true})
+	eng.Run(context.Background())
+	if calls != 0 || strings.Contains(traceBuf.String(), "hook_warning") {
+		t.Errorf("calls = %d, want no hooks with SkipHooks", calls)
+	}
+}
+
+// T164: hooks only run in real mode, and captures they receive are redacted
+func TestEngine_StepHooks_RealModeRedacted(t *testing.T) {
+	t.Setenv("GERT_TEST_TOKEN", "s3cret")
+	var mu sync.Mutex
+	var posted []hookEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var evt hookEvent
+		json.NewDecoder(r.Body).Decode(&evt)
+		mu.Lock()
+		posted = append(posted, evt)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta: schema.Meta{
+			Name:    "test",
+			Secrets: []schema.SecretRef{{Env: "GERT_TEST_TOKEN"}},
+		},
+		Steps: []schema.Step{
+			{
+				ID: "collect", Type: schema.StepManual, PostHook: srv.URL,
+				RequiredEvidence: []schema.EvidenceRequirement{{Kind: "text", Name: "token"}},
+			},
+			{ID: "done", Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+
+	for _, mode := range []string{"dry-run", "probe"} {
+		eng := New(rb, RunConfig{RunID: "r1", Mode: mode, Stdout: io.Discard})
+		if result := eng.Run(context.Background()); result.Status != "completed" {
+			t.Fatalf("%s: status = %q, error = %v", mode, result.Status, result.Error)
+		}
+	}
+	if len(posted) != 0 {
+		t.Fatalf("hooks ran outside real mode: %+v", posted)
+	}
+
+	eng := New(rb, RunConfig{RunID: "r2", Mode: "real", Stdin: strings.NewReader("token=s3cret\n"), Stdout: io.Discard})
+	if result := eng.Run(context.Background()); result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if len(posted) != 1 {
+		t.Fatalf("post hook posts = %+v, want 1", posted)
+	}
+	if c := posted[0].Captures; strings.Contains(c, "s3cret") || !strings.Contains(c, "<REDACTED>") {
+		t.Errorf("captures = %s, want the secret redacted", c)
+	}
+}
+
+// T145: a tool step past its timeout is killed and errors with step_timeout
+func TestEngine_StepTimeout_Tool(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("tool uses sleep")
+	}
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test", Defaults: &schema.Defaults{Timeout: "1h"}},
+		Steps: []schema.Step{
+			{ID: "slow", Type: schema.StepTool, Tool: "sleeper", Action: "run", Timeout: "100ms"},
+			{ID: "done", Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	var traceBuf bytes.Buffer
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Trace: trace.NewWriter(&traceBuf, "r1"), Stdout: io.Discard})
+	eng.tools["sleeper"] = &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "sleeper"},
+		Actions: map[string]schema.ToolAction{"run": {Argv: []string{"sleep", "10"}}},
+	}
+
+	begin := time.Now()
+	result := eng.Run(context.Background())
+	if elapsed := time.Since(begin); elapsed > 5*time.Second {
+		t.Fatalf("tool was not killed at the timeout (ran %s)", elapsed)
+	}
+	if result.Status != "error" || result.Error == nil || !strings.Contains(result.Error.Error(), "timed out after 100ms") {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if !strings.Contains(traceBuf.String(), `"step_timeout"`) {
+		t.Errorf("expected step_timeout event:\n%s", traceBuf.String())
+	}
+}
+
+// T146: meta.defaults.timeout bounds manual steps waiting for input
+func TestEngine_StepTimeout_ManualDefault(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test", Defaults: &schema.Defaults{Timeout: "50ms"}},
+		Steps: []schema.Step{
+			{ID: "ask", Type: schema.StepManual, Instructions: "Check the dashboard",
+				RequiredEvidence: []schema.EvidenceRequirement{{Name: "note", Kind: "text"}}},
+			{ID: "done", Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	stdin, w := io.Pipe() // never written: the operator does not answer
+	defer w.Close()
+	var traceBuf bytes.Buffer
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Trace: trace.NewWriter(&traceBuf, "r1"), Stdin: stdin, Stdout: io.Discard})
+	result := eng.Run(context.Background())
+	if result.Status != "error" || result.Error == nil || !strings.Contains(result.Error.Error(), "timed out") {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if !strings.Contains(traceBuf.String(), `"step_timeout"`) {
+		t.Errorf("expected step_timeout event:\n%s", traceBuf.String())
+	}
+
+	// Input that arrives in time completes the step
+	eng = New(rb, RunConfig{RunID: "r2", Mode: "real", Stdin: strings.NewReader("all green\n"), Stdout: io.Discard})
+	if result := eng.Run(context.Background()); result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+}
+
+// T166: a step blocked by the host policy restores the variables its
+// visibility hid, so on_failure routing continues with them
+func TestEngine_EnforceEffects_RestoresVisibility(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID: "wipe", Type: schema.StepTool, Tool: "fs", Action: "delete",
+				Visibility: &schema.Visibility{Deny: []string{"secret"}},
+				OnFailure:  "after",
+			},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeEscalated, Code: "skipped"}},
+			{
+				ID:     "after",
+				Type:   schema.StepAssert,
+				Assert: []schema.Assertion{{Type: "equals", Value: "{{ .secret }}", Expected: "s3cr3t"}},
+			},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	eng := New(rb, RunConfig{
+		RunID:            "r1",
+		Mode:             "real",
+		Stdout:           io.Discard,
+		Vars:             map[string]string{"secret": "s3cr3t"},
+		ToolExec:         &seqToolExecutor{},
+		GovernancePolicy: &schema.GovernancePolicy{AllowedEffects: []string{"reads"}},
+	})
+	eng.tools["fs"] = &schema.ToolDefinition{
+		Meta:    schema.ToolMeta{Name: "fs"},
+		Actions: map[string]schema.ToolAction{"delete": {Contract: &contract.Contract{Effects: []string{"filesystem"}}}},
+	}
+	result := eng.Run(context.Background())
+	if result.Status != "completed" || result.Outcome == nil || result.Outcome.Code != "done" {
+		t.Fatalf("result = %+v (error %v), want done via on_failure", result, result.Error)
+	}
+}
+
+// T167: a step denied by governance or by its approver restores the
+// variables its visibility hid, so on_failure routing continues with them
+func TestEngine_GovernanceDeny_RestoresVisibility(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		stdin  string
+	}{
+		{name: "deny", action: "deny"},
+		{name: "approval rejected", action: "require-approval", stdin: "no\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rb := &schema.Runbook{
+				APIVersion: "kernel/v0",
+				Meta: schema.Meta{
+					Name: "test",
+					Governance: &schema.GovernancePolicy{Rules: []schema.GovernanceRule{
+						{Effects: []string{"kubernetes"}, Steps: []schema.StepType{schema.StepTool}, Action: tt.action},
+						{Default: "allow"},
+					}},
+				},
+				Steps: []schema.Step{
+					{
+						ID: "restart", Type: schema.StepTool, Tool: "kubectl", Action: "restart",
+						Visibility: &schema.Visibility{Deny: []string{"secret"}},
+						OnFailure:  "after",
+					},
+					{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeEscalated, Code: "skipped"}},
+					{
+						ID:     "after",
+						Type:   schema.StepAssert,
+						Assert: []schema.Assertion{{Type: "equals", Value: "{{ .secret }}", Expected: "s3cr3t"}},
+					},
+					{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+				},
+			}
+			eng := New(rb, RunConfig{
+				RunID:    "r1",
+				Mode:     "real",
+				Stdin:    strings.NewReader(tt.stdin),
+				Stdout:   io.Discard,
+				Vars:     map[string]string{"secret": "s3cr3t"},
+				ToolExec: &seqToolExecutor{},
+			})
+			eng.tools["kubectl"] = &schema.ToolDefinition{
+				Meta:    schema.ToolMeta{Name: "kubectl"},
+				Actions: map[string]schema.ToolAction{"restart": {Contract: &contract.Contract{Effects: []string{"kubernetes"}}}},
+			}
+			result := eng.Run(context.Background())
+			if result.Status != "completed" || result.Outcome == nil || result.Outcome.Code != "done" {
+				t.Fatalf("result = %+v (error %v), want done via on_failure", result, result.Error)
+			}
+		})
+	}
+}
+
+// T165: a prompt that times out leaves the line being typed for the next
+// prompt rather than to an abandoned reader
+func TestLineReader_ExpiredPromptKeepsLine(t *testing.T) {
+	stdin, w := io.Pipe()
+	lr := newLineReader(stdin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := lr.readLine(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+
+	go func() {
+		io.WriteString(w, "late answer\nnext\n")
+		w.Close()
+	}()
+	for _, want := range []string{"late answer", "next"} {
+		line, ok, err := lr.readLine(context.Background())
+		if err != nil || !ok || line != want {
+			t.Fatalf("readLine = %q, %v, %v; want %q", line, ok, err, want)
+		}
+	}
+	if _, ok, err := lr.readLine(context.Background()); ok || err != nil {
+		t.Fatalf("readLine at end of input: ok = %v, err = %v", ok, err)
+	}
+}
+
+// T147: parallel for_each.key merges outputs into a map in declaration
+// order; a duplicate key emits for_each_key_collision and errors
+func TestEngine_ForEachKey_Parallel(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:      "fan",
+				Type:    schema.StepAssert,
+				ForEach: &schema.ForEach{As: "item", Over: "{{ .items }}", Key: "{{ .item }}", Parallel: true},
+				Assert:  []schema.Assertion{{Type: "equals", Value: "{{ .item }}", Expected: "{{ .item }}"}},
+			},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real"})
+	eng.vars["items"] = []any{"alpha", "beta"}
+	if result := eng.Run(context.Background()); result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	keyed, ok := eng.Vars()["fan"].(map[string]any)
+	if !ok || len(keyed) != 2 || keyed["alpha"] == nil || keyed["beta"] == nil {
+		t.Fatalf("fan = %#v, want map keyed by item", eng.Vars()["fan"])
+	}
+
+	var traceBuf bytes.Buffer
+	eng = New(rb, RunConfig{RunID: "r2", Mode: "real", Trace: trace.NewWriter(&traceBuf, "r2")})
+	eng.vars["items"] = []any{"alpha", "alpha"}
+	result := eng.Run(context.Background())
+	if result.Status != "error" || !strings.Contains(result.Error.Error(), `"alpha" duplicated`) {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if !strings.Contains(traceBuf.String(), `"for_each_key_collision"`) {
+		t.Errorf("expected for_each_key_collision event:\n%s", traceBuf.String())
+	}
+}
+
+// T148: a host governance policy blocks tool steps declaring disallowed effects
+func TestEngine_EnforceEffects(t *testing.T) {
+	var traceBuf bytes.Buffer
+	tw := trace.NewWriter(&traceBuf, "r1")
+
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{ID: "look", Type: schema.StepTool, Tool: "fs", Action: "read"},
+			{ID: "wipe", Type: schema.StepTool, Tool: "fs", Action: "delete"},
+			{
+				Type:    schema.StepEnd,
+				Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"},
+			},
+		},
+	}
+	mock := &seqToolExecutor{results: []*executor.Result{{Outputs: map[string]any{}}}}
+	eng := New(rb, RunConfig{
+		RunID:            "r1",
+		Mode:             "real",
+		Trace:            tw,
+		Stdout:           io.Discard,
+		ToolExec:         mock,
+		GovernancePolicy: &schema.GovernancePolicy{AllowedEffects: []string{"reads"}},
+	})
+	eng.tools["fs"] = &schema.ToolDefinition{
+		Meta: schema.ToolMeta{Name: "fs"},
+		Actions: map[string]schema.ToolAction{
+			"read":   {Contract: &contract.Contract{Effects: []string{"reads"}}},
+			"delete": {Contract: &contract.Contract{Effects: []string{"filesystem"}}},
+		},
+	}
+
+	result := eng.Run(context.Background())
+	if result.Status != "failed" {
+		t.Fatalf("status = %q, want failed", result.Status)
+	}
+	if strings.Join(eng.VisitedSteps, ",") != "look,wipe" {
+		t.Errorf("visited = %v", eng.VisitedSteps)
+	}
+	if mock.calls != 1 {
+		t.Errorf("tool executed %d times, want 1 (wipe must be blocked)", mock.calls)
+	}
+	traceOutput := traceBuf.String()
+	if !strings.Contains(traceOutput, "contract_violation") || !strings.Contains(traceOutput, "effect_not_allowed") {
+		t.Errorf("expected effect_not_allowed contract_violation in trace:\n%s", traceOutput)
+	}
+
+	// Without a policy, enforcement is skipped
+	eng = New(rb, RunConfig{RunID: "r2", Mode: "real", Stdout: io.Discard, ToolExec: mock})
+	eng.tools["fs"] = &schema.ToolDefinition{Meta: schema.ToolMeta{Name: "fs"}, Actions: map[string]schema.ToolAction{"read": {}, "delete": {}}}
+	if result := eng.Run(context.Background()); result.Status != "completed" {
+		t.Errorf("without policy: status = %q, error = %v", result.Status, result.Error)
+	}
+}
+
+// T149: a run past its context deadline stops at the current step, even
+// inside parallel branches, and ends with a timed_out outcome
+func TestEngine_RunTimeout(t *testing.T) {
+	spin := func(id string) schema.Step {
+		return schema.Step{
+			ID:     id,
+			Type:   schema.StepAssert,
+			Assert: []schema.Assertion{{Type: "equals", Value: "x", Expected: "x"}},
+			Next:   id, // unbounded backward jump
+		}
+	}
+	end := schema.Step{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}}
+	tests := []struct {
+		name  string
+		steps []schema.Step
+	}{
+		{name: "sequential", steps: []schema.Step{spin("loop"), end}},
+		{name: "parallel", steps: []schema.Step{{
+			ID:   "par",
+			Type: schema.StepParallel,
+			Branches: []schema.Branch{
+				{Label: "a", Steps: []schema.Step{spin("a_loop")}},
+				{Label: "b", Steps: []schema.Step{spin("b_loop")}},
+			},
+		}, end}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var traceBuf bytes.Buffer
+			rb := &schema.Runbook{APIVersion: "kernel/v0", Meta: schema.Meta{Name: "spin"}, Steps: tt.steps}
+			eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Stdout: io.Discard, Trace: trace.NewWriter(&traceBuf, "r1")})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			start := time.Now()
+			result := eng.Run(ctx)
+
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Fatalf("run took %s after a 50ms timeout", elapsed)
+			}
+			if result.Status != "error" || result.Outcome == nil || result.Outcome.Category != schema.OutcomeTimedOut {
+				t.Fatalf("result = %+v, want timed_out outcome", result)
+			}
+			if !strings.Contains(traceBuf.String(), `"timed_out"`) {
+				t.Error("expected timed_out outcome in run_complete")
+			}
+			m := eng.Manifest(result)
+			if m.Outcome.Category != schema.OutcomeTimedOut || m.Status != "error" || m.LastStep == "" {
+				t.Errorf("manifest = %+v", m)
+			}
+		})
+	}
+}
+
+// T150: conditional next jumps to the first matching entry, or falls
+// through when none match
+func TestEngine_ConditionalNext(t *testing.T) {
+	endStep := func(id string) schema.Step {
+		return schema.Step{ID: id, Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: id}}
+	}
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:     "check",
+				Type:   schema.StepAssert,
+				Assert: []schema.Assertion{{Type: "equals", Value: "x", Expected: "x"}},
+				Next: []any{
+					map[string]any{"if": "{{ .degraded }}", "step": "remediate"},
+					map[string]any{"if": "{{ eq .flapping \"true\" }}", "step": "observe"},
+				},
+			},
+			endStep("fallthrough"),
+			endStep("remediate"),
+			endStep("observe"),
+		},
+	}
+	tests := []struct {
+		name string
+		vars map[string]string
+		want string
+	}{
+		{name: "no match", vars: map[string]string{"degraded": "false", "flapping": "false"}, want: "fallthrough"},
+		{name: "one match", vars: map[string]string{"degraded": "false", "flapping": "true"}, want: "observe"},
+		{name: "two matches", vars: map[string]string{"degraded": "true", "flapping": "true"}, want: "remediate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Vars: tt.vars, Stdout: io.Discard})
+			result := eng.Run(context.Background())
+			if result.Status != "completed" || result.Outcome == nil || result.Outcome.Code != tt.want {
+				t.Errorf("result = %+v (error %v), want outcome %s", result, result.Error, tt.want)
+			}
+		})
+	}
+}
+
+// T151: --var values are coerced to their declared input types, so numeric
+// comparisons work on CLI-provided values
+func TestEngine_VarTypeCoercion(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta: schema.Meta{
+			Name:   "test",
+			Inputs: map[string]contract.ParamDef{"threshold": {Type: "int"}, "count": {Type: "int"}},
+		},
+		Steps: []schema.Step{
+			{
+				ID:      "page",
+				Type:    schema.StepEnd,
+				When:    "{{ gt .count .threshold }}",
+				Outcome: &schema.Outcome{Category: schema.OutcomeEscalated, Code: "page"},
+			},
+			{ID: "ok", Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "ok"}},
+		},
+	}
+	tests := []struct {
+		count, threshold string
+		want             string
+	}{
+		{"9", "100", "ok"}, // "9" > "100" as strings
+		{"150", "100", "page"},
+	}
+	for _, tt := range tests {
+		eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Stdout: io.Discard,
+			Vars: map[string]string{"count": tt.count, "threshold": tt.threshold}})
+		if w := eng.Warnings(); len(w) != 0 {
+			t.Errorf("warnings = %v", w)
+		}
+		result := eng.Run(context.Background())
+		if result.Outcome == nil || result.Outcome.Code != tt.want {
+			t.Errorf("count=%s threshold=%s: result = %+v (error %v), want %s", tt.count, tt.threshold, result, result.Error, tt.want)
+		}
+	}
+
+	eng := New(rb, RunConfig{RunID: "r1", Vars: map[string]string{"count": "many", "threshold": "100"}})
+	if w := eng.Warnings(); len(w) != 1 || !strings.Contains(w[0], `"many" is not a valid int`) {
+		t.Errorf("warnings = %v", w)
+	}
+}
+
+// T152: manual steps record evidence, one line per entry, and emit
+// manual_prompt/manual_complete; dry-run records a placeholder instead
+func TestEngine_ManualEvidence(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:           "inspect",
+				Type:         schema.StepManual,
+				Instructions: "Check the dashboard for {{ .host }}",
+				RequiredEvidence: []schema.EvidenceRequirement{
+					{Kind: "text", Name: "error_rate"},
+					{Kind: "text", Name: "notes"},
+				},
+			},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+
+	tests := []struct {
+		mode string
+		want map[string]any
+	}{
+		{"real", map[string]any{"error_rate": "0.5%", "notes": "spike at 10:00"}},
+		{"dry-run", map[string]any{"error_rate": "<dry-run>", "notes": "<dry-run>"}},
+	}
+	for _, tt := range tests {
+		var traceBuf bytes.Buffer
+		eng := New(rb, RunConfig{
+			RunID:  "r1",
+			Mode:   tt.mode,
+			Vars:   map[string]string{"host": "srv1"},
+			Trace:  trace.NewWriter(&traceBuf, "r1"),
+			Stdin:  strings.NewReader("0.5%\nspike at 10:00\n"),
+			Stdout: io.Discard,
+		})
+		if result := eng.Run(context.Background()); result.Status != "completed" {
+			t.Fatalf("%s: status = %q, error = %v", tt.mode, result.Status, result.Error)
+		}
+		got, _ := eng.vars["inspect"].(map[string]any)
+		for k, v := range tt.want {
+			if got[k] != v {
+				t.Errorf("%s: evidence %s = %v, want %v", tt.mode, k, got[k], v)
+			}
+		}
+
+		var prompt, complete map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(traceBuf.String()), "\n") {
+			var evt trace.Event
+			json.Unmarshal([]byte(line), &evt)
+			switch evt.Type {
+			case trace.EventManualPrompt:
+				prompt = evt.Data
+			case trace.EventManualComplete:
+				complete = evt.Data
+			}
+		}
+		if prompt["instructions"] != "Check the dashboard for srv1" {
+			t.Errorf("%s: manual_prompt = %v", tt.mode, prompt)
+		}
+		if evidence, _ := complete["evidence"].(map[string]any); evidence["notes"] != tt.want["notes"] {
+			t.Errorf("%s: manual_complete = %v", tt.mode, complete)
+		}
+	}
+}
+
+// T156: a manual step with choices stores the selected option's value,
+// asks again on an out-of-range number, and emits manual_choice; dry-run
+// selects the first option
+func TestEngine_ManualChoices(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{
+				ID:           "pick",
+				Type:         schema.StepManual,
+				Instructions: "Pick the target environment",
+				Choices: &schema.ChoiceConfig{
+					Variable: "env",
+					Prompt:   "Environment for {{ .service }}",
+					Options: []schema.ChoiceOption{
+						{Value: "staging", Label: "Staging"},
+						{Value: "prod", Label: "Production", Description: "live traffic"},
+					},
+				},
+			},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+
+	tests := []struct {
+		mode, stdin, want string
+	}{
+		{"real", "3\nabc\n2\n", "prod"},
+		{"dry-run", "", "staging"},
+	}
+	for _, tt := range tests {
+		var traceBuf, out bytes.Buffer
+		eng := New(rb, RunConfig{
+			RunID:  "r1",
+			Mode:   tt.mode,
+			Vars:   map[string]string{"service": "api"},
+			Trace:  trace.NewWriter(&traceBuf, "r1"),
+			Stdin:  strings.NewReader(tt.stdin),
+			Stdout: &out,
+		})
+		result := eng.Run(context.Background())
+		if result.Status != "completed" {
+			t.Fatalf("%s: status = %q, error = %v", tt.mode, result.Status, result.Error)
+		}
+		if eng.vars["env"] != tt.want {
+			t.Errorf("%s: env = %v, want %s", tt.mode, eng.vars["env"], tt.want)
+		}
+		if !strings.Contains(out.String(), "Environment for api") || !strings.Contains(out.String(), "2) Production — live traffic") {
+			t.Errorf("%s: options not listed:\n%s", tt.mode, out.String())
+		}
+		if tt.mode == "real" && strings.Count(out.String(), "invalid selection") != 2 {
+			t.Errorf("expected two invalid selections:\n%s", out.String())
+		}
+
+		var choice map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(traceBuf.String()), "\n") {
+			var evt trace.Event
+			json.Unmarshal([]byte(line), &evt)
+			if evt.Type == trace.EventManualChoice {
+				choice = evt.Data
+			}
+		}
+		if choice["value"] != tt.want || choice["variable"] != "env" {
+			t.Errorf("%s: manual_choice = %v", tt.mode, choice)
+		}
+	}
+
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Stdin: strings.NewReader("9\n"), Stdout: io.Discard})
+	if result := eng.Run(context.Background()); result.Status != "error" || !strings.Contains(result.Error.Error(), "no selection made") {
+		t.Errorf("closed stdin: status = %q, error = %v", result.Status, result.Error)
+	}
+}
+
+// T153: a governance rule with require-approval prompts before a tool step
+// runs; only y/yes approves, and dry-run denies without prompting
+func TestEngine_ToolApproval(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta: schema.Meta{
+			Name: "test",
+			Governance: &schema.GovernancePolicy{Rules: []schema.GovernanceRule{
+				{Effects: []string{"kubernetes"}, Steps: []schema.StepType{schema.StepTool}, Action: "require-approval"},
+				{Default: "allow"},
+			}},
+		},
+		Steps: []schema.Step{
+			{ID: "restart", Type: schema.StepTool, Tool: "kubectl", Action: "restart"},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+
+	tests := []struct {
+		mode, stdin string
+		wantStatus  string
+		wantEvent   trace.EventType
+		wantCalls   int
+	}{
+		{"real", "yes\n", "completed", trace.EventApprovalGranted, 1},
+		{"real", "sure\n", "failed", trace.EventApprovalDenied, 0},
+		{"real", "", "failed", trace.EventApprovalDenied, 0},
+		{"dry-run", "y\n", "failed", trace.EventApprovalDenied, 0},
+	}
+	for _, tt := range tests {
+		var traceBuf, out bytes.Buffer
+		mock := &seqToolExecutor{results: []*executor.Result{{Outputs: map[string]any{}}}}
+		eng := New(rb, RunConfig{
+			RunID:    "r1",
+			Mode:     tt.mode,
+			Trace:    trace.NewWriter(&traceBuf, "r1"),
+			Stdin:    strings.NewReader(tt.stdin),
+			Stdout:   &out,
+			ToolExec: mock,
+		})
+		eng.tools["kubectl"] = &schema.ToolDefinition{
+			Meta: schema.ToolMeta{Name: "kubectl"},
+			Actions: map[string]schema.ToolAction{
+				"restart": {Contract: &contract.Contract{Effects: []string{"kubernetes"}}},
+			},
+		}
+
+		result := eng.Run(context.Background())
+		if result.Status != tt.wantStatus {
+			t.Errorf("%s %q: status = %q, want %s (error %v)", tt.mode, tt.stdin, result.Status, tt.wantStatus, result.Error)
+		}
+		if mock.calls != tt.wantCalls {
+			t.Errorf("%s %q: tool ran %d times, want %d", tt.mode, tt.stdin, mock.calls, tt.wantCalls)
+		}
+		traceOutput := traceBuf.String()
+		for _, evt := range []trace.EventType{trace.EventApprovalRequested, tt.wantEvent} {
+			if !strings.Contains(traceOutput, `"type":"`+string(evt)+`"`) {
+				t.Errorf("%s %q: trace missing %s", tt.mode, tt.stdin, evt)
+			}
+		}
+		if prompted := strings.Contains(out.String(), "Approve? [y/N]"); prompted != (tt.mode == "real") {
+			t.Errorf("%s %q: prompted = %v", tt.mode, tt.stdin, prompted)
+		}
+	}
+}
+
+// T157: an actor group with at least min_approvers members approves a
+// gated step without prompting, records each member, and is the manifest
+// actor; a smaller group falls back to prompting
+func TestEngine_ActorGroupApproval(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta: schema.Meta{
+			Name: "test",
+			Governance: &schema.GovernancePolicy{Rules: []schema.GovernanceRule{
+				{Effects: []string{"kubernetes"}, Action: "require-approval", MinApprovers: 2},
+				{Default: "allow"},
+			}},
+		},
+		Steps: []schema.Step{
+			{ID: "restart", Type: schema.StepTool, Tool: "kubectl", Action: "restart"},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+
+	tests := []struct {
+		group      []string
+		wantStatus string
+		wantRecord int
+		prompted   bool
+	}{
+		{[]string{"alice", "bob", "carol"}, "completed", 3, false},
+		{[]string{"alice"}, "failed", 0, true},
+	}
+	for _, tt := range tests {
+		var traceBuf, out bytes.Buffer
+		mock := &seqToolExecutor{results: []*executor.Result{{Outputs: map[string]any{}}}}
+		eng := New(rb, RunConfig{
+			RunID:      "r1",
+			Mode:       "real",
+			ActorGroup: tt.group,
+			Trace:      trace.NewWriter(&traceBuf, "r1"),
+			Stdin:      strings.NewReader(""),
+			Stdout:     &out,
+			ToolExec:   mock,
+		})
+		eng.tools["kubectl"] = &schema.ToolDefinition{
+			Meta: schema.ToolMeta{Name: "kubectl"},
+			Actions: map[string]schema.ToolAction{
+				"restart": {Contract: &contract.Contract{Effects: []string{"kubernetes"}}},
+			},
+		}
+
+		result := eng.Run(context.Background())
+		if result.Status != tt.wantStatus {
+			t.Errorf("%v: status = %q, want %s (error %v)", tt.group, result.Status, tt.wantStatus, result.Error)
+		}
+		if n := strings.Count(traceBuf.String(), `"type":"`+string(trace.EventApprovalRecorded)+`"`); n != tt.wantRecord {
+			t.Errorf("%v: %d approval_recorded events, want %d", tt.group, n, tt.wantRecord)
+		}
+		if prompted := strings.Contains(out.String(), "Approve? [y/N]"); prompted != tt.prompted {
+			t.Errorf("%v: prompted = %v", tt.group, prompted)
+		}
+		if m := eng.Manifest(result); m.Actor != strings.Join(tt.group, ",") {
+			t.Errorf("%v: manifest actor = %q", tt.group, m.Actor)
+		}
+	}
+}
+
+// T154: duration_less_than times the previous step, or the step named in
+// step:, against the expected duration
+func TestEngine_DurationLessThan(t *testing.T) {
+	tests := []struct {
+		name       string
+		assertion  schema.Assertion
+		wantStatus string
+		wantMsg    string
+	}{
+		{"within limit", schema.Assertion{Type: "duration_less_than", Expected: "5s"}, "completed", ""},
+		{"over limit", schema.Assertion{Type: "duration_less_than", Expected: "10ms"}, "failed", "step slow took"},
+		{"named step", schema.Assertion{Type: "duration_less_than", Step: "slow", Expected: "{{ .limit }}"}, "failed", "want <= 10ms"},
+		{"step not run", schema.Assertion{Type: "duration_less_than", Step: "missing", Expected: "5s"}, "failed", `step "missing" has not run`},
+	}
+	for _, tt := range tests {
+		rb := &schema.Runbook{
+			APIVersion: "kernel/v0",
+			Meta:       schema.Meta{Name: "test"},
+			Steps: []schema.Step{
+				{ID: "slow", Type: schema.StepTool, Tool: "slow-tool", Action: "run"},
+				{ID: "check", Type: schema.StepAssert, Assert: []schema.Assertion{tt.assertion}},
+				{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+			},
+		}
+		eng := New(rb, RunConfig{
+			RunID: "r1",
+			Mode:  "real",
+			Vars:  map[string]string{"limit": "10ms"},
+			ToolExec: &mockToolExecutor{
+				result: &executor.Result{Outputs: map[string]any{}},
+				delay:  50 * time.Millisecond,
+			},
+		})
+		eng.tools["slow-tool"] = &schema.ToolDefinition{
+			Meta:    schema.ToolMeta{Name: "slow-tool"},
+			Actions: map[string]schema.ToolAction{"run": {}},
+		}
+
+		result := eng.Run(context.Background())
+		if result.Status != tt.wantStatus {
+			t.Errorf("%s: status = %q, want %s (error %v)", tt.name, result.Status, tt.wantStatus, result.Error)
+		}
+		if tt.wantMsg != "" && (result.Error == nil || !strings.Contains(result.Error.Error(), tt.wantMsg)) {
+			t.Errorf("%s: error = %v, want it to contain %q", tt.name, result.Error, tt.wantMsg)
+		}
+	}
+}
+
+// overlapToolExecutor records which tools were executing at the same time.
+type overlapToolExecutor struct {
+	delay   time.Duration
+	mu      sync.Mutex
+	active  map[string]bool
+	overlap map[[2]string]bool // sorted tool name pairs seen running together
+}
+
+func (m *overlapToolExecutor) Execute(ctx context.Context, toolDef *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any) (*executor.Result, error) {
+	name := toolDef.Meta.Name
+	m.mu.Lock()
+	for other := range m.active {
+		pair := [2]string{name, other}
+		slices.Sort(pair[:])
+		m.overlap[pair] = true
+	}
+	m.active[name] = true
+	m.mu.Unlock()
+
+	time.Sleep(m.delay)
+
+	m.mu.Lock()
+	delete(m.active, name)
+	m.mu.Unlock()
+	return &executor.Result{Outputs: map[string]any{}}, nil
+}
+
+// parallelToolRunbook builds a parallel step with one tool step per branch;
+// writes[i] is the resource branch i writes.
+func parallelToolRunbook(writes []string) (*schema.Runbook, map[string]*schema.ToolDefinition) {
+	tools := make(map[string]*schema.ToolDefinition)
+	var branches []schema.Branch
+	for i, w := range writes {
+		name := fmt.Sprintf("tool%d", i)
+		tools[name] = &schema.ToolDefinition{
+			Meta:    schema.ToolMeta{Name: name},
+			Actions: map[string]schema.ToolAction{"run": {Contract: &contract.Contract{Writes: []string{w}}}},
+		}
+		branches = append(branches, schema.Branch{
+			Label: fmt.Sprintf("b%d", i),
+			Steps: []schema.Step{{ID: fmt.Sprintf("s%d", i), Type: schema.StepTool, Tool: name, Action: "run"}},
+		})
+	}
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "test"},
+		Steps: []schema.Step{
+			{ID: "par", Type: schema.StepParallel, Branches: branches},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	return rb, tools
+}
+
+// T155: only branches whose contracts conflict are serialized; the others
+// keep running concurrently
+func TestEngine_ParallelPartialSerialization(t *testing.T) {
+	rb, tools := parallelToolRunbook([]string{"service", "service", "database"})
+
+	var traceBuf bytes.Buffer
+	mock := &overlapToolExecutor{delay: 50 * time.Millisecond, active: map[string]bool{}, overlap: map[[2]string]bool{}}
+	eng := New(rb, RunConfig{RunID: "r1", Mode: "real", Trace: trace.NewWriter(&traceBuf, "r1"), ToolExec: mock})
+	eng.tools = tools
+
+	result := eng.Run(context.Background())
+	if result.Status != "completed" {
+		t.Fatalf("status = %q, error = %v", result.Status, result.Error)
+	}
+	if mock.overlap[[2]string{"tool0", "tool1"}] {
+		t.Error("conflicting branches b0 and b1 ran concurrently")
+	}
+	if !mock.overlap[[2]string{"tool0", "tool2"}] {
+		t.Error("non-conflicting branch b2 did not run alongside b0")
+	}
+	if !strings.Contains(traceBuf.String(), `"serialized":true`) {
+		t.Error("expected serialized=true in trace")
+	}
+	for _, id := range []string{"s0", "s1", "s2"} {
+		if !slices.Contains(eng.VisitedSteps, id) {
+			t.Errorf("visited = %v, missing %s", eng.VisitedSteps, id)
+		}
+	}
+}
+
+func TestConflictGroups(t *testing.T) {
+	contracts := []contract.Contract{
+		{Writes: []string{"service"}},
+		{Reads: []string{"database"}},
+		{Reads: []string{"service"}},
+		{Writes: []string{"database"}},
+		{Reads: []string{"cache"}},
+	}
+	got := conflictGroups(contracts)
+	want := [][]int{{0, 2}, {1, 3}, {4}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("conflictGroups = %v, want %v", got, want)
+	}
+}
+
+// BenchmarkParallelNonConflicting shows that branches without conflicts run
+// concurrently: each op takes about one branch delay, not four.
+func BenchmarkParallelNonConflicting(b *testing.B) {
+	rb, tools := parallelToolRunbook([]string{"a", "b", "c", "d"})
+	mock := &overlapToolExecutor{delay: 5 * time.Millisecond, active: map[string]bool{}, overlap: map[[2]string]bool{}}
+	for b.Loop() {
+		eng := New(rb, RunConfig{RunID: "r1", Mode: "real", ToolExec: mock})
+		eng.tools = tools
+		if result := eng.Run(context.Background()); result.Status != "completed" {
+			b.Fatalf("status = %q, error = %v", result.Status, result.Error)
+		}
+	}
+}
+
+// T159: not: inverts an assertion, with a message naming the inverted
+// check; an assertion that cannot be evaluated still fails
+func TestEngine_NotAssertion(t *testing.T) {
+	rb := &schema.Runbook{APIVersion: "kernel/v0", Meta: schema.Meta{Name: "test"}}
+	eng := New(rb, RunConfig{RunID: "r1", Vars: map[string]string{"status": "ok"}})
+
+	tests := []struct {
+		assertion  schema.Assertion
+		wantPassed bool
+		wantMsg    string
+	}{
+		{schema.Assertion{Type: "equals", Value: "{{ .status }}", Expected: "error", Not: true}, true, ""},
+		{schema.Assertion{Type: "equals", Value: "{{ .status }}", Expected: "ok", Not: true},
+			false, `expected "{{ .status }}" to NOT equal "ok", got "ok"`},
+		{schema.Assertion{Type: "contains", Value: "{{ .status }}", Expected: "o", Not: true}, false, `"ok" contains "o"`},
+		{schema.Assertion{Type: "matches", Value: "{{ .status }}", Pattern: "^err", Not: true}, true, ""},
+		{schema.Assertion{Type: "matches", Value: "{{ .status }}", Pattern: "([", Not: true}, false, "pattern: "},
+		{schema.Assertion{Type: "equals", Value: "{{ .status", Expected: "x", Not: true}, false, "value template"},
+	}
+	for _, tt := range tests {
+		passed, msg := eng.evaluateAssertion(tt.assertion)
+		if passed != tt.wantPassed || !strings.HasPrefix(msg, tt.wantMsg) {
+			t.Errorf("%+v: passed = %v, msg = %q; want %v, %q", tt.assertion, passed, msg, tt.wantPassed, tt.wantMsg)
+		}
+	}
+}
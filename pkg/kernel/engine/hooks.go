@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/governance"
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
+	"github.com/ormasoftchile/gert/pkg/kernel/trace"
+)
+
+// hookTimeout bounds a single hook invocation so a stuck integration cannot
+// stall the run.
+const hookTimeout = 30 * time.Second
+
+// hookEvent is what a hook learns about a step. Command hooks receive it as
+// GERT_* environment variables, URL hooks as a JSON POST body.
+type hookEvent struct {
+	Hook     string `json:"hook"` // "pre" or "post"
+	RunID    string `json:"run_id"`
+	StepID   string `json:"step_id"`
+	Status   string `json:"status"`
+	Captures string `json:"captures"` // JSON object of the step's outputs
+}
+
+// stepHooks returns the pre and post hooks for a step, falling back to
+// meta.defaults. Both are empty when hooks are skipped for the run, and
+// outside real mode: a dry-run or probe must not notify the systems hooks
+// report to.
+func (e *Engine) stepHooks(step schema.Step) (pre, post string) {
+	if e.cfg.SkipHooks || e.cfg.Mode != "real" {
+		return "", ""
+	}
+	pre, post = step.PreHook, step.PostHook
+	if d := e.rb.Meta.Defaults; d != nil {
+		if pre == "" {
+			pre = d.PreHook
+		}
+		if post == "" {
+			post = d.PostHook
+		}
+	}
+	return pre, post
+}
+
+// runHook invokes a lifecycle hook. A hook that fails, times out or is not
+// in governance allowed_hooks emits hook_warning; the step is unaffected.
+func (e *Engine) runHook(ctx context.Context, kind, hook, stepID, status string) {
+	evt := hookEvent{
+		Hook:     kind,
+		RunID:    e.cfg.RunID,
+		StepID:   stepID,
+		Status:   status,
+		Captures: "{}",
+	}
+	if kind == "post" {
+		if outputs, ok := e.vars[stepID].(map[string]any); ok {
+			if b, err := json.Marshal(outputs); err == nil {
+				evt.Captures = trace.Redact(string(b), e.secretEnvVars())
+			}
+		}
+	}
+
+	var err error
+	if !governance.AllowHook(hook, e.rb.Meta.Governance) {
+		err = fmt.Errorf("hook not allowed by governance allowed_hooks")
+	} else {
+		hctx, cancel := context.WithTimeout(ctx, hookTimeout)
+		if isHookURL(hook) {
+			err = postHook(hctx, hook, evt)
+		} else {
+			err = execHook(hctx, hook, evt)
+		}
+		cancel()
+	}
+	if err == nil {
+		return
+	}
+
+	fmt.Fprintf(e.cfg.Stdout, "  ⚠ %s %s_hook: %v\n", stepID, kind, err)
+	if e.trace != nil {
+		e.trace.Emit(trace.EventHookWarning, map[string]any{
+			"step_id": stepID,
+			"hook":    kind,
+			"target":  hook,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// hookStatus maps a step result to the status reported to post hooks.
+func hookStatus(result *RunResult) string {
+	if result == nil {
+		return string(trace.StatusSuccess)
+	}
+	return result.Status
+}
+
+func isHookURL(hook string) bool {
+	return strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://")
+}
+
+// execHook runs a command hook directly (no shell) with the event in its
+// environment. Stdout is discarded; stderr is reported on failure.
+func execHook(ctx context.Context, hook string, evt hookEvent) error {
+	argv := strings.Fields(hook)
+	if len(argv) == 0 {
+		return fmt.Errorf("empty hook command")
+	}
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Env = append(os.Environ(),
+		"GERT_HOOK="+evt.Hook,
+		"GERT_RUN_ID="+evt.RunID,
+		"GERT_STEP_ID="+evt.StepID,
+		"GERT_STEP_STATUS="+evt.Status,
+		"GERT_CAPTURES="+evt.Captures,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// postHook sends the event to a URL hook; any non-2xx response is a failure.
+func postHook(ctx context.Context, url string, evt hookEvent) error {
+	body, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("hook returned %s", resp.Status)
+	}
+	return nil
+}
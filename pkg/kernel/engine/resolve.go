@@ -3,9 +3,15 @@ package engine
 import (
 	"context"
 	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/schema"
 	"github.com/ormasoftchile/gert/pkg/kernel/trace"
+	"gopkg.in/yaml.v3"
 )
 
 // InputResolver resolves a single input binding. Kernel-defined interface,
@@ -102,3 +108,117 @@ func ResolveInputs(
 
 	return result, nil
 }
+
+// LoadVarFiles reads host vars from YAML files, each a flat map of names to
+// scalar values, applied in order so later files override earlier ones.
+// Hosts apply --var flags on top of the result.
+func LoadVarFiles(paths []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("var file: %w", err)
+		}
+		var raw map[string]any
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("var file %s: %w", path, err)
+		}
+		for name, v := range raw {
+			switch v.(type) {
+			case map[string]any, []any:
+				return nil, fmt.Errorf("var file %s: %q must be a scalar value", path, name)
+			case nil:
+				vars[name] = ""
+			default:
+				vars[name] = fmt.Sprint(v)
+			}
+		}
+	}
+	return vars, nil
+}
+
+// SecretVarWarnings flags host vars that carry a secret the runbook declares
+// in meta.secrets: a var named after the secret's env var, or a value
+// containing its current value. Secrets should come from the environment,
+// not from files on disk.
+func SecretVarWarnings(rb *schema.Runbook, vars map[string]string) []string {
+	var warnings []string
+	for _, secret := range rb.Meta.Secrets {
+		if secret.Env == "" {
+			continue
+		}
+		if _, ok := vars[secret.Env]; ok {
+			warnings = append(warnings, fmt.Sprintf("var %q is declared as a secret; set it in the environment instead", secret.Env))
+			continue
+		}
+		val := os.Getenv(secret.Env)
+		if val == "" {
+			continue
+		}
+		for _, name := range slices.Sorted(maps.Keys(vars)) {
+			if strings.Contains(vars[name], val) {
+				warnings = append(warnings, fmt.Sprintf("var %q contains the value of secret %s", name, secret.Env))
+			}
+		}
+	}
+	return warnings
+}
+
+// CoerceVars converts host vars, which arrive as strings, to the types
+// declared in meta.inputs (int, number, bool) so expressions compare them
+// as values. Undeclared vars become numbers when they round-trip exactly
+// ("100" and "2.5", but not "007" or "1.10"). A value that does not parse
+// as its declared type stays a string and is reported as a warning.
+func CoerceVars(rb *schema.Runbook, vars map[string]string) (map[string]any, []string) {
+	out := make(map[string]any, len(vars))
+	var warnings []string
+	for _, name := range slices.Sorted(maps.Keys(vars)) {
+		raw := vars[name]
+		param, declared := rb.Meta.Inputs[name]
+		if !declared {
+			out[name] = coerceNumber(raw)
+			continue
+		}
+		v, err := coerceValue(param.Type, raw)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("input %q: %q is not a valid %s; using it as a string", name, raw, param.Type))
+			v = raw
+		}
+		out[name] = v
+	}
+	return out, warnings
+}
+
+// coerceValue parses s as a declared input type. Types without a scalar
+// form (string, object, array) keep the string.
+func coerceValue(typ, s string) (any, error) {
+	t := strings.TrimSpace(s)
+	switch typ {
+	case "int", "integer":
+		n, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return int(n), nil
+	case "number", "float":
+		return strconv.ParseFloat(t, 64)
+	case "bool", "boolean":
+		return strconv.ParseBool(t)
+	case "", "any":
+		return coerceNumber(s), nil
+	default:
+		return s, nil
+	}
+}
+
+// coerceNumber returns s as an int or float64 when it is the canonical
+// form of that number, and s unchanged otherwise.
+func coerceNumber(s string) any {
+	if n, err := strconv.Atoi(s); err == nil && strconv.Itoa(n) == s {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == s {
+		return f
+	}
+	return s
+}
@@ -2,6 +2,9 @@ package engine
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/contract"
@@ -96,3 +99,87 @@ func TestResolveInputs_TracesSource(t *testing.T) {
 		t.Errorf("threshold source = %q, want default", sources["threshold"])
 	}
 }
+
+func TestLoadVarFiles_LaterFilesOverride(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	prod := filepath.Join(dir, "prod.yaml")
+	os.WriteFile(base, []byte("region: westus\nreplicas: 3\ndebug: false\n"), 0o644)
+	os.WriteFile(prod, []byte("region: eastus\n"), 0o644)
+
+	vars, err := LoadVarFiles([]string{base, prod})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars["region"] != "eastus" || vars["replicas"] != "3" || vars["debug"] != "false" {
+		t.Errorf("vars = %v", vars)
+	}
+
+	nested := filepath.Join(dir, "nested.yaml")
+	os.WriteFile(nested, []byte("hosts: [a, b]\n"), 0o644)
+	if _, err := LoadVarFiles([]string{nested}); err == nil || !strings.Contains(err.Error(), "scalar") {
+		t.Errorf("expected scalar error for a list value, got %v", err)
+	}
+}
+
+func TestSecretVarWarnings(t *testing.T) {
+	t.Setenv("API_TOKEN", "s3cr3t-value")
+	rb := &schema.Runbook{Meta: schema.Meta{Secrets: []schema.SecretRef{{Env: "API_TOKEN"}, {Env: "DB_PASSWORD"}}}}
+
+	warnings := SecretVarWarnings(rb, map[string]string{
+		"header":      "Bearer s3cr3t-value",
+		"DB_PASSWORD": "hunter2",
+		"region":      "eastus",
+	})
+	if len(warnings) != 2 {
+		t.Fatalf("warnings = %v, want 2", warnings)
+	}
+	if !strings.Contains(warnings[0], `"header" contains the value of secret API_TOKEN`) ||
+		!strings.Contains(warnings[1], `"DB_PASSWORD" is declared as a secret`) {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
+
+func TestCoerceVars(t *testing.T) {
+	rb := &schema.Runbook{
+		Meta: schema.Meta{
+			Inputs: map[string]contract.ParamDef{
+				"threshold": {Type: "int"},
+				"ratio":     {Type: "number"},
+				"dry":       {Type: "bool"},
+				"zone":      {Type: "string"},
+				"replicas":  {Type: "int"},
+			},
+		},
+	}
+	vars, warnings := CoerceVars(rb, map[string]string{
+		"threshold": "100",
+		"ratio":     "0.75",
+		"dry":       "true",
+		"zone":      "42",
+		"replicas":  "three",
+		"count":     "7",    // undeclared, canonical int
+		"version":   "1.10", // undeclared, not canonical: stays a string
+		"build":     "007",
+		"name":      "api",
+	})
+	want := map[string]any{
+		"threshold": 100,
+		"ratio":     0.75,
+		"dry":       true,
+		"zone":      "42",
+		"replicas":  "three",
+		"count":     7,
+		"version":   "1.10",
+		"build":     "007",
+		"name":      "api",
+	}
+	for k, v := range want {
+		if vars[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, vars[k], v)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], `input "replicas": "three" is not a valid int`) {
+		t.Errorf("warnings = %v", warnings)
+	}
+}
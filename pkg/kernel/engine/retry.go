@@ -0,0 +1,101 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
+	"github.com/ormasoftchile/gert/pkg/kernel/trace"
+)
+
+// executeWithRetry runs attempt until it succeeds or the step's retry policy
+// is exhausted. Each failed attempt that will be retried emits step_retry.
+// Outputs from the last successful attempt remain in vars.
+func (e *Engine) executeWithRetry(ctx context.Context, step schema.Step, stepID string, attempt func() *RunResult) *RunResult {
+	if step.Retry == nil || step.Retry.Max <= 1 {
+		return attempt()
+	}
+
+	for n := 1; ; n++ {
+		result := attempt()
+		if result == nil || result.Error == nil || n >= step.Retry.Max {
+			return result
+		}
+
+		delay := retryDelay(step.Retry, n)
+		if e.trace != nil {
+			e.trace.Emit(trace.EventStepRetry, map[string]any{
+				"step_id":      stepID,
+				"attempt":      n,
+				"max_attempts": step.Retry.Max,
+				"delay":        delay.String(),
+				"error":        result.Error.Error(),
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(delay):
+		}
+	}
+}
+
+// retryDelay returns the wait before the attempt following attempt n (1-based).
+func retryDelay(p *schema.RetryPolicy, n int) time.Duration {
+	base, err := time.ParseDuration(p.Delay)
+	if err != nil || base <= 0 {
+		return 0
+	}
+	switch p.Backoff {
+	case schema.BackoffExponential:
+		return base * time.Duration(1<<(n-1))
+	case schema.BackoffLinear:
+		return base * time.Duration(n)
+	default:
+		return base
+	}
+}
+
+// routeFailure returns the index in steps of the on_failure target when
+// result is a failure of a step that declares one, or -1 to stop the run.
+// The failure message is exposed to the target as {{ .<step>.error }}. Each step
+// routes at most once per block, so a failing target cannot loop back.
+func (e *Engine) routeFailure(ctx context.Context, steps []schema.Step, step schema.Step, stepID string, result *RunResult, routed map[string]bool) int {
+	if step.OnFailure == "" || routed[stepID] || ctx.Err() != nil {
+		return -1
+	}
+	if result.Status != "failed" && result.Status != "error" {
+		return -1
+	}
+	for j, s := range steps {
+		if s.ID != step.OnFailure {
+			continue
+		}
+		routed[stepID] = true
+		msg := ""
+		if result.Error != nil {
+			msg = result.Error.Error()
+		}
+		outputs := map[string]any{"error": msg}
+		if prev, ok := e.vars[stepID].(map[string]any); ok {
+			for k, v := range prev {
+				if k != "error" {
+					outputs[k] = v
+				}
+			}
+		}
+		e.vars[stepID] = outputs
+		if e.trace != nil {
+			e.trace.Emit(trace.EventStepOnFailure, map[string]any{
+				"step_id": stepID,
+				"target":  step.OnFailure,
+				"error":   msg,
+			})
+		}
+		fmt.Fprintf(e.cfg.Stdout, "  ↪ %s failed, continuing at %s\n", stepID, step.OnFailure)
+		return j
+	}
+	return -1
+}
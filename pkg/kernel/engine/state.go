@@ -1,12 +1,32 @@
 package engine
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/evidence"
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
+	"gopkg.in/yaml.v3"
 )
 
+// ManifestFile is the run manifest written to a run's directory.
+const ManifestFile = "run.yaml"
+
+// StateFile holds a paused run's RunState in its directory.
+const StateFile = "state.json"
+
+// TraceFile is the trace written to a run's directory unless exec is given
+// --trace.
+const TraceFile = "trace.jsonl"
+
+// DefaultRunsDir is where run directories are written unless exec is given
+// --output-dir, relative to the working directory.
+const DefaultRunsDir = "runs"
+
 // RunState captures the engine state at a point in time for resume.
 type RunState struct {
 	RunID         string          `json:"run_id"`
@@ -17,14 +37,20 @@ type RunState struct {
 	PendingTicket *ApprovalTicket `json:"pending_ticket,omitempty"`
 }
 
-// SaveState persists the run state to a JSON file for later resume.
+// SaveState persists the run state to runs/<run-id>/state.json for later
+// resume.
 func SaveState(state *RunState) error {
-	dir := filepath.Join("runs", state.RunID)
+	return SaveStateIn(DefaultRunsDir, state)
+}
+
+// SaveStateIn persists the run state to <runsDir>/<run-id>/state.json.
+func SaveStateIn(runsDir string, state *RunState) error {
+	dir := filepath.Join(runsDir, state.RunID)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return fmt.Errorf("create state dir: %w", err)
 	}
 
-	path := filepath.Join(dir, "state.json")
+	path := filepath.Join(dir, StateFile)
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal state: %w", err)
@@ -36,9 +62,15 @@ func SaveState(state *RunState) error {
 	return nil
 }
 
-// LoadState reads a persisted run state from disk.
+// LoadState reads a persisted run state from runs/<run-id>/state.json.
 func LoadState(runID string) (*RunState, error) {
-	path := filepath.Join("runs", runID, "state.json")
+	return LoadStateFrom(DefaultRunsDir, runID)
+}
+
+// LoadStateFrom reads a persisted run state from
+// <runsDir>/<run-id>/state.json.
+func LoadStateFrom(runsDir, runID string) (*RunState, error) {
+	path := filepath.Join(runsDir, runID, StateFile)
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read state: %w", err)
@@ -50,3 +82,104 @@ func LoadState(runID string) (*RunState, error) {
 	}
 	return &state, nil
 }
+
+// NewRunID returns a run ID unique to this run, in the form
+// YYYYMMDDTHHmmss-xxxxxxxx, so runs sharing an output directory never
+// overwrite each other.
+func NewRunID() string {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%x", time.Now().Format("20060102T150405"), suffix)
+}
+
+// RunManifest summarizes a run in run.yaml. Exec writes one for every run,
+// including runs cut short (e.g. by --timeout) so the partial run can be
+// inspected; gert history and gert clean read them.
+type RunManifest struct {
+	RunID         string          `yaml:"run_id"`
+	Runbook       string          `yaml:"runbook"`
+	Actor         string          `yaml:"actor,omitempty"`
+	Mode          string          `yaml:"mode"`
+	StartedAt     string          `yaml:"started_at"`
+	EndedAt       string          `yaml:"ended_at"`
+	Status        string          `yaml:"status"`
+	Outcome       *schema.Outcome `yaml:"outcome,omitempty"`
+	Error         string          `yaml:"error,omitempty"`
+	StepsExecuted int             `yaml:"steps_executed"`
+	LastStep      string          `yaml:"last_step,omitempty"` // the step running when the run ended
+}
+
+// Manifest returns the manifest of a run that ended with result.
+func (e *Engine) Manifest(result *RunResult) *RunManifest {
+	runbook := e.cfg.RunbookPath
+	if runbook == "" {
+		runbook = e.rb.Meta.Name
+	}
+	m := &RunManifest{
+		RunID:         e.cfg.RunID,
+		Runbook:       runbook,
+		Actor:         e.actor(),
+		Mode:          e.cfg.Mode,
+		StartedAt:     e.startTime.UTC().Format(time.RFC3339),
+		EndedAt:       e.startTime.Add(result.Duration).UTC().Format(time.RFC3339),
+		Status:        result.Status,
+		Outcome:       result.Outcome,
+		StepsExecuted: len(e.VisitedSteps),
+	}
+	if n := len(e.VisitedSteps); n > 0 {
+		m.LastStep = e.VisitedSteps[n-1]
+	}
+	if result.Error != nil {
+		m.Error = result.Error.Error()
+	}
+	return m
+}
+
+// WriteManifest writes m to runs/<run-id>/run.yaml and returns the run
+// directory.
+func WriteManifest(m *RunManifest) (string, error) {
+	return WriteManifestIn(DefaultRunsDir, m)
+}
+
+// WriteManifestIn writes m to <runsDir>/<run-id>/run.yaml and returns the
+// run directory. When GERT_SIGNING_KEY is set, it also signs the manifest
+// into run.yaml.sig.
+func WriteManifestIn(runsDir string, m *RunManifest) (string, error) {
+	dir := filepath.Join(runsDir, m.RunID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create run dir: %w", err)
+	}
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), data, 0o644); err != nil {
+		return "", fmt.Errorf("write manifest: %w", err)
+	}
+
+	key, err := evidence.SigningKeyFromEnv()
+	if err != nil {
+		return dir, fmt.Errorf("sign manifest: %w", err)
+	}
+	if key != nil {
+		if _, err := evidence.SignManifest(dir, key); err != nil {
+			return dir, fmt.Errorf("sign manifest: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+// CheckWritable creates dir if needed and verifies a file can be written in
+// it, so a run fails before it starts rather than when saving artifacts.
+func CheckWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("output directory %s: %w", dir, err)
+	}
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("output directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
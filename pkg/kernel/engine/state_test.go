@@ -1,9 +1,16 @@
 package engine
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/ormasoftchile/gert/pkg/evidence"
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
 )
 
 func TestSaveLoadState(t *testing.T) {
@@ -57,3 +64,118 @@ func TestLoadState_NotFound(t *testing.T) {
 		t.Error("expected error for nonexistent state")
 	}
 }
+
+func TestSaveStateIn_LoadStateFrom(t *testing.T) {
+	runsDir := filepath.Join(t.TempDir(), "gert-runs")
+	state := &RunState{RunID: "run-9", RunbookPath: "runbooks/test.yaml", StepIndex: 1}
+	if err := SaveStateIn(runsDir, state); err != nil {
+		t.Fatalf("SaveStateIn: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(runsDir, "run-9", StateFile)); err != nil {
+		t.Fatalf("state not written under runsDir: %v", err)
+	}
+	loaded, err := LoadStateFrom(runsDir, "run-9")
+	if err != nil {
+		t.Fatalf("LoadStateFrom: %v", err)
+	}
+	if loaded.StepIndex != 1 || loaded.RunbookPath != state.RunbookPath {
+		t.Errorf("loaded = %+v", loaded)
+	}
+	if _, err := LoadState("run-9"); err == nil {
+		t.Error("LoadState found a run saved under another directory")
+	}
+}
+
+func TestNewRunID_Unique(t *testing.T) {
+	a, b := NewRunID(), NewRunID()
+	if a == b {
+		t.Errorf("NewRunID returned %q twice", a)
+	}
+	if strings.ContainsAny(a, `/\`) {
+		t.Errorf("run ID %q is not a plain directory name", a)
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	dir, err := WriteManifest(&RunManifest{
+		RunID:   "run-7",
+		Runbook: "runbooks/spin.yaml",
+		Mode:    "real",
+		Status:  "error",
+		Outcome: &schema.Outcome{Category: schema.OutcomeTimedOut, Code: "run_timeout"},
+	})
+	if err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	if dir != filepath.Join("runs", "run-7") {
+		t.Errorf("dir = %q", dir)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatalf("read manifest: %v", err)
+	}
+	if !strings.Contains(string(data), "category: timed_out") {
+		t.Errorf("manifest missing timed_out outcome:\n%s", data)
+	}
+}
+
+func TestWriteManifestIn(t *testing.T) {
+	runsDir := filepath.Join(t.TempDir(), "gert-runs")
+	if err := CheckWritable(runsDir); err != nil {
+		t.Fatalf("CheckWritable: %v", err)
+	}
+	dir, err := WriteManifestIn(runsDir, &RunManifest{RunID: "run-8", Mode: "real", Status: "error"})
+	if err != nil {
+		t.Fatalf("WriteManifestIn: %v", err)
+	}
+	if dir != filepath.Join(runsDir, "run-8") {
+		t.Errorf("dir = %q", dir)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err != nil {
+		t.Errorf("manifest not written: %v", err)
+	}
+	entries, _ := os.ReadDir(runsDir)
+	if len(entries) != 1 {
+		t.Errorf("write check left files behind: %v", entries)
+	}
+}
+
+func TestWriteManifestIn_SignsWithEnvKey(t *testing.T) {
+	runsDir := t.TempDir()
+	dir, err := WriteManifestIn(runsDir, &RunManifest{RunID: "unsigned", Mode: "real"})
+	if err != nil {
+		t.Fatalf("WriteManifestIn: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, evidence.SignatureFile)); !os.IsNotExist(err) {
+		t.Errorf("manifest signed without %s (err %v)", evidence.EnvSigningKey, err)
+	}
+
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 7
+	key := ed25519.NewKeyFromSeed(seed)
+	t.Setenv(evidence.EnvSigningKey, base64.StdEncoding.EncodeToString(seed))
+	dir, err = WriteManifestIn(runsDir, &RunManifest{RunID: "signed", Mode: "real", Status: "completed"})
+	if err != nil {
+		t.Fatalf("WriteManifestIn: %v", err)
+	}
+	if _, err := evidence.VerifyManifest(dir, key.Public().(ed25519.PublicKey)); err != nil {
+		t.Errorf("VerifyManifest: %v", err)
+	}
+
+	t.Setenv(evidence.EnvSigningKey, "not-a-key")
+	if _, err := WriteManifestIn(runsDir, &RunManifest{RunID: "bad-key"}); err == nil {
+		t.Error("expected an error for an unusable signing key")
+	}
+}
+
+func TestCheckWritable_NotADirectory(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckWritable(filepath.Join(file, "runs")); err == nil {
+		t.Error("expected error for a path under a regular file")
+	}
+}
@@ -1,6 +1,8 @@
 package engine
 
 import (
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/schema"
@@ -39,6 +41,95 @@ func CheckVisibility(vis *schema.Visibility, varPath string) bool {
 	return true // no allow list = everything allowed (minus denies above)
 }
 
+// visibilityView is the variable scope a step with visibility rules runs
+// in: a filtered copy of the engine vars plus what is needed to merge the
+// step's writes back into the full scope afterwards.
+type visibilityView struct {
+	full    map[string]any
+	partial map[string]map[string]any // top-level keys replaced by a filtered copy
+}
+
+// applyVisibility swaps e.vars for a copy without the paths vis hides and
+// returns the view to restore, with the sorted dotted paths that were
+// removed. A map is dropped whole when its own path is denied, or when it
+// is outside the allow list and nothing under it is visible.
+func (e *Engine) applyVisibility(vis *schema.Visibility) (*visibilityView, []string) {
+	view := &visibilityView{full: e.vars, partial: make(map[string]map[string]any)}
+	var denied []string
+	filtered := filterVars(e.vars, vis, "", &denied)
+	for k, v := range filtered {
+		if m, ok := v.(map[string]any); ok && !sameMap(m, e.vars[k]) {
+			view.partial[k] = m
+		}
+	}
+	e.vars = filtered
+	sort.Strings(denied)
+	return view, denied
+}
+
+// restoreVisibility merges the writes made in the filtered scope into the full scope
+// and makes it current again. Hidden variables keep their values unless the
+// step overwrote them.
+func (e *Engine) restoreVisibility(view *visibilityView) {
+	for k, v := range e.vars {
+		if m, ok := v.(map[string]any); ok && sameMap(m, view.partial[k]) {
+			continue // still the filtered copy; keep the full original
+		}
+		view.full[k] = v
+	}
+	e.vars = view.full
+}
+
+func filterVars(vars map[string]any, vis *schema.Visibility, prefix string, denied *[]string) map[string]any {
+	out := make(map[string]any, len(vars))
+	for k, v := range vars {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		if deniedPath(vis, path) {
+			*denied = append(*denied, path)
+			continue
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			if CheckVisibility(vis, path) {
+				out[k] = v
+			} else {
+				*denied = append(*denied, path)
+			}
+			continue
+		}
+		var sub []string
+		child := filterVars(m, vis, path, &sub)
+		if len(child) == 0 && !CheckVisibility(vis, path) {
+			*denied = append(*denied, path)
+			continue
+		}
+		*denied = append(*denied, sub...)
+		if len(sub) == 0 {
+			out[k] = m // nothing hidden below; keep the original
+		} else {
+			out[k] = child
+		}
+	}
+	return out
+}
+
+func deniedPath(vis *schema.Visibility, path string) bool {
+	for _, pattern := range vis.Deny {
+		if globMatch(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameMap(a map[string]any, b any) bool {
+	bm, ok := b.(map[string]any)
+	return ok && a != nil && bm != nil && reflect.ValueOf(a).Pointer() == reflect.ValueOf(bm).Pointer()
+}
+
 // globMatch matches a dot-separated path against a glob pattern.
 // `*` matches exactly one segment, `**` matches zero or more segments.
 func globMatch(pattern, path string) bool {
@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/schema"
@@ -89,3 +90,51 @@ func TestGlobMatch_DoubleStar(t *testing.T) {
 		t.Error("foo.**.baz should match foo.a.b.baz")
 	}
 }
+
+func TestFilterVars(t *testing.T) {
+	vars := map[string]any{
+		"question": "q",
+		"token":    "t",
+		"scope": map[string]any{
+			"round": map[string]any{
+				"0": map[string]any{
+					"agent_a": map[string]any{"response": "yes"},
+					"agent_b": map[string]any{"response": "no"},
+				},
+			},
+		},
+	}
+	vis := &schema.Visibility{
+		Allow: []string{"question", "scope.round.0.**"},
+		Deny:  []string{"scope.round.0.agent_b"},
+	}
+	eng := &Engine{vars: vars}
+	view, denied := eng.applyVisibility(vis)
+
+	if got, want := strings.Join(denied, ","), "scope.round.0.agent_b,token"; got != want {
+		t.Errorf("denied = %s, want %s", got, want)
+	}
+	if _, ok := eng.vars["token"]; ok {
+		t.Error("token should be hidden")
+	}
+	round := eng.vars["scope"].(map[string]any)["round"].(map[string]any)["0"].(map[string]any)
+	if _, ok := round["agent_b"]; ok {
+		t.Error("agent_b should be hidden")
+	}
+	if _, ok := round["agent_a"]; !ok {
+		t.Error("agent_a should stay visible")
+	}
+	if _, ok := vars["scope"].(map[string]any)["round"].(map[string]any)["0"].(map[string]any)["agent_b"]; !ok {
+		t.Error("filtering must not modify the full scope")
+	}
+
+	// Writes made while filtered survive; hidden values come back
+	eng.vars["verdict"] = "approve"
+	eng.restoreVisibility(view)
+	if eng.vars["verdict"] != "approve" || eng.vars["token"] != "t" {
+		t.Errorf("restored vars = %v", eng.vars)
+	}
+	if _, ok := eng.vars["scope"].(map[string]any)["round"].(map[string]any)["0"].(map[string]any)["agent_b"]; !ok {
+		t.Error("agent_b should be restored")
+	}
+}
@@ -4,7 +4,10 @@ package eval
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
 )
@@ -29,6 +32,15 @@ func Resolve(tmpl string, vars map[string]any) (string, error) {
 	return buf.String(), nil
 }
 
+// Check parses a template without evaluating it, so malformed expressions
+// can be reported at validation time.
+func Check(tmpl string) error {
+	if _, err := template.New("").Funcs(builtinFuncs()).Parse(tmpl); err != nil {
+		return fmt.Errorf("template parse: %w", err)
+	}
+	return nil
+}
+
 // ResolveMap resolves all string values in a map[string]any.
 func ResolveMap(inputs map[string]any, vars map[string]any) (map[string]any, error) {
 	if inputs == nil {
@@ -52,6 +64,7 @@ func ResolveMap(inputs map[string]any, vars map[string]any) (map[string]any, err
 
 // EvalBool evaluates a template expression that should produce a boolean-ish result.
 // Returns true for "true", non-empty non-"false" strings. Empty and "false" return false.
+// An expression without {{ that names a variable path (see Lookup) tests that variable.
 func EvalBool(expr string, vars map[string]any) (bool, error) {
 	if expr == "" {
 		return true, nil // no condition = always true
@@ -59,12 +72,55 @@ func EvalBool(expr string, vars map[string]any) (bool, error) {
 	if expr == "default" {
 		return true, nil // default branch always matches
 	}
+	if path := strings.TrimSpace(expr); dottedPathRe.MatchString(path) {
+		// A bare dotted path such as round.0.check.passed reads the variable.
+		// Undotted words stay literals, even when a variable shares the name.
+		if val, ok := Lookup(vars, path); ok {
+			return truthy(fmt.Sprint(val)), nil
+		}
+	}
 	result, err := Resolve(expr, vars)
 	if err != nil {
 		return false, err
 	}
-	result = strings.TrimSpace(result)
-	return result != "" && result != "false" && result != "<no value>", nil
+	return truthy(result), nil
+}
+
+// dottedPathRe matches a variable path with at least one dot, such as a
+// scoped output (round.0.check.passed) or a nested key (check.status).
+var dottedPathRe = regexp.MustCompile(`^[A-Za-z_][\w-]*(\.[\w-]+)+$`)
+
+func truthy(s string) bool {
+	s = strings.TrimSpace(s)
+	return s != "" && s != "false" && s != "<no value>"
+}
+
+// Lookup resolves a dotted variable path. Segments may name a flat key that
+// itself contains dots (scoped outputs are stored as "round.0.check.passed")
+// or a key in a nested map, so "check.passed" finds vars["check"]["passed"].
+// The longest matching flat key is tried first.
+func Lookup(vars map[string]any, path string) (any, bool) {
+	if path == "" {
+		return nil, false
+	}
+	if v, ok := vars[path]; ok {
+		return v, true
+	}
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i] != '.' {
+			continue
+		}
+		v, ok := vars[path[:i]]
+		if !ok {
+			continue
+		}
+		if m, ok := v.(map[string]any); ok {
+			if val, ok := Lookup(m, path[i+1:]); ok {
+				return val, true
+			}
+		}
+	}
+	return nil, false
 }
 
 // builtinFuncs provides template functions for expressions.
@@ -77,10 +133,10 @@ func builtinFuncs() template.FuncMap {
 			return fmt.Sprint(a) != fmt.Sprint(b)
 		},
 		"gt": func(a, b any) bool {
-			return fmt.Sprint(a) > fmt.Sprint(b)
+			return compare(a, b) > 0
 		},
 		"lt": func(a, b any) bool {
-			return fmt.Sprint(a) < fmt.Sprint(b)
+			return compare(a, b) < 0
 		},
 		"contains": func(s, substr any) bool {
 			return strings.Contains(fmt.Sprint(s), fmt.Sprint(substr))
@@ -97,6 +153,10 @@ func builtinFuncs() template.FuncMap {
 			}
 			return val
 		},
+		"lookup": func(vars map[string]any, path string) any {
+			val, _ := Lookup(vars, path)
+			return val
+		},
 		"index": func(collection any, keys ...any) (any, error) {
 			// Delegate to the built-in index — just make it available
 			switch c := collection.(type) {
@@ -153,6 +213,36 @@ func indexRecursive(val any, keys []any) (any, error) {
 	}
 }
 
+// compare orders a and b numerically when both are numbers or numeric
+// strings, so typed vars and string captures compare as values (9 < 10),
+// and as strings otherwise.
+func compare(a, b any) int {
+	x, okA := toFloat(a)
+	y, okB := toFloat(b)
+	if okA && okB {
+		return cmp.Compare(x, y)
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
 func toInt(v any) (int, bool) {
 	switch n := v.(type) {
 	case int:
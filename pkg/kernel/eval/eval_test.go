@@ -100,6 +100,12 @@ func TestEvalBool(t *testing.T) {
 		{`{{ eq .x "yes" }}`, map[string]any{"x": "yes"}, true},
 		{`{{ eq .x "yes" }}`, map[string]any{"x": "no"}, false},
 		{`{{ ne .x "ok" }}`, map[string]any{"x": "err"}, true},
+		// gt/lt compare numbers as values, typed or not
+		{`{{ gt .count .threshold }}`, map[string]any{"count": 9, "threshold": 10}, false},
+		{`{{ gt .count .threshold }}`, map[string]any{"count": "9", "threshold": "10"}, false},
+		{`{{ lt .count .threshold }}`, map[string]any{"count": 9.5, "threshold": "10"}, true},
+		{`{{ gt .count 100 }}`, map[string]any{"count": 150}, true},
+		{`{{ lt .a .b }}`, map[string]any{"a": "abc", "b": "abd"}, true},
 	}
 
 	for _, tt := range tests {
@@ -124,3 +130,106 @@ func TestResolveMap_Nil(t *testing.T) {
 		t.Error("nil input should return nil")
 	}
 }
+
+func TestJSONPathString(t *testing.T) {
+	doc := `{"status": {"phase": "Running", "ready": true, "restarts": 3},
+		"items": [{"name": "a"}, {"name": "b", "ports": [80, 443]}],
+		"labels": {"app.kubernetes.io/name": "web"}, "owner": null}`
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"$.status.phase", "Running"},
+		{"$.status.ready", "true"},
+		{"$.status.restarts", "3"},
+		{"$.items[0].name", "a"},
+		{"$.items[-1].ports[1]", "443"},
+		{"$['labels']['app.kubernetes.io/name']", "web"},
+		{"$.owner", "null"},
+		{"$.items[1].ports", "[80,443]"},
+		{"$.status", `{"phase":"Running","ready":true,"restarts":3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := JSONPathString(doc, tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("JSONPathString(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	for path, want := range map[string]string{
+		"$.status.missing": `$.status: key "missing" not found`,
+		"$.items[2]":       "$.items: index 2 out of range (length 2)",
+		"$.items.name":     `$.items: cannot read key "name" of array`,
+		"$.status.phase.x": "$.status.phase: cannot apply .x to string",
+	} {
+		_, err := JSONPathString(doc, path)
+		if err == nil || err.Error() != want {
+			t.Errorf("JSONPathString(%q) error = %v, want %q", path, err, want)
+		}
+	}
+
+	if _, err := JSONPathString("not json", "$.a"); err == nil {
+		t.Error("expected invalid JSON error")
+	}
+}
+
+func TestCheckJSONPath(t *testing.T) {
+	for _, ok := range []string{"$", "$.a.b", "$.a[0]", "$[-1]", `$["a b"]`} {
+		if err := CheckJSONPath(ok); err != nil {
+			t.Errorf("CheckJSONPath(%q): %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "status", "$.", "$..a", "$.a[", "$.a[x]", "$.*", "$['a]"} {
+		if err := CheckJSONPath(bad); err == nil {
+			t.Errorf("CheckJSONPath(%q): expected error", bad)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	vars := map[string]any{
+		"round.0.check.passed": true,
+		"check":                map[string]any{"status": "ok"},
+		"verify.retry_count":   2,
+	}
+	for path, want := range map[string]any{
+		"round.0.check.passed": true,
+		"check.status":         "ok",
+		"verify.retry_count":   2,
+	} {
+		got, ok := Lookup(vars, path)
+		if !ok || got != want {
+			t.Errorf("Lookup(%q) = %v, %v; want %v", path, got, ok, want)
+		}
+	}
+	if _, ok := Lookup(vars, "round.1.check.passed"); ok {
+		t.Error("expected missing path")
+	}
+
+	if got, _ := EvalBool("round.0.check.passed", vars); !got {
+		t.Error("EvalBool should read a bare dotted path")
+	}
+	vars["round.1.check.passed"] = false
+	if got, _ := EvalBool("round.1.check.passed", vars); got {
+		t.Error("EvalBool should be false for a false variable")
+	}
+	// Only dotted paths are looked up: a bare word is a literal even when a
+	// variable has that name
+	vars["ready"] = false
+	if got, _ := EvalBool("ready", vars); !got {
+		t.Error("EvalBool should treat a bare word as a literal")
+	}
+	vars["false"] = true
+	if got, _ := EvalBool("false", vars); got {
+		t.Error("EvalBool should treat a bare false as a literal")
+	}
+	if got, _ := Resolve(`{{ lookup . "round.0.check.passed" }}`, vars); got != "true" {
+		t.Errorf("lookup = %q", got)
+	}
+}
@@ -0,0 +1,172 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathSegment is one step of a parsed JSONPath: an object key or an
+// array index (negative indexes count from the end).
+type jsonPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s jsonPathSegment) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return "." + s.key
+}
+
+// CheckJSONPath parses a JSONPath expression without evaluating it, so
+// malformed paths can be reported at validation time.
+func CheckJSONPath(path string) error {
+	_, err := parseJSONPath(path)
+	return err
+}
+
+// JSONPath evaluates a JSONPath expression against decoded JSON. The
+// supported subset is the root `$`, dot keys (`$.status.phase`), bracketed
+// keys (`$['content-type']`) and array indexes (`$.items[0]`, `$.items[-1]`).
+func JSONPath(data any, path string) (any, error) {
+	segs, err := parseJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	current := data
+	at := "$"
+	for _, seg := range segs {
+		switch c := current.(type) {
+		case map[string]any:
+			if seg.isIndex {
+				return nil, fmt.Errorf("%s: cannot index object with %s", at, seg)
+			}
+			val, ok := c[seg.key]
+			if !ok {
+				return nil, fmt.Errorf("%s: key %q not found", at, seg.key)
+			}
+			current = val
+		case []any:
+			if !seg.isIndex {
+				return nil, fmt.Errorf("%s: cannot read key %q of array", at, seg.key)
+			}
+			i := seg.index
+			if i < 0 {
+				i += len(c)
+			}
+			if i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("%s: index %d out of range (length %d)", at, seg.index, len(c))
+			}
+			current = c[i]
+		default:
+			return nil, fmt.Errorf("%s: cannot apply %s to %s", at, seg, jsonKind(current))
+		}
+		at += seg.String()
+	}
+	return current, nil
+}
+
+// JSONPathString decodes doc as JSON, evaluates path against it and renders
+// the result as text: strings as-is, numbers as written in doc, null as
+// "null", and objects and arrays as compact JSON.
+func JSONPathString(doc, path string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(doc))
+	dec.UseNumber()
+	var data any
+	if err := dec.Decode(&data); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+	val, err := JSONPath(data, path)
+	if err != nil {
+		return "", err
+	}
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "null", nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	default:
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(v); err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(buf.String(), "\n"), nil
+	}
+}
+
+func parseJSONPath(path string) ([]jsonPathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("json path %q must start with $", path)
+	}
+	var segs []jsonPathSegment
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			key := rest[:end]
+			if key == "" {
+				return nil, fmt.Errorf("json path %q: empty key", path)
+			}
+			if key == "*" {
+				return nil, fmt.Errorf("json path %q: wildcards are not supported", path)
+			}
+			segs = append(segs, jsonPathSegment{key: key})
+			rest = rest[end:]
+		case '[':
+			if q := rest[1:min(2, len(rest))]; q == "'" || q == `"` {
+				// Quoted keys may contain ']' and '.'
+				n := strings.Index(rest[2:], q+"]")
+				if n < 0 {
+					return nil, fmt.Errorf("json path %q: unterminated key", path)
+				}
+				segs = append(segs, jsonPathSegment{key: rest[2 : 2+n]})
+				rest = rest[2+n+2:]
+				continue
+			}
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("json path %q: missing ]", path)
+			}
+			idx, err := strconv.Atoi(strings.TrimSpace(rest[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("json path %q: invalid index %q", path, rest[1:end])
+			}
+			segs = append(segs, jsonPathSegment{index: idx, isIndex: true})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("json path %q: unexpected %q", path, rest[0])
+		}
+	}
+	return segs, nil
+}
+
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
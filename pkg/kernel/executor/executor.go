@@ -3,10 +3,14 @@ package executor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/eval"
@@ -21,9 +25,62 @@ type Result struct {
 	Outputs  map[string]any // extracted outputs mapped to contract
 }
 
-// RunTool executes a tool action via its declared transport.
+type envKey struct{}
+
+// WithEnv returns a context carrying extra KEY=value environment entries for
+// the tool process started under it.
+func WithEnv(ctx context.Context, env []string) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// EnvFromContext returns the entries set by WithEnv, or nil.
+func EnvFromContext(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return env
+}
+
+type cleanEnvKey struct{}
+
+// WithCleanEnv returns a context under which tool processes do not inherit
+// the parent environment: they get only their env entries and the
+// variables in MinimalEnv.
+func WithCleanEnv(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cleanEnvKey{}, true)
+}
+
+// CleanEnvFromContext reports whether WithCleanEnv applies to ctx.
+func CleanEnvFromContext(ctx context.Context) bool {
+	clean, _ := ctx.Value(cleanEnvKey{}).(bool)
+	return clean
+}
+
+// MinimalEnv names the parent environment variables a clean environment
+// keeps, so tools can still be found and locate the user's home.
+var MinimalEnv = []string{"PATH", "HOME", "USER"}
+
+// CleanEnviron returns the MinimalEnv variables set in the parent
+// environment followed by env. On Windows SystemRoot is kept as well,
+// since processes cannot start without it.
+func CleanEnviron(env []string) []string {
+	names := MinimalEnv
+	if runtime.GOOS == "windows" {
+		names = append(slices.Clip(names), "SystemRoot")
+	}
+	out := make([]string, 0, len(names)+len(env))
+	for _, name := range names {
+		if val, ok := os.LookupEnv(name); ok {
+			out = append(out, name+"="+val)
+		}
+	}
+	return append(out, env...)
+}
+
+// RunTool executes a tool action via its declared transport. env entries
+// (KEY=value) are added to the inherited process environment, or under
+// WithCleanEnv to the minimal one. The tool process is killed when ctx is
+// done (e.g. at the step's timeout).
 // Currently supports stdio only. jsonrpc and mcp are Phase 3+ / ecosystem.
-func RunTool(td *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any) (*Result, error) {
+func RunTool(ctx context.Context, td *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any, env []string) (*Result, error) {
 	action, ok := td.Actions[actionName]
 	if !ok {
 		return nil, fmt.Errorf("action %q not found in tool %q", actionName, td.Meta.Name)
@@ -36,7 +93,7 @@ func RunTool(td *schema.ToolDefinition, actionName string, inputs map[string]any
 
 	switch transport {
 	case "stdio":
-		return runStdio(td, &action, inputs, vars)
+		return runStdio(ctx, td, &action, inputs, vars, env)
 	case "jsonrpc":
 		return nil, fmt.Errorf("jsonrpc transport not yet implemented")
 	case "mcp":
@@ -47,7 +104,7 @@ func RunTool(td *schema.ToolDefinition, actionName string, inputs map[string]any
 }
 
 // runStdio executes a tool action by spawning a process.
-func runStdio(td *schema.ToolDefinition, action *schema.ToolAction, inputs map[string]any, vars map[string]any) (*Result, error) {
+func runStdio(ctx context.Context, td *schema.ToolDefinition, action *schema.ToolAction, inputs map[string]any, vars map[string]any, env []string) (*Result, error) {
 	if len(action.Argv) == 0 {
 		return nil, fmt.Errorf("stdio action has no argv")
 	}
@@ -72,12 +129,20 @@ func runStdio(td *schema.ToolDefinition, action *schema.ToolAction, inputs map[s
 	}
 
 	// Execute
-	cmd := exec.Command(binaryName, argv[1:]...) //#nosec G204 -- argv comes from tool definition authored by runbook owner
+	cmd := exec.CommandContext(ctx, binaryName, argv[1:]...) //#nosec G204 -- argv comes from tool definition authored by runbook owner
+	if CleanEnvFromContext(ctx) {
+		cmd.Env = CleanEnviron(env)
+	} else if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("exec %q: %w", argv[0], ctx.Err())
+	}
 	exitCode := 0
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
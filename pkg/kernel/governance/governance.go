@@ -2,6 +2,11 @@
 package governance
 
 import (
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
 	"github.com/ormasoftchile/gert/pkg/kernel/contract"
 	"github.com/ormasoftchile/gert/pkg/kernel/schema"
 )
@@ -15,8 +20,15 @@ type Decision struct {
 }
 
 // Evaluate evaluates governance policy against a resolved contract.
-// Returns the most restrictive matching decision.
+// Returns the most restrictive matching decision. Rules limited to
+// particular step types (steps:) never match; use EvaluateStep for those.
 func Evaluate(c *contract.Contract, policy *schema.GovernancePolicy) Decision {
+	return EvaluateStep("", c, policy)
+}
+
+// EvaluateStep is Evaluate for a step of the given type, so rules with a
+// steps: list apply only when it includes stepType.
+func EvaluateStep(stepType schema.StepType, c *contract.Contract, policy *schema.GovernancePolicy) Decision {
 	resolved := c.Resolved()
 	risk := resolved.Risk()
 
@@ -28,6 +40,9 @@ func Evaluate(c *contract.Contract, policy *schema.GovernancePolicy) Decision {
 	}
 
 	for _, rule := range policy.Rules {
+		if len(rule.Steps) > 0 && !slices.Contains(rule.Steps, stepType) {
+			continue
+		}
 		if ruleMatches(rule, &resolved, risk) {
 			action := schema.GovernanceDecision(rule.Action)
 			if rule.Default != "" {
@@ -49,6 +64,72 @@ func Evaluate(c *contract.Contract, policy *schema.GovernancePolicy) Decision {
 	}
 }
 
+// EvaluateEnv applies env rules to the environment variable names a step
+// sets and returns the more restrictive of d and the first matching rule.
+// Env rules match on names only; Evaluate never matches them.
+func EvaluateEnv(d Decision, names []string, policy *schema.GovernancePolicy) Decision {
+	if policy == nil || len(names) == 0 {
+		return d
+	}
+	for _, rule := range policy.Rules {
+		if len(rule.Env) == 0 || !envMatches(rule.Env, names) {
+			continue
+		}
+		return MostRestrictive(d, Decision{
+			Action:       schema.GovernanceDecision(rule.Action),
+			RiskLevel:    d.RiskLevel,
+			MinApprovers: rule.MinApprovers,
+			MatchedRule:  describeRule(rule),
+		})
+	}
+	return d
+}
+
+// envMatches reports whether any name matches any of the glob patterns.
+// An invalid pattern matches, so a typo blocks rather than allows.
+func envMatches(patterns, names []string) bool {
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if ok, err := path.Match(pattern, name); ok || err != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowHook reports whether policy permits a step hook to run. Commands
+// match allowed_hooks globs on their program (full path or base name), URLs
+// on their host. Without an allowed_hooks list every hook is allowed; an
+// invalid pattern never matches, so a typo blocks rather than allows.
+func AllowHook(hook string, policy *schema.GovernancePolicy) bool {
+	if policy == nil || len(policy.AllowedHooks) == 0 {
+		return true
+	}
+	var targets []string
+	if strings.HasPrefix(hook, "http://") || strings.HasPrefix(hook, "https://") {
+		u, err := url.Parse(hook)
+		if err != nil {
+			return false
+		}
+		targets = []string{u.Hostname()}
+	} else {
+		fields := strings.Fields(hook)
+		if len(fields) == 0 {
+			return false
+		}
+		targets = []string{fields[0], path.Base(fields[0])}
+	}
+	for _, pattern := range policy.AllowedHooks {
+		for _, t := range targets {
+			if ok, _ := path.Match(pattern, t); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // MostRestrictive returns the more restrictive of two governance decisions.
 // deny > require-approval > allow
 func MostRestrictive(a, b Decision) Decision {
@@ -76,6 +157,11 @@ func ruleMatches(rule schema.GovernanceRule, c *contract.Contract, risk contract
 		return true
 	}
 
+	// Env rules are applied by EvaluateEnv
+	if len(rule.Env) > 0 {
+		return false
+	}
+
 	// Risk-based matching
 	if rule.Risk != "" {
 		if contract.RiskLevel(rule.Risk) == risk {
@@ -152,6 +238,9 @@ func describeRule(rule schema.GovernanceRule) string {
 	if rule.Contract != nil {
 		return "contract match"
 	}
+	if len(rule.Env) > 0 {
+		return "env match"
+	}
 	return "unknown"
 }
 
@@ -172,6 +172,55 @@ func TestEvaluate_EffectsBased(t *testing.T) {
 }
 
 // T125b: HasContractViolationsDeny
+func TestEvaluateEnv(t *testing.T) {
+	policy := &schema.GovernancePolicy{
+		Rules: []schema.GovernanceRule{
+			{Env: []string{"AWS_*", "GCP_CREDENTIALS"}, Action: "deny"},
+			{Env: []string{"KUBECONFIG"}, Action: "require-approval", MinApprovers: 2},
+			{Default: "allow"},
+		},
+	}
+	c := &contract.Contract{Effects: []string{}}
+	allow := Evaluate(c, policy)
+	if allow.Action != schema.DecisionAllow {
+		t.Fatalf("env rules must not match in Evaluate, got %q", allow.Action)
+	}
+
+	if d := EvaluateEnv(allow, []string{"REGION", "AWS_PROFILE"}, policy); d.Action != schema.DecisionDeny || d.MatchedRule != "env match" {
+		t.Errorf("AWS_PROFILE: got %+v, want deny", d)
+	}
+	if d := EvaluateEnv(allow, []string{"KUBECONFIG"}, policy); d.Action != schema.DecisionRequireApproval || d.MinApprovers != 2 {
+		t.Errorf("KUBECONFIG: got %+v, want require-approval", d)
+	}
+	if d := EvaluateEnv(allow, []string{"REGION"}, policy); d.Action != schema.DecisionAllow {
+		t.Errorf("REGION: got %+v, want allow", d)
+	}
+	deny := Decision{Action: schema.DecisionDeny}
+	if d := EvaluateEnv(deny, []string{"KUBECONFIG"}, policy); d.Action != schema.DecisionDeny {
+		t.Errorf("a contract deny must win, got %+v", d)
+	}
+}
+
+func TestAllowHook(t *testing.T) {
+	if !AllowHook("anything --flag", nil) {
+		t.Error("nil policy must allow every hook")
+	}
+	policy := &schema.GovernancePolicy{AllowedHooks: []string{"dd-notify", "*.datadoghq.com", "["}}
+	cases := map[string]bool{
+		"dd-notify --event start":                true,
+		"/usr/local/bin/dd-notify":               true,
+		"curl https://example.com":               false,
+		"https://http-intake.datadoghq.com/v1/x": true,
+		"https://evil.example.com/datadoghq.com": false,
+		"":                                       false,
+	}
+	for hook, want := range cases {
+		if got := AllowHook(hook, policy); got != want {
+			t.Errorf("AllowHook(%q) = %v, want %v", hook, got, want)
+		}
+	}
+}
+
 func TestHasContractViolationsDeny(t *testing.T) {
 	// Policy with deny
 	policy := &schema.GovernancePolicy{
@@ -199,3 +248,23 @@ func TestHasContractViolationsDeny(t *testing.T) {
 		t.Error("expected false for nil policy")
 	}
 }
+
+func TestEvaluateStep_StepTypes(t *testing.T) {
+	policy := &schema.GovernancePolicy{
+		Rules: []schema.GovernanceRule{
+			{Effects: []string{"kubernetes"}, Steps: []schema.StepType{schema.StepTool}, Action: "require-approval", MinApprovers: 2},
+			{Default: "allow"},
+		},
+	}
+	c := &contract.Contract{Effects: []string{"kubernetes"}}
+
+	if d := EvaluateStep(schema.StepTool, c, policy); d.Action != schema.DecisionRequireApproval || d.MinApprovers != 2 {
+		t.Errorf("tool step: got %+v, want require-approval", d)
+	}
+	if d := EvaluateStep(schema.StepExtension, c, policy); d.Action != schema.DecisionAllow {
+		t.Errorf("extension step: got %+v, want allow", d)
+	}
+	if d := Evaluate(c, policy); d.Action != schema.DecisionAllow {
+		t.Errorf("Evaluate must skip step-limited rules, got %+v", d)
+	}
+}
@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/executor"
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
+)
+
+// fuzzSpread bounds how far a numeric value may move from its recorded
+// value, as a fraction of it.
+const fuzzSpread = 0.2
+
+// FuzzingExecutor replays a scenario like ReplayExecutor but mutates each
+// canned response: numbers move within ±20% of their recorded value,
+// arrays are shuffled, and string outputs the tool contract does not mark
+// required are randomly omitted. JSON stdout is mutated the same way.
+// Mutations are deterministic for a given seed.
+type FuzzingExecutor struct {
+	*ReplayExecutor
+	rng *rand.Rand
+}
+
+// NewFuzzingExecutor creates a fuzzing executor from a scenario and seed.
+func NewFuzzingExecutor(s *Scenario, seed int64) *FuzzingExecutor {
+	return &FuzzingExecutor{
+		ReplayExecutor: NewReplayExecutor(s),
+		rng:            rand.New(rand.NewPCG(uint64(seed), 0)),
+	}
+}
+
+// Execute returns the next canned response for the tool and action,
+// mutated. Implements engine.ToolExecutor.
+func (f *FuzzingExecutor) Execute(ctx context.Context, td *schema.ToolDefinition, actionName string, inputs map[string]any, vars map[string]any) (*executor.Result, error) {
+	result, err := f.ReplayExecutor.Execute(ctx, td, actionName, inputs, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	required := requiredOutputs(td, actionName)
+	for _, k := range sortedOutputKeys(result.Outputs) {
+		v := result.Outputs[k]
+		if _, ok := v.(string); ok && !required[k] && f.rng.IntN(4) == 0 {
+			delete(result.Outputs, k)
+			continue
+		}
+		result.Outputs[k] = f.mutate(v)
+	}
+
+	if trimmed := strings.TrimSpace(result.Stdout); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		dec := json.NewDecoder(strings.NewReader(trimmed))
+		dec.UseNumber()
+		var doc any
+		if dec.Decode(&doc) == nil {
+			if data, err := json.Marshal(f.mutate(doc)); err == nil {
+				result.Stdout = string(data)
+			}
+		}
+	}
+	return result, nil
+}
+
+// mutate returns a fuzzed copy of a decoded YAML or JSON value.
+func (f *FuzzingExecutor) mutate(v any) any {
+	switch v := v.(type) {
+	case int:
+		return int(math.Round(float64(v) * f.factor()))
+	case int64:
+		return int64(math.Round(float64(v) * f.factor()))
+	case float64:
+		return v * f.factor()
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return int64(math.Round(float64(n) * f.factor()))
+		}
+		if n, err := v.Float64(); err == nil {
+			return n * f.factor()
+		}
+		return v
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = f.mutate(item)
+		}
+		f.rng.Shuffle(len(out), func(i, j int) { out[i], out[j] = out[j], out[i] })
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for _, k := range sortedOutputKeys(v) {
+			out[k] = f.mutate(v[k])
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// factor returns a random multiplier in [1-fuzzSpread, 1+fuzzSpread].
+func (f *FuzzingExecutor) factor() float64 {
+	return 1 + (f.rng.Float64()*2-1)*fuzzSpread
+}
+
+// requiredOutputs lists the outputs the tool or action contract marks
+// required; those are never omitted.
+func requiredOutputs(td *schema.ToolDefinition, actionName string) map[string]bool {
+	required := make(map[string]bool)
+	for name, p := range td.Contract.Outputs {
+		if p.Required {
+			required[name] = true
+		}
+	}
+	if action, ok := td.Actions[actionName]; ok && action.Contract != nil {
+		for name, p := range action.Contract.Outputs {
+			if p.Required {
+				required[name] = true
+			}
+		}
+	}
+	return required
+}
+
+// sortedOutputKeys returns the keys of m in sorted order, so the random
+// stream is consumed the same way on every run.
+func sortedOutputKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
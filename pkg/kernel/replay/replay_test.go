@@ -2,8 +2,12 @@ package replay
 
 import (
 	"context"
+	"encoding/json"
+	"reflect"
 	"testing"
 
+	"github.com/ormasoftchile/gert/pkg/kernel/contract"
+	"github.com/ormasoftchile/gert/pkg/kernel/executor"
 	"github.com/ormasoftchile/gert/pkg/kernel/schema"
 )
 
@@ -113,3 +117,68 @@ func TestReplayExecutor_EvidenceForStep(t *testing.T) {
 		t.Error("expected nil for nonexistent step")
 	}
 }
+
+func TestFuzzingExecutor(t *testing.T) {
+	s := &Scenario{
+		ToolResponses: map[string][]ToolResponse{
+			"my-tool:check": {{
+				ExitCode: 0,
+				Stdout:   `{"latency_ms": 100, "pods": ["a", "b", "c", "d"]}`,
+				Outputs:  map[string]any{"count": 50, "ratio": 0.5, "status": "ok", "note": "fine", "items": []any{1, 2, 3}},
+			}},
+		},
+	}
+	td := &schema.ToolDefinition{
+		Meta: schema.ToolMeta{Name: "my-tool"},
+		Contract: contract.Contract{Outputs: map[string]contract.ParamDef{
+			"status": {Type: "string", Required: true},
+		}},
+	}
+
+	run := func(seed int64) *executor.Result {
+		r, err := NewFuzzingExecutor(s, seed).Execute(context.Background(), td, "check", nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	omitted := 0
+	for seed := int64(0); seed < 50; seed++ {
+		r := run(seed)
+		if !reflect.DeepEqual(r, run(seed)) {
+			t.Fatalf("seed %d: results differ between runs", seed)
+		}
+		if r.Outputs["status"] != "ok" {
+			t.Errorf("seed %d: required output status = %v", seed, r.Outputs["status"])
+		}
+		if _, ok := r.Outputs["note"]; !ok {
+			omitted++
+		}
+		if c := r.Outputs["count"].(int); c < 40 || c > 60 {
+			t.Errorf("seed %d: count = %d, want within 20%% of 50", seed, c)
+		}
+		if f := r.Outputs["ratio"].(float64); f < 0.4 || f > 0.6 {
+			t.Errorf("seed %d: ratio = %v, want within 20%% of 0.5", seed, f)
+		}
+		if n := len(r.Outputs["items"].([]any)); n != 3 {
+			t.Errorf("seed %d: items has %d elements, want 3", seed, n)
+		}
+		var doc struct {
+			LatencyMs int      `json:"latency_ms"`
+			Pods      []string `json:"pods"`
+		}
+		if err := json.Unmarshal([]byte(r.Stdout), &doc); err != nil {
+			t.Fatalf("seed %d: stdout is not JSON: %v", seed, err)
+		}
+		if doc.LatencyMs < 80 || doc.LatencyMs > 120 || len(doc.Pods) != 4 {
+			t.Errorf("seed %d: stdout = %s", seed, r.Stdout)
+		}
+	}
+	if omitted == 0 || omitted == 50 {
+		t.Errorf("optional string output omitted in %d of 50 runs", omitted)
+	}
+	if s.ToolResponses["my-tool:check"][0].Outputs["count"] != 50 {
+		t.Error("fuzzing modified the scenario")
+	}
+}
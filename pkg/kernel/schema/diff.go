@@ -0,0 +1,153 @@
+package schema
+
+import "reflect"
+
+// Diff kinds reported in DiffEntry.Kind.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DiffEntry is one structural difference between two runbooks.
+// Path addresses the step by ID ("steps.<id>") or one of its fields
+// ("steps.<id>.<field>", using the YAML field name).
+type DiffEntry struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// diffFields are the step fields compared by Diff. Steps nested in branches
+// and repeat blocks are compared on their own, so branches and repeat only
+// contribute their conditions and bounds here.
+var diffFields = []struct {
+	name string
+	get  func(*Step) any
+}{
+	{"type", func(s *Step) any { return s.Type }},
+	{"when", func(s *Step) any { return s.When }},
+	{"next", func(s *Step) any { return s.Next }},
+	{"tool", func(s *Step) any { return s.Tool }},
+	{"action", func(s *Step) any { return s.Action }},
+	{"inputs", func(s *Step) any { return s.Inputs }},
+	{"env", func(s *Step) any { return s.Env }},
+	{"instructions", func(s *Step) any { return s.Instructions }},
+	{"required_evidence", func(s *Step) any { return s.RequiredEvidence }},
+	{"choices", func(s *Step) any { return s.Choices }},
+	{"assert", func(s *Step) any { return s.Assert }},
+	{"branches", func(s *Step) any { return branchHeads(s.Branches) }},
+	{"for_each", func(s *Step) any { return s.ForEach }},
+	{"repeat", func(s *Step) any { return repeatBounds(s.Repeat) }},
+	{"retry", func(s *Step) any { return s.Retry }},
+	{"on_failure", func(s *Step) any { return s.OnFailure }},
+	{"timeout", func(s *Step) any { return s.Timeout }},
+	{"outcome", func(s *Step) any { return s.Outcome }},
+	{"extension", func(s *Step) any { return s.Extension }},
+	{"contract", func(s *Step) any { return s.Contract }},
+}
+
+// Diff compares two runbooks step by step, matching steps by ID rather than
+// position so reordering alone is not reported. Entries for removed and
+// changed steps follow a's order, then added steps follow b's order.
+// Identical runbooks produce an empty slice.
+func Diff(a, b *Runbook) []DiffEntry {
+	before, beforeOrder := indexSteps(a)
+	after, afterOrder := indexSteps(b)
+
+	entries := []DiffEntry{}
+	for _, id := range beforeOrder {
+		sa := before[id]
+		sb, ok := after[id]
+		if !ok {
+			entries = append(entries, DiffEntry{Path: "steps." + id, Kind: DiffRemoved, Before: *sa})
+			continue
+		}
+		for _, f := range diffFields {
+			va, vb := normalizeDiffValue(f.get(sa)), normalizeDiffValue(f.get(sb))
+			if !reflect.DeepEqual(va, vb) {
+				entries = append(entries, DiffEntry{
+					Path:   "steps." + id + "." + f.name,
+					Kind:   DiffChanged,
+					Before: va,
+					After:  vb,
+				})
+			}
+		}
+	}
+	for _, id := range afterOrder {
+		if _, ok := before[id]; !ok {
+			entries = append(entries, DiffEntry{Path: "steps." + id, Kind: DiffAdded, After: *after[id]})
+		}
+	}
+	return entries
+}
+
+// indexSteps collects every step with an ID, including those in branch and
+// repeat bodies, in document order.
+func indexSteps(rb *Runbook) (map[string]*Step, []string) {
+	byID := make(map[string]*Step)
+	var order []string
+	if rb == nil {
+		return byID, order
+	}
+	walkAllSteps(rb.Steps, func(s *Step) {
+		if s.ID == "" {
+			return
+		}
+		if _, dup := byID[s.ID]; !dup {
+			order = append(order, s.ID)
+		}
+		byID[s.ID] = s
+	})
+	return byID, order
+}
+
+// branchHead is a branch arm without its steps.
+type branchHead struct {
+	Condition string `json:"condition,omitempty"`
+	Label     string `json:"label,omitempty"`
+}
+
+func branchHeads(branches []Branch) []branchHead {
+	if len(branches) == 0 {
+		return nil
+	}
+	heads := make([]branchHead, len(branches))
+	for i, br := range branches {
+		heads[i] = branchHead{Condition: br.Condition, Label: br.Label}
+	}
+	return heads
+}
+
+// repeatBound is a repeat block without its steps.
+type repeatBound struct {
+	Max   int    `json:"max"`
+	Until string `json:"until,omitempty"`
+}
+
+func repeatBounds(r *RepeatBlock) *repeatBound {
+	if r == nil {
+		return nil
+	}
+	return &repeatBound{Max: r.Max, Until: r.Until}
+}
+
+// normalizeDiffValue maps empty values (nil pointers, empty slices and maps,
+// empty strings) to nil so that omitted and explicitly-empty fields compare
+// equal.
+func normalizeDiffValue(v any) any {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if rv.IsNil() || (rv.Kind() != reflect.Ptr && rv.Len() == 0) {
+			return nil
+		}
+	case reflect.String:
+		if rv.Len() == 0 {
+			return nil
+		}
+	}
+	return v
+}
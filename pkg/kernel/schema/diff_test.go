@@ -0,0 +1,82 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+const diffBase = `apiVersion: kernel/v0
+meta:
+  name: diff-test
+steps:
+  - id: check
+    type: tool
+    tool: curl
+    action: get
+    inputs:
+      url: http://localhost
+  - id: route
+    type: branch
+    branches:
+      - condition: "check.status == 500"
+        steps:
+          - id: restart
+            type: manual
+            instructions: Restart the service
+  - id: done
+    type: end
+    outcome:
+      category: resolved
+      code: healthy
+`
+
+func mustLoad(t *testing.T, src string) *Runbook {
+	t.Helper()
+	rb, err := Load(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return rb
+}
+
+func TestDiff_Identical(t *testing.T) {
+	got := Diff(mustLoad(t, diffBase), mustLoad(t, diffBase))
+	if got == nil || len(got) != 0 {
+		t.Fatalf("Diff of identical runbooks = %#v, want empty slice", got)
+	}
+}
+
+func TestDiff_ChangedAddedRemoved(t *testing.T) {
+	changed := strings.NewReplacer(
+		"url: http://localhost", "url: http://localhost/healthz",
+		"Restart the service", "Restart the service and wait",
+		`"check.status == 500"`, `"check.status >= 500"`,
+		"  - id: done\n", "  - id: finish\n",
+	).Replace(diffBase)
+
+	got := Diff(mustLoad(t, diffBase), mustLoad(t, changed))
+
+	want := []struct{ path, kind string }{
+		{"steps.check.inputs", DiffChanged},
+		{"steps.route.branches", DiffChanged},
+		{"steps.restart.instructions", DiffChanged},
+		{"steps.done", DiffRemoved},
+		{"steps.finish", DiffAdded},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %#v, want %d entries", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Path != w.path || got[i].Kind != w.kind {
+			t.Errorf("entry %d = %s (%s), want %s (%s)", i, got[i].Path, got[i].Kind, w.path, w.kind)
+		}
+	}
+}
+
+func TestDiff_Reordered(t *testing.T) {
+	reordered := mustLoad(t, diffBase)
+	reordered.Steps[0], reordered.Steps[2] = reordered.Steps[2], reordered.Steps[0]
+	if got := Diff(mustLoad(t, diffBase), reordered); len(got) != 0 {
+		t.Errorf("reordering reported differences: %#v", got)
+	}
+}
@@ -0,0 +1,143 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format rewrites kernel/v0 runbook YAML in canonical form: struct keys in
+// the order the Go types declare them (the JSON Schema properties order),
+// block style, two-space indentation, quotes only where YAML needs them and a
+// blank line between top-level sections. The document is rewritten at the
+// node level, so values, comments, literal blocks and the key order inside
+// free-form maps (inputs, constants, extensions) are kept as written. src
+// must pass Load.
+func Format(src []byte) ([]byte, error) {
+	if _, err := Load(bytes.NewReader(src)); err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, fmt.Errorf("parse runbook: %w", err)
+	}
+	canonicalize(&doc, reflect.TypeOf(Runbook{}))
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("encode runbook: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encode runbook: %w", err)
+	}
+	return separateSections(buf.Bytes()), nil
+}
+
+// separateSections puts a blank line before each top-level key after the
+// first, above any comment lines that lead into it.
+func separateSections(data []byte) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	var b strings.Builder
+	inHeader := true
+	for i, line := range lines {
+		topLevel := line != "" && line[0] != ' ' && line[0] != '-' && line != "\n"
+		if topLevel && !inHeader && i > 0 && !strings.HasPrefix(lines[i-1], "#") {
+			b.WriteString("\n")
+		}
+		if topLevel && !strings.HasPrefix(line, "#") {
+			inHeader = false
+		}
+		b.WriteString(line)
+	}
+	return []byte(b.String())
+}
+
+// canonicalize orders the keys of every mapping that decodes into a struct
+// and clears flow and quoting styles. t is the Go type n decodes into, or
+// nil below an `any` field.
+func canonicalize(n *yaml.Node, t reflect.Type) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			canonicalize(c, t)
+		}
+	case yaml.MappingNode:
+		n.Style &^= yaml.FlowStyle
+		if t != nil && t.Kind() == reflect.Struct {
+			sortFields(n, t)
+			return
+		}
+		var elem reflect.Type
+		if t != nil && t.Kind() == reflect.Map {
+			elem = t.Elem()
+		}
+		for i := 1; i < len(n.Content); i += 2 {
+			canonicalize(n.Content[i], elem)
+		}
+	case yaml.SequenceNode:
+		n.Style &^= yaml.FlowStyle
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for _, c := range n.Content {
+			canonicalize(c, elem)
+		}
+	case yaml.ScalarNode:
+		n.Style &^= yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle | yaml.FlowStyle
+	}
+}
+
+// sortFields reorders the key/value pairs of a struct mapping by field
+// declaration order and canonicalizes each value against its field type.
+func sortFields(n *yaml.Node, t reflect.Type) {
+	type pair struct {
+		key, value *yaml.Node
+		index      int
+	}
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f
+	}
+
+	pairs := make([]pair, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		p := pair{key: n.Content[i], value: n.Content[i+1], index: t.NumField()}
+		if f, ok := fields[p.key.Value]; ok {
+			p.index = f.Index[0]
+			canonicalize(p.value, f.Type)
+		} else {
+			canonicalize(p.value, nil)
+		}
+		canonicalize(p.key, nil)
+		pairs = append(pairs, p)
+	}
+	if len(pairs) == 0 {
+		return
+	}
+	// A comment above the first key belongs to the mapping, not to that key
+	first := pairs[0].key
+	sort.SliceStable(pairs, func(i, j int) bool { return pairs[i].index < pairs[j].index })
+	if pairs[0].key != first && pairs[0].key.HeadComment == "" {
+		pairs[0].key.HeadComment, first.HeadComment = first.HeadComment, ""
+	}
+
+	n.Content = n.Content[:0]
+	for _, p := range pairs {
+		n.Content = append(n.Content, p.key, p.value)
+	}
+}
@@ -28,10 +28,45 @@ func Load(r io.Reader) (*Runbook, error) {
 	if err := dec.Decode(&rb); err != nil {
 		return nil, fmt.Errorf("structural decode: %w", err)
 	}
+	if err := desugarBranches(rb.Steps); err != nil {
+		return nil, fmt.Errorf("structural decode: %w", err)
+	}
 	normalizeScopes(&rb)
 	return &rb, nil
 }
 
+// desugarBranches rewrites every `branch: {if, steps, else}` shorthand into
+// the canonical two-entry Branches form.
+func desugarBranches(steps []Step) error {
+	for i := range steps {
+		s := &steps[i]
+		if ie := s.Branch; ie != nil {
+			switch {
+			case s.Type != StepBranch:
+				return fmt.Errorf("step %q: branch: shorthand is only allowed on branch steps", s.ID)
+			case len(s.Branches) > 0:
+				return fmt.Errorf("step %q: branch and branches are mutually exclusive", s.ID)
+			}
+			s.Branches = []Branch{{Condition: ie.If, Label: "if", Steps: ie.Steps}}
+			if len(ie.Else) > 0 {
+				s.Branches = append(s.Branches, Branch{Condition: "default", Label: "else", Steps: ie.Else})
+			}
+			s.Branch = nil
+		}
+		for j := range s.Branches {
+			if err := desugarBranches(s.Branches[j].Steps); err != nil {
+				return err
+			}
+		}
+		if s.Repeat != nil {
+			if err := desugarBranches(s.Repeat.Steps); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // normalizeScopes converts `/` to `.` in all step scope paths.
 func normalizeScopes(rb *Runbook) {
 	walkAllSteps(rb.Steps, func(s *Step) {
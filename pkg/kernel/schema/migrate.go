@@ -0,0 +1,88 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Migrate rewrites kernel/v0 runbook YAML to current idioms and returns it
+// in Format's canonical form, with the number of rewrites made. Today it
+// turns branch steps whose two unlabeled branches are a condition and a
+// default into the `branch: {if, steps, else}` shorthand. src must pass
+// Load.
+func Migrate(src []byte) ([]byte, int, error) {
+	if _, err := Load(bytes.NewReader(src)); err != nil {
+		return nil, 0, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(src, &doc); err != nil {
+		return nil, 0, fmt.Errorf("parse runbook: %w", err)
+	}
+	n := migrateIfElse(&doc)
+	canonicalize(&doc, reflect.TypeOf(Runbook{}))
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, 0, fmt.Errorf("encode runbook: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, 0, fmt.Errorf("encode runbook: %w", err)
+	}
+	return separateSections(buf.Bytes()), n, nil
+}
+
+// migrateIfElse rewrites every if/default branch step below n into the
+// shorthand form and returns how many it rewrote.
+func migrateIfElse(n *yaml.Node) int {
+	count := 0
+	for _, c := range n.Content {
+		count += migrateIfElse(c)
+	}
+	if n.Kind != yaml.MappingNode || mappingValue(n, "type") == nil || mappingValue(n, "type").Value != string(StepBranch) {
+		return count
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != "branches" {
+			continue
+		}
+		branches := n.Content[i+1]
+		if branches.Kind != yaml.SequenceNode || len(branches.Content) != 2 {
+			return count
+		}
+		first, second := branches.Content[0], branches.Content[1]
+		cond, steps := mappingValue(first, "condition"), mappingValue(first, "steps")
+		def, elseSteps := mappingValue(second, "condition"), mappingValue(second, "steps")
+		if cond == nil || steps == nil || def == nil || elseSteps == nil ||
+			cond.Value == "default" || def.Value != "default" ||
+			mappingValue(first, "label") != nil || mappingValue(second, "label") != nil {
+			return count
+		}
+
+		n.Content[i].Value = "branch"
+		n.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "if"}, cond,
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "steps"}, steps,
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: "else"}, elseSteps,
+		}}
+		return count + 1
+	}
+	return count
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
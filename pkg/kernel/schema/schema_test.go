@@ -147,6 +147,46 @@ func TestParseNext_Nil(t *testing.T) {
 	}
 }
 
+func TestParseNextTargets(t *testing.T) {
+	targets, err := ParseNextTargets([]any{
+		map[string]any{"if": "degraded", "step": "remediate"},
+		map[string]any{"if": "flapping", "step": "check", "max": 2},
+		map[string]any{"step": "verify"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ConditionalNext{
+		{If: "degraded", Step: "remediate"},
+		{If: "flapping", Step: "check", Max: 2},
+		{Step: "verify"},
+	}
+	if len(targets) != len(want) {
+		t.Fatalf("got %+v, want %+v", targets, want)
+	}
+	for i := range want {
+		if targets[i] != want[i] {
+			t.Errorf("targets[%d] = %+v, want %+v", i, targets[i], want[i])
+		}
+	}
+
+	// Single-target forms are one unconditional entry
+	targets, err = ParseNextTargets(map[string]any{"step": "retry", "max": 3})
+	if err != nil || len(targets) != 1 || targets[0] != (ConditionalNext{Step: "retry", Max: 3}) {
+		t.Errorf("map form = %+v, %v", targets, err)
+	}
+	if targets, err := ParseNextTargets(nil); err != nil || len(targets) != 0 {
+		t.Errorf("nil = %+v, %v", targets, err)
+	}
+
+	if _, err := ParseNextTargets([]any{map[string]any{"if": "x"}}); err == nil {
+		t.Error("expected error for an entry without step")
+	}
+	if _, _, _, err := ParseNext([]any{}); err == nil {
+		t.Error("ParseNext should reject the conditional form")
+	}
+}
+
 func TestLoadTool_Valid(t *testing.T) {
 	yaml := `
 apiVersion: tool/v0
@@ -220,3 +260,222 @@ steps:
 		t.Errorf("scope = %q, want 'round.0' (normalized from round/0)", rb.Steps[0].Scope)
 	}
 }
+
+func TestLoad_BranchShorthand(t *testing.T) {
+	src := `
+apiVersion: kernel/v0
+meta:
+  name: test-if-else
+steps:
+  - id: decide
+    type: branch
+    branch:
+      if: "{{ eq .env \"prod\" }}"
+      steps:
+        - id: careful
+          type: manual
+          instructions: Be careful
+          scope: "prod/checks"
+      else:
+        - id: fast
+          type: manual
+          instructions: Go fast
+  - type: end
+    outcome:
+      category: no_action
+      code: done
+`
+	rb, err := Load(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := rb.Steps[0]
+	if step.Branch != nil || len(step.Branches) != 2 {
+		t.Fatalf("branch not desugared: %+v", step)
+	}
+	if br := step.Branches[0]; br.Label != "if" || br.Condition != `{{ eq .env "prod" }}` || br.Steps[0].ID != "careful" {
+		t.Errorf("if branch = %+v", br)
+	}
+	if br := step.Branches[1]; br.Label != "else" || br.Condition != "default" || br.Steps[0].ID != "fast" {
+		t.Errorf("else branch = %+v", br)
+	}
+	if scope := step.Branches[0].Steps[0].Scope; scope != "prod.checks" {
+		t.Errorf("nested scope = %q, want prod.checks", scope)
+	}
+
+	both := strings.Replace(src, "    branch:\n", "    branches: [{condition: default, steps: [{type: end}]}]\n    branch:\n", 1)
+	if _, err := Load(strings.NewReader(both)); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("expected mutually exclusive error, got %v", err)
+	}
+	parallel := strings.Replace(src, "type: branch", "type: parallel", 1)
+	if _, err := Load(strings.NewReader(parallel)); err == nil || !strings.Contains(err.Error(), "only allowed on branch steps") {
+		t.Errorf("expected branch-only error, got %v", err)
+	}
+}
+
+func TestMigrate_IfElse(t *testing.T) {
+	src := `apiVersion: kernel/v0
+meta:
+  name: migrate
+steps:
+  - id: decide
+    type: branch
+    branches:
+      - condition: "{{ .degraded }}"
+        steps:
+          - id: fix
+            type: manual
+            instructions: Fix it
+      - condition: default
+        steps:
+          - id: ok
+            type: manual
+            instructions: All good
+  - id: labeled
+    type: branch
+    branches:
+      - condition: "{{ .degraded }}"
+        label: degraded
+        steps:
+          - type: end
+            outcome: {category: resolved, code: fixed}
+      - condition: default
+        steps:
+          - type: end
+            outcome: {category: no_action, code: ok}
+`
+	out, n, err := Migrate([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("rewrites = %d, want 1 (labeled branches are kept)", n)
+	}
+	want := `    branch:
+      if: '{{ .degraded }}'
+      steps:
+        - id: fix
+          type: manual
+          instructions: Fix it
+      else:
+        - id: ok
+          type: manual
+          instructions: All good
+`
+	if !strings.Contains(string(out), want) {
+		t.Errorf("migrated output:\n%s", out)
+	}
+	if strings.Count(string(out), "branches:") != 1 {
+		t.Errorf("labeled branch step should keep branches:\n%s", out)
+	}
+
+	rb, err := Load(strings.NewReader(string(out)))
+	if err != nil {
+		t.Fatalf("migrated runbook does not load: %v", err)
+	}
+	if br := rb.Steps[0].Branches; len(br) != 2 || br[0].Condition != "{{ .degraded }}" || br[1].Condition != "default" {
+		t.Errorf("migrated branches = %+v", br)
+	}
+
+	if _, n, _ := Migrate(out); n != 0 {
+		t.Errorf("second migration rewrote %d step(s)", n)
+	}
+}
+
+func TestNewRunbookTemplate(t *testing.T) {
+	rb, err := NewRunbookTemplate(TemplateOptions{
+		Name:  "restart",
+		Kind:  TemplateMixed,
+		Steps: []string{"Check pod status", "Approve restart", "Check pod status"},
+		Tool:  "kubectl",
+	})
+	if err != nil {
+		t.Fatalf("NewRunbookTemplate: %v", err)
+	}
+	var got []string
+	for _, s := range rb.Steps {
+		got = append(got, s.ID+":"+string(s.Type))
+	}
+	want := "check_pod_status:tool,approve_restart:manual,step_3:tool,done:end"
+	if strings.Join(got, ",") != want {
+		t.Errorf("steps = %v, want %s", got, want)
+	}
+	if len(rb.Tools) != 1 || rb.Tools[0] != "kubectl" || rb.Steps[0].Tool != "kubectl" {
+		t.Errorf("tools = %v, step tool = %q", rb.Tools, rb.Steps[0].Tool)
+	}
+
+	rb, err = NewRunbookTemplate(TemplateOptions{Name: "checklist", Kind: TemplateManual, Steps: []string{"a"}})
+	if err != nil {
+		t.Fatalf("NewRunbookTemplate: %v", err)
+	}
+	if len(rb.Tools) != 0 {
+		t.Errorf("manual template declares tools %v", rb.Tools)
+	}
+
+	if _, err := NewRunbookTemplate(TemplateOptions{Name: "x", Kind: "script", Steps: []string{"a"}}); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	src := `# header
+steps:
+  - type: end   # the end
+    outcome: {category: no_action, code: "123"}
+    id: done
+meta:
+    name: "fmt"
+    constants: {z: 'true', a: "{{ .x }}"}
+    description: |
+      line one
+      line two
+    extensions:
+      x-team: sre
+    governance:
+      rules:
+        - effects: []
+          action: allow
+apiVersion: kernel/v0
+`
+	want := `# header
+apiVersion: kernel/v0
+
+meta:
+  name: fmt
+  description: |
+    line one
+    line two
+  constants:
+    z: "true"
+    a: '{{ .x }}'
+  governance:
+    rules:
+      - effects: []
+        action: allow
+  extensions:
+    x-team: sre
+
+steps:
+  - id: done
+    type: end # the end
+    outcome:
+      category: no_action
+      code: "123"
+`
+	out, err := Format([]byte(src))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != want {
+		t.Errorf("Format =\n%s\nwant\n%s", out, want)
+	}
+
+	again, err := Format(out)
+	if err != nil || string(again) != string(out) {
+		t.Errorf("Format is not idempotent (err %v):\n%s", err, again)
+	}
+
+	if _, err := Format([]byte("apiVersion: kernel/v0\nmeta:\n  name: x\n  bogus: 1\nsteps: []\n")); err == nil {
+		t.Error("expected unknown field to be rejected")
+	}
+}
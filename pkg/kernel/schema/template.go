@@ -0,0 +1,114 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/contract"
+)
+
+// Template kinds accepted by NewRunbookTemplate.
+const (
+	TemplateCLI    = "cli"    // tool steps only
+	TemplateManual = "manual" // manual steps only
+	TemplateMixed  = "mixed"  // alternating tool and manual steps, starting with a tool step
+)
+
+// DefaultTemplateTool is the tool referenced by cli and mixed templates
+// when no tool is given.
+const DefaultTemplateTool = "shell"
+
+// TemplateInput is the input every template declares, so generated steps
+// show how inputs are referenced.
+const TemplateInput = "target"
+
+// TemplateOptions configures NewRunbookTemplate.
+type TemplateOptions struct {
+	Name        string
+	Description string
+	Kind        string   // cli, manual, or mixed
+	Steps       []string // step names, turned into step IDs
+	Tool        string   // tool used by tool steps (default: shell)
+}
+
+var nonIDChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// TemplateStepID turns a free-form step name into a step ID:
+// "Check pod status" becomes "check_pod_status".
+func TemplateStepID(name string) string {
+	return strings.Trim(nonIDChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+}
+
+// NewRunbookTemplate builds a minimal runbook with one placeholder step per
+// name in opts.Steps, ending in a resolved end step. The result passes
+// validation as long as the tool's definition can be found, or is absent.
+func NewRunbookTemplate(opts TemplateOptions) (*Runbook, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("runbook name is required")
+	}
+	switch opts.Kind {
+	case TemplateCLI, TemplateManual, TemplateMixed:
+	default:
+		return nil, fmt.Errorf("invalid kind %q: expected cli, manual, or mixed", opts.Kind)
+	}
+	if len(opts.Steps) == 0 {
+		return nil, fmt.Errorf("at least one step is required")
+	}
+	tool := opts.Tool
+	if tool == "" {
+		tool = DefaultTemplateTool
+	}
+
+	rb := &Runbook{
+		APIVersion: APIVersionKernel,
+		Meta: Meta{
+			Name:        opts.Name,
+			Description: opts.Description,
+			Inputs: map[string]contract.ParamDef{
+				TemplateInput: {Type: "string", Required: true, Description: "Resource the runbook operates on"},
+			},
+		},
+	}
+
+	seen := map[string]bool{"done": true}
+	usesTool := false
+	for i, name := range opts.Steps {
+		id := TemplateStepID(name)
+		if id == "" || seen[id] {
+			id = fmt.Sprintf("step_%d", i+1)
+		}
+		seen[id] = true
+
+		isTool := opts.Kind == TemplateCLI || (opts.Kind == TemplateMixed && i%2 == 0)
+		if isTool {
+			usesTool = true
+			rb.Steps = append(rb.Steps, Step{
+				ID:     id,
+				Type:   StepTool,
+				Tool:   tool,
+				Action: "run",
+				Inputs: map[string]any{TemplateInput: "{{ ." + TemplateInput + " }}"},
+			})
+			continue
+		}
+		rb.Steps = append(rb.Steps, Step{
+			ID:           id,
+			Type:         StepManual,
+			Instructions: fmt.Sprintf("TODO: %s on {{ .%s }}", name, TemplateInput),
+		})
+	}
+	if usesTool {
+		rb.Tools = []string{tool}
+	}
+
+	rb.Steps = append(rb.Steps, Step{
+		ID:   "done",
+		Type: StepEnd,
+		Outcome: &Outcome{
+			Category: OutcomeResolved,
+			Code:     "completed",
+		},
+	})
+	return rb, nil
+}
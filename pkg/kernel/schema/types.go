@@ -35,9 +35,18 @@ type Meta struct {
 	Constants   map[string]any               `yaml:"constants,omitempty" json:"constants,omitempty"`
 	Governance  *GovernancePolicy            `yaml:"governance,omitempty" json:"governance,omitempty"`
 	Secrets     []SecretRef                  `yaml:"secrets,omitempty"   json:"secrets,omitempty"`
+	Defaults    *Defaults                    `yaml:"defaults,omitempty"  json:"defaults,omitempty"`
 	Extensions  map[string]any               `yaml:"extensions,omitempty" json:"extensions,omitempty"`
 }
 
+// Defaults holds runbook-wide settings that steps inherit unless they set
+// their own.
+type Defaults struct {
+	PreHook  string `yaml:"pre_hook,omitempty"  json:"pre_hook,omitempty"`
+	PostHook string `yaml:"post_hook,omitempty" json:"post_hook,omitempty"`
+	Timeout  string `yaml:"timeout,omitempty"   json:"timeout,omitempty"` // e.g. "5m"; steps without a timeout are unbounded
+}
+
 // ---------------------------------------------------------------------------
 // Governance
 // ---------------------------------------------------------------------------
@@ -46,6 +55,8 @@ type Meta struct {
 type GovernancePolicy struct {
 	Rules           []GovernanceRule `yaml:"rules" json:"rules"`
 	ApprovalTimeout string           `yaml:"approval_timeout,omitempty" json:"approval_timeout,omitempty"` // e.g. "30m", parsed as time.Duration
+	AllowedHooks    []string         `yaml:"allowed_hooks,omitempty"    json:"allowed_hooks,omitempty"`    // globs matched against hook commands and URL hosts
+	AllowedEffects  []string         `yaml:"allowed_effects,omitempty"  json:"allowed_effects,omitempty"`  // effects tool steps may declare; enforced from RunConfig.GovernancePolicy
 }
 
 // GovernanceRule is a single governance policy rule.
@@ -58,6 +69,8 @@ type GovernanceRule struct {
 	Action             string              `yaml:"action,omitempty"   json:"action,omitempty"`
 	MinApprovers       int                 `yaml:"min_approvers,omitempty" json:"min_approvers,omitempty"`
 	ContractViolations string              `yaml:"contract_violations,omitempty" json:"contract_violations,omitempty"` // "deny" to promote violations to errors
+	Env                []string            `yaml:"env,omitempty"      json:"env,omitempty"`                            // globs matched against step env names, e.g. AWS_*
+	Steps              []StepType          `yaml:"steps,omitempty"    json:"steps,omitempty"`                          // step types the rule applies to, e.g. [tool]; empty matches all
 }
 
 // GovernanceContract matches steps by contract properties.
@@ -113,21 +126,41 @@ type Step struct {
 	// Repeat block — bounded multi-step iteration
 	Repeat *RepeatBlock `yaml:"repeat,omitempty" json:"repeat,omitempty"`
 
+	// Retry policy — re-run a failing step
+	Retry *RetryPolicy `yaml:"retry,omitempty" json:"retry,omitempty"`
+
+	// Failure routing — step ID (same scope) to continue at once the step
+	// has failed, after any retries are exhausted
+	OnFailure string `yaml:"on_failure,omitempty" json:"on_failure,omitempty"`
+
+	// Timeout — deadline for the step including retries (e.g. "30s");
+	// overrides meta.defaults
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+
+	// Lifecycle hooks — a command line or http(s) URL invoked before and
+	// after the step; overrides meta.defaults
+	PreHook  string `yaml:"pre_hook,omitempty"  json:"pre_hook,omitempty"`
+	PostHook string `yaml:"post_hook,omitempty" json:"post_hook,omitempty"`
+
 	// Tool step
-	Tool       string         `yaml:"tool,omitempty"   json:"tool,omitempty"`
-	Action     string         `yaml:"action,omitempty" json:"action,omitempty"`
-	Inputs     map[string]any `yaml:"inputs,omitempty" json:"inputs,omitempty"`
-	InputsFrom any            `yaml:"inputs_from,omitempty" json:"inputs_from,omitempty"` // string or []string
+	Tool       string            `yaml:"tool,omitempty"   json:"tool,omitempty"`
+	Action     string            `yaml:"action,omitempty" json:"action,omitempty"`
+	Inputs     map[string]any    `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	InputsFrom any               `yaml:"inputs_from,omitempty" json:"inputs_from,omitempty"` // string or []string
+	Env        map[string]string `yaml:"env,omitempty" json:"env,omitempty"`                 // added to the tool process environment
+	Cache      *CacheConfig      `yaml:"cache,omitempty" json:"cache,omitempty"`             // reuse outputs of an earlier run with the same key
 
 	// Manual step
 	Instructions     string                `yaml:"instructions,omitempty"      json:"instructions,omitempty"`
 	RequiredEvidence []EvidenceRequirement `yaml:"required_evidence,omitempty" json:"required_evidence,omitempty"`
+	Choices          *ChoiceConfig         `yaml:"choices,omitempty"           json:"choices,omitempty"`
 
 	// Assert step
 	Assert []Assertion `yaml:"assert,omitempty" json:"assert,omitempty"`
 
 	// Branch step
 	Branches []Branch `yaml:"branches,omitempty" json:"branches,omitempty"`
+	Branch   *IfElse  `yaml:"branch,omitempty"   json:"branch,omitempty"` // if/else shorthand; Load desugars it into Branches
 
 	// Parallel step  (reuses Branches with parallel semantics)
 
@@ -149,6 +182,8 @@ type NextBounded struct {
 // Returns (target, max, isBounded).
 //   - Simple string: ("step_id", 0, false)
 //   - Map: ("step_id", max, true)
+//
+// The conditional list form has no single target; use ParseNextTargets.
 func ParseNext(raw any) (target string, max int, bounded bool, err error) {
 	switch v := raw.(type) {
 	case nil:
@@ -156,28 +191,81 @@ func ParseNext(raw any) (target string, max int, bounded bool, err error) {
 	case string:
 		return v, 0, false, nil
 	case map[string]any:
-		s, _ := v["step"].(string)
-		m, _ := v["max"].(int)
-		if fm, ok := v["max"].(float64); ok {
-			m = int(fm)
-		}
-		// Also handle string-valued max (template expression)
-		if ms, ok := v["max"].(string); ok && ms != "" {
-			// At validation time we can't resolve templates — treat as bounded
-			return s, 1, true, nil
-		}
-		return s, m, m > 0 || s != "", nil
+		target, max, bounded = parseNextMap(v)
+		return target, max, bounded, nil
+	case []any:
+		return "", 0, false, fmt.Errorf("conditional next has no single target")
 	default:
 		return "", 0, false, fmt.Errorf("invalid next value: %T", raw)
 	}
 }
 
+// parseNextMap reads the {step, max} form.
+func parseNextMap(v map[string]any) (target string, max int, bounded bool) {
+	s, _ := v["step"].(string)
+	m, _ := v["max"].(int)
+	if fm, ok := v["max"].(float64); ok {
+		m = int(fm)
+	}
+	// Also handle string-valued max (template expression)
+	if ms, ok := v["max"].(string); ok && ms != "" {
+		// At validation time we can't resolve templates — treat as bounded
+		return s, 1, true
+	}
+	return s, m, m > 0 || s != ""
+}
+
+// ConditionalNext is one jump target of Step.Next. The conditional form
+// lists several, tried in order:
+//
+//	next:
+//	  - if: check.degraded
+//	    step: remediate
+//	  - step: verify      # no if: the default
+//
+// The first entry whose If holds, or that has no If, wins.
+type ConditionalNext struct {
+	If   string
+	Step string
+	Max  int // bound on backward jumps, as in {step, max}
+}
+
+// ParseNextTargets returns the jump targets of Step.Next in every form: a
+// string or {step, max} map yields one unconditional target, and the
+// conditional list yields its entries in order.
+func ParseNextTargets(raw any) ([]ConditionalNext, error) {
+	list, ok := raw.([]any)
+	if !ok {
+		target, max, _, err := ParseNext(raw)
+		if err != nil || target == "" {
+			return nil, err
+		}
+		return []ConditionalNext{{Step: target, Max: max}}, nil
+	}
+	targets := make([]ConditionalNext, 0, len(list))
+	for i, entry := range list {
+		m, ok := entry.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("next[%d]: expected {if, step} mapping, got %T", i, entry)
+		}
+		target, max, _ := parseNextMap(m)
+		if target == "" {
+			return nil, fmt.Errorf("next[%d]: step is required", i)
+		}
+		cond, _ := m["if"].(string)
+		targets = append(targets, ConditionalNext{If: cond, Step: target, Max: max})
+	}
+	return targets, nil
+}
+
 // ForEach is the iteration modifier.
 type ForEach struct {
 	As       string `yaml:"as"       json:"as"`
 	Over     string `yaml:"over"     json:"over"`
 	Key      string `yaml:"key,omitempty" json:"key,omitempty"` // produces map-structured outputs
 	Parallel bool   `yaml:"parallel,omitempty" json:"parallel,omitempty"`
+	// MaxParallel bounds concurrent iterations when Parallel is set; 0 means unbounded.
+	MaxParallel int `yaml:"max_parallel,omitempty" json:"max_parallel,omitempty"`
 }
 
 // Visibility declares which variable paths a step can access.
@@ -193,6 +281,28 @@ type RepeatBlock struct {
 	Steps []Step `yaml:"steps"           json:"steps"`           // steps to execute per iteration
 }
 
+// CacheConfig caches a tool step's outputs across runs. Key is a template;
+// a real-mode run whose resolved key matches a stored result younger than
+// TTL reuses its outputs instead of executing the tool.
+type CacheConfig struct {
+	TTL string `yaml:"ttl" json:"ttl"` // e.g. "5m"
+	Key string `yaml:"key" json:"key"` // e.g. "{{ .query }}_{{ .env }}"
+}
+
+// RetryPolicy re-runs a failing step up to Max attempts.
+type RetryPolicy struct {
+	Max     int    `yaml:"max"               json:"max"`               // total attempts, including the first
+	Delay   string `yaml:"delay,omitempty"   json:"delay,omitempty"`   // base delay between attempts (e.g. "5s")
+	Backoff string `yaml:"backoff,omitempty" json:"backoff,omitempty"` // fixed (default), linear, exponential
+}
+
+// Backoff strategies for RetryPolicy.
+const (
+	BackoffFixed       = "fixed"
+	BackoffLinear      = "linear"
+	BackoffExponential = "exponential"
+)
+
 // ---------------------------------------------------------------------------
 // Branch (used by branch + parallel steps)
 // ---------------------------------------------------------------------------
@@ -204,6 +314,21 @@ type Branch struct {
 	Steps     []Step `yaml:"steps"               json:"steps"`
 }
 
+// IfElse is the two-branch shorthand of a branch step:
+//
+//	branch:
+//	  if: "{{ gt .error_rate 5 }}"
+//	  steps: [...]
+//	  else: [...]
+//
+// Load rewrites it into Branches labeled "if" and "else", the second with
+// condition default, so everything after loading sees the canonical form.
+type IfElse struct {
+	If    string `yaml:"if"             json:"if"`
+	Steps []Step `yaml:"steps"          json:"steps"`
+	Else  []Step `yaml:"else,omitempty" json:"else,omitempty"`
+}
+
 // ---------------------------------------------------------------------------
 // Assertion
 // ---------------------------------------------------------------------------
@@ -214,6 +339,13 @@ type Assertion struct {
 	Value    string `yaml:"value,omitempty"     json:"value,omitempty"`
 	Expected string `yaml:"expected,omitempty"  json:"expected,omitempty"`
 	Pattern  string `yaml:"pattern,omitempty"   json:"pattern,omitempty"`
+	Path     string `yaml:"path,omitempty"      json:"path,omitempty"` // json_path: JSONPath into value
+	Step     string `yaml:"step,omitempty"      json:"step,omitempty"` // duration_less_than: step to time (default: the previous step)
+	Not      bool   `yaml:"not,omitempty"       json:"not,omitempty"`  // invert the check
+
+	// Values lists the substrings contains_all requires and contains_none
+	// forbids in value.
+	Values []string `yaml:"values,omitempty" json:"values,omitempty"`
 }
 
 // ---------------------------------------------------------------------------
@@ -228,6 +360,10 @@ const (
 	OutcomeEscalated OutcomeCategory = "escalated"
 	OutcomeNoAction  OutcomeCategory = "no_action"
 	OutcomeNeedsRCA  OutcomeCategory = "needs_rca"
+
+	// OutcomeTimedOut is set by the engine when the run's deadline passes.
+	// End steps cannot declare it.
+	OutcomeTimedOut OutcomeCategory = "timed_out"
 )
 
 // Outcome is the structured outcome carried by an end step.
@@ -248,6 +384,21 @@ type EvidenceRequirement struct {
 	Items []string `yaml:"items,omitempty"  json:"items,omitempty"`
 }
 
+// ChoiceConfig asks the operator of a manual step to pick one of a list of
+// options. The selected value is stored in the variable named by Variable.
+type ChoiceConfig struct {
+	Variable string         `yaml:"variable"         json:"variable"`
+	Prompt   string         `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Options  []ChoiceOption `yaml:"options"          json:"options"`
+}
+
+// ChoiceOption is a single selectable option in a choice.
+type ChoiceOption struct {
+	Value       string `yaml:"value"                 json:"value"`
+	Label       string `yaml:"label,omitempty"       json:"label,omitempty"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
 // ---------------------------------------------------------------------------
 // Secrets
 // ---------------------------------------------------------------------------
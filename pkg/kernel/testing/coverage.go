@@ -0,0 +1,125 @@
+package testing
+
+import (
+	"strings"
+
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+)
+
+// StepCoverage reports which scenarios visited one step.
+type StepCoverage struct {
+	ID        string   `json:"id"`
+	Type      string   `json:"type"`
+	Covered   bool     `json:"covered"`
+	Scenarios []string `json:"scenarios,omitempty"`
+}
+
+// CoverageReport is the step coverage of one runbook.
+type CoverageReport struct {
+	Runbook string         `json:"runbook"`
+	Steps   []StepCoverage `json:"steps"`
+	Covered int            `json:"covered"`
+	Total   int            `json:"total"`
+	Percent float64        `json:"percent"`
+}
+
+// CoverageSummary aggregates coverage across every runbook a Runner tested.
+type CoverageSummary struct {
+	Runbooks []*CoverageReport `json:"runbooks"`
+	Covered  int               `json:"covered"`
+	Total    int               `json:"total"`
+	Percent  float64           `json:"percent"`
+}
+
+// coverageRun holds the results recorded for one runbook.
+type coverageRun struct {
+	path    string
+	rb      *kschema.Runbook
+	results []TestResult
+}
+
+// record keeps results for Coverage. Runs of the same runbook accumulate.
+func (r *Runner) record(path string, rb *kschema.Runbook, results []TestResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.path == path {
+			run.results = append(run.results, results...)
+			return
+		}
+	}
+	r.runs = append(r.runs, &coverageRun{path: path, rb: rb, results: results})
+}
+
+// Coverage reports, for every step of every runbook tested so far, which
+// scenarios visited it. Runbooks appear in the order they were first run.
+func (r *Runner) Coverage() *CoverageSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	summary := &CoverageSummary{}
+	for _, run := range r.runs {
+		report := ComputeCoverage(run.rb, run.results)
+		summary.Runbooks = append(summary.Runbooks, report)
+		summary.Covered += report.Covered
+		summary.Total += report.Total
+	}
+	summary.Percent = percent(summary.Covered, summary.Total)
+	return summary
+}
+
+// ComputeCoverage matches the steps of rb, including those nested in
+// branches and repeat blocks, against the steps each result visited.
+// for_each iterations ("step[2]") count toward their step.
+func ComputeCoverage(rb *kschema.Runbook, results []TestResult) *CoverageReport {
+	visitedBy := make(map[string][]string)
+	for _, res := range results {
+		seen := make(map[string]bool)
+		for _, id := range res.VisitedSteps {
+			if i := strings.IndexByte(id, '['); i > 0 {
+				id = id[:i]
+			}
+			if !seen[id] {
+				seen[id] = true
+				visitedBy[id] = append(visitedBy[id], res.ScenarioName)
+			}
+		}
+	}
+
+	report := &CoverageReport{Runbook: rb.Meta.Name}
+	var walk func(steps []kschema.Step)
+	walk = func(steps []kschema.Step) {
+		for _, step := range steps {
+			if step.ID != "" {
+				scenarios := visitedBy[step.ID]
+				report.Steps = append(report.Steps, StepCoverage{
+					ID:        step.ID,
+					Type:      string(step.Type),
+					Covered:   len(scenarios) > 0,
+					Scenarios: scenarios,
+				})
+				report.Total++
+				if len(scenarios) > 0 {
+					report.Covered++
+				}
+			}
+			for _, br := range step.Branches {
+				walk(br.Steps)
+			}
+			if step.Repeat != nil {
+				walk(step.Repeat.Steps)
+			}
+		}
+	}
+	walk(rb.Steps)
+
+	report.Percent = percent(report.Covered, report.Total)
+	return report
+}
+
+func percent(covered, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return float64(covered) * 100 / float64(total)
+}
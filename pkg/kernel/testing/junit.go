@@ -0,0 +1,143 @@
+package testing
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JUnitWriter collects test outputs and writes them as JUnit XML, one
+// <testsuite> per runbook and one <testcase> per scenario.
+type JUnitWriter struct {
+	suites []junitSuite
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	TestCases []junitCase `xml:"testcase"`
+
+	durationMs int64
+}
+
+type junitCase struct {
+	Name      string          `xml:"name,attr"`
+	ClassName string          `xml:"classname,attr"`
+	Time      string          `xml:"time,attr"`
+	Skipped   *struct{}       `xml:"skipped"`
+	Errors    []junitProblem  `xml:"error"`
+	Failures  []junitProblem  `xml:"failure"`
+	SystemOut *junitSystemOut `xml:"system-out"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Body    string `xml:",cdata"`
+}
+
+type junitSystemOut struct {
+	Body string `xml:",cdata"`
+}
+
+// Add appends the scenarios of one runbook as a test suite. Summary.Failed
+// becomes the suite's failures count; each failed assertion of a scenario
+// is reported as its own <failure>.
+func (j *JUnitWriter) Add(output *TestOutput) {
+	suite := junitSuite{
+		Name:     output.Runbook,
+		Tests:    output.Summary.Total,
+		Failures: output.Summary.Failed,
+		Errors:   output.Summary.Errors,
+		Skipped:  output.Summary.Skipped,
+	}
+	for _, r := range output.Scenarios {
+		suite.durationMs += r.DurationMs
+		tc := junitCase{
+			Name:      r.ScenarioName,
+			ClassName: output.Runbook,
+			Time:      seconds(r.DurationMs),
+		}
+		switch r.Status {
+		case "skipped":
+			tc.Skipped = &struct{}{}
+		case "error":
+			tc.Errors = append(tc.Errors, junitProblem{Message: r.Error, Type: "error", Body: r.Error})
+		case "failed":
+			for _, a := range r.Assertions {
+				if a.Passed {
+					continue
+				}
+				tc.Failures = append(tc.Failures, junitProblem{
+					Message: a.Message,
+					Type:    a.Type,
+					Body:    assertionDetail(a),
+				})
+			}
+			if len(tc.Failures) == 0 {
+				tc.Failures = append(tc.Failures, junitProblem{Message: r.Error, Body: r.Error})
+			}
+		}
+		if len(r.VisitedSteps) > 0 {
+			tc.SystemOut = &junitSystemOut{Body: "visited: " + strings.Join(r.VisitedSteps, " → ")}
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Time = seconds(suite.durationMs)
+	j.suites = append(j.suites, suite)
+}
+
+// Write emits the collected suites as a <testsuites> document.
+func (j *JUnitWriter) Write(w io.Writer) error {
+	doc := junitSuites{Suites: j.suites}
+	var totalMs int64
+	for _, s := range j.suites {
+		doc.Tests += s.Tests
+		doc.Failures += s.Failures
+		doc.Errors += s.Errors
+		doc.Skipped += s.Skipped
+		totalMs += s.durationMs
+	}
+	doc.Time = seconds(totalMs)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode junit: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func assertionDetail(a AssertionResult) string {
+	var b strings.Builder
+	if a.Key != "" {
+		fmt.Fprintf(&b, "%s %s\n", a.Type, a.Key)
+	} else {
+		fmt.Fprintf(&b, "%s\n", a.Type)
+	}
+	fmt.Fprintf(&b, "expected: %s\nactual:   %s", a.Expected, a.Actual)
+	return b.String()
+}
+
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
@@ -0,0 +1,68 @@
+package testing
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestJUnitWriter(t *testing.T) {
+	output := &TestOutput{
+		Runbook: "disk-full.yaml",
+		Scenarios: []TestResult{
+			{ScenarioName: "healthy", Status: "passed", DurationMs: 120, VisitedSteps: []string{"check", "done"}},
+			{ScenarioName: "full", Status: "failed", DurationMs: 80, Assertions: []AssertionResult{
+				{Type: "expected_outcome", Expected: "resolved", Actual: "escalated", Passed: false, Message: `outcome "escalated" != "resolved"`},
+				{Type: "must_reach", Key: "cleanup", Passed: true},
+				{Type: "must_reach", Key: "notify", Expected: "reached", Actual: "not reached", Passed: false, Message: "step notify was not reached"},
+			}},
+			{ScenarioName: "broken", Status: "error", Error: "load scenario: no such file"},
+			{ScenarioName: "later", Status: "skipped"},
+		},
+		Summary: TestSummary{Total: 4, Passed: 1, Failed: 1, Errors: 1, Skipped: 1},
+	}
+
+	var j JUnitWriter
+	j.Add(output)
+	var buf bytes.Buffer
+	if err := j.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, xml.Header) {
+		t.Errorf("missing XML header:\n%s", out)
+	}
+	for _, want := range []string{
+		`<testsuites tests="4" failures="1" errors="1" skipped="1" time="0.200">`,
+		`<testsuite name="disk-full.yaml" tests="4" failures="1" errors="1" skipped="1" time="0.200">`,
+		`<testcase name="healthy" classname="disk-full.yaml" time="0.120">`,
+		`<failure message="outcome &#34;escalated&#34; != &#34;resolved&#34;" type="expected_outcome"><![CDATA[expected_outcome` + "\nexpected: resolved\nactual:   escalated]]></failure>",
+		`<failure message="step notify was not reached" type="must_reach">`,
+		`<error message="load scenario: no such file" type="error">`,
+		`<skipped></skipped>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %s in:\n%s", want, out)
+		}
+	}
+
+	// The document round-trips with one failure per failed assertion
+	var doc struct {
+		Suites []struct {
+			Cases []struct {
+				Name     string `xml:"name,attr"`
+				Failures []struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v", err)
+	}
+	cases := doc.Suites[0].Cases
+	if len(cases) != 4 || cases[1].Name != "full" || len(cases[1].Failures) != 2 || len(cases[0].Failures) != 0 {
+		t.Errorf("unexpected structure: %+v", doc)
+	}
+}
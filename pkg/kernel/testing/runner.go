@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/engine"
@@ -24,6 +25,7 @@ type TestResult struct {
 	Status       string            `json:"status"` // passed, failed, skipped, error
 	DurationMs   int64             `json:"duration_ms"`
 	Assertions   []AssertionResult `json:"assertions,omitempty"`
+	VisitedSteps []string          `json:"visited_steps,omitempty"`
 	Error        string            `json:"error,omitempty"`
 }
 
@@ -44,9 +46,19 @@ type TestOutput struct {
 }
 
 // Runner executes scenario-based tests against a runbook.
+// Concurrency bounds how many scenarios RunAll replays at once;
+// values below 2 run them sequentially. Results are kept for Coverage.
+// With Fuzz set, scenarios replay through a FuzzingExecutor seeded with
+// FuzzSeed instead of replaying their responses verbatim.
 type Runner struct {
-	Timeout  time.Duration
-	FailFast bool
+	Timeout     time.Duration
+	FailFast    bool
+	Concurrency int
+	Fuzz        bool
+	FuzzSeed    int64
+
+	mu   sync.Mutex
+	runs []*coverageRun
 }
 
 // ScenarioInfo describes a discovered scenario directory.
@@ -87,7 +99,9 @@ func DiscoverScenarios(runbookPath string) ([]ScenarioInfo, error) {
 	return scenarios, nil
 }
 
-// RunAll discovers and runs all scenarios for a runbook.
+// RunAll discovers and runs all scenarios for a runbook. Results are reported
+// in discovery order regardless of Concurrency. With FailFast, the first
+// failure cancels scenarios still in flight and nothing after it is reported.
 func (r *Runner) RunAll(runbookPath string) (*TestOutput, error) {
 	scenarios, err := DiscoverScenarios(runbookPath)
 	if err != nil {
@@ -103,8 +117,7 @@ func (r *Runner) RunAll(runbookPath string) (*TestOutput, error) {
 		Runbook: rb.Meta.Name,
 	}
 
-	for _, si := range scenarios {
-		result := r.runScenario(rb, runbookPath, si)
+	for _, result := range r.runScenarios(rb, runbookPath, scenarios) {
 		output.Scenarios = append(output.Scenarios, result)
 
 		switch result.Status {
@@ -119,14 +132,63 @@ func (r *Runner) RunAll(runbookPath string) (*TestOutput, error) {
 		}
 		output.Summary.Total++
 
-		if r.FailFast && (result.Status == "failed" || result.Status == "error") {
+		if r.FailFast && isFailure(result) {
 			break
 		}
 	}
 
+	r.record(runbookPath, rb, output.Scenarios)
 	return output, nil
 }
 
+// runScenarios replays scenarios with up to r.Concurrency workers. Results
+// come back in scenario order, ending before the first scenario that was
+// never started.
+func (r *Runner) runScenarios(rb *kschema.Runbook, runbookPath string, scenarios []ScenarioInfo) []TestResult {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	workers := r.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	results := make([]*TestResult, len(scenarios))
+	var wg sync.WaitGroup
+
+	for i, si := range scenarios {
+		sem <- struct{}{}
+		if ctx.Err() != nil {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result := r.runScenario(ctx, rb, runbookPath, si)
+			results[i] = &result
+			if r.FailFast && isFailure(result) {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	ran := make([]TestResult, 0, len(results))
+	for _, res := range results {
+		if res == nil {
+			break
+		}
+		ran = append(ran, *res)
+	}
+	return ran
+}
+
+func isFailure(result TestResult) bool {
+	return result.Status == "failed" || result.Status == "error"
+}
+
 // RunScenario runs a single named scenario.
 func (r *Runner) RunScenario(runbookPath, scenarioName string) (*TestResult, error) {
 	rb, valErrs := validate.ValidateFile(runbookPath)
@@ -139,13 +201,14 @@ func (r *Runner) RunScenario(runbookPath, scenarioName string) (*TestResult, err
 	scenarioDir := filepath.Join(dir, "scenarios", base, scenarioName)
 
 	si := ScenarioInfo{Name: scenarioName, Dir: scenarioDir}
-	result := r.runScenario(rb, runbookPath, si)
+	result := r.runScenario(context.Background(), rb, runbookPath, si)
+	r.record(runbookPath, rb, []TestResult{result})
 	return &result, nil
 }
 
 // runScenario executes a single scenario and evaluates its test spec.
-func (r *Runner) runScenario(rb *kschema.Runbook, runbookPath string, si ScenarioInfo) TestResult {
-	ctx := context.Background()
+// Cancelling ctx abandons the replay and reports the scenario as skipped.
+func (r *Runner) runScenario(ctx context.Context, rb *kschema.Runbook, runbookPath string, si ScenarioInfo) TestResult {
 	start := time.Now()
 
 	// Load scenario
@@ -188,6 +251,11 @@ func (r *Runner) runScenario(rb *kschema.Runbook, runbookPath string, si Scenari
 
 	// Build replay executor
 	replayExec := replay.NewReplayExecutor(scenario)
+	var toolExec engine.ToolExecutor = replayExec
+	if r.Fuzz {
+		fuzzExec := replay.NewFuzzingExecutor(scenario, r.FuzzSeed)
+		replayExec, toolExec = fuzzExec.ReplayExecutor, fuzzExec
+	}
 
 	// Merge scenario inputs
 	vars := make(map[string]string)
@@ -198,48 +266,57 @@ func (r *Runner) runScenario(rb *kschema.Runbook, runbookPath string, si Scenari
 	// Execute in replay mode
 	var traceBuf bytes.Buffer
 	tw := trace.NewWriter(&traceBuf, "test-"+si.Name)
+	timer := &stepTimer{durations: make(map[string]time.Duration)}
+	tw.AddSink(timer)
 
 	cfg := engine.RunConfig{
-		RunID:    "test-" + si.Name,
-		Mode:     "replay",
-		Vars:     vars,
-		BaseDir:  filepath.Dir(runbookPath),
-		Trace:    tw,
-		ToolExec: replayExec,
-		Stdin:    buildReplayStdin(replayExec, rb),
-		Stdout:   io.Discard,
+		RunID:     "test-" + si.Name,
+		Mode:      "replay",
+		Vars:      vars,
+		BaseDir:   filepath.Dir(runbookPath),
+		Trace:     tw,
+		ToolExec:  toolExec,
+		Stdin:     buildReplayStdin(replayExec, rb),
+		Stdout:    io.Discard,
+		SkipHooks: true, // scenarios replay offline; never notify external systems
 	}
 
 	eng := engine.New(rb, cfg)
 
 	// Run with timeout
-	var engineResult *engine.RunResult
+	runCtx := ctx
 	if r.Timeout > 0 {
-		done := make(chan struct{})
-		go func() {
-			engineResult = eng.Run(ctx)
-			close(done)
-		}()
-		select {
-		case <-done:
-		case <-time.After(r.Timeout):
-			return TestResult{
-				RunbookName:  rb.Meta.Name,
-				ScenarioName: si.Name,
-				Status:       "error",
-				DurationMs:   time.Since(start).Milliseconds(),
-				Error:        "timeout",
-			}
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	done := make(chan *engine.RunResult, 1)
+	go func() { done <- eng.Run(runCtx) }()
+
+	var engineResult *engine.RunResult
+	select {
+	case engineResult = <-done:
+	case <-runCtx.Done():
+		result := TestResult{
+			RunbookName:  rb.Meta.Name,
+			ScenarioName: si.Name,
+			Status:       "error",
+			DurationMs:   time.Since(start).Milliseconds(),
+			Error:        "timeout",
 		}
-	} else {
-		engineResult = eng.Run(ctx)
+		if ctx.Err() != nil {
+			result.Status = "skipped"
+			result.Error = "cancelled (fail-fast)"
+		}
+		return result
 	}
 
 	// Build RunResult for assertion evaluation
 	runResult := &RunResult{
-		Status:       engineResult.Status,
-		VisitedSteps: eng.VisitedSteps,
-		Outputs:      eng.Vars(),
+		Status:        engineResult.Status,
+		VisitedSteps:  eng.VisitedSteps,
+		Outputs:       eng.Vars(),
+		StepDurations: timer.durations,
 	}
 	if engineResult.Outcome != nil {
 		runResult.OutcomeCategory = string(engineResult.Outcome.Category)
@@ -262,6 +339,31 @@ func (r *Runner) runScenario(rb *kschema.Runbook, runbookPath string, si Scenari
 		Status:       status,
 		DurationMs:   time.Since(start).Milliseconds(),
 		Assertions:   assertions,
+		VisitedSteps: eng.VisitedSteps,
+	}
+}
+
+// stepTimer is a trace sink that records how long each step took, keeping
+// the longest execution of steps that run more than once.
+type stepTimer struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+}
+
+// Export implements trace.Sink.
+func (t *stepTimer) Export(evt trace.Event) {
+	if evt.Type != trace.EventStepComplete {
+		return
+	}
+	stepID, _ := evt.Data["step_id"].(string)
+	d, err := time.ParseDuration(fmt.Sprint(evt.Data["duration"]))
+	if stepID == "" || err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if d > t.durations[stepID] {
+		t.durations[stepID] = d
 	}
 }
 
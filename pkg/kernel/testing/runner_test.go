@@ -0,0 +1,208 @@
+package testing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kschema "github.com/ormasoftchile/gert/pkg/kernel/schema"
+)
+
+const runnerTestRunbook = `apiVersion: kernel/v0
+meta:
+  name: runner-test
+  inputs:
+    expected:
+      type: string
+steps:
+  - id: check
+    type: assert
+    assert:
+      - type: equals
+        value: "ok"
+        expected: "{{ .expected }}"
+  - id: done
+    type: end
+    outcome:
+      category: no_action
+      code: checked
+`
+
+// writeScenarios lays out runner-test.yaml with one scenario per entry in
+// expected; a scenario passes when its entry is "ok".
+func writeScenarios(tb testing.TB, expected []string) string {
+	tb.Helper()
+	dir := tb.TempDir()
+	rbPath := filepath.Join(dir, "runner-test.yaml")
+	if err := os.WriteFile(rbPath, []byte(runnerTestRunbook), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	for i, exp := range expected {
+		sdir := filepath.Join(dir, "scenarios", "runner-test", fmt.Sprintf("s%03d", i))
+		if err := os.MkdirAll(sdir, 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		scenario := fmt.Sprintf("inputs:\n  expected: %q\n", exp)
+		if err := os.WriteFile(filepath.Join(sdir, "scenario.yaml"), []byte(scenario), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		spec := "expected_status: completed\nmust_reach:\n  - done\n"
+		if err := os.WriteFile(filepath.Join(sdir, "test.yaml"), []byte(spec), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return rbPath
+}
+
+func TestRunAll_ParallelKeepsScenarioOrder(t *testing.T) {
+	expected := make([]string, 12)
+	for i := range expected {
+		expected[i] = "ok"
+	}
+	expected[5] = "nope"
+	rbPath := writeScenarios(t, expected)
+
+	runner := &Runner{Concurrency: 4}
+	output, err := runner.RunAll(rbPath)
+	if err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	if output.Summary.Total != 12 || output.Summary.Passed != 11 || output.Summary.Failed != 1 {
+		t.Fatalf("summary = %+v", output.Summary)
+	}
+	for i, s := range output.Scenarios {
+		if want := fmt.Sprintf("s%03d", i); s.ScenarioName != want {
+			t.Errorf("scenario[%d] = %s, want %s", i, s.ScenarioName, want)
+		}
+	}
+	if output.Scenarios[5].Status != "failed" {
+		t.Errorf("s005 status = %s, want failed", output.Scenarios[5].Status)
+	}
+}
+
+func TestRunAll_ParallelFailFast(t *testing.T) {
+	expected := make([]string, 20)
+	for i := range expected {
+		expected[i] = "ok"
+	}
+	expected[2] = "nope"
+	rbPath := writeScenarios(t, expected)
+
+	runner := &Runner{Concurrency: 4, FailFast: true}
+	output, err := runner.RunAll(rbPath)
+	if err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	if n := len(output.Scenarios); n != 3 {
+		t.Fatalf("scenarios reported = %d, want 3 (stop after s002)", n)
+	}
+	if last := output.Scenarios[2]; last.ScenarioName != "s002" || last.Status != "failed" {
+		t.Errorf("last scenario = %s/%s, want s002/failed", last.ScenarioName, last.Status)
+	}
+	if output.Summary.Failed != 1 || output.Summary.Total != 3 {
+		t.Errorf("summary = %+v", output.Summary)
+	}
+}
+
+func TestRunScenario_DurationAssertion(t *testing.T) {
+	rbPath := writeScenarios(t, []string{"ok"})
+	spec := `assertions:
+  - type: duration_less_than
+    step_id: check
+    expected: 1m
+  - type: duration_less_than
+    step_id: missing
+    expected: 1m
+`
+	specPath := filepath.Join(filepath.Dir(rbPath), "scenarios", "runner-test", "s000", "test.yaml")
+	if err := os.WriteFile(specPath, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := (&Runner{}).RunScenario(rbPath, "s000")
+	if err != nil {
+		t.Fatalf("RunScenario: %v", err)
+	}
+	if len(result.Assertions) != 2 {
+		t.Fatalf("assertions = %+v", result.Assertions)
+	}
+	if !result.Assertions[0].Passed {
+		t.Errorf("check step should finish within 1m: %+v", result.Assertions[0])
+	}
+	if result.Assertions[1].Passed || result.Status != "failed" {
+		t.Errorf("a step that never ran should fail the scenario: %+v", result)
+	}
+}
+
+func TestRunner_Coverage(t *testing.T) {
+	// s001 fails its assert and never reaches done
+	rbPath := writeScenarios(t, []string{"ok", "nope"})
+
+	runner := &Runner{}
+	if _, err := runner.RunAll(rbPath); err != nil {
+		t.Fatalf("RunAll: %v", err)
+	}
+	cov := runner.Coverage()
+	if len(cov.Runbooks) != 1 || cov.Covered != 2 || cov.Total != 2 || cov.Percent != 100 {
+		t.Fatalf("coverage = %+v", cov)
+	}
+	steps := cov.Runbooks[0].Steps
+	if got := fmt.Sprint(steps[0].Scenarios, steps[1].Scenarios); got != "[s000 s001] [s000]" {
+		t.Errorf("scenarios = %s", got)
+	}
+
+	// A second run of a failing scenario only adds to the same runbook
+	if _, err := runner.RunScenario(rbPath, "s001"); err != nil {
+		t.Fatalf("RunScenario: %v", err)
+	}
+	if cov := runner.Coverage(); len(cov.Runbooks) != 1 || cov.Total != 2 {
+		t.Errorf("coverage after RunScenario = %+v", cov)
+	}
+}
+
+func TestComputeCoverage_NestedSteps(t *testing.T) {
+	rb := &kschema.Runbook{
+		Meta: kschema.Meta{Name: "nested"},
+		Steps: []kschema.Step{
+			{ID: "each", Type: kschema.StepTool},
+			{ID: "pick", Type: kschema.StepBranch, Branches: []kschema.Branch{
+				{Steps: []kschema.Step{{ID: "left", Type: kschema.StepManual}}},
+				{Steps: []kschema.Step{{ID: "right", Type: kschema.StepManual}}},
+			}},
+		},
+	}
+	results := []TestResult{{ScenarioName: "a", VisitedSteps: []string{"each[0]", "each[1]", "pick", "left"}}}
+
+	report := ComputeCoverage(rb, results)
+	if report.Covered != 3 || report.Total != 4 || report.Percent != 75 {
+		t.Fatalf("report = %+v", report)
+	}
+	for _, s := range report.Steps {
+		if s.ID == "right" && s.Covered {
+			t.Error("right should be uncovered")
+		}
+		if s.ID == "each" && len(s.Scenarios) != 1 {
+			t.Errorf("each scenarios = %v, want one entry per scenario", s.Scenarios)
+		}
+	}
+}
+
+func BenchmarkRunAll(b *testing.B) {
+	expected := make([]string, 64)
+	for i := range expected {
+		expected[i] = "ok"
+	}
+	rbPath := writeScenarios(b, expected)
+
+	for _, n := range []int{1, 4, 8} {
+		b.Run(fmt.Sprintf("parallel=%d", n), func(b *testing.B) {
+			runner := &Runner{Concurrency: n}
+			for b.Loop() {
+				if _, err := runner.RunAll(rbPath); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -15,14 +16,24 @@ import (
 // TestSpec declares what to assert about a scenario replay result.
 // All fields are optional — omitted fields produce no assertions.
 type TestSpec struct {
-	Description     string            `yaml:"description,omitempty" json:"description,omitempty"`
-	ExpectedOutcome string            `yaml:"expected_outcome,omitempty" json:"expected_outcome,omitempty"` // outcome category
-	ExpectedCode    string            `yaml:"expected_code,omitempty" json:"expected_code,omitempty"`       // outcome code
-	MustReach       []string          `yaml:"must_reach,omitempty" json:"must_reach,omitempty"`             // step IDs that must be visited
-	MustNotReach    []string          `yaml:"must_not_reach,omitempty" json:"must_not_reach,omitempty"`     // step IDs that must NOT be visited
-	ExpectedOutputs map[string]string `yaml:"expected_outputs,omitempty" json:"expected_outputs,omitempty"` // variable → expected value
-	ExpectedStatus  string            `yaml:"expected_status,omitempty" json:"expected_status,omitempty"`   // completed, failed, error
-	Tags            []string          `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Description     string              `yaml:"description,omitempty" json:"description,omitempty"`
+	ExpectedOutcome string              `yaml:"expected_outcome,omitempty" json:"expected_outcome,omitempty"` // outcome category
+	ExpectedCode    string              `yaml:"expected_code,omitempty" json:"expected_code,omitempty"`       // outcome code
+	MustReach       []string            `yaml:"must_reach,omitempty" json:"must_reach,omitempty"`             // step IDs that must be visited
+	MustNotReach    []string            `yaml:"must_not_reach,omitempty" json:"must_not_reach,omitempty"`     // step IDs that must NOT be visited
+	ExpectedOutputs map[string]string   `yaml:"expected_outputs,omitempty" json:"expected_outputs,omitempty"` // variable → expected value
+	ExpectedStatus  string              `yaml:"expected_status,omitempty" json:"expected_status,omitempty"`   // completed, failed, error
+	Tags            []string            `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Assertions      []DurationAssertion `yaml:"assertions,omitempty" json:"assertions,omitempty"`
+}
+
+// DurationAssertion bounds how long a step may take during replay, for
+// performance regression tests. Type must be "duration_less_than" and
+// Expected a duration such as "10s".
+type DurationAssertion struct {
+	Type     string `yaml:"type" json:"type"`
+	StepID   string `yaml:"step_id" json:"step_id"`
+	Expected string `yaml:"expected" json:"expected"`
 }
 
 // LoadTestSpec loads a test spec from a YAML file.
@@ -51,9 +62,10 @@ func ParseTestSpec(data []byte) (*TestSpec, error) {
 type RunResult struct {
 	OutcomeCategory string
 	OutcomeCode     string
-	Status          string         // completed, failed, error
-	VisitedSteps    []string       // ordered step IDs
-	Outputs         map[string]any // final variable state
+	Status          string                   // completed, failed, error
+	VisitedSteps    []string                 // ordered step IDs
+	Outputs         map[string]any           // final variable state
+	StepDurations   map[string]time.Duration // step ID → longest single execution
 	Error           error
 }
 
@@ -150,9 +162,47 @@ func Evaluate(spec *TestSpec, run *RunResult) []AssertionResult {
 		})
 	}
 
+	for _, a := range spec.Assertions {
+		results = append(results, evaluateDuration(a, run.StepDurations))
+	}
+
 	return results
 }
 
+// evaluateDuration checks a duration_less_than assertion against the
+// recorded step durations.
+func evaluateDuration(a DurationAssertion, durations map[string]time.Duration) AssertionResult {
+	result := AssertionResult{Type: a.Type, Key: a.StepID, Expected: "< " + a.Expected}
+	if a.Type != "duration_less_than" {
+		result.Message = fmt.Sprintf("unknown assertion type %q", a.Type)
+		return result
+	}
+	limit, err := time.ParseDuration(a.Expected)
+	if err != nil {
+		result.Message = fmt.Sprintf("step '%s': invalid duration %q", a.StepID, a.Expected)
+		return result
+	}
+	took, ok := durations[a.StepID]
+	if !ok {
+		result.Actual = "not run"
+		result.Message = fmt.Sprintf("step '%s' did not run, expected < %s", a.StepID, a.Expected)
+		return result
+	}
+	result.Actual = roundDuration(took).String()
+	result.Passed = took < limit
+	result.Message = fmt.Sprintf("step '%s' took %s, expected < %s", a.StepID, result.Actual, a.Expected)
+	return result
+}
+
+// roundDuration trims a step duration for display: milliseconds below a
+// second, tenths of a second above.
+func roundDuration(d time.Duration) time.Duration {
+	if d < time.Second {
+		return d.Round(time.Millisecond)
+	}
+	return d.Round(100 * time.Millisecond)
+}
+
 // HasFailures returns true if any assertion failed.
 func HasFailures(results []AssertionResult) bool {
 	for _, r := range results {
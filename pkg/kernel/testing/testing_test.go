@@ -2,6 +2,7 @@ package testing
 
 import (
 	"testing"
+	"time"
 )
 
 func TestParseTestSpec(t *testing.T) {
@@ -145,6 +146,36 @@ func TestEvaluate_RegexNoMatch(t *testing.T) {
 	}
 }
 
+func TestEvaluate_DurationLessThan(t *testing.T) {
+	spec := &TestSpec{Assertions: []DurationAssertion{
+		{Type: "duration_less_than", StepID: "fast", Expected: "10s"},
+		{Type: "duration_less_than", StepID: "query_step", Expected: "10s"},
+		{Type: "duration_less_than", StepID: "skipped", Expected: "10s"},
+		{Type: "duration_less_than", StepID: "fast", Expected: "soon"},
+		{Type: "duration_more_than", StepID: "fast", Expected: "10s"},
+	}}
+	run := &RunResult{StepDurations: map[string]time.Duration{
+		"fast":       250 * time.Millisecond,
+		"query_step": 12340 * time.Millisecond,
+	}}
+
+	results := Evaluate(spec, run)
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	if !results[0].Passed || results[0].Actual != "250ms" {
+		t.Errorf("fast step should pass, got %+v", results[0])
+	}
+	if results[1].Passed || results[1].Message != "step 'query_step' took 12.3s, expected < 10s" {
+		t.Errorf("slow step should fail, got %+v", results[1])
+	}
+	for _, r := range results[2:] {
+		if r.Passed {
+			t.Errorf("expected failure, got %+v", r)
+		}
+	}
+}
+
 func TestEvaluate_EmptySpec(t *testing.T) {
 	spec := &TestSpec{}
 	run := &RunResult{}
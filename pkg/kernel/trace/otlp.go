@@ -0,0 +1,203 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// EnvOTLPEndpoint and EnvOTLPHeaders are the standard OpenTelemetry
+// environment variables for the OTLP exporter.
+const (
+	EnvOTLPEndpoint = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvOTLPHeaders  = "OTEL_EXPORTER_OTLP_HEADERS"
+)
+
+// otlpTracesPath is appended to a base endpoint that has no path, as the
+// OpenTelemetry spec requires for OTEL_EXPORTER_OTLP_ENDPOINT.
+const otlpTracesPath = "/v1/traces"
+
+// Sink receives every event after it has been written to the JSONL stream.
+type Sink interface {
+	Export(evt Event)
+}
+
+// OTLPExporter turns trace events into OpenTelemetry spans: run_start and
+// run_complete bound the root span, step_start and step_complete bound a
+// child span per step.
+type OTLPExporter struct {
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+	tracer   oteltrace.Tracer
+	runCtx   context.Context
+	runSpan  oteltrace.Span
+	steps    map[string]oteltrace.Span
+}
+
+// NewOTLPExporter creates an exporter that sends spans to an OTLP/HTTP
+// endpoint. A base URL without a path (e.g. http://collector:4318) gets
+// /v1/traces appended.
+func NewOTLPExporter(endpoint string, headers map[string]string) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpTracesPath
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(u.String())}
+	if len(headers) > 0 {
+		opts = append(opts, otlptracehttp.WithHeaders(headers))
+	}
+	exp, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+	return newOTLPExporter(sdktrace.WithBatcher(exp)), nil
+}
+
+func newOTLPExporter(opt sdktrace.TracerProviderOption) *OTLPExporter {
+	provider := sdktrace.NewTracerProvider(opt, sdktrace.WithResource(resource.NewSchemaless(
+		semconv.ServiceName("gert"),
+	)))
+	return &OTLPExporter{
+		provider: provider,
+		tracer:   provider.Tracer("github.com/ormasoftchile/gert/pkg/kernel/trace"),
+		runCtx:   context.Background(),
+		steps:    make(map[string]oteltrace.Span),
+	}
+}
+
+// NewOTLPWriter creates a trace writer that exports spans to an OTLP endpoint
+// instead of writing JSONL. Call Close to flush spans when the run ends.
+func NewOTLPWriter(endpoint string, headers map[string]string, runID string) (*Writer, error) {
+	exp, err := NewOTLPExporter(endpoint, headers)
+	if err != nil {
+		return nil, err
+	}
+	tw := NewWriter(io.Discard, runID)
+	tw.AddSink(exp)
+	return tw, nil
+}
+
+// ParseOTLPHeaders parses the OTEL_EXPORTER_OTLP_HEADERS format
+// ("key1=value1,key2=value2"). Malformed entries are ignored.
+func ParseOTLPHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			continue
+		}
+		if unescaped, err := url.QueryUnescape(strings.TrimSpace(v)); err == nil {
+			v = unescaped
+		}
+		headers[k] = strings.TrimSpace(v)
+	}
+	return headers
+}
+
+// Export maps one trace event onto the span tree.
+func (x *OTLPExporter) Export(evt Event) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	switch evt.Type {
+	case EventRunStart:
+		runbook, _ := evt.Data["runbook"].(string)
+		x.runCtx, x.runSpan = x.tracer.Start(context.Background(), "run "+runbook,
+			oteltrace.WithTimestamp(evt.Timestamp),
+			oteltrace.WithAttributes(
+				attribute.String("gert.run.id", evt.RunID),
+				attribute.String("gert.runbook", runbook),
+			))
+
+	case EventStepStart:
+		stepID, _ := evt.Data["step_id"].(string)
+		stepType, _ := evt.Data["type"].(string)
+		_, span := x.tracer.Start(x.runCtx, "step "+stepID,
+			oteltrace.WithTimestamp(evt.Timestamp),
+			oteltrace.WithAttributes(
+				attribute.String("gert.step.id", stepID),
+				attribute.String("gert.step.type", stepType),
+			))
+		x.steps[stepID] = span
+
+	case EventStepComplete:
+		stepID, _ := evt.Data["step_id"].(string)
+		span, ok := x.steps[stepID]
+		if !ok {
+			return
+		}
+		delete(x.steps, stepID)
+		status, _ := evt.Data["status"].(string)
+		span.SetAttributes(attribute.String("gert.step.status", status))
+		if d, err := time.ParseDuration(fmt.Sprint(evt.Data["duration"])); err == nil {
+			span.SetAttributes(attribute.Int64("gert.step.duration_ms", d.Milliseconds()))
+		}
+		if status == string(StatusFailed) || status == string(StatusError) {
+			span.SetStatus(codes.Error, failureMessage(evt.Data))
+		}
+		span.End(oteltrace.WithTimestamp(evt.Timestamp))
+
+	case EventRunComplete:
+		if x.runSpan == nil {
+			return
+		}
+		status, _ := evt.Data["status"].(string)
+		x.runSpan.SetAttributes(attribute.String("gert.run.status", status))
+		if status != "" && status != "completed" {
+			x.runSpan.SetStatus(codes.Error, status)
+		}
+		x.endOpenSteps(evt.Timestamp)
+		x.runSpan.End(oteltrace.WithTimestamp(evt.Timestamp))
+		x.runSpan = nil
+		x.runCtx = context.Background()
+	}
+}
+
+// Close ends any open spans and flushes pending spans to the endpoint.
+func (x *OTLPExporter) Close() error {
+	x.mu.Lock()
+	now := time.Now()
+	x.endOpenSteps(now)
+	if x.runSpan != nil {
+		x.runSpan.End(oteltrace.WithTimestamp(now))
+		x.runSpan = nil
+	}
+	x.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return x.provider.Shutdown(ctx)
+}
+
+func (x *OTLPExporter) endOpenSteps(at time.Time) {
+	for id, span := range x.steps {
+		span.End(oteltrace.WithTimestamp(at))
+		delete(x.steps, id)
+	}
+}
+
+func failureMessage(data map[string]any) string {
+	if f, ok := data["failure"].(map[string]any); ok {
+		if msg, ok := f["message"].(string); ok {
+			return msg
+		}
+	}
+	return ""
+}
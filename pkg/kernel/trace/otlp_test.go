@@ -0,0 +1,124 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// keepSpans is an in-memory exporter whose spans survive provider shutdown.
+type keepSpans struct{ *tracetest.InMemoryExporter }
+
+func (keepSpans) Shutdown(context.Context) error { return nil }
+
+func spanAttr(s tracetest.SpanStub, key string) attribute.Value {
+	for _, kv := range s.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestOTLPExporter_RunAndStepSpans(t *testing.T) {
+	mem := tracetest.NewInMemoryExporter()
+	exp := newOTLPExporter(sdktrace.WithSyncer(keepSpans{mem}))
+
+	var buf bytes.Buffer
+	tw := NewWriter(&buf, "run-1")
+	tw.AddSink(exp)
+
+	tw.EmitRunStart("deploy.yaml", nil, nil)
+	tw.EmitStepStart("check", "tool", nil)
+	tw.EmitStepComplete("check", StatusSuccess, nil, 1500*time.Millisecond, nil)
+	tw.EmitStepStart("apply", "tool", nil)
+	tw.EmitStepComplete("apply", StatusFailed, nil, time.Second, &Failure{Kind: "exit_code", Message: "exit 1"})
+	tw.EmitRunComplete(nil, "failed", 3*time.Second)
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// JSONL stream is unaffected by the sink
+	if n := strings.Count(buf.String(), "\n"); n != 6 {
+		t.Errorf("JSONL lines = %d, want 6", n)
+	}
+
+	spans := mem.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("spans = %d, want 3", len(spans))
+	}
+	byName := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+
+	root, ok := byName["run deploy.yaml"]
+	if !ok {
+		t.Fatalf("missing root span, got %v", spans)
+	}
+	if got := spanAttr(root, "gert.run.id").AsString(); got != "run-1" {
+		t.Errorf("gert.run.id = %q, want run-1", got)
+	}
+	if root.Status.Code != codes.Error {
+		t.Errorf("root status = %v, want Error", root.Status.Code)
+	}
+
+	check := byName["step check"]
+	if check.Parent.SpanID() != root.SpanContext.SpanID() {
+		t.Error("step span should be a child of the run span")
+	}
+	if got := spanAttr(check, "gert.step.type").AsString(); got != "tool" {
+		t.Errorf("gert.step.type = %q, want tool", got)
+	}
+	if got := spanAttr(check, "gert.step.status").AsString(); got != "success" {
+		t.Errorf("gert.step.status = %q, want success", got)
+	}
+	if got := spanAttr(check, "gert.step.duration_ms").AsInt64(); got != 1500 {
+		t.Errorf("gert.step.duration_ms = %d, want 1500", got)
+	}
+
+	apply := byName["step apply"]
+	if apply.Status.Code != codes.Error || apply.Status.Description != "exit 1" {
+		t.Errorf("apply status = %+v, want Error/exit 1", apply.Status)
+	}
+}
+
+func TestOTLPExporter_CloseEndsOpenSpans(t *testing.T) {
+	mem := tracetest.NewInMemoryExporter()
+	exp := newOTLPExporter(sdktrace.WithSyncer(keepSpans{mem}))
+
+	tw := NewWriter(&bytes.Buffer{}, "run-1")
+	tw.AddSink(exp)
+	tw.EmitRunStart("r.yaml", nil, nil)
+	tw.EmitStepStart("s1", "manual", nil)
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if n := len(mem.GetSpans()); n != 2 {
+		t.Errorf("spans = %d, want 2 (run + open step)", n)
+	}
+}
+
+func TestNewOTLPExporter_InvalidEndpoint(t *testing.T) {
+	if _, err := NewOTLPExporter("not a url", nil); err == nil {
+		t.Fatal("expected error for invalid endpoint")
+	}
+}
+
+func TestParseOTLPHeaders(t *testing.T) {
+	got := ParseOTLPHeaders("api-key=secret, x-team = ops%20team,broken,=x")
+	if len(got) != 2 {
+		t.Fatalf("headers = %v, want 2 entries", got)
+	}
+	if got["api-key"] != "secret" || got["x-team"] != "ops team" {
+		t.Errorf("headers = %v", got)
+	}
+}
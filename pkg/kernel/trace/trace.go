@@ -18,28 +18,45 @@ import (
 type EventType string
 
 const (
-	EventRunStart           EventType = "run_start"
-	EventRunComplete        EventType = "run_complete"
-	EventStepStart          EventType = "step_start"
-	EventStepComplete       EventType = "step_complete"
-	EventBranchEnter        EventType = "branch_enter"
-	EventBranchExit         EventType = "branch_exit"
-	EventParallelFork       EventType = "parallel_fork"
-	EventParallelMerge      EventType = "parallel_merge"
-	EventOutcomeResolved    EventType = "outcome_resolved"
-	EventContractEvaluated  EventType = "contract_evaluated"
-	EventGovernanceDecision EventType = "governance_decision"
-	EventRedactionApplied   EventType = "redaction_applied"
-	EventForEachStart       EventType = "for_each_start"
-	EventForEachItem        EventType = "for_each_item"
-	EventApprovalSubmitted  EventType = "approval_submitted"
-	EventApprovalResolved   EventType = "approval_resolved"
-	EventScopeExport        EventType = "scope_export"
-	EventVisibilityApplied  EventType = "visibility_applied"
-	EventRepeatStart        EventType = "repeat_start"
-	EventRepeatIteration    EventType = "repeat_iteration"
-	EventContractViolation  EventType = "contract_violation"
-	EventInputResolved      EventType = "input_resolved"
+	EventRunStart            EventType = "run_start"
+	EventRunComplete         EventType = "run_complete"
+	EventStepStart           EventType = "step_start"
+	EventStepComplete        EventType = "step_complete"
+	EventBranchEnter         EventType = "branch_enter"
+	EventBranchExit          EventType = "branch_exit"
+	EventParallelFork        EventType = "parallel_fork"
+	EventParallelMerge       EventType = "parallel_merge"
+	EventOutcomeResolved     EventType = "outcome_resolved"
+	EventContractEvaluated   EventType = "contract_evaluated"
+	EventGovernanceDecision  EventType = "governance_decision"
+	EventRedactionApplied    EventType = "redaction_applied"
+	EventForEachStart        EventType = "for_each_start"
+	EventForEachItem         EventType = "for_each_item"
+	EventForEachKeyCollision EventType = "for_each_key_collision"
+	EventApprovalSubmitted   EventType = "approval_submitted"
+	EventApprovalResolved    EventType = "approval_resolved"
+	EventApprovalRequested   EventType = "approval_requested"
+	EventApprovalGranted     EventType = "approval_granted"
+	EventApprovalDenied      EventType = "approval_denied"
+	EventApprovalRecorded    EventType = "approval_recorded"
+	EventScopeExport         EventType = "scope_export"
+	EventVisibilityApplied   EventType = "visibility_applied"
+	EventRepeatStart         EventType = "repeat_start"
+	EventRepeatIteration     EventType = "repeat_iteration"
+	EventRepeatComplete      EventType = "repeat_complete"
+	EventContractViolation   EventType = "contract_violation"
+	EventInputResolved       EventType = "input_resolved"
+	EventStepRetry           EventType = "step_retry"
+	EventStepOnFailure       EventType = "step_on_failure"
+	EventExtensionStarted    EventType = "extension_started"
+	EventExtensionCompleted  EventType = "extension_completed"
+	EventHookWarning         EventType = "hook_warning"
+	EventStepTimeout         EventType = "step_timeout"
+	EventManualPrompt        EventType = "manual_prompt"
+	EventManualComplete      EventType = "manual_complete"
+	EventManualChoice        EventType = "manual_choice"
+	EventToolCacheHit        EventType = "tool_cache_hit"
+	EventToolCacheMiss       EventType = "tool_cache_miss"
 )
 
 // StepStatus is the execution status of a step.
@@ -76,6 +93,7 @@ type Writer struct {
 	secretVars []string
 	prevHash   string // SHA-256 of previous event JSON
 	chainHash  string // running chain hash
+	sinks      []Sink // additional exporters (e.g. OTLP)
 }
 
 // NewWriter creates a trace writer that writes to the given io.Writer.
@@ -96,9 +114,14 @@ func (tw *Writer) SetSecrets(envVars []string) {
 
 // RedactSecrets replaces secret values in a string with "<REDACTED>".
 func (tw *Writer) RedactSecrets(s string) string {
-	for _, envVar := range tw.secretVars {
+	return Redact(s, tw.secretVars)
+}
+
+// Redact replaces the values of the given env vars in s with "<REDACTED>".
+func Redact(s string, envVars []string) string {
+	for _, envVar := range envVars {
 		val := os.Getenv(envVar)
-		if val != "" && len(val) > 0 {
+		if val != "" {
 			s = strings.ReplaceAll(s, val, "<REDACTED>")
 		}
 	}
@@ -147,9 +170,39 @@ func (tw *Writer) Emit(eventType EventType, data map[string]any) error {
 	// Write the JSON line
 	jsonBytes = append(jsonBytes, '\n')
 	_, err = tw.w.Write(jsonBytes)
+
+	for _, s := range tw.sinks {
+		s.Export(evt)
+	}
 	return err
 }
 
+// AddSink registers an exporter that receives every subsequent event in
+// addition to the JSONL stream.
+func (tw *Writer) AddSink(s Sink) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.sinks = append(tw.sinks, s)
+}
+
+// Close flushes and closes any sinks that implement io.Closer. The
+// underlying io.Writer is left open.
+func (tw *Writer) Close() error {
+	tw.mu.Lock()
+	sinks := tw.sinks
+	tw.mu.Unlock()
+
+	var firstErr error
+	for _, s := range sinks {
+		if c, ok := s.(io.Closer); ok {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
 // EmitStepStart emits a step_start event.
 func (tw *Writer) EmitStepStart(stepID, stepType string, contractSummary map[string]any) error {
 	data := map[string]any{
@@ -217,6 +270,18 @@ func (tw *Writer) EmitBranchExit(label string) error {
 	})
 }
 
+// EmitParallelFork emits a parallel_fork event. serialized reports whether
+// any branches had to run one after another because their contracts
+// conflict.
+func (tw *Writer) EmitParallelFork(stepID string, branches []string, serialized bool) error {
+	return tw.Emit(EventParallelFork, map[string]any{
+		"step_id":      stepID,
+		"branch_count": len(branches),
+		"branches":     branches,
+		"serialized":   serialized,
+	})
+}
+
 // EmitOutcomeResolved emits an outcome_resolved event.
 func (tw *Writer) EmitOutcomeResolved(category, code string, meta map[string]any) error {
 	outcome := map[string]any{
@@ -2,12 +2,18 @@ package validate
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/contract"
+	"github.com/ormasoftchile/gert/pkg/kernel/eval"
 	"github.com/ormasoftchile/gert/pkg/kernel/schema"
 )
 
@@ -50,6 +56,9 @@ func validateDomain(rb *schema.Runbook, baseDir string) []*ValidationError {
 		errs = append(errs, validateNextTarget(s, rb.Steps, path)...)
 	})
 
+	// D6b: on_failure targets must be scope-local
+	errs = append(errs, validateOnFailureTargets(rb.Steps, "steps")...)
+
 	// D7: next backward jumps must have max
 	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
 		errs = append(errs, validateNextBounded(s, rb.Steps, path)...)
@@ -89,11 +98,44 @@ func validateDomain(rb *schema.Runbook, baseDir string) []*ValidationError {
 		}
 	})
 
-	// D14: assert step must have assertions
+	// D14: assert step must have assertions; json_path assertions need a valid
+	// path, duration_less_than assertions a valid duration and
+	// contains_all/contains_none assertions a non-empty values list; not: on
+	// not_equals is a double negative
 	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
 		if s.Type == schema.StepAssert && len(s.Assert) == 0 {
 			errs = append(errs, errorf("domain", path, "assert step must have at least one assertion"))
 		}
+		for i, a := range s.Assert {
+			apath := fmt.Sprintf("%s.assert[%d]", path, i)
+			if a.Not && a.Type == "not_equals" {
+				errs = append(errs, warningf("domain", apath+".not", "not: true on a not_equals assertion is a double negative; use equals"))
+			}
+			if a.Type == "duration_less_than" {
+				if a.Expected == "" {
+					errs = append(errs, errorf("domain", apath, "duration_less_than assertion requires 'expected'"))
+				} else if !strings.Contains(a.Expected, "{{") {
+					if _, err := time.ParseDuration(a.Expected); err != nil {
+						errs = append(errs, errorf("domain", apath+".expected", "%s", err))
+					}
+				}
+				continue
+			}
+			if a.Type == "contains_all" || a.Type == "contains_none" {
+				if len(a.Values) == 0 {
+					errs = append(errs, errorf("domain", apath, "%s assertion requires a non-empty 'values' list", a.Type))
+				}
+				continue
+			}
+			if a.Type != "json_path" {
+				continue
+			}
+			if a.Path == "" {
+				errs = append(errs, errorf("domain", apath, "json_path assertion requires 'path'"))
+			} else if err := eval.CheckJSONPath(a.Path); err != nil {
+				errs = append(errs, errorf("domain", apath+".path", "%s", err))
+			}
+		}
 	})
 
 	// D15: for_each validation
@@ -106,12 +148,7 @@ func validateDomain(rb *schema.Runbook, baseDir string) []*ValidationError {
 	// D15b: repeat block validation
 	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
 		if s.Repeat != nil {
-			if s.Repeat.Max <= 0 {
-				errs = append(errs, errorf("domain", path+".repeat.max", "repeat.max must be > 0"))
-			}
-			if len(s.Repeat.Steps) == 0 {
-				errs = append(errs, errorf("domain", path+".repeat.steps", "repeat block must have at least one step"))
-			}
+			errs = append(errs, validateRepeat(s.Repeat, path)...)
 		}
 	})
 
@@ -132,6 +169,30 @@ func validateDomain(rb *schema.Runbook, baseDir string) []*ValidationError {
 		}
 	})
 
+	// D15d: retry policy validation
+	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
+		if s.Retry != nil {
+			errs = append(errs, validateRetry(s, path)...)
+		}
+	})
+
+	// D15e: timeouts must be valid, positive durations
+	if d := rb.Meta.Defaults; d != nil && d.Timeout != "" {
+		errs = append(errs, validateTimeout(d.Timeout, "meta.defaults.timeout")...)
+	}
+	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
+		if s.Timeout != "" {
+			errs = append(errs, validateTimeout(s.Timeout, path+".timeout")...)
+		}
+	})
+
+	// D15f: a keyed for_each must have outputs to key
+	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
+		if s.ForEach != nil && s.ForEach.Key != "" && !declaresOutputs(s, baseDir) {
+			errs = append(errs, errorf("domain", path+".for_each.key", "for_each.key requires a step that declares outputs (contract.outputs)"))
+		}
+	})
+
 	// D16: tool step — validate tool is in the allow-list
 	if len(rb.Tools) > 0 {
 		toolSet := make(map[string]struct{}, len(rb.Tools))
@@ -148,9 +209,12 @@ func validateDomain(rb *schema.Runbook, baseDir string) []*ValidationError {
 	}
 
 	// D17: extension step must have inline contract
+	// D17b: the contract must declare parameters and its binary should resolve
 	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
 		if s.Type == schema.StepExtension && s.Contract == nil {
 			errs = append(errs, errorf("domain", path, "extension step must declare an inline contract"))
+		} else if s.Type == schema.StepExtension {
+			errs = append(errs, validateExtensionContract(s.Contract, baseDir, path+".contract")...)
 		}
 	})
 
@@ -197,6 +261,102 @@ func validateDomain(rb *schema.Runbook, baseDir string) []*ValidationError {
 			errs = append(errs, warningf("domain", fmt.Sprintf("meta.secrets[%d]", i), "secret env var %q is not set", secret.Env))
 		}
 	}
+
+	// D23: step env — tool steps only, names must be valid variable names
+	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
+		if len(s.Env) > 0 {
+			errs = append(errs, validateStepEnv(s, path)...)
+		}
+	})
+
+	// D24: dead steps — warn on steps no path from the start of their block reaches
+	errs = append(errs, validateReachability(rb)...)
+
+	// D25: tool result cache — tool steps only, with a key and a positive ttl
+	walkSteps(rb.Steps, "steps", func(s schema.Step, path string) {
+		if s.Cache != nil {
+			errs = append(errs, validateCache(s, path)...)
+		}
+	})
+	return errs
+}
+
+// envNameRe matches a portable environment variable name.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateStepEnv checks a step's env map.
+func validateStepEnv(s schema.Step, path string) []*ValidationError {
+	if s.Type != schema.StepTool {
+		return []*ValidationError{errorf("domain", path+".env", "env is only allowed on tool steps")}
+	}
+	var errs []*ValidationError
+	for _, name := range slices.Sorted(maps.Keys(s.Env)) {
+		if !envNameRe.MatchString(name) {
+			errs = append(errs, errorf("domain", path+".env", "invalid environment variable name %q", name))
+		}
+	}
+	return errs
+}
+
+// validateCache checks a step's tool result cache.
+func validateCache(s schema.Step, path string) []*ValidationError {
+	if s.Type != schema.StepTool {
+		return []*ValidationError{errorf("domain", path+".cache", "cache is only allowed on tool steps")}
+	}
+	var errs []*ValidationError
+	if strings.TrimSpace(s.Cache.Key) == "" {
+		errs = append(errs, errorf("domain", path+".cache.key", "cache.key is required"))
+	}
+	if d, err := time.ParseDuration(s.Cache.TTL); err != nil || d <= 0 {
+		errs = append(errs, errorf("domain", path+".cache.ttl", "invalid cache.ttl %q: must be a positive duration like 5m", s.Cache.TTL))
+	}
+	return errs
+}
+
+// validateRetry checks a step's retry policy.
+func validateRetry(s schema.Step, path string) []*ValidationError {
+	var errs []*ValidationError
+	if s.Type == schema.StepEnd || s.Type == schema.StepManual {
+		errs = append(errs, errorf("domain", path+".retry", "retry is not allowed on %s steps", s.Type))
+	}
+	if s.Retry.Max <= 0 {
+		errs = append(errs, errorf("domain", path+".retry.max", "retry.max must be > 0"))
+	}
+	if s.Retry.Delay != "" {
+		if _, err := time.ParseDuration(s.Retry.Delay); err != nil {
+			errs = append(errs, errorf("domain", path+".retry.delay", "invalid retry.delay %q: %v", s.Retry.Delay, err))
+		}
+	}
+	switch s.Retry.Backoff {
+	case "", schema.BackoffFixed, schema.BackoffLinear, schema.BackoffExponential:
+	default:
+		errs = append(errs, errorf("domain", path+".retry.backoff", "invalid retry.backoff %q: must be fixed, linear, or exponential", s.Retry.Backoff))
+	}
+	return errs
+}
+
+// validateExtensionContract checks an extension step's inline contract: it
+// must name a plugin binary and declare at least one input or output. A
+// binary that cannot be found is only a warning, since it may be installed
+// on the machine that runs the runbook.
+func validateExtensionContract(c *contract.Contract, baseDir, path string) []*ValidationError {
+	var errs []*ValidationError
+	if len(c.Inputs) == 0 && len(c.Outputs) == 0 {
+		errs = append(errs, errorf("domain", path, "extension contract must declare at least one input or output"))
+	}
+	if c.Binary == "" {
+		return append(errs, errorf("domain", path+".binary", "extension contract must declare the plugin binary"))
+	}
+
+	// Resolve the binary the way the engine does: paths relative to the
+	// runbook, bare names through PATH.
+	binary := c.Binary
+	if strings.ContainsAny(binary, `/\`) && !filepath.IsAbs(binary) && baseDir != "" {
+		binary = filepath.Join(baseDir, binary)
+	}
+	if _, err := exec.LookPath(binary); err != nil {
+		errs = append(errs, warningf("domain", path+".binary", "extension binary %q not found on %s/%s", c.Binary, runtime.GOOS, runtime.GOARCH))
+	}
 	return errs
 }
 
@@ -255,6 +415,9 @@ func validateStepType(s schema.Step, path string) []*ValidationError {
 
 func validateStepFields(s schema.Step, path string) []*ValidationError {
 	var errs []*ValidationError
+	if s.Choices != nil && s.Type != schema.StepManual {
+		errs = append(errs, errorf("domain", path+".choices", "choices is only allowed on manual steps"))
+	}
 	switch s.Type {
 	case schema.StepTool:
 		if s.Tool == "" {
@@ -264,6 +427,9 @@ func validateStepFields(s schema.Step, path string) []*ValidationError {
 		if s.Instructions == "" {
 			errs = append(errs, errorf("domain", path, "manual step requires 'instructions' field"))
 		}
+		if s.Choices != nil {
+			errs = append(errs, validateChoices(s.Choices, path+".choices")...)
+		}
 	case schema.StepAssert:
 		// validated separately in D14
 	case schema.StepBranch:
@@ -284,6 +450,30 @@ func validateStepFields(s schema.Step, path string) []*ValidationError {
 	return errs
 }
 
+// validateChoices checks a manual step's choices: the variable must be a
+// plain identifier templates can reference, and there must be at least two
+// options with distinct, non-empty values.
+func validateChoices(c *schema.ChoiceConfig, path string) []*ValidationError {
+	var errs []*ValidationError
+	if !envNameRe.MatchString(c.Variable) {
+		errs = append(errs, errorf("domain", path+".variable", "choices.variable %q is not a valid identifier", c.Variable))
+	}
+	if len(c.Options) < 2 {
+		errs = append(errs, errorf("domain", path+".options", "choices requires at least two options"))
+	}
+	seen := make(map[string]bool)
+	for i, opt := range c.Options {
+		optPath := fmt.Sprintf("%s.options[%d]", path, i)
+		if opt.Value == "" {
+			errs = append(errs, errorf("domain", optPath, "choice option requires 'value'"))
+		} else if seen[opt.Value] {
+			errs = append(errs, errorf("domain", optPath, "duplicate choice value %q", opt.Value))
+		}
+		seen[opt.Value] = true
+	}
+	return errs
+}
+
 // ---------------------------------------------------------------------------
 // End-step reachability
 // ---------------------------------------------------------------------------
@@ -344,31 +534,167 @@ func stepsReachEnd(steps []schema.Step) bool {
 	return false
 }
 
+// ---------------------------------------------------------------------------
+// Step reachability (dead steps)
+// ---------------------------------------------------------------------------
+
+// validateReachability warns on steps that can never run: every step before
+// them ends the run or jumps past them. Each block (top-level steps, branch
+// arms, repeat bodies) is checked on its own, since next and on_failure
+// targets are scope-local.
+func validateReachability(rb *schema.Runbook) []*ValidationError {
+	return unreachableSteps(rb.Steps, "steps")
+}
+
+func unreachableSteps(steps []schema.Step, basePath string) []*ValidationError {
+	var errs []*ValidationError
+	index := make(map[string]int, len(steps))
+	for i, s := range steps {
+		if s.ID != "" {
+			index[s.ID] = i
+		}
+	}
+
+	// Edges: fall-through to the next step, next jumps and on_failure routes.
+	// Unknown targets are reported by D6/D6b and ignored here.
+	edges := make([][]int, len(steps))
+	for i, s := range steps {
+		targets, _ := schema.ParseNextTargets(s.Next)
+		alwaysJumps := false
+		for _, t := range targets {
+			if j, ok := index[t.Step]; ok {
+				edges[i] = append(edges[i], j)
+			}
+			if t.If == "" && t.Max == 0 {
+				alwaysJumps = true
+			}
+		}
+		if j, ok := index[s.OnFailure]; ok {
+			edges[i] = append(edges[i], j)
+		}
+		// A step falls through unless it always stops the block: an end
+		// step, a branch whose arms all end, or an unconditional, unbounded next.
+		stops := s.Type == schema.StepEnd ||
+			(s.Type == schema.StepBranch && stepsReachEnd([]schema.Step{s})) ||
+			alwaysJumps
+		if (!stops || s.When != "") && i+1 < len(steps) {
+			edges[i] = append(edges[i], i+1)
+		}
+	}
+
+	reached := make([]bool, len(steps))
+	queue := []int{0}
+	if len(steps) > 0 {
+		reached[0] = true
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, j := range edges[i] {
+			if !reached[j] {
+				reached[j] = true
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	for i, s := range steps {
+		path := fmt.Sprintf("%s[%d]", basePath, i)
+		if !reached[i] {
+			name := s.ID
+			if name == "" {
+				name = string(s.Type)
+			}
+			errs = append(errs, warningf("domain", path, "step %q is unreachable: no path from the start of its block leads to it", name))
+			continue
+		}
+		for j, br := range s.Branches {
+			errs = append(errs, unreachableSteps(br.Steps, fmt.Sprintf("%s.branches[%d].steps", path, j))...)
+		}
+		if s.Repeat != nil {
+			errs = append(errs, unreachableSteps(s.Repeat.Steps, path+".repeat.steps")...)
+		}
+	}
+	return errs
+}
+
 // ---------------------------------------------------------------------------
 // next target scoping
 // ---------------------------------------------------------------------------
 
 func validateNextTarget(s schema.Step, scopeSteps []schema.Step, path string) []*ValidationError {
-	target, _, _, err := schema.ParseNext(s.Next)
+	targets, err := schema.ParseNextTargets(s.Next)
 	if err != nil {
 		return []*ValidationError{errorf("domain", path+".next", "%s", err.Error())}
 	}
-	if target == "" {
-		return nil
-	}
 
-	// Target must exist in the same scope
-	found := false
-	for _, ss := range scopeSteps {
-		if ss.ID == target {
-			found = true
-			break
+	var errs []*ValidationError
+	for i, t := range targets {
+		tPath := nextPath(s, path, i)
+		// Target must exist in the same scope
+		found := false
+		for _, ss := range scopeSteps {
+			if ss.ID == t.Step {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, errorf("domain", tPath, "target %q not found in current scope (next targets must be scope-local)", t.Step))
+		}
+		if t.If != "" {
+			if err := eval.Check(t.If); err != nil {
+				errs = append(errs, errorf("domain", tPath+".if", "invalid next condition: %v", err))
+			}
+		}
+		if t.If == "" && i < len(targets)-1 {
+			errs = append(errs, warningf("domain", tPath, "next entries after the default (no 'if') are never used"))
 		}
 	}
-	if !found {
-		return []*ValidationError{errorf("domain", path+".next", "target %q not found in current scope (next targets must be scope-local)", target)}
+	return errs
+}
+
+// nextPath is the error path of the i'th next target: "<path>.next" for
+// the single-target forms and "<path>.next[i]" for the conditional list.
+func nextPath(s schema.Step, path string, i int) string {
+	if _, ok := s.Next.([]any); ok {
+		return fmt.Sprintf("%s.next[%d]", path, i)
 	}
-	return nil
+	return path + ".next"
+}
+
+// validateOnFailureTargets checks that each on_failure names another step in
+// the same block (the engine only jumps within the block that failed), and
+// recurses into branch and repeat bodies with their own scope.
+func validateOnFailureTargets(steps []schema.Step, basePath string) []*ValidationError {
+	var errs []*ValidationError
+	for i, s := range steps {
+		path := fmt.Sprintf("%s[%d]", basePath, i)
+		if s.OnFailure != "" {
+			found := false
+			for _, ss := range steps {
+				if ss.ID == s.OnFailure {
+					found = true
+					break
+				}
+			}
+			switch {
+			case s.Type == schema.StepEnd:
+				errs = append(errs, errorf("domain", path+".on_failure", "on_failure is not allowed on end steps"))
+			case s.OnFailure == s.ID:
+				errs = append(errs, errorf("domain", path+".on_failure", "step %q cannot route its own failure to itself", s.ID))
+			case !found:
+				errs = append(errs, errorf("domain", path+".on_failure", "target %q not found in current scope (on_failure targets must be scope-local)", s.OnFailure))
+			}
+		}
+		for j, br := range s.Branches {
+			errs = append(errs, validateOnFailureTargets(br.Steps, fmt.Sprintf("%s.branches[%d].steps", path, j))...)
+		}
+		if s.Repeat != nil {
+			errs = append(errs, validateOnFailureTargets(s.Repeat.Steps, path+".repeat.steps")...)
+		}
+	}
+	return errs
 }
 
 // ---------------------------------------------------------------------------
@@ -376,30 +702,30 @@ func validateNextTarget(s schema.Step, scopeSteps []schema.Step, path string) []
 // ---------------------------------------------------------------------------
 
 func validateNextBounded(s schema.Step, scopeSteps []schema.Step, path string) []*ValidationError {
-	target, max, _, err := schema.ParseNext(s.Next)
+	targets, err := schema.ParseNextTargets(s.Next)
 	if err != nil {
 		return nil // already reported
 	}
-	if target == "" {
-		return nil
-	}
 
-	// Determine if backward by checking if target appears before this step
-	isBackward := false
-	for _, ss := range scopeSteps {
-		if ss.ID == target {
-			isBackward = true
-			break
-		}
-		if ss.ID == s.ID {
-			break // reached self first — target is forward
+	var errs []*ValidationError
+	for i, t := range targets {
+		// Determine if backward by checking if target appears before this step
+		isBackward := false
+		for _, ss := range scopeSteps {
+			if ss.ID == t.Step {
+				isBackward = true
+				break
+			}
+			if ss.ID == s.ID {
+				break // reached self first — target is forward
+			}
 		}
-	}
 
-	if isBackward && max <= 0 {
-		return []*ValidationError{errorf("domain", path+".next", "backward jump to %q requires a 'max' bound to guarantee termination", target)}
+		if isBackward && t.Max <= 0 {
+			errs = append(errs, errorf("domain", nextPath(s, path, i), "backward jump to %q requires a 'max' bound to guarantee termination", t.Step))
+		}
 	}
-	return nil
+	return errs
 }
 
 // ---------------------------------------------------------------------------
@@ -425,7 +751,7 @@ func validateVariableResolution(rb *schema.Runbook, baseDir string) []*Validatio
 	toolOutputs := loadToolOutputs(rb, baseDir)
 
 	// Walk steps in order, adding outputs
-	errs = append(errs, walkVariableResolution(rb.Steps, "steps", available, toolOutputs)...)
+	errs = append(errs, walkVariableResolution(rb.Steps, "steps", available, toolOutputs, nil)...)
 
 	return errs
 }
@@ -460,21 +786,33 @@ func loadToolOutputs(rb *schema.Runbook, baseDir string) map[string][]string {
 	return outputs
 }
 
-func walkVariableResolution(steps []schema.Step, basePath string, available map[string]bool, toolOutputs map[string][]string) []*ValidationError {
+// walkVariableResolution checks that every template reference resolves to a
+// variable available at that point. When flow is non-nil it also records
+// which step produces and consumes each variable.
+func walkVariableResolution(steps []schema.Step, basePath string, available map[string]bool, toolOutputs map[string][]string, flow *variableFlow) []*ValidationError {
 	var errs []*ValidationError
 
 	for i, s := range steps {
 		path := fmt.Sprintf("%s[%d]", basePath, i)
+		label := path
+		if s.ID != "" {
+			label = s.ID
+		}
+		produce := func(name string) {
+			available[name] = true
+			flow.produce(name, label)
+		}
 
 		// ForEach scoping — the `as` variable is available within this step's own templates
 		if s.ForEach != nil && s.ForEach.As != "" {
-			available[s.ForEach.As] = true
+			produce(s.ForEach.As)
 		}
 
 		// Check all template references in this step
 		refs := collectTemplateRefs(s)
 		for _, ref := range refs {
 			rootVar := strings.Split(ref, ".")[0]
+			flow.consume(rootVar, label)
 			if !available[rootVar] {
 				errs = append(errs, errorf("domain", path, "variable reference %q does not resolve to a declared input, constant, or prior step output", ref))
 			}
@@ -482,12 +820,15 @@ func walkVariableResolution(steps []schema.Step, basePath string, available map[
 
 		// After this step, its outputs become available
 		if s.ID != "" {
-			available[s.ID] = true
+			produce(s.ID)
+		}
+		if s.Choices != nil && s.Choices.Variable != "" {
+			produce(s.Choices.Variable)
 		}
 		// Step contract outputs (inline)
 		if s.Contract != nil {
 			for name := range s.Contract.Outputs {
-				available[name] = true
+				produce(name)
 			}
 		}
 		// Tool contract outputs (from loaded tool definitions)
@@ -495,14 +836,14 @@ func walkVariableResolution(steps []schema.Step, basePath string, available map[
 			// Tool-level outputs
 			if names, ok := toolOutputs[s.Tool]; ok {
 				for _, name := range names {
-					available[name] = true
+					produce(name)
 				}
 			}
 			// Action-specific outputs
 			if s.Action != "" {
 				if names, ok := toolOutputs[s.Tool+":"+s.Action]; ok {
 					for _, name := range names {
-						available[name] = true
+						produce(name)
 					}
 				}
 			}
@@ -514,7 +855,7 @@ func walkVariableResolution(steps []schema.Step, basePath string, available map[
 				brPath := fmt.Sprintf("%s.branches[%d].steps", path, j)
 				// Fork the available set for each branch
 				brAvail := copySet(available)
-				errs = append(errs, walkVariableResolution(br.Steps, brPath, brAvail, toolOutputs)...)
+				errs = append(errs, walkVariableResolution(br.Steps, brPath, brAvail, toolOutputs, flow)...)
 			}
 		}
 	}
@@ -526,11 +867,20 @@ func collectTemplateRefs(s schema.Step) []string {
 	// Collect from all string fields that may contain templates
 	refs = append(refs, extractRefs(s.When)...)
 	refs = append(refs, extractRefs(s.Instructions)...)
+	if s.Choices != nil {
+		refs = append(refs, extractRefs(s.Choices.Prompt)...)
+	}
 	for _, v := range s.Inputs {
 		if str, ok := v.(string); ok {
 			refs = append(refs, extractRefs(str)...)
 		}
 	}
+	for _, v := range s.Env {
+		refs = append(refs, extractRefs(v)...)
+	}
+	if s.Cache != nil {
+		refs = append(refs, extractRefs(s.Cache.Key)...)
+	}
 	if s.Outcome != nil {
 		refs = append(refs, extractRefs(s.Outcome.Code)...)
 		for _, v := range s.Outcome.Meta {
@@ -550,12 +900,23 @@ func collectTemplateRefs(s schema.Step) []string {
 	if s.ForEach != nil {
 		refs = append(refs, extractRefs(s.ForEach.Over)...)
 	}
-	// next target — can reference templates in max
+	// next target — can reference templates in max (and if, in the conditional form)
 	if m, ok := s.Next.(map[string]any); ok {
 		if ms, ok := m["max"].(string); ok {
 			refs = append(refs, extractRefs(ms)...)
 		}
 	}
+	if list, ok := s.Next.([]any); ok {
+		for _, entry := range list {
+			if m, ok := entry.(map[string]any); ok {
+				for _, field := range []string{"if", "max"} {
+					if v, ok := m[field].(string); ok {
+						refs = append(refs, extractRefs(v)...)
+					}
+				}
+			}
+		}
+	}
 	return refs
 }
 
@@ -730,6 +1091,11 @@ func validateBranchConditions(s schema.Step, path string) []*ValidationError {
 	if !hasDefault {
 		errs = append(errs, warningf("domain", path, "branch has no 'default' condition — not all inputs may be handled"))
 	}
+	// An unlabeled condition plus an unlabeled default reads better as if/else
+	if len(s.Branches) == 2 && s.Branches[0].Condition != "default" && s.Branches[1].Condition == "default" &&
+		s.Branches[0].Label == "" && s.Branches[1].Label == "" {
+		errs = append(errs, hintf("domain", path+".branches", "a condition and a default can be written as branch: {if, steps, else} (gert migrate rewrites it)"))
+	}
 	return errs
 }
 
@@ -737,6 +1103,9 @@ func validateBranchConditions(s schema.Step, path string) []*ValidationError {
 // ForEach
 // ---------------------------------------------------------------------------
 
+// maxForEachParallel is the for_each.max_parallel above which D15 warns.
+const maxForEachParallel = 50
+
 func validateForEach(s schema.Step, path string) []*ValidationError {
 	var errs []*ValidationError
 	if s.ForEach.As == "" {
@@ -745,6 +1114,60 @@ func validateForEach(s schema.Step, path string) []*ValidationError {
 	if s.ForEach.Over == "" {
 		errs = append(errs, errorf("domain", path+".for_each.over", "for_each requires 'over' field"))
 	}
+	if s.ForEach.MaxParallel < 0 {
+		errs = append(errs, errorf("domain", path+".for_each.max_parallel", "for_each.max_parallel must be >= 0"))
+	} else if s.ForEach.MaxParallel > maxForEachParallel {
+		errs = append(errs, warningf("domain", path+".for_each.max_parallel", "for_each.max_parallel %d exceeds %d; very wide fan-out can exhaust local resources", s.ForEach.MaxParallel, maxForEachParallel))
+	}
+	if s.ForEach.MaxParallel > 0 && !s.ForEach.Parallel {
+		errs = append(errs, warningf("domain", path+".for_each.max_parallel", "for_each.max_parallel has no effect without parallel: true"))
+	}
+	if s.ForEach.Key != "" && !strings.Contains(s.ForEach.Key, "{{") {
+		errs = append(errs, errorf("domain", path+".for_each.key", "for_each.key must be a template evaluated per item (e.g. \"{{ .%s.id }}\"), got %q", s.ForEach.As, s.ForEach.Key))
+	}
+	return errs
+}
+
+// declaresOutputs reports whether a step produces outputs that a keyed
+// for_each can collect. Tool files that cannot be loaded are reported by
+// other rules and count as declaring outputs.
+func declaresOutputs(s schema.Step, baseDir string) bool {
+	if s.Contract != nil && len(s.Contract.Outputs) > 0 {
+		return true
+	}
+	switch s.Type {
+	case schema.StepAssert:
+		return true // always outputs passed
+	case schema.StepManual:
+		return len(s.RequiredEvidence) > 0 || s.Choices != nil
+	case schema.StepTool:
+		toolPath := ResolveToolPath(s.Tool, baseDir, "")
+		if toolPath == "" {
+			return true
+		}
+		td, err := schema.LoadToolFile(toolPath)
+		return err != nil || len(td.Contract.Outputs) > 0
+	default:
+		return false
+	}
+}
+
+func validateRepeat(rep *schema.RepeatBlock, path string) []*ValidationError {
+	var errs []*ValidationError
+	if rep.Max <= 0 {
+		errs = append(errs, errorf("domain", path+".repeat.max", "repeat.max must be > 0"))
+	}
+	if len(rep.Steps) == 0 {
+		errs = append(errs, errorf("domain", path+".repeat.steps", "repeat block must have at least one step"))
+	}
+	if rep.Until != "" {
+		if err := eval.Check(rep.Until); err != nil {
+			errs = append(errs, errorf("domain", path+".repeat.until", "invalid until expression: %v", err))
+		} else if !strings.Contains(rep.Until, "{{") {
+			// A literal is always truthy, so the block would stop after one pass
+			errs = append(errs, warningf("domain", path+".repeat.until", "until %q is not an expression; the block stops after the first iteration", rep.Until))
+		}
+	}
 	return errs
 }
 
@@ -791,6 +1214,39 @@ func validateContractTightening(rb *schema.Runbook, baseDir string) []*Validatio
 	return errs
 }
 
+// validateTimeout checks a timeout: value (D15e). The engine treats an
+// unparsable or non-positive timeout as no timeout, so reject both here.
+func validateTimeout(v, path string) []*ValidationError {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return []*ValidationError{errorf("domain", path, "invalid timeout %q: %v", v, err)}
+	}
+	if d <= 0 {
+		return []*ValidationError{errorf("domain", path, "timeout %q must be positive", v)}
+	}
+	return nil
+}
+
+// CheckToolFiles resolves and loads the definition of every tool listed
+// under tools:, reporting an error for each one that is missing or fails to
+// load. Domain validation skips such tools silently (D18, D21) so runbooks
+// validate without their tool files; this check is opt-in.
+func CheckToolFiles(rb *schema.Runbook, baseDir string) []*ValidationError {
+	var errs []*ValidationError
+	for i, name := range rb.Tools {
+		path := fmt.Sprintf("tools[%d]", i)
+		toolPath := ResolveToolPath(name, baseDir, "")
+		if toolPath == "" {
+			errs = append(errs, errorf("domain", path, "tool %q: definition not found (expected tools/%s.tool.yaml)", name, name))
+			continue
+		}
+		if _, err := schema.LoadToolFile(toolPath); err != nil {
+			errs = append(errs, errorf("domain", path, "tool %q: %s", name, err))
+		}
+	}
+	return errs
+}
+
 // ---------------------------------------------------------------------------
 // inputs_from
 // ---------------------------------------------------------------------------
@@ -0,0 +1,178 @@
+package validate
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/contract"
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
+)
+
+// Inspection summarizes what a runbook declares and how variables flow
+// between its steps, without executing it.
+type Inspection struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Inputs      []InputInfo    `json:"inputs"`
+	Constants   []ConstantInfo `json:"constants"`
+	Tools       []ToolInfo     `json:"tools"`
+	Variables   []VariableInfo `json:"variables"`
+}
+
+// InputInfo is a declared meta.inputs entry.
+type InputInfo struct {
+	Name string `json:"name"`
+	contract.ParamDef
+}
+
+// ConstantInfo is a declared meta.constants entry.
+type ConstantInfo struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+// ToolInfo describes a tool listed under tools:. Error is set when the tool
+// definition could not be loaded.
+type ToolInfo struct {
+	Name        string           `json:"name"`
+	Path        string           `json:"path,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Effects     []string         `json:"effects,omitempty"`
+	Actions     []ToolActionInfo `json:"actions,omitempty"`
+	Error       string           `json:"error,omitempty"`
+}
+
+// ToolActionInfo is one action of a tool with its effective effects.
+type ToolActionInfo struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Effects     []string `json:"effects,omitempty"`
+}
+
+// VariableInfo records where a variable comes from and which steps read it.
+// Source is input, constant, step, or unresolved.
+type VariableInfo struct {
+	Name      string   `json:"name"`
+	Source    string   `json:"source"`
+	Producers []string `json:"producers,omitempty"`
+	Consumers []string `json:"consumers,omitempty"`
+}
+
+// variableFlow collects producer and consumer steps per variable during
+// walkVariableResolution. A nil *variableFlow records nothing.
+type variableFlow struct {
+	producers map[string][]string
+	consumers map[string][]string
+}
+
+func (f *variableFlow) produce(name, step string) {
+	if f != nil && !slices.Contains(f.producers[name], step) {
+		f.producers[name] = append(f.producers[name], step)
+	}
+}
+
+func (f *variableFlow) consume(name, step string) {
+	if f != nil && !slices.Contains(f.consumers[name], step) {
+		f.consumers[name] = append(f.consumers[name], step)
+	}
+}
+
+// Inspect reports the runbook's inputs, constants, tools and variable flow.
+// Tools are resolved relative to baseDir, as in validation.
+func Inspect(rb *schema.Runbook, baseDir string) *Inspection {
+	ins := &Inspection{
+		Name:        rb.Meta.Name,
+		Description: rb.Meta.Description,
+		Inputs:      []InputInfo{},
+		Constants:   []ConstantInfo{},
+		Tools:       []ToolInfo{},
+		Variables:   []VariableInfo{},
+	}
+
+	for _, name := range sortedKeys(rb.Meta.Inputs) {
+		ins.Inputs = append(ins.Inputs, InputInfo{Name: name, ParamDef: rb.Meta.Inputs[name]})
+	}
+	for _, name := range sortedKeys(rb.Meta.Constants) {
+		ins.Constants = append(ins.Constants, ConstantInfo{Name: name, Value: rb.Meta.Constants[name]})
+	}
+	for _, name := range rb.Tools {
+		ins.Tools = append(ins.Tools, inspectTool(name, baseDir))
+	}
+
+	// Replay the variable resolution walk, recording the flow
+	flow := &variableFlow{producers: map[string][]string{}, consumers: map[string][]string{}}
+	available := make(map[string]bool)
+	for name := range rb.Meta.Inputs {
+		available[name] = true
+	}
+	for name := range rb.Meta.Constants {
+		available[name] = true
+	}
+	walkVariableResolution(rb.Steps, "steps", available, loadToolOutputs(rb, baseDir), flow)
+
+	names := make(map[string]bool)
+	for name := range available {
+		names[name] = true
+	}
+	for name := range flow.producers {
+		names[name] = true
+	}
+	for name := range flow.consumers {
+		names[name] = true
+	}
+	for _, name := range sortedKeys(names) {
+		v := VariableInfo{Name: name, Producers: flow.producers[name], Consumers: flow.consumers[name]}
+		// Every step ID is addressable, but only list the ones something reads
+		if len(v.Consumers) == 0 && slices.Equal(v.Producers, []string{name}) {
+			continue
+		}
+		if _, ok := rb.Meta.Inputs[name]; ok {
+			v.Source = "input"
+		} else if _, ok := rb.Meta.Constants[name]; ok {
+			v.Source = "constant"
+		} else if len(v.Producers) > 0 {
+			v.Source = "step"
+		} else {
+			v.Source = "unresolved"
+		}
+		ins.Variables = append(ins.Variables, v)
+	}
+	return ins
+}
+
+func inspectTool(name, baseDir string) ToolInfo {
+	info := ToolInfo{Name: name, Path: ResolveToolPath(name, baseDir, "")}
+	if info.Path == "" {
+		info.Error = "tool definition not found"
+		return info
+	}
+	td, err := schema.LoadToolFile(info.Path)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	info.Description = td.Meta.Description
+	info.Effects = td.Contract.Effects
+	for _, actionName := range sortedKeys(td.Actions) {
+		action := td.Actions[actionName]
+		effects := td.Contract.Effects
+		if action.Contract != nil && len(action.Contract.Effects) > 0 {
+			effects = action.Contract.Effects
+		}
+		info.Actions = append(info.Actions, ToolActionInfo{
+			Name:        actionName,
+			Description: action.Description,
+			Effects:     effects,
+		})
+	}
+	return info
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,91 @@
+package validate
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestInspect_VariableFlow(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "tools"), 0o755)
+	os.WriteFile(filepath.Join(dir, "tools", "probe.tool.yaml"), []byte(`apiVersion: tool/v0
+meta:
+  name: probe
+  description: Probe an endpoint
+contract:
+  effects: [network]
+  outputs:
+    status_code: { type: string }
+actions:
+  get:
+    argv: ["probe", "{{ .url }}"]
+  reset:
+    argv: ["probe", "--reset", "{{ .url }}"]
+    contract:
+      effects: [network, state]
+`), 0o644)
+	rbPath := filepath.Join(dir, "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: inspect-test
+  inputs:
+    hostname: { type: string, required: true }
+    port: { type: string, default: "443" }
+  constants:
+    path: /healthz
+tools: [probe]
+steps:
+  - id: check
+    type: tool
+    tool: probe
+    action: get
+    inputs:
+      url: "https://{{ .hostname }}:{{ .port }}{{ .path }}"
+  - id: verify
+    type: assert
+    assert:
+      - type: equals
+        value: "{{ .status_code }}"
+        expected: "200"
+  - id: done
+    type: end
+    outcome:
+      category: no_action
+      code: "ok_{{ .hostname }}"
+`), 0o644)
+
+	rb, errs := ValidateFile(rbPath)
+	if len(filterErrors(errs)) > 0 {
+		t.Fatalf("unexpected validation errors: %v", filterErrors(errs))
+	}
+	ins := Inspect(rb, dir)
+
+	if len(ins.Inputs) != 2 || ins.Inputs[0].Name != "hostname" || ins.Inputs[1].Default != "443" {
+		t.Errorf("inputs = %+v", ins.Inputs)
+	}
+	if len(ins.Constants) != 1 || ins.Constants[0].Value != "/healthz" {
+		t.Errorf("constants = %+v", ins.Constants)
+	}
+	if len(ins.Tools) != 1 || len(ins.Tools[0].Actions) != 2 {
+		t.Fatalf("tools = %+v", ins.Tools)
+	}
+	if reset := ins.Tools[0].Actions[1]; reset.Name != "reset" || !slices.Equal(reset.Effects, []string{"network", "state"}) {
+		t.Errorf("reset action = %+v", reset)
+	}
+
+	vars := map[string]VariableInfo{}
+	for _, v := range ins.Variables {
+		vars[v.Name] = v
+	}
+	if v := vars["hostname"]; v.Source != "input" || !slices.Equal(v.Consumers, []string{"check", "done"}) {
+		t.Errorf("hostname = %+v", v)
+	}
+	if v := vars["status_code"]; v.Source != "step" || !slices.Equal(v.Producers, []string{"check"}) || !slices.Equal(v.Consumers, []string{"verify"}) {
+		t.Errorf("status_code = %+v", v)
+	}
+	if v := vars["path"]; v.Source != "constant" {
+		t.Errorf("path = %+v", v)
+	}
+}
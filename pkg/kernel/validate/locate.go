@@ -0,0 +1,72 @@
+package validate
+
+import (
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlLineRe finds the line number in a YAML decoder error.
+var yamlLineRe = regexp.MustCompile(`line (\d+):`)
+
+// pathTokenRe splits a finding path such as "steps[2].assert[0].expected"
+// into keys and indexes.
+var pathTokenRe = regexp.MustCompile(`\[(\d+)\]|[^.\[\]]+`)
+
+// locate fills in the line and column of each finding from the YAML
+// source: structural errors from the decoder's message, the rest from the
+// node their path names, or its nearest ancestor that exists.
+func locate(data []byte, errs []*ValidationError) {
+	var doc yaml.Node
+	var root *yaml.Node
+	if yaml.Unmarshal(data, &doc) == nil && len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	for _, e := range errs {
+		if m := yamlLineRe.FindStringSubmatch(e.Message); e.Phase == "structural" && m != nil {
+			e.Line, _ = strconv.Atoi(m[1])
+			continue
+		}
+		if root == nil || e.Path == "" {
+			continue
+		}
+		if n := nodeAt(root, e.Path); n != nil {
+			e.Line, e.Column = n.Line, n.Column
+		}
+	}
+}
+
+// nodeAt returns the node a finding path names: the key of a mapping
+// entry or the element of a sequence. It stops at the deepest node that
+// exists, so a missing field points at its parent.
+func nodeAt(root *yaml.Node, path string) *yaml.Node {
+	var found *yaml.Node
+	n := root
+	for _, tok := range pathTokenRe.FindAllStringSubmatch(path, -1) {
+		switch {
+		case tok[1] != "" && n.Kind == yaml.SequenceNode:
+			i, _ := strconv.Atoi(tok[1])
+			if i >= len(n.Content) {
+				return found
+			}
+			n = n.Content[i]
+			found = n
+		case tok[1] == "" && n.Kind == yaml.MappingNode:
+			next := -1
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == tok[0] {
+					next = i
+					break
+				}
+			}
+			if next < 0 {
+				return found
+			}
+			found, n = n.Content[next], n.Content[next+1]
+		default:
+			return found
+		}
+	}
+	return found
+}
@@ -60,6 +60,14 @@ func validateSemantic(rb *schema.Runbook) []*ValidationError {
 					errs = append(errs, errorf("semantic", path+".risk", "invalid risk level %q", rule.Risk))
 				}
 			}
+			for j, st := range rule.Steps {
+				// Only these step types carry a contract for governance to evaluate
+				switch st {
+				case schema.StepTool, schema.StepManual, schema.StepAssert, schema.StepExtension:
+				default:
+					errs = append(errs, errorf("semantic", gfmt("%s.steps[%d]", path, j), "invalid step type %q: must be tool, manual, assert, or extension", st))
+				}
+			}
 		}
 	}
 
@@ -3,9 +3,11 @@
 package validate
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/ormasoftchile/gert/pkg/kernel/schema"
 )
@@ -15,7 +17,9 @@ type ValidationError struct {
 	Phase    string `json:"phase"` // structural, semantic, domain
 	Path     string `json:"path"`  // JSON-path-like location
 	Message  string `json:"message"`
-	Severity string `json:"severity"` // error, warning
+	Severity string `json:"severity"`         // error, warning, hint
+	Line     int    `json:"line,omitempty"`   // 1-based source line, when known
+	Column   int    `json:"column,omitempty"` // 1-based source column, when known
 }
 
 func (e *ValidationError) Error() string {
@@ -43,32 +47,83 @@ func warningf(phase, path, msg string, args ...any) *ValidationError {
 	}
 }
 
+// hintf reports a style suggestion. Hints never fail validation, even
+// with --strict.
+func hintf(phase, path, msg string, args ...any) *ValidationError {
+	return &ValidationError{
+		Phase:    phase,
+		Path:     path,
+		Message:  fmt.Sprintf(msg, args...),
+		Severity: "hint",
+	}
+}
+
+// ValidationProfile records how long each validation phase took, in
+// milliseconds. A phase that did not run reports 0.
+type ValidationProfile struct {
+	ParseMs  int64 `json:"parse_ms"`  // structural: strict YAML decode
+	SchemaMs int64 `json:"schema_ms"` // semantic: JSON Schema validation
+	DomainMs int64 `json:"domain_ms"` // domain rules, including tool file loading
+}
+
 // ValidateFile runs the full 3-phase pipeline on a runbook file.
 func ValidateFile(path string) (*schema.Runbook, []*ValidationError) {
+	rb, errs, _ := ValidateFileProfiled(path)
+	return rb, errs
+}
+
+// ValidateFileProfiled is ValidateFile, also timing each phase.
+func ValidateFileProfiled(path string) (*schema.Runbook, []*ValidationError, *ValidationProfile) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, []*ValidationError{errorf("structural", "", "failed to load: open runbook: %s", err)}, &ValidationProfile{}
+	}
+	baseDir := ""
+	if path != "" {
+		baseDir = filepath.Dir(path)
+	}
+	return ValidateSource(data, baseDir)
+}
+
+// ValidateSource runs the full 3-phase pipeline on runbook YAML held in
+// memory, such as an unsaved editor buffer. baseDir is where tool files
+// are resolved from. Findings carry the line and column of the YAML node
+// their path names, when it can be found.
+func ValidateSource(data []byte, baseDir string) (*schema.Runbook, []*ValidationError, *ValidationProfile) {
+	rb, errs, profile := validateSource(data, baseDir)
+	locate(data, errs)
+	return rb, errs, profile
+}
+
+func validateSource(data []byte, baseDir string) (*schema.Runbook, []*ValidationError, *ValidationProfile) {
+	profile := &ValidationProfile{}
+
 	// Phase 1: Structural (strict YAML decode)
-	rb, err := schema.LoadFile(path)
+	start := time.Now()
+	rb, err := schema.Load(bytes.NewReader(data))
+	profile.ParseMs = time.Since(start).Milliseconds()
 	if err != nil {
-		return nil, []*ValidationError{errorf("structural", "", "failed to load: %s", err)}
+		return nil, []*ValidationError{errorf("structural", "", "failed to load: %s", err)}, profile
 	}
 
 	var errs []*ValidationError
 
 	// Phase 2: Semantic (JSON Schema validation)
+	start = time.Now()
 	errs = append(errs, validateSemantic(rb)...)
+	profile.SchemaMs = time.Since(start).Milliseconds()
 
 	// If we have structural/semantic errors, don't proceed to domain
 	if hasErrors(errs) {
-		return rb, errs
+		return rb, errs, profile
 	}
 
 	// Phase 3: Domain (hand-coded rules)
-	baseDir := ""
-	if path != "" {
-		baseDir = filepath.Dir(path)
-	}
+	start = time.Now()
 	errs = append(errs, validateDomain(rb, baseDir)...)
+	profile.DomainMs = time.Since(start).Milliseconds()
 
-	return rb, errs
+	return rb, errs, profile
 }
 
 // ValidateRunbook runs phases 2+3 on an already-loaded runbook.
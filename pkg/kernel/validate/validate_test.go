@@ -1,9 +1,15 @@
 package validate
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/contract"
+	"github.com/ormasoftchile/gert/pkg/kernel/schema"
 )
 
 func testdataPath(name string) string {
@@ -206,3 +212,499 @@ func filterWarnings(errs []*ValidationError) []*ValidationError {
 	}
 	return out
 }
+
+func TestValidateForEach_MaxParallel(t *testing.T) {
+	step := func(fe schema.ForEach) schema.Step {
+		fe.As, fe.Over = "item", "{{ .items }}"
+		return schema.Step{ID: "fan", Type: schema.StepAssert, ForEach: &fe}
+	}
+
+	if errs := validateForEach(step(schema.ForEach{Parallel: true, MaxParallel: 10}), "steps[0]"); len(errs) != 0 {
+		t.Errorf("expected no diagnostics, got %v", errs)
+	}
+	if warns := filterWarnings(validateForEach(step(schema.ForEach{Parallel: true, MaxParallel: 51}), "steps[0]")); !containsMessage(warns, "exceeds 50") {
+		t.Errorf("expected max_parallel > 50 warning, got %v", warns)
+	}
+	if warns := filterWarnings(validateForEach(step(schema.ForEach{MaxParallel: 4}), "steps[0]")); !containsMessage(warns, "without parallel") {
+		t.Errorf("expected no-effect warning, got %v", warns)
+	}
+	if errs := filterErrors(validateForEach(step(schema.ForEach{Parallel: true, MaxParallel: -1}), "steps[0]")); len(errs) != 1 {
+		t.Errorf("expected negative max_parallel error, got %v", errs)
+	}
+}
+
+func TestValidateForEach_Key(t *testing.T) {
+	fe := &schema.ForEach{As: "agent", Over: "{{ .agents }}", Key: "{{ .agent.id }}"}
+	step := schema.Step{ID: "ask", Type: schema.StepAssert, ForEach: fe, Assert: []schema.Assertion{{Type: "equals", Value: "a", Expected: "a"}}}
+	if errs := validateForEach(step, "steps[0]"); len(errs) != 0 {
+		t.Errorf("expected no diagnostics, got %v", errs)
+	}
+	fe.Key = "agent"
+	if errs := validateForEach(step, "steps[0]"); !containsMessage(errs, "must be a template") {
+		t.Errorf("expected constant key error, got %v", errs)
+	}
+
+	if !declaresOutputs(step, t.TempDir()) {
+		t.Error("assert steps always output passed")
+	}
+	step.Type = schema.StepManual
+	if declaresOutputs(step, t.TempDir()) {
+		t.Error("a manual step without required_evidence declares no outputs")
+	}
+	step.RequiredEvidence = []schema.EvidenceRequirement{{Name: "answer", Kind: "text"}}
+	if !declaresOutputs(step, t.TempDir()) {
+		t.Error("required_evidence should count as outputs")
+	}
+	step.Type = schema.StepExtension
+	step.Contract = &contract.Contract{Binary: "agent"}
+	if declaresOutputs(step, t.TempDir()) {
+		t.Error("an extension contract without outputs declares none")
+	}
+}
+
+func TestValidateExtensionContract(t *testing.T) {
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "plugin.sh")
+	if err := os.WriteFile(plugin, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	params := map[string]contract.ParamDef{"target": {Type: "string"}}
+
+	if errs := validateExtensionContract(&contract.Contract{Binary: "./plugin.sh", Inputs: params}, dir, "steps[0].contract"); len(errs) != 0 {
+		t.Errorf("expected no diagnostics for a resolvable binary, got %v", errs)
+	}
+
+	errs := validateExtensionContract(&contract.Contract{Binary: "gert-no-such-plugin", Outputs: params}, dir, "steps[0].contract")
+	if len(errs) != 1 || errs[0].Severity != "warning" || errs[0].Path != "steps[0].contract.binary" {
+		t.Errorf("expected one binary warning, got %v", errs)
+	}
+
+	errs = validateExtensionContract(&contract.Contract{Binary: "./plugin.sh"}, dir, "steps[0].contract")
+	if !containsMessage(errs, "at least one input or output") || errs[0].Severity == "warning" {
+		t.Errorf("expected empty contract error, got %v", errs)
+	}
+
+	errs = validateExtensionContract(&contract.Contract{Inputs: params}, dir, "steps[0].contract")
+	if !containsMessage(errs, "must declare the plugin binary") {
+		t.Errorf("expected missing binary error, got %v", errs)
+	}
+}
+
+func TestValidateBranchConditions_IfElseHint(t *testing.T) {
+	ifDefault := schema.Step{Type: schema.StepBranch, Branches: []schema.Branch{
+		{Condition: "{{ .degraded }}", Steps: []schema.Step{{Type: schema.StepEnd}}},
+		{Condition: "default", Steps: []schema.Step{{Type: schema.StepEnd}}},
+	}}
+	errs := validateBranchConditions(ifDefault, "steps[0]")
+	if len(errs) != 1 || errs[0].Severity != "hint" || !strings.Contains(errs[0].Message, "branch: {if, steps, else}") {
+		t.Errorf("expected an if/else hint, got %v", errs)
+	}
+
+	// The desugared shorthand carries labels, so it gets no hint
+	ifDefault.Branches[0].Label, ifDefault.Branches[1].Label = "if", "else"
+	if errs := validateBranchConditions(ifDefault, "steps[0]"); len(errs) != 0 {
+		t.Errorf("expected no diagnostics for labeled branches, got %v", errs)
+	}
+}
+
+func TestValidateCache(t *testing.T) {
+	step := schema.Step{ID: "q", Type: schema.StepTool, Tool: "db", Cache: &schema.CacheConfig{TTL: "5m", Key: "{{ .query }}"}}
+	if errs := validateCache(step, "steps[0]"); len(errs) != 0 {
+		t.Errorf("expected no diagnostics, got %v", errs)
+	}
+
+	step.Cache = &schema.CacheConfig{TTL: "-1m"}
+	errs := validateCache(step, "steps[0]")
+	if !containsMessage(errs, "cache.key is required") || !containsMessage(errs, "invalid cache.ttl") {
+		t.Errorf("expected key and ttl errors, got %v", errs)
+	}
+
+	step.Type = schema.StepManual
+	if errs := validateCache(step, "steps[0]"); !containsMessage(errs, "only allowed on tool steps") {
+		t.Errorf("expected tool-only error, got %v", errs)
+	}
+}
+
+func TestValidateTimeout(t *testing.T) {
+	if errs := validateTimeout("90s", "steps[0].timeout"); len(errs) != 0 {
+		t.Errorf("expected no diagnostics, got %v", errs)
+	}
+	if errs := validateTimeout("soon", "steps[0].timeout"); !containsMessage(errs, "invalid timeout") {
+		t.Errorf("expected invalid timeout error, got %v", errs)
+	}
+	for _, v := range []string{"0s", "-5m"} {
+		if errs := validateTimeout(v, "meta.defaults.timeout"); !containsMessage(errs, "must be positive") {
+			t.Errorf("timeout %q: expected positive error, got %v", v, errs)
+		}
+	}
+}
+
+func TestValidateRepeat(t *testing.T) {
+	body := []schema.Step{{ID: "poll", Type: schema.StepAssert}}
+
+	if errs := validateRepeat(&schema.RepeatBlock{Max: 3, Until: `{{ eq .status "ready" }}`, Steps: body}, "steps[0]"); len(errs) != 0 {
+		t.Errorf("expected no diagnostics, got %v", errs)
+	}
+	errs := filterErrors(validateRepeat(&schema.RepeatBlock{Until: "{{ .status", Steps: nil}, "steps[0]"))
+	for _, want := range []string{"repeat.max must be > 0", "at least one step", "invalid until expression"} {
+		if !containsMessage(errs, want) {
+			t.Errorf("expected error containing %q, got %v", want, errs)
+		}
+	}
+	if warns := filterWarnings(validateRepeat(&schema.RepeatBlock{Max: 3, Until: "ready", Steps: body}, "steps[0]")); !containsMessage(warns, "not an expression") {
+		t.Errorf("expected literal until warning, got %v", warns)
+	}
+}
+
+func TestValidateOnFailureTargets(t *testing.T) {
+	steps := []schema.Step{
+		{ID: "drain", Type: schema.StepTool, OnFailure: "escalate"},
+		{ID: "check", Type: schema.StepAssert, OnFailure: "check"},
+		{ID: "fork", Type: schema.StepBranch, Branches: []schema.Branch{{Steps: []schema.Step{
+			{ID: "inner", Type: schema.StepTool, OnFailure: "escalate"},
+		}}}},
+		{ID: "escalate", Type: schema.StepEnd, OnFailure: "drain"},
+	}
+
+	errs := validateOnFailureTargets(steps, "steps")
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	for _, want := range []string{"to itself", "on_failure targets must be scope-local", "not allowed on end steps"} {
+		if !containsMessage(errs, want) {
+			t.Errorf("expected error containing %q, got %v", want, errs)
+		}
+	}
+	if errs[1].Path != "steps[2].branches[0].steps[0].on_failure" {
+		t.Errorf("nested path = %s", errs[1].Path)
+	}
+}
+
+func TestValidateStepEnv(t *testing.T) {
+	ok := schema.Step{ID: "run", Type: schema.StepTool, Env: map[string]string{"AWS_REGION": "{{ .region }}", "_X1": "y"}}
+	if errs := validateStepEnv(ok, "steps[0]"); len(errs) != 0 {
+		t.Errorf("expected no diagnostics, got %v", errs)
+	}
+	bad := schema.Step{ID: "run", Type: schema.StepTool, Env: map[string]string{"1BAD": "x", "A-B": "y"}}
+	if errs := validateStepEnv(bad, "steps[0]"); len(errs) != 2 || !containsMessage(errs, `"1BAD"`) {
+		t.Errorf("expected two invalid name errors, got %v", errs)
+	}
+	manual := schema.Step{ID: "ask", Type: schema.StepManual, Env: map[string]string{"X": "y"}}
+	if errs := validateStepEnv(manual, "steps[0]"); !containsMessage(errs, "only allowed on tool steps") {
+		t.Errorf("expected tool-only error, got %v", errs)
+	}
+}
+
+func TestValidateChoices(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "choices"},
+		Steps: []schema.Step{
+			{ID: "pick", Type: schema.StepManual, Instructions: "Pick", Choices: &schema.ChoiceConfig{
+				Variable: "env",
+				Options:  []schema.ChoiceOption{{Value: "staging"}, {Value: "prod"}},
+			}},
+			{ID: "bad", Type: schema.StepManual, Instructions: "Pick", Choices: &schema.ChoiceConfig{
+				Variable: "target-env",
+				Options:  []schema.ChoiceOption{{Value: "a"}, {Value: "a"}},
+			}},
+			{ID: "deploy", Type: schema.StepManual, Instructions: "Deploy to {{ .env }}"},
+			{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}},
+		},
+	}
+	errs := validateDomain(rb, "")
+	if !containsMessage(errs, `"target-env" is not a valid identifier`) {
+		t.Errorf("expected invalid identifier error, got %v", errs)
+	}
+	if !containsMessage(errs, `duplicate choice value "a"`) {
+		t.Errorf("expected duplicate value error, got %v", errs)
+	}
+	for _, e := range errs {
+		if strings.HasPrefix(e.Path, "steps[0]") || strings.HasPrefix(e.Path, "steps[2]") {
+			t.Errorf("unexpected error: %v", e)
+		}
+	}
+}
+
+func TestValidateJSONPathAssertion(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "jp"},
+		Steps: []schema.Step{{
+			ID:   "check",
+			Type: schema.StepAssert,
+			Assert: []schema.Assertion{
+				{Type: "json_path", Value: "{}", Path: "$.items[0].name", Expected: "a"},
+				{Type: "json_path", Value: "{}", Expected: "a"},
+				{Type: "json_path", Value: "{}", Path: "items[0]", Expected: "a"},
+			},
+		}},
+	}
+	errs := validateDomain(rb, "")
+	if !containsMessage(errs, "json_path assertion requires 'path'") {
+		t.Errorf("expected missing path error, got %v", errs)
+	}
+	if !containsMessage(errs, "must start with $") {
+		t.Errorf("expected invalid path error, got %v", errs)
+	}
+	for _, e := range errs {
+		if strings.HasPrefix(e.Path, "steps[0].assert[0]") {
+			t.Errorf("unexpected error for valid assertion: %v", e)
+		}
+	}
+}
+
+func TestValidateDurationLessThanAssertion(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "dlt"},
+		Steps: []schema.Step{{
+			ID:   "check",
+			Type: schema.StepAssert,
+			Assert: []schema.Assertion{
+				{Type: "duration_less_than", Expected: "5s"},
+				{Type: "duration_less_than", Expected: "{{ .limit }}"},
+				{Type: "duration_less_than"},
+				{Type: "duration_less_than", Expected: "soon"},
+			},
+		}},
+	}
+	errs := validateDomain(rb, "")
+	if !containsMessage(errs, "duration_less_than assertion requires 'expected'") {
+		t.Errorf("expected missing expected error, got %v", errs)
+	}
+	found := false
+	for _, e := range errs {
+		if strings.HasPrefix(e.Path, "steps[0].assert[0]") || strings.HasPrefix(e.Path, "steps[0].assert[1]") {
+			t.Errorf("unexpected error for valid assertion: %v", e)
+		}
+		if e.Path == "steps[0].assert[3].expected" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected invalid duration error, got %v", errs)
+	}
+}
+
+func TestValidateContainsAllNoneAssertion(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "lists"},
+		Steps: []schema.Step{{
+			ID:   "check",
+			Type: schema.StepAssert,
+			Assert: []schema.Assertion{
+				{Type: "contains_all", Value: "{{ .log }}", Values: []string{"started", "ready"}},
+				{Type: "contains_none", Value: "{{ .log }}"},
+			},
+		}},
+	}
+	errs := validateDomain(rb, "")
+	if !containsMessage(errs, "contains_none assertion requires a non-empty 'values' list") {
+		t.Errorf("expected empty values error, got %v", errs)
+	}
+	for _, e := range errs {
+		if strings.HasPrefix(e.Path, "steps[0].assert[0]") {
+			t.Errorf("unexpected error for valid assertion: %v", e)
+		}
+	}
+}
+
+func TestValidateReachability(t *testing.T) {
+	pass := []schema.Assertion{{Type: "equals", Value: "a", Expected: "a"}}
+	end := &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "dead"},
+		Steps: []schema.Step{
+			{ID: "start", Type: schema.StepAssert, Assert: pass},
+			{ID: "jump", Type: schema.StepAssert, Assert: pass, Next: "finish"},
+			{ID: "skipped", Type: schema.StepAssert, Assert: pass},
+			{ID: "finish", Type: schema.StepEnd, Outcome: end},
+			{ID: "after_end", Type: schema.StepAssert, Assert: pass},
+		},
+	}
+	errs := validateReachability(rb)
+	if len(errs) != 2 || errs[0].Path != "steps[2]" || errs[1].Path != "steps[4]" || errs[0].Severity != "warning" {
+		t.Fatalf("expected warnings for steps[2] and steps[4], got %v", errs)
+	}
+	if !containsMessage(errs, `step "skipped" is unreachable`) {
+		t.Errorf("unexpected message: %v", errs)
+	}
+
+	// A when guard lets the jump fall through; on_failure reaches its target
+	rb.Steps[1].When = "{{ .retry }}"
+	rb.Steps[4].Type = schema.StepEnd
+	rb.Steps[4].Outcome = end
+	rb.Steps[4].Assert = nil
+	rb.Steps[0].OnFailure = "after_end"
+	if errs := validateReachability(rb); len(errs) != 0 {
+		t.Errorf("expected no warnings, got %v", errs)
+	}
+}
+
+func TestCheckToolFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "tools"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	good := "apiVersion: tool/v0\nmeta:\n  name: good\n  binary: good\nactions:\n  run:\n    argv: [\"good\"]\n"
+	if err := os.WriteFile(filepath.Join(dir, "tools", "good.tool.yaml"), []byte(good), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "tools", "broken.tool.yaml"), []byte("meta: [not, a, map"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rb := &schema.Runbook{Tools: []string{"good", "broken", "missing"}}
+	errs := CheckToolFiles(rb, dir)
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 errors, got %v", errs)
+	}
+	if errs[0].Path != "tools[1]" || errs[1].Path != "tools[2]" {
+		t.Errorf("paths = %s, %s", errs[0].Path, errs[1].Path)
+	}
+	if !containsMessage(errs, "definition not found") {
+		t.Errorf("expected missing-tool error, got %v", errs)
+	}
+}
+
+// BenchmarkValidation times the pipeline on a generated 500-step runbook and
+// reports the per-phase split from ValidationProfile.
+func BenchmarkValidation(b *testing.B) {
+	var sb strings.Builder
+	sb.WriteString("apiVersion: kernel/v0\nmeta:\n  name: bench\n  inputs:\n    x: { type: string }\nsteps:\n")
+	for i := 0; i < 500; i++ {
+		fmt.Fprintf(&sb, "  - id: check_%d\n    type: assert\n    assert:\n      - type: equals\n        value: \"{{ .x }}\"\n        expected: \"ok\"\n", i)
+	}
+	sb.WriteString("  - id: done\n    type: end\n    outcome:\n      category: no_action\n      code: checked\n")
+	path := filepath.Join(b.TempDir(), "bench.yaml")
+	if err := os.WriteFile(path, []byte(sb.String()), 0o644); err != nil {
+		b.Fatal(err)
+	}
+
+	var total ValidationProfile
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, errs, profile := ValidateFileProfiled(path)
+		if len(filterErrors(errs)) > 0 {
+			b.Fatalf("unexpected errors: %v", errs)
+		}
+		total.ParseMs += profile.ParseMs
+		total.SchemaMs += profile.SchemaMs
+		total.DomainMs += profile.DomainMs
+	}
+	b.ReportMetric(float64(total.ParseMs)/float64(b.N), "parse-ms/op")
+	b.ReportMetric(float64(total.SchemaMs)/float64(b.N), "schema-ms/op")
+	b.ReportMetric(float64(total.DomainMs)/float64(b.N), "domain-ms/op")
+}
+
+func TestValidateNext_Conditional(t *testing.T) {
+	scope := []schema.Step{
+		{ID: "check", Type: schema.StepAssert},
+		{ID: "decide", Type: schema.StepAssert},
+		{ID: "fix", Type: schema.StepAssert},
+	}
+	step := scope[1]
+	step.Next = []any{
+		map[string]any{"if": "check.degraded", "step": "fix"},
+		map[string]any{"if": "check.flapping", "step": "check", "max": 3},
+		map[string]any{"step": "fix"},
+	}
+	if errs := append(validateNextTarget(step, scope, "steps[1]"), validateNextBounded(step, scope, "steps[1]")...); len(errs) != 0 {
+		t.Errorf("expected no diagnostics, got %v", errs)
+	}
+
+	step.Next = []any{
+		map[string]any{"if": "check.degraded", "step": "missing"},
+		map[string]any{"if": "check.flapping", "step": "check"},
+	}
+	errs := append(validateNextTarget(step, scope, "steps[1]"), validateNextBounded(step, scope, "steps[1]")...)
+	if !containsMessage(errs, `target "missing" not found`) {
+		t.Errorf("expected missing target error, got %v", errs)
+	}
+	if !containsMessage(errs, "requires a 'max' bound") || errs[len(errs)-1].Path != "steps[1].next[1]" {
+		t.Errorf("expected backward bound error on next[1], got %v", errs)
+	}
+
+	step.Next = []any{map[string]any{"step": "fix"}, map[string]any{"if": "check.degraded", "step": "check", "max": 1}}
+	if errs := validateNextTarget(step, scope, "steps[1]"); !containsMessage(errs, "never used") {
+		t.Errorf("expected unreachable entry warning, got %v", errs)
+	}
+}
+
+func TestValidateGovernanceRuleSteps(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta: schema.Meta{
+			Name: "gov",
+			Governance: &schema.GovernancePolicy{Rules: []schema.GovernanceRule{
+				{Effects: []string{"kubernetes"}, Steps: []schema.StepType{schema.StepTool, schema.StepExtension}, Action: "require-approval"},
+				{Risk: "high", Steps: []schema.StepType{"branch"}, Action: "deny"},
+			}},
+		},
+		Steps: []schema.Step{{Type: schema.StepEnd, Outcome: &schema.Outcome{Category: schema.OutcomeResolved, Code: "done"}}},
+	}
+	errs := validateSemantic(rb)
+	if len(errs) != 1 || errs[0].Path != "meta.governance.rules[1].steps[0]" || !containsMessage(errs, `invalid step type "branch"`) {
+		t.Errorf("expected one invalid step type error, got %v", errs)
+	}
+}
+
+func TestValidateSource_Locations(t *testing.T) {
+	src := `apiVersion: kernel/v0
+meta:
+  name: located
+steps:
+  - id: done
+    type: end
+    outcome:
+      category: bogus
+      code: ok
+`
+	_, errs, _ := ValidateSource([]byte(src), "")
+	if len(errs) == 0 {
+		t.Fatal("expected findings")
+	}
+	for _, e := range errs {
+		if e.Line == 0 {
+			t.Errorf("finding has no line: %v", e)
+		}
+		if strings.HasPrefix(e.Path, "steps[0].outcome") && e.Line < 7 {
+			t.Errorf("outcome finding at line %d, want 7 or later: %v", e.Line, e)
+		}
+	}
+
+	_, errs, _ = ValidateSource([]byte("apiVersion: kernel/v0\nmeta:\n  name: x\n  bogus: 1\n"), "")
+	if len(errs) != 1 || errs[0].Phase != "structural" {
+		t.Fatalf("expected one structural error, got %v", errs)
+	}
+	if errs[0].Line != 4 {
+		t.Errorf("structural error at line %d, want 4", errs[0].Line)
+	}
+}
+
+func TestValidateNotAssertion(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "kernel/v0",
+		Meta:       schema.Meta{Name: "not"},
+		Steps: []schema.Step{{
+			ID:   "check",
+			Type: schema.StepAssert,
+			Assert: []schema.Assertion{
+				{Type: "equals", Value: "a", Expected: "b", Not: true},
+				{Type: "not_equals", Value: "a", Expected: "b", Not: true},
+			},
+		}},
+	}
+	var got []string
+	for _, e := range validateDomain(rb, "") {
+		if strings.HasSuffix(e.Path, ".not") {
+			got = append(got, e.Path+" "+e.Severity)
+		}
+	}
+	if want := "steps[0].assert[1].not warning"; len(got) != 1 || got[0] != want {
+		t.Errorf("not findings = %v, want [%s]", got, want)
+	}
+}
@@ -0,0 +1,248 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// Credential types accepted by AzureBlobCollector.CredentialType.
+const (
+	AzureCredentialManagedIdentity = "managed-identity"
+	AzureCredentialSAS             = "sas"
+)
+
+// Environment variables read by NewAzureBlobCollectorFromEnv.
+const (
+	EnvAzureStorageAccount   = "AZURE_STORAGE_ACCOUNT"
+	EnvAzureStorageContainer = "AZURE_STORAGE_CONTAINER"
+	EnvAzureStorageAuth      = "AZURE_STORAGE_AUTH" // managed-identity (default) or sas
+	EnvAzureStorageSASToken  = "AZURE_STORAGE_SAS_TOKEN"
+)
+
+// azureStorageScope is the token scope for Azure Storage data-plane calls.
+const azureStorageScope = "https://storage.azure.com/.default"
+
+// azureBlobAPIVersion is the Blob service REST version sent with uploads.
+const azureBlobAPIVersion = "2023-11-03"
+
+// RemoteEvidenceCollector is an EvidenceCollector that stores evidence
+// off-machine. EvidenceURL is where the run's evidence lives; the runtime
+// records it in run.yaml.
+type RemoteEvidenceCollector interface {
+	EvidenceCollector
+	EvidenceURL() string
+}
+
+// AzureBlobCollector collects evidence through Inner and uploads it to an
+// Azure Blob Storage container. Attachments are uploaded as-is and their
+// returned Path is the blob URL; text and checklist evidence are uploaded
+// as JSON. Blobs are named <Prefix>/<evidence name>[...].
+type AzureBlobCollector struct {
+	AccountName    string
+	ContainerName  string
+	CredentialType string // AzureCredentialManagedIdentity (default) or AzureCredentialSAS
+	SASToken       string // query string for CredentialType "sas", with or without a leading "?"
+	Prefix         string // blob name prefix, usually the run ID
+	Endpoint       string // blob service URL; default https://<account>.blob.core.windows.net
+
+	Inner      EvidenceCollector      // prompts for the evidence before it is uploaded
+	Credential azcore.TokenCredential // managed identity when nil
+	Client     *http.Client           // http.DefaultClient when nil
+
+	credOnce sync.Once
+	credErr  error
+}
+
+// NewAzureBlobCollectorFromEnv configures an AzureBlobCollector around inner
+// from AZURE_STORAGE_ACCOUNT, AZURE_STORAGE_CONTAINER, AZURE_STORAGE_AUTH
+// and AZURE_STORAGE_SAS_TOKEN.
+func NewAzureBlobCollectorFromEnv(inner EvidenceCollector) (*AzureBlobCollector, error) {
+	c := &AzureBlobCollector{
+		AccountName:    os.Getenv(EnvAzureStorageAccount),
+		ContainerName:  os.Getenv(EnvAzureStorageContainer),
+		CredentialType: os.Getenv(EnvAzureStorageAuth),
+		SASToken:       os.Getenv(EnvAzureStorageSASToken),
+		Inner:          inner,
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks that the collector has a destination and usable auth
+// settings. It does not contact Azure.
+func (c *AzureBlobCollector) Validate() error {
+	if c.AccountName == "" && c.Endpoint == "" {
+		return fmt.Errorf("azure blob collector: account name is required (%s)", EnvAzureStorageAccount)
+	}
+	if c.ContainerName == "" {
+		return fmt.Errorf("azure blob collector: container name is required (%s)", EnvAzureStorageContainer)
+	}
+	switch c.CredentialType {
+	case "", AzureCredentialManagedIdentity:
+	case AzureCredentialSAS:
+		if c.SASToken == "" {
+			return fmt.Errorf("azure blob collector: sas auth needs a token (%s)", EnvAzureStorageSASToken)
+		}
+	default:
+		return fmt.Errorf("azure blob collector: unknown credential type %q (want %s or %s)",
+			c.CredentialType, AzureCredentialManagedIdentity, AzureCredentialSAS)
+	}
+	if c.Inner == nil {
+		return fmt.Errorf("azure blob collector: no inner collector")
+	}
+	return nil
+}
+
+// EvidenceURL returns the URL of the run's evidence folder in the
+// container. It never includes the SAS token.
+func (c *AzureBlobCollector) EvidenceURL() string {
+	return c.blobURL(c.Prefix)
+}
+
+func (c *AzureBlobCollector) PromptText(name string, instructions string) (string, error) {
+	text, err := c.Inner.PromptText(name, instructions)
+	if err != nil {
+		return "", err
+	}
+	if err := c.uploadJSON(name+".json", map[string]any{"name": name, "type": "text", "value": text}); err != nil {
+		return "", err
+	}
+	return text, nil
+}
+
+func (c *AzureBlobCollector) PromptChecklist(name string, items []string) (map[string]bool, error) {
+	checked, err := c.Inner.PromptChecklist(name, items)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.uploadJSON(name+".json", map[string]any{"name": name, "type": "checklist", "value": checked}); err != nil {
+		return nil, err
+	}
+	return checked, nil
+}
+
+func (c *AzureBlobCollector) PromptAttachment(name string, instructions string) (*AttachmentInfo, error) {
+	info, err := c.Inner.PromptAttachment(name, instructions)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(info.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read attachment %s: %w", info.Path, err)
+	}
+	blob := name + "/" + filepath.Base(info.Path)
+	if err := c.upload(blob, data, "application/octet-stream"); err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	return &AttachmentInfo{
+		Path:   c.blobURL(path.Join(c.Prefix, blob)),
+		SHA256: hex.EncodeToString(sum[:]),
+		Size:   int64(len(data)),
+	}, nil
+}
+
+// PromptApproval is passed through; approvals are recorded in the trace.
+func (c *AzureBlobCollector) PromptApproval(roles []string, min int) ([]Approval, error) {
+	return c.Inner.PromptApproval(roles, min)
+}
+
+func (c *AzureBlobCollector) uploadJSON(blob string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return c.upload(blob, data, "application/json")
+}
+
+// upload writes data to <Prefix>/<blob> as a block blob, replacing any
+// existing blob of that name.
+func (c *AzureBlobCollector) upload(blob string, data []byte, contentType string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	target := c.blobURL(path.Join(c.Prefix, blob))
+	if c.CredentialType == AzureCredentialSAS {
+		target += "?" + strings.TrimPrefix(c.SASToken, "?")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Header.Set("x-ms-version", azureBlobAPIVersion)
+	req.Header.Set("Content-Type", contentType)
+	if c.CredentialType != AzureCredentialSAS {
+		token, err := c.token(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload evidence %s: %w", blob, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("upload evidence %s: %s: %s", blob, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (c *AzureBlobCollector) token(ctx context.Context) (string, error) {
+	c.credOnce.Do(func() {
+		if c.Credential == nil {
+			c.Credential, c.credErr = azidentity.NewManagedIdentityCredential(nil)
+		}
+	})
+	if c.credErr != nil {
+		return "", fmt.Errorf("azure credential: %w", c.credErr)
+	}
+	tok, err := c.Credential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{azureStorageScope}})
+	if err != nil {
+		return "", fmt.Errorf("azure credential: %w", err)
+	}
+	return tok.Token, nil
+}
+
+// blobURL returns the URL of a blob (or folder) in the container, with
+// each path segment escaped.
+func (c *AzureBlobCollector) blobURL(name string) string {
+	base := c.Endpoint
+	if base == "" {
+		base = "https://" + c.AccountName + ".blob.core.windows.net"
+	}
+	segs := []string{strings.TrimSuffix(base, "/"), url.PathEscape(c.ContainerName)}
+	for _, s := range strings.Split(name, "/") {
+		if s != "" {
+			segs = append(segs, url.PathEscape(s))
+		}
+	}
+	return strings.Join(segs, "/")
+}
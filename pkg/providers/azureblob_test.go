@@ -0,0 +1,155 @@
+package providers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+// stubCollector returns fixed evidence.
+type stubCollector struct {
+	DryRunCollector
+	attachment string
+}
+
+func (s *stubCollector) PromptText(name, instructions string) (string, error) {
+	return "pods restarted", nil
+}
+
+func (s *stubCollector) PromptAttachment(name, instructions string) (*AttachmentInfo, error) {
+	return &AttachmentInfo{Path: s.attachment}, nil
+}
+
+type staticToken string
+
+func (t staticToken) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: string(t), ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+type blobRequest struct {
+	path, query, auth, blobType, body string
+}
+
+func blobServer(t *testing.T) (*httptest.Server, func() []blobRequest) {
+	var mu sync.Mutex
+	var reqs []blobRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		reqs = append(reqs, blobRequest{r.URL.Path, r.URL.RawQuery, r.Header.Get("Authorization"), r.Header.Get("x-ms-blob-type"), string(body)})
+		mu.Unlock()
+		if r.Method != http.MethodPut {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []blobRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]blobRequest(nil), reqs...)
+	}
+}
+
+func TestAzureBlobCollector_SAS(t *testing.T) {
+	srv, requests := blobServer(t)
+	file := filepath.Join(t.TempDir(), "dump.log")
+	if err := os.WriteFile(file, []byte("log line"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	c := &AzureBlobCollector{
+		ContainerName:  "evidence",
+		CredentialType: AzureCredentialSAS,
+		SASToken:       "?sv=2023&sig=abc",
+		Prefix:         "run-1",
+		Endpoint:       srv.URL,
+		Inner:          &stubCollector{attachment: file},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	if text, err := c.PromptText("summary", ""); err != nil || text != "pods restarted" {
+		t.Fatalf("PromptText = %q, %v", text, err)
+	}
+	info, err := c.PromptAttachment("logs", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Path != srv.URL+"/evidence/run-1/logs/dump.log" || info.Size != 8 || info.SHA256 == "" {
+		t.Errorf("attachment = %+v", info)
+	}
+	if strings.Contains(info.Path, "sig=") || strings.Contains(c.EvidenceURL(), "sig=") {
+		t.Error("blob URLs must not include the SAS token")
+	}
+
+	reqs := requests()
+	if len(reqs) != 2 {
+		t.Fatalf("requests = %+v", reqs)
+	}
+	if reqs[0].path != "/evidence/run-1/summary.json" || !strings.Contains(reqs[0].body, `"pods restarted"`) {
+		t.Errorf("text upload = %+v", reqs[0])
+	}
+	if reqs[1].body != "log line" || reqs[1].blobType != "BlockBlob" {
+		t.Errorf("attachment upload = %+v", reqs[1])
+	}
+	for _, r := range reqs {
+		if r.query != "sv=2023&sig=abc" || r.auth != "" {
+			t.Errorf("sas request should carry the token in the query only: %+v", r)
+		}
+	}
+}
+
+func TestAzureBlobCollector_ManagedIdentity(t *testing.T) {
+	srv, requests := blobServer(t)
+	c := &AzureBlobCollector{
+		ContainerName: "evidence",
+		Prefix:        "run-2",
+		Endpoint:      srv.URL,
+		Inner:         &stubCollector{},
+		Credential:    staticToken("tok"),
+	}
+	if _, err := c.PromptChecklist("steps", []string{"drain", "restart"}); err != nil {
+		t.Fatal(err)
+	}
+	reqs := requests()
+	if len(reqs) != 1 || reqs[0].auth != "Bearer tok" || reqs[0].query != "" || !strings.Contains(reqs[0].body, `"drain": true`) {
+		t.Errorf("requests = %+v", reqs)
+	}
+	if got := c.EvidenceURL(); got != srv.URL+"/evidence/run-2" {
+		t.Errorf("EvidenceURL = %q", got)
+	}
+}
+
+func TestAzureBlobCollector_Validate(t *testing.T) {
+	inner := &DryRunCollector{}
+	cases := map[string]*AzureBlobCollector{
+		"account name is required": {ContainerName: "c", Inner: inner},
+		"container name":           {AccountName: "a", Inner: inner},
+		"needs a token":            {AccountName: "a", ContainerName: "c", CredentialType: AzureCredentialSAS, Inner: inner},
+		"unknown credential type":  {AccountName: "a", ContainerName: "c", CredentialType: "key", Inner: inner},
+	}
+	for want, c := range cases {
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() = %v, want error containing %q", err, want)
+		}
+	}
+	ok := &AzureBlobCollector{AccountName: "acct", ContainerName: "c", Inner: inner}
+	if err := ok.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if got := ok.blobURL("run 1/a.json"); got != "https://acct.blob.core.windows.net/c/run%201/a.json" {
+		t.Errorf("blobURL = %q", got)
+	}
+}
@@ -5,23 +5,29 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"runtime"
 	"time"
+
+	"github.com/ormasoftchile/gert/pkg/kernel/executor"
 )
 
 // RealExecutor runs commands via os/exec with timeout support.
-type RealExecutor struct{}
+type RealExecutor struct {
+	// CleanEnv runs commands without the parent environment: only env and
+	// the variables in executor.MinimalEnv are passed.
+	CleanEnv bool
+}
 
-// Execute runs a command with the given arguments and environment.
+// Execute runs a command with the given arguments. env entries (KEY=value)
+// are added to the inherited environment, overriding inherited values.
 // On Windows, if the command is not found directly it is retried through
 // cmd.exe /C so that shell builtins (echo, set, …) work transparently.
 func (r *RealExecutor) Execute(ctx context.Context, command string, args []string, env []string) (*CommandResult, error) {
 	start := time.Now()
 	cmd := exec.CommandContext(ctx, command, args...)
-	if len(env) > 0 {
-		cmd.Env = env
-	}
+	cmd.Env = r.environ(env)
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -41,9 +47,7 @@ func (r *RealExecutor) Execute(ctx context.Context, command string, args []strin
 			cmdLine += " " + a
 		}
 		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", cmdLine)
-		if len(env) > 0 {
-			cmd.Env = env
-		}
+		cmd.Env = r.environ(env)
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
 		err = cmd.Run()
@@ -68,6 +72,19 @@ func (r *RealExecutor) Execute(ctx context.Context, command string, args []strin
 	}, nil
 }
 
+// environ returns the process environment for env: nil (inherit) when
+// there is nothing to add, the parent environment plus env, or with
+// CleanEnv the minimal environment plus env.
+func (r *RealExecutor) environ(env []string) []string {
+	if r.CleanEnv {
+		return executor.CleanEnviron(env)
+	}
+	if len(env) == 0 {
+		return nil
+	}
+	return append(os.Environ(), env...)
+}
+
 // isExecNotFound returns true when the error indicates the executable was not found.
 func isExecNotFound(err error) bool {
 	if err == exec.ErrNotFound {
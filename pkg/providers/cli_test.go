@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"os"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -23,6 +24,36 @@ func TestRealExecutorEcho(t *testing.T) {
 	}
 }
 
+func TestRealExecutorEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("GERT_PARENT_VAR", "inherited")
+	r := &RealExecutor{}
+	result, err := r.Execute(context.Background(), "sh", []string{"-c", "echo $GERT_PARENT_VAR $GERT_STEP_VAR"}, []string{"GERT_STEP_VAR=step"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out := strings.TrimSpace(string(result.Stdout)); out != "inherited step" {
+		t.Errorf("stdout = %q, want parent and step env merged", out)
+	}
+}
+
+func TestRealExecutorCleanEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	t.Setenv("GERT_PARENT_VAR", "inherited")
+	r := &RealExecutor{CleanEnv: true}
+	result, err := r.Execute(context.Background(), "sh", []string{"-c", "echo [$GERT_PARENT_VAR] $GERT_STEP_VAR $HOME"}, []string{"GERT_STEP_VAR=step"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out, want := strings.TrimSpace(string(result.Stdout)), "[] step "+os.Getenv("HOME"); out != want {
+		t.Errorf("stdout = %q, want %q", out, want)
+	}
+}
+
 func TestIsExecNotFound(t *testing.T) {
 	if !isExecNotFound(exec.ErrNotFound) {
 		t.Error("expected ErrNotFound to be detected")
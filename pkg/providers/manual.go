@@ -10,6 +10,11 @@ import (
 // InteractiveCollector prompts the user via CLI for evidence collection.
 type InteractiveCollector struct {
 	reader *bufio.Reader
+
+	// Group lists pre-authorised approvers (--as-group). When it has at
+	// least the required number of members, approvals are granted from it
+	// without prompting.
+	Group []string
 }
 
 // NewInteractiveCollector creates an evidence collector that reads from stdin.
@@ -73,6 +78,16 @@ func (ic *InteractiveCollector) PromptAttachment(name string, instructions strin
 }
 
 func (ic *InteractiveCollector) PromptApproval(roles []string, min int) ([]Approval, error) {
+	if len(ic.Group) > 0 && len(ic.Group) >= min {
+		approvals := make([]Approval, len(ic.Group))
+		for i, actor := range ic.Group {
+			approvals[i] = Approval{Actor: actor}
+			if len(roles) > 0 {
+				approvals[i].Role = roles[i%len(roles)]
+			}
+		}
+		return approvals, nil
+	}
 	fmt.Printf("\n✅ Approval required (min %d from roles: %s)\n", min, strings.Join(roles, ", "))
 	var approvals []Approval
 	for i := 0; i < min; i++ {
@@ -45,7 +45,8 @@ type CommandExecutor interface {
 }
 
 // EvidenceCollector abstracts interactive vs pre-recorded evidence collection.
-// Implementations: InteractiveCollector, ScenarioCollector, DryRunCollector.
+// Implementations: InteractiveCollector, ScenarioCollector, DryRunCollector,
+// and AzureBlobCollector, which wraps one of them to upload evidence.
 type EvidenceCollector interface {
 	PromptText(name string, instructions string) (string, error)
 	PromptChecklist(name string, items []string) (map[string]bool, error)
@@ -92,6 +93,7 @@ type StepResult struct {
 	Captures    map[string]string         `json:"captures,omitempty"`
 	Assertions  []*AssertionResult        `json:"assertions,omitempty"`
 	Error       string                    `json:"error,omitempty"`
+	Env         map[string]string         `json:"env,omitempty"` // step env after redaction
 	Usage       *UsageReport              `json:"usage,omitempty"`
 	RawResponse []byte                    `json:"-"` // raw provider response (not serialized to trace, used for auto-save)
 }
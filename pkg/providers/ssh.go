@@ -0,0 +1,240 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// DefaultSSHDialTimeout bounds the TCP connect and SSH handshake.
+const DefaultSSHDialTimeout = 30 * time.Second
+
+// SSHExecutor runs commands on a remote host over SSH. Only public key
+// authentication is offered; the host key must be listed in KnownHostsPath.
+type SSHExecutor struct {
+	Host           string
+	Port           string // default "22"
+	User           string // default $USER
+	KeyPath        string // default ~/.ssh/id_ed25519, then ~/.ssh/id_rsa
+	KnownHostsPath string // default ~/.ssh/known_hosts
+	DialTimeout    time.Duration
+
+	// Output, when set, receives stdout and stderr chunks as they arrive.
+	// Calls are serialized. The full output is still returned in the result.
+	Output func(stream string, data []byte)
+}
+
+// ParseSSHExecutor builds an SSHExecutor from an executor URI of the form
+// ssh://[user@]host[:port].
+func ParseSSHExecutor(uri string) (*SSHExecutor, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("parse executor %q: %w", uri, err)
+	}
+	if u.Scheme != "ssh" {
+		return nil, fmt.Errorf("unsupported executor %q: want ssh://[user@]host[:port]", uri)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("executor %q has no host", uri)
+	}
+	if _, hasPassword := u.User.Password(); hasPassword {
+		return nil, fmt.Errorf("executor %q: passwords are not supported, use key authentication", uri)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+		return nil, fmt.Errorf("executor %q: unexpected path or query", uri)
+	}
+	if p := u.Port(); p != "" {
+		if _, err := strconv.ParseUint(p, 10, 16); err != nil {
+			return nil, fmt.Errorf("executor %q: invalid port %q", uri, p)
+		}
+	}
+	return &SSHExecutor{
+		Host: u.Hostname(),
+		Port: u.Port(),
+		User: u.User.Username(),
+	}, nil
+}
+
+// Execute runs the command in a new session on the remote host. env entries
+// (KEY=value) are passed as variable assignments on the command line, since
+// most servers reject the SSH "env" request.
+func (s *SSHExecutor) Execute(ctx context.Context, command string, args []string, env []string) (*CommandResult, error) {
+	start := time.Now()
+	config, err := s.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	addr := net.JoinHostPort(s.Host, s.port())
+
+	timeout := s.DialTimeout
+	if timeout <= 0 {
+		timeout = DefaultSSHDialTimeout
+	}
+	config.Timeout = timeout
+	dialCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(dialCtx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("ssh dial %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ssh handshake %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Time{})
+	client := ssh.NewClient(c, chans, reqs)
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("ssh session %s: %w", addr, err)
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	var mu sync.Mutex
+	session.Stdout = &streamWriter{buf: &stdout, stream: "stdout", mu: &mu, fn: s.Output}
+	session.Stderr = &streamWriter{buf: &stderr, stream: "stderr", mu: &mu, fn: s.Output}
+
+	done := make(chan error, 1)
+	go func() { done <- session.Run(remoteCommand(command, args, env)) }()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		client.Close()
+		<-done
+		return nil, fmt.Errorf("execute command %q on %s: %w", command, s.Host, ctx.Err())
+	}
+
+	exitCode := 0
+	if err != nil {
+		var exitErr *ssh.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("execute command %q on %s: %w", command, s.Host, err)
+		}
+		exitCode = exitErr.ExitStatus()
+	}
+
+	return &CommandResult{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: exitCode,
+		Duration: time.Since(start),
+	}, nil
+}
+
+func (s *SSHExecutor) port() string {
+	if s.Port == "" {
+		return "22"
+	}
+	return s.Port
+}
+
+// clientConfig loads the private key and known_hosts file. Password and
+// keyboard-interactive authentication are never offered.
+func (s *SSHExecutor) clientConfig() (*ssh.ClientConfig, error) {
+	home, _ := os.UserHomeDir()
+
+	user := s.User
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+	if user == "" {
+		return nil, fmt.Errorf("ssh %s: no user given", s.Host)
+	}
+
+	keyPaths := []string{s.KeyPath}
+	if s.KeyPath == "" {
+		keyPaths = []string{filepath.Join(home, ".ssh", "id_ed25519"), filepath.Join(home, ".ssh", "id_rsa")}
+	}
+	var signer ssh.Signer
+	var keyErr error
+	for _, p := range keyPaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			keyErr = err
+			continue
+		}
+		signer, keyErr = ssh.ParsePrivateKey(data)
+		if keyErr != nil {
+			return nil, fmt.Errorf("ssh key %s: %w", p, keyErr)
+		}
+		break
+	}
+	if signer == nil {
+		return nil, fmt.Errorf("ssh key: %w", keyErr)
+	}
+
+	knownHostsPath := s.KnownHostsPath
+	if knownHostsPath == "" {
+		knownHostsPath = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsPath)
+	if err != nil {
+		return nil, fmt.Errorf("ssh known_hosts: %w", err)
+	}
+
+	return &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: hostKeyCallback,
+	}, nil
+}
+
+// remoteCommand builds a POSIX shell command line, quoting every word.
+func remoteCommand(command string, args []string, env []string) string {
+	words := make([]string, 0, len(env)+len(args)+2)
+	if len(env) > 0 {
+		words = append(words, "env")
+		for _, kv := range env {
+			words = append(words, shellQuote(kv))
+		}
+	}
+	words = append(words, shellQuote(command))
+	for _, a := range args {
+		words = append(words, shellQuote(a))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote wraps s in single quotes unless it is made of safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:@,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// streamWriter collects one output stream and forwards each chunk to fn.
+type streamWriter struct {
+	buf    *bytes.Buffer
+	stream string
+	mu     *sync.Mutex
+	fn     func(stream string, data []byte)
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	if w.fn != nil {
+		w.fn(w.stream, p)
+	}
+	return len(p), nil
+}
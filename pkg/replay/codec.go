@@ -0,0 +1,164 @@
+package replay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// KeyEnv names the environment variable LoadStepScenario reads the
+// base64-encoded AES-256 key of encrypted step files from.
+const KeyEnv = "GERT_SCENARIO_KEY"
+
+// encMagic prefixes every encrypted step file, ahead of the GCM nonce.
+const encMagic = "GERTENC1"
+
+// RecordOptions controls how step responses are written to a scenario.
+type RecordOptions struct {
+	Compress   bool   // gzip each step file (.json.gz)
+	EncryptKey []byte // AES-256 key; encrypts each step file (.enc) when set
+}
+
+// Recording is the scenario.yaml metadata describing how step files were
+// written, so a reader knows to decompress or decrypt them.
+type Recording struct {
+	Compression string `yaml:"compression,omitempty"` // gzip
+	Encryption  string `yaml:"encryption,omitempty"`  // aes-256-gcm
+}
+
+// Recording returns the scenario.yaml metadata for these options, or nil
+// when step files are written as plain JSON.
+func (o RecordOptions) Recording() *Recording {
+	if !o.Compress && o.EncryptKey == nil {
+		return nil
+	}
+	r := &Recording{}
+	if o.Compress {
+		r.Compression = "gzip"
+	}
+	if o.EncryptKey != nil {
+		r.Encryption = "aes-256-gcm"
+	}
+	return r
+}
+
+// ParseKey decodes a base64-encoded AES-256 key.
+func ParseKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("decode encryption key: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes for AES-256, got %d", len(key))
+	}
+	return key, nil
+}
+
+// EncodeStepFile applies the record options to a step response, returning
+// the file name to write (name plus .gz and/or .enc) and its contents.
+func EncodeStepFile(name string, data []byte, opts RecordOptions) (string, []byte, error) {
+	if opts.Compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return "", nil, fmt.Errorf("compress %s: %w", name, err)
+		}
+		if err := zw.Close(); err != nil {
+			return "", nil, fmt.Errorf("compress %s: %w", name, err)
+		}
+		name, data = name+".gz", buf.Bytes()
+	}
+	if opts.EncryptKey != nil {
+		gcm, err := newGCM(opts.EncryptKey)
+		if err != nil {
+			return "", nil, err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", nil, fmt.Errorf("encrypt %s: %w", name, err)
+		}
+		out := append([]byte(encMagic), nonce...)
+		name, data = name+".enc", gcm.Seal(out, nonce, data, nil)
+	}
+	return name, data, nil
+}
+
+// DecodeStepFile reverses EncodeStepFile. Encryption is detected by the
+// magic prefix or a .enc extension and compression by the gzip header, so
+// plain JSON files pass through unchanged. It returns the name with the
+// .enc and .gz extensions removed.
+func DecodeStepFile(name string, data []byte, key []byte) (string, []byte, error) {
+	if bytes.HasPrefix(data, []byte(encMagic)) || strings.HasSuffix(name, ".enc") {
+		if key == nil {
+			return "", nil, fmt.Errorf("%s is encrypted — set %s to the scenario key", name, KeyEnv)
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", nil, err
+		}
+		body, ok := bytes.CutPrefix(data, []byte(encMagic))
+		if !ok || len(body) < gcm.NonceSize() {
+			return "", nil, fmt.Errorf("%s: not a gert encrypted file", name)
+		}
+		nonce, sealed := body[:gcm.NonceSize()], body[gcm.NonceSize():]
+		data, err = gcm.Open(nil, nonce, sealed, nil)
+		if err != nil {
+			return "", nil, fmt.Errorf("decrypt %s: wrong key or corrupted file", name)
+		}
+		name = strings.TrimSuffix(name, ".enc")
+	}
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", nil, fmt.Errorf("decompress %s: %w", name, err)
+		}
+		data, err = io.ReadAll(zr)
+		if err != nil {
+			return "", nil, fmt.Errorf("decompress %s: %w", name, err)
+		}
+		name = strings.TrimSuffix(name, ".gz")
+	}
+	return name, data, nil
+}
+
+// DecodeInputs returns the plain contents of a scenario's inputs.yaml,
+// decrypting it with the key in KeyEnv when it was saved encrypted.
+func DecodeInputs(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(encMagic)) {
+		return data, nil
+	}
+	key, err := keyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	_, data, err = DecodeStepFile("inputs.yaml", data, key)
+	return data, err
+}
+
+// keyFromEnv returns the scenario key from KeyEnv, or nil when unset.
+func keyFromEnv() ([]byte, error) {
+	s := os.Getenv(KeyEnv)
+	if s == "" {
+		return nil, nil
+	}
+	key, err := ParseKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", KeyEnv, err)
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
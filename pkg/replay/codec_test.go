@@ -0,0 +1,95 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecodeStepFile(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	plain := []byte(`{"rows":[1,2,3]}`)
+
+	tests := []struct {
+		name     string
+		opts     RecordOptions
+		wantName string
+	}{
+		{"plain", RecordOptions{}, "001-a.json"},
+		{"compressed", RecordOptions{Compress: true}, "001-a.json.gz"},
+		{"encrypted", RecordOptions{EncryptKey: key}, "001-a.json.enc"},
+		{"both", RecordOptions{Compress: true, EncryptKey: key}, "001-a.json.gz.enc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, data, err := EncodeStepFile("001-a.json", plain, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tt.wantName {
+				t.Errorf("name = %q, want %q", name, tt.wantName)
+			}
+			if tt.opts.EncryptKey != nil && bytes.Contains(data, []byte("rows")) {
+				t.Error("encrypted file contains plaintext")
+			}
+			gotName, got, err := DecodeStepFile(name, data, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotName != "001-a.json" || !bytes.Equal(got, plain) {
+				t.Errorf("decoded %q = %s", gotName, got)
+			}
+		})
+	}
+}
+
+func TestDecodeStepFile_KeyErrors(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	name, data, err := EncodeStepFile("001-a.json", []byte(`{}`), RecordOptions{EncryptKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := DecodeStepFile(name, data, nil); err == nil || !strings.Contains(err.Error(), KeyEnv) {
+		t.Errorf("missing key: err = %v", err)
+	}
+	if _, _, err := DecodeStepFile(name, data, bytes.Repeat([]byte{8}, 32)); err == nil || !strings.Contains(err.Error(), "wrong key") {
+		t.Errorf("wrong key: err = %v", err)
+	}
+	if _, err := ParseKey(base64.StdEncoding.EncodeToString([]byte("short"))); err == nil {
+		t.Error("expected short key to be rejected")
+	}
+}
+
+func TestLoadStepScenario_Encrypted(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	dir := t.TempDir()
+	stepsDir := filepath.Join(dir, "steps")
+	os.MkdirAll(stepsDir, 0o755)
+	name, data, err := EncodeStepFile("001-check-login.json", []byte(`{"ok":true}`), RecordOptions{Compress: true, EncryptKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(stepsDir, name), data, 0o644)
+	os.WriteFile(filepath.Join(dir, "scenario.yaml"), []byte("recording:\n  compression: gzip\n  encryption: aes-256-gcm\n"), 0o644)
+
+	t.Setenv(KeyEnv, base64.StdEncoding.EncodeToString(key))
+	sc, err := LoadStepScenario(dir, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, ok := sc.FindStepResponse("check_login"); !ok || string(resp) != `{"ok":true}` {
+		t.Errorf("step response = %s, %v", resp, ok)
+	}
+	if sc.Recording == nil || sc.Recording.Encryption != "aes-256-gcm" {
+		t.Errorf("recording = %+v", sc.Recording)
+	}
+
+	t.Setenv(KeyEnv, "")
+	if _, err := LoadStepScenario(dir, time.Time{}); err == nil {
+		t.Error("expected an error without the key")
+	}
+}
@@ -0,0 +1,286 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/evidence"
+	kreplay "github.com/ormasoftchile/gert/pkg/kernel/replay"
+	"gopkg.in/yaml.v3"
+)
+
+// RunManifest is the part of a run's run.yaml that DiffScenarios compares.
+// It reads the file runtime.RunManifest writes; replay cannot import runtime.
+type RunManifest struct {
+	RunID          string            `yaml:"run_id"                    json:"run_id"`
+	Runbook        string            `yaml:"runbook"                   json:"runbook"`
+	Outcome        *ManifestOutcome  `yaml:"outcome,omitempty"         json:"outcome,omitempty"`
+	InputsResolved map[string]string `yaml:"inputs_resolved,omitempty" json:"inputs_resolved,omitempty"`
+}
+
+// ManifestOutcome is the terminal outcome recorded in run.yaml.
+type ManifestOutcome struct {
+	State          string `yaml:"state"                    json:"state"`
+	StepID         string `yaml:"step_id"                  json:"step_id"`
+	Recommendation string `yaml:"recommendation,omitempty" json:"recommendation,omitempty"`
+}
+
+// DiffReport is the result of comparing two scenario runs. A is the
+// baseline, B the run being compared against it.
+type DiffReport struct {
+	RunA           string           `json:"run_a"`
+	RunB           string           `json:"run_b"`
+	OutcomeA       *ManifestOutcome `json:"outcome_a,omitempty"`
+	OutcomeB       *ManifestOutcome `json:"outcome_b,omitempty"`
+	OutcomeChanged bool             `json:"outcome_changed"`
+	Inputs         []ValueChange    `json:"inputs,omitempty"`
+	AddedSteps     []string         `json:"added_steps,omitempty"`
+	RemovedSteps   []string         `json:"removed_steps,omitempty"`
+	ChangedSteps   []StepDiff       `json:"changed_steps,omitempty"`
+}
+
+// StepDiff lists the captured values that differ for a step present in
+// both runs.
+type StepDiff struct {
+	StepID  string        `json:"step_id"`
+	Changes []ValueChange `json:"changes"`
+}
+
+// ValueChange is one value that differs between the runs. Path is a
+// dotted JSON path into the step response ("status.phase", "items[0]"),
+// or the input name. Old or New is empty when the value is missing on that
+// side.
+type ValueChange struct {
+	Path string `json:"path"`
+	Old  string `json:"old,omitempty"`
+	New  string `json:"new,omitempty"`
+}
+
+// Empty reports whether the two runs are indistinguishable.
+func (r *DiffReport) Empty() bool {
+	return !r.OutcomeChanged && len(r.Inputs) == 0 && len(r.AddedSteps) == 0 &&
+		len(r.RemovedSteps) == 0 && len(r.ChangedSteps) == 0
+}
+
+// stepOrderPrefix is the ordering prefix on step response files
+// ("003-check-pod.json"), which can differ between runs of the same step.
+var stepOrderPrefix = regexp.MustCompile(`^\d+-`)
+
+// LoadRunDir loads a scenario run directory: run.yaml when present, and
+// the step responses under steps/ via LoadStepScenario. A scenario saved
+// without a manifest is compared on its inputs.yaml and step responses. A
+// kernel scenario (scenario.yaml) is loaded through the kernel replay
+// package. A directory with none of these is an error.
+func LoadRunDir(dir string) (*RunManifest, map[string][]byte, error) {
+	if _, err := os.Stat(filepath.Join(dir, "scenario.yaml")); err == nil {
+		return loadKernelScenario(dir)
+	}
+
+	m := &RunManifest{RunID: filepath.Base(dir)}
+	if data, err := os.ReadFile(filepath.Join(dir, evidence.ManifestFile)); err == nil {
+		if err := yaml.Unmarshal(data, m); err != nil {
+			return nil, nil, fmt.Errorf("parse %s: %w", filepath.Join(dir, evidence.ManifestFile), err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, nil, err
+	} else if data, err := os.ReadFile(filepath.Join(dir, "inputs.yaml")); err == nil {
+		if data, err = DecodeInputs(data); err != nil {
+			return nil, nil, err
+		}
+		if err := yaml.Unmarshal(data, &m.InputsResolved); err != nil {
+			return nil, nil, fmt.Errorf("parse %s: %w", filepath.Join(dir, "inputs.yaml"), err)
+		}
+	} else if os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("%s has no %s, inputs.yaml or scenario.yaml", dir, evidence.ManifestFile)
+	} else {
+		return nil, nil, err
+	}
+
+	sc, err := LoadStepScenario(dir, time.Time{})
+	if err != nil {
+		return nil, nil, err
+	}
+	steps := make(map[string][]byte, len(sc.StepResponses))
+	for key, raw := range sc.StepResponses {
+		steps[key] = raw
+	}
+	return m, steps, nil
+}
+
+// loadKernelScenario loads a kernel scenario.yaml for comparison. Each
+// canned tool response is a step keyed "tool:action" ("tool:action#2" for
+// later responses to the same action), and each manual step's evidence is
+// a step keyed "evidence:<step_id>".
+func loadKernelScenario(dir string) (*RunManifest, map[string][]byte, error) {
+	sc, err := kreplay.LoadScenarioDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	m := &RunManifest{RunID: filepath.Base(dir), InputsResolved: sc.Inputs}
+	steps := make(map[string][]byte)
+	for key, responses := range sc.ToolResponses {
+		for i, resp := range responses {
+			data, err := json.Marshal(resp)
+			if err != nil {
+				return nil, nil, fmt.Errorf("encode %s response: %w", key, err)
+			}
+			if i > 0 {
+				steps[fmt.Sprintf("%s#%d", key, i+1)] = data
+			} else {
+				steps[key] = data
+			}
+		}
+	}
+	for stepID, ev := range sc.Evidence {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return nil, nil, fmt.Errorf("encode %s evidence: %w", stepID, err)
+		}
+		steps["evidence:"+stepID] = data
+	}
+	return m, steps, nil
+}
+
+// DiffScenarios compares two scenario runs: their outcomes, resolved
+// inputs, and the response captured for each step. Steps are matched on
+// their response file name without the ordering prefix.
+func DiffScenarios(a, b *RunManifest, stepsA, stepsB map[string][]byte) (*DiffReport, error) {
+	if a == nil || b == nil {
+		return nil, fmt.Errorf("both run manifests are required")
+	}
+	r := &DiffReport{
+		RunA:     a.RunID,
+		RunB:     b.RunID,
+		OutcomeA: a.Outcome,
+		OutcomeB: b.Outcome,
+	}
+	r.OutcomeChanged = outcomeKey(a.Outcome) != outcomeKey(b.Outcome)
+	r.Inputs = diffStrings(a.InputsResolved, b.InputsResolved)
+
+	byIDA := stepsByID(stepsA)
+	byIDB := stepsByID(stepsB)
+	for _, id := range sortedKeys(byIDA, byIDB) {
+		before, inA := byIDA[id]
+		after, inB := byIDB[id]
+		switch {
+		case !inB:
+			r.RemovedSteps = append(r.RemovedSteps, id)
+		case !inA:
+			r.AddedSteps = append(r.AddedSteps, id)
+		default:
+			if changes := diffResponses(before, after); len(changes) > 0 {
+				r.ChangedSteps = append(r.ChangedSteps, StepDiff{StepID: id, Changes: changes})
+			}
+		}
+	}
+	return r, nil
+}
+
+func outcomeKey(o *ManifestOutcome) string {
+	if o == nil {
+		return ""
+	}
+	return o.State + "\x00" + o.StepID + "\x00" + o.Recommendation
+}
+
+func stepsByID(steps map[string][]byte) map[string][]byte {
+	out := make(map[string][]byte, len(steps))
+	for key, data := range steps {
+		out[stepOrderPrefix.ReplaceAllString(key, "")] = data
+	}
+	return out
+}
+
+func sortedKeys[V any](maps ...map[string]V) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func diffStrings(a, b map[string]string) []ValueChange {
+	var changes []ValueChange
+	for _, k := range sortedKeys(a, b) {
+		if a[k] != b[k] {
+			changes = append(changes, ValueChange{Path: k, Old: a[k], New: b[k]})
+		}
+	}
+	return changes
+}
+
+// diffResponses compares two step responses leaf by leaf. Responses that
+// are not JSON are compared as whole text under the path ".".
+func diffResponses(a, b []byte) []ValueChange {
+	leavesA, errA := flattenJSON(a)
+	leavesB, errB := flattenJSON(b)
+	if errA != nil || errB != nil {
+		if !bytes.Equal(bytes.TrimSpace(a), bytes.TrimSpace(b)) {
+			return []ValueChange{{Path: ".", Old: string(bytes.TrimSpace(a)), New: string(bytes.TrimSpace(b))}}
+		}
+		return nil
+	}
+	return diffStrings(leavesA, leavesB)
+}
+
+// flattenJSON maps each scalar in a JSON document to its dotted path, with
+// scalars rendered as JSON text. Empty objects and arrays are kept as leaves
+// so that emptying a collection shows up as a change.
+func flattenJSON(data []byte) (map[string]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	leaves := map[string]string{}
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			if len(t) == 0 {
+				leaves[pathOrRoot(path)] = "{}"
+			}
+			for k, child := range t {
+				if path == "" {
+					walk(k, child)
+				} else {
+					walk(path+"."+k, child)
+				}
+			}
+		case []any:
+			if len(t) == 0 {
+				leaves[pathOrRoot(path)] = "[]"
+			}
+			for i, child := range t {
+				walk(path+"["+strconv.Itoa(i)+"]", child)
+			}
+		default:
+			text, _ := json.Marshal(t)
+			leaves[pathOrRoot(path)] = string(text)
+		}
+	}
+	walk("", v)
+	return leaves, nil
+}
+
+func pathOrRoot(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
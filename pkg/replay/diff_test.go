@@ -0,0 +1,157 @@
+package replay
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffScenarios(t *testing.T) {
+	a := &RunManifest{
+		RunID:          "run-a",
+		Outcome:        &ManifestOutcome{State: "resolved", StepID: "done"},
+		InputsResolved: map[string]string{"pod": "web-1", "namespace": "prod"},
+	}
+	b := &RunManifest{
+		RunID:          "run-b",
+		Outcome:        &ManifestOutcome{State: "escalated", StepID: "page"},
+		InputsResolved: map[string]string{"pod": "web-2", "namespace": "prod"},
+	}
+	stepsA := map[string][]byte{
+		"001-get-pod":  []byte(`{"status":{"phase":"Running","restarts":0},"items":[1,2]}`),
+		"002-same":     []byte(`{"ok":true}`),
+		"003-old-step": []byte(`{}`),
+	}
+	stepsB := map[string][]byte{
+		"001-get-pod":  []byte(`{"status":{"phase":"CrashLoopBackOff","restarts":0},"items":[]}`),
+		"004-same":     []byte(`{"ok":true}`),
+		"005-new-step": []byte(`not json`),
+	}
+
+	r, err := DiffScenarios(a, b, stepsA, stepsB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.OutcomeChanged || r.OutcomeB.State != "escalated" {
+		t.Errorf("outcome change not reported: %+v", r)
+	}
+	if len(r.Inputs) != 1 || r.Inputs[0] != (ValueChange{Path: "pod", Old: "web-1", New: "web-2"}) {
+		t.Errorf("inputs = %+v", r.Inputs)
+	}
+	if len(r.AddedSteps) != 1 || r.AddedSteps[0] != "new-step" {
+		t.Errorf("added = %v", r.AddedSteps)
+	}
+	if len(r.RemovedSteps) != 1 || r.RemovedSteps[0] != "old-step" {
+		t.Errorf("removed = %v", r.RemovedSteps)
+	}
+	if len(r.ChangedSteps) != 1 || r.ChangedSteps[0].StepID != "get-pod" {
+		t.Fatalf("changed = %+v, want only get-pod (ordering prefixes are ignored)", r.ChangedSteps)
+	}
+	want := []ValueChange{
+		{Path: "items", New: "[]"},
+		{Path: "items[0]", Old: "1"},
+		{Path: "items[1]", Old: "2"},
+		{Path: "status.phase", Old: `"Running"`, New: `"CrashLoopBackOff"`},
+	}
+	got := r.ChangedSteps[0].Changes
+	if len(got) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	same, _ := DiffScenarios(a, a, stepsA, stepsA)
+	if !same.Empty() {
+		t.Errorf("identical runs should produce an empty report: %+v", same)
+	}
+}
+
+func TestLoadRunDir(t *testing.T) {
+	dir := t.TempDir()
+	manifest := "run_id: 20260101T000000-abcd\nrunbook: restart.yaml\noutcome:\n  state: resolved\n  step_id: done\ninputs_resolved:\n  pod: web-1\n"
+	if err := os.WriteFile(filepath.Join(dir, "run.yaml"), []byte(manifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "steps"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "steps", "001-get-pod.json"), []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, steps, err := LoadRunDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.RunID != "20260101T000000-abcd" || m.Outcome == nil || m.Outcome.State != "resolved" || m.InputsResolved["pod"] != "web-1" {
+		t.Errorf("manifest = %+v", m)
+	}
+	if string(steps["001-get-pod"]) != `{"a":1}` {
+		t.Errorf("steps = %v", steps)
+	}
+}
+
+func TestLoadRunDir_KernelScenario(t *testing.T) {
+	write := func(scenario string) string {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "scenario.yaml"), []byte(scenario), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+	dirA := write(`inputs:
+  pod: web-1
+tool_responses:
+  kubectl:get:
+    - exit_code: 0
+      stdout: Running
+    - exit_code: 0
+      stdout: Running
+evidence:
+  confirm:
+    ok: "yes"
+`)
+	dirB := write(`inputs:
+  pod: web-2
+tool_responses:
+  kubectl:get:
+    - exit_code: 0
+      stdout: Running
+    - exit_code: 1
+      stdout: CrashLoopBackOff
+`)
+
+	a, stepsA, err := LoadRunDir(dirA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, stepsB, err := LoadRunDir(dirB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := DiffScenarios(a, b, stepsA, stepsB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Empty() {
+		t.Fatal("different kernel scenarios reported no differences")
+	}
+	if len(r.Inputs) != 1 || r.Inputs[0] != (ValueChange{Path: "pod", Old: "web-1", New: "web-2"}) {
+		t.Errorf("inputs = %+v", r.Inputs)
+	}
+	if len(r.RemovedSteps) != 1 || r.RemovedSteps[0] != "evidence:confirm" {
+		t.Errorf("removed = %v", r.RemovedSteps)
+	}
+	if len(r.ChangedSteps) != 1 || r.ChangedSteps[0].StepID != "kubectl:get#2" {
+		t.Fatalf("changed = %+v, want the second kubectl:get response", r.ChangedSteps)
+	}
+}
+
+func TestLoadRunDir_Empty(t *testing.T) {
+	if _, _, err := LoadRunDir(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory with no manifest, inputs or scenario")
+	}
+}
@@ -13,8 +13,9 @@ import (
 // Scenario represents a replay scenario file containing pre-recorded
 // CLI command responses and evidence for manual steps.
 type Scenario struct {
-	Commands []ScenarioCommand                              `yaml:"commands"`
-	Evidence map[string]map[string]*providers.EvidenceValue `yaml:"evidence"` // step_id → evidence_name → value
+	Commands  []ScenarioCommand                              `yaml:"commands,omitempty"`
+	Evidence  map[string]map[string]*providers.EvidenceValue `yaml:"evidence,omitempty"`  // step_id → evidence_name → value
+	Recording *Recording                                     `yaml:"recording,omitempty"` // how steps/ files were encoded
 }
 
 // ScenarioCommand is a pre-recorded command with its expected output.
@@ -40,7 +41,7 @@ func ParseScenario(data []byte) (*Scenario, error) {
 	if err := yaml.Unmarshal(data, &s); err != nil {
 		return nil, fmt.Errorf("parse scenario: %w", err)
 	}
-	if len(s.Commands) == 0 && len(s.Evidence) == 0 {
+	if len(s.Commands) == 0 && len(s.Evidence) == 0 && s.Recording == nil {
 		return nil, fmt.Errorf("scenario must have at least one command or evidence entry")
 	}
 	return &s, nil
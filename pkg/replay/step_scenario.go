@@ -104,8 +104,20 @@ type StepScenario struct {
 //   - scenario.yaml (manifest with inputs and step mappings)
 //   - steps/*.json (step responses keyed by filename prefix = step order)
 //
-// If referenceTime is non-zero, timestamps in step responses are rebased.
+// Step files may be gzipped (.json.gz) and/or encrypted (.enc); encrypted
+// files are decrypted with the key in GERT_SCENARIO_KEY. If referenceTime
+// is non-zero, timestamps in step responses are rebased.
 func LoadStepScenario(scenarioDir string, referenceTime time.Time) (*StepScenario, error) {
+	key, err := keyFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return LoadStepScenarioWithKey(scenarioDir, referenceTime, key)
+}
+
+// LoadStepScenarioWithKey is LoadStepScenario with an explicit AES-256 key
+// for encrypted step files.
+func LoadStepScenarioWithKey(scenarioDir string, referenceTime time.Time, key []byte) (*StepScenario, error) {
 	var base *Scenario
 	scenarioFile := filepath.Join(scenarioDir, "scenario.yaml")
 	if data, err := os.ReadFile(scenarioFile); err == nil {
@@ -134,13 +146,17 @@ func LoadStepScenario(scenarioDir string, referenceTime time.Time) (*StepScenari
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+		if entry.IsDir() || !isStepFile(entry.Name()) {
 			continue
 		}
 		data, err := os.ReadFile(filepath.Join(stepsDir, entry.Name()))
 		if err != nil {
 			return nil, fmt.Errorf("read step response %q: %w", entry.Name(), err)
 		}
+		name, data, err := DecodeStepFile(entry.Name(), data, key)
+		if err != nil {
+			return nil, fmt.Errorf("read step response: %w", err)
+		}
 		if rebaser != nil {
 			rebased, err := rebaser.RebaseJSON(data)
 			if err != nil {
@@ -148,8 +164,7 @@ func LoadStepScenario(scenarioDir string, referenceTime time.Time) (*StepScenari
 			}
 			data = rebased
 		}
-		key := strings.TrimSuffix(entry.Name(), ".json")
-		stepResponses[key] = json.RawMessage(data)
+		stepResponses[strings.TrimSuffix(name, ".json")] = json.RawMessage(data)
 	}
 
 	return &StepScenario{
@@ -159,6 +174,13 @@ func LoadStepScenario(scenarioDir string, referenceTime time.Time) (*StepScenari
 	}, nil
 }
 
+// isStepFile reports whether name is a step response, plain or encoded.
+func isStepFile(name string) bool {
+	name = strings.TrimSuffix(name, ".enc")
+	name = strings.TrimSuffix(name, ".gz")
+	return strings.HasSuffix(name, ".json")
+}
+
 // FindStepResponse looks up a step response by step ID.
 // It matches against filenames using a suffix match (the filename prefix is the order number).
 // E.g., step_id "check_login_failures_kusto" matches "001-check-login-failures-kusto".
@@ -0,0 +1,90 @@
+// Package runs manages run directories: one per run, each holding a
+// run.yaml manifest (runs/ for gert exec, .runbook/runs for serve sessions).
+package runs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultTTL is how long a finished run directory is kept.
+const DefaultTTL = 7 * 24 * time.Hour
+
+// Expired is a run directory old enough to be removed.
+type Expired struct {
+	RunID   string
+	Dir     string
+	EndedAt time.Time
+}
+
+// manifest is the subset of run.yaml expiry needs; the kernel and runtime
+// manifests both record ended_at.
+type manifest struct {
+	EndedAt string `yaml:"ended_at"`
+}
+
+// FindExpired lists the run directories under dir that ended more than ttl
+// before now, oldest first. A run counts as finished only when its run.yaml
+// records ended_at; a directory without one, or with a session.json (a
+// serve session that can still be resumed), is never listed.
+func FindExpired(dir string, ttl time.Duration, now time.Time) ([]Expired, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read runs: %w", err)
+	}
+
+	var expired []Expired
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		runDir := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(runDir, "session.json")); err == nil {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(runDir, "run.yaml"))
+		if err != nil {
+			continue
+		}
+		var m manifest
+		if err := yaml.Unmarshal(data, &m); err != nil || m.EndedAt == "" {
+			continue
+		}
+		ended, err := time.Parse(time.RFC3339, m.EndedAt)
+		if err != nil || now.Sub(ended) <= ttl {
+			continue
+		}
+		expired = append(expired, Expired{RunID: entry.Name(), Dir: runDir, EndedAt: ended})
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].EndedAt.Before(expired[j].EndedAt)
+	})
+	return expired, nil
+}
+
+// Clean removes the run directories FindExpired reports and returns them.
+// With dryRun it only reports them. It stops at the first directory that
+// cannot be removed, returning the ones removed so far.
+func Clean(dir string, ttl time.Duration, dryRun bool) ([]Expired, error) {
+	expired, err := FindExpired(dir, ttl, time.Now())
+	if err != nil || dryRun {
+		return expired, err
+	}
+	for i, e := range expired {
+		if err := os.RemoveAll(e.Dir); err != nil {
+			return expired[:i], fmt.Errorf("remove run %s: %w", e.RunID, err)
+		}
+	}
+	return expired, nil
+}
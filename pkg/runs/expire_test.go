@@ -0,0 +1,76 @@
+package runs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRun(t *testing.T, dir, runID, endedAt string, session bool) {
+	t.Helper()
+	runDir := filepath.Join(dir, runID)
+	if err := os.MkdirAll(runDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := "run_id: " + runID + "\nstarted_at: \"2026-01-01T00:00:00Z\"\n"
+	if endedAt != "" {
+		manifest += "ended_at: \"" + endedAt + "\"\n"
+	}
+	os.WriteFile(filepath.Join(runDir, "run.yaml"), []byte(manifest), 0o644)
+	if session {
+		os.WriteFile(filepath.Join(runDir, "session.json"), []byte("{}"), 0o644)
+	}
+}
+
+func TestFindExpired(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	writeRun(t, dir, "old", "2026-02-01T10:00:00Z", false)
+	writeRun(t, dir, "older", "2026-01-15T10:00:00Z", false)
+	writeRun(t, dir, "recent", "2026-02-28T10:00:00Z", false)
+	writeRun(t, dir, "running", "", false)
+	writeRun(t, dir, "session", "2026-01-10T10:00:00Z", true)
+	writeRun(t, dir, "garbled", "yesterday", false)
+	os.MkdirAll(filepath.Join(dir, "no-manifest"), 0o755)
+
+	expired, err := FindExpired(dir, DefaultTTL, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range expired {
+		ids = append(ids, e.RunID)
+	}
+	if len(ids) != 2 || ids[0] != "older" || ids[1] != "old" {
+		t.Errorf("expired = %v, want [older old]", ids)
+	}
+
+	if expired, err := FindExpired(filepath.Join(dir, "missing"), DefaultTTL, now); err != nil || len(expired) != 0 {
+		t.Errorf("missing dir: %v, %v", expired, err)
+	}
+}
+
+func TestClean(t *testing.T) {
+	dir := t.TempDir()
+	writeRun(t, dir, "old", time.Now().Add(-48*time.Hour).UTC().Format(time.RFC3339), false)
+	writeRun(t, dir, "new", time.Now().UTC().Format(time.RFC3339), false)
+
+	expired, err := Clean(dir, 24*time.Hour, true)
+	if err != nil || len(expired) != 1 {
+		t.Fatalf("dry run = %v, %v", expired, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old")); err != nil {
+		t.Error("dry run removed the run")
+	}
+
+	if _, err := Clean(dir, 24*time.Hour, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old")); !os.IsNotExist(err) {
+		t.Error("expired run was not removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new")); err != nil {
+		t.Error("recent run was removed")
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"crypto/rand"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -17,6 +19,7 @@ import (
 	"github.com/ormasoftchile/gert/pkg/assertions"
 	"github.com/ormasoftchile/gert/pkg/evidence"
 	"github.com/ormasoftchile/gert/pkg/governance"
+	"github.com/ormasoftchile/gert/pkg/logging"
 	"github.com/ormasoftchile/gert/pkg/providers"
 	"github.com/ormasoftchile/gert/pkg/replay"
 	"github.com/ormasoftchile/gert/pkg/schema"
@@ -56,12 +59,27 @@ type Engine struct {
 	ChainDepth  int                 // current chain depth (0 = root)
 	ParentRunID string              // parent run ID (if chained)
 	ChildRuns   []ChildRunRef       // child runs spawned by this engine
+	routed      map[string]bool     // steps whose on_failure has fired
+	Logger      *slog.Logger        // diagnostics; defaults to text on stderr
 }
 
-// NewEngine creates a new engine for executing a runbook.
+// DefaultRunsDir is where NewEngine creates run directories.
+var DefaultRunsDir = filepath.Join(".runbook", "runs")
+
+// NewEngine creates a new engine for executing a runbook, with its run
+// directory under DefaultRunsDir.
 func NewEngine(rb *schema.Runbook, executor providers.CommandExecutor, collector providers.EvidenceCollector, mode string, actor string) (*Engine, error) {
+	return NewEngineInDir(rb, executor, collector, mode, actor, DefaultRunsDir)
+}
+
+// NewEngineInDir creates a new engine whose run directory is
+// <runsDir>/<run_id>. It fails early when runsDir is not writable.
+func NewEngineInDir(rb *schema.Runbook, executor providers.CommandExecutor, collector providers.EvidenceCollector, mode string, actor string, runsDir string) (*Engine, error) {
+	if err := checkWritable(runsDir); err != nil {
+		return nil, err
+	}
 	runID := GenerateRunID()
-	baseDir := filepath.Join(".runbook", "runs", runID)
+	baseDir := filepath.Join(runsDir, runID)
 
 	// Create directory structure
 	for _, sub := range []string{"snapshots", "attachments"} {
@@ -108,13 +126,14 @@ func NewEngine(rb *schema.Runbook, executor providers.CommandExecutor, collector
 
 	// Initialize XTS provider if runbook has xts config
 	// Initialize XTS provider (legacy path — provides CLIPath for tool registration)
+	logger := logging.Default()
 	var xtsProv *providers.XTSProvider
 	if rb.Meta.XTS != nil {
 		var err2 error
 		xtsProv, err2 = providers.NewXTSProvider(rb.Meta.XTS)
 		if err2 != nil {
 			// Non-fatal: warn but allow non-xts steps to run
-			fmt.Fprintf(os.Stderr, "warning: XTS provider init failed: %v\n", err2)
+			logger.Warn("XTS provider init failed", slog.Any("error", err2))
 		}
 	}
 
@@ -128,6 +147,7 @@ func NewEngine(rb *schema.Runbook, executor providers.CommandExecutor, collector
 		Trace:       trace,
 		BaseDir:     baseDir,
 		xtsProvider: xtsProv,
+		Logger:      logger,
 	}, nil
 }
 
@@ -143,7 +163,8 @@ func (e *Engine) Run(ctx context.Context) error {
 
 // runTree recursively walks the tree, executing steps and evaluating branches.
 func (e *Engine) runTree(ctx context.Context, nodes []schema.TreeNode) error {
-	for _, node := range nodes {
+	for i := 0; i < len(nodes); i++ {
+		node := nodes[i]
 		step := node.Step
 		stepIdx := e.stepCounts.Total
 
@@ -168,14 +189,16 @@ func (e *Engine) runTree(ctx context.Context, nodes []schema.TreeNode) error {
 		}
 
 		// Merge captures
-		for k, v := range result.Captures {
-			e.State.Captures[k] = v
-		}
+		e.mergeCaptures(step, result.Captures)
 
 		if result.Status == "failed" {
 			e.stepCounts.Failed++
 			e.stepCounts.Total++
 			fmt.Printf("  ✗ Step %q failed: %s\n", step.ID, result.Error)
+			if j := e.routeFailure(step, result, treeStepIDs(nodes)); j >= 0 {
+				i = j - 1
+				continue
+			}
 			return fmt.Errorf("step %q failed: %s", step.ID, result.Error)
 		}
 
@@ -236,6 +259,45 @@ func (e *Engine) runTree(ctx context.Context, nodes []schema.TreeNode) error {
 	return nil
 }
 
+// routeFailure returns the index in scope (the step IDs of the failed
+// step's block) of its on_failure target, or -1 to halt. The failure message
+// is captured as {{ .<step>.error }}. Each step routes at most once, so a
+// failing target cannot loop back.
+func (e *Engine) routeFailure(step schema.Step, result *providers.StepResult, scope []string) int {
+	if step.OnFailure == "" || e.routed[step.ID] {
+		return -1
+	}
+	for j, id := range scope {
+		if id != step.OnFailure {
+			continue
+		}
+		if e.routed == nil {
+			e.routed = make(map[string]bool)
+		}
+		e.routed[step.ID] = true
+		e.State.Captures[step.ID+".error"] = result.Error
+		fmt.Printf("  ↪ %s failed, continuing at %s\n", step.ID, step.OnFailure)
+		return j
+	}
+	return -1
+}
+
+func flatStepIDs(steps []schema.Step) []string {
+	ids := make([]string, len(steps))
+	for i, s := range steps {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+func treeStepIDs(nodes []schema.TreeNode) []string {
+	ids := make([]string, len(nodes))
+	for i, n := range nodes {
+		ids[i] = n.Step.ID
+	}
+	return ids
+}
+
 // runFlat executes flat steps[] (backward compatibility).
 func (e *Engine) runFlat(ctx context.Context) error {
 
@@ -292,15 +354,17 @@ func (e *Engine) runFlat(ctx context.Context) error {
 		}
 
 		// Merge captures into engine state
-		for k, v := range result.Captures {
-			e.State.Captures[k] = v
-		}
+		e.mergeCaptures(step, result.Captures)
 
 		// Halt on failure
 		if result.Status == "failed" {
 			e.stepCounts.Failed++
 			e.stepCounts.Total++
 			fmt.Printf("  ✗ Step %q failed: %s\n", step.ID, result.Error)
+			if j := e.routeFailure(step, result, flatStepIDs(e.Runbook.Steps)); j >= 0 {
+				i = j - 1 // -1 because loop will i++
+				continue
+			}
 			fmt.Printf("  Artifacts: %s\n", e.BaseDir)
 			fmt.Printf("  Resume with: gert exec <runbook> --resume %s\n", e.State.RunID)
 			return fmt.Errorf("step %q failed: %s", step.ID, result.Error)
@@ -412,6 +476,7 @@ func (e *Engine) chainToRunbook(ctx context.Context, outcome schema.Outcome) err
 	childEngine.RunbookPath = resolvedFile
 	childEngine.ChainDepth = depth
 	childEngine.ParentRunID = e.State.RunID
+	childEngine.Logger = e.Logger
 
 	// Inherit XTS provider and scenario
 	childEngine.xtsProvider = e.xtsProvider
@@ -472,7 +537,7 @@ func (e *Engine) executeInvokeStep(ctx context.Context, step schema.Step, result
 		resolvedFile = filepath.Join(filepath.Dir(e.RunbookPath), resolvedFile)
 	}
 
-	fmt.Fprintf(os.Stderr, "  → Invoking child runbook: %s\n", resolvedFile)
+	e.Logger.Info("invoking child runbook", slog.String("stepId", step.ID), slog.String("runbook", resolvedFile))
 
 	// Load and validate child runbook
 	childRB, errs := schema.ValidateFile(resolvedFile)
@@ -510,12 +575,13 @@ func (e *Engine) executeInvokeStep(ctx context.Context, step schema.Step, result
 	childEngine.RunbookPath = resolvedFile
 	childEngine.ChainDepth = depth
 	childEngine.ParentRunID = e.State.RunID
+	childEngine.Logger = e.Logger
 
 	// Inherit XTS provider and scenario
 	childEngine.xtsProvider = e.xtsProvider
 	childEngine.XTSScenario = e.XTSScenario
 
-	fmt.Fprintf(os.Stderr, "  Child Run ID: %s (depth: %d)\n", childEngine.GetRunID(), depth)
+	e.Logger.Info("child run started", slog.String("runId", childEngine.GetRunID()), slog.Int("depth", depth))
 
 	// Run child runbook to completion
 	childErr := childEngine.Run(ctx)
@@ -537,7 +603,7 @@ func (e *Engine) executeInvokeStep(ctx context.Context, step schema.Step, result
 	// Handle child execution error
 	if childErr != nil {
 		if step.Gate != nil && step.Gate.OnError == "skip" {
-			fmt.Fprintf(os.Stderr, "  ⊘ Child runbook errored but gate.on_error=skip: %v\n", childErr)
+			e.Logger.Warn("child runbook errored, skipped by gate.on_error", slog.String("stepId", step.ID), slog.Any("error", childErr))
 			result.Status = "skipped"
 			result.Error = fmt.Sprintf("child runbook error (skipped via gate): %v", childErr)
 			result.Actor = "engine"
@@ -552,7 +618,7 @@ func (e *Engine) executeInvokeStep(ctx context.Context, step schema.Step, result
 	if step.Gate != nil && len(step.Gate.StopIf) > 0 {
 		for _, stopState := range step.Gate.StopIf {
 			if childOutcome == stopState {
-				fmt.Fprintf(os.Stderr, "  ■ Gate triggered: child outcome %q matches stop_if\n", childOutcome)
+				e.Logger.Info("gate triggered: child outcome matches stop_if", slog.String("stepId", step.ID), slog.String("outcome", childOutcome))
 				result.Status = "failed"
 				result.Error = fmt.Sprintf("gate: child outcome %q matches stop_if", childOutcome)
 				// Propagate child outcome to parent
@@ -575,7 +641,7 @@ func (e *Engine) executeInvokeStep(ctx context.Context, step schema.Step, result
 
 	result.Status = "passed"
 	result.Actor = "engine"
-	fmt.Fprintf(os.Stderr, "  ✓ Child runbook completed: outcome=%s\n", childOutcome)
+	e.Logger.Info("child runbook completed", slog.String("stepId", step.ID), slog.String("outcome", childOutcome))
 }
 
 // executeStep runs a single step based on its type.
@@ -603,12 +669,86 @@ func (e *Engine) executeStep(ctx context.Context, index int, step schema.Step) (
 				result.Actor = "engine"
 				result.EndedAt = time.Now()
 				result.Error = msg
-				fmt.Fprintf(os.Stderr, "  ⊘ Precondition satisfied for %q: %s\n", step.ID, msg)
+				e.Logger.Info("precondition satisfied, step skipped", slog.String("stepId", step.ID), slog.String("reason", msg))
 				return result, nil
 			}
 		}
 	}
 
+	// Probe mode: run read-only steps, skip anything that may write
+	if e.State.Mode == "probe" && e.IsWriteEffectStep(step) {
+		result.Status = "skipped"
+		result.Actor = "engine"
+		result.EndedAt = time.Now()
+		result.Error = ProbeSkipReason
+		fmt.Printf("  ⊘ [probe] Skipping %s: write-effect step\n", step.ID)
+		return result, nil
+	}
+
+	attempts := 1
+	if step.Retry != nil && step.Retry.Max > 1 {
+		attempts = step.Retry.Max
+	}
+	for attempt := 1; ; attempt++ {
+		e.dispatchStep(ctx, step, result)
+		if result.Status != "failed" || attempt >= attempts {
+			break
+		}
+
+		delay := getRetryDelay(step.Retry, attempt)
+		if err := e.Trace.WriteRetry(result, attempt); err != nil {
+			return nil, fmt.Errorf("write retry trace: %w", err)
+		}
+		fmt.Printf("  ↻ Attempt %d/%d failed: %s — retrying in %s\n", attempt, attempts, result.Error, delay)
+		select {
+		case <-ctx.Done():
+			result.EndedAt = time.Now()
+			return result, nil
+		case <-time.After(delay):
+		}
+
+		// Reset per-attempt state; only the final attempt's captures are kept
+		result.Status = ""
+		result.Error = ""
+		result.Captures = make(map[string]string)
+		result.Assertions = nil
+		result.Evidence = nil
+	}
+
+	result.EndedAt = time.Now()
+	return result, nil
+}
+
+// ProbeSkipReason is recorded on steps skipped in probe mode.
+const ProbeSkipReason = "probe: write-effect step"
+
+// IsWriteEffectStep reports whether a step may mutate external state. Only tool
+// actions governed as read_only are safe; cli steps carry no effect metadata
+// and are treated as writes. Manual and invoke steps run (children inherit
+// probe mode).
+func (e *Engine) IsWriteEffectStep(step schema.Step) bool {
+	switch step.Type {
+	case "manual", "invoke":
+		return false
+	case "tool":
+		if step.Tool == nil || e.ToolManager == nil {
+			return true
+		}
+		td := e.ToolManager.GetDef(step.Tool.Name)
+		if td == nil {
+			return true
+		}
+		if act, ok := td.Actions[step.Tool.Action]; ok && act.Governance != nil && act.Governance.ReadOnly {
+			return false
+		}
+		return td.Governance == nil || !td.Governance.ReadOnly
+	default:
+		return true
+	}
+}
+
+// dispatchStep runs one attempt of a step, applying the step timeout.
+func (e *Engine) dispatchStep(ctx context.Context, step schema.Step, result *providers.StepResult) {
 	// Create step context with timeout
 	stepCtx := ctx
 	if step.Type == "cli" {
@@ -647,9 +787,6 @@ func (e *Engine) executeStep(ctx context.Context, index int, step schema.Step) (
 		result.Status = "failed"
 		result.Error = fmt.Sprintf("unknown step type: %q", step.Type)
 	}
-
-	result.EndedAt = time.Now()
-	return result, nil
 }
 
 // executeCLIStep handles CLI step execution.
@@ -670,15 +807,40 @@ func (e *Engine) executeCLIStep(ctx context.Context, step schema.Step, result *p
 		return
 	}
 
-	// Governance: check command against allowlist/denylist
-	if err := e.Gov.CheckCommand(resolvedArgv[0]); err != nil {
+	// Governance: check command against allowlist/denylist and rules
+	decision := e.Gov.EvaluateArgv(resolvedArgv)
+	commandLine := strings.Join(resolvedArgv, " ")
+	if len(e.Redact) > 0 {
+		commandLine = governance.RedactOutput(commandLine, e.Redact)
+		decision.Reason = governance.RedactOutput(decision.Reason, e.Redact)
+	}
+	if err := e.Trace.WriteGovernanceCheck(e.State.RunID, step.ID, commandLine, decision); err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		return
+	}
+	if !decision.Allowed {
 		result.Status = "failed"
-		result.Error = fmt.Sprintf("governance: %v", err)
+		result.Error = fmt.Sprintf("governance: %s", decision.Reason)
 		return
 	}
 
+	env, redactedEnv, err := e.resolveStepEnv(step.With.Env)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		return
+	}
+	result.Env = redactedEnv
+
 	// Execute command (real, replay, or dry-run based on injected executor)
-	cmdResult, err := e.Executor.Execute(ctx, resolvedArgv[0], resolvedArgv[1:], nil)
+	executor, err := e.stepExecutor(step)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		return
+	}
+	cmdResult, err := executor.Execute(ctx, resolvedArgv[0], resolvedArgv[1:], env)
 	if err != nil {
 		result.Status = "failed"
 		result.Error = fmt.Sprintf("execute: %v", err)
@@ -706,7 +868,7 @@ func (e *Engine) executeCLIStep(ctx context.Context, step schema.Step, result *p
 	// Evaluate assertions
 	allPassed := true
 	for _, a := range step.Assertions {
-		ar := assertions.Evaluate(a, stdout, cmdResult.ExitCode)
+		ar := assertions.Evaluate(a, stdout, cmdResult.ExitCode, time.Since(result.StartedAt))
 		result.Assertions = append(result.Assertions, ar)
 		if !ar.Passed {
 			allPassed = false
@@ -721,6 +883,30 @@ func (e *Engine) executeCLIStep(ctx context.Context, step schema.Step, result *p
 	}
 }
 
+// stepExecutor returns the executor for a CLI step. In real mode a step with
+// with.executor runs over SSH, its output streamed into the trace; other
+// modes keep the injected executor so dry-runs and replays stay local.
+func (e *Engine) stepExecutor(step schema.Step) (providers.CommandExecutor, error) {
+	if step.With.Executor == "" || e.State.Mode != "real" {
+		return e.Executor, nil
+	}
+	target, err := e.resolveTemplate(step.With.Executor)
+	if err != nil {
+		return nil, fmt.Errorf("resolve executor: %v", err)
+	}
+	ssh, err := providers.ParseSSHExecutor(target)
+	if err != nil {
+		return nil, err
+	}
+	ssh.Output = func(stream string, data []byte) {
+		if len(e.Redact) > 0 {
+			data = []byte(governance.RedactOutput(string(data), e.Redact))
+		}
+		e.Trace.WriteOutput(e.State.RunID, step.ID, stream, data)
+	}
+	return ssh, nil
+}
+
 // executeToolStep handles type:tool step execution via the tool manager.
 func (e *Engine) executeToolStep(ctx context.Context, step schema.Step, result *providers.StepResult) {
 	result.Actor = "engine"
@@ -737,8 +923,14 @@ func (e *Engine) executeToolStep(ctx context.Context, step schema.Step, result *
 		return
 	}
 
-	// Resolve template expressions in tool args
+	// Seed args from inputs_from, then resolve template expressions in tool
+	// args; explicit args win over merged inputs
 	resolvedArgs := make(map[string]string)
+	if err := e.mergeInputsFrom(step, resolvedArgs); err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		return
+	}
 	for k, v := range step.Tool.Args {
 		resolved, err := e.resolveTemplate(v)
 		if err != nil {
@@ -749,6 +941,17 @@ func (e *Engine) executeToolStep(ctx context.Context, step schema.Step, result *
 		resolvedArgs[k] = resolved
 	}
 
+	env, redactedEnv, err := e.resolveStepEnv(step.Tool.Env)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		return
+	}
+	result.Env = redactedEnv
+	if len(env) > 0 {
+		ctx = tools.WithEnv(ctx, env)
+	}
+
 	// Build vars map from engine state for argv template resolution
 	vars := make(map[string]string)
 	for k, v := range e.State.Vars {
@@ -772,8 +975,8 @@ func (e *Engine) executeToolStep(ctx context.Context, step schema.Step, result *
 		if approvalMin < 1 {
 			approvalMin = 1
 		}
-		fmt.Fprintf(os.Stderr, "  ⚠ Tool action %s.%s requires approval (min=%d)\n",
-			step.Tool.Name, step.Tool.Action, approvalMin)
+		e.Logger.Warn("tool action requires approval", slog.String("stepId", step.ID),
+			slog.String("tool", step.Tool.Name), slog.String("action", step.Tool.Action), slog.Int("minApprovers", approvalMin))
 		approvals, err := e.Collector.PromptApproval(nil, approvalMin)
 		if err != nil || len(approvals) < approvalMin {
 			result.Status = "failed"
@@ -809,7 +1012,7 @@ func (e *Engine) executeToolStep(ctx context.Context, step schema.Step, result *
 	// Evaluate assertions against stdout
 	allPassed := true
 	for _, a := range step.Assertions {
-		ar := assertions.Evaluate(a, stdout, actionResult.ExitCode)
+		ar := assertions.Evaluate(a, stdout, actionResult.ExitCode, time.Since(result.StartedAt))
 		result.Assertions = append(result.Assertions, ar)
 		if !ar.Passed {
 			allPassed = false
@@ -830,6 +1033,72 @@ func (e *Engine) executeToolStep(ctx context.Context, step schema.Step, result *
 	}
 }
 
+// mergeInputsFrom merges the JSON objects named by step.inputs_from into args,
+// in order, later entries winning. An entry is the ID of an earlier step, whose
+// captures that hold a JSON object are merged, or the name of a capture.
+func (e *Engine) mergeInputsFrom(step schema.Step, args map[string]string) error {
+	sources, err := step.InputsFromSources()
+	if err != nil {
+		return err
+	}
+	for _, src := range sources {
+		objects, err := e.inputsFromSource(src)
+		if err != nil {
+			return fmt.Errorf("inputs_from %q: %w", src, err)
+		}
+		for _, obj := range objects {
+			for k, v := range obj {
+				if s, ok := v.(string); ok {
+					args[k] = s
+					continue
+				}
+				data, err := json.Marshal(v)
+				if err != nil {
+					return fmt.Errorf("inputs_from %q: key %q: %w", src, k, err)
+				}
+				args[k] = string(data)
+			}
+		}
+	}
+	return nil
+}
+
+// inputsFromSource returns the JSON objects an inputs_from entry refers to.
+func (e *Engine) inputsFromSource(src string) ([]map[string]any, error) {
+	for i := len(e.State.History) - 1; i >= 0; i-- {
+		h := e.State.History[i]
+		if h.StepID != src {
+			continue
+		}
+		names := make([]string, 0, len(h.Captures))
+		for name := range h.Captures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var objects []map[string]any
+		for _, name := range names {
+			var obj map[string]any
+			if json.Unmarshal([]byte(h.Captures[name]), &obj) == nil {
+				objects = append(objects, obj)
+			}
+		}
+		if len(objects) == 0 {
+			return nil, fmt.Errorf("step captured no JSON object")
+		}
+		return objects, nil
+	}
+
+	raw, ok := e.State.Captures[src]
+	if !ok {
+		return nil, fmt.Errorf("no earlier step or capture with this name")
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		return nil, fmt.Errorf("capture is not a JSON object: %w", err)
+	}
+	return []map[string]any{obj}, nil
+}
+
 // executeManualStep handles manual step execution.
 func (e *Engine) executeManualStep(ctx context.Context, step schema.Step, result *providers.StepResult) {
 	result.Actor = "human"
@@ -924,7 +1193,7 @@ func (e *Engine) executeManualStep(ctx context.Context, step schema.Step, result
 		}
 		if !valid && len(step.Choices.Options) > 0 {
 			// Accept it anyway but log a warning
-			fmt.Fprintf(os.Stderr, "  warning: choice value %q not in options for %q\n", text, step.Choices.Variable)
+			e.Logger.Warn("choice value not in options", slog.String("stepId", step.ID), slog.String("variable", step.Choices.Variable), slog.String("value", text))
 		}
 		// Store the choice as a variable and capture
 		e.State.Vars[step.Choices.Variable] = text
@@ -1079,7 +1348,7 @@ func (e *Engine) executeXTSStep(ctx context.Context, step schema.Step, result *p
 		os.MkdirAll(stepsDir, 0755)
 		stepFile := filepath.Join(stepsDir, fmt.Sprintf("%03d-%s.json", result.StepIndex, strings.ReplaceAll(step.ID, "_", "-")))
 		if err := os.WriteFile(stepFile, xtsResult.RawResponse, 0644); err != nil {
-			fmt.Fprintf(os.Stderr, "  warning: failed to save step response: %v\n", err)
+			e.Logger.Warn("failed to save step response", slog.String("stepId", step.ID), slog.Any("error", err))
 		}
 	}
 }
@@ -1097,6 +1366,39 @@ func (e *Engine) resolveArgv(argv []string) ([]string, error) {
 	return resolved, nil
 }
 
+// resolveStepEnv resolves the templates in a step's env map into sorted
+// KEY=value entries, failing on names denied by governance.deny_env_vars.
+// The second result holds the values with redaction rules applied, for the
+// trace.
+func (e *Engine) resolveStepEnv(env map[string]string) ([]string, map[string]string, error) {
+	if len(env) == 0 {
+		return nil, nil, nil
+	}
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := make([]string, 0, len(names))
+	redacted := make(map[string]string, len(names))
+	for _, name := range names {
+		if err := e.Gov.CheckEnvVar(name); err != nil {
+			return nil, nil, fmt.Errorf("governance: %v", err)
+		}
+		val, err := e.resolveTemplate(env[name])
+		if err != nil {
+			return nil, nil, fmt.Errorf("resolve env %s: %v", name, err)
+		}
+		resolved = append(resolved, name+"="+val)
+		if len(e.Redact) > 0 {
+			val = governance.RedactOutput(val, e.Redact)
+		}
+		redacted[name] = val
+	}
+	return resolved, redacted, nil
+}
+
 // checkUnresolvedVars scans a step's templates for {{ .varName }} references and
 // returns warnings for any that are empty or missing in the current state. This
 // catches misconfigured inputs early with a clear message instead of a cryptic
@@ -1151,15 +1453,57 @@ func (e *Engine) checkUnresolvedVars(step schema.Step) []string {
 	return warnings
 }
 
+// mergeCaptures copies a step's captures into engine state. The flat name is
+// always set (last writer wins); namespaced steps additionally store each
+// capture as "<stepID>.<name>" so it survives later steps reusing the name.
+func (e *Engine) mergeCaptures(step schema.Step, captures map[string]string) {
+	namespaced := e.captureNamespaced(step)
+	for k, v := range captures {
+		e.State.Captures[k] = v
+		if namespaced {
+			e.State.Captures[step.ID+"."+k] = v
+		}
+	}
+}
+
+// captureNamespaced reports whether a step's captures are stored under its ID,
+// either via step.capture_as_namespace or meta.defaults.default_capture_namespace.
+func (e *Engine) captureNamespaced(step schema.Step) bool {
+	if step.ID == "" {
+		return false
+	}
+	if step.CaptureAsNamespace {
+		return true
+	}
+	return e.Runbook.Meta.Defaults != nil && e.Runbook.Meta.Defaults.DefaultCaptureNamespace
+}
+
 // buildEnv merges vars and captures into a single map for template/expr evaluation.
+// Namespaced captures ("step1.status") are nested as data["step1"]["status"] so
+// both {{ .step1.status }} and step1.status resolve.
 func (e *Engine) buildEnv() map[string]interface{} {
 	data := make(map[string]interface{})
 	for k, v := range e.State.Vars {
 		data[k] = v
 	}
 	for k, v := range e.State.Captures {
+		if strings.Contains(k, ".") {
+			continue
+		}
 		data[k] = parseCapture(v)
 	}
+	for k, v := range e.State.Captures {
+		ns, name, ok := strings.Cut(k, ".")
+		if !ok {
+			continue
+		}
+		if _, exists := data[ns]; !exists {
+			data[ns] = make(map[string]interface{})
+		}
+		if m, isMap := data[ns].(map[string]interface{}); isMap {
+			m[name] = parseCapture(v)
+		}
+	}
 	return data
 }
 
@@ -1295,6 +1639,26 @@ func parseDuration(s string) (time.Duration, error) {
 	return time.ParseDuration(s)
 }
 
+// getRetryDelay returns the wait after the given failed attempt (1-based),
+// scaling the base delay by the configured backoff strategy.
+func getRetryDelay(retry *schema.RetryConfig, attempt int) time.Duration {
+	if retry == nil || retry.Delay == "" {
+		return 0
+	}
+	d, err := parseDuration(retry.Delay)
+	if err != nil {
+		return 0
+	}
+	switch retry.Backoff {
+	case "exponential":
+		return d * time.Duration(1<<(attempt-1))
+	case "linear":
+		return d * time.Duration(attempt)
+	default:
+		return d
+	}
+}
+
 // ExecuteStep runs a single step by index and returns the result.
 // This is the public entry point used by the debugger.
 func (e *Engine) ExecuteStep(ctx context.Context, index int) (*providers.StepResult, error) {
@@ -1314,9 +1678,7 @@ func (e *Engine) ExecuteStep(ctx context.Context, index int) (*providers.StepRes
 
 	// Save snapshot
 	e.State.History = append(e.State.History, result)
-	for k, v := range result.Captures {
-		e.State.Captures[k] = v
-	}
+	e.mergeCaptures(step, result.Captures)
 	e.State.CurrentStepIndex = index + 1
 	snapshotPath := filepath.Join(e.BaseDir, "snapshots", fmt.Sprintf("step-%04d.json", index))
 	if err := SaveSnapshot(e.State, snapshotPath); err != nil {
@@ -1350,15 +1712,21 @@ func (e *Engine) ResolveTemplatePublic(tmpl string) string {
 func (e *Engine) EvalConditionPublic(condition string) bool {
 	result, err := e.evalCondition(condition)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "  [condition error] %s: %v\n", condition, err)
+		e.Logger.Error("condition evaluation failed", slog.String("condition", condition), slog.Any("error", err))
 		return false
 	}
 	return result
 }
 
+// RouteFailurePublic exposes on_failure routing for the serve package. It
+// returns the index in scope of the failed step's target, or -1.
+func (e *Engine) RouteFailurePublic(step schema.Step, result *providers.StepResult, scope []schema.TreeNode) int {
+	return e.routeFailure(step, result, treeStepIDs(scope))
+}
+
 // BuildManifest produces a RunManifest from the current engine state.
 func (e *Engine) BuildManifest() *RunManifest {
-	return &RunManifest{
+	m := &RunManifest{
 		RunID:          e.State.RunID,
 		ICMID:          e.ICMID,
 		Runbook:        e.RunbookPath,
@@ -1372,19 +1740,34 @@ func (e *Engine) BuildManifest() *RunManifest {
 		ParentRunID:    e.ParentRunID,
 		ChildRuns:      e.ChildRuns,
 	}
+	if rc, ok := e.Collector.(providers.RemoteEvidenceCollector); ok {
+		m.EvidenceURL = rc.EvidenceURL()
+	}
+	return m
 }
 
-// WriteManifest writes run.yaml to the run artifacts directory.
+// WriteManifest writes run.yaml to the run artifacts directory. When
+// GERT_SIGNING_KEY is set, it also signs it into run.yaml.sig.
 func (e *Engine) WriteManifest() error {
 	m := e.BuildManifest()
 	data, err := yaml.Marshal(m)
 	if err != nil {
 		return fmt.Errorf("marshal manifest: %w", err)
 	}
-	path := filepath.Join(e.BaseDir, "run.yaml")
+	path := filepath.Join(e.BaseDir, evidence.ManifestFile)
 	if err := os.WriteFile(path, data, 0644); err != nil {
 		return fmt.Errorf("write manifest: %w", err)
 	}
+
+	key, err := evidence.SigningKeyFromEnv()
+	if err != nil {
+		return fmt.Errorf("sign manifest: %w", err)
+	}
+	if key != nil {
+		if _, err := evidence.SignManifest(e.BaseDir, key); err != nil {
+			return fmt.Errorf("sign manifest: %w", err)
+		}
+	}
 	return nil
 }
 
@@ -1404,9 +1787,7 @@ func (e *Engine) ExecuteTreeStep(ctx context.Context, index int, step schema.Ste
 
 	// Save to history and merge captures
 	e.State.History = append(e.State.History, result)
-	for k, v := range result.Captures {
-		e.State.Captures[k] = v
-	}
+	e.mergeCaptures(step, result.Captures)
 
 	// Save snapshot
 	snapshotPath := filepath.Join(e.BaseDir, "snapshots", fmt.Sprintf("step-%04d.json", index))
@@ -1427,14 +1808,35 @@ func (e *Engine) ExecuteTreeStep(ctx context.Context, index int, step schema.Ste
 
 // SaveScenario writes the current run's inputs and XTS step responses to a
 // replay scenario folder. The folder will contain inputs.yaml and steps/*.json,
-// matching the format expected by LoadXTSScenario.
+// matching the format expected by replay.LoadStepScenario.
 func (e *Engine) SaveScenario(outputDir string) error {
+	return e.SaveScenarioWithOptions(outputDir, replay.RecordOptions{})
+}
+
+// SaveScenarioWithOptions is SaveScenario with step files compressed and/or
+// encrypted as opts asks; scenario.yaml records which. With an encryption
+// key inputs.yaml is encrypted too. Without one, values matching a
+// redaction rule are redacted from inputs.yaml and the step files rather
+// than written in the clear.
+func (e *Engine) SaveScenarioWithOptions(outputDir string, opts replay.RecordOptions) error {
 	// Write inputs.yaml from resolved vars
 	if len(e.State.Vars) > 0 {
-		data, err := yaml.Marshal(e.State.Vars)
+		vars := e.State.Vars
+		if opts.EncryptKey == nil && len(e.Redact) > 0 {
+			vars = make(map[string]string, len(e.State.Vars))
+			for k, v := range e.State.Vars {
+				vars[k] = governance.RedactOutput(v, e.Redact)
+			}
+		}
+		data, err := yaml.Marshal(vars)
 		if err != nil {
 			return fmt.Errorf("marshal inputs: %w", err)
 		}
+		if opts.EncryptKey != nil {
+			if _, data, err = replay.EncodeStepFile("inputs.yaml", data, replay.RecordOptions{EncryptKey: opts.EncryptKey}); err != nil {
+				return err
+			}
+		}
 		if err := os.WriteFile(filepath.Join(outputDir, "inputs.yaml"), data, 0644); err != nil {
 			return fmt.Errorf("write inputs.yaml: %w", err)
 		}
@@ -1455,15 +1857,64 @@ func (e *Engine) SaveScenario(outputDir string) error {
 			if err != nil {
 				return fmt.Errorf("read step file %s: %w", entry.Name(), err)
 			}
-			if err := os.WriteFile(filepath.Join(dstStepsDir, entry.Name()), data, 0644); err != nil {
-				return fmt.Errorf("write step file %s: %w", entry.Name(), err)
+			if opts.EncryptKey == nil && e.matchesRedaction(data) {
+				data = []byte(governance.RedactOutput(string(data), e.Redact))
+			}
+			name, data, err := replay.EncodeStepFile(entry.Name(), data, opts)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(dstStepsDir, name), data, 0644); err != nil {
+				return fmt.Errorf("write step file %s: %w", name, err)
 			}
 		}
 	}
 
+	return writeRecording(filepath.Join(outputDir, "scenario.yaml"), opts.Recording())
+}
+
+// writeRecording sets the recording section of scenario.yaml at path,
+// keeping whatever else an existing file holds. A nil rec removes the
+// section, and no file is created just to say nothing.
+func writeRecording(path string, rec *replay.Recording) error {
+	doc := map[string]interface{}{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("parse scenario.yaml: %w", err)
+		}
+		if doc == nil {
+			doc = map[string]interface{}{}
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("read scenario.yaml: %w", err)
+	} else if rec == nil {
+		return nil
+	}
+	if rec != nil {
+		doc["recording"] = rec
+	} else {
+		delete(doc, "recording")
+	}
+	data, err := yaml.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("marshal scenario.yaml: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write scenario.yaml: %w", err)
+	}
 	return nil
 }
 
+// matchesRedaction reports whether any redaction rule matches data.
+func (e *Engine) matchesRedaction(data []byte) bool {
+	for _, r := range e.Redact {
+		if r.Pattern.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+
 // SetOutcome sets the engine outcome record (used by serve for step-by-step tree execution).
 // SetVar sets a variable in the engine state (used for choice captures).
 func (e *Engine) SetVar(name, value string) {
@@ -1492,3 +1943,18 @@ func (e *Engine) GetXTSCLIPath() string {
 	}
 	return ""
 }
+
+// checkWritable creates dir if needed and verifies a file can be written
+// in it.
+func checkWritable(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("output directory %s: %w", dir, err)
+	}
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("output directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return nil
+}
@@ -2,12 +2,20 @@ package runtime
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ormasoftchile/gert/pkg/providers"
+	"github.com/ormasoftchile/gert/pkg/replay"
 	"github.com/ormasoftchile/gert/pkg/schema"
+	"github.com/ormasoftchile/gert/pkg/tools"
 )
 
 // TestRunIDFormat validates the run ID format: timestamp+short random suffix.
@@ -47,6 +55,29 @@ func (d *dryRunExecutor) Execute(ctx context.Context, command string, args []str
 
 // TestDryRunZeroSideEffects verifies dry-run mode executes no real commands
 // and produces placeholder output.
+func TestNewEngineInDir(t *testing.T) {
+	rb := &schema.Runbook{APIVersion: "runbook/v0", Meta: schema.Meta{Name: "out-dir"}}
+	runsDir := filepath.Join(t.TempDir(), "gert-runs")
+
+	engine, err := NewEngineInDir(rb, nil, nil, "dry-run", "test", runsDir)
+	if err != nil {
+		t.Fatalf("NewEngineInDir: %v", err)
+	}
+	defer engine.Trace.Close()
+	if want := filepath.Join(runsDir, engine.GetRunID()); engine.GetBaseDir() != want {
+		t.Errorf("base dir = %q, want %q", engine.GetBaseDir(), want)
+	}
+	if _, err := os.Stat(filepath.Join(engine.GetBaseDir(), "trace.jsonl")); err != nil {
+		t.Errorf("trace not under output dir: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0644)
+	if _, err := NewEngineInDir(rb, nil, nil, "dry-run", "test", filepath.Join(file, "runs")); err == nil {
+		t.Error("expected error for an unwritable output directory")
+	}
+}
+
 func TestDryRunZeroSideEffects(t *testing.T) {
 	rb := &schema.Runbook{
 		APIVersion: "runbook/v0",
@@ -270,3 +301,437 @@ func TestNormalizeRunbookPathRef(t *testing.T) {
 		})
 	}
 }
+
+// TestCaptureAsNamespace verifies two steps capturing the same name keep
+// independent values under their step IDs while the flat key is last-writer-wins.
+func TestCaptureAsNamespace(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "capture-ns-test"},
+		Steps: []schema.Step{
+			{
+				ID:                 "step1",
+				Type:               "cli",
+				With:               &schema.CLIStepConfig{Argv: []string{"echo", "one"}},
+				Capture:            map[string]string{"status": "stdout"},
+				CaptureAsNamespace: true,
+			},
+			{
+				ID:                 "step2",
+				Type:               "cli",
+				With:               &schema.CLIStepConfig{Argv: []string{"echo", "two"}},
+				Capture:            map[string]string{"status": "stdout"},
+				CaptureAsNamespace: true,
+			},
+		},
+	}
+
+	engine, err := NewEngine(rb, &echoExecutor{}, &providers.DryRunCollector{}, "real", "")
+	if err != nil {
+		t.Fatalf("NewEngine error: %v", err)
+	}
+	defer engine.Trace.Close()
+
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+
+	if got := engine.State.Captures["step1.status"]; got != "one" {
+		t.Errorf("step1.status = %q, want %q", got, "one")
+	}
+	if got := engine.State.Captures["step2.status"]; got != "two" {
+		t.Errorf("step2.status = %q, want %q", got, "two")
+	}
+	if got := engine.State.Captures["status"]; got != "two" {
+		t.Errorf("status = %q, want %q (last writer wins)", got, "two")
+	}
+	if got := engine.ResolveTemplatePublic("{{ .step1.status }}/{{ .status }}"); got != "one/two" {
+		t.Errorf("template resolved to %q, want %q", got, "one/two")
+	}
+	ok, err := engine.evalCondition(`step1.status == "one" && step2.status == "two"`)
+	if err != nil || !ok {
+		t.Errorf("evalCondition = %v, %v; want true", ok, err)
+	}
+}
+
+func TestStepExecutor_SSHRouting(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "ssh-routing", Vars: map[string]string{"host": "db01"}},
+		Steps: []schema.Step{{
+			ID:   "remote",
+			Type: "cli",
+			With: &schema.CLIStepConfig{Argv: []string{"uptime"}, Executor: "ssh://ops@{{ .host }}:2222"},
+		}},
+	}
+	step := rb.Steps[0]
+
+	for _, mode := range []string{"dry-run", "real"} {
+		engine, err := NewEngine(rb, &echoExecutor{}, &providers.DryRunCollector{}, mode, "")
+		if err != nil {
+			t.Fatalf("NewEngine error: %v", err)
+		}
+		defer engine.Trace.Close()
+
+		executor, err := engine.stepExecutor(step)
+		if err != nil {
+			t.Fatalf("%s: stepExecutor error: %v", mode, err)
+		}
+		sshExec, isSSH := executor.(*providers.SSHExecutor)
+		if mode == "dry-run" && isSSH {
+			t.Error("dry-run must keep the injected executor")
+		}
+		if mode == "real" && (!isSSH || sshExec.Host != "db01" || sshExec.Port != "2222" || sshExec.User != "ops") {
+			t.Errorf("real: got executor %#v", executor)
+		}
+	}
+}
+
+func TestEngine_InputsFrom(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "inputs-from"},
+		Steps: []schema.Step{
+			{
+				ID:      "describe",
+				Type:    "tool",
+				Tool:    &schema.ToolStepConfig{Name: "svc", Action: "describe"},
+				Capture: map[string]string{"info": "stdout"},
+			},
+			{
+				ID:      "plan",
+				Type:    "tool",
+				Tool:    &schema.ToolStepConfig{Name: "svc", Action: "plan"},
+				Capture: map[string]string{"patch": "stdout"},
+			},
+			{
+				ID:         "scale",
+				Type:       "tool",
+				Tool:       &schema.ToolStepConfig{Name: "svc", Action: "scale", Args: map[string]string{"zone": "west"}},
+				InputsFrom: []any{"describe", "patch"},
+				Capture:    map[string]string{"out": "stdout"},
+			},
+		},
+	}
+
+	engine, err := NewEngine(rb, &echoExecutor{}, &providers.DryRunCollector{}, "real", "")
+	if err != nil {
+		t.Fatalf("NewEngine error: %v", err)
+	}
+	defer engine.Trace.Close()
+	engine.ToolManager = tools.NewManager(&echoExecutor{}, nil)
+	engine.ToolManager.RegisterBuiltin("svc", &schema.ToolDefinition{
+		APIVersion: "tool/v0",
+		Meta:       schema.ToolMeta{Name: "svc", Binary: "svc"},
+		Actions: map[string]schema.ToolAction{
+			"describe": {Argv: []string{`{"service":"web","replicas":3,"zone":"east"}`}},
+			"plan":     {Argv: []string{`{"replicas":5}`}},
+			"scale":    {Argv: []string{"scale", "{{ .service }}", "{{ .replicas }}", "{{ .zone }}"}},
+		},
+	})
+
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	// The later "patch" capture wins for replicas; the explicit zone arg wins over both
+	if got := engine.State.Captures["out"]; got != "scale web 5 west" {
+		t.Errorf("out = %q, want %q", got, "scale web 5 west")
+	}
+
+	// A source that is not a JSON object fails the step
+	engine.State.Captures["plain"] = "not json"
+	result := &providers.StepResult{Captures: map[string]string{}}
+	step := rb.Steps[2]
+	step.InputsFrom = "plain"
+	engine.executeToolStep(context.Background(), step, result)
+	if result.Status != "failed" || !strings.Contains(result.Error, `inputs_from "plain": capture is not a JSON object`) {
+		t.Errorf("status = %q, error = %q", result.Status, result.Error)
+	}
+}
+
+// echoExecutor returns its arguments joined by spaces as stdout.
+type echoExecutor struct{}
+
+func (echoExecutor) Execute(ctx context.Context, command string, args []string, env []string) (*providers.CommandResult, error) {
+	return &providers.CommandResult{Stdout: []byte(strings.Join(args, " "))}, nil
+}
+
+// failingExecutor fails every command named "fail" and echoes the rest.
+type failingExecutor struct {
+	commands []string
+}
+
+func (f *failingExecutor) Execute(ctx context.Context, command string, args []string, env []string) (*providers.CommandResult, error) {
+	f.commands = append(f.commands, command+" "+strings.Join(args, " "))
+	if command == "fail" {
+		return nil, fmt.Errorf("%s exploded", strings.Join(args, " "))
+	}
+	return &providers.CommandResult{Stdout: []byte(strings.Join(args, " "))}, nil
+}
+
+func onFailureRunbook(cleanupCmd string) *schema.Runbook {
+	return &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "on-failure-test"},
+		Steps: []schema.Step{
+			{ID: "drain", Type: "cli", With: &schema.CLIStepConfig{Argv: []string{"fail", "drain"}}, OnFailure: "cleanup"},
+			{ID: "verify", Type: "cli", With: &schema.CLIStepConfig{Argv: []string{"echo", "verify"}}},
+			{
+				ID:      "cleanup",
+				Type:    "cli",
+				With:    &schema.CLIStepConfig{Argv: []string{cleanupCmd, "{{ .drain.error }}"}},
+				Capture: map[string]string{"cause": "stdout"},
+			},
+		},
+	}
+}
+
+// TestOnFailureRoutesToTarget verifies a failed step continues at its
+// on_failure target, skipping the steps in between.
+func TestOnFailureRoutesToTarget(t *testing.T) {
+	executor := &failingExecutor{}
+	engine, err := NewEngine(onFailureRunbook("echo"), executor, &providers.DryRunCollector{}, "real", "")
+	if err != nil {
+		t.Fatalf("NewEngine error: %v", err)
+	}
+	defer engine.Trace.Close()
+
+	if err := engine.Run(context.Background()); err != nil {
+		t.Fatalf("Run error: %v", err)
+	}
+	if len(executor.commands) != 2 {
+		t.Errorf("commands = %v, want drain then cleanup", executor.commands)
+	}
+	if got := engine.State.Captures["cause"]; got != "execute: drain exploded" {
+		t.Errorf("cause = %q", got)
+	}
+}
+
+// TestOnFailureTargetFails verifies a failing on_failure target halts the
+// run instead of routing again.
+func TestOnFailureTargetFails(t *testing.T) {
+	executor := &failingExecutor{}
+	engine, err := NewEngine(onFailureRunbook("fail"), executor, &providers.DryRunCollector{}, "real", "")
+	if err != nil {
+		t.Fatalf("NewEngine error: %v", err)
+	}
+	defer engine.Trace.Close()
+
+	err = engine.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `step "cleanup" failed`) {
+		t.Fatalf("Run error = %v, want cleanup failure", err)
+	}
+	if len(executor.commands) != 2 {
+		t.Errorf("commands = %v, want drain then cleanup", executor.commands)
+	}
+}
+
+// envExecutor records the environment of each command and echoes its args.
+type envExecutor struct {
+	env [][]string
+}
+
+func (x *envExecutor) Execute(ctx context.Context, command string, args []string, env []string) (*providers.CommandResult, error) {
+	x.env = append(x.env, env)
+	return &providers.CommandResult{Stdout: []byte(strings.Join(args, " "))}, nil
+}
+
+// TestEngine_StepEnv verifies CLI and tool steps pass their resolved env to
+// the executor, record it redacted, and honour deny_env_vars.
+func TestEngine_StepEnv(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta: schema.Meta{
+			Name: "step-env",
+			Vars: map[string]string{"region": "eu-west-1"},
+			Governance: &schema.GovernancePolicy{
+				DenyEnvVars: []string{"AWS_*"},
+				Redact:      []schema.RedactionRule{{Pattern: `tok-\w+`, Replace: "[REDACTED]"}},
+			},
+		},
+		Steps: []schema.Step{
+			{ID: "cli", Type: "cli", With: &schema.CLIStepConfig{
+				Argv: []string{"deploy"},
+				Env:  map[string]string{"REGION": "{{ .region }}", "TOKEN": "tok-123"},
+			}},
+			{ID: "tool", Type: "tool", Tool: &schema.ToolStepConfig{
+				Name: "svc", Action: "run", Env: map[string]string{"REGION": "{{ .region }}"},
+			}},
+		},
+	}
+
+	exec := &envExecutor{}
+	engine, err := NewEngine(rb, exec, &providers.DryRunCollector{}, "real", "")
+	if err != nil {
+		t.Fatalf("NewEngine error: %v", err)
+	}
+	defer engine.Trace.Close()
+	engine.ToolManager = tools.NewManager(exec, nil)
+	engine.ToolManager.RegisterBuiltin("svc", &schema.ToolDefinition{
+		APIVersion: "tool/v0",
+		Meta:       schema.ToolMeta{Name: "svc", Binary: "svc"},
+		Actions:    map[string]schema.ToolAction{"run": {Argv: []string{"run"}}},
+	})
+
+	result := &providers.StepResult{Captures: map[string]string{}}
+	engine.executeCLIStep(context.Background(), rb.Steps[0], result)
+	if result.Status != "passed" {
+		t.Fatalf("cli: status = %q, error = %q", result.Status, result.Error)
+	}
+	if got := strings.Join(exec.env[0], " "); got != "REGION=eu-west-1 TOKEN=tok-123" {
+		t.Errorf("cli env = %q", got)
+	}
+	if result.Env["TOKEN"] != "[REDACTED]" || result.Env["REGION"] != "eu-west-1" {
+		t.Errorf("recorded env = %v, want TOKEN redacted", result.Env)
+	}
+
+	result = &providers.StepResult{Captures: map[string]string{}}
+	engine.executeToolStep(context.Background(), rb.Steps[1], result)
+	if result.Status != "passed" {
+		t.Fatalf("tool: status = %q, error = %q", result.Status, result.Error)
+	}
+	if got := strings.Join(exec.env[1], " "); got != "REGION=eu-west-1" {
+		t.Errorf("tool env = %q", got)
+	}
+
+	denied := rb.Steps[0]
+	denied.With = &schema.CLIStepConfig{Argv: []string{"deploy"}, Env: map[string]string{"AWS_PROFILE": "ops"}}
+	result = &providers.StepResult{Captures: map[string]string{}}
+	engine.executeCLIStep(context.Background(), denied, result)
+	if result.Status != "failed" || !strings.Contains(result.Error, `"AWS_PROFILE" matches denied pattern "AWS_*"`) {
+		t.Errorf("denied: status = %q, error = %q", result.Status, result.Error)
+	}
+	if len(exec.env) != 2 {
+		t.Error("denied step must not execute")
+	}
+}
+
+// TestEngine_SaveScenarioEncoded verifies saved step files and inputs are
+// compressed and encrypted on request, that values matching a redaction
+// rule are redacted when no key is given, and that an existing
+// scenario.yaml keeps its content.
+func TestEngine_SaveScenarioEncoded(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta: schema.Meta{
+			Name: "save-scenario",
+			Governance: &schema.GovernancePolicy{
+				Redact: []schema.RedactionRule{{Pattern: `tok-\w+`, Replace: "[REDACTED]"}},
+			},
+		},
+		Steps: []schema.Step{{ID: "noop", Type: "manual"}},
+	}
+	engine, err := NewEngine(rb, &echoExecutor{}, &providers.DryRunCollector{}, "real", "")
+	if err != nil {
+		t.Fatalf("NewEngine error: %v", err)
+	}
+	defer engine.Trace.Close()
+	engine.BaseDir = t.TempDir()
+	os.MkdirAll(filepath.Join(engine.BaseDir, "steps"), 0o755)
+	os.WriteFile(filepath.Join(engine.BaseDir, "steps", "001-login.json"), []byte(`{"token":"tok-abc"}`), 0o644)
+
+	engine.State.Vars["api_token"] = "tok-xyz"
+
+	plain := t.TempDir()
+	if err := engine.SaveScenario(plain); err != nil {
+		t.Fatalf("SaveScenario: %v", err)
+	}
+	for _, f := range []string{"inputs.yaml", filepath.Join("steps", "001-login.json")} {
+		data, _ := os.ReadFile(filepath.Join(plain, f))
+		if strings.Contains(string(data), "tok-") || !strings.Contains(string(data), "[REDACTED]") {
+			t.Errorf("plain %s not redacted: %s", f, data)
+		}
+	}
+
+	out := t.TempDir()
+	os.WriteFile(filepath.Join(out, "scenario.yaml"), []byte("evidence:\n  check:\n    note:\n      kind: text\n      value: ok\n"), 0o644)
+	key := make([]byte, 32)
+	if err := engine.SaveScenarioWithOptions(out, replay.RecordOptions{Compress: true, EncryptKey: key}); err != nil {
+		t.Fatalf("SaveScenarioWithOptions: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(out, "steps", "001-login.json.gz.enc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "tok-abc") {
+		t.Error("saved step file contains the token in the clear")
+	}
+	sc, err := replay.LoadStepScenarioWithKey(out, time.Time{}, key)
+	if err != nil {
+		t.Fatalf("LoadStepScenarioWithKey: %v", err)
+	}
+	if resp, ok := sc.FindStepResponse("login"); !ok || string(resp) != `{"token":"tok-abc"}` {
+		t.Errorf("step response = %s, %v", resp, ok)
+	}
+	if sc.Recording == nil || sc.Recording.Compression != "gzip" || sc.Recording.Encryption != "aes-256-gcm" {
+		t.Errorf("scenario.yaml recording = %+v", sc.Recording)
+	}
+	if sc.Evidence["check"]["note"] == nil {
+		t.Errorf("scenario.yaml lost its evidence: %+v", sc.Evidence)
+	}
+	inputs, _ := os.ReadFile(filepath.Join(out, "inputs.yaml"))
+	if strings.Contains(string(inputs), "tok-xyz") {
+		t.Error("inputs.yaml contains the token in the clear")
+	}
+	t.Setenv(replay.KeyEnv, base64.StdEncoding.EncodeToString(key))
+	if inputs, err = replay.DecodeInputs(inputs); err != nil || !strings.Contains(string(inputs), "tok-xyz") {
+		t.Errorf("DecodeInputs = %s, %v", inputs, err)
+	}
+}
+
+// TestEngine_GovernanceCheckTrace verifies every evaluated command line is
+// traced as a governance_check event, allowed or denied, with redaction.
+func TestEngine_GovernanceCheckTrace(t *testing.T) {
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta: schema.Meta{
+			Name: "gov-trace",
+			Governance: &schema.GovernancePolicy{
+				Rules:  []schema.CommandRule{{Action: "deny", Pattern: "kubectl delete *"}},
+				Redact: []schema.RedactionRule{{Pattern: `token=\S+`, Replace: "token=***"}},
+			},
+		},
+		Steps: []schema.Step{
+			{ID: "list", Type: "cli", With: &schema.CLIStepConfig{Argv: []string{"kubectl", "get", "pods", "token=abc"}}},
+			{ID: "drop", Type: "cli", With: &schema.CLIStepConfig{Argv: []string{"kubectl", "delete", "pod/api-0"}}},
+		},
+	}
+	engine, err := NewEngine(rb, &echoExecutor{}, &providers.DryRunCollector{}, "real", "")
+	if err != nil {
+		t.Fatalf("NewEngine error: %v", err)
+	}
+	defer engine.Trace.Close()
+
+	for i := range rb.Steps {
+		if _, err := engine.ExecuteStep(context.Background(), i); err != nil {
+			t.Fatalf("ExecuteStep(%d): %v", i, err)
+		}
+	}
+	if got := engine.State.History[1]; got.Status != "failed" || !strings.Contains(got.Error, "denied by governance policy") {
+		t.Errorf("drop = %s %q, want governance failure", got.Status, got.Error)
+	}
+
+	data, err := os.ReadFile(filepath.Join(engine.BaseDir, "trace.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checks []TraceEvent
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var ev TraceEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Type == "governance_check" {
+			checks = append(checks, ev)
+		}
+	}
+	if len(checks) != 2 {
+		t.Fatalf("got %d governance_check events, want 2", len(checks))
+	}
+	if c := checks[0]; c.StepID != "list" || c.Decision != "allow" || c.Command != "kubectl get pods token=***" {
+		t.Errorf("list check = %+v", c)
+	}
+	if c := checks[1]; c.StepID != "drop" || c.Decision != "deny" || c.Rule != `rules[0] "kubectl delete *"` {
+		t.Errorf("drop check = %+v", c)
+	}
+}
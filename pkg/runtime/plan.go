@@ -0,0 +1,237 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/governance"
+	"github.com/ormasoftchile/gert/pkg/logging"
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// Governance risk levels reported in PlannedStep.GovernanceRisk.
+const (
+	PlanRiskRead   = "read"   // read-only step
+	PlanRiskWrite  = "write"  // step may change external state
+	PlanRiskDenied = "denied" // governance would block the step
+)
+
+// ExecutionPlan is the structured preview returned by Engine.DryRun.
+type ExecutionPlan struct {
+	Runbook string        `json:"runbook"`
+	Mode    string        `json:"mode"`
+	Steps   []PlannedStep `json:"steps"`
+}
+
+// PlannedStep describes how one step would run. Steps inside tree branches
+// are listed for every branch, with Branch naming the arm; only the arm
+// whose condition holds with the current variables would execute.
+type PlannedStep struct {
+	StepID            string `json:"step_id"`
+	Type              string `json:"type"`
+	Branch            string `json:"branch,omitempty"`             // branch label (or condition) for steps inside a branch
+	ResolvedCommand   string `json:"resolved_command,omitempty"`   // argv, tool call or invoked runbook with templates resolved
+	EstimatedDuration string `json:"estimated_duration,omitempty"` // upper bound from timeout and retries; empty when unbounded
+	GovernanceRisk    string `json:"governance_risk"`              // read, write or denied
+	WouldExecute      bool   `json:"would_execute"`
+	Reason            string `json:"reason,omitempty"` // why WouldExecute is false
+}
+
+// missingKeyRe extracts the variable name from a missingkey=error template failure.
+var missingKeyRe = regexp.MustCompile(`map has no entry for key "([^"]+)"`)
+
+// DryRun walks every step of the runbook without executing anything. It
+// resolves templates with the current vars and captures, applies the
+// governance allowlist/denylist and step guards, and returns the plan.
+// Conditions that depend on captures from earlier steps are evaluated
+// against what is known now, so they usually report the step as not taken.
+func (e *Engine) DryRun(ctx context.Context) (*ExecutionPlan, error) {
+	plan := &ExecutionPlan{Runbook: e.Runbook.Meta.Name, Mode: e.State.Mode}
+	var err error
+	if len(e.Runbook.Tree) > 0 {
+		plan.Steps, err = e.planTree(ctx, e.Runbook.Tree, "", "")
+	} else {
+		for _, step := range e.Runbook.Steps {
+			if err = ctx.Err(); err != nil {
+				break
+			}
+			plan.Steps = append(plan.Steps, e.planStep(step, "", ""))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// PlanRunbook returns the DryRun plan for rb using its meta.vars, without
+// creating a run directory or trace.
+func PlanRunbook(ctx context.Context, rb *schema.Runbook, mode string) (*ExecutionPlan, error) {
+	vars := make(map[string]string, len(rb.Meta.Vars))
+	for k, v := range rb.Meta.Vars {
+		vars[k] = v
+	}
+	e := &Engine{
+		Runbook: rb,
+		State:   &RunState{Mode: mode, Vars: vars, Captures: make(map[string]string)},
+		Gov:     governance.NewGovernanceEngine(rb.Meta.Governance),
+		Logger:  logging.Default(),
+	}
+	return e.DryRun(ctx)
+}
+
+// planTree plans nodes in order. skip, when set, is why none of these
+// nodes would execute (their branch is not taken).
+func (e *Engine) planTree(ctx context.Context, nodes []schema.TreeNode, branch, skip string) ([]PlannedStep, error) {
+	var steps []PlannedStep
+	for _, node := range nodes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if node.Iterate != nil {
+			inner, err := e.planTree(ctx, node.Iterate.Steps, branch, skip)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, inner...)
+			continue
+		}
+		steps = append(steps, e.planStep(node.Step, branch, skip))
+
+		// Only the first matching arm runs; every arm is listed
+		taken := false
+		for _, br := range node.Branches {
+			label := br.Label
+			if label == "" {
+				label = br.Condition
+			}
+			armSkip := skip
+			if armSkip == "" {
+				matched, err := e.evalCondition(br.Condition)
+				switch {
+				case taken:
+					armSkip = "an earlier branch is taken"
+				case err != nil:
+					armSkip = "branch condition: " + unresolvedReason(err)
+				case !matched:
+					armSkip = fmt.Sprintf("branch condition %q is false", br.Condition)
+				default:
+					taken = true
+				}
+			}
+			inner, err := e.planTree(ctx, br.Steps, label, armSkip)
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, inner...)
+		}
+	}
+	return steps, nil
+}
+
+// planStep plans a single step. skip, when set, is why it cannot run.
+func (e *Engine) planStep(step schema.Step, branch, skip string) PlannedStep {
+	ps := PlannedStep{
+		StepID:            step.ID,
+		Type:              step.Type,
+		Branch:            branch,
+		EstimatedDuration: e.estimateDuration(step),
+		GovernanceRisk:    PlanRiskRead,
+	}
+	if e.IsWriteEffectStep(step) {
+		ps.GovernanceRisk = PlanRiskWrite
+	}
+
+	var denied error
+	switch step.Type {
+	case "cli":
+		if step.With != nil && len(step.With.Argv) > 0 {
+			argv, err := e.resolveArgv(step.With.Argv)
+			if err != nil {
+				ps.ResolvedCommand = "<unresolvable: " + unresolvedReason(err) + ">"
+			} else {
+				ps.ResolvedCommand = strings.Join(argv, " ")
+				denied = e.Gov.CheckArgv(argv)
+			}
+		}
+	case "tool":
+		if step.Tool != nil {
+			ps.ResolvedCommand = e.planToolCall(step.Tool)
+		}
+	case "invoke":
+		if step.Invoke != nil {
+			ps.ResolvedCommand = "invoke " + step.Invoke.Runbook
+		}
+	}
+	if denied != nil {
+		ps.GovernanceRisk = PlanRiskDenied
+	}
+
+	switch {
+	case skip != "":
+		ps.Reason = skip
+	case denied != nil:
+		ps.Reason = fmt.Sprintf("governance: %v", denied)
+	case strings.HasPrefix(ps.ResolvedCommand, "<unresolvable"):
+		ps.Reason = "command cannot be resolved"
+	case e.State.Mode == "probe" && ps.GovernanceRisk == PlanRiskWrite:
+		ps.Reason = ProbeSkipReason
+	case step.When != "":
+		matched, err := e.evalCondition(step.When)
+		if err != nil {
+			ps.Reason = "when: " + unresolvedReason(err)
+		} else if !matched {
+			ps.Reason = fmt.Sprintf("when %q is false", step.When)
+		}
+	}
+	ps.WouldExecute = ps.Reason == ""
+	return ps
+}
+
+// planToolCall renders a tool step as "<tool> <action> k=v ...", with
+// args sorted by name.
+func (e *Engine) planToolCall(tool *schema.ToolStepConfig) string {
+	parts := []string{tool.Name, tool.Action}
+	names := make([]string, 0, len(tool.Args))
+	for k := range tool.Args {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		v, err := e.resolveTemplate(tool.Args[k])
+		if err != nil {
+			return "<unresolvable: " + unresolvedReason(err) + ">"
+		}
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, " ")
+}
+
+// estimateDuration bounds a step by its timeout across all retry attempts
+// plus the delays between them. Steps without a timeout are unbounded.
+func (e *Engine) estimateDuration(step schema.Step) string {
+	timeout := e.getStepTimeout(step)
+	if timeout == 0 {
+		return ""
+	}
+	total := timeout
+	if step.Retry != nil && step.Retry.Max > 1 {
+		for attempt := 1; attempt < step.Retry.Max; attempt++ {
+			total += timeout + getRetryDelay(step.Retry, attempt)
+		}
+	}
+	return total.Round(time.Second).String()
+}
+
+// unresolvedReason turns a template error into "var X not set" when a
+// variable is missing, and the error text otherwise.
+func unresolvedReason(err error) string {
+	if m := missingKeyRe.FindStringSubmatch(err.Error()); m != nil {
+		return fmt.Sprintf("var %s not set", m[1])
+	}
+	return err.Error()
+}
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+func planRunbook() *schema.Runbook {
+	return &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta: schema.Meta{
+			Name:       "plan-test",
+			Vars:       map[string]string{"ns": "prod", "env": "staging"},
+			Governance: &schema.GovernancePolicy{DeniedCommands: []string{"rm"}},
+		},
+		Tree: []schema.TreeNode{
+			{Step: schema.Step{ID: "get", Type: "cli", Timeout: "10s",
+				Retry: &schema.RetryConfig{Max: 2, Delay: "5s"},
+				With:  &schema.CLIStepConfig{Argv: []string{"kubectl", "get", "pods", "-n", "{{ .ns }}"}}}},
+			{Step: schema.Step{ID: "missing", Type: "cli",
+				With: &schema.CLIStepConfig{Argv: []string{"echo", "{{ .pod }}"}}}},
+			{Step: schema.Step{ID: "wipe", Type: "cli",
+				With: &schema.CLIStepConfig{Argv: []string{"rm", "-rf", "/tmp/x"}}}},
+			{
+				Step: schema.Step{ID: "decide", Type: "manual"},
+				Branches: []schema.Branch{
+					{Condition: `env == "prod"`, Label: "prod", Steps: []schema.TreeNode{
+						{Step: schema.Step{ID: "page", Type: "manual"}},
+					}},
+					{Condition: `env == "staging"`, Label: "staging", Steps: []schema.TreeNode{
+						{Step: schema.Step{ID: "restart", Type: "cli",
+							With: &schema.CLIStepConfig{Argv: []string{"kubectl", "rollout", "restart"}}}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	plan, err := PlanRunbook(context.Background(), planRunbook(), "real")
+	if err != nil {
+		t.Fatalf("PlanRunbook: %v", err)
+	}
+	byID := map[string]PlannedStep{}
+	for _, ps := range plan.Steps {
+		byID[ps.StepID] = ps
+	}
+	if len(plan.Steps) != 6 {
+		t.Fatalf("planned %d steps, want 6 (every branch listed): %+v", len(plan.Steps), plan.Steps)
+	}
+
+	get := byID["get"]
+	if get.ResolvedCommand != "kubectl get pods -n prod" || !get.WouldExecute || get.GovernanceRisk != PlanRiskWrite {
+		t.Errorf("get = %+v", get)
+	}
+	if get.EstimatedDuration != "25s" {
+		t.Errorf("get duration = %q, want 25s (two 10s attempts plus 5s delay)", get.EstimatedDuration)
+	}
+	if got := byID["missing"].ResolvedCommand; got != "<unresolvable: var pod not set>" {
+		t.Errorf("missing command = %q", got)
+	}
+	if byID["missing"].WouldExecute {
+		t.Error("a step with an unresolvable command should not execute")
+	}
+	if wipe := byID["wipe"]; wipe.GovernanceRisk != PlanRiskDenied || wipe.WouldExecute {
+		t.Errorf("wipe = %+v, want denied", wipe)
+	}
+	if page := byID["page"]; page.Branch != "prod" || page.WouldExecute {
+		t.Errorf("page = %+v, want listed on prod branch but not executed", page)
+	}
+	if restart := byID["restart"]; restart.Branch != "staging" || !restart.WouldExecute {
+		t.Errorf("restart = %+v, want executed on staging branch", restart)
+	}
+}
+
+func TestDryRun_ProbeSkipsWrites(t *testing.T) {
+	plan, err := PlanRunbook(context.Background(), planRunbook(), "probe")
+	if err != nil {
+		t.Fatalf("PlanRunbook: %v", err)
+	}
+	for _, ps := range plan.Steps {
+		if ps.StepID == "get" && (ps.WouldExecute || ps.Reason != ProbeSkipReason) {
+			t.Errorf("get in probe mode = %+v", ps)
+		}
+		if ps.StepID == "decide" && !ps.WouldExecute {
+			t.Errorf("manual step should still run in probe mode: %+v", ps)
+		}
+	}
+}
@@ -8,13 +8,14 @@ import (
 	"time"
 
 	"github.com/ormasoftchile/gert/pkg/governance"
+	"github.com/ormasoftchile/gert/pkg/logging"
 	"github.com/ormasoftchile/gert/pkg/providers"
 	"github.com/ormasoftchile/gert/pkg/schema"
 )
 
 // ResumeEngine creates an Engine that resumes from the most recent snapshot.
 func ResumeEngine(rb *schema.Runbook, executor providers.CommandExecutor, collector providers.EvidenceCollector, runID string) (*Engine, error) {
-	baseDir := filepath.Join(".runbook", "runs", runID)
+	baseDir := filepath.Join(DefaultRunsDir, runID)
 
 	// Find the most recent snapshot
 	snapshotDir := filepath.Join(baseDir, "snapshots")
@@ -69,6 +70,7 @@ func ResumeEngine(rb *schema.Runbook, executor providers.CommandExecutor, collec
 		Collector: collector,
 		Trace:     trace,
 		BaseDir:   baseDir,
+		Logger:    logging.Default(),
 	}, nil
 }
 
@@ -79,7 +81,7 @@ func ResumeForServe(rb *schema.Runbook, executor providers.CommandExecutor, coll
 	runID string, vars, captures map[string]string, history []*providers.StepResult,
 	mode, actor string, startedAt time.Time) (*Engine, error) {
 
-	baseDir := filepath.Join(".runbook", "runs", runID)
+	baseDir := filepath.Join(DefaultRunsDir, runID)
 
 	// Ensure directories exist
 	for _, sub := range []string{"snapshots", "attachments"} {
@@ -123,6 +125,7 @@ func ResumeForServe(rb *schema.Runbook, executor providers.CommandExecutor, coll
 		Collector: collector,
 		Trace:     trace,
 		BaseDir:   baseDir,
+		Logger:    logging.Default(),
 	}
 	e.RestoreStepCounts()
 	return e, nil
@@ -7,6 +7,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/ormasoftchile/gert/pkg/governance"
 	"github.com/ormasoftchile/gert/pkg/providers"
 )
 
@@ -33,12 +34,59 @@ func NewTraceWriter(path string) (*TraceWriter, error) {
 
 // Write appends a StepResult as a JSONL event and flushes to disk.
 func (tw *TraceWriter) Write(result *providers.StepResult) error {
-	event := TraceEvent{
+	return tw.writeEvent(TraceEvent{
 		Type:      "step_result",
 		Timestamp: time.Now(),
 		RunID:     result.RunID,
 		Result:    result,
+	})
+}
+
+// WriteRetry appends a step_retry event for a failed attempt that will be retried.
+func (tw *TraceWriter) WriteRetry(result *providers.StepResult, attempt int) error {
+	return tw.writeEvent(TraceEvent{
+		Type:      "step_retry",
+		Timestamp: time.Now(),
+		RunID:     result.RunID,
+		Attempt:   attempt,
+		Result:    result,
+	})
+}
+
+// WriteOutput appends a step_output event carrying a chunk of a running
+// command's stdout or stderr.
+func (tw *TraceWriter) WriteOutput(runID, stepID, stream string, data []byte) error {
+	return tw.writeEvent(TraceEvent{
+		Type:      "step_output",
+		Timestamp: time.Now(),
+		RunID:     runID,
+		StepID:    stepID,
+		Stream:    stream,
+		Data:      string(data),
+	})
+}
+
+// WriteGovernanceCheck appends a governance_check event recording the
+// policy decision for a command line, whether it was allowed or denied.
+func (tw *TraceWriter) WriteGovernanceCheck(runID, stepID, command string, d governance.CommandDecision) error {
+	decision := "allow"
+	if !d.Allowed {
+		decision = "deny"
 	}
+	return tw.writeEvent(TraceEvent{
+		Type:      "governance_check",
+		Timestamp: time.Now(),
+		RunID:     runID,
+		StepID:    stepID,
+		Command:   command,
+		Decision:  decision,
+		Rule:      d.Rule,
+		Reason:    d.Reason,
+	})
+}
+
+// writeEvent encodes a trace event and flushes to disk.
+func (tw *TraceWriter) writeEvent(event TraceEvent) error {
 	if err := tw.enc.Encode(event); err != nil {
 		return fmt.Errorf("encode trace event: %w", err)
 	}
@@ -23,10 +23,18 @@ type RunState struct {
 
 // TraceEvent wraps a StepResult for JSONL trace output with extra metadata.
 type TraceEvent struct {
-	Type      string                `json:"type"` // step_result
+	Type      string                `json:"type"` // step_result, step_retry, step_output, governance_check
 	Timestamp time.Time             `json:"timestamp"`
 	RunID     string                `json:"run_id"`
-	Result    *providers.StepResult `json:"result"`
+	Attempt   int                   `json:"attempt,omitempty"` // step_retry only
+	Result    *providers.StepResult `json:"result,omitempty"`
+	StepID    string                `json:"step_id,omitempty"`  // step_output, governance_check
+	Stream    string                `json:"stream,omitempty"`   // step_output only: stdout, stderr
+	Data      string                `json:"data,omitempty"`     // step_output only
+	Command   string                `json:"command,omitempty"`  // governance_check only: the command line
+	Decision  string                `json:"decision,omitempty"` // governance_check only: allow, deny
+	Rule      string                `json:"rule,omitempty"`     // governance_check only: the deciding entry
+	Reason    string                `json:"reason,omitempty"`   // governance_check only: why it was denied
 }
 
 // RunManifest records the complete metadata for a runbook execution.
@@ -43,6 +51,7 @@ type RunManifest struct {
 	StepsSummary   StepsSummary      `yaml:"steps_summary"     json:"steps_summary"`
 	ParentRunID    string            `yaml:"parent_run_id,omitempty" json:"parent_run_id,omitempty"`
 	ChildRuns      []ChildRunRef     `yaml:"child_runs,omitempty"    json:"child_runs,omitempty"`
+	EvidenceURL    string            `yaml:"evidence_url,omitempty"  json:"evidence_url,omitempty"` // set when evidence is stored off-machine
 }
 
 // OutcomeRecord captures the terminal outcome of a run.
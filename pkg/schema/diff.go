@@ -0,0 +1,130 @@
+package schema
+
+import "reflect"
+
+// Diff kinds reported in DiffEntry.Kind.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DiffEntry is one structural difference between two runbooks.
+// Path addresses the step by ID ("steps.<id>") or one of its fields
+// ("steps.<id>.<field>", using the YAML field name).
+type DiffEntry struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// diffFields are the step fields compared by Diff: what the step is, what it
+// tells the operator, what it checks, when it runs, and its execution contract
+// (command, tool call, invocation, captures and outcomes).
+var diffFields = []struct {
+	name string
+	get  func(*Step) any
+}{
+	{"type", func(s *Step) any { return s.Type }},
+	{"title", func(s *Step) any { return s.Title }},
+	{"instructions", func(s *Step) any { return s.Instructions }},
+	{"assertions", func(s *Step) any { return s.Assertions }},
+	{"when", func(s *Step) any { return s.When }},
+	{"with", func(s *Step) any { return s.With }},
+	{"tool", func(s *Step) any { return s.Tool }},
+	{"invoke", func(s *Step) any { return s.Invoke }},
+	{"capture", func(s *Step) any { return s.Capture }},
+	{"outcomes", func(s *Step) any { return s.Outcomes }},
+}
+
+// Diff compares two runbooks step by step, matching steps by ID rather than
+// position so reordering alone is not reported. Entries for removed and
+// changed steps follow a's order, then added steps follow b's order.
+// Identical runbooks produce an empty slice.
+func Diff(a, b *Runbook) []DiffEntry {
+	before, beforeOrder := indexSteps(a)
+	after, afterOrder := indexSteps(b)
+
+	entries := []DiffEntry{}
+	for _, id := range beforeOrder {
+		sa := before[id]
+		sb, ok := after[id]
+		if !ok {
+			entries = append(entries, DiffEntry{Path: "steps." + id, Kind: DiffRemoved, Before: *sa})
+			continue
+		}
+		for _, f := range diffFields {
+			va, vb := normalizeDiffValue(f.get(sa)), normalizeDiffValue(f.get(sb))
+			if !reflect.DeepEqual(va, vb) {
+				entries = append(entries, DiffEntry{
+					Path:   "steps." + id + "." + f.name,
+					Kind:   DiffChanged,
+					Before: va,
+					After:  vb,
+				})
+			}
+		}
+	}
+	for _, id := range afterOrder {
+		if _, ok := before[id]; !ok {
+			entries = append(entries, DiffEntry{Path: "steps." + id, Kind: DiffAdded, After: *after[id]})
+		}
+	}
+	return entries
+}
+
+// indexSteps collects every step with an ID from both the flat steps list and
+// the tree (including branch and iterate bodies), in document order.
+func indexSteps(rb *Runbook) (map[string]*Step, []string) {
+	byID := make(map[string]*Step)
+	var order []string
+	add := func(s *Step) {
+		if s.ID == "" {
+			return
+		}
+		if _, dup := byID[s.ID]; !dup {
+			order = append(order, s.ID)
+		}
+		byID[s.ID] = s
+	}
+
+	var walk func(nodes []TreeNode)
+	walk = func(nodes []TreeNode) {
+		for i := range nodes {
+			add(&nodes[i].Step)
+			if nodes[i].Iterate != nil {
+				walk(nodes[i].Iterate.Steps)
+			}
+			for _, br := range nodes[i].Branches {
+				walk(br.Steps)
+			}
+		}
+	}
+
+	if rb != nil {
+		for i := range rb.Steps {
+			add(&rb.Steps[i])
+		}
+		walk(rb.Tree)
+	}
+	return byID, order
+}
+
+// normalizeDiffValue maps empty values (nil pointers, empty slices and maps,
+// empty strings) to nil so that omitted and explicitly-empty fields compare
+// equal.
+func normalizeDiffValue(v any) any {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map:
+		if rv.IsNil() || (rv.Kind() != reflect.Ptr && rv.Len() == 0) {
+			return nil
+		}
+	case reflect.String:
+		if rv.Len() == 0 {
+			return nil
+		}
+	}
+	return v
+}
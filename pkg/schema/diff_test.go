@@ -0,0 +1,84 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+const diffBase = `apiVersion: runbook/v0
+meta:
+  name: diff-test
+tree:
+  - step:
+      id: check
+      type: cli
+      title: Check service
+      with:
+        argv: ["curl", "-s", "http://localhost"]
+    branches:
+      - condition: "{{ .status }} == 500"
+        steps:
+          - step:
+              id: restart
+              type: manual
+              title: Restart
+              instructions: Restart the service
+  - step:
+      id: done
+      type: manual
+      title: Done
+`
+
+func mustLoad(t *testing.T, src string) *Runbook {
+	t.Helper()
+	rb, err := Load(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return rb
+}
+
+func TestDiff_Identical(t *testing.T) {
+	got := Diff(mustLoad(t, diffBase), mustLoad(t, diffBase))
+	if got == nil || len(got) != 0 {
+		t.Fatalf("Diff of identical runbooks = %#v, want empty slice", got)
+	}
+}
+
+func TestDiff_ChangedAddedRemoved(t *testing.T) {
+	changed := strings.NewReplacer(
+		"title: Check service", "title: Check service health",
+		`"http://localhost"`, `"http://localhost/healthz"`,
+		"      id: done\n      type: manual\n      title: Done\n",
+		"      id: verify\n      type: manual\n      title: Verify\n",
+	).Replace(diffBase)
+
+	got := Diff(mustLoad(t, diffBase), mustLoad(t, changed))
+
+	want := []struct{ path, kind string }{
+		{"steps.check.title", DiffChanged},
+		{"steps.check.with", DiffChanged},
+		{"steps.done", DiffRemoved},
+		{"steps.verify", DiffAdded},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Path != w.path || got[i].Kind != w.kind {
+			t.Errorf("entry %d = %s %s, want %s %s", i, got[i].Kind, got[i].Path, w.kind, w.path)
+		}
+	}
+	if got[0].Before != "Check service" || got[0].After != "Check service health" {
+		t.Errorf("title change = %v -> %v", got[0].Before, got[0].After)
+	}
+}
+
+func TestDiff_MatchesStepsByID(t *testing.T) {
+	a := &Runbook{Steps: []Step{{ID: "a", Type: "manual"}, {ID: "b", Type: "manual"}}}
+	b := &Runbook{Steps: []Step{{ID: "b", Type: "manual"}, {ID: "a", Type: "manual", Capture: map[string]string{}}}}
+
+	if got := Diff(a, b); len(got) != 0 {
+		t.Errorf("reordering should not be a diff, got %+v", got)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/invopop/jsonschema"
@@ -38,9 +39,9 @@ func (rb *Runbook) ResolveToolPath(name string) string {
 // TreeNode is a node in the runbook execution tree.
 // It contains either a step (with optional branches) or an iterate block.
 type TreeNode struct {
-	Step     Step           `yaml:"step"               json:"step"`
-	Iterate  *IterateBlock  `yaml:"iterate,omitempty"  json:"iterate,omitempty"`
-	Branches []Branch       `yaml:"branches,omitempty" json:"branches,omitempty"`
+	Step     Step          `yaml:"step"               json:"step"`
+	Iterate  *IterateBlock `yaml:"iterate,omitempty"  json:"iterate,omitempty"`
+	Branches []Branch      `yaml:"branches,omitempty" json:"branches,omitempty"`
 }
 
 // JSONSchemaExtend customizes the generated JSON Schema for TreeNode.
@@ -109,6 +110,31 @@ type Meta struct {
 	Defaults    *Defaults            `yaml:"defaults,omitempty"    json:"defaults,omitempty"`
 	Governance  *GovernancePolicy    `yaml:"governance,omitempty"  json:"governance,omitempty"`
 	Prose       *Prose               `yaml:"prose,omitempty"       json:"prose,omitempty"`
+
+	// InputOrder lists meta.inputs keys in the order the file declares
+	// them. Load fills it in; runbooks built in code leave it empty.
+	InputOrder []string `yaml:"-" json:"-"`
+}
+
+// OrderedInputs returns the input names in declaration order. Inputs
+// missing from InputOrder follow, sorted by name.
+func (m *Meta) OrderedInputs() []string {
+	names := make([]string, 0, len(m.Inputs))
+	seen := make(map[string]bool, len(m.Inputs))
+	for _, name := range m.InputOrder {
+		if _, ok := m.Inputs[name]; ok && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	var rest []string
+	for name := range m.Inputs {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	return append(names, rest...)
 }
 
 // SourceMeta tracks provenance — where this runbook was compiled from.
@@ -153,16 +179,29 @@ type ProseOwnership struct {
 //   - enrichment                   — requires a lookup step (future)
 //   - <provider>.<field>           — resolved by an external input provider
 type InputDef struct {
-	From        string `yaml:"from"                 json:"from"                 jsonschema:"required"`
-	Pattern     string `yaml:"pattern,omitempty"     json:"pattern,omitempty"`
-	Description string `yaml:"description,omitempty" json:"description,omitempty"`
-	Default     string `yaml:"default,omitempty"     json:"default,omitempty"`
-	Example     string `yaml:"example,omitempty"     json:"example,omitempty"`
+	From        string           `yaml:"from"                 json:"from"                 jsonschema:"required"`
+	Pattern     string           `yaml:"pattern,omitempty"     json:"pattern,omitempty"`
+	Description string           `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     string           `yaml:"default,omitempty"     json:"default,omitempty"`
+	Example     string           `yaml:"example,omitempty"     json:"example,omitempty"`
+	Validation  *InputValidation `yaml:"validation,omitempty"  json:"validation,omitempty"`
+	When        string           `yaml:"when,omitempty"        json:"when,omitempty"` // expr-lang condition over earlier inputs; false skips the input
+}
+
+// InputValidation constrains the value of an input at collection time.
+// Prompts re-ask until the response passes.
+type InputValidation struct {
+	Type    string   `yaml:"type"              json:"type"              jsonschema:"required,enum=regex,enum=range,enum=enum"`
+	Pattern string   `yaml:"pattern,omitempty" json:"pattern,omitempty"` // regex: anchored by the author, e.g. ^\d{6}$
+	Min     *float64 `yaml:"min,omitempty"     json:"min,omitempty"`     // range: inclusive lower bound
+	Max     *float64 `yaml:"max,omitempty"     json:"max,omitempty"`     // range: inclusive upper bound
+	Options []string `yaml:"options,omitempty" json:"options,omitempty"` // enum: allowed values
 }
 
 // Defaults specifies default execution settings applied to all steps.
 type Defaults struct {
-	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty" jsonschema:"pattern=^[0-9]+(s|m|h)$"`
+	Timeout                 string `yaml:"timeout,omitempty"                   json:"timeout,omitempty" jsonschema:"pattern=^[0-9]+(s|m|h)$"`
+	DefaultCaptureNamespace bool   `yaml:"default_capture_namespace,omitempty" json:"default_capture_namespace,omitempty"`
 }
 
 // GovernancePolicy defines safety rules evaluated before and during execution.
@@ -172,6 +211,16 @@ type GovernancePolicy struct {
 	DenyEnvVars     []string        `yaml:"deny_env_vars,omitempty"    json:"deny_env_vars,omitempty"`
 	Redact          []RedactionRule `yaml:"redact,omitempty"           json:"redact,omitempty"`
 	Evidence        *EvidencePolicy `yaml:"evidence,omitempty"         json:"evidence,omitempty"`
+	Rules           []CommandRule   `yaml:"rules,omitempty"            json:"rules,omitempty"`
+}
+
+// CommandRule allows or denies CLI commands. Command matches argv[0]
+// exactly; Pattern is a glob over the whole command line, so
+// "kubectl delete *" denies every kubectl delete. Set one of the two.
+type CommandRule struct {
+	Action  string `yaml:"action"            json:"action"            jsonschema:"required,enum=allow,enum=deny"`
+	Command string `yaml:"command,omitempty" json:"command,omitempty"`
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
 }
 
 // RedactionRule is a regex pattern-replacement pair for sanitizing output.
@@ -188,25 +237,54 @@ type EvidencePolicy struct {
 
 // Step is a single unit of work. Dispatched to a Provider based on Type.
 type Step struct {
-	ID               string                `yaml:"id"                json:"id"                jsonschema:"required"`
-	Type             string                `yaml:"type"              json:"type"              jsonschema:"required,enum=cli,enum=manual,enum=invoke,enum=tool"`
-	Title            string                `yaml:"title,omitempty"   json:"title,omitempty"`
-	When             string                `yaml:"when,omitempty"    json:"when,omitempty"`
-	Precondition     *Precondition         `yaml:"precondition,omitempty" json:"precondition,omitempty"`
-	Outcomes         []Outcome             `yaml:"outcomes,omitempty" json:"outcomes,omitempty"`
-	With             *CLIStepConfig        `yaml:"with,omitempty"    json:"with,omitempty"`
-	Instructions     string                `yaml:"instructions,omitempty"  json:"instructions,omitempty"`
-	RequiredEvidence []EvidenceRequirement `yaml:"required_evidence,omitempty" json:"required_evidence,omitempty"`
-	Approvals        *ApprovalRequirement  `yaml:"approvals,omitempty"   json:"approvals,omitempty"`
-	Choices          *ChoiceConfig         `yaml:"choices,omitempty"     json:"choices,omitempty"`
-	Capture          map[string]string     `yaml:"capture,omitempty"     json:"capture,omitempty"`
-	Assertions       []Assertion           `yaml:"assertions,omitempty"  json:"assertions,omitempty"`
-	Timeout          string                `yaml:"timeout,omitempty"     json:"timeout,omitempty"  jsonschema:"pattern=^[0-9]+(s|m|h)$"`
-	Delay            string                `yaml:"delay,omitempty"       json:"delay,omitempty"    jsonschema:"pattern=^[0-9]+(ms|s|m|h)$"`
-	ReplayMode       string                `yaml:"replay_mode,omitempty" json:"replay_mode,omitempty" jsonschema:"enum=reuse_evidence"`
-	Invoke           *InvokeConfig         `yaml:"invoke,omitempty"      json:"invoke,omitempty"`
-	Gate             *Gate                 `yaml:"gate,omitempty"        json:"gate,omitempty"`
-	Tool             *ToolStepConfig       `yaml:"tool,omitempty"        json:"tool,omitempty"`
+	ID                 string                `yaml:"id"                json:"id"                jsonschema:"required"`
+	Type               string                `yaml:"type"              json:"type"              jsonschema:"required,enum=cli,enum=manual,enum=invoke,enum=tool"`
+	Title              string                `yaml:"title,omitempty"   json:"title,omitempty"`
+	When               string                `yaml:"when,omitempty"    json:"when,omitempty"`
+	Precondition       *Precondition         `yaml:"precondition,omitempty" json:"precondition,omitempty"`
+	Outcomes           []Outcome             `yaml:"outcomes,omitempty" json:"outcomes,omitempty"`
+	With               *CLIStepConfig        `yaml:"with,omitempty"    json:"with,omitempty"`
+	Instructions       string                `yaml:"instructions,omitempty"  json:"instructions,omitempty"`
+	RequiredEvidence   []EvidenceRequirement `yaml:"required_evidence,omitempty" json:"required_evidence,omitempty"`
+	Approvals          *ApprovalRequirement  `yaml:"approvals,omitempty"   json:"approvals,omitempty"`
+	Choices            *ChoiceConfig         `yaml:"choices,omitempty"     json:"choices,omitempty"`
+	Capture            map[string]string     `yaml:"capture,omitempty"     json:"capture,omitempty"`
+	CaptureAsNamespace bool                  `yaml:"capture_as_namespace,omitempty" json:"capture_as_namespace,omitempty"`
+	Assertions         []Assertion           `yaml:"assertions,omitempty"  json:"assertions,omitempty"`
+	Timeout            string                `yaml:"timeout,omitempty"     json:"timeout,omitempty"  jsonschema:"pattern=^[0-9]+(s|m|h)$"`
+	Retry              *RetryConfig          `yaml:"retry,omitempty"       json:"retry,omitempty"`
+	OnFailure          string                `yaml:"on_failure,omitempty"  json:"on_failure,omitempty"`
+	Delay              string                `yaml:"delay,omitempty"       json:"delay,omitempty"    jsonschema:"pattern=^[0-9]+(ms|s|m|h)$"`
+	ReplayMode         string                `yaml:"replay_mode,omitempty" json:"replay_mode,omitempty" jsonschema:"enum=reuse_evidence"`
+	Invoke             *InvokeConfig         `yaml:"invoke,omitempty"      json:"invoke,omitempty"`
+	Gate               *Gate                 `yaml:"gate,omitempty"        json:"gate,omitempty"`
+	Tool               *ToolStepConfig       `yaml:"tool,omitempty"        json:"tool,omitempty"`
+	InputsFrom         any                   `yaml:"inputs_from,omitempty" json:"inputs_from,omitempty"` // string or []string
+}
+
+// InputsFromSources normalizes inputs_from to its list form: a single step
+// ID or capture name, or a list of them merged in order.
+func (s *Step) InputsFromSources() ([]string, error) {
+	switch v := s.InputsFrom.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("inputs_from entries must be strings, got %T", item)
+			}
+			out = append(out, str)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("inputs_from must be a string or a list of strings, got %T", v)
+	}
 }
 
 // Outcome defines a terminal state that a step can reach after execution.
@@ -227,8 +305,12 @@ type NextRunbook struct {
 }
 
 // CLIStepConfig is the configuration for a CLI step's command execution.
+// Executor, when set, runs the command on a remote host (ssh://user@host:22)
+// instead of locally.
 type CLIStepConfig struct {
-	Argv []string `yaml:"argv" json:"argv" jsonschema:"required,minItems=1"`
+	Argv     []string          `yaml:"argv"               json:"argv"               jsonschema:"required,minItems=1"`
+	Executor string            `yaml:"executor,omitempty" json:"executor,omitempty" jsonschema:"pattern=^ssh://"`
+	Env      map[string]string `yaml:"env,omitempty"      json:"env,omitempty"` // added to the command's environment; values are templates
 }
 
 // InvokeConfig specifies a child runbook to run inline as a sub-procedure.
@@ -243,6 +325,14 @@ type Gate struct {
 	OnError string   `yaml:"on_error,omitempty" json:"on_error,omitempty" jsonschema:"enum=skip"`
 }
 
+// RetryConfig re-runs a failing step. Max counts total attempts; Delay is the
+// base wait between attempts, scaled by Backoff (fixed, linear, exponential).
+type RetryConfig struct {
+	Max     int    `yaml:"max"               json:"max"               jsonschema:"required,minimum=1"`
+	Delay   string `yaml:"delay,omitempty"   json:"delay,omitempty"   jsonschema:"pattern=^[0-9]+(ms|s|m|h)$"`
+	Backoff string `yaml:"backoff,omitempty" json:"backoff,omitempty" jsonschema:"enum=fixed,enum=linear,enum=exponential"`
+}
+
 // Precondition defines a probe command that runs before a step.
 // If the probe succeeds (exit code 0), the step is auto-skipped with status "already_satisfied".
 // This makes steps idempotent — useful for installation or setup steps.
@@ -290,6 +380,18 @@ type Assertion struct {
 	Equals      string             `yaml:"equals"       json:"equals,omitempty"`
 	NotEquals   string             `yaml:"not_equals"   json:"not_equals,omitempty"`
 	JSONPath    *JSONPathAssertion `yaml:"json_path"    json:"json_path,omitempty"`
+
+	// DurationLessThan fails the step when it ran longer than this
+	// duration, e.g. "5s".
+	DurationLessThan string `yaml:"duration_less_than" json:"duration_less_than,omitempty"`
+
+	// ContainsAll passes when output contains every listed substring;
+	// ContainsNone when it contains none of them (e.g. error keywords).
+	ContainsAll  []string `yaml:"contains_all"  json:"contains_all,omitempty"  jsonschema:"minItems=1"`
+	ContainsNone []string `yaml:"contains_none" json:"contains_none,omitempty" jsonschema:"minItems=1"`
+
+	// Not inverts the assertion: it passes when the check fails.
+	Not bool `yaml:"not" json:"not,omitempty"`
 }
 
 // JSONPathAssertion is a structured query into JSON output.
@@ -317,16 +419,33 @@ func Load(r io.Reader) (*Runbook, error) {
 	}
 
 	rb, strictErr := decodeRunbookStrict(data)
-	if strictErr == nil {
-		return rb, nil
+	if strictErr != nil {
+		// Fallback for shorthand/verbose imports/tools forms.
+		var flexErr error
+		rb, flexErr = decodeRunbookFlexible(data)
+		if flexErr != nil {
+			return nil, fmt.Errorf("decode runbook: %w", strictErr)
+		}
 	}
+	rb.Meta.InputOrder = inputOrder(data)
+	return rb, nil
+}
 
-	// Fallback for shorthand/verbose imports/tools forms.
-	flexRB, flexErr := decodeRunbookFlexible(data)
-	if flexErr != nil {
-		return nil, fmt.Errorf("decode runbook: %w", strictErr)
+// inputOrder returns the keys of meta.inputs in document order.
+func inputOrder(data []byte) []string {
+	var doc struct {
+		Meta struct {
+			Inputs yaml.Node `yaml:"inputs"`
+		} `yaml:"meta"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil || doc.Meta.Inputs.Kind != yaml.MappingNode {
+		return nil
+	}
+	var names []string
+	for i := 0; i < len(doc.Meta.Inputs.Content); i += 2 {
+		names = append(names, doc.Meta.Inputs.Content[i].Value)
 	}
-	return flexRB, nil
+	return names
 }
 
 func decodeRunbookStrict(data []byte) (*Runbook, error) {
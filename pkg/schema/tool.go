@@ -106,6 +106,7 @@ type ToolStepConfig struct {
 	Name   string            `yaml:"name"           json:"name"   jsonschema:"required"`
 	Action string            `yaml:"action"         json:"action" jsonschema:"required"`
 	Args   map[string]string `yaml:"args,omitempty" json:"args,omitempty"`
+	Env    map[string]string `yaml:"env,omitempty"  json:"env,omitempty"` // added to the tool process environment (stdio transport)
 }
 
 // LoadToolFile reads and parses a .tool.yaml file with strict unknown-field rejection.
@@ -7,7 +7,10 @@ import (
 	"regexp"
 	"slices"
 	"strings"
+	"time"
 
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
 	sjsonschema "github.com/santhosh-tekuri/jsonschema/v6"
 )
 
@@ -231,6 +234,41 @@ func ValidateDomain(rb *Runbook) []*ValidationError {
 					})
 				}
 			}
+			errs = append(errs, validateInputValidation(name, input.Validation)...)
+		}
+	}
+
+	// Validate input when: conditions — they can only see inputs resolved
+	// before them, so later inputs read as empty
+	inputOrder := rb.Meta.OrderedInputs()
+	inputPos := make(map[string]int, len(inputOrder))
+	for i, name := range inputOrder {
+		inputPos[name] = i
+	}
+	for i, name := range inputOrder {
+		input := rb.Meta.Inputs[name]
+		if input == nil || input.When == "" {
+			continue
+		}
+		refs, err := InputWhenRefs(input.When)
+		if err != nil {
+			errs = append(errs, &ValidationError{
+				Phase:    "domain",
+				Path:     fmt.Sprintf("meta.inputs.%s.when", name),
+				Message:  fmt.Sprintf("invalid when expression in input %q: %v", name, err),
+				Severity: "error",
+			})
+			continue
+		}
+		for _, ref := range refs {
+			if j, ok := inputPos[ref]; ok && j >= i {
+				errs = append(errs, &ValidationError{
+					Phase:    "domain",
+					Path:     fmt.Sprintf("meta.inputs.%s.when", name),
+					Message:  fmt.Sprintf("input %q: when references %q, which is declared after it and reads as empty", name, ref),
+					Severity: "warning",
+				})
+			}
 		}
 	}
 
@@ -246,7 +284,9 @@ func ValidateDomain(rb *Runbook) []*ValidationError {
 
 	// Step ID uniqueness
 	seen := make(map[string]int)
+	flatIDs := make([]string, 0, len(rb.Steps))
 	for i, s := range rb.Steps {
+		flatIDs = append(flatIDs, s.ID)
 		if prev, ok := seen[s.ID]; ok {
 			errs = append(errs, &ValidationError{
 				Phase:    "domain",
@@ -310,6 +350,33 @@ func ValidateDomain(rb *Runbook) []*ValidationError {
 		if s.Precondition != nil {
 			errs = append(errs, validatePrecondition(i, s)...)
 		}
+
+		// Retry is meaningless for human-driven steps
+		if s.Retry != nil && s.Type == "manual" {
+			errs = append(errs, &ValidationError{
+				Phase:    "domain",
+				Path:     fmt.Sprintf("steps[%d].retry", i),
+				Message:  fmt.Sprintf("manual step %q cannot declare 'retry'", s.ID),
+				Severity: "error",
+			})
+		}
+
+		if s.OnFailure != "" {
+			errs = append(errs, validateOnFailure(fmt.Sprintf("steps[%d].on_failure", i), s, flatIDs)...)
+		}
+		if s.InputsFrom != nil {
+			errs = append(errs, validateInputsFrom(fmt.Sprintf("steps[%d].inputs_from", i), s)...)
+		}
+
+		// NS1: namespaced captures are keyed by step ID
+		if s.CaptureAsNamespace && s.ID == "" {
+			errs = append(errs, &ValidationError{
+				Phase:    "domain",
+				Path:     fmt.Sprintf("steps[%d].capture_as_namespace", i),
+				Message:  "capture_as_namespace requires the step to have an 'id'",
+				Severity: "error",
+			})
+		}
 	}
 
 	// Governance consistency: allowed & denied overlap
@@ -332,6 +399,8 @@ func ValidateDomain(rb *Runbook) []*ValidationError {
 			}
 		}
 
+		errs = append(errs, validateCommandRules(gov)...)
+
 		// Validate redaction regex patterns
 		for i, rule := range gov.Redact {
 			if _, err := regexp.Compile(rule.Pattern); err != nil {
@@ -415,6 +484,37 @@ func ValidateDomain(rb *Runbook) []*ValidationError {
 					})
 				}
 			}
+			if a.DurationLessThan != "" {
+				if _, err := time.ParseDuration(a.DurationLessThan); err != nil {
+					errs = append(errs, &ValidationError{
+						Phase:    "domain",
+						Path:     fmt.Sprintf("steps[%d].assertions[%d].duration_less_than", i, j),
+						Message:  fmt.Sprintf("invalid duration in 'duration_less_than' assertion: %v", err),
+						Severity: "error",
+					})
+				}
+			}
+			for _, f := range []struct {
+				name string
+				list []string
+			}{{"contains_all", a.ContainsAll}, {"contains_none", a.ContainsNone}} {
+				if f.list != nil && len(f.list) == 0 {
+					errs = append(errs, &ValidationError{
+						Phase:    "domain",
+						Path:     fmt.Sprintf("steps[%d].assertions[%d].%s", i, j, f.name),
+						Message:  fmt.Sprintf("'%s' assertion must list at least one string", f.name),
+						Severity: "error",
+					})
+				}
+			}
+			if a.Not && (a.NotContains != "" || a.NotEquals != "" || a.ContainsNone != nil) {
+				errs = append(errs, &ValidationError{
+					Phase:    "domain",
+					Path:     fmt.Sprintf("steps[%d].assertions[%d].not", i, j),
+					Message:  "'not' on a negative assertion is a double negative; use contains or equals instead",
+					Severity: "warning",
+				})
+			}
 			// Verify exactly one assertion field set
 			count := countAssertionFields(a)
 			if count != 1 {
@@ -595,6 +695,16 @@ func ValidateDomain(rb *Runbook) []*ValidationError {
 				if s.Type == "tool" {
 					errs = append(errs, validateToolStep(nodePath+".step", s, rb)...)
 				}
+				if s.OnFailure != "" {
+					scope := make([]string, 0, len(nodes))
+					for _, sib := range nodes {
+						scope = append(scope, sib.Step.ID)
+					}
+					errs = append(errs, validateOnFailure(nodePath+".step.on_failure", s, scope)...)
+				}
+				if s.InputsFrom != nil {
+					errs = append(errs, validateInputsFrom(nodePath+".step.inputs_from", s)...)
+				}
 				for _, b := range n.Branches {
 					walkTree(b.Steps, nodePath+".branches")
 				}
@@ -606,6 +716,147 @@ func ValidateDomain(rb *Runbook) []*ValidationError {
 	return errs
 }
 
+// validateOnFailure checks that a step's on_failure names another step in
+// the same scope; the engine only jumps between siblings.
+func validateOnFailure(path string, s Step, scope []string) []*ValidationError {
+	if s.OnFailure == s.ID {
+		return []*ValidationError{{
+			Phase:    "domain",
+			Path:     path,
+			Message:  fmt.Sprintf("step %q cannot route its own failure to itself", s.ID),
+			Severity: "error",
+		}}
+	}
+	if !slices.Contains(scope, s.OnFailure) {
+		return []*ValidationError{{
+			Phase:    "domain",
+			Path:     path,
+			Message:  fmt.Sprintf("step %q on_failure target %q not found in the same scope", s.ID, s.OnFailure),
+			Severity: "error",
+		}}
+	}
+	return nil
+}
+
+// validateCommandRules checks governance command globs. Patterns that
+// contain spaces but no wildcard only match one exact command line, which
+// is rarely what a policy author means, so they are warned about.
+func validateCommandRules(gov *GovernancePolicy) []*ValidationError {
+	var errs []*ValidationError
+	checkGlob := func(path, pattern string) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			errs = append(errs, &ValidationError{
+				Phase:    "domain",
+				Path:     path,
+				Message:  fmt.Sprintf("invalid command pattern %q: %v", pattern, err),
+				Severity: "error",
+			})
+			return
+		}
+		if strings.Contains(pattern, " ") && !strings.ContainsAny(pattern, "*?[") {
+			errs = append(errs, &ValidationError{
+				Phase:    "domain",
+				Path:     path,
+				Message:  fmt.Sprintf("command pattern %q has no wildcard and matches only that exact command line; did you mean %q?", pattern, pattern+" *"),
+				Severity: "warning",
+			})
+		}
+	}
+	for i, cmd := range gov.AllowedCommands {
+		checkGlob(fmt.Sprintf("meta.governance.allowed_commands[%d]", i), cmd)
+	}
+	for i, cmd := range gov.DeniedCommands {
+		checkGlob(fmt.Sprintf("meta.governance.denied_commands[%d]", i), cmd)
+	}
+	for i, rule := range gov.Rules {
+		path := fmt.Sprintf("meta.governance.rules[%d]", i)
+		if (rule.Command == "") == (rule.Pattern == "") {
+			errs = append(errs, &ValidationError{
+				Phase:    "domain",
+				Path:     path,
+				Message:  "governance rule must set exactly one of command or pattern",
+				Severity: "error",
+			})
+			continue
+		}
+		if rule.Pattern != "" {
+			checkGlob(path+".pattern", rule.Pattern)
+		}
+	}
+	return errs
+}
+
+// validateInputValidation checks that an input's validation spec can be
+// applied: a known type with the fields that type needs.
+func validateInputValidation(name string, v *InputValidation) []*ValidationError {
+	if v == nil {
+		return nil
+	}
+	path := fmt.Sprintf("meta.inputs.%s.validation", name)
+	invalid := func(msg string) []*ValidationError {
+		return []*ValidationError{{
+			Phase:    "domain",
+			Path:     path,
+			Message:  fmt.Sprintf("input %q: %s", name, msg),
+			Severity: "error",
+		}}
+	}
+	switch v.Type {
+	case "regex":
+		if v.Pattern == "" {
+			return invalid("regex validation requires a pattern")
+		}
+		if _, err := regexp.Compile(v.Pattern); err != nil {
+			return invalid(fmt.Sprintf("invalid regex pattern: %v", err))
+		}
+	case "range":
+		if v.Min == nil && v.Max == nil {
+			return invalid("range validation requires min or max")
+		}
+		if v.Min != nil && v.Max != nil && *v.Min > *v.Max {
+			return invalid(fmt.Sprintf("range min %v is greater than max %v", *v.Min, *v.Max))
+		}
+	case "enum":
+		if len(v.Options) == 0 {
+			return invalid("enum validation requires options")
+		}
+	default:
+		return invalid(fmt.Sprintf("unknown validation type %q (must be regex, range or enum)", v.Type))
+	}
+	return nil
+}
+
+// validateInputsFrom checks that inputs_from is a step ID or capture name (or
+// a list of them) on a tool step, the only step type with named inputs.
+func validateInputsFrom(path string, s Step) []*ValidationError {
+	if s.Type != "tool" {
+		return []*ValidationError{{
+			Phase:    "domain",
+			Path:     path,
+			Message:  fmt.Sprintf("step %q: inputs_from is only supported on tool steps", s.ID),
+			Severity: "error",
+		}}
+	}
+	sources, err := s.InputsFromSources()
+	if err != nil {
+		return []*ValidationError{{
+			Phase:    "domain",
+			Path:     path,
+			Message:  fmt.Sprintf("step %q: %v", s.ID, err),
+			Severity: "error",
+		}}
+	}
+	if slices.Contains(sources, "") || slices.Contains(sources, s.ID) {
+		return []*ValidationError{{
+			Phase:    "domain",
+			Path:     path,
+			Message:  fmt.Sprintf("step %q: inputs_from entries must name an earlier step or capture", s.ID),
+			Severity: "error",
+		}}
+	}
+	return nil
+}
+
 // countAssertionFields returns the number of assertion fields set.
 func countAssertionFields(a Assertion) int {
 	count := 0
@@ -630,6 +881,15 @@ func countAssertionFields(a Assertion) int {
 	if a.JSONPath != nil {
 		count++
 	}
+	if a.DurationLessThan != "" {
+		count++
+	}
+	if a.ContainsAll != nil {
+		count++
+	}
+	if a.ContainsNone != nil {
+		count++
+	}
 	return count
 }
 
@@ -872,3 +1132,28 @@ func validateToolStep(path string, s Step, rb *Runbook) []*ValidationError {
 
 	return errs
 }
+
+// InputWhenRefs returns the identifiers an input's when: expression
+// references, in order of first use.
+func InputWhenRefs(when string) ([]string, error) {
+	tree, err := parser.Parse(when)
+	if err != nil {
+		return nil, err
+	}
+	v := &identVisitor{seen: make(map[string]bool)}
+	ast.Walk(&tree.Node, v)
+	return v.names, nil
+}
+
+// identVisitor collects identifier names from an expr-lang AST.
+type identVisitor struct {
+	names []string
+	seen  map[string]bool
+}
+
+func (v *identVisitor) Visit(node *ast.Node) {
+	if id, ok := (*node).(*ast.IdentifierNode); ok && !v.seen[id.Value] {
+		v.seen[id.Value] = true
+		v.names = append(v.names, id.Value)
+	}
+}
@@ -1,6 +1,7 @@
 package schema
 
 import (
+	"fmt"
 	"strings"
 	"testing"
 )
@@ -224,3 +225,275 @@ func TestValidateAPIVersionCheck(t *testing.T) {
 		t.Fatal("expected error for unrecognized apiVersion")
 	}
 }
+
+// TestValidateCaptureNamespaceRequiresID checks NS1: capture_as_namespace needs a step id.
+func TestValidateCaptureNamespaceRequiresID(t *testing.T) {
+	rb := &Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       Meta{Name: "ns-no-id"},
+		Steps: []Step{
+			{
+				Type:               "cli",
+				With:               &CLIStepConfig{Argv: []string{"echo"}},
+				Capture:            map[string]string{"status": "stdout"},
+				CaptureAsNamespace: true,
+			},
+		},
+	}
+	errs := ValidateDomain(rb)
+	found := false
+	for _, e := range errs {
+		if strings.Contains(e.Path, "capture_as_namespace") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected capture_as_namespace error, got: %v", errs)
+	}
+}
+
+// TestValidateRetryOnManual checks that manual steps cannot declare retry.
+func TestValidateRetryOnManual(t *testing.T) {
+	rb := &Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       Meta{Name: "retry-manual"},
+		Steps: []Step{
+			{ID: "confirm", Type: "manual", Instructions: "Confirm", Retry: &RetryConfig{Max: 3}},
+		},
+	}
+	errs := ValidateDomain(rb)
+	found := false
+	for _, e := range errs {
+		if strings.Contains(e.Path, "retry") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected retry error, got: %v", errs)
+	}
+}
+
+// TestValidateOnFailureScope checks that on_failure targets a sibling step.
+func TestValidateOnFailureScope(t *testing.T) {
+	rb := &Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       Meta{Name: "on-failure"},
+		Tree: []TreeNode{
+			{
+				Step: Step{ID: "check", Type: "manual", Instructions: "Check"},
+				Branches: []Branch{{
+					Condition: "true",
+					Steps: []TreeNode{
+						{Step: Step{ID: "drain", Type: "cli", With: &CLIStepConfig{Argv: []string{"drain"}}, OnFailure: "cleanup"}},
+						{Step: Step{ID: "loop", Type: "cli", With: &CLIStepConfig{Argv: []string{"loop"}}, OnFailure: "loop"}},
+					},
+				}},
+			},
+			{Step: Step{ID: "cleanup", Type: "manual", Instructions: "Clean up"}},
+			{Step: Step{ID: "notify", Type: "manual", Instructions: "Notify", OnFailure: "cleanup"}},
+		},
+	}
+	errs := ValidateDomain(rb)
+	var paths []string
+	for _, e := range errs {
+		if strings.HasSuffix(e.Path, "on_failure") {
+			paths = append(paths, e.Path)
+		}
+	}
+	// drain's target is outside its branch; loop targets itself; notify is valid
+	if len(paths) != 2 {
+		t.Errorf("expected 2 on_failure errors, got: %v", errs)
+	}
+}
+
+// TestValidateInputsFrom checks inputs_from is a name or list of names on a tool step.
+func TestValidateInputsFrom(t *testing.T) {
+	tool := &ToolStepConfig{Name: "svc", Action: "scale"}
+	rb := &Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       Meta{Name: "inputs-from"},
+		Steps: []Step{
+			{ID: "describe", Type: "tool", Tool: tool},
+			{ID: "scale", Type: "tool", Tool: tool, InputsFrom: []any{"describe", "patch"}},
+			{ID: "bad_list", Type: "tool", Tool: tool, InputsFrom: []any{"describe", 3}},
+			{ID: "run", Type: "cli", With: &CLIStepConfig{Argv: []string{"run"}}, InputsFrom: "describe"},
+		},
+	}
+	var paths []string
+	for _, e := range ValidateDomain(rb) {
+		if strings.HasSuffix(e.Path, "inputs_from") {
+			paths = append(paths, e.Path)
+		}
+	}
+	want := []string{"steps[2].inputs_from", "steps[3].inputs_from"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("inputs_from errors at %v, want %v", paths, want)
+	}
+}
+
+// TestValidateInputValidation checks meta.inputs validation specs are usable.
+func TestValidateInputValidation(t *testing.T) {
+	lo, hi := 10.0, 1.0
+	rb := &Runbook{
+		APIVersion: "runbook/v0",
+		Meta: Meta{Name: "input-validation", Inputs: map[string]*InputDef{
+			"ok":        {From: "prompt", Validation: &InputValidation{Type: "enum", Options: []string{"a"}}},
+			"bad_regex": {From: "prompt", Validation: &InputValidation{Type: "regex", Pattern: "("}},
+			"bad_range": {From: "prompt", Validation: &InputValidation{Type: "range", Min: &lo, Max: &hi}},
+			"no_opts":   {From: "prompt", Validation: &InputValidation{Type: "enum"}},
+			"bad_type":  {From: "prompt", Validation: &InputValidation{Type: "length"}},
+		}},
+		Steps: []Step{{ID: "s1", Type: "cli", With: &CLIStepConfig{Argv: []string{"echo"}}}},
+	}
+	got := map[string]bool{}
+	for _, e := range ValidateDomain(rb) {
+		if strings.HasSuffix(e.Path, ".validation") {
+			got[strings.TrimSuffix(strings.TrimPrefix(e.Path, "meta.inputs."), ".validation")] = true
+		}
+	}
+	for _, name := range []string{"bad_regex", "bad_range", "no_opts", "bad_type"} {
+		if !got[name] {
+			t.Errorf("expected validation error for input %q, got %v", name, got)
+		}
+	}
+	if got["ok"] || len(got) != 4 {
+		t.Errorf("unexpected validation errors: %v", got)
+	}
+}
+
+// TestValidateInputWhen checks when: expressions parse and only look back
+// at earlier inputs, using the declaration order Load records.
+func TestValidateInputWhen(t *testing.T) {
+	rb, err := Load(strings.NewReader(`apiVersion: runbook/v0
+meta:
+  name: input-when
+  inputs:
+    region:
+      from: prompt
+      when: cloud == "aws"
+    cloud:
+      from: prompt
+    zone:
+      from: prompt
+      when: region != "" && cloud != "gcp"
+    broken:
+      from: prompt
+      when: cloud ==
+steps:
+  - id: s1
+    type: cli
+    with:
+      argv: [echo]
+`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := strings.Join(rb.Meta.InputOrder, ","); got != "region,cloud,zone,broken" {
+		t.Errorf("InputOrder = %s", got)
+	}
+	got := map[string]string{}
+	for _, e := range ValidateDomain(rb) {
+		if strings.HasSuffix(e.Path, ".when") {
+			got[e.Path] = e.Severity
+		}
+	}
+	want := map[string]string{
+		"meta.inputs.region.when": "warning",
+		"meta.inputs.broken.when": "error",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("when findings = %v, want %v", got, want)
+	}
+}
+
+// TestValidateCommandRules checks governance globs and rule shape.
+func TestValidateNotAssertion(t *testing.T) {
+	rb, err := Load(strings.NewReader(`apiVersion: runbook/v0
+meta:
+  name: not-assert
+steps:
+  - id: s1
+    type: cli
+    with:
+      argv: [echo, ok]
+    assertions:
+      - equals: error
+        not: true
+      - not_equals: error
+        not: true
+`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var got []string
+	for _, e := range ValidateDomain(rb) {
+		if strings.HasSuffix(e.Path, ".not") {
+			got = append(got, e.Path+" "+e.Severity)
+		}
+	}
+	if want := "steps[0].assertions[1].not warning"; len(got) != 1 || got[0] != want {
+		t.Errorf("not findings = %v, want [%s]", got, want)
+	}
+}
+
+func TestValidateContainsAllNone(t *testing.T) {
+	rb, err := Load(strings.NewReader(`apiVersion: runbook/v0
+meta:
+  name: contains-lists
+steps:
+  - id: s1
+    type: cli
+    with:
+      argv: [echo, ok]
+    assertions:
+      - contains_all: [ok]
+      - contains_none: []
+`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	var got []string
+	for _, e := range ValidateDomain(rb) {
+		if strings.HasPrefix(e.Path, "steps[0].assertions") {
+			got = append(got, e.Path+": "+e.Message)
+		}
+	}
+	want := "steps[0].assertions[1].contains_none: 'contains_none' assertion must list at least one string"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("findings = %v, want [%s]", got, want)
+	}
+}
+
+func TestValidateCommandRules(t *testing.T) {
+	rb := &Runbook{
+		APIVersion: "runbook/v0",
+		Meta: Meta{Name: "command-rules", Governance: &GovernancePolicy{
+			DeniedCommands: []string{"kubectl delete", "rm"},
+			Rules: []CommandRule{
+				{Action: "deny", Pattern: "az vm delete *"},
+				{Action: "deny", Pattern: "az [vm"},
+				{Action: "allow", Command: "kubectl", Pattern: "kubectl *"},
+			},
+		}},
+		Steps: []Step{{ID: "s1", Type: "cli", With: &CLIStepConfig{Argv: []string{"echo"}}}},
+	}
+	got := map[string]string{}
+	for _, e := range ValidateDomain(rb) {
+		if strings.HasPrefix(e.Path, "meta.governance.") {
+			got[e.Path] = e.Severity
+		}
+	}
+	want := map[string]string{
+		"meta.governance.denied_commands[0]": "warning", // literal with spaces
+		"meta.governance.rules[1].pattern":   "error",   // bad glob
+		"meta.governance.rules[2]":           "error",   // command and pattern
+	}
+	if len(got) != len(want) {
+		t.Errorf("governance findings = %v, want %v", got, want)
+	}
+	for path, sev := range want {
+		if got[path] != sev {
+			t.Errorf("%s: severity %q, want %q", path, got[path], sev)
+		}
+	}
+}
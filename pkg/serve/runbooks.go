@@ -0,0 +1,167 @@
+package serve
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// maxListedRunbooks caps runbook/list results; larger trees report truncated.
+const maxListedRunbooks = 500
+
+// RunbookListParams are the params for runbook/list.
+type RunbookListParams struct {
+	Dir       string `json:"dir"`       // defaults to the project root (or working directory)
+	Recursive bool   `json:"recursive"` // descend into subdirectories
+}
+
+// RunbookEntry describes one runbook found by runbook/list.
+type RunbookEntry struct {
+	Path       string `json:"path"`
+	Name       string `json:"name"`
+	StepCount  int    `json:"stepCount"` // top-level steps (or tree nodes)
+	Kind       string `json:"kind,omitempty"`
+	APIVersion string `json:"apiVersion"`
+}
+
+// runbookHeader is the part of a runbook file runbook/list reads. It is
+// decoded loosely so that runbooks with validation errors are still listed.
+type runbookHeader struct {
+	APIVersion string `yaml:"apiVersion"`
+	Meta       struct {
+		Name string `yaml:"name"`
+		Kind string `yaml:"kind"`
+	} `yaml:"meta"`
+	Steps []yaml.Node `yaml:"steps"`
+	Tree  []yaml.Node `yaml:"tree"`
+}
+
+// handleRunbookList finds runbook files (runbook/* and kernel/* apiVersions)
+// under a directory, sorted by path.
+func (s *Server) handleRunbookList(msg *Message) {
+	var params RunbookListParams
+	if msg.Params != nil {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
+			return
+		}
+	}
+
+	dir := params.Dir
+	if dir == "" {
+		dir = "."
+	}
+	proj, err := schema.DiscoverProject(dir)
+	if err != nil {
+		s.sendError(msg.ID, -32603, err.Error())
+		return
+	}
+	if params.Dir == "" && proj != nil {
+		dir = proj.Root
+	}
+
+	entries, truncated, err := listRunbooks(dir, params.Recursive)
+	if err != nil {
+		s.sendError(msg.ID, -32603, err.Error())
+		return
+	}
+	result := map[string]interface{}{
+		"dir":       dir,
+		"runbooks":  entries,
+		"truncated": truncated,
+	}
+	if proj != nil {
+		result["project"] = proj.Name
+		result["projectRoot"] = proj.Root
+	}
+	s.sendResult(msg.ID, result)
+}
+
+// listRunbooks scans dir for runbook files, skipping .git and .runbook
+// directories. At most maxListedRunbooks entries are returned.
+func listRunbooks(dir string, recursive bool) ([]RunbookEntry, bool, error) {
+	entries := []RunbookEntry{}
+	truncated := false
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			return nil // unreadable entries are skipped
+		}
+		if d.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if !recursive || d.Name() == ".git" || d.Name() == ".runbook" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(d.Name(), ".yaml") {
+			return nil
+		}
+		entry, ok := readRunbookEntry(path)
+		if !ok {
+			return nil
+		}
+		if len(entries) == maxListedRunbooks {
+			truncated = true
+			return filepath.SkipAll
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, truncated, nil
+}
+
+// readRunbookEntry reports whether path is a runbook: its first
+// non-comment line must be "apiVersion: runbook/..." or "apiVersion: kernel/...".
+func readRunbookEntry(path string) (RunbookEntry, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RunbookEntry{}, false
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		version := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "apiVersion:")), `"'`)
+		if !strings.HasPrefix(line, "apiVersion:") ||
+			!(strings.HasPrefix(version, "runbook/") || strings.HasPrefix(version, "kernel/")) {
+			return RunbookEntry{}, false
+		}
+		break
+	}
+
+	var h runbookHeader
+	if err := yaml.Unmarshal(data, &h); err != nil {
+		return RunbookEntry{}, false
+	}
+	steps := len(h.Steps)
+	if len(h.Tree) > 0 {
+		steps = len(h.Tree)
+	}
+	return RunbookEntry{
+		Path:       path,
+		Name:       h.Meta.Name,
+		StepCount:  steps,
+		Kind:       h.Meta.Kind,
+		APIVersion: h.APIVersion,
+	}, h.APIVersion != ""
+}
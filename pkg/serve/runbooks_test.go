@@ -0,0 +1,82 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandleRunbookList(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("gert.yaml", "name: ops\n")
+	write("b.runbook.yaml", "# triage\napiVersion: runbook/v0\nmeta:\n  name: triage\n  kind: mitigation\ntree:\n  - step: {id: a, type: manual}\n  - step: {id: b, type: manual}\n")
+	write("nested/a.yaml", "apiVersion: kernel/v0\nmeta:\n  name: restart\nsteps:\n  - id: go\n    type: manual\n")
+	write("tools/ping.tool.yaml", "apiVersion: tool/v0\nmeta:\n  name: ping\n")
+	write("config.yaml", "logLevel: debug\n")
+	write(".runbook/runs/r1/copy.yaml", "apiVersion: runbook/v0\nmeta:\n  name: copy\n")
+	write(".git/x.yaml", "apiVersion: runbook/v0\nmeta:\n  name: git\n")
+
+	list := func(params string) (result struct {
+		Project   string         `json:"project"`
+		Runbooks  []RunbookEntry `json:"runbooks"`
+		Truncated bool           `json:"truncated"`
+	}) {
+		t.Helper()
+		var out bytes.Buffer
+		s := NewWithIO(strings.NewReader(""), &out)
+		id := 1
+		s.dispatch(&Message{JSONRPC: "2.0", ID: &id, Method: "runbook/list", Params: json.RawMessage(params)})
+		msgs := decodeMessages(t, &out)
+		if msgs[0].Error != nil {
+			t.Fatalf("runbook/list: %s", msgs[0].Error.Message)
+		}
+		if err := json.Unmarshal(msgs[0].Result, &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	got := list(fmt.Sprintf(`{"dir": %q, "recursive": true}`, dir))
+	if got.Project != "ops" || got.Truncated || len(got.Runbooks) != 2 {
+		t.Fatalf("result = %+v", got)
+	}
+	triage, restart := got.Runbooks[0], got.Runbooks[1]
+	if triage.Name != "triage" || triage.StepCount != 2 || triage.Kind != "mitigation" || triage.APIVersion != "runbook/v0" {
+		t.Errorf("triage = %+v", triage)
+	}
+	if restart.Path != filepath.Join(dir, "nested", "a.yaml") || restart.StepCount != 1 || restart.APIVersion != "kernel/v0" {
+		t.Errorf("restart = %+v", restart)
+	}
+
+	if got := list(fmt.Sprintf(`{"dir": %q}`, dir)); len(got.Runbooks) != 1 || got.Runbooks[0].Name != "triage" {
+		t.Errorf("non-recursive = %+v", got.Runbooks)
+	}
+}
+
+func TestListRunbooks_Truncated(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < maxListedRunbooks+1; i++ {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("rb-%03d.yaml", i)), []byte("apiVersion: runbook/v0\nmeta:\n  name: x\n"), 0o644)
+	}
+	entries, truncated, err := listRunbooks(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != maxListedRunbooks || !truncated {
+		t.Errorf("got %d entries, truncated=%v", len(entries), truncated)
+	}
+}
@@ -1,24 +1,32 @@
 // Package serve implements the JSON-RPC server for the gert VS Code extension.
-// It communicates over stdio (stdin/stdout) using newline-delimited JSON messages.
+// It communicates over stdio (stdin/stdout) using newline-delimited JSON
+// messages, or over WebSocket with one message per frame (see ListenWebSocket).
 package serve
 
 import (
-	"bufio"
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ormasoftchile/gert/pkg/diagram"
+	"github.com/ormasoftchile/gert/pkg/governance"
 	"github.com/ormasoftchile/gert/pkg/inputs"
+	"github.com/ormasoftchile/gert/pkg/inputs/akv"
+	kvalidate "github.com/ormasoftchile/gert/pkg/kernel/validate"
+	"github.com/ormasoftchile/gert/pkg/logging"
 	"github.com/ormasoftchile/gert/pkg/providers"
 	"github.com/ormasoftchile/gert/pkg/replay"
+	"github.com/ormasoftchile/gert/pkg/runs"
 	"github.com/ormasoftchile/gert/pkg/runtime"
 	"github.com/ormasoftchile/gert/pkg/schema"
 	"github.com/ormasoftchile/gert/pkg/tools"
@@ -61,6 +69,7 @@ type ExecStartParams struct {
 	Actor       string            `json:"actor,omitempty"`
 	ResumeRunID string            `json:"resumeRunId,omitempty"` // if set, resume an existing run
 	Display     *DisplayConfig    `json:"display,omitempty"`     // UI display preferences
+	Strict      bool              `json:"strict,omitempty"`      // reject the runbook on validation warnings too
 }
 
 // SubmitEvidenceParams are the parameters for exec/submitEvidence.
@@ -69,10 +78,20 @@ type SubmitEvidenceParams struct {
 	Evidence map[string]*providers.EvidenceValue `json:"evidence"`
 }
 
+// HistoryEntry is one completed step as returned by exec/getHistory and in
+// the exec/resume result.
+type HistoryEntry struct {
+	StepID    string            `json:"stepId"`
+	Status    string            `json:"status"`
+	Captures  map[string]string `json:"captures,omitempty"`
+	StartedAt time.Time         `json:"startedAt"`
+	EndedAt   time.Time         `json:"endedAt"`
+	Error     string            `json:"error,omitempty"`
+}
+
 // Server is the JSON-RPC server that wraps the gert engine.
 type Server struct {
-	reader  *bufio.Reader
-	writer  io.Writer
+	conn    transport
 	mu      sync.Mutex
 	engine  *runtime.Engine
 	runbook *schema.Runbook
@@ -94,13 +113,67 @@ type Server struct {
 	// Invoke stack for nested runbook execution
 	invokeStack []invokeFrame
 
-	// Session persistence — root run's base dir for session.json
+	// Root run's artifact dir, and its session.json ("" when persistence
+	// is disabled)
 	rootBaseDir string
+	sessionFile string
 
 	// Display preferences from exec/start (echoed back to client)
 	display *DisplayConfig
+
+	// Rewind points recorded before each tree step, oldest first
+	rewindPoints []rewindPoint
+
+	// AllowRewind permits exec/rewind over side-effect steps in real mode
+	// (the host's --allow-rewind flag).
+	AllowRewind bool
+
+	// AllowedOrigins lists browser origins (e.g. "vscode-webview://abc")
+	// allowed to open a WebSocket connection besides same-origin pages; "*"
+	// allows any. Unused over stdio.
+	AllowedOrigins []string
+
+	// SessionDir holds session files as <SessionDir>/<root_run_id>/session.json
+	// (the host's --session-dir flag). Empty keeps sessions in memory only.
+	SessionDir string
+
+	// SessionTTL is how long a finished run directory in SessionDir is kept
+	// (the host's --session-ttl flag). While the server runs, older ones are
+	// removed at startup and every 24 hours. Zero disables cleanup.
+	SessionTTL time.Duration
+
+	// Logger receives the server's diagnostics (the host's --log-level and
+	// --log-format flags). It defaults to text records on stderr and is
+	// handed to every engine the server creates.
+	Logger *slog.Logger
+
+	// Heartbeat, when positive, is how long the client may stay silent
+	// before the server sends a ping notification (the host's --heartbeat
+	// flag). After two unanswered pings the server shuts down. Zero disables it.
+	Heartbeat time.Duration
+
+	// EvidenceCollector selects where real-mode evidence is stored (the
+	// host's --evidence-collector flag). "azureblob" uploads it to the
+	// container configured by AZURE_STORAGE_* variables; empty keeps it local.
+	EvidenceCollector string
+
+	// TelemetryEndpoint, when set, receives an anonymous RunTelemetry report
+	// for each completed run (the host's --telemetry-endpoint flag).
+	// NoTelemetry (--no-telemetry) or GERT_NO_TELEMETRY=1 turns it off.
+	TelemetryEndpoint string
+	NoTelemetry       bool
+
+	// telemetryRunID is the root run already reported to TelemetryEndpoint.
+	telemetryRunID string
+
+	// lastReceived is the UnixNano time of the last message from the client.
+	lastReceived atomic.Int64
 }
 
+// DefaultSessionDir is where sessions are persisted unless the host sets
+// Server.SessionDir, relative to the working directory.
+var DefaultSessionDir = filepath.Join(".runbook", "runs")
+
 // invokeFrame stores parent context when entering a child invoke runbook.
 type invokeFrame struct {
 	parentEngine  *runtime.Engine
@@ -112,6 +185,18 @@ type invokeFrame struct {
 	msg           *Message          // the exec/next message that triggered the invoke
 }
 
+// rewindPoint records the cursor just before a tree step executed, so
+// exec/rewind can requeue that step and everything after it.
+type rewindPoint struct {
+	stepID   string
+	stepIdx  int
+	runID    string            // engine run the step belongs to (invoke boundary)
+	vars     map[string]string // vars before the step
+	captures map[string]string // captures before the step; nil for points from older sessions
+	history  int               // length of the run's history before the step
+	pending  []pendingNode     // cursor queue with the step at its head
+}
+
 // treeCursor walks a tree one step at a time.
 // After each step, it evaluates outcomes/branches and queues the next steps.
 type treeCursor struct {
@@ -171,6 +256,38 @@ func (tc *treeCursor) insertBranchSteps(nodes []schema.TreeNode, depth int) {
 	tc.pending = append(items, tc.pending...)
 }
 
+// routeTo replaces the remaining siblings of a step at depth with nodes,
+// the scope's suffix starting at an on_failure target.
+func (tc *treeCursor) routeTo(nodes []schema.TreeNode, depth int) {
+	rest := tc.pending
+	for len(rest) > 0 && rest[0].depth == depth && rest[0].watchpoint == nil && rest[0].overWatchpoint == nil {
+		rest = rest[1:]
+	}
+	tc.pending = rest
+	tc.insertBranchSteps(nodes, depth)
+}
+
+// findTreeScope returns the sibling list containing the step with id,
+// searching branches and iterate bodies.
+func findTreeScope(nodes []schema.TreeNode, id string) []schema.TreeNode {
+	for _, n := range nodes {
+		if n.Step.ID == id {
+			return nodes
+		}
+		if n.Iterate != nil {
+			if scope := findTreeScope(n.Iterate.Steps, id); scope != nil {
+				return scope
+			}
+		}
+		for _, b := range n.Branches {
+			if scope := findTreeScope(b.Steps, id); scope != nil {
+				return scope
+			}
+		}
+	}
+	return nil
+}
+
 // pushIteratePass inserts the iterate steps followed by a watchpoint into
 // the front of the cursor queue. The watchpoint fires after all steps in
 // the pass complete, triggering convergence evaluation.
@@ -203,60 +320,155 @@ func (tc *treeCursor) pushIterateOverPass(block *schema.IterateBlock, items []st
 
 // New creates a new server reading from stdin and writing to stdout.
 func New() *Server {
-	ctx, cancel := context.WithCancel(context.Background())
-	return &Server{
-		reader:     bufio.NewReader(os.Stdin),
-		writer:     os.Stdout,
-		ctx:        ctx,
-		cancel:     cancel,
-		nextCh:     make(chan struct{}, 1),
-		evidenceCh: make(chan SubmitEvidenceParams, 1),
-	}
+	return NewWithIO(os.Stdin, os.Stdout)
 }
 
 // NewWithIO creates a server reading from r and writing to w instead of stdio.
 // Used by the TUI to communicate over in-memory pipes.
 func NewWithIO(r io.Reader, w io.Writer) *Server {
+	return newWithTransport(newStreamTransport(r, w))
+}
+
+func newWithTransport(conn transport) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
-		reader:     bufio.NewReader(r),
-		writer:     w,
+		conn:       conn,
 		ctx:        ctx,
 		cancel:     cancel,
 		nextCh:     make(chan struct{}, 1),
 		evidenceCh: make(chan SubmitEvidenceParams, 1),
+		SessionDir: DefaultSessionDir,
+		SessionTTL: runs.DefaultTTL,
+		Logger:     logging.Default(),
 	}
 }
 
-// Run starts the server main loop — reads messages from stdin and dispatches them.
+// Run starts the server main loop — reads messages from the transport and
+// dispatches them until the client disconnects or the server is shut down
+// (a shutdown request, or a client that stopped answering heartbeat pings).
 func (s *Server) Run() error {
 	defer s.cancel()
 
-	scanner := bufio.NewScanner(s.reader)
-	// Increase buffer for large messages
-	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024)
+	type received struct {
+		msg *Message
+		err error
+	}
+	incoming := make(chan received)
+	go func() {
+		for {
+			msg, err := s.conn.recv()
+			// Record the message as soon as it is read, and absorb pongs
+			// here, so a client answering pings during a slow handler
+			// is not taken for a silent one.
+			s.lastReceived.Store(time.Now().UnixNano())
+			if err == nil && msg.Method == "pong" {
+				continue
+			}
+			select {
+			case incoming <- received{msg, err}:
+			case <-s.ctx.Done():
+				return
+			}
+			var perr *parseError
+			if err != nil && !errors.As(err, &perr) {
+				return
+			}
+		}
+	}()
+
+	s.lastReceived.Store(time.Now().UnixNano())
+	if s.Heartbeat > 0 {
+		go s.heartbeat(s.Heartbeat)
+	}
+	if s.SessionTTL > 0 && s.SessionDir != "" {
+		go s.expireSessions(s.SessionTTL)
+	}
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
+	for {
+		var r received
+		select {
+		case r = <-incoming:
+		case <-s.ctx.Done():
+			return nil
+		}
+		var perr *parseError
+		switch {
+		case errors.As(r.err, &perr):
+			s.sendError(nil, -32700, perr.Error())
 			continue
+		case errors.Is(r.err, io.EOF):
+			return nil
+		case r.err != nil:
+			return r.err
 		}
+		s.dispatch(r.msg)
+	}
+}
 
-		var msg Message
-		if err := json.Unmarshal(line, &msg); err != nil {
-			s.sendError(nil, -32700, fmt.Sprintf("parse error: %v", err))
+// heartbeat pings a client that has been silent for a full interval and
+// shuts the server down once two pings in a row go unanswered. Any message
+// from the client counts as an answer.
+func (s *Server) heartbeat(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	missed := 0
+	var pinged time.Time
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		last := time.Unix(0, s.lastReceived.Load())
+		if last.After(pinged) {
+			missed = 0
+		}
+		if time.Since(last) < interval {
 			continue
 		}
-
-		s.dispatch(&msg)
+		if missed == 2 {
+			s.Logger.Warn("no reply to pings, shutting down", slog.Int("missed", missed))
+			s.cancel()
+			return
+		}
+		pinged = time.Now()
+		missed++
+		s.send(&Message{JSONRPC: "2.0", Method: "ping"})
 	}
+}
+
+// sessionCleanupInterval is how often expireSessions looks for stale runs.
+var sessionCleanupInterval = 24 * time.Hour
 
-	return scanner.Err()
+// expireSessions removes run directories in SessionDir that finished more
+// than ttl ago, once now and then every sessionCleanupInterval until the
+// server stops. Runs still in progress are never touched.
+func (s *Server) expireSessions(ttl time.Duration) {
+	ticker := time.NewTicker(sessionCleanupInterval)
+	defer ticker.Stop()
+	for {
+		removed, err := runs.Clean(s.SessionDir, ttl, false)
+		for _, r := range removed {
+			s.Logger.Info("removed expired run", slog.String("runId", r.RunID), slog.Time("endedAt", r.EndedAt))
+		}
+		if err != nil {
+			s.Logger.Error("session cleanup failed", slog.Any("error", err))
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
 
 // dispatch routes a message to the appropriate handler.
 func (s *Server) dispatch(msg *Message) {
 	switch msg.Method {
+	case "pong":
+		// heartbeat reply; Run's reader records it
+	case "exec/plan":
+		s.handleExecPlan(msg)
 	case "exec/start":
 		s.handleExecStart(msg)
 		s.saveSession()
@@ -272,14 +484,34 @@ func (s *Server) dispatch(msg *Message) {
 	case "exec/submitEvidence":
 		s.handleSubmitEvidence(msg)
 		s.saveSession()
+	case "exec/setVar":
+		s.handleSetVar(msg)
+		s.saveSession()
 	case "exec/getVariables":
 		s.handleGetVariables(msg)
 	case "exec/getManifest":
 		s.handleGetManifest(msg)
+	case "exec/getHistory":
+		s.handleGetHistory(msg)
 	case "exec/saveScenario":
 		s.handleSaveScenario(msg)
+	case "exec/previewNext":
+		s.handlePreviewNext(msg)
+	case "exec/getStepDef":
+		s.handleGetStepDef(msg)
+	case "exec/rewind":
+		s.handleRewind(msg)
+		s.saveSession()
 	case "runbook/diagram":
 		s.handleDiagram(msg)
+	case "runbook/diff":
+		s.handleRunbookDiff(msg)
+	case "runbook/validate":
+		s.handleValidate(msg)
+	case "runbook/inspect":
+		s.handleInspect(msg)
+	case "runbook/list":
+		s.handleRunbookList(msg)
 	case "shutdown":
 		s.cancel()
 		s.sendResult(msg.ID, map[string]string{"status": "shutting down"})
@@ -288,6 +520,50 @@ func (s *Server) dispatch(msg *Message) {
 	}
 }
 
+// handleExecPlan returns the execution plan for a runbook without starting
+// a run. It takes the same params as exec/start; the current run, if any,
+// is left untouched.
+func (s *Server) handleExecPlan(msg *Message) {
+	var params ExecStartParams
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
+		return
+	}
+	if params.Cwd != "" {
+		if err := os.Chdir(params.Cwd); err != nil {
+			s.Logger.Warn("failed to chdir", slog.String("cwd", params.Cwd), slog.Any("error", err))
+		}
+	}
+
+	rb, errs := schema.ValidateFile(params.Runbook)
+	if hasServeValidationErrors(errs) {
+		s.sendError(msg.ID, -32603, fmt.Sprintf("validation failed: %v", firstServeError(errs)))
+		return
+	}
+	if rb.Meta.Vars == nil {
+		rb.Meta.Vars = make(map[string]string)
+	}
+	for k, v := range params.Vars {
+		rb.Meta.Vars[k] = v
+	}
+	for name, input := range rb.Meta.Inputs {
+		if _, ok := rb.Meta.Vars[name]; !ok && input.Default != "" {
+			rb.Meta.Vars[name] = input.Default
+		}
+	}
+
+	mode := params.Mode
+	if mode == "" {
+		mode = "real"
+	}
+	plan, err := runtime.PlanRunbook(s.ctx, rb, mode)
+	if err != nil {
+		s.sendError(msg.ID, -32603, fmt.Sprintf("plan: %v", err))
+		return
+	}
+	s.sendResult(msg.ID, plan)
+}
+
 // handleExecStart loads a runbook and starts execution in a goroutine.
 func (s *Server) handleExecStart(msg *Message) {
 	var params ExecStartParams
@@ -302,14 +578,14 @@ func (s *Server) handleExecStart(msg *Message) {
 		return
 	}
 
-	fmt.Fprintf(os.Stderr, "serve: exec/start runbook=%q mode=%q scenarioDir=%q\n", params.Runbook, params.Mode, params.ScenarioDir)
+	s.Logger.Info("exec/start", slog.String("runbook", params.Runbook), slog.String("mode", params.Mode), slog.String("scenarioDir", params.ScenarioDir))
 
 	// Change working directory if specified (so child commands resolve relative paths correctly)
 	if params.Cwd != "" {
 		if err := os.Chdir(params.Cwd); err != nil {
-			fmt.Fprintf(os.Stderr, "serve: WARNING failed to chdir to %q: %v\n", params.Cwd, err)
+			s.Logger.Warn("failed to chdir", slog.String("cwd", params.Cwd), slog.Any("error", err))
 		} else {
-			fmt.Fprintf(os.Stderr, "serve: chdir to %q\n", params.Cwd)
+			s.Logger.Debug("chdir", slog.String("cwd", params.Cwd))
 		}
 	}
 
@@ -319,6 +595,11 @@ func (s *Server) handleExecStart(msg *Message) {
 		s.sendError(msg.ID, -32603, fmt.Sprintf("validation failed: %v", firstServeError(errs)))
 		return
 	}
+	if params.Strict && hasServeValidationWarnings(errs) {
+		s.sendError(msg.ID, -32603, fmt.Sprintf("validation failed (strict): %d warning(s), first: %v",
+			countServeValidationWarnings(errs), firstServeError(errs)))
+		return
+	}
 	s.runbook = rb
 
 	// Check source hash for staleness
@@ -326,8 +607,8 @@ func (s *Server) handleExecStart(msg *Message) {
 		if srcData, err := os.ReadFile(rb.Meta.Source.File); err == nil {
 			currentHash := fmt.Sprintf("%x", sha256.Sum256(srcData))
 			if currentHash != rb.Meta.Source.SourceHash {
-				fmt.Fprintf(os.Stderr, "serve: WARNING source TSG has changed since compilation (compiled=%s, current=%s)\n",
-					rb.Meta.Source.SourceHash[:12], currentHash[:12])
+				s.Logger.Warn("source TSG has changed since compilation",
+					slog.String("compiledHash", rb.Meta.Source.SourceHash[:12]), slog.String("currentHash", currentHash[:12]))
 				s.sendEvent("runbook/staleSource", map[string]interface{}{
 					"sourceFile":   rb.Meta.Source.File,
 					"compiledAt":   rb.Meta.Source.CompiledAt,
@@ -336,7 +617,7 @@ func (s *Server) handleExecStart(msg *Message) {
 				})
 			}
 		} else {
-			fmt.Fprintf(os.Stderr, "serve: could not read source file for hash check: %v\n", err)
+			s.Logger.Warn("could not read source file for hash check", slog.Any("error", err))
 		}
 	}
 
@@ -360,24 +641,49 @@ func (s *Server) handleExecStart(msg *Message) {
 	}
 
 	// Input resolution: dispatch to registered input providers.
+	// Values resolved from secret stores are masked in logs and output.
+	// Inputs whose when: is false are dropped, so they never reach the
+	// run manifest.
+	var secretValues []string
 	if rb.Meta.Inputs != nil && s.InputManager != nil {
 		execCtx := make(map[string]string)
 
-		resolved, warnings, err := s.InputManager.Resolve(s.ctx, rb.Meta.Inputs, execCtx)
+		known := make(map[string]string, len(rb.Meta.Vars))
+		for k, v := range rb.Meta.Vars {
+			known[k] = v
+		}
+		resolved, skipped, warnings, err := s.InputManager.ResolveMeta(s.ctx, &rb.Meta, known, execCtx)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "serve: input resolution error: %v\n", err)
+			s.Logger.Error("input resolution failed", slog.Any("error", err))
+		}
+		for _, name := range skipped {
+			delete(rb.Meta.Vars, name)
+			s.Logger.Debug("input skipped", slog.String("input", name))
 		}
 		for _, w := range warnings {
-			fmt.Fprintf(os.Stderr, "serve: input warning: %s\n", w)
+			s.Logger.Warn("input warning", slog.String("warning", w))
 		}
 		for k, v := range resolved {
 			if _, already := rb.Meta.Vars[k]; !already {
 				rb.Meta.Vars[k] = v
-				fmt.Fprintf(os.Stderr, "serve: input resolved %s = %q\n", k, v)
+				if in := rb.Meta.Inputs[k]; in != nil && strings.HasPrefix(in.From, akv.Prefix) {
+					secretValues = append(secretValues, v)
+					s.Logger.Debug("input resolved", slog.String("input", k), slog.String("value", "***"))
+					continue
+				}
+				s.Logger.Debug("input resolved", slog.String("input", k), slog.String("value", v))
 			}
 		}
 		if len(resolved) > 0 {
-			fmt.Fprintf(os.Stderr, "serve: resolved %d inputs\n", len(resolved))
+			s.Logger.Debug("inputs resolved", slog.Int("count", len(resolved)))
+		}
+	} else if rb.Meta.Inputs != nil {
+		skipped, err := inputs.Skipped(&rb.Meta, rb.Meta.Vars)
+		if err != nil {
+			s.Logger.Error("input resolution failed", slog.Any("error", err))
+		}
+		for _, name := range skipped {
+			delete(rb.Meta.Vars, name)
 		}
 	}
 
@@ -387,15 +693,19 @@ func (s *Server) handleExecStart(msg *Message) {
 	var stepScenario *replay.StepScenario
 
 	switch params.Mode {
-	case "real":
+	case "real", "probe":
 		executor = &providers.RealExecutor{}
-		collector = &ServeCollector{server: s}
+		var err error
+		if collector, err = s.evidenceCollector(); err != nil {
+			s.sendError(msg.ID, -32606, err.Error())
+			return
+		}
 	case "dry-run":
 		executor = &DryRunExecutor{}
 		collector = &providers.DryRunCollector{}
 	case "replay":
 		if params.ScenarioDir != "" {
-			fmt.Fprintf(os.Stderr, "serve: loading scenario from %q\n", params.ScenarioDir)
+			s.Logger.Debug("loading scenario", slog.String("scenarioDir", params.ScenarioDir))
 			var err error
 			stepScenario, err = replay.LoadStepScenario(params.ScenarioDir, parseTimeOrZero(params.RebaseTime))
 			if err != nil {
@@ -417,6 +727,11 @@ func (s *Server) handleExecStart(msg *Message) {
 		return
 	}
 	engine.RunbookPath = params.Runbook
+	engine.Logger = s.Logger
+	engine.Redact = append(engine.Redact, governance.SecretRedactions(secretValues)...)
+	if bc, ok := collector.(*providers.AzureBlobCollector); ok {
+		bc.Prefix = engine.GetRunID()
+	}
 	if stepScenario != nil {
 		engine.StepScenario = stepScenario
 	}
@@ -441,7 +756,7 @@ func (s *Server) handleExecStart(msg *Message) {
 		for _, name := range rb.Tools {
 			resolved := schema.ResolveToolPathCompat(proj, rb, name, baseDir)
 			if err := tm.Load(name, resolved, ""); err != nil {
-				fmt.Fprintf(os.Stderr, "serve: WARNING failed to load tool %q: %v\n", name, err)
+				s.Logger.Warn("failed to load tool", slog.String("tool", name), slog.Any("error", err))
 			}
 		}
 		engine.ToolManager = tm
@@ -449,6 +764,7 @@ func (s *Server) handleExecStart(msg *Message) {
 
 	s.engine = engine
 	s.rootBaseDir = engine.GetBaseDir()
+	s.sessionFile = s.sessionPath(engine.GetRunID())
 
 	// Store display preferences
 	s.display = params.Display
@@ -482,6 +798,7 @@ func (s *Server) handleExecStart(msg *Message) {
 		result["tree"] = s.resolveTreeForDisplay(rb.Tree)
 		s.treeCursor = newTreeCursor(rb.Tree)
 	}
+	s.rewindPoints = nil
 	s.sendResult(msg.ID, result)
 }
 
@@ -489,9 +806,13 @@ func (s *Server) handleExecStart(msg *Message) {
 // It loads the session file, rebuilds the engine/cursor/invoke stack, and
 // returns the run info with history of already-completed steps.
 func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
-	fmt.Fprintf(os.Stderr, "serve: exec/start resumeRunId=%q\n", params.ResumeRunID)
+	s.Logger.Info("exec/start resume", slog.String("resumeRunId", params.ResumeRunID))
 
-	sessionPath := filepath.Join(".runbook", "runs", params.ResumeRunID, "session.json")
+	sessionPath := s.sessionPath(params.ResumeRunID)
+	if sessionPath == "" {
+		s.sendError(msg.ID, -32610, "load session: session persistence is disabled")
+		return
+	}
 	session, err := loadSessionFile(sessionPath)
 	if err != nil {
 		s.sendError(msg.ID, -32610, fmt.Sprintf("load session: %v", err))
@@ -501,7 +822,7 @@ func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
 	// Restore working directory
 	if session.Cwd != "" {
 		if err := os.Chdir(session.Cwd); err != nil {
-			fmt.Fprintf(os.Stderr, "serve: WARNING failed to chdir to %q: %v\n", session.Cwd, err)
+			s.Logger.Warn("failed to chdir", slog.String("cwd", session.Cwd), slog.Any("error", err))
 		}
 	}
 
@@ -535,9 +856,16 @@ func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
 	var collector providers.EvidenceCollector
 
 	switch session.Mode {
-	case "real":
+	case "real", "probe":
 		executor = &providers.RealExecutor{}
-		collector = &ServeCollector{server: s}
+		var err error
+		if collector, err = s.evidenceCollector(); err != nil {
+			s.sendError(msg.ID, -32606, err.Error())
+			return
+		}
+		if bc, ok := collector.(*providers.AzureBlobCollector); ok {
+			bc.Prefix = session.RunID
+		}
 	case "dry-run":
 		executor = &DryRunExecutor{}
 		collector = &providers.DryRunCollector{}
@@ -568,6 +896,7 @@ func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
 		return
 	}
 	engine.RunbookPath = activeRunbookPath
+	engine.Logger = s.Logger
 
 	// Discover project context
 	var proj *schema.Project
@@ -586,13 +915,14 @@ func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
 		for _, name := range activeRB.Tools {
 			resolved := schema.ResolveToolPathCompat(proj, activeRB, name, baseDir)
 			if err := tm.Load(name, resolved, ""); err != nil {
-				fmt.Fprintf(os.Stderr, "serve: WARNING failed to load tool %q on resume: %v\n", name, err)
+				s.Logger.Warn("failed to load tool on resume", slog.String("tool", name), slog.Any("error", err))
 			}
 		}
 		engine.ToolManager = tm
 	}
 	s.engine = engine
 	s.rootBaseDir = filepath.Join(".runbook", "runs", session.RunID)
+	s.sessionFile = sessionPath
 
 	// Rebuild tree cursor from the active runbook's tree
 	activeTidx := buildTreeIndex(activeRB.Tree)
@@ -611,34 +941,37 @@ func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
 	if session.PendingManual != nil {
 		pn, err := deserializePendingNode(*session.PendingManual, activeTidx)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "serve: WARNING couldn't restore pending manual: %v\n", err)
+			s.Logger.Warn("couldn't restore pending manual", slog.Any("error", err))
 		} else {
 			s.pendingManual = &pn
 		}
 	}
 
+	s.rewindPoints = deserializeRewindPoints(session.RewindPoints, activeTidx, s.engine.GetRunID(), s.Logger)
+
 	// Rebuild invoke stack
 	s.invokeStack = nil
 	for _, frameRef := range session.InvokeStack {
 		parentRB, errs := schema.ValidateFile(frameRef.RunbookPath)
 		if hasServeValidationErrors(errs) {
-			fmt.Fprintf(os.Stderr, "serve: WARNING couldn't restore invoke frame for %q: %v\n", frameRef.RunbookPath, firstServeError(errs))
+			s.Logger.Warn("couldn't restore invoke frame", slog.String("runbook", frameRef.RunbookPath), slog.Any("error", firstServeError(errs)))
 			continue
 		}
 		parentTidx := buildTreeIndex(parentRB.Tree)
 		parentPending, err := deserializePendingQueue(frameRef.Pending, parentTidx)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "serve: WARNING couldn't restore invoke cursor: %v\n", err)
+			s.Logger.Warn("couldn't restore invoke cursor", slog.Any("error", err))
 			continue
 		}
 		parentEngine, err := runtime.ResumeForServe(parentRB, executor, collector,
 			frameRef.RunID, frameRef.Vars, frameRef.Captures, nil,
 			session.Mode, session.Actor, session.StartedAt)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "serve: WARNING couldn't restore invoke engine: %v\n", err)
+			s.Logger.Warn("couldn't restore invoke engine", slog.Any("error", err))
 			continue
 		}
 		parentEngine.RunbookPath = frameRef.RunbookPath
+		parentEngine.Logger = s.Logger
 		parentEngine.ChainDepth = frameRef.ChainDepth
 		parentEngine.Project = proj
 
@@ -649,7 +982,7 @@ func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
 			for _, name := range parentRB.Tools {
 				resolved := schema.ResolveToolPathCompat(proj, parentRB, name, baseDir)
 				if err := tm.Load(name, resolved, ""); err != nil {
-					fmt.Fprintf(os.Stderr, "serve: WARNING failed to load parent tool %q on resume: %v\n", name, err)
+					s.Logger.Warn("failed to load parent tool on resume", slog.String("tool", name), slog.Any("error", err))
 				}
 			}
 			parentEngine.ToolManager = tm
@@ -666,8 +999,8 @@ func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
 		})
 	}
 
-	fmt.Fprintf(os.Stderr, "serve: resumed run %s (%d steps completed, %d pending, %d invoke frames)\n",
-		session.RunID, len(session.History), len(session.Pending), len(session.InvokeStack))
+	s.Logger.Info("resumed run", slog.String("runId", session.RunID), slog.Int("completed", len(session.History)),
+		slog.Int("pending", len(session.Pending)), slog.Int("invokeFrames", len(session.InvokeStack)))
 
 	// Build step summaries: prefer flat steps, fall back to flattened tree
 	resumeStepSummaries := buildStepSummaries(activeRB.Steps)
@@ -697,15 +1030,7 @@ func (s *Server) handleExecResume(msg *Message, params ExecStartParams) {
 	}
 
 	// Include completed step history so the extension can mark them done
-	completedSteps := make([]map[string]interface{}, 0, len(session.History))
-	for _, h := range session.History {
-		completedSteps = append(completedSteps, map[string]interface{}{
-			"stepId":   h.StepID,
-			"status":   h.Status,
-			"captures": h.Captures,
-		})
-	}
-	result["history"] = completedSteps
+	result["history"] = historyEntries(session.History)
 
 	if s.pendingManual != nil {
 		result["pendingManual"] = map[string]interface{}{
@@ -732,6 +1057,7 @@ func (s *Server) handleExecNext(msg *Message) {
 
 	idx := s.engine.State.CurrentStepIndex
 	if idx >= len(s.runbook.Steps) {
+		s.reportRun(nil)
 		s.sendResult(msg.ID, map[string]string{"status": "completed"})
 		return
 	}
@@ -793,6 +1119,15 @@ func (s *Server) handleExecNext(msg *Message) {
 		return
 	}
 
+	// Probe mode skips write-effect steps inside the engine
+	if result.Status == "skipped" && result.Error == runtime.ProbeSkipReason {
+		s.sendEvent("event/stepSkipped", map[string]interface{}{
+			"stepId": step.ID,
+			"index":  idx,
+			"reason": result.Error,
+		})
+	}
+
 	// Send stepCompleted with captures
 	s.sendEvent("event/stepCompleted", map[string]interface{}{
 		"stepId":   step.ID,
@@ -816,6 +1151,7 @@ func (s *Server) handleExecNext(msg *Message) {
 					"state":          outcome.State,
 					"recommendation": strings.TrimSpace(rec),
 				})
+				s.reportRun(nil)
 				s.sendResult(msg.ID, map[string]interface{}{
 					"stepId":         step.ID,
 					"status":         "outcome",
@@ -843,6 +1179,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 		s.pendingManual = nil
 		step := pn.node.Step
 		stepIdx := s.treeCursor.stepIdx
+		s.recordRewindPoint(*pn)
 
 		// Execute the step
 		origStdout := os.Stdout
@@ -910,6 +1247,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 						"recommendation": strings.TrimSpace(rec),
 					})
 					s.emitSkippedSteps()
+					s.reportRun(nil)
 					s.sendResult(msg.ID, map[string]interface{}{
 						"stepId": step.ID, "status": "outcome",
 						"outcomeState": outcome.State, "recommendation": strings.TrimSpace(rec),
@@ -954,7 +1292,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 			wp := pn.watchpoint
 			converged := s.engine.EvalConditionPublic(wp.block.Until)
 			if converged {
-				fmt.Fprintf(os.Stderr, "serve: iterate converged at pass %d/%d\n", wp.pass+1, wp.max)
+				s.Logger.Debug("iterate converged", slog.Int("pass", wp.pass+1), slog.Int("max", wp.max))
 				s.sendEvent("event/iterateConverged", map[string]interface{}{
 					"pass": wp.pass + 1,
 					"max":  wp.max,
@@ -964,7 +1302,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 			nextPass := wp.pass + 1
 			if nextPass >= wp.max {
 				errMsg := fmt.Sprintf("iterate did not converge after %d passes (until: %s)", wp.max, wp.block.Until)
-				fmt.Fprintf(os.Stderr, "serve: %s\n", errMsg)
+				s.Logger.Warn("iterate did not converge", slog.Int("max", wp.max), slog.String("until", wp.block.Until))
 				s.sendEvent("event/iterateFailed", map[string]interface{}{
 					"error": errMsg,
 					"max":   wp.max,
@@ -978,7 +1316,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 			}
 			// Not converged — start next pass
 			s.engine.State.Vars["iteration"] = fmt.Sprintf("%d", nextPass)
-			fmt.Fprintf(os.Stderr, "serve: iterate pass %d/%d starting\n", nextPass+1, wp.max)
+			s.Logger.Debug("iterate pass starting", slog.Int("pass", nextPass+1), slog.Int("max", wp.max))
 			s.sendEvent("event/iteratePass", map[string]interface{}{
 				"pass": nextPass + 1,
 				"max":  wp.max,
@@ -993,7 +1331,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 			nextIdx := ow.index + 1
 			if nextIdx >= len(ow.items) {
 				// All items processed — done
-				fmt.Fprintf(os.Stderr, "serve: iterate over completed (%d items)\n", len(ow.items))
+				s.Logger.Debug("iterate over completed", slog.Int("items", len(ow.items)))
 				s.sendEvent("event/iterateConverged", map[string]interface{}{
 					"mode":  "over",
 					"pass":  len(ow.items),
@@ -1004,7 +1342,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 			// Advance to next item
 			s.engine.State.Vars["iteration"] = fmt.Sprintf("%d", nextIdx)
 			s.engine.State.Vars[ow.asVar] = ow.items[nextIdx]
-			fmt.Fprintf(os.Stderr, "serve: iterate over [%d/%d] %s = %s\n", nextIdx+1, len(ow.items), ow.asVar, ow.items[nextIdx])
+			s.Logger.Debug("iterate over item", slog.Int("index", nextIdx+1), slog.Int("items", len(ow.items)), slog.String(ow.asVar, ow.items[nextIdx]))
 			s.sendEvent("event/iteratePass", map[string]interface{}{
 				"mode":  "over",
 				"pass":  nextIdx + 1,
@@ -1035,7 +1373,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 				}
 
 				if len(listItems) == 0 {
-					fmt.Fprintf(os.Stderr, "serve: iterate over: empty list, skipping\n")
+					s.Logger.Debug("iterate over empty list, skipping")
 					s.sendEvent("event/iterateStarted", map[string]interface{}{
 						"mode":  "over",
 						"as":    asVar,
@@ -1050,7 +1388,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 					continue
 				}
 
-				fmt.Fprintf(os.Stderr, "serve: iterate over %d items (as: %s)\n", len(listItems), asVar)
+				s.Logger.Debug("iterate over started", slog.Int("items", len(listItems)), slog.String("as", asVar))
 				s.engine.State.Vars["iteration"] = "0"
 				s.engine.State.Vars[asVar] = listItems[0]
 				s.sendEvent("event/iterateStarted", map[string]interface{}{
@@ -1066,7 +1404,7 @@ func (s *Server) handleTreeNext(msg *Message) {
 
 			// Convergence mode: retry until condition
 			s.engine.State.Vars["iteration"] = "0"
-			fmt.Fprintf(os.Stderr, "serve: iterate started (max=%d, until=%q)\n", iter.Max, iter.Until)
+			s.Logger.Debug("iterate started", slog.Int("max", iter.Max), slog.String("until", iter.Until))
 			s.sendEvent("event/iterateStarted", map[string]interface{}{
 				"max":   iter.Max,
 				"until": iter.Until,
@@ -1083,129 +1421,30 @@ func (s *Server) handleTreeNext(msg *Message) {
 		stepIdx := s.treeCursor.stepIdx
 
 		// Evaluate precondition
-		if step.Precondition != nil && step.Precondition.SkipIfSucceeds && len(step.Precondition.Check) > 0 {
-			resolvedCheck := make([]string, len(step.Precondition.Check))
-			canCheck := true
-			for i, arg := range step.Precondition.Check {
-				r := s.engine.ResolveTemplatePublic(arg)
-				if r == "<no value>" {
-					canCheck = false
-					break
-				}
-				resolvedCheck[i] = r
-			}
-			if canCheck {
-				probeResult, probeErr := s.engine.Executor.Execute(s.ctx, resolvedCheck[0], resolvedCheck[1:], nil)
-				if probeErr == nil && probeResult.ExitCode == 0 {
-					precondMsg := step.Precondition.Message
-					if precondMsg == "" {
-						precondMsg = fmt.Sprintf("precondition satisfied: %s", strings.Join(resolvedCheck, " "))
-					}
-					s.treeCursor.stepIdx++
-					s.sendEvent("event/stepSkipped", map[string]interface{}{
-						"stepId": step.ID, "index": stepIdx, "reason": precondMsg,
-					})
-					continue // skip to next step in the loop
-				}
-			}
+		if satisfied, precondMsg := s.checkPrecondition(step); satisfied {
+			s.treeCursor.stepIdx++
+			s.sendEvent("event/stepSkipped", map[string]interface{}{
+				"stepId": step.ID, "index": stepIdx, "reason": precondMsg,
+			})
+			continue // skip to next step in the loop
 		}
 
 		// Send stepStarted event
-		resolvedInstructions := s.engine.ResolveTemplatePublic(step.Instructions)
-		resolvedTitle := s.engine.ResolveTemplatePublic(step.Title)
-		if resolvedTitle == "" || resolvedTitle == "<no value>" {
-			resolvedTitle = step.Title
-		}
-		treeStepEvent := map[string]interface{}{
-			"stepId":       step.ID,
-			"index":        stepIdx,
-			"type":         step.Type,
-			"title":        resolvedTitle,
-			"instructions": resolvedInstructions,
-			"outcomes":     s.buildOutcomeSummaries(step.Outcomes),
-		}
-		if len(s.invokeStack) > 0 {
-			treeStepEvent["invokeChild"] = true
-		}
-		if step.With != nil && len(step.With.Argv) > 0 {
-			treeStepEvent["command"] = s.resolveArgv(step.With.Argv)
-		}
-		if step.Tool != nil {
-			toolInfo := map[string]interface{}{
-				"name":   step.Tool.Name,
-				"action": step.Tool.Action,
-			}
-			if len(step.Tool.Args) > 0 {
-				resolvedArgs := make(map[string]string)
-				for k, v := range step.Tool.Args {
-					resolvedArgs[k] = s.engine.ResolveTemplatePublic(v)
-				}
-				toolInfo["args"] = resolvedArgs
-			}
-			// Include governance info if available
-			if s.engine.ToolManager != nil {
-				if td := s.engine.ToolManager.GetDef(step.Tool.Name); td != nil {
-					if act, ok := td.Actions[step.Tool.Action]; ok && act.Governance != nil {
-						toolInfo["governance"] = map[string]interface{}{
-							"read_only":         act.Governance.ReadOnly,
-							"requires_approval": act.Governance.RequiresApproval,
-						}
-					}
-				}
-			}
-			treeStepEvent["tool"] = toolInfo
-			// Build command display for the extension
-			if s.engine.ToolManager != nil {
-				if td := s.engine.ToolManager.GetDef(step.Tool.Name); td != nil {
-					treeStepEvent["command"] = td.Meta.Binary + " " + step.Tool.Action
-				}
-			}
-			// Extract query/queryType from tool args for syntax highlighting
-			if q, ok := step.Tool.Args["query"]; ok {
-				treeStepEvent["query"] = s.engine.ResolveTemplatePublic(q)
-				if qt, ok := step.Tool.Args["query_type"]; ok {
-					treeStepEvent["queryType"] = qt
-				}
-			}
-		}
-		if step.Choices != nil {
-			options := make([]map[string]interface{}, len(step.Choices.Options))
-			for j, opt := range step.Choices.Options {
-				options[j] = map[string]interface{}{
-					"value":       opt.Value,
-					"label":       opt.Label,
-					"description": opt.Description,
-				}
-			}
-			treeStepEvent["choices"] = map[string]interface{}{
-				"variable": step.Choices.Variable,
-				"prompt":   s.engine.ResolveTemplatePublic(step.Choices.Prompt),
-				"options":  options,
-			}
-		}
+		treeStepEvent := s.previewTreeStep(pn)
+		resolvedTitle, resolvedInstructions := treeStepEvent["title"], treeStepEvent["instructions"]
 		s.sendEvent("event/stepStarted", treeStepEvent)
 
 		// ── Check if manual step can auto-advance ────────────────────
 		// Never auto-advance steps with choices — the user must select an option first.
-		if step.Type == "manual" && step.Choices == nil && len(step.RequiredEvidence) == 0 && (step.Approvals == nil || step.Approvals.Min == 0) {
-			hasBranches := len(pn.node.Branches) > 0
-			hasOnlyBranchOutcome := len(step.Outcomes) == 0 && hasBranches
-			hasSingleAutoOutcome := false
-			if len(step.Outcomes) == 1 && step.Outcomes[0].When == "" {
-				hasSingleAutoOutcome = true
-			}
-
-			if hasOnlyBranchOutcome || hasSingleAutoOutcome {
-				fmt.Fprintf(os.Stderr, "serve: auto-advancing manual step %q (routing=%v, autoOutcome=%v)\n",
-					step.ID, hasOnlyBranchOutcome, hasSingleAutoOutcome)
-				s.executeTreeStep(msg, pn)
-				// executeTreeStep may have inserted branch steps or triggered an outcome.
-				// If it triggered an outcome, it already sent the result — we're done.
-				if s.treeCursor.pending == nil {
-					return // outcome was reached
-				}
-				continue // loop to pick up the next step
+		if autoAdvances(pn) {
+			s.Logger.Debug("auto-advancing manual step", slog.String("stepId", step.ID))
+			s.executeTreeStep(msg, pn)
+			// executeTreeStep may have inserted branch steps or triggered an outcome.
+			// If it triggered an outcome, it already sent the result — we're done.
+			if s.treeCursor.pending == nil {
+				return // outcome was reached
 			}
+			continue // loop to pick up the next step
 		}
 
 		// ── Non-auto-advanceable step: present to user or execute ────
@@ -1280,10 +1519,274 @@ func (s *Server) handleTreeNext(msg *Message) {
 	} // end auto-advance loop
 }
 
+// handlePreviewNext reports what exec/next would present next without
+// running it: the step as event/stepStarted would show it, its when guard,
+// its resolved precondition check and whether it would auto-advance. No
+// command runs, the cursor and engine state are left untouched and no
+// events are sent.
+func (s *Server) handlePreviewNext(msg *Message) {
+	if s.engine == nil {
+		s.sendError(msg.ID, -32607, "no active execution — call exec/start first")
+		return
+	}
+	if s.treeCursor == nil {
+		s.sendError(msg.ID, -32611, "exec/previewNext requires a tree runbook")
+		return
+	}
+
+	// A manual step already presented is what exec/next completes
+	if s.pendingManual != nil {
+		preview := s.previewTreeStep(*s.pendingManual)
+		preview["status"] = "awaiting_user"
+		preview["autoAdvance"] = false
+		s.sendResult(msg.ID, preview)
+		return
+	}
+	if !s.treeCursor.hasNext() {
+		s.sendResult(msg.ID, map[string]interface{}{"status": "completed"})
+		return
+	}
+
+	pn := s.treeCursor.pending[0]
+	switch {
+	case pn.watchpoint != nil || pn.overWatchpoint != nil:
+		s.sendResult(msg.ID, map[string]interface{}{"status": "iterate", "autoAdvance": true})
+		return
+	case pn.node.Iterate != nil:
+		s.sendResult(msg.ID, map[string]interface{}{
+			"status":      "iterate",
+			"autoAdvance": true,
+			"max":         pn.node.Iterate.Max,
+			"until":       pn.node.Iterate.Until,
+			"over":        s.engine.ResolveTemplatePublic(pn.node.Iterate.Over),
+		})
+		return
+	}
+
+	step := pn.node.Step
+	preview := s.previewTreeStep(pn)
+	preview["status"] = "pending"
+	preview["autoAdvance"] = autoAdvances(pn)
+	if step.When != "" {
+		preview["when"] = map[string]interface{}{
+			"condition": step.When,
+			"matched":   s.engine.EvalConditionPublic(step.When),
+		}
+	}
+	// The check is only resolved: running it here would execute a command
+	// the user asked merely to preview
+	if step.Precondition != nil {
+		pc := map[string]interface{}{
+			"skipIfSucceeds": step.Precondition.SkipIfSucceeds,
+			"message":        step.Precondition.Message,
+		}
+		if check, ok := s.resolvePreconditionCheck(step); ok {
+			pc["check"] = strings.Join(check, " ")
+		}
+		preview["precondition"] = pc
+	}
+	s.sendResult(msg.ID, preview)
+}
+
+// handleGetStepDef returns the full definition of the pending step — the
+// manual step presented by exec/next in tree mode, or the manual step at the
+// current index in flat mode — with templates in its strings resolved.
+func (s *Server) handleGetStepDef(msg *Message) {
+	if s.engine == nil {
+		s.sendError(msg.ID, -32607, "no active execution — call exec/start first")
+		return
+	}
+	var step schema.Step
+	switch {
+	case s.pendingManual != nil:
+		step = s.pendingManual.node.Step
+	case s.treeCursor == nil && s.engine.State.CurrentStepIndex < len(s.runbook.Steps) &&
+		s.runbook.Steps[s.engine.State.CurrentStepIndex].Type == "manual":
+		step = s.runbook.Steps[s.engine.State.CurrentStepIndex]
+	default:
+		s.sendError(msg.ID, -32608, "no pending step")
+		return
+	}
+
+	data, err := json.Marshal(step)
+	if err != nil {
+		s.sendError(msg.ID, -32603, fmt.Sprintf("encode step: %v", err))
+		return
+	}
+	var def interface{}
+	if err := json.Unmarshal(data, &def); err != nil {
+		s.sendError(msg.ID, -32603, fmt.Sprintf("encode step: %v", err))
+		return
+	}
+	s.sendResult(msg.ID, s.resolveStepDef(def))
+}
+
+// resolveStepDef resolves templates in every string of a decoded step
+// definition. Strings that do not resolve yet are returned unchanged.
+func (s *Server) resolveStepDef(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v
+		}
+		if r := s.engine.ResolveTemplatePublic(v); r != "<no value>" {
+			return r
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = s.resolveStepDef(item)
+		}
+		return v
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = s.resolveStepDef(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// checkPrecondition runs a skip_if_succeeds precondition check and reports
+// whether it passed, with the skip reason. A check whose arguments do not
+// resolve yet is not run.
+func (s *Server) checkPrecondition(step schema.Step) (bool, string) {
+	if step.Precondition == nil || !step.Precondition.SkipIfSucceeds {
+		return false, ""
+	}
+	resolvedCheck, ok := s.resolvePreconditionCheck(step)
+	if !ok {
+		return false, ""
+	}
+	probeResult, probeErr := s.engine.Executor.Execute(s.ctx, resolvedCheck[0], resolvedCheck[1:], nil)
+	if probeErr != nil || probeResult.ExitCode != 0 {
+		return false, ""
+	}
+	if step.Precondition.Message != "" {
+		return true, step.Precondition.Message
+	}
+	return true, fmt.Sprintf("precondition satisfied: %s", strings.Join(resolvedCheck, " "))
+}
+
+// resolvePreconditionCheck resolves the argv of a step's precondition
+// check. It reports false when there is no check or an argument references
+// an unset variable.
+func (s *Server) resolvePreconditionCheck(step schema.Step) ([]string, bool) {
+	if step.Precondition == nil || len(step.Precondition.Check) == 0 {
+		return nil, false
+	}
+	resolved := make([]string, len(step.Precondition.Check))
+	for i, arg := range step.Precondition.Check {
+		r := s.engine.ResolveTemplatePublic(arg)
+		if r == "<no value>" {
+			return nil, false
+		}
+		resolved[i] = r
+	}
+	return resolved, true
+}
+
+// autoAdvances reports whether handleTreeNext runs a manual step without
+// waiting for the user: it has no choices, evidence or approvals, and either
+// only routes to branches or has a single unconditional outcome. Steps with
+// choices never auto-advance — the user must select an option first.
+func autoAdvances(pn pendingNode) bool {
+	step := pn.node.Step
+	if step.Type != "manual" || step.Choices != nil || len(step.RequiredEvidence) > 0 || (step.Approvals != nil && step.Approvals.Min > 0) {
+		return false
+	}
+	hasOnlyBranchOutcome := len(step.Outcomes) == 0 && len(pn.node.Branches) > 0
+	hasSingleAutoOutcome := len(step.Outcomes) == 1 && step.Outcomes[0].When == ""
+	return hasOnlyBranchOutcome || hasSingleAutoOutcome
+}
+
+// previewTreeStep builds the presentation of a tree step — resolved title,
+// instructions, command, tool args and choices — as sent in
+// event/stepStarted. It reads engine state but never changes it.
+func (s *Server) previewTreeStep(pn pendingNode) map[string]interface{} {
+	step := pn.node.Step
+	stepIdx := s.treeCursor.stepIdx
+	resolvedInstructions := s.engine.ResolveTemplatePublic(step.Instructions)
+	resolvedTitle := s.engine.ResolveTemplatePublic(step.Title)
+	if resolvedTitle == "" || resolvedTitle == "<no value>" {
+		resolvedTitle = step.Title
+	}
+	preview := map[string]interface{}{
+		"stepId":       step.ID,
+		"index":        stepIdx,
+		"type":         step.Type,
+		"title":        resolvedTitle,
+		"instructions": resolvedInstructions,
+		"outcomes":     s.buildOutcomeSummaries(step.Outcomes),
+	}
+	if len(s.invokeStack) > 0 {
+		preview["invokeChild"] = true
+	}
+	if step.With != nil && len(step.With.Argv) > 0 {
+		preview["command"] = s.resolveArgv(step.With.Argv)
+	}
+	if step.Tool != nil {
+		toolInfo := map[string]interface{}{
+			"name":   step.Tool.Name,
+			"action": step.Tool.Action,
+		}
+		if len(step.Tool.Args) > 0 {
+			resolvedArgs := make(map[string]string)
+			for k, v := range step.Tool.Args {
+				resolvedArgs[k] = s.engine.ResolveTemplatePublic(v)
+			}
+			toolInfo["args"] = resolvedArgs
+		}
+		// Include governance info if available
+		if s.engine.ToolManager != nil {
+			if td := s.engine.ToolManager.GetDef(step.Tool.Name); td != nil {
+				if act, ok := td.Actions[step.Tool.Action]; ok && act.Governance != nil {
+					toolInfo["governance"] = map[string]interface{}{
+						"read_only":         act.Governance.ReadOnly,
+						"requires_approval": act.Governance.RequiresApproval,
+					}
+				}
+			}
+		}
+		preview["tool"] = toolInfo
+		// Build command display for the extension
+		if s.engine.ToolManager != nil {
+			if td := s.engine.ToolManager.GetDef(step.Tool.Name); td != nil {
+				preview["command"] = td.Meta.Binary + " " + step.Tool.Action
+			}
+		}
+		// Extract query/queryType from tool args for syntax highlighting
+		if q, ok := step.Tool.Args["query"]; ok {
+			preview["query"] = s.engine.ResolveTemplatePublic(q)
+			if qt, ok := step.Tool.Args["query_type"]; ok {
+				preview["queryType"] = qt
+			}
+		}
+	}
+	if step.Choices != nil {
+		options := make([]map[string]interface{}, len(step.Choices.Options))
+		for j, opt := range step.Choices.Options {
+			options[j] = map[string]interface{}{
+				"value":       opt.Value,
+				"label":       opt.Label,
+				"description": opt.Description,
+			}
+		}
+		preview["choices"] = map[string]interface{}{
+			"variable": step.Choices.Variable,
+			"prompt":   s.engine.ResolveTemplatePublic(step.Choices.Prompt),
+			"options":  options,
+		}
+	}
+	return preview
+}
+
 // executeTreeStep runs a single tree step and evaluates outcomes/branches.
 func (s *Server) executeTreeStep(msg *Message, pn pendingNode) {
 	step := pn.node.Step
 	stepIdx := s.treeCursor.stepIdx
+	s.recordRewindPoint(pn)
 
 	// Redirect stdout to stderr during execution
 	origStdout := os.Stdout
@@ -1292,6 +1795,14 @@ func (s *Server) executeTreeStep(msg *Message, pn pendingNode) {
 	// Execute the step
 	result, err := s.engine.ExecuteTreeStep(s.ctx, stepIdx, step)
 
+	// Route a failure to its on_failure sibling while stdout is redirected
+	var scope []schema.TreeNode
+	target := -1
+	if err == nil && result.Status == "failed" && step.OnFailure != "" {
+		scope = findTreeScope(s.runbook.Tree, step.ID)
+		target = s.engine.RouteFailurePublic(step, result, scope)
+	}
+
 	os.Stdout = origStdout
 
 	if err != nil {
@@ -1338,6 +1849,30 @@ func (s *Server) executeTreeStep(msg *Message, pn pendingNode) {
 	}
 	s.sendEvent("event/stepCompleted", stepCompletedEvt)
 
+	// on_failure: continue at the target instead of evaluating outcomes
+	if target >= 0 {
+		s.treeCursor.routeTo(scope[target:], pn.depth)
+		s.sendEvent("event/stepRouted", map[string]interface{}{
+			"stepId": step.ID,
+			"target": step.OnFailure,
+			"error":  result.Error,
+		})
+		if len(s.invokeStack) > 0 {
+			return
+		}
+		s.sendResult(msg.ID, map[string]interface{}{
+			"stepId":       step.ID,
+			"status":       result.Status,
+			"error":        result.Error,
+			"onFailure":    step.OnFailure,
+			"captures":     result.Captures,
+			"title":        step.Title,
+			"type":         step.Type,
+			"instructions": step.Instructions,
+		})
+		return
+	}
+
 	// Evaluate outcomes — if one triggers, stop the tree.
 	// Outcomes are evaluated when the step passed OR when the step failed
 	// but captures were extracted (e.g., tool exited non-zero but produced
@@ -1387,6 +1922,7 @@ func (s *Server) executeTreeStep(msg *Message, pn pendingNode) {
 
 				// Emit skipped for remaining steps
 				s.emitSkippedSteps()
+				s.reportRun(nil)
 
 				s.sendResult(msg.ID, map[string]interface{}{
 					"stepId":         step.ID,
@@ -1451,6 +1987,7 @@ func (s *Server) handleChooseOutcome(msg *Message) {
 	s.pendingManual = nil
 	step := pn.node.Step
 	stepIdx := s.treeCursor.stepIdx
+	s.recordRewindPoint(*pn)
 
 	// Execute the step (records it in history)
 	origStdout := os.Stdout
@@ -1520,6 +2057,7 @@ func (s *Server) handleChooseOutcome(msg *Message) {
 	})
 
 	s.emitSkippedSteps()
+	s.reportRun(nil)
 
 	s.sendResult(msg.ID, map[string]interface{}{
 		"stepId":         step.ID,
@@ -1545,7 +2083,7 @@ func (s *Server) handleSubmitChoice(msg *Message) {
 
 	// Store the choice as a capture/var in the engine
 	s.engine.SetVar(params.Variable, params.Value)
-	fmt.Fprintf(os.Stderr, "serve: choice %s = %q (step %s)\n", params.Variable, params.Value, params.StepID)
+	s.Logger.Debug("choice", slog.String("stepId", params.StepID), slog.String("variable", params.Variable), slog.String("value", params.Value))
 
 	s.sendResult(msg.ID, map[string]interface{}{
 		"stepId":   params.StepID,
@@ -1554,6 +2092,50 @@ func (s *Server) handleSubmitChoice(msg *Message) {
 	})
 }
 
+// handleSetVar changes a runtime variable while a manual step is waiting
+// for the user, so a bad input can be corrected before later steps use it.
+// Variables fixed by meta.vars cannot be changed.
+func (s *Server) handleSetVar(msg *Message) {
+	if s.engine == nil {
+		s.sendError(msg.ID, -32607, "no active execution")
+		return
+	}
+	var params struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
+		return
+	}
+	if params.Name == "" {
+		s.sendError(msg.ID, -32602, "name is required")
+		return
+	}
+	if s.pendingManual == nil {
+		s.sendError(msg.ID, -32608, "exec/setVar requires a pending manual step")
+		return
+	}
+	if _, ok := s.runbook.Meta.Vars[params.Name]; ok {
+		s.sendError(msg.ID, -32609, fmt.Sprintf("cannot set %q: it is a constant defined in meta.vars", params.Name))
+		return
+	}
+
+	oldValue := s.engine.State.Vars[params.Name]
+	s.engine.SetVar(params.Name, params.Value)
+	s.Logger.Debug("setVar", slog.String("name", params.Name))
+
+	s.sendEvent("event/varChanged", map[string]interface{}{
+		"name":     params.Name,
+		"oldValue": governance.RedactOutput(oldValue, s.engine.Redact),
+		"newValue": governance.RedactOutput(params.Value, s.engine.Redact),
+	})
+	s.sendResult(msg.ID, map[string]interface{}{
+		"name":  params.Name,
+		"value": governance.RedactOutput(params.Value, s.engine.Redact),
+	})
+}
+
 // enterInvoke loads a child runbook, pushes the parent context onto the invoke
 // stack, and replaces the server's engine/cursor/runbook with the child's.
 // The auto-advance loop in handleTreeNext then continues with child steps.
@@ -1584,12 +2166,35 @@ func (s *Server) enterInvoke(msg *Message, step schema.Step) error {
 		resolvedFile = filepath.Join(filepath.Dir(s.engine.RunbookPath), resolvedFile)
 	}
 
-	fmt.Fprintf(os.Stderr, "serve: entering invoke %q → %s\n", step.ID, resolvedFile)
+	s.Logger.Info("entering invoke", slog.String("stepId", step.ID), slog.String("runbook", resolvedFile))
+
+	// Check chain depth before loading the child, so the client sees the
+	// limit as its own event rather than a generic step failure.
+	depth := s.engine.ChainDepth + 1
+	if depth > runtime.MaxChainDepth {
+		s.sendEvent("event/invokeDepthExceeded", map[string]interface{}{
+			"stepId":        step.ID,
+			"runbook":       resolvedFile,
+			"chainDepth":    depth,
+			"maxChainDepth": runtime.MaxChainDepth,
+		})
+		return fmt.Errorf("invoke chain depth %d exceeds maximum %d", depth, runtime.MaxChainDepth)
+	}
+	if depth == runtime.MaxChainDepth-1 {
+		s.sendEvent("event/invokeDepthWarning", map[string]interface{}{
+			"stepId":        step.ID,
+			"runbook":       resolvedFile,
+			"chainDepth":    depth,
+			"maxChainDepth": runtime.MaxChainDepth,
+		})
+	}
 
 	// Send event for the invoke step itself
 	s.sendEvent("event/invokeStarted", map[string]interface{}{
-		"stepId":  step.ID,
-		"runbook": resolvedFile,
+		"stepId":        step.ID,
+		"runbook":       resolvedFile,
+		"chainDepth":    depth,
+		"maxChainDepth": runtime.MaxChainDepth,
 	})
 
 	// Load and validate child runbook
@@ -1606,12 +2211,6 @@ func (s *Server) enterInvoke(msg *Message, step schema.Step) error {
 		childRB.Meta.Vars[k] = s.engine.ResolveTemplatePublic(v)
 	}
 
-	// Check chain depth
-	depth := s.engine.ChainDepth + 1
-	if depth > runtime.MaxChainDepth {
-		return fmt.Errorf("invoke chain depth %d exceeds maximum %d", depth, runtime.MaxChainDepth)
-	}
-
 	// Create child engine with same executor/collector
 	childEngine, err := runtime.NewEngine(childRB, s.engine.Executor, s.engine.Collector,
 		s.engine.State.Mode, s.engine.State.Actor)
@@ -1621,6 +2220,7 @@ func (s *Server) enterInvoke(msg *Message, step schema.Step) error {
 	childEngine.RunbookPath = resolvedFile
 	childEngine.ChainDepth = depth
 	childEngine.ParentRunID = s.engine.State.RunID
+	childEngine.Logger = s.Logger
 
 	// Inherit project context from parent
 	childEngine.Project = s.engine.Project
@@ -1632,13 +2232,13 @@ func (s *Server) enterInvoke(msg *Message, step schema.Step) error {
 		for _, name := range childRB.Tools {
 			resolved := schema.ResolveToolPathCompat(s.engine.Project, childRB, name, childBaseDir)
 			if err := tm.Load(name, resolved, ""); err != nil {
-				fmt.Fprintf(os.Stderr, "serve: WARNING failed to load child tool %q: %v\n", name, err)
+				s.Logger.Warn("failed to load child tool", slog.String("tool", name), slog.Any("error", err))
 			}
 		}
 		childEngine.ToolManager = tm
 	}
 
-	fmt.Fprintf(os.Stderr, "serve: child engine %s (depth %d)\n", childEngine.GetRunID(), depth)
+	s.Logger.Info("child run started", slog.String("runId", childEngine.GetRunID()), slog.Int("depth", depth))
 
 	// Push parent context onto invoke stack
 	s.invokeStack = append(s.invokeStack, invokeFrame{
@@ -1676,7 +2276,7 @@ func (s *Server) exitInvoke(msg *Message) (gateStop bool) {
 		childOutcome = childEngine.GetOutcome().State
 	}
 
-	fmt.Fprintf(os.Stderr, "serve: exiting invoke %q, child outcome=%q\n", frame.invokeStepID, childOutcome)
+	s.Logger.Info("exiting invoke", slog.String("stepId", frame.invokeStepID), slog.String("outcome", childOutcome))
 
 	// Record child run in parent
 	frame.parentEngine.ChildRuns = append(frame.parentEngine.ChildRuns, runtime.ChildRunRef{
@@ -1709,8 +2309,8 @@ func (s *Server) exitInvoke(msg *Message) (gateStop bool) {
 	if frame.gate != nil && len(frame.gate.StopIf) > 0 {
 		for _, stopState := range frame.gate.StopIf {
 			if childOutcome == stopState {
-				fmt.Fprintf(os.Stderr, "serve: gate triggered for %q: child %q matches stop_if\n",
-					frame.invokeStepID, childOutcome)
+				s.Logger.Info("gate triggered: child outcome matches stop_if",
+					slog.String("stepId", frame.invokeStepID), slog.String("outcome", childOutcome))
 
 				// Propagate child outcome to parent
 				if childEngine.GetOutcome() != nil {
@@ -1731,6 +2331,7 @@ func (s *Server) exitInvoke(msg *Message) (gateStop bool) {
 				s.sendEvent("event/runCompleted", map[string]interface{}{
 					"status": "completed",
 				})
+				s.reportRun(nil)
 				s.sendResult(msg.ID, map[string]interface{}{
 					"stepId":       frame.invokeStepID,
 					"status":       "outcome",
@@ -1775,6 +2376,7 @@ func (s *Server) completeTree(msg *Message) {
 	s.sendEvent("event/runCompleted", map[string]interface{}{
 		"status": "completed",
 	})
+	s.reportRun(nil)
 
 	s.sendResult(msg.ID, map[string]interface{}{
 		"status":  "completed",
@@ -1876,6 +2478,7 @@ func (s *Server) handleExecRunTree(msg *Message) {
 		})
 	}
 
+	s.reportRun(err)
 	if err != nil {
 		s.sendResult(msg.ID, map[string]interface{}{
 			"status": "failed",
@@ -1928,15 +2531,71 @@ func (s *Server) handleGetManifest(msg *Message) {
 	s.sendResult(msg.ID, s.engine.BuildManifest())
 }
 
+// handleGetHistory returns the completed step results of the active run,
+// including after the tree has finished. sinceStep skips that many entries,
+// so a client can fetch only what it has not seen yet.
+func (s *Server) handleGetHistory(msg *Message) {
+	if s.engine == nil {
+		s.sendError(msg.ID, -32607, "no active execution")
+		return
+	}
+	var params struct {
+		SinceStep int `json:"sinceStep"`
+	}
+	if len(msg.Params) > 0 {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
+			return
+		}
+	}
+	if params.SinceStep < 0 {
+		s.sendError(msg.ID, -32602, "sinceStep must be >= 0")
+		return
+	}
+
+	history := s.engine.State.History
+	total := len(history)
+	if params.SinceStep < total {
+		history = history[params.SinceStep:]
+	} else {
+		history = nil
+	}
+	s.sendResult(msg.ID, map[string]interface{}{
+		"history": historyEntries(history),
+		"total":   total,
+	})
+}
+
+// historyEntries converts engine step results to their wire form.
+func historyEntries(history []*providers.StepResult) []HistoryEntry {
+	entries := make([]HistoryEntry, 0, len(history))
+	for _, h := range history {
+		if h == nil {
+			continue
+		}
+		entries = append(entries, HistoryEntry{
+			StepID:    h.StepID,
+			Status:    h.Status,
+			Captures:  h.Captures,
+			StartedAt: h.StartedAt,
+			EndedAt:   h.EndedAt,
+			Error:     h.Error,
+		})
+	}
+	return entries
+}
+
 // handleSaveScenario saves the current run's inputs and step responses
-// as a replay scenario folder.
+// as a replay scenario folder, optionally gzipped and/or encrypted.
 func (s *Server) handleSaveScenario(msg *Message) {
 	if s.engine == nil {
 		s.sendError(msg.ID, -32607, "no active execution")
 		return
 	}
 	var params struct {
-		OutputDir string `json:"outputDir"`
+		OutputDir  string `json:"outputDir"`
+		Compress   bool   `json:"compress,omitempty"`
+		EncryptKey string `json:"encryptKey,omitempty"` // base64 AES-256 key
 	}
 	if err := json.Unmarshal(msg.Params, &params); err != nil {
 		s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
@@ -1946,12 +2605,21 @@ func (s *Server) handleSaveScenario(msg *Message) {
 		s.sendError(msg.ID, -32602, "outputDir is required")
 		return
 	}
+	opts := replay.RecordOptions{Compress: params.Compress}
+	if params.EncryptKey != "" {
+		key, err := replay.ParseKey(params.EncryptKey)
+		if err != nil {
+			s.sendError(msg.ID, -32602, fmt.Sprintf("invalid encryptKey: %v", err))
+			return
+		}
+		opts.EncryptKey = key
+	}
 	// Create the output directory
 	if err := os.MkdirAll(params.OutputDir, 0755); err != nil {
 		s.sendError(msg.ID, -32603, fmt.Sprintf("create output dir: %v", err))
 		return
 	}
-	if err := s.engine.SaveScenario(params.OutputDir); err != nil {
+	if err := s.engine.SaveScenarioWithOptions(params.OutputDir, opts); err != nil {
 		s.sendError(msg.ID, -32603, fmt.Sprintf("save scenario: %v", err))
 		return
 	}
@@ -1961,11 +2629,171 @@ func (s *Server) handleSaveScenario(msg *Message) {
 	})
 }
 
+// recordRewindPoint remembers the cursor just before pn executes.
+func (s *Server) recordRewindPoint(pn pendingNode) {
+	pending := make([]pendingNode, 0, len(s.treeCursor.pending)+1)
+	pending = append(pending, pn)
+	pending = append(pending, s.treeCursor.pending...)
+	captures := cloneMap(s.engine.State.Captures)
+	if captures == nil {
+		captures = make(map[string]string)
+	}
+	s.rewindPoints = append(s.rewindPoints, rewindPoint{
+		stepID:   pn.node.Step.ID,
+		stepIdx:  s.treeCursor.stepIdx,
+		runID:    s.engine.GetRunID(),
+		vars:     cloneMap(s.engine.State.Vars),
+		captures: captures,
+		history:  len(s.engine.State.History),
+		pending:  pending,
+	})
+}
+
+// handleRewind rolls execution back to just before a previously executed
+// step, identified by stepId (most recent execution) or global index. Engine
+// state is restored from the preceding step's snapshot and the step becomes
+// the next one to run.
+func (s *Server) handleRewind(msg *Message) {
+	if s.engine == nil {
+		s.sendError(msg.ID, -32607, "no active execution — call exec/start first")
+		return
+	}
+	if s.treeCursor == nil {
+		s.sendError(msg.ID, -32611, "exec/rewind requires a tree runbook")
+		return
+	}
+
+	var params struct {
+		StepID string `json:"stepId"`
+		Index  *int   `json:"index,omitempty"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
+		return
+	}
+	if params.StepID == "" && params.Index == nil {
+		s.sendError(msg.ID, -32602, "stepId or index is required")
+		return
+	}
+
+	target := -1
+	for i := len(s.rewindPoints) - 1; i >= 0; i-- {
+		pt := s.rewindPoints[i]
+		if (params.Index != nil && pt.stepIdx == *params.Index) || (params.Index == nil && pt.stepID == params.StepID) {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		s.sendError(msg.ID, -32611, "no snapshot for the requested step")
+		return
+	}
+
+	// Every rolled-back step must belong to the active run: rewinding into
+	// or across an invoke would mix parent and child runbook state.
+	rolledBack := s.rewindPoints[target:]
+	runID := s.engine.GetRunID()
+	for _, pt := range rolledBack {
+		if pt.runID != runID {
+			s.sendError(msg.ID, -32611, fmt.Sprintf("cannot rewind across invoke boundary (step %q ran in run %s)", pt.stepID, pt.runID))
+			return
+		}
+	}
+	if s.engine.State.Mode == "real" && !s.AllowRewind {
+		for _, pt := range rolledBack {
+			if len(pt.pending) > 0 && s.engine.IsWriteEffectStep(pt.pending[0].node.Step) {
+				s.sendError(msg.ID, -32611, fmt.Sprintf("cannot rewind past side-effect step %q in real mode (requires --allow-rewind)", pt.stepID))
+				return
+			}
+		}
+	}
+
+	s.restoreRewindState(target)
+
+	pt := s.rewindPoints[target]
+	s.treeCursor.pending = append([]pendingNode(nil), pt.pending...)
+	s.treeCursor.stepIdx = pt.stepIdx
+	s.pendingManual = nil
+	s.pendingManualMsg = nil
+	s.rewindPoints = s.rewindPoints[:target]
+
+	reason := fmt.Sprintf("rewound to %s", pt.stepID)
+	for _, rb := range rolledBack {
+		s.sendEvent("event/stepSkipped", map[string]interface{}{
+			"stepId": rb.stepID, "index": rb.stepIdx, "reason": reason,
+		})
+	}
+
+	s.sendResult(msg.ID, map[string]interface{}{
+		"stepId":     pt.stepID,
+		"index":      pt.stepIdx,
+		"status":     "rewound",
+		"rolledBack": len(rolledBack),
+	})
+}
+
+// restoreRewindState resets engine vars, captures and history to their
+// state just before rewind point target, so nothing the rolled-back steps
+// captured or set survives, and removes those steps' snapshots. Points
+// restored from a session saved without captures fall back to the latest
+// earlier snapshot in the same run.
+func (s *Server) restoreRewindState(target int) {
+	pt := s.rewindPoints[target]
+	if pt.captures != nil {
+		s.engine.State.Vars = cloneMap(pt.vars)
+		s.engine.State.Captures = cloneMap(pt.captures)
+		if pt.history == 0 {
+			s.engine.State.History = nil
+		} else if pt.history < len(s.engine.State.History) {
+			s.engine.State.History = s.engine.State.History[:pt.history]
+		}
+	} else {
+		s.restoreRewindSnapshot(target)
+	}
+
+	for _, rb := range s.rewindPoints[target:] {
+		os.Remove(s.rewindSnapshotPath(rb.stepIdx))
+	}
+}
+
+// restoreRewindSnapshot restores state from the latest snapshot before
+// rewind point target in the same run. Steps that failed before
+// snapshotting are passed over.
+func (s *Server) restoreRewindSnapshot(target int) {
+	pt := s.rewindPoints[target]
+	for i := target - 1; i >= 0; i-- {
+		prev := s.rewindPoints[i]
+		if prev.runID != pt.runID {
+			break
+		}
+		snap, err := runtime.LoadSnapshot(s.rewindSnapshotPath(prev.stepIdx))
+		if err != nil {
+			continue
+		}
+		s.engine.State.Vars = snap.Vars
+		s.engine.State.Captures = snap.Captures
+		s.engine.State.History = snap.History
+		return
+	}
+
+	// First step of this run: nothing had been captured yet
+	s.engine.State.Vars = cloneMap(pt.vars)
+	s.engine.State.Captures = make(map[string]string)
+	s.engine.State.History = nil
+}
+
+func (s *Server) rewindSnapshotPath(stepIdx int) string {
+	return filepath.Join(s.engine.GetBaseDir(), "snapshots", fmt.Sprintf("step-%04d.json", stepIdx))
+}
+
 // handleDiagram generates a diagram from a runbook file or the currently loaded runbook.
+// format is any diagram.Format (mermaid, d2, plantuml, ascii, html); mermaid is the default.
+// With history set, mermaid and html nodes are coloured by the active run's step results.
 func (s *Server) handleDiagram(msg *Message) {
 	var params struct {
-		File   string `json:"file"`
-		Format string `json:"format"`
+		File    string `json:"file"`
+		Format  string `json:"format"`
+		History bool   `json:"history"`
 	}
 	if msg.Params != nil {
 		if err := json.Unmarshal(msg.Params, &params); err != nil {
@@ -1995,7 +2823,16 @@ func (s *Server) handleDiagram(msg *Message) {
 		format = diagram.Format(params.Format)
 	}
 
-	out, err := diagram.Generate(rb, format)
+	var opts diagram.Options
+	if params.History && s.engine != nil {
+		for _, r := range s.engine.State.History {
+			if r != nil {
+				opts.Statuses = append(opts.Statuses, diagram.StepStatus{StepID: r.StepID, Status: r.Status})
+			}
+		}
+	}
+
+	out, err := diagram.GenerateWithOptions(rb, format, opts)
 	if err != nil {
 		s.sendError(msg.ID, -32603, fmt.Sprintf("generate diagram: %v", err))
 		return
@@ -2007,6 +2844,131 @@ func (s *Server) handleDiagram(msg *Message) {
 	})
 }
 
+// handleRunbookDiff compares two runbooks step by step (schema.Diff).
+// before is required; after defaults to the currently loaded runbook.
+func (s *Server) handleRunbookDiff(msg *Message) {
+	var params struct {
+		Before string `json:"before"`
+		After  string `json:"after"`
+	}
+	if msg.Params != nil {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
+			return
+		}
+	}
+	if params.Before == "" {
+		s.sendError(msg.ID, -32602, "before is required")
+		return
+	}
+
+	before, err := schema.LoadFile(params.Before)
+	if err != nil {
+		s.sendError(msg.ID, -32603, fmt.Sprintf("load runbook: %v", err))
+		return
+	}
+
+	var after *schema.Runbook
+	if params.After != "" {
+		after, err = schema.LoadFile(params.After)
+		if err != nil {
+			s.sendError(msg.ID, -32603, fmt.Sprintf("load runbook: %v", err))
+			return
+		}
+	} else if s.runbook != nil {
+		after = s.runbook
+	} else {
+		s.sendError(msg.ID, -32604, "no runbook specified or loaded")
+		return
+	}
+
+	s.sendResult(msg.ID, map[string]interface{}{
+		"entries": schema.Diff(before, after),
+	})
+}
+
+// handleValidate runs the kernel/v0 validation pipeline on a runbook and
+// returns its errors and warnings, each with a line and column when known;
+// with "profile" it adds per-phase timings. The runbook is "content" when
+// given (an unsaved buffer; "file", if also given, locates its tools) and
+// otherwise read from "file". Nothing is written and no engine is started.
+func (s *Server) handleValidate(msg *Message) {
+	var params struct {
+		File    string  `json:"file"`
+		Content *string `json:"content"`
+		Profile bool    `json:"profile"`
+	}
+	if msg.Params != nil {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
+			return
+		}
+	}
+	if params.File == "" && params.Content == nil {
+		s.sendError(msg.ID, -32602, "file or content is required")
+		return
+	}
+
+	var errs []*kvalidate.ValidationError
+	var profile *kvalidate.ValidationProfile
+	if params.Content != nil {
+		baseDir := ""
+		if params.File != "" {
+			baseDir = filepath.Dir(params.File)
+		}
+		_, errs, profile = kvalidate.ValidateSource([]byte(*params.Content), baseDir)
+	} else {
+		_, errs, profile = kvalidate.ValidateFileProfiled(params.File)
+	}
+
+	failures := []*kvalidate.ValidationError{}
+	warnings := []*kvalidate.ValidationError{}
+	for _, e := range errs {
+		if e.Severity == "error" {
+			failures = append(failures, e)
+		} else {
+			warnings = append(warnings, e)
+		}
+	}
+	result := map[string]interface{}{
+		"valid":    len(failures) == 0,
+		"errors":   failures,
+		"warnings": warnings,
+	}
+	if params.Profile {
+		result["profile"] = profile
+	}
+	s.sendResult(msg.ID, result)
+}
+
+// handleInspect validates a kernel/v0 runbook file and returns its inputs,
+// constants, tools and variable flow (validate.Inspect).
+func (s *Server) handleInspect(msg *Message) {
+	var params struct {
+		File string `json:"file"`
+	}
+	if msg.Params != nil {
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			s.sendError(msg.ID, -32602, fmt.Sprintf("invalid params: %v", err))
+			return
+		}
+	}
+	if params.File == "" {
+		s.sendError(msg.ID, -32602, "file is required")
+		return
+	}
+
+	rb, errs := kvalidate.ValidateFile(params.File)
+	for _, e := range errs {
+		if e.Severity == "error" {
+			s.sendError(msg.ID, -32603, fmt.Sprintf("validation failed: %s", e))
+			return
+		}
+	}
+
+	s.sendResult(msg.ID, kvalidate.Inspect(rb, filepath.Dir(params.File)))
+}
+
 // --- Message sending ---
 
 func (s *Server) sendResult(id *int, result interface{}) {
@@ -2041,8 +3003,9 @@ func (s *Server) sendEvent(method string, params interface{}) {
 func (s *Server) send(msg *Message) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	data, _ := json.Marshal(msg)
-	fmt.Fprintf(s.writer, "%s\n", data)
+	if err := s.conn.send(msg); err != nil {
+		s.Logger.Error("send failed", slog.String("method", msg.Method), slog.Any("error", err))
+	}
 }
 
 // hasServeValidationErrors returns true if any non-warning validation error exists.
@@ -2055,6 +3018,22 @@ func hasServeValidationErrors(errs []*schema.ValidationError) bool {
 	return false
 }
 
+// hasServeValidationWarnings returns true if the list contains any warnings.
+func hasServeValidationWarnings(errs []*schema.ValidationError) bool {
+	return countServeValidationWarnings(errs) > 0
+}
+
+// countServeValidationWarnings counts warning-severity entries.
+func countServeValidationWarnings(errs []*schema.ValidationError) int {
+	n := 0
+	for _, e := range errs {
+		if e.Severity == "warning" {
+			n++
+		}
+	}
+	return n
+}
+
 // firstServeError returns the first non-warning error.
 func firstServeError(errs []*schema.ValidationError) *schema.ValidationError {
 	for _, e := range errs {
@@ -2081,6 +3060,20 @@ func (d *DryRunExecutor) Execute(ctx context.Context, command string, args []str
 	}, nil
 }
 
+// evidenceCollector returns the collector for real and probe runs: the
+// client, wrapped to upload evidence when the host set EvidenceCollector.
+func (s *Server) evidenceCollector() (providers.EvidenceCollector, error) {
+	client := &ServeCollector{server: s}
+	switch s.EvidenceCollector {
+	case "":
+		return client, nil
+	case "azureblob":
+		return providers.NewAzureBlobCollectorFromEnv(client)
+	default:
+		return nil, fmt.Errorf("unknown evidence collector %q", s.EvidenceCollector)
+	}
+}
+
 // ServeCollector implements EvidenceCollector by waiting for messages from the extension.
 type ServeCollector struct {
 	server *Server
@@ -2094,17 +3087,34 @@ func (c *ServeCollector) PromptText(name string, instructions string) (string, e
 			return val, nil
 		}
 	}
-	c.server.sendEvent("event/inputRequired", map[string]interface{}{
+	// Inputs with a validation spec send it along so the client can check
+	// values before submitting; values that still fail are asked again.
+	var validation *schema.InputValidation
+	if in := c.server.engine.Runbook.Meta.Inputs[name]; in != nil {
+		validation = in.Validation
+	}
+	event := map[string]interface{}{
 		"kind":         "text",
 		"name":         name,
 		"instructions": instructions,
-	})
-	// Wait for evidence submission
-	ev := <-c.server.evidenceCh
-	if val, ok := ev.Evidence[name]; ok {
+	}
+	if validation != nil {
+		event["validation"] = validation
+	}
+	for {
+		c.server.sendEvent("event/inputRequired", event)
+		// Wait for evidence submission
+		ev := <-c.server.evidenceCh
+		val, ok := ev.Evidence[name]
+		if !ok {
+			return "", fmt.Errorf("no evidence received for %q", name)
+		}
+		if err := inputs.Validate(validation, val.Value); err != nil {
+			event["error"] = err.Error()
+			continue
+		}
 		return val.Value, nil
 	}
-	return "", fmt.Errorf("no evidence received for %q", name)
 }
 
 func (c *ServeCollector) PromptChecklist(name string, items []string) (map[string]bool, error) {
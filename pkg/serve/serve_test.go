@@ -0,0 +1,1251 @@
+package serve
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ormasoftchile/gert/pkg/governance"
+	"github.com/ormasoftchile/gert/pkg/providers"
+	"github.com/ormasoftchile/gert/pkg/runtime"
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// ─── exec/rewind tests ──────────────────────────────────────────────
+
+// newRewindServer builds a server mid-run on a three-step tree: s1 and s2
+// have executed (each with a snapshot), s3 is next.
+func newRewindServer(t *testing.T, mode string) (*Server, *bytes.Buffer) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "rewind-test", Vars: map[string]string{"env": "prod"}},
+		Tree: []schema.TreeNode{
+			{Step: schema.Step{ID: "s1", Type: "manual", Title: "S1"}},
+			{Step: schema.Step{ID: "s2", Type: "cli", Title: "S2"}},
+			{Step: schema.Step{ID: "s3", Type: "manual", Title: "S3"}},
+		},
+	}
+	engine, err := runtime.NewEngine(rb, nil, nil, mode, "tester")
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	s.engine = engine
+	s.runbook = rb
+	s.treeCursor = newTreeCursor(rb.Tree)
+
+	for i, capture := range []string{"a", "b"} {
+		s.recordRewindPoint(s.treeCursor.pop())
+		engine.State.Captures[capture] = capture
+		engine.State.History = append(engine.State.History, nil)
+		path := filepath.Join(engine.GetBaseDir(), "snapshots", fmt.Sprintf("step-%04d.json", i))
+		if err := runtime.SaveSnapshot(engine.State, path); err != nil {
+			t.Fatalf("SaveSnapshot: %v", err)
+		}
+		s.treeCursor.stepIdx++
+	}
+	return s, &out
+}
+
+func rewind(s *Server, params string) {
+	id := 1
+	s.handleRewind(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/rewind", Params: json.RawMessage(params)})
+}
+
+func decodeMessages(t *testing.T, out *bytes.Buffer) []Message {
+	t.Helper()
+	var msgs []Message
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var m Message
+		if err := json.Unmarshal([]byte(line), &m); err != nil {
+			t.Fatalf("decode %q: %v", line, err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs
+}
+
+func TestHandleRewind_ByStepID(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+
+	rewind(s, `{"stepId": "s2"}`)
+
+	msgs := decodeMessages(t, out)
+	if len(msgs) != 2 {
+		t.Fatalf("expected 1 event + 1 result, got %d: %s", len(msgs), out.String())
+	}
+	if msgs[0].Method != "event/stepSkipped" || !strings.Contains(string(msgs[0].Params), `"stepId":"s2"`) {
+		t.Errorf("expected stepSkipped for s2, got %s %s", msgs[0].Method, msgs[0].Params)
+	}
+	if msgs[1].Error != nil {
+		t.Fatalf("unexpected error: %s", msgs[1].Error.Message)
+	}
+
+	if got := s.engine.State.Captures; len(got) != 1 || got["a"] != "a" {
+		t.Errorf("captures = %v, want only a", got)
+	}
+	if len(s.engine.State.History) != 1 {
+		t.Errorf("history len = %d, want 1", len(s.engine.State.History))
+	}
+	if s.treeCursor.stepIdx != 1 {
+		t.Errorf("stepIdx = %d, want 1", s.treeCursor.stepIdx)
+	}
+	if len(s.treeCursor.pending) != 2 || s.treeCursor.pending[0].node.Step.ID != "s2" {
+		t.Errorf("cursor head should be s2, got %v", serializePendingQueue(s.treeCursor.pending))
+	}
+	if len(s.rewindPoints) != 1 {
+		t.Errorf("rewind points = %d, want 1", len(s.rewindPoints))
+	}
+}
+
+func TestHandleRewind_FirstStepByIndex(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+	s.pendingManual = &pendingNode{node: schema.TreeNode{Step: schema.Step{ID: "s3"}}}
+
+	rewind(s, `{"index": 0}`)
+
+	msgs := decodeMessages(t, out)
+	skipped := 0
+	for _, m := range msgs {
+		if m.Method == "event/stepSkipped" {
+			skipped++
+		}
+	}
+	if skipped != 2 {
+		t.Errorf("expected 2 stepSkipped events, got %d", skipped)
+	}
+	if len(s.engine.State.Captures) != 0 || s.engine.State.History != nil {
+		t.Errorf("expected empty captures/history, got %v / %v", s.engine.State.Captures, s.engine.State.History)
+	}
+	if s.engine.State.Vars["env"] != "prod" {
+		t.Errorf("vars not restored: %v", s.engine.State.Vars)
+	}
+	if s.pendingManual != nil {
+		t.Error("pendingManual should be cleared")
+	}
+	if len(s.treeCursor.pending) != 3 {
+		t.Errorf("expected all 3 steps pending, got %d", len(s.treeCursor.pending))
+	}
+}
+
+func TestHandleRewind_RealModeSideEffects(t *testing.T) {
+	s, out := newRewindServer(t, "real")
+
+	rewind(s, `{"stepId": "s1"}`)
+	msgs := decodeMessages(t, out)
+	if msgs[len(msgs)-1].Error == nil || !strings.Contains(msgs[len(msgs)-1].Error.Message, "--allow-rewind") {
+		t.Fatalf("expected side-effect rejection, got %s", out.String())
+	}
+	if s.treeCursor.stepIdx != 2 {
+		t.Errorf("rejected rewind must not move the cursor, stepIdx = %d", s.treeCursor.stepIdx)
+	}
+
+	out.Reset()
+	s.AllowRewind = true
+	rewind(s, `{"stepId": "s1"}`)
+	msgs = decodeMessages(t, out)
+	if msgs[len(msgs)-1].Error != nil {
+		t.Fatalf("unexpected error with AllowRewind: %s", msgs[len(msgs)-1].Error.Message)
+	}
+}
+
+func TestHandleRewind_InvokeBoundary(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+	s.rewindPoints[0].runID = "parent-run"
+
+	rewind(s, `{"stepId": "s1"}`)
+	msgs := decodeMessages(t, out)
+	if msgs[0].Error == nil || !strings.Contains(msgs[0].Error.Message, "invoke boundary") {
+		t.Fatalf("expected invoke boundary rejection, got %s", out.String())
+	}
+}
+
+// ─── exec/setVar tests ──────────────────────────────────────────────
+
+func setVar(s *Server, params string) {
+	id := 1
+	s.handleSetVar(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/setVar", Params: json.RawMessage(params)})
+}
+
+func TestHandleSetVar(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+	s.engine.Redact = []*governance.CompiledRedaction{{Pattern: regexp.MustCompile(`tok-\w+`), Replace: "***"}}
+
+	// Rejected while no manual step is waiting
+	setVar(s, `{"name": "host", "value": "db1"}`)
+	msgs := decodeMessages(t, out)
+	if msgs[0].Error == nil || msgs[0].Error.Code != -32608 {
+		t.Fatalf("expected -32608 without a pending manual step, got %s", out.String())
+	}
+
+	s.pendingManual = &pendingNode{node: schema.TreeNode{Step: schema.Step{ID: "s3"}}}
+
+	// Constants from meta.vars cannot be changed
+	out.Reset()
+	setVar(s, `{"name": "env", "value": "dev"}`)
+	msgs = decodeMessages(t, out)
+	if msgs[0].Error == nil || msgs[0].Error.Code != -32609 {
+		t.Fatalf("expected -32609 for a meta.vars constant, got %s", out.String())
+	}
+	if s.engine.State.Vars["env"] != "prod" {
+		t.Errorf("env = %q, want prod", s.engine.State.Vars["env"])
+	}
+
+	out.Reset()
+	setVar(s, `{"name": "token", "value": "tok-abc123"}`)
+	msgs = decodeMessages(t, out)
+	if len(msgs) != 2 || msgs[0].Method != "event/varChanged" || msgs[1].Error != nil {
+		t.Fatalf("expected varChanged event and result, got %s", out.String())
+	}
+	var evt map[string]string
+	json.Unmarshal(msgs[0].Params, &evt)
+	if evt["name"] != "token" || evt["oldValue"] != "" || evt["newValue"] != "***" {
+		t.Errorf("varChanged = %v, want masked newValue", evt)
+	}
+	if strings.Contains(out.String(), "abc123") {
+		t.Errorf("redacted value leaked: %s", out.String())
+	}
+	if got := s.engine.ResolveTemplatePublic("{{ .token }}"); got != "tok-abc123" {
+		t.Errorf("template resolved to %q, want the new value", got)
+	}
+}
+
+// ─── exec/getStepDef tests ──────────────────────────────────────────
+
+func getStepDef(s *Server) {
+	id := 1
+	s.handleGetStepDef(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/getStepDef"})
+}
+
+func TestHandleGetStepDef(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+
+	getStepDef(s)
+	msgs := decodeMessages(t, out)
+	if msgs[0].Error == nil || msgs[0].Error.Code != -32608 || msgs[0].Error.Message != "no pending step" {
+		t.Fatalf("expected -32608 without a pending step, got %s", out.String())
+	}
+
+	s.pendingManual = &pendingNode{node: schema.TreeNode{Step: schema.Step{
+		ID:               "s3",
+		Type:             "manual",
+		Title:            "Check {{ .env }}",
+		Instructions:     "Inspect {{ .missing }}",
+		RequiredEvidence: []schema.EvidenceRequirement{{Kind: "checklist", Name: "checks", Items: []string{"logs in {{ .env }}"}}},
+		Choices: &schema.ChoiceConfig{Variable: "action", Options: []schema.ChoiceOption{
+			{Value: "restart", Label: "Restart {{ .env }}"}, {Value: "skip"},
+		}},
+	}}}
+	out.Reset()
+	getStepDef(s)
+	msgs = decodeMessages(t, out)
+	if msgs[0].Error != nil {
+		t.Fatalf("unexpected error: %s", out.String())
+	}
+	var def struct {
+		ID               string                       `json:"id"`
+		Title            string                       `json:"title"`
+		Instructions     string                       `json:"instructions"`
+		RequiredEvidence []schema.EvidenceRequirement `json:"required_evidence"`
+		Choices          *schema.ChoiceConfig         `json:"choices"`
+	}
+	json.Unmarshal(msgs[0].Result, &def)
+	if def.ID != "s3" || def.Title != "Check prod" {
+		t.Errorf("id/title = %q/%q, want s3/Check prod", def.ID, def.Title)
+	}
+	if def.Instructions != "Inspect {{ .missing }}" {
+		t.Errorf("unresolvable template should be kept, got %q", def.Instructions)
+	}
+	if len(def.RequiredEvidence) != 1 || def.RequiredEvidence[0].Items[0] != "logs in prod" {
+		t.Errorf("required_evidence = %+v", def.RequiredEvidence)
+	}
+	if def.Choices == nil || def.Choices.Options[0].Label != "Restart prod" {
+		t.Errorf("choices = %+v", def.Choices)
+	}
+}
+
+func TestHandleGetStepDef_FlatMode(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+	s.treeCursor = nil
+	s.runbook.Steps = []schema.Step{{ID: "ack", Type: "manual", Title: "Acknowledge {{ .env }}"}}
+
+	getStepDef(s)
+	msgs := decodeMessages(t, out)
+	var def map[string]interface{}
+	json.Unmarshal(msgs[0].Result, &def)
+	if msgs[0].Error != nil || def["id"] != "ack" || def["title"] != "Acknowledge prod" {
+		t.Fatalf("expected flat manual step, got %s", out.String())
+	}
+
+	s.engine.State.CurrentStepIndex = 1
+	out.Reset()
+	getStepDef(s)
+	msgs = decodeMessages(t, out)
+	if msgs[0].Error == nil || msgs[0].Error.Code != -32608 {
+		t.Fatalf("expected -32608 past the last step, got %s", out.String())
+	}
+}
+
+func TestHandleRewind_DropsRolledBackOutputs(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+	// s3 is waiting on a choice when the run is rewound to s2
+	s.pendingManual = &pendingNode{node: schema.TreeNode{Step: schema.Step{ID: "s3"}}}
+	s.engine.SetVar("action", "restart")
+
+	rewind(s, `{"stepId": "s2"}`)
+	out.Reset()
+	id := 2
+	s.handleGetVariables(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/getVariables"})
+
+	var got struct {
+		Result struct {
+			Vars     map[string]string `json:"vars"`
+			Captures map[string]string `json:"captures"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("decode %s: %v", out.String(), err)
+	}
+	if len(got.Result.Captures) != 1 || got.Result.Captures["a"] != "a" {
+		t.Errorf("captures = %v, want only s1's a", got.Result.Captures)
+	}
+	if _, ok := got.Result.Vars["action"]; ok {
+		t.Errorf("vars still hold the rolled-back choice: %v", got.Result.Vars)
+	}
+	if got.Result.Vars["env"] != "prod" {
+		t.Errorf("vars = %v, want env kept", got.Result.Vars)
+	}
+
+	snapshots := filepath.Join(s.engine.GetBaseDir(), "snapshots")
+	if _, err := os.Stat(filepath.Join(snapshots, "step-0001.json")); !os.IsNotExist(err) {
+		t.Errorf("rolled-back s2 snapshot still present (err %v)", err)
+	}
+	if _, err := os.Stat(filepath.Join(snapshots, "step-0000.json")); err != nil {
+		t.Errorf("s1 snapshot removed: %v", err)
+	}
+}
+
+func TestHandleRewind_UnknownStep(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+
+	rewind(s, `{"stepId": "nope"}`)
+	msgs := decodeMessages(t, out)
+	if msgs[0].Error == nil || msgs[0].Error.Code != -32611 {
+		t.Fatalf("expected -32611 error, got %s", out.String())
+	}
+}
+
+// ─── runbook/diff tests ─────────────────────────────────────────────
+
+func TestHandleRunbookDiff_AgainstLoadedRunbook(t *testing.T) {
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.yaml")
+	os.WriteFile(before, []byte(`apiVersion: runbook/v0
+meta:
+  name: diff-test
+steps:
+  - id: s1
+    type: manual
+    title: Old title
+`), 0o644)
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	s.runbook = &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "diff-test"},
+		Steps:      []schema.Step{{ID: "s1", Type: "manual", Title: "New title"}},
+	}
+
+	id := 1
+	s.handleRunbookDiff(&Message{JSONRPC: "2.0", ID: &id, Method: "runbook/diff",
+		Params: json.RawMessage(fmt.Sprintf(`{"before": %q}`, before))})
+
+	msgs := decodeMessages(t, &out)
+	if msgs[0].Error != nil {
+		t.Fatalf("unexpected error: %s", msgs[0].Error.Message)
+	}
+	var result struct {
+		Entries []schema.DiffEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(msgs[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Path != "steps.s1.title" {
+		t.Errorf("entries = %+v, want one title change", result.Entries)
+	}
+}
+
+func TestHandleRunbookDiff_RequiresBefore(t *testing.T) {
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+
+	id := 1
+	s.handleRunbookDiff(&Message{JSONRPC: "2.0", ID: &id, Method: "runbook/diff", Params: json.RawMessage(`{}`)})
+
+	msgs := decodeMessages(t, &out)
+	if msgs[0].Error == nil || msgs[0].Error.Code != -32602 {
+		t.Fatalf("expected -32602, got %s", out.String())
+	}
+}
+
+// ─── runbook/inspect tests ──────────────────────────────────────────
+
+func TestHandleInspect(t *testing.T) {
+	rbPath := filepath.Join(t.TempDir(), "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: inspect-test
+  inputs:
+    hostname: { type: string, required: true }
+steps:
+  - id: ask
+    type: manual
+    instructions: "Log in to {{ .hostname }}"
+  - id: done
+    type: end
+    outcome:
+      category: no_action
+      code: ok
+`), 0o644)
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	id := 1
+	s.handleInspect(&Message{JSONRPC: "2.0", ID: &id, Method: "runbook/inspect",
+		Params: json.RawMessage(fmt.Sprintf(`{"file": %q}`, rbPath))})
+
+	msgs := decodeMessages(t, &out)
+	if msgs[0].Error != nil {
+		t.Fatalf("unexpected error: %s", msgs[0].Error.Message)
+	}
+	var result struct {
+		Name      string `json:"name"`
+		Variables []struct {
+			Name      string   `json:"name"`
+			Consumers []string `json:"consumers"`
+		} `json:"variables"`
+	}
+	if err := json.Unmarshal(msgs[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Name != "inspect-test" || len(result.Variables) != 1 || result.Variables[0].Consumers[0] != "ask" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestHandleValidate_Profile(t *testing.T) {
+	rbPath := filepath.Join(t.TempDir(), "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: kernel/v0
+meta:
+  name: validate-test
+steps:
+  - id: done
+    type: end
+    outcome:
+      category: bogus
+      code: ok
+`), 0o644)
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	id := 1
+	s.handleValidate(&Message{JSONRPC: "2.0", ID: &id, Method: "runbook/validate",
+		Params: json.RawMessage(fmt.Sprintf(`{"file": %q, "profile": true}`, rbPath))})
+
+	msgs := decodeMessages(t, &out)
+	if msgs[0].Error != nil {
+		t.Fatalf("unexpected error: %s", msgs[0].Error.Message)
+	}
+	var result struct {
+		Valid  bool `json:"valid"`
+		Errors []struct {
+			Phase string `json:"phase"`
+		} `json:"errors"`
+		Profile *struct {
+			ParseMs int64 `json:"parse_ms"`
+		} `json:"profile"`
+	}
+	if err := json.Unmarshal(msgs[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || len(result.Errors) == 0 || result.Profile == nil {
+		t.Errorf("result = %s", msgs[0].Result)
+	}
+}
+
+func TestHandleValidate_Content(t *testing.T) {
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	content := "apiVersion: kernel/v0\nmeta:\n  name: buffer\nsteps:\n  - id: done\n    type: end\n    outcome:\n      category: bogus\n      code: ok\n"
+	id := 1
+	s.handleValidate(&Message{JSONRPC: "2.0", ID: &id, Method: "runbook/validate",
+		Params: json.RawMessage(fmt.Sprintf(`{"content": %q}`, content))})
+	s.handleValidate(&Message{JSONRPC: "2.0", ID: &id, Method: "runbook/validate",
+		Params: json.RawMessage(`{}`)})
+
+	msgs := decodeMessages(t, &out)
+	if msgs[0].Error != nil {
+		t.Fatalf("unexpected error: %s", msgs[0].Error.Message)
+	}
+	var result struct {
+		Valid  bool `json:"valid"`
+		Errors []struct {
+			Path string `json:"path"`
+			Line int    `json:"line"`
+		} `json:"errors"`
+		Warnings []any `json:"warnings"`
+	}
+	if err := json.Unmarshal(msgs[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Valid || len(result.Errors) == 0 || result.Warnings == nil {
+		t.Errorf("result = %s", msgs[0].Result)
+	}
+	for _, e := range result.Errors {
+		if e.Line == 0 {
+			t.Errorf("error at %s has no line", e.Path)
+		}
+	}
+	if msgs[1].Error == nil || msgs[1].Error.Code != -32602 {
+		t.Errorf("missing file and content: %+v", msgs[1])
+	}
+}
+
+func TestHandleDiagram_HistoryOverlay(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+	s.engine.State.History = []*providers.StepResult{
+		{StepID: "s1", Status: "passed"},
+		{StepID: "s2", Status: "failed"},
+	}
+	id := 1
+	s.handleDiagram(&Message{JSONRPC: "2.0", ID: &id, Method: "runbook/diagram",
+		Params: json.RawMessage(`{"format": "html", "history": true}`)})
+
+	msgs := decodeMessages(t, out)
+	if msgs[0].Error != nil {
+		t.Fatalf("unexpected error: %s", msgs[0].Error.Message)
+	}
+	var result struct {
+		Format  string `json:"format"`
+		Diagram string `json:"diagram"`
+	}
+	if err := json.Unmarshal(msgs[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"class s1 passed", "class s2 failed", "class s3 pending"} {
+		if result.Format != "html" || !strings.Contains(result.Diagram, want) {
+			t.Errorf("diagram missing %q:\n%s", want, result.Diagram)
+		}
+	}
+}
+
+// ─── exec/start strict validation ───────────────────────────────────
+
+func TestHandleExecStart_StrictRejectsWarnings(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	rbPath := filepath.Join(dir, "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: runbook/v0
+meta:
+  name: strict-test
+steps:
+  - id: s1
+    type: manual
+    title: Check
+    instructions: Look at the dashboard
+    precondition:
+      check: ["true"]
+`), 0o644)
+
+	_, errs := schema.ValidateFile(rbPath)
+	if hasServeValidationErrors(errs) || countServeValidationWarnings(errs) != 1 {
+		t.Fatalf("fixture should produce exactly one warning, got %v", errs)
+	}
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	id := 1
+	s.handleExecStart(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/start",
+		Params: json.RawMessage(fmt.Sprintf(`{"runbook": %q, "mode": "dry-run", "strict": true}`, rbPath))})
+
+	msgs := decodeMessages(t, &out)
+	if msgs[0].Error == nil || !strings.Contains(msgs[0].Error.Message, "strict") {
+		t.Fatalf("expected strict validation error, got %s", out.String())
+	}
+	if s.runbook != nil {
+		t.Error("runbook should not be loaded after a strict rejection")
+	}
+}
+
+func TestHandleExecStart_SkipsInputsByWhen(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	rbPath := filepath.Join(dir, "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: runbook/v0
+meta:
+  name: when-test
+  inputs:
+    cloud:
+      from: prompt
+    region:
+      from: prompt
+      default: us-east-1
+      when: cloud == "aws"
+    project:
+      from: prompt
+      default: p1
+      when: cloud == "gcp"
+steps:
+  - id: s1
+    type: manual
+    title: Check
+    instructions: Look at the dashboard
+`), 0o644)
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	id := 1
+	s.handleExecStart(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/start",
+		Params: json.RawMessage(fmt.Sprintf(`{"runbook": %q, "mode": "dry-run", "vars": {"cloud": "aws"}}`, rbPath))})
+
+	if s.engine == nil {
+		t.Fatalf("exec/start failed: %s", out.String())
+	}
+	vars := s.engine.BuildManifest().InputsResolved
+	if vars["region"] != "us-east-1" {
+		t.Errorf("region = %q, want its default", vars["region"])
+	}
+	if _, ok := vars["project"]; ok {
+		t.Errorf("project has a false when: but is in the manifest: %v", vars)
+	}
+}
+
+// ─── exec/plan tests ────────────────────────────────────────────────
+
+func TestHandleExecPlan(t *testing.T) {
+	dir := t.TempDir()
+	rbPath := filepath.Join(dir, "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: runbook/v0
+meta:
+  name: plan-test
+  vars:
+    dir: /tmp
+steps:
+  - id: s1
+    type: cli
+    title: List
+    with:
+      argv: ["ls", "{{ .dir }}"]
+`), 0o644)
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	id := 1
+	s.dispatch(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/plan",
+		Params: json.RawMessage(fmt.Sprintf(`{"runbook": %q, "mode": "real", "cwd": %q, "vars": {"dir": "/var/log"}}`, rbPath, dir))})
+
+	msgs := decodeMessages(t, &out)
+	if msgs[0].Error != nil {
+		t.Fatalf("exec/plan error: %s", msgs[0].Error.Message)
+	}
+	var plan runtime.ExecutionPlan
+	if err := json.Unmarshal(msgs[0].Result, &plan); err != nil {
+		t.Fatalf("decode plan: %v", err)
+	}
+	if len(plan.Steps) != 1 || plan.Steps[0].ResolvedCommand != "ls /var/log" || !plan.Steps[0].WouldExecute {
+		t.Errorf("plan = %+v", plan)
+	}
+	if s.engine != nil || s.runbook != nil {
+		t.Error("exec/plan should not start a run")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".runbook")); !os.IsNotExist(err) {
+		t.Error("exec/plan should not create a run directory")
+	}
+}
+
+// ─── exec/getHistory tests ──────────────────────────────────────────
+
+func TestHandleGetHistory_SinceStep(t *testing.T) {
+	rb := &schema.Runbook{APIVersion: "runbook/v0", Meta: schema.Meta{Name: "history-test"}}
+	t.Chdir(t.TempDir())
+	engine, err := runtime.NewEngine(rb, nil, nil, "dry-run", "tester")
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	started := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	engine.State.History = []*providers.StepResult{
+		{StepID: "s1", Status: "passed", StartedAt: started, EndedAt: started.Add(time.Second), Captures: map[string]string{"host": "db1"}},
+		{StepID: "s2", Status: "failed", StartedAt: started.Add(time.Second), EndedAt: started.Add(2 * time.Second), Error: "exit 1"},
+		{StepID: "s3", Status: "passed"},
+	}
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	s.engine = engine
+	id := 1
+	s.handleGetHistory(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/getHistory", Params: json.RawMessage(`{"sinceStep": 1}`)})
+	s.handleGetHistory(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/getHistory"})
+	s.handleGetHistory(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/getHistory", Params: json.RawMessage(`{"sinceStep": 9}`)})
+
+	msgs := decodeMessages(t, &out)
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 results, got %d: %s", len(msgs), out.String())
+	}
+	var page struct {
+		History []HistoryEntry `json:"history"`
+		Total   int            `json:"total"`
+	}
+	if err := json.Unmarshal(msgs[0].Result, &page); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if page.Total != 3 || len(page.History) != 2 {
+		t.Fatalf("since 1: total=%d entries=%d, want 3/2", page.Total, len(page.History))
+	}
+	if h := page.History[0]; h.StepID != "s2" || h.Status != "failed" || h.Error != "exit 1" || !h.EndedAt.Equal(started.Add(2*time.Second)) {
+		t.Errorf("first entry = %+v", h)
+	}
+
+	if err := json.Unmarshal(msgs[1].Result, &page); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if len(page.History) != 3 || page.History[0].Captures["host"] != "db1" {
+		t.Errorf("full history = %+v", page.History)
+	}
+
+	page.History = nil
+	if err := json.Unmarshal(msgs[2].Result, &page); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if page.History == nil || len(page.History) != 0 {
+		t.Errorf("past the end: history = %v, want []", page.History)
+	}
+}
+
+func TestHandleGetHistory_NoExecution(t *testing.T) {
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	id := 1
+	s.handleGetHistory(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/getHistory"})
+	msgs := decodeMessages(t, &out)
+	if len(msgs) != 1 || msgs[0].Error == nil || msgs[0].Error.Code != -32607 {
+		t.Fatalf("expected -32607 error, got %s", out.String())
+	}
+}
+
+// ─── exec/previewNext tests ─────────────────────────────────────────
+
+// recordingExecutor records every command it is asked to run.
+type recordingExecutor struct {
+	calls []string
+}
+
+func (r *recordingExecutor) Execute(ctx context.Context, command string, args []string, env []string) (*providers.CommandResult, error) {
+	r.calls = append(r.calls, strings.Join(append([]string{command}, args...), " "))
+	return &providers.CommandResult{}, nil
+}
+
+func TestHandlePreviewNext(t *testing.T) {
+	t.Chdir(t.TempDir())
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "preview-test", Vars: map[string]string{"host": "web1"}},
+		Tree: []schema.TreeNode{
+			{Step: schema.Step{
+				ID: "drain", Type: "cli", Title: "Drain {{ .host }}", When: "{{ .host }}",
+				With:         &schema.CLIStepConfig{Argv: []string{"drain", "{{ .host }}"}},
+				Precondition: &schema.Precondition{Check: []string{"probe", "{{ .host }}"}, SkipIfSucceeds: true, Message: "already drained"},
+			}},
+			{
+				Step:     schema.Step{ID: "route", Type: "manual", Title: "Route"},
+				Branches: []schema.Branch{{Condition: "true", Steps: []schema.TreeNode{{Step: schema.Step{ID: "fix", Type: "manual"}}}}},
+			},
+		},
+	}
+	executor := &recordingExecutor{}
+	engine, err := runtime.NewEngine(rb, executor, nil, "real", "tester")
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	s.engine = engine
+	s.runbook = rb
+	s.treeCursor = newTreeCursor(rb.Tree)
+
+	preview := func() map[string]any {
+		t.Helper()
+		out.Reset()
+		id := 1
+		s.handlePreviewNext(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/previewNext"})
+		msgs := decodeMessages(t, &out)
+		if len(msgs) != 1 || msgs[0].Result == nil {
+			t.Fatalf("expected a single result and no events, got %s", out.String())
+		}
+		var res map[string]any
+		json.Unmarshal(msgs[0].Result, &res)
+		return res
+	}
+
+	res := preview()
+	if res["stepId"] != "drain" || res["title"] != "Drain web1" || res["command"] != "drain web1" {
+		t.Errorf("preview = %v", res)
+	}
+	if res["status"] != "pending" || res["autoAdvance"] != false {
+		t.Errorf("status = %v, autoAdvance = %v, want pending/false", res["status"], res["autoAdvance"])
+	}
+	if pc, _ := res["precondition"].(map[string]any); pc["check"] != "probe web1" || pc["message"] != "already drained" {
+		t.Errorf("precondition = %v", res["precondition"])
+	}
+	if n := len(executor.calls); n != 0 {
+		t.Errorf("preview ran %d command(s): %v", n, executor.calls)
+	}
+	if when, _ := res["when"].(map[string]any); when["matched"] != true {
+		t.Errorf("when = %v", res["when"])
+	}
+	if len(s.treeCursor.pending) != 2 || s.treeCursor.stepIdx != 0 {
+		t.Fatalf("preview moved the cursor: %d pending, index %d", len(s.treeCursor.pending), s.treeCursor.stepIdx)
+	}
+
+	s.treeCursor.pop()
+	if res := preview(); res["stepId"] != "route" || res["status"] != "pending" || res["autoAdvance"] != true {
+		t.Errorf("routing step preview = %v", res)
+	}
+
+	s.treeCursor.pop()
+	if res := preview(); res["status"] != "completed" {
+		t.Errorf("empty cursor preview = %v", res)
+	}
+}
+
+// ─── invoke depth tests ─────────────────────────────────────────────
+
+func invokeEvents(t *testing.T, out *bytes.Buffer) map[string]map[string]interface{} {
+	t.Helper()
+	events := make(map[string]map[string]interface{})
+	for _, m := range decodeMessages(t, out) {
+		var params map[string]interface{}
+		json.Unmarshal(m.Params, &params)
+		events[m.Method] = params
+	}
+	return events
+}
+
+func TestEnterInvoke_DepthEvents(t *testing.T) {
+	s, out := newRewindServer(t, "dry-run")
+	os.WriteFile("child.yaml", []byte(`apiVersion: runbook/v0
+meta:
+  name: child
+steps:
+  - id: c1
+    type: manual
+    title: Child step
+    instructions: Nothing to do
+`), 0o644)
+	step := schema.Step{ID: "inv", Type: "invoke", Invoke: &schema.InvokeConfig{Runbook: "child.yaml"}}
+
+	// One level before the limit: warning, then started with the depth
+	out.Reset()
+	s.engine.ChainDepth = runtime.MaxChainDepth - 2
+	if err := s.enterInvoke(&Message{}, step); err != nil {
+		t.Fatalf("enterInvoke: %v", err)
+	}
+	events := invokeEvents(t, out)
+	if _, ok := events["event/invokeDepthWarning"]; !ok {
+		t.Errorf("expected event/invokeDepthWarning, got %s", out.String())
+	}
+	started := events["event/invokeStarted"]
+	if started["chainDepth"] != float64(runtime.MaxChainDepth-1) || started["maxChainDepth"] != float64(runtime.MaxChainDepth) {
+		t.Errorf("invokeStarted = %v, want chainDepth %d and maxChainDepth %d", started, runtime.MaxChainDepth-1, runtime.MaxChainDepth)
+	}
+	if s.engine.ChainDepth != runtime.MaxChainDepth-1 {
+		t.Errorf("child ChainDepth = %d, want %d", s.engine.ChainDepth, runtime.MaxChainDepth-1)
+	}
+
+	// Shallow invokes carry the depth without a warning
+	out.Reset()
+	s.engine.ChainDepth = 0
+	if err := s.enterInvoke(&Message{}, step); err != nil {
+		t.Fatalf("enterInvoke: %v", err)
+	}
+	if _, ok := invokeEvents(t, out)["event/invokeDepthWarning"]; ok {
+		t.Errorf("unexpected depth warning at depth 1: %s", out.String())
+	}
+
+	// At the limit: exceeded event and no invokeStarted
+	out.Reset()
+	s.engine.ChainDepth = runtime.MaxChainDepth
+	if err := s.enterInvoke(&Message{}, step); err == nil || !strings.Contains(err.Error(), "exceeds maximum") {
+		t.Fatalf("expected depth error, got %v", err)
+	}
+	events = invokeEvents(t, out)
+	exceeded, ok := events["event/invokeDepthExceeded"]
+	if !ok || exceeded["chainDepth"] != float64(runtime.MaxChainDepth+1) {
+		t.Errorf("expected event/invokeDepthExceeded at depth %d, got %s", runtime.MaxChainDepth+1, out.String())
+	}
+	if _, ok := events["event/invokeStarted"]; ok {
+		t.Errorf("invokeStarted must not be sent past the limit: %s", out.String())
+	}
+}
+
+// ─── on_failure tests ───────────────────────────────────────────────
+
+// failExecutor fails every command.
+type failExecutor struct{}
+
+func (failExecutor) Execute(ctx context.Context, command string, args []string, env []string) (*providers.CommandResult, error) {
+	return nil, fmt.Errorf("%s: connection refused", command)
+}
+
+func TestExecuteTreeStep_OnFailureRoutes(t *testing.T) {
+	t.Chdir(t.TempDir())
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "on-failure-test"},
+		Tree: []schema.TreeNode{
+			{Step: schema.Step{ID: "drain", Type: "cli", With: &schema.CLIStepConfig{Argv: []string{"drain"}}, OnFailure: "cleanup"}},
+			{Step: schema.Step{ID: "verify", Type: "manual", Title: "Verify"}},
+			{Step: schema.Step{ID: "cleanup", Type: "cli", With: &schema.CLIStepConfig{Argv: []string{"cleanup"}}, OnFailure: "verify"}},
+			{Step: schema.Step{ID: "report", Type: "manual", Title: "Report"}},
+		},
+	}
+	engine, err := runtime.NewEngine(rb, failExecutor{}, nil, "real", "tester")
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	s.engine = engine
+	s.runbook = rb
+	s.treeCursor = newTreeCursor(rb.Tree)
+	id := 1
+	msg := &Message{JSONRPC: "2.0", ID: &id, Method: "exec/next"}
+
+	s.executeTreeStep(msg, s.treeCursor.pop())
+	if got := s.treeCursor.pending[0].node.Step.ID; got != "cleanup" || len(s.treeCursor.pending) != 2 {
+		t.Fatalf("after drain: next = %q (%d pending), want cleanup then report", got, len(s.treeCursor.pending))
+	}
+	if got := engine.ResolveTemplatePublic("{{ .drain.error }}"); !strings.Contains(got, "connection refused") {
+		t.Errorf("drain.error = %q", got)
+	}
+
+	// The target fails too and routes backward to verify
+	s.executeTreeStep(msg, s.treeCursor.pop())
+	if got := s.treeCursor.pending[0].node.Step.ID; got != "verify" || len(s.treeCursor.pending) != 3 {
+		t.Fatalf("after cleanup: next = %q (%d pending), want verify", got, len(s.treeCursor.pending))
+	}
+
+	var routed []string
+	for _, m := range decodeMessages(t, &out) {
+		if m.Method == "event/stepRouted" {
+			var p struct {
+				StepID string `json:"stepId"`
+				Target string `json:"target"`
+			}
+			json.Unmarshal(m.Params, &p)
+			routed = append(routed, p.StepID+"→"+p.Target)
+		}
+	}
+	if strings.Join(routed, ",") != "drain→cleanup,cleanup→verify" {
+		t.Errorf("routed events = %v", routed)
+	}
+}
+
+// ─── transport tests ────────────────────────────────────────────────
+
+func TestWebSocketHandler_DispatchesPerConnection(t *testing.T) {
+	var configured atomic.Int32
+	srv := httptest.NewServer(WebSocketHandler(func(s *Server) { configured.Add(1) }))
+	defer srv.Close()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{not json`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteJSON(map[string]any{"jsonrpc": "2.0", "id": 7, "method": "exec/getHistory"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var parseErr, noExec Message
+	if err := conn.ReadJSON(&parseErr); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if err := conn.ReadJSON(&noExec); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if parseErr.Error == nil || parseErr.Error.Code != -32700 {
+		t.Errorf("expected -32700 parse error, got %+v", parseErr)
+	}
+	if noExec.ID == nil || *noExec.ID != 7 || noExec.Error == nil || noExec.Error.Code != -32607 {
+		t.Errorf("expected -32607 reply to id 7, got %+v", noExec)
+	}
+	if n := configured.Load(); n != 1 {
+		t.Errorf("configure called %d times, want 1", n)
+	}
+}
+
+func TestWebSocketHandler_CheckOrigin(t *testing.T) {
+	var allowed []string
+	srv := httptest.NewServer(WebSocketHandler(func(s *Server) { s.AllowedOrigins = allowed }))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	dial := func(origin string) error {
+		h := http.Header{}
+		if origin != "" {
+			h.Set("Origin", origin)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(url, h)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	if err := dial(""); err != nil {
+		t.Errorf("no Origin: %v", err)
+	}
+	if err := dial(srv.URL); err != nil {
+		t.Errorf("same origin: %v", err)
+	}
+	if err := dial("https://evil.example"); err == nil {
+		t.Error("cross-origin page was allowed to connect")
+	}
+
+	allowed = []string{"vscode-webview://abc"}
+	if err := dial("vscode-webview://abc"); err != nil {
+		t.Errorf("allowed origin: %v", err)
+	}
+	if err := dial("https://evil.example"); err == nil {
+		t.Error("origin outside AllowedOrigins was allowed to connect")
+	}
+}
+
+func TestRun_StreamTransportEOF(t *testing.T) {
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader("\n{\"jsonrpc\":\"2.0\",\"id\":1,\"method\":\"nope\"}\n"), &out)
+	if err := s.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	msgs := decodeMessages(t, &out)
+	if len(msgs) != 1 || msgs[0].Error == nil || msgs[0].Error.Code != -32601 {
+		t.Fatalf("expected one -32601 reply, got %s", out.String())
+	}
+}
+
+func TestRun_HeartbeatShutsDownSilentClient(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	defer inW.Close()
+
+	s := NewWithIO(inR, outW)
+	s.Heartbeat = 20 * time.Millisecond
+	done := make(chan error, 1)
+	go func() { done <- s.Run() }()
+
+	scanner := bufio.NewScanner(outR)
+	readPing := func() {
+		t.Helper()
+		if !scanner.Scan() {
+			t.Fatalf("expected a ping: %v", scanner.Err())
+		}
+		var m Message
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil || m.Method != "ping" || m.ID != nil {
+			t.Fatalf("expected ping notification, got %s", scanner.Text())
+		}
+	}
+
+	// An answered ping resets the count
+	readPing()
+	if _, err := io.WriteString(inW, `{"jsonrpc":"2.0","method":"pong"}`+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	readPing()
+	readPing()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("server did not shut down after two unanswered pings")
+	}
+	if s.ctx.Err() == nil {
+		t.Error("expected the server context to be cancelled")
+	}
+}
+
+func TestRun_HeartbeatDuringSlowHandler(t *testing.T) {
+	dir := t.TempDir()
+	rbPath := filepath.Join(dir, "rb.yaml")
+	os.WriteFile(rbPath, []byte(`apiVersion: runbook/v0
+meta:
+  name: slow-test
+steps:
+  - id: wait
+    type: cli
+    title: Wait
+    with:
+      argv: ["sleep", "0.3"]
+`), 0o644)
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	defer inW.Close()
+
+	s := NewWithIO(inR, outW)
+	s.Heartbeat = 30 * time.Millisecond
+	done := make(chan error, 1)
+	go func() { done <- s.Run() }()
+
+	// Answer every ping while waiting for the exec/next reply
+	replies := make(chan Message, 4)
+	go func() {
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			var m Message
+			if json.Unmarshal(scanner.Bytes(), &m) != nil {
+				continue
+			}
+			if m.Method == "ping" {
+				io.WriteString(inW, `{"jsonrpc":"2.0","method":"pong"}`+"\n")
+			} else if m.ID != nil {
+				replies <- m
+			}
+		}
+	}()
+
+	send := func(id int, method, params string) {
+		t.Helper()
+		line := fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":%q,"params":%s}`+"\n", id, method, params)
+		if _, err := io.WriteString(inW, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	await := func(id int) Message {
+		t.Helper()
+		for {
+			select {
+			case m := <-replies:
+				if *m.ID == id {
+					return m
+				}
+			case err := <-done:
+				t.Fatalf("server stopped while awaiting reply %d: %v", id, err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("no reply to request %d", id)
+			}
+		}
+	}
+
+	send(1, "exec/start", fmt.Sprintf(`{"runbook": %q, "mode": "real", "cwd": %q}`, rbPath, dir))
+	if m := await(1); m.Error != nil {
+		t.Fatalf("exec/start: %s", m.Error.Message)
+	}
+	// The step outlasts several heartbeat intervals
+	send(2, "exec/next", "{}")
+	if m := await(2); m.Error != nil {
+		t.Fatalf("exec/next: %s", m.Error.Message)
+	}
+	if s.ctx.Err() != nil {
+		t.Error("server shut down although the client answered every ping")
+	}
+}
+
+func TestExpireSessions(t *testing.T) {
+	s := NewWithIO(strings.NewReader(""), io.Discard)
+	s.SessionDir = t.TempDir()
+	if s.SessionTTL != 7*24*time.Hour {
+		t.Errorf("default SessionTTL = %v, want 7 days", s.SessionTTL)
+	}
+	old := time.Now().Add(-30 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	runs := map[string]string{
+		"finished":   "ended_at: \"" + old + "\"\n",
+		"running":    "started_at: \"" + old + "\"\n",
+		"resumable":  "ended_at: \"" + old + "\"\n",
+		"just-ended": "ended_at: \"" + time.Now().UTC().Format(time.RFC3339) + "\"\n",
+	}
+	for id, manifest := range runs {
+		os.MkdirAll(filepath.Join(s.SessionDir, id), 0o755)
+		os.WriteFile(filepath.Join(s.SessionDir, id, "run.yaml"), []byte(manifest), 0o644)
+	}
+	os.WriteFile(filepath.Join(s.SessionDir, "resumable", "session.json"), []byte("{}"), 0o644)
+
+	var logs bytes.Buffer
+	s.Logger = slog.New(slog.NewJSONHandler(&logs, nil))
+
+	// A stopped server still cleans once, then returns
+	s.cancel()
+	s.expireSessions(s.SessionTTL)
+
+	var rec map[string]any
+	if err := json.Unmarshal(logs.Bytes(), &rec); err != nil {
+		t.Fatalf("want one JSON log record, got %q: %v", logs.String(), err)
+	}
+	if rec["msg"] != "removed expired run" || rec["runId"] != "finished" {
+		t.Errorf("log record = %v", rec)
+	}
+
+	for id := range runs {
+		_, err := os.Stat(filepath.Join(s.SessionDir, id))
+		if removed := os.IsNotExist(err); removed != (id == "finished") {
+			t.Errorf("run %s removed = %v", id, removed)
+		}
+	}
+}
+
+func TestEvidenceCollectorSelection(t *testing.T) {
+	s := &Server{}
+	if c, err := s.evidenceCollector(); err != nil {
+		t.Fatal(err)
+	} else if _, ok := c.(*ServeCollector); !ok {
+		t.Errorf("default collector = %T, want *ServeCollector", c)
+	}
+
+	s.EvidenceCollector = "azureblob"
+	t.Setenv(providers.EnvAzureStorageAccount, "acct")
+	t.Setenv(providers.EnvAzureStorageContainer, "evidence")
+	c, err := s.evidenceCollector()
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, ok := c.(*providers.AzureBlobCollector)
+	if !ok {
+		t.Fatalf("collector = %T, want *providers.AzureBlobCollector", c)
+	}
+	if _, ok := bc.Inner.(*ServeCollector); !ok {
+		t.Errorf("inner collector = %T, want *ServeCollector", bc.Inner)
+	}
+
+	s.EvidenceCollector = "s3"
+	if _, err := s.evidenceCollector(); err == nil {
+		t.Error("expected an error for an unknown collector")
+	}
+}
@@ -3,6 +3,7 @@ package serve
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
@@ -14,7 +15,7 @@ import (
 // ─── Serializable types ─────────────────────────────────────────────
 
 // SessionState captures the full serializable state of a serve-mode tree execution.
-// Written to .runbook/runs/<root_run_id>/session.json after each mutation.
+// Written to <SessionDir>/<root_run_id>/session.json after each mutation.
 type SessionState struct {
 	RunID             string                  `json:"run_id"`
 	RunbookPath       string                  `json:"runbook_path"`
@@ -34,6 +35,9 @@ type SessionState struct {
 
 	// Invoke stack (outermost parent first)
 	InvokeStack []InvokeFrameRef `json:"invoke_stack,omitempty"`
+
+	// Rewind points for exec/rewind (oldest first)
+	RewindPoints []RewindPointRef `json:"rewind_points,omitempty"`
 }
 
 // PendingNodeRef is a serializable reference to a pending tree cursor node.
@@ -72,6 +76,18 @@ type InvokeFrameRef struct {
 	Pending      []PendingNodeRef  `json:"pending"`
 }
 
+// RewindPointRef is a serializable rewind point: the cursor queue just
+// before a step executed, headed by that step.
+type RewindPointRef struct {
+	StepID   string            `json:"step_id"`
+	StepIdx  int               `json:"step_idx"`
+	RunID    string            `json:"run_id"`
+	Vars     map[string]string `json:"vars"`
+	Captures map[string]string `json:"captures"`
+	History  int               `json:"history"`
+	Pending  []PendingNodeRef  `json:"pending"`
+}
+
 // ─── Session save ───────────────────────────────────────────────────
 
 // saveSession persists the current server state to session.json in the root
@@ -83,14 +99,12 @@ func (s *Server) saveSession() {
 	if s.treeCursor == nil && s.pendingManual == nil {
 		return
 	}
-	baseDir := s.rootBaseDir
-	if baseDir == "" {
+	if s.sessionFile == "" {
 		return
 	}
 	session := s.buildSessionState()
-	path := filepath.Join(baseDir, "session.json")
-	if err := writeSessionFile(session, path); err != nil {
-		fmt.Fprintf(os.Stderr, "serve: session save error: %v\n", err)
+	if err := writeSessionFile(session, s.sessionFile); err != nil {
+		s.Logger.Error("session save failed", slog.Any("error", err))
 	}
 }
 
@@ -136,6 +150,18 @@ func (s *Server) buildSessionState() *SessionState {
 		})
 	}
 
+	for _, pt := range s.rewindPoints {
+		session.RewindPoints = append(session.RewindPoints, RewindPointRef{
+			StepID:   pt.stepID,
+			StepIdx:  pt.stepIdx,
+			RunID:    pt.runID,
+			Vars:     pt.vars,
+			Captures: pt.captures,
+			History:  pt.history,
+			Pending:  serializePendingQueue(pt.pending),
+		})
+	}
+
 	// When inside an invoke, adjust top-level IDs to root run
 	if len(s.invokeStack) > 0 {
 		session.ActiveRunbookPath = s.engine.RunbookPath
@@ -277,13 +303,50 @@ func deserializePendingNode(ref PendingNodeRef, tidx *treeIndex) (pendingNode, e
 	}
 }
 
+// deserializeRewindPoints rebuilds rewind points. Only points from runID can
+// be resolved against tidx; the rest keep their identity (for invoke
+// boundary checks) but no cursor queue.
+func deserializeRewindPoints(refs []RewindPointRef, tidx *treeIndex, runID string, logger *slog.Logger) []rewindPoint {
+	points := make([]rewindPoint, 0, len(refs))
+	for _, ref := range refs {
+		pt := rewindPoint{stepID: ref.StepID, stepIdx: ref.StepIdx, runID: ref.RunID, vars: ref.Vars, captures: ref.Captures, history: ref.History}
+		if ref.RunID == runID {
+			pending, err := deserializePendingQueue(ref.Pending, tidx)
+			if err != nil {
+				logger.Warn("couldn't restore rewind point", slog.String("stepId", ref.StepID), slog.Any("error", err))
+				pt.runID = ""
+			} else {
+				pt.pending = pending
+			}
+		}
+		points = append(points, pt)
+	}
+	return points
+}
+
 // ─── File I/O ───────────────────────────────────────────────────────
 
+// sessionPath returns the session.json path for a root run, made absolute
+// so a resumed run's chdir does not move it. It is "" when SessionDir is.
+func (s *Server) sessionPath(runID string) string {
+	if s.SessionDir == "" {
+		return ""
+	}
+	path := filepath.Join(s.SessionDir, runID, "session.json")
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path
+}
+
 func writeSessionFile(session *SessionState, path string) error {
 	data, err := json.MarshalIndent(session, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal session: %w", err)
 	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create session dir: %w", err)
+	}
 	return os.WriteFile(path, data, 0644)
 }
 
@@ -1,13 +1,17 @@
 package serve
 
 import (
+	"bytes"
 	"encoding/json"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ormasoftchile/gert/pkg/providers"
+	"github.com/ormasoftchile/gert/pkg/runtime"
 	"github.com/ormasoftchile/gert/pkg/schema"
 )
 
@@ -271,6 +275,74 @@ func TestWriteLoadSessionFile(t *testing.T) {
 	}
 }
 
+func TestSaveSession_SessionDir(t *testing.T) {
+	t.Chdir(t.TempDir())
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "session-dir-test"},
+		Tree:       []schema.TreeNode{{Step: schema.Step{ID: "s1", Type: "manual", Title: "S1"}}},
+	}
+	engine, err := runtime.NewEngine(rb, nil, nil, "real", "tester")
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	s.SessionDir = filepath.Join(t.TempDir(), "sessions")
+	s.engine = engine
+	s.runbook = rb
+	s.treeCursor = newTreeCursor(rb.Tree)
+	s.sessionFile = s.sessionPath(engine.GetRunID())
+
+	s.saveSession()
+	if _, err := os.Stat(filepath.Join(s.SessionDir, engine.GetRunID(), "session.json")); err != nil {
+		t.Errorf("session not written under SessionDir: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(engine.GetBaseDir(), "session.json")); err == nil {
+		t.Error("session also written to the run directory")
+	}
+
+	// An empty SessionDir keeps sessions in memory: nothing to resume from
+	s.SessionDir = ""
+	id := 1
+	s.handleExecResume(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/start"}, ExecStartParams{ResumeRunID: engine.GetRunID()})
+	msgs := decodeMessages(t, &out)
+	if last := msgs[len(msgs)-1]; last.Error == nil || !strings.Contains(last.Error.Message, "persistence is disabled") {
+		t.Errorf("expected resume to fail with persistence disabled, got %+v", last)
+	}
+}
+
+func TestDeserializeRewindPoints(t *testing.T) {
+	tree := []schema.TreeNode{
+		{Step: schema.Step{ID: "s1", Type: "cli", Title: "S1"}},
+		{Step: schema.Step{ID: "s2", Type: "manual", Title: "S2"}},
+	}
+	tidx := buildTreeIndex(tree)
+
+	refs := []RewindPointRef{
+		{StepID: "p1", StepIdx: 0, RunID: "run-parent", Pending: []PendingNodeRef{{Kind: "step", StepID: "p1"}}},
+		{StepID: "s1", StepIdx: 1, RunID: "run-child", Vars: map[string]string{"a": "b"},
+			Captures: map[string]string{"c": "d"}, History: 1,
+			Pending: []PendingNodeRef{{Kind: "step", StepID: "s1"}, {Kind: "step", StepID: "s2"}}},
+	}
+
+	points := deserializeRewindPoints(refs, tidx, "run-child", slog.New(slog.DiscardHandler))
+	if len(points) != 2 {
+		t.Fatalf("len(points) = %d, want 2", len(points))
+	}
+	// Parent-run points keep identity but no cursor queue
+	if points[0].runID != "run-parent" || points[0].pending != nil {
+		t.Errorf("parent point = %+v, want runID kept and no pending", points[0])
+	}
+	if len(points[1].pending) != 2 || points[1].pending[0].node.Step.ID != "s1" {
+		t.Errorf("child point pending not restored: %v", serializePendingQueue(points[1].pending))
+	}
+	if points[1].vars["a"] != "b" || points[1].captures["c"] != "d" || points[1].history != 1 || points[1].stepIdx != 1 {
+		t.Errorf("child point = %+v", points[1])
+	}
+}
+
 // ─── cloneMap test ──────────────────────────────────────────────────
 
 func TestCloneMap(t *testing.T) {
@@ -0,0 +1,156 @@
+package serve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+// TelemetrySchemaVersion is the version of the RunTelemetry payload.
+// Bump it whenever a field is added, removed or changes meaning.
+const TelemetrySchemaVersion = 1
+
+// EnvNoTelemetry disables telemetry when set to 1 (or true), whatever the
+// host's flags say.
+const EnvNoTelemetry = "GERT_NO_TELEMETRY"
+
+// telemetryTimeout bounds a telemetry POST; reports are never retried.
+const telemetryTimeout = 2 * time.Second
+
+// Error categories reported in RunTelemetry.ErrorCategory.
+const (
+	TelemetryErrorNone       = ""
+	TelemetryErrorStepFailed = "step_failed" // at least one step failed
+	TelemetryErrorRun        = "run_error"   // the engine stopped the run with an error
+)
+
+// RunTelemetry is the anonymous usage report sent for each completed run.
+// It holds counts and categories only: never variables, captures, step IDs,
+// titles, runbook names or incident IDs.
+type RunTelemetry struct {
+	SchemaVersion   int            `json:"schema_version"`
+	Mode            string         `json:"mode"`
+	StepCount       int            `json:"step_count"`
+	StepTypes       map[string]int `json:"step_types"`                  // steps in the runbook by type
+	FailedStepTypes map[string]int `json:"failed_step_types,omitempty"` // failed step executions by type
+	DurationMS      int64          `json:"duration_ms"`
+	OutcomeCategory string         `json:"outcome_category,omitempty"` // resolved, escalated, no_action, needs_rca
+	ErrorCategory   string         `json:"error_category,omitempty"`
+}
+
+// telemetryEnabled reports whether run reports should be sent.
+func (s *Server) telemetryEnabled() bool {
+	if s.TelemetryEndpoint == "" || s.NoTelemetry {
+		return false
+	}
+	off, _ := strconv.ParseBool(os.Getenv(EnvNoTelemetry))
+	return !off
+}
+
+// reportRun sends the telemetry report for the current root run once,
+// without waiting for the endpoint. runErr is the error that ended the run,
+// if any.
+func (s *Server) reportRun(runErr error) {
+	if !s.telemetryEnabled() || s.engine == nil || s.runbook == nil {
+		return
+	}
+	if s.telemetryRunID == s.engine.State.RunID {
+		return
+	}
+	s.telemetryRunID = s.engine.State.RunID
+
+	report := s.buildTelemetry(runErr)
+	go postTelemetry(s.TelemetryEndpoint, report)
+}
+
+// buildTelemetry summarizes the current run. Step IDs are used only to
+// look up step types and are not included in the report.
+func (s *Server) buildTelemetry(runErr error) *RunTelemetry {
+	types := map[string]string{}
+	var collect func(steps []schema.Step)
+	var collectTree func(nodes []schema.TreeNode)
+	collect = func(steps []schema.Step) {
+		for _, step := range steps {
+			types[step.ID] = step.Type
+		}
+	}
+	collectTree = func(nodes []schema.TreeNode) {
+		for _, n := range nodes {
+			if n.Iterate != nil {
+				collectTree(n.Iterate.Steps)
+				continue
+			}
+			types[n.Step.ID] = n.Step.Type
+			for _, b := range n.Branches {
+				collectTree(b.Steps)
+			}
+		}
+	}
+	collect(s.runbook.Steps)
+	collectTree(s.runbook.Tree)
+
+	report := &RunTelemetry{
+		SchemaVersion: TelemetrySchemaVersion,
+		Mode:          s.engine.State.Mode,
+		StepCount:     len(types),
+		StepTypes:     map[string]int{},
+	}
+	for _, t := range types {
+		report.StepTypes[t]++
+	}
+	for _, r := range s.engine.State.History {
+		if r == nil || r.Status != "failed" {
+			continue
+		}
+		if report.FailedStepTypes == nil {
+			report.FailedStepTypes = map[string]int{}
+		}
+		t := types[r.StepID]
+		if t == "" {
+			t = "unknown" // a step from an invoked runbook
+		}
+		report.FailedStepTypes[t]++
+	}
+	if !s.engine.State.StartedAt.IsZero() {
+		report.DurationMS = time.Since(s.engine.State.StartedAt).Milliseconds()
+	}
+	if o := s.engine.GetOutcome(); o != nil {
+		report.OutcomeCategory = o.State
+	}
+	switch {
+	case runErr != nil:
+		report.ErrorCategory = TelemetryErrorRun
+	case len(report.FailedStepTypes) > 0:
+		report.ErrorCategory = TelemetryErrorStepFailed
+	}
+	return report
+}
+
+// postTelemetry sends report to endpoint. Failures are ignored: telemetry
+// must never affect a run.
+func postTelemetry(endpoint string, report *RunTelemetry) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
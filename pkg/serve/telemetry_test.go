@@ -0,0 +1,107 @@
+package serve
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ormasoftchile/gert/pkg/providers"
+	"github.com/ormasoftchile/gert/pkg/runtime"
+	"github.com/ormasoftchile/gert/pkg/schema"
+)
+
+func TestReportRun(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv(EnvNoTelemetry, "")
+
+	bodies := make(chan []byte, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies <- b
+	}))
+	defer srv.Close()
+
+	rb := &schema.Runbook{
+		APIVersion: "runbook/v0",
+		Meta:       schema.Meta{Name: "secret-incident-1234", Vars: map[string]string{"cluster": "prod-eu"}},
+		Tree: []schema.TreeNode{
+			{Step: schema.Step{ID: "drain-node", Type: "cli", Title: "Drain", With: &schema.CLIStepConfig{Argv: []string{"drain"}}}},
+			{Step: schema.Step{ID: "confirm", Type: "manual", Title: "Confirm"}},
+		},
+	}
+	engine, err := runtime.NewEngine(rb, nil, nil, "real", "tester")
+	if err != nil {
+		t.Fatalf("NewEngine: %v", err)
+	}
+	engine.State.History = append(engine.State.History,
+		&providers.StepResult{StepID: "drain-node", Status: "failed", Captures: map[string]string{"node": "aks-1"}},
+		&providers.StepResult{StepID: "confirm", Status: "passed"},
+	)
+	engine.SetOutcome("escalated", "confirm", "page the on-call")
+
+	var out bytes.Buffer
+	s := NewWithIO(strings.NewReader(""), &out)
+	s.engine = engine
+	s.runbook = rb
+	s.treeCursor = newTreeCursor(nil)
+	s.TelemetryEndpoint = srv.URL
+	id := 1
+	s.completeTree(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/next"})
+
+	var body []byte
+	select {
+	case body = <-bodies:
+	case <-time.After(3 * time.Second):
+		t.Fatal("no telemetry report received")
+	}
+	var report RunTelemetry
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	if report.SchemaVersion != TelemetrySchemaVersion || report.StepCount != 2 ||
+		report.StepTypes["cli"] != 1 || report.StepTypes["manual"] != 1 || report.FailedStepTypes["cli"] != 1 {
+		t.Errorf("report = %+v", report)
+	}
+	if report.OutcomeCategory != "escalated" || report.ErrorCategory != TelemetryErrorStepFailed || report.Mode != "real" {
+		t.Errorf("report = %+v", report)
+	}
+	for _, private := range []string{"secret-incident", "prod-eu", "drain-node", "confirm", "Drain", "aks-1", "on-call", "tester"} {
+		if strings.Contains(string(body), private) {
+			t.Errorf("telemetry leaked %q: %s", private, body)
+		}
+	}
+
+	// A run is reported once
+	s.completeTree(&Message{JSONRPC: "2.0", ID: &id, Method: "exec/next"})
+	select {
+	case b := <-bodies:
+		t.Errorf("unexpected second report: %s", b)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestTelemetryEnabled(t *testing.T) {
+	t.Setenv(EnvNoTelemetry, "")
+	s := &Server{}
+	if s.telemetryEnabled() {
+		t.Error("telemetry should be off without an endpoint")
+	}
+	s.TelemetryEndpoint = "https://telemetry.example.com/v1"
+	if !s.telemetryEnabled() {
+		t.Error("telemetry should be on with an endpoint")
+	}
+	t.Setenv(EnvNoTelemetry, "1")
+	if s.telemetryEnabled() {
+		t.Errorf("%s=1 should disable telemetry", EnvNoTelemetry)
+	}
+	t.Setenv(EnvNoTelemetry, "")
+	s.NoTelemetry = true
+	if s.telemetryEnabled() {
+		t.Error("NoTelemetry should disable telemetry")
+	}
+}
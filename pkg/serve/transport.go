@@ -0,0 +1,181 @@
+package serve
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+	"github.com/ormasoftchile/gert/pkg/logging"
+)
+
+// transport carries JSON-RPC messages between a Server and one client.
+// recv returns io.EOF once the client has gone, and a *parseError for a
+// message that is not valid JSON (the server replies and keeps reading).
+type transport interface {
+	send(msg *Message) error
+	recv() (*Message, error)
+}
+
+// parseError reports a malformed incoming message.
+type parseError struct {
+	err error
+}
+
+func (e *parseError) Error() string { return fmt.Sprintf("parse error: %v", e.err) }
+
+func decodeMessage(data []byte) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, &parseError{err: err}
+	}
+	return &msg, nil
+}
+
+// ─── stdio ──────────────────────────────────────────────────────────
+
+// streamTransport exchanges newline-delimited JSON over a reader/writer
+// pair: stdio, or the in-memory pipes used by the TUI.
+type streamTransport struct {
+	scanner *bufio.Scanner
+	w       io.Writer
+}
+
+func newStreamTransport(r io.Reader, w io.Writer) *streamTransport {
+	scanner := bufio.NewScanner(r)
+	// Increase buffer for large messages
+	scanner.Buffer(make([]byte, 0, 1024*1024), 1024*1024)
+	return &streamTransport{scanner: scanner, w: w}
+}
+
+func (t *streamTransport) send(msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(t.w, "%s\n", data)
+	return err
+}
+
+func (t *streamTransport) recv() (*Message, error) {
+	for t.scanner.Scan() {
+		line := t.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		return decodeMessage(line)
+	}
+	if err := t.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// ─── WebSocket ──────────────────────────────────────────────────────
+
+// wsTransport exchanges one JSON-RPC message per WebSocket text frame.
+type wsTransport struct {
+	conn *websocket.Conn
+}
+
+func (t *wsTransport) send(msg *Message) error {
+	return t.conn.WriteJSON(msg)
+}
+
+func (t *wsTransport) recv() (*Message, error) {
+	_, data, err := t.conn.ReadMessage()
+	if err != nil {
+		if websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	return decodeMessage(data)
+}
+
+// checkOrigin accepts requests without an Origin header (non-browser
+// clients), same-origin requests, and origins listed in allowed, where "*"
+// accepts any. Browsers always send Origin, so a page on another site
+// cannot open a connection unless its origin is allowed.
+func checkOrigin(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, a := range allowed {
+			if a == "*" || strings.EqualFold(a, origin) {
+				return true
+			}
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+}
+
+// WebSocketHandler upgrades each request to a WebSocket and runs a fresh
+// Server on it until the client disconnects, so connections share no
+// execution state. configure, if non-nil, is applied to every new Server
+// before it starts (e.g. to set InputManager, AllowRewind or
+// AllowedOrigins). Cross-origin requests are refused unless their origin
+// is in Server.AllowedOrigins.
+func WebSocketHandler(configure func(*Server)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := newWithTransport(nil)
+		if configure != nil {
+			configure(s)
+		}
+		upgrader := websocket.Upgrader{CheckOrigin: checkOrigin(s.AllowedOrigins)}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			s.Logger.Error("websocket upgrade failed", slog.String("remoteAddr", r.RemoteAddr), slog.Any("error", err))
+			s.cancel()
+			return
+		}
+		defer conn.Close()
+
+		s.conn = &wsTransport{conn: conn}
+		s.Logger.Info("websocket client connected", slog.String("remoteAddr", r.RemoteAddr))
+		if err := s.Run(); err != nil {
+			s.Logger.Error("websocket client failed", slog.String("remoteAddr", r.RemoteAddr), slog.Any("error", err))
+		}
+		s.Logger.Info("websocket client disconnected", slog.String("remoteAddr", r.RemoteAddr))
+	})
+}
+
+// ListenWebSocket serves the JSON-RPC protocol over WebSocket on addr, for
+// hosts where stdio is not available (containers, codespaces). TLS is used
+// when both certFile and keyFile are set.
+//
+// The endpoint has no authentication: any client that can reach addr can
+// call exec/start and run a runbook's CLI steps. Bind addr to loopback or a
+// trusted network. Browser pages from other origins are refused; list the
+// origins a webview or port-forward connects from in Server.AllowedOrigins.
+func ListenWebSocket(addr, certFile, keyFile string, configure func(*Server)) error {
+	if (certFile == "") != (keyFile == "") {
+		return errors.New("TLS needs both a certificate and a key")
+	}
+	srv := &http.Server{Addr: addr, Handler: WebSocketHandler(configure)}
+	logger := configuredLogger(configure)
+	if certFile != "" {
+		logger.Info("listening", slog.String("url", "wss://"+addr))
+		return srv.ListenAndServeTLS(certFile, keyFile)
+	}
+	logger.Info("listening", slog.String("url", "ws://"+addr))
+	return srv.ListenAndServe()
+}
+
+// configuredLogger returns the logger configure would give each Server.
+func configuredLogger(configure func(*Server)) *slog.Logger {
+	s := &Server{Logger: logging.Default()}
+	if configure != nil {
+		configure(s)
+	}
+	return s.Logger
+}
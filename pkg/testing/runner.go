@@ -200,6 +200,9 @@ func (r *Runner) executeReplay(runbookPath string, originalRB *schema.Runbook, s
 	if err != nil {
 		return nil, fmt.Errorf("read inputs: %w", err)
 	}
+	if inputsData, err = replay.DecodeInputs(inputsData); err != nil {
+		return nil, fmt.Errorf("read inputs: %w", err)
+	}
 
 	// Parse inputs — support both flat key:value and nested structures
 	inputs := make(map[string]string)
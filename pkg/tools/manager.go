@@ -57,6 +57,21 @@ func NewManager(executor providers.CommandExecutor, redact []*governance.Compile
 	}
 }
 
+type envKey struct{}
+
+// WithEnv returns a context carrying extra KEY=value environment entries for
+// stdio tool processes started under it. Persistent jsonrpc and mcp
+// processes are shared across steps and keep their own environment.
+func WithEnv(ctx context.Context, env []string) context.Context {
+	return context.WithValue(ctx, envKey{}, env)
+}
+
+// EnvFromContext returns the entries set by WithEnv, or nil.
+func EnvFromContext(ctx context.Context) []string {
+	env, _ := ctx.Value(envKey{}).([]string)
+	return env
+}
+
 // Load parses and validates a .tool.yaml file, registering it by alias.
 // The baseDir is used to resolve relative tool file paths.
 func (m *Manager) Load(alias, path, baseDir string) error {
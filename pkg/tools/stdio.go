@@ -129,7 +129,7 @@ func (m *Manager) executeWithBinaryFallback(ctx context.Context, binary string,
 		}
 		seen[candidate] = true
 		lastBin = candidate
-		result, err := m.executor.Execute(ctx, candidate, argv, nil)
+		result, err := m.executor.Execute(ctx, candidate, argv, EnvFromContext(ctx))
 		if err == nil {
 			return result, candidate, nil
 		}
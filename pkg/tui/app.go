@@ -14,6 +14,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/ormasoftchile/gert/pkg/inputs"
+	"github.com/ormasoftchile/gert/pkg/inputs/akv"
 	"github.com/ormasoftchile/gert/pkg/serve"
 )
 
@@ -146,6 +147,14 @@ func Run(cfg Config) error {
 		srv.InputManager = cfg.InputMgr
 	} else {
 		srv.InputManager = inputs.NewManager()
+		// Key Vault bindings (akv.<vault>/<secret>) when AZURE_KEYVAULT_URL is set
+		kv, err := akv.NewProviderFromEnv()
+		if err != nil {
+			return err
+		}
+		if kv != nil {
+			srv.InputManager.Register(kv)
+		}
 	}
 
 	// Run server in background
@@ -677,6 +686,9 @@ func (m *Model) handleServerEvent(ev serverEventMsg) tea.Cmd {
 		label := fmt.Sprintf("\n%s requires %s evidence: %s\n",
 			detailLabelStyle.Render(stepID), req.Kind, req.Name)
 		m.output.AppendOutput(stepID, label)
+		if req.Error != "" {
+			m.output.AppendOutput(stepID, fmt.Sprintf("  %s\n", req.Error))
+		}
 
 	}
 	return nil
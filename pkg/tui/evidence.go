@@ -52,6 +52,7 @@ type evidenceRequestMsg struct {
 	Items        []string `json:"items,omitempty"`
 	Roles        []string `json:"roles,omitempty"`
 	Min          int      `json:"min,omitempty"`
+	Error        string   `json:"error,omitempty"` // previous value failed validation
 }
 
 // evidenceSubmittedMsg is sent after evidence is submitted to the server.